@@ -17,6 +17,7 @@ import (
 	"github.com/torresglauco/pganalytics-v3/backend/internal/config"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/crypto"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/jobs"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/retention"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/timescale"
 	"go.uber.org/zap"
@@ -169,15 +170,52 @@ func main() {
 		logger.Fatal("Invalid authentication configuration", zap.Error(err))
 	}
 
+	// Initialize data retention; expired data is archived to S3 when an
+	// endpoint is configured, otherwise to the archive directory if set
+	var retentionArchiver retention.Archiver
+	switch {
+	case cfg.RetentionArchiveS3Endpoint != "":
+		retentionArchiver = retention.NewS3Archiver(retention.S3Config{
+			Endpoint:  cfg.RetentionArchiveS3Endpoint,
+			Bucket:    cfg.RetentionArchiveS3Bucket,
+			Region:    cfg.RetentionArchiveS3Region,
+			AccessKey: cfg.RetentionArchiveAccessKey,
+			SecretKey: cfg.RetentionArchiveSecretKey,
+			Prefix:    cfg.RetentionArchiveS3Prefix,
+		})
+	case cfg.RetentionArchiveDir != "":
+		retentionArchiver = retention.NewDirArchiver(cfg.RetentionArchiveDir)
+	}
+	retentionService := retention.NewService(postgresDB, retentionArchiver, logger)
+	apiServer.SetRetentionService(retentionService)
+
 	// Register routes
 	apiServer.RegisterRoutes(router)
 
+	// Deliver logs ingested by other API replicas to this replica's log
+	// stream subscribers
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	go func() {
+		if err := apiServer.LogStream().Listen(backgroundCtx, cfg.DatabaseURL); err != nil && backgroundCtx.Err() == nil {
+			logger.Error("Log stream listener stopped", zap.Error(err))
+		}
+	}()
+
+	// Periodically re-resolve WebSocket access so that tenant membership
+	// and permission changes reach live connections
+	go apiServer.Realtime().RunAccessRefresh(backgroundCtx, time.Minute)
+
 	// Initialize and start health check scheduler for managed instances
 	healthCheckScheduler := jobs.NewHealthCheckScheduler(postgresDB, secretManager, logger)
 	if err := healthCheckScheduler.Start(); err != nil {
 		logger.Error("Failed to start health check scheduler", zap.Error(err))
 	}
 
+	// Apply retention policies periodically
+	retentionJob := jobs.NewRetentionJob(retentionService, cfg.RetentionInterval, logger)
+	retentionJob.Start()
+
 	// Start dashboard aggregation worker if TimescaleDB is available
 	var dashboardWorker *jobs.DashboardAggregationWorker
 	if timescaleDB != nil {
@@ -222,6 +260,9 @@ func main() {
 		}
 	}
 
+	// Stop retention job
+	retentionJob.Stop()
+
 	// Stop dashboard aggregation worker
 	if dashboardWorker != nil {
 		if err := dashboardWorker.Stop(10 * time.Second); err != nil {
@@ -229,6 +270,8 @@ func main() {
 		}
 	}
 
+	stopBackground()
+
 	// Graceful shutdown - stop HTTP server
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
@@ -13,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/auth"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/metrics"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
 	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
 	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 	"go.uber.org/zap"
@@ -324,6 +325,9 @@ func (s *Server) handleUpdateUser(c *gin.Context) {
 		zap.String("updated_by", user.Username),
 	)
 
+	// A changed role or deactivation applies to live WebSocket connections
+	s.refreshRealtimeAccess(updatedUser.ID)
+
 	c.JSON(http.StatusOK, updatedUser)
 }
 
@@ -394,6 +398,8 @@ func (s *Server) handleDeleteUser(c *gin.Context) {
 		zap.String("deleted_by", user.Username),
 	)
 
+	s.closeRealtimeConnections(userID)
+
 	c.JSON(http.StatusNoContent, nil)
 }
 
@@ -1312,6 +1318,16 @@ func (s *Server) handleMetricsPush(c *gin.Context) {
 	startTime := time.Now()
 	metricsInserted := 0
 
+	// Query text is redacted per the collector's tenant policy before storage.
+	// If the policy cannot be loaded, fall back to storing normalized text only.
+	redactor, err := query_performance.LoadCollectorRedactor(c.Request.Context(), s.postgres, req.CollectorID)
+	if err != nil {
+		s.logger.Warn("Failed to load query redaction policy, storing normalized query text",
+			zap.String("collector_id", req.CollectorID),
+			zap.Error(err))
+		redactor, _ = query_performance.NewRedactor(models.RedactionModeNormalize, nil)
+	}
+
 	s.logger.Info("Metrics push received",
 		zap.Int("metrics_count", len(req.Metrics)),
 		zap.String("collector_id", req.CollectorID),
@@ -1398,23 +1414,13 @@ func (s *Server) handleMetricsPush(c *gin.Context) {
 							zap.Int("queries_count", len(db.Queries)),
 						)
 						for _, queryInfo := range db.Queries {
-							// Parse collector ID - if it's not a valid UUID, try to look it up or use a placeholder
-							var collectorUUID uuid.UUID
-							if uid, err := uuid.Parse(req.CollectorID); err == nil {
-								collectorUUID = uid
-							} else {
-								// For collector IDs like "col_demo_001", we'll create a deterministic UUID
-								// by hashing the string
-								collectorUUID = uuid.NewSHA1(uuid.Nil, []byte(req.CollectorID))
-							}
-
 							stat := &models.QueryStats{
 								Time:              timestamp,
-								CollectorID:       collectorUUID,
+								CollectorID:       metricsCollectorUUID(req.CollectorID),
 								DatabaseName:      db.Database,
 								UserName:          "system", // Set from query info if available
 								QueryHash:         queryInfo.Hash,
-								QueryText:         queryInfo.Text,
+								QueryText:         redactor.RedactQuery(queryInfo.Text),
 								Calls:             queryInfo.Calls,
 								TotalTime:         queryInfo.TotalTime,
 								MeanTime:          queryInfo.MeanTime,
@@ -1476,6 +1482,33 @@ func (s *Server) handleMetricsPush(c *gin.Context) {
 							}
 						}
 					}
+				} else if metricType == "pg_activity_samples" {
+					// Active session history: pg_stat_activity sampled by the collector
+					metricsInserted += s.ingestActivitySamples(c, req.CollectorID, metric, redactor)
+				} else if metricType == "pg_locks" {
+					// Lock snapshot: pg_locks and blocked/blocking pairs
+					metricsInserted += s.ingestLockMetrics(c, req.CollectorID, metric, redactor)
+				} else if metricType == "pg_vacuum_stats" {
+					// Vacuum statistics: pg_stat_user_tables, reloptions and autovacuum settings
+					metricsInserted += s.ingestVacuumStats(c, req.CollectorID, metric)
+				} else if metricType == "pg_wraparound" {
+					// Wraparound: database and table XID/multixact ages and xmin horizon blockers
+					metricsInserted += s.ingestWraparoundMetrics(c, req.CollectorID, metric, redactor)
+				} else if metricType == "pg_replication" {
+					// Streaming replication: pg_stat_replication and pg_replication_slots
+					metricsInserted += s.ingestReplicationMetrics(c, req.CollectorID, metric)
+				} else if metricType == "pg_logical_replication" {
+					// Logical replication and the WAL receiver of standbys
+					metricsInserted += s.ingestLogicalReplicationMetrics(c, req.CollectorID, metric)
+				} else if metricType == "pg_health_checks" {
+					// Version health checks: result sets of the check queries the collector pulled
+					metricsInserted += s.ingestHealthCheckResults(c, req.CollectorID, metric)
+				} else if metricType == "pg_settings" {
+					// Server settings: a full pg_settings snapshot
+					metricsInserted += s.ingestSettings(c, req.CollectorID, metric)
+				} else if metricType == "pg_upgrade_objects" {
+					// Catalog objects that block major-version upgrades
+					metricsInserted += s.ingestUpgradeObjects(c, req.CollectorID, metric)
 				}
 			}
 		}
@@ -1487,7 +1520,7 @@ func (s *Server) handleMetricsPush(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	err := s.postgres.UpdateCollectorMetricsCount(ctx, req.CollectorID, len(req.Metrics))
+	err = s.postgres.UpdateCollectorMetricsCount(ctx, req.CollectorID, len(req.Metrics))
 	if err != nil {
 		s.logger.Warn("Failed to update collector metrics",
 			zap.String("collector_id", req.CollectorID),
@@ -1514,6 +1547,16 @@ func (s *Server) handleMetricsPush(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// metricsCollectorUUID returns the UUID metrics are stored under for a collector
+// For collector IDs like "col_demo_001" that are not UUIDs, a deterministic
+// UUID is derived by hashing the string.
+func metricsCollectorUUID(collectorID string) uuid.UUID {
+	if uid, err := uuid.Parse(collectorID); err == nil {
+		return uid
+	}
+	return uuid.NewSHA1(uuid.Nil, []byte(collectorID))
+}
+
 // ============================================================================
 // CONFIGURATION ENDPOINTS
 // ============================================================================
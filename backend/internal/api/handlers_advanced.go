@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
 	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 	"go.uber.org/zap"
 )
@@ -664,7 +666,7 @@ func (s *Server) handleGetSnapshotComparison(c *gin.Context) {
 // ============================================================================
 
 // handleStoreExplainPlan stores EXPLAIN plans from collector
-// POST /api/v1/internal/explain-plans (internal endpoint, no auth shown here)
+// POST /api/v1/internal/explain-plans (internal endpoint, collector token auth)
 func (s *Server) handleStoreExplainPlan(c *gin.Context) {
 	// Parse request body
 	var req struct {
@@ -690,8 +692,8 @@ func (s *Server) handleStoreExplainPlan(c *gin.Context) {
 		return
 	}
 
-	// Create model (not actually stored yet)
-	_ = &models.ExplainPlan{
+	plan := &models.ExplainPlan{
+		Source:               models.ExplainPlanSourceCollector,
 		QueryHash:            req.QueryHash,
 		QueryFingerprintHash: req.QueryFingerprintHash,
 		CollectedAt:          req.CollectedAt,
@@ -708,15 +710,40 @@ func (s *Server) handleStoreExplainPlan(c *gin.Context) {
 		TotalBuffersRead:     req.TotalBuffersRead,
 		TotalBuffersHit:      req.TotalBuffersHit,
 	}
+	collectorID, err := uuid.Parse(c.GetString("collector_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Collector authentication required"})
+		return
+	}
+	plan.CollectorID = &collectorID
 
-	// Store in database (StoreExplainPlan not yet implemented in storage layer)
-	// For now, just return success
-	s.logger.Info("Explain plan received", zap.Int64("query_hash", req.QueryHash))
+	// Plans are redacted per the collector's tenant policy before storage,
+	// falling back to normalized text if the policy cannot be loaded
+	redactor, err := query_performance.LoadCollectorRedactor(c.Request.Context(), s.postgres, collectorID.String())
+	if err != nil {
+		s.logger.Warn("Failed to load query redaction policy, storing normalized plan",
+			zap.String("collector_id", collectorID.String()),
+			zap.Error(err))
+		redactor, _ = query_performance.NewRedactor(models.RedactionModeNormalize, nil)
+	}
+	redactor.RedactExplainPlan(plan)
+
+	// Plans in EXPLAIN (FORMAT JSON) form are checked for issues; others
+	// are stored as sent
+	if err := query_performance.AnalyzeExplainPlan(plan); err != nil {
+		s.logger.Debug("Explain plan not analyzed", zap.Int64("query_hash", req.QueryHash), zap.Error(err))
+	}
+
+	if err := s.postgres.StoreExplainPlan(c.Request.Context(), plan); err != nil {
+		s.logger.Error("Failed to store explain plan", zap.Int64("query_hash", req.QueryHash), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store explain plan"})
+		return
+	}
 
-	// Return response
 	c.JSON(http.StatusCreated, gin.H{
+		"id":         plan.ID,
 		"query_hash": req.QueryHash,
-		"status":     "received",
+		"status":     "stored",
 	})
 }
 
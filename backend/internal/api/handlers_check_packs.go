@@ -0,0 +1,678 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/check_packs"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// CUSTOM HEALTH CHECK PACK ENDPOINTS
+// ============================================================================
+
+// @Summary List check packs
+// @Description List a tenant's custom health check packs with their check counts
+// @Tags HealthChecks
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Success 200 {array} models.HealthCheckPack
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs [get]
+func (s *Server) handleListCheckPacks(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+
+	packs, err := s.postgres.ListHealthCheckPacks(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, packs)
+}
+
+// @Summary Create check pack
+// @Description Create a versioned pack of custom health checks, each with its SQL, version range, assertion, severity, category and remediation (admin only)
+// @Tags HealthChecks
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param pack body models.HealthCheckPackRequest true "Check pack"
+// @Success 201 {object} models.HealthCheckPack
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 409 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs [post]
+func (s *Server) handleCreateCheckPack(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.HealthCheckPackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp := apperrors.BadRequest("Invalid request body", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	pack, err := check_packs.BuildPack(&req)
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid check pack", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+	pack.TenantID = tenantID
+
+	if err := s.postgres.CreateHealthCheckPack(c.Request.Context(), pack); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, pack)
+}
+
+// @Summary Import check pack
+// @Description Import a check pack from a YAML document. A pack with the same name and version is a conflict unless replace=true, which updates its checks by name and removes the ones missing from the document (admin only)
+// @Tags HealthChecks
+// @Accept application/x-yaml
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param replace query bool false "Replace an existing pack with the same name and version"
+// @Success 201 {object} models.HealthCheckPack
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 409 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs/import [post]
+func (s *Server) handleImportCheckPack(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+
+	replace := c.Query("replace") == "true"
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, check_packs.MaxDocumentBytes+1))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid request body", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	pack, err := check_packs.ParseYAML(data)
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid check pack", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+	pack.TenantID = tenantID
+
+	if err := s.postgres.ImportHealthCheckPack(c.Request.Context(), pack, replace); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, pack)
+}
+
+// @Summary Get check pack
+// @Description Get a tenant's check pack with its checks
+// @Tags HealthChecks
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param packId path int true "Check pack ID"
+// @Success 200 {object} models.HealthCheckPack
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs/{packId} [get]
+func (s *Server) handleGetCheckPack(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+	packID, ok := int64Param(c, "packId", "Invalid check pack ID")
+	if !ok {
+		return
+	}
+
+	pack, err := s.postgres.GetHealthCheckPack(c.Request.Context(), tenantID, packID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pack)
+}
+
+// @Summary Export check pack
+// @Description Export a tenant's check pack as a YAML document that can be imported into another tenant
+// @Tags HealthChecks
+// @Produce application/x-yaml
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param packId path int true "Check pack ID"
+// @Success 200 {string} string "Check pack document"
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs/{packId}/export [get]
+func (s *Server) handleExportCheckPack(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+	packID, ok := int64Param(c, "packId", "Invalid check pack ID")
+	if !ok {
+		return
+	}
+
+	pack, err := s.postgres.GetHealthCheckPack(c.Request.Context(), tenantID, packID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	data, err := check_packs.ExportYAML(pack)
+	if err != nil {
+		errResp := apperrors.InternalServerError("Failed to export check pack", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.yaml"`, pack.Name, pack.Version))
+	c.Data(http.StatusOK, "application/x-yaml; charset=utf-8", data)
+}
+
+// @Summary Update check pack
+// @Description Rename a tenant's check pack or change its version and description; checks are edited through the checks endpoints (admin only)
+// @Tags HealthChecks
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param packId path int true "Check pack ID"
+// @Param pack body models.HealthCheckPackRequest true "Check pack"
+// @Success 200 {object} models.HealthCheckPack
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Failure 409 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs/{packId} [put]
+func (s *Server) handleUpdateCheckPack(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+	packID, ok := int64Param(c, "packId", "Invalid check pack ID")
+	if !ok {
+		return
+	}
+
+	var req models.HealthCheckPackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp := apperrors.BadRequest("Invalid request body", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+	if len(req.Checks) > 0 {
+		errResp := apperrors.BadRequest("Invalid check pack", "checks are edited through the checks endpoints or a YAML import")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	pack, err := check_packs.BuildPack(&req)
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid check pack", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+	pack.ID = packID
+	pack.TenantID = tenantID
+	pack.Checks = nil
+
+	if err := s.postgres.UpdateHealthCheckPack(c.Request.Context(), pack); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pack)
+}
+
+// @Summary Delete check pack
+// @Description Remove a tenant's check pack with its checks, results and schedules (admin only)
+// @Tags HealthChecks
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param packId path int true "Check pack ID"
+// @Success 204
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs/{packId} [delete]
+func (s *Server) handleDeleteCheckPack(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+	packID, ok := int64Param(c, "packId", "Invalid check pack ID")
+	if !ok {
+		return
+	}
+
+	if err := s.postgres.DeleteHealthCheckPack(c.Request.Context(), tenantID, packID); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Add check to pack
+// @Description Add a custom health check to a tenant's check pack (admin only)
+// @Tags HealthChecks
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param packId path int true "Check pack ID"
+// @Param check body models.HealthCheckRequest true "Health check"
+// @Success 201 {object} models.VersionHealthCheck
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Failure 409 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs/{packId}/checks [post]
+func (s *Server) handleCreatePackCheck(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+	packID, ok := int64Param(c, "packId", "Invalid check pack ID")
+	if !ok {
+		return
+	}
+
+	check, ok := bindPackCheck(c)
+	if !ok {
+		return
+	}
+
+	if err := s.postgres.CreatePackHealthCheck(c.Request.Context(), tenantID, packID, check); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, check)
+}
+
+// @Summary Update check in pack
+// @Description Replace the definition of a custom health check in a tenant's check pack (admin only)
+// @Tags HealthChecks
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param packId path int true "Check pack ID"
+// @Param checkId path int true "Health check ID"
+// @Param check body models.HealthCheckRequest true "Health check"
+// @Success 200 {object} models.VersionHealthCheck
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Failure 409 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs/{packId}/checks/{checkId} [put]
+func (s *Server) handleUpdatePackCheck(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+	packID, ok := int64Param(c, "packId", "Invalid check pack ID")
+	if !ok {
+		return
+	}
+	checkID, ok := int64Param(c, "checkId", "Invalid health check ID")
+	if !ok {
+		return
+	}
+
+	check, ok := bindPackCheck(c)
+	if !ok {
+		return
+	}
+	check.ID = int(checkID)
+
+	if err := s.postgres.UpdatePackHealthCheck(c.Request.Context(), tenantID, packID, check); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, check)
+}
+
+// @Summary Delete check from pack
+// @Description Remove a custom health check and its results from a tenant's check pack (admin only)
+// @Tags HealthChecks
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param packId path int true "Check pack ID"
+// @Param checkId path int true "Health check ID"
+// @Success 204
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs/{packId}/checks/{checkId} [delete]
+func (s *Server) handleDeletePackCheck(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+	packID, ok := int64Param(c, "packId", "Invalid check pack ID")
+	if !ok {
+		return
+	}
+	checkID, ok := int64Param(c, "checkId", "Invalid health check ID")
+	if !ok {
+		return
+	}
+
+	if err := s.postgres.DeletePackHealthCheck(c.Request.Context(), tenantID, packID, int(checkID)); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary List check pack schedules
+// @Description List the collector groups a tenant's check pack runs on
+// @Tags HealthChecks
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param packId path int true "Check pack ID"
+// @Success 200 {array} models.HealthCheckSchedule
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs/{packId}/schedules [get]
+func (s *Server) handleListCheckPackSchedules(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+	packID, ok := int64Param(c, "packId", "Invalid check pack ID")
+	if !ok {
+		return
+	}
+
+	schedules, err := s.postgres.ListHealthCheckSchedules(c.Request.Context(), tenantID, packID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedules)
+}
+
+// @Summary Schedule check pack
+// @Description Run a tenant's check pack on the collectors of one of its groups every interval_seconds (default one hour). Collectors pick the checks up on their next health check config pull (admin only)
+// @Tags HealthChecks
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param packId path int true "Check pack ID"
+// @Param schedule body models.HealthCheckScheduleRequest true "Schedule"
+// @Success 201 {object} models.HealthCheckSchedule
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Failure 409 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs/{packId}/schedules [post]
+func (s *Server) handleCreateCheckPackSchedule(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+	packID, ok := int64Param(c, "packId", "Invalid check pack ID")
+	if !ok {
+		return
+	}
+
+	var req models.HealthCheckScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp := apperrors.BadRequest("Invalid request body", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	schedule, err := check_packs.BuildSchedule(packID, &req)
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid schedule", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	if err := s.postgres.CreateHealthCheckSchedule(c.Request.Context(), tenantID, schedule); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// @Summary Delete check pack schedule
+// @Description Stop running a tenant's check pack on a collector group (admin only)
+// @Tags HealthChecks
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param packId path int true "Check pack ID"
+// @Param scheduleId path int true "Schedule ID"
+// @Success 204
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/check-packs/{packId}/schedules/{scheduleId} [delete]
+func (s *Server) handleDeleteCheckPackSchedule(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+	packID, ok := int64Param(c, "packId", "Invalid check pack ID")
+	if !ok {
+		return
+	}
+	scheduleID, ok := int64Param(c, "scheduleId", "Invalid schedule ID")
+	if !ok {
+		return
+	}
+
+	if err := s.postgres.DeleteHealthCheckSchedule(c.Request.Context(), tenantID, packID, scheduleID); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ============================================================================
+// COLLECTOR GROUP ENDPOINTS
+// ============================================================================
+
+// @Summary List collector groups
+// @Description List a tenant's collector groups with their collectors
+// @Tags Tenants
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Success 200 {array} models.CollectorGroup
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/collector-groups [get]
+func (s *Server) handleListCollectorGroups(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+
+	groups, err := s.postgres.ListCollectorGroups(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+// @Summary Create collector group
+// @Description Create a named group of the tenant's collectors that check packs can be scheduled on (admin only)
+// @Tags Tenants
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param group body models.CollectorGroupRequest true "Collector group"
+// @Success 201 {object} models.CollectorGroup
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 409 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/collector-groups [post]
+func (s *Server) handleCreateCollectorGroup(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.CollectorGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp := apperrors.BadRequest("Invalid request body", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	group, err := check_packs.BuildGroup(&req)
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector group", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+	group.TenantID = tenantID
+
+	if err := s.postgres.CreateCollectorGroup(c.Request.Context(), group); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// @Summary Set collector group members
+// @Description Replace the collectors of a tenant's collector group (admin only)
+// @Tags Tenants
+// @Accept json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param groupId path int true "Collector group ID"
+// @Param members body models.CollectorGroupMembersRequest true "Collectors"
+// @Success 204
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/collector-groups/{groupId}/members [put]
+func (s *Server) handleSetCollectorGroupMembers(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+	groupID, ok := int64Param(c, "groupId", "Invalid collector group ID")
+	if !ok {
+		return
+	}
+
+	var req models.CollectorGroupMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp := apperrors.BadRequest("Invalid request body", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	if err := s.postgres.SetCollectorGroupMembers(c.Request.Context(), tenantID, groupID, req.CollectorIDs); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Delete collector group
+// @Description Remove a tenant's collector group and the check pack schedules on it (admin only)
+// @Tags Tenants
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param groupId path int true "Collector group ID"
+// @Success 204
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/collector-groups/{groupId} [delete]
+func (s *Server) handleDeleteCollectorGroup(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+	groupID, ok := int64Param(c, "groupId", "Invalid collector group ID")
+	if !ok {
+		return
+	}
+
+	if err := s.postgres.DeleteCollectorGroup(c.Request.Context(), tenantID, groupID); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// bindPackCheck binds and validates a custom check request
+func bindPackCheck(c *gin.Context) (*models.VersionHealthCheck, bool) {
+	var req models.HealthCheckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp := apperrors.BadRequest("Invalid request body", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return nil, false
+	}
+
+	check, err := check_packs.BuildCheck(&req)
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid health check", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return nil, false
+	}
+
+	return check, true
+}
+
+// int64Param parses a numeric path parameter, answering 400 when it is not
+// one
+func int64Param(c *gin.Context, name, message string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		errResp := apperrors.BadRequest(message, err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TestCheckPacks_TenantRole lets tenant members read check packs and
+// collector groups and only tenant admins change them
+func TestCheckPacks_TenantRole(t *testing.T) {
+	now := time.Now()
+	check := `{"check_name":"ssl_enabled","severity":"critical","check_query":"SHOW ssl","assertion_type":"equals","assertion_value":"on"}`
+	packRow := func(tenantID uuid.UUID) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "tenant_id", "name", "version", "description", "check_count", "created_at", "updated_at"}).
+			AddRow(1, tenantID, "security", "1.0", "", 0, now, now)
+	}
+	expectPack := func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+		mock.ExpectQuery(regexp.QuoteMeta("FROM health_check_packs p")).
+			WithArgs(tenantID, int64(1)).
+			WillReturnRows(packRow(tenantID))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM postgres_health_checks hc")).
+			WithArgs(int64(1)).
+			WillReturnRows(emptyRows())
+	}
+	expectTouch := func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE health_check_packs SET updated_at")).
+			WithArgs(tenantID, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	created := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, now, now)
+	}
+
+	testTenantRoutes(t, func(s *Server, tenants *gin.RouterGroup) {
+		tenants.GET("/:id/check-packs", s.handleListCheckPacks)
+		tenants.POST("/:id/check-packs", s.handleCreateCheckPack)
+		tenants.POST("/:id/check-packs/import", s.handleImportCheckPack)
+		tenants.GET("/:id/check-packs/:packId", s.handleGetCheckPack)
+		tenants.PUT("/:id/check-packs/:packId", s.handleUpdateCheckPack)
+		tenants.DELETE("/:id/check-packs/:packId", s.handleDeleteCheckPack)
+		tenants.GET("/:id/check-packs/:packId/export", s.handleExportCheckPack)
+		tenants.POST("/:id/check-packs/:packId/checks", s.handleCreatePackCheck)
+		tenants.PUT("/:id/check-packs/:packId/checks/:checkId", s.handleUpdatePackCheck)
+		tenants.DELETE("/:id/check-packs/:packId/checks/:checkId", s.handleDeletePackCheck)
+		tenants.GET("/:id/check-packs/:packId/schedules", s.handleListCheckPackSchedules)
+		tenants.POST("/:id/check-packs/:packId/schedules", s.handleCreateCheckPackSchedule)
+		tenants.DELETE("/:id/check-packs/:packId/schedules/:scheduleId", s.handleDeleteCheckPackSchedule)
+		tenants.GET("/:id/collector-groups", s.handleListCollectorGroups)
+		tenants.POST("/:id/collector-groups", s.handleCreateCollectorGroup)
+		tenants.PUT("/:id/collector-groups/:groupId/members", s.handleSetCollectorGroupMembers)
+		tenants.DELETE("/:id/collector-groups/:groupId", s.handleDeleteCollectorGroup)
+	}, []tenantRouteCase{
+		{"GET", "/check-packs", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM health_check_packs p")).
+				WithArgs(tenantID).
+				WillReturnRows(packRow(tenantID))
+		}},
+		{"POST", "/check-packs", `{"name":"security","version":"1.0","checks":[` + check + `]}`, true, http.StatusCreated, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO health_check_packs")).WillReturnRows(created())
+			mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO postgres_health_checks")).WillReturnRows(created())
+			mock.ExpectCommit()
+		}},
+		{"POST", "/check-packs/import", "name: security\nversion: \"1.0\"\nchecks:\n  - name: ssl_enabled\n    severity: critical\n    query: SHOW ssl\n    assertion:\n      type: equals\n      value: \"on\"\n", true, http.StatusCreated, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM health_check_packs")).
+				WithArgs(tenantID, "security", "1.0").
+				WillReturnRows(emptyRows())
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO health_check_packs")).WillReturnRows(created())
+			mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO postgres_health_checks")).WillReturnRows(created())
+			mock.ExpectCommit()
+		}},
+		{"GET", "/check-packs/1", "", false, http.StatusOK, expectPack},
+		{"GET", "/check-packs/1/export", "", false, http.StatusOK, expectPack},
+		{"PUT", "/check-packs/1", `{"name":"security","version":"1.1"}`, true, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("UPDATE health_check_packs")).
+				WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(now, now))
+		}},
+		{"DELETE", "/check-packs/1", "", true, http.StatusNoContent, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectExec(regexp.QuoteMeta("DELETE FROM health_check_packs")).
+				WithArgs(tenantID, int64(1)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}},
+		{"POST", "/check-packs/1/checks", check, true, http.StatusCreated, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectBegin()
+			expectTouch(mock, tenantID)
+			mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO postgres_health_checks")).WillReturnRows(created())
+			mock.ExpectCommit()
+		}},
+		{"PUT", "/check-packs/1/checks/2", check, true, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectBegin()
+			expectTouch(mock, tenantID)
+			mock.ExpectQuery(regexp.QuoteMeta("UPDATE postgres_health_checks")).
+				WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(now, now))
+			mock.ExpectCommit()
+		}},
+		{"DELETE", "/check-packs/1/checks/2", "", true, http.StatusNoContent, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectBegin()
+			expectTouch(mock, tenantID)
+			mock.ExpectExec(regexp.QuoteMeta("DELETE FROM postgres_health_checks")).
+				WithArgs(int64(1), 2).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		}},
+		{"GET", "/check-packs/1/schedules", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM health_check_schedules s")).
+				WithArgs(tenantID, int64(1)).
+				WillReturnRows(emptyRows())
+		}},
+		{"POST", "/check-packs/1/schedules", `{"group_id":3}`, true, http.StatusCreated, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO health_check_schedules")).
+				WithArgs(tenantID, int64(1), int64(3), 3600, true).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "name"}).AddRow(4, now, "production"))
+		}},
+		{"DELETE", "/check-packs/1/schedules/4", "", true, http.StatusNoContent, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectExec(regexp.QuoteMeta("DELETE FROM health_check_schedules")).
+				WithArgs(tenantID, int64(1), int64(4)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}},
+		{"GET", "/collector-groups", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM collector_groups g")).
+				WithArgs(tenantID).
+				WillReturnRows(emptyRows())
+		}},
+		{"POST", "/collector-groups", `{"name":"production"}`, true, http.StatusCreated, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO collector_groups")).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+			mock.ExpectCommit()
+		}},
+		{"PUT", "/collector-groups/3/members", `{"collector_ids":[]}`, true, http.StatusNoContent, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM collector_groups")).
+				WithArgs(tenantID, int64(3)).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			mock.ExpectExec(regexp.QuoteMeta("DELETE FROM collector_group_members")).
+				WithArgs(int64(3)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectCommit()
+		}},
+		{"DELETE", "/collector-groups/3", "", true, http.StatusNoContent, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectExec(regexp.QuoteMeta("DELETE FROM collector_groups")).
+				WithArgs(tenantID, int64(3)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}},
+	})
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/cluster_events"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// CLUSTER EVENT ENDPOINTS
+// ============================================================================
+
+// @Summary Get Cluster Event Timeline of a Collector
+// @Description Failovers, switchovers, timeline and upstream changes of the physical replication cluster a collector belongs to, most recent first, with the cluster topology before and after each
+// @Tags Replication
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param type query string false "Comma separated event types"
+// @Param from query string false "Start time (RFC3339)" default(30 days ago)
+// @Param to query string false "End time (RFC3339)" default(now)
+// @Param limit query int false "Result limit" default(100)
+// @Success 200 {array} models.ClusterEvent
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/cluster-events [get]
+func (s *Server) handleGetCollectorClusterEvents(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	filter, errResp := parseClusterEventFilter(c)
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	service := cluster_events.NewService(storage.NewClusterEventRepository(s.postgres.GetDB()), s.wsManager, s.logger)
+	if filter.ClusterID, err = service.ClusterIDOf(c.Request.Context(), collectorID, filter.To); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	events, err := service.ListEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// @Summary Get Tenant Cluster Events
+// @Description Failovers, switchovers, timeline and upstream changes across a tenant's clusters, or of one cluster by system identifier, most recent first
+// @Tags Replication
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param cluster query string false "Cluster ID (system identifier)"
+// @Param type query string false "Comma separated event types"
+// @Param from query string false "Start time (RFC3339)" default(30 days ago)
+// @Param to query string false "End time (RFC3339)" default(now)
+// @Param limit query int false "Result limit" default(100)
+// @Success 200 {array} models.ClusterEvent
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/cluster-events [get]
+func (s *Server) handleGetTenantClusterEvents(c *gin.Context) {
+	filter, errResp := parseClusterEventFilter(c)
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+	filter.ClusterID = c.Query("cluster")
+
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+	filter.TenantID = &tenantID
+
+	service := cluster_events.NewService(storage.NewClusterEventRepository(s.postgres.GetDB()), s.wsManager, s.logger)
+	events, err := service.ListEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// parseClusterEventFilter reads the type, from, to and limit query parameters
+func parseClusterEventFilter(c *gin.Context) (*models.ClusterEventFilter, *apperrors.AppError) {
+	filter := &models.ClusterEventFilter{To: time.Now(), Limit: 100}
+
+	var err error
+	if v := c.Query("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, apperrors.BadRequest("Invalid to timestamp", "expected RFC3339")
+		}
+	}
+	filter.From = filter.To.Add(-30 * 24 * time.Hour)
+	if v := c.Query("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, apperrors.BadRequest("Invalid from timestamp", "expected RFC3339")
+		}
+	}
+	if !filter.From.Before(filter.To) {
+		return nil, apperrors.BadRequest("Invalid time range", "from must be before to")
+	}
+
+	if v := c.Query("type"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			if !cluster_events.ValidEventType(t) {
+				return nil, apperrors.BadRequest("Invalid event type", t)
+			}
+			filter.EventTypes = append(filter.EventTypes, t)
+		}
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > 1000 {
+			return nil, apperrors.BadRequest("Invalid limit", "expected 1 to 1000")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// TestGetClusterEvents_InvalidRequest rejects bad parameters before querying
+func TestGetClusterEvents_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{logger: zap.NewNop()}
+	router := gin.New()
+	router.GET("/api/v1/collectors/:id/cluster-events", server.handleGetCollectorClusterEvents)
+	router.GET("/api/v1/tenants/:id/cluster-events", server.handleGetTenantClusterEvents)
+
+	collector := "/api/v1/collectors/" + uuid.New().String() + "/cluster-events"
+	tenant := "/api/v1/tenants/" + uuid.New().String() + "/cluster-events"
+	for path, message := range map[string]string{
+		"/api/v1/collectors/not-a-uuid/cluster-events":                   "Invalid collector ID",
+		collector + "?type=failover,crash":                               "Invalid event type",
+		collector + "?from=2026-03-02T00:00:00Z&to=2026-03-01T00:00:00Z": "Invalid time range",
+		collector + "?to=today":                                          "Invalid to timestamp",
+		tenant + "?limit=0":                                              "Invalid limit",
+		tenant + "?from=last-week":                                       "Invalid from timestamp",
+		"/api/v1/tenants/not-a-uuid/cluster-events":                      "Invalid tenant ID",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Contains(t, w.Body.String(), message, path)
+	}
+}
+
+// TestGetTenantClusterEvents_TenantRole lets only tenant members list events
+func TestGetTenantClusterEvents_TenantRole(t *testing.T) {
+	testTenantRoutes(t, func(s *Server, tenants *gin.RouterGroup) {
+		tenants.GET("/:id/cluster-events", s.handleGetTenantClusterEvents)
+	}, []tenantRouteCase{
+		{"GET", "/cluster-events", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM cluster_events")).
+				WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), tenantID, 100).
+				WillReturnRows(emptyRows())
+		}},
+	})
+}
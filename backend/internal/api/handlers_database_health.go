@@ -0,0 +1,217 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/database_health"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/wraparound"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// DATABASE HEALTH SCORE ENDPOINTS
+// ============================================================================
+
+func (s *Server) databaseHealthService() *database_health.Service {
+	return database_health.NewService(
+		s.postgres,
+		wraparound.NewService(storage.NewWraparoundRepository(s.postgres.GetDB()), s.logger),
+		log_analysis.NewTemplateService(s.postgres),
+		s.logger,
+	)
+}
+
+// @Summary Get database health score
+// @Description Get the latest database health score of a collector with the score, weight and explanation of each component
+// @Tags Health
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Success 200 {object} models.DatabaseHealthScore
+// @Failure 400 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/database-health [get]
+func (s *Server) handleGetDatabaseHealth(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	score, err := s.postgres.GetLatestDatabaseHealthScore(c.Request.Context(), collectorID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, score)
+}
+
+// @Summary Get database health score history
+// @Description Get historical database health scores of a collector with pagination
+// @Tags Health
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param time_range query string false "Time range (1h, 24h, 7d, 30d)" default(24h)
+// @Param limit query int false "Result limit" default(100)
+// @Param offset query int false "Result offset" default(0)
+// @Success 200 {object} models.DatabaseHealthHistoryResponse
+// @Failure 400 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/database-health/history [get]
+func (s *Server) handleGetDatabaseHealthHistory(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	timeRange := c.DefaultQuery("time_range", "24h")
+
+	limit := 100
+	if l, err := strconv.Atoi(c.DefaultQuery("limit", "100")); err == nil && l > 0 && l <= 1000 {
+		limit = l
+	}
+
+	offset := 0
+	if o, err := strconv.Atoi(c.DefaultQuery("offset", "0")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	scores, err := s.postgres.GetDatabaseHealthScoreHistory(c.Request.Context(), collectorID, timeRange, limit, offset)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &models.DatabaseHealthHistoryResponse{
+		Scores: scores,
+		Pagination: models.PaginationParams{
+			Page:     (offset / limit) + 1,
+			PageSize: limit,
+		},
+	})
+}
+
+// @Summary Calculate and store database health score
+// @Description Score a collector's database health now from cache hit ratio, replication lag, connection saturation, bloat, wraparound headroom, health checks, log anomalies and error-log rate, weighted by its tenant's weights. Components without recent data are left out and their weight redistributed.
+// @Tags Health
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Success 200 {object} models.DatabaseHealthScore
+// @Failure 400 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/database-health/calculate [post]
+func (s *Server) handleCalculateDatabaseHealth(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	score, err := s.databaseHealthService().Calculate(c.Request.Context(), collectorID, time.Now())
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, score)
+}
+
+// @Summary Get database health weights
+// @Description Get the weights a tenant's database health scores are calculated with, the defaults when the tenant has not set its own
+// @Tags Tenants
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.DatabaseHealthWeights
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/database-health/weights [get]
+func (s *Server) handleGetDatabaseHealthWeights(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+
+	weights, err := s.databaseHealthService().Weights(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, weights)
+}
+
+// @Summary Update database health weights
+// @Description Set the relative weight of each database health component for a tenant (admin only). Weights must be non-negative and not all zero; they are normalized, so they need not add up to 1.
+// @Tags Tenants
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param weights body models.DatabaseHealthWeights true "Component weights"
+// @Success 200 {object} models.DatabaseHealthWeights
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/database-health/weights [put]
+func (s *Server) handleUpdateDatabaseHealthWeights(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+
+	var weights models.DatabaseHealthWeights
+	if err := c.ShouldBindJSON(&weights); err != nil {
+		errResp := apperrors.BadRequest("Invalid request body", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	if err := s.databaseHealthService().SetWeights(c.Request.Context(), tenantID, &weights); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, weights)
+}
+
+// @Summary Rank database health across the fleet
+// @Description Rank a tenant's collectors by their latest database health score from the last 24 hours, worst first, with each collector's weakest component. Collectors without a recent score are listed last.
+// @Tags Tenants
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.DatabaseHealthRanking
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/database-health/ranking [get]
+func (s *Server) handleGetDatabaseHealthRanking(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+
+	ranking, err := s.databaseHealthService().Ranking(c.Request.Context(), tenantID, time.Now())
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ranking)
+}
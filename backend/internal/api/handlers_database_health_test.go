@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// TestDatabaseHealth_InvalidRequest rejects bad IDs before querying
+func TestDatabaseHealth_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{logger: zap.NewNop()}
+	router := gin.New()
+	router.GET("/api/v1/collectors/:id/database-health", server.handleGetDatabaseHealth)
+	router.GET("/api/v1/collectors/:id/database-health/history", server.handleGetDatabaseHealthHistory)
+	router.POST("/api/v1/collectors/:id/database-health/calculate", server.handleCalculateDatabaseHealth)
+	router.GET("/api/v1/tenants/:id/database-health/weights", server.handleGetDatabaseHealthWeights)
+	router.PUT("/api/v1/tenants/:id/database-health/weights", server.handleUpdateDatabaseHealthWeights)
+	router.GET("/api/v1/tenants/:id/database-health/ranking", server.handleGetDatabaseHealthRanking)
+
+	for _, tc := range []struct {
+		method, path, message string
+	}{
+		{"GET", "/api/v1/collectors/not-a-uuid/database-health", "Invalid collector ID"},
+		{"GET", "/api/v1/collectors/not-a-uuid/database-health/history", "Invalid collector ID"},
+		{"POST", "/api/v1/collectors/not-a-uuid/database-health/calculate", "Invalid collector ID"},
+		{"GET", "/api/v1/tenants/not-a-uuid/database-health/weights", "Invalid tenant ID"},
+		{"PUT", "/api/v1/tenants/not-a-uuid/database-health/weights", "Invalid tenant ID"},
+		{"GET", "/api/v1/tenants/not-a-uuid/database-health/ranking", "Invalid tenant ID"},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, tc.path)
+		assert.Contains(t, w.Body.String(), tc.message, tc.path)
+	}
+}
+
+// TestDatabaseHealth_TenantRole lets tenant members read weights and the
+// fleet ranking and only tenant admins change weights
+func TestDatabaseHealth_TenantRole(t *testing.T) {
+	testTenantRoutes(t, func(s *Server, tenants *gin.RouterGroup) {
+		tenants.GET("/:id/database-health/weights", s.handleGetDatabaseHealthWeights)
+		tenants.PUT("/:id/database-health/weights", s.handleUpdateDatabaseHealthWeights)
+		tenants.GET("/:id/database-health/ranking", s.handleGetDatabaseHealthRanking)
+	}, []tenantRouteCase{
+		{"GET", "/database-health/weights", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM database_health_weights")).
+				WithArgs(tenantID).
+				WillReturnRows(emptyRows())
+		}},
+		{"PUT", "/database-health/weights", `{"cache_hit":1,"connections":1}`, true, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectExec(regexp.QuoteMeta("INSERT INTO database_health_weights")).
+				WithArgs(tenantID, 1.0, 0.0, 1.0, 0.0, 0.0, 0.0, 0.0, 0.0).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}},
+		{"GET", "/database-health/ranking", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM collectors")).
+				WithArgs(tenantID).
+				WillReturnRows(emptyRows())
+		}},
+	})
+}
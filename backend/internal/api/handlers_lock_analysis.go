@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/lock_analysis"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ============================================================================
+// LOCK ANALYSIS ENDPOINTS
+// ============================================================================
+
+// ingestLockMetrics stores a pg_locks metric as one snapshot and returns the
+// number of rows inserted
+func (s *Server) ingestLockMetrics(c *gin.Context, collectorID string, metric interface{}, redactor *query_performance.Redactor) int {
+	metricJSON, _ := json.Marshal(metric)
+
+	var req models.LockMetricsRequest
+	if err := json.Unmarshal(metricJSON, &req); err != nil {
+		s.logger.Error("Failed to unmarshal pg_locks metric", zap.Error(err))
+		return 0
+	}
+
+	locks, waits := buildLockSnapshot(metricsCollectorUUID(collectorID), &req, redactor)
+	if err := s.postgres.StoreLockMetrics(c.Request.Context(), locks, waits); err != nil {
+		s.logger.Error("Failed to store lock metrics",
+			zap.Error(err),
+			zap.String("collector_id", collectorID),
+			zap.Int("locks", len(locks)),
+			zap.Int("lock_waits", len(waits)),
+		)
+		return 0
+	}
+
+	return len(locks) + len(waits)
+}
+
+// buildLockSnapshot converts a pg_locks metric into rows sharing one timestamp
+// pg_locks is cluster-wide, so each lock and wait is kept once, under the
+// first database (by name) that reported it.
+func buildLockSnapshot(collectorID uuid.UUID, req *models.LockMetricsRequest, redactor *query_performance.Redactor) ([]*models.Lock, []*models.LockWait) {
+	ts := time.Now()
+	if parsed, err := time.Parse(time.RFC3339, req.Timestamp); err == nil {
+		ts = parsed
+	}
+
+	databases := make([]string, 0, len(req.Databases))
+	for name := range req.Databases {
+		databases = append(databases, name)
+	}
+	sort.Strings(databases)
+
+	var locks []*models.Lock
+	var waits []*models.LockWait
+	seenLocks := make(map[string]bool)
+	seenWaits := make(map[[2]int]bool)
+
+	for _, name := range databases {
+		batch := req.Databases[name]
+		for _, l := range batch.ActiveLocks {
+			key := fmt.Sprintf("%d|%s|%s|%t|%s|%s|%s", l.PID, l.LockType, l.Mode, l.Granted,
+				optionalInt(l.Relation), optionalInt(l.Page), optionalInt(l.Tuple))
+			if seenLocks[key] {
+				continue
+			}
+			seenLocks[key] = true
+
+			lock := &models.Lock{
+				CollectorID:    collectorID,
+				DatabaseName:   name,
+				PID:            l.PID,
+				LockType:       l.LockType,
+				Mode:           l.Mode,
+				Granted:        l.Granted,
+				RelationID:     l.Relation,
+				PageNumber:     l.Page,
+				TupleID:        l.Tuple,
+				Username:       l.Username,
+				SessionState:   l.State,
+				LockAgeSeconds: l.LockAgeSeconds,
+				Timestamp:      ts,
+			}
+			if l.Query != nil {
+				query := redactor.RedactQuery(*l.Query)
+				lock.Query = &query
+			}
+			locks = append(locks, lock)
+		}
+
+		for _, w := range batch.LockWaitChains {
+			pair := [2]int{w.BlockedPID, w.BlockingPID}
+			if seenWaits[pair] {
+				continue
+			}
+			seenWaits[pair] = true
+
+			waits = append(waits, &models.LockWait{
+				CollectorID:         collectorID,
+				DatabaseName:        name,
+				BlockedPID:          w.BlockedPID,
+				BlockingPID:         w.BlockingPID,
+				BlockedUsername:     w.BlockedUser,
+				BlockingUsername:    w.BlockingUser,
+				BlockedQuery:        redactor.RedactQuery(w.BlockedQuery),
+				BlockingQuery:       redactor.RedactQuery(w.BlockingQuery),
+				WaitTimeSeconds:     w.WaitTimeSeconds,
+				BlockedApplication:  w.BlockedApplication,
+				BlockingApplication: w.BlockingApplication,
+				Timestamp:           ts,
+			})
+		}
+	}
+
+	return locks, waits
+}
+
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *v)
+}
+
+// @Summary Get Blocking Tree
+// @Description Reconstruct the lock blocking trees from the latest lock snapshot at or before a point in time
+// @Tags Metrics
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param at query string false "Point in time (RFC3339)" default(now)
+// @Param database query string false "Database name"
+// @Success 200 {object} models.BlockingTreeSnapshot
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/locks/blocking-tree [get]
+func (s *Server) handleGetBlockingTree(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	at := time.Now()
+	if v := c.Query("at"); v != "" {
+		if at, err = time.Parse(time.RFC3339, v); err != nil {
+			errResp := apperrors.BadRequest("Invalid at timestamp", "expected RFC3339")
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+	}
+
+	var database *string
+	if v := c.Query("database"); v != "" {
+		database = &v
+	}
+
+	service := lock_analysis.NewService(storage.NewLockSnapshotRepository(s.postgres.GetDB()), s.logger)
+	snapshot, err := service.GetBlockingTree(c.Request.Context(), collectorID, at, database)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
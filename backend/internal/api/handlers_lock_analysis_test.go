@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+func newLockAnalysisTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	server := &Server{logger: zap.NewNop()}
+
+	router := gin.New()
+	router.GET("/api/v1/collectors/:id/locks/blocking-tree", server.handleGetBlockingTree)
+	return router
+}
+
+// TestGetBlockingTree_InvalidRequest rejects bad collector IDs and timestamps
+func TestGetBlockingTree_InvalidRequest(t *testing.T) {
+	router := newLockAnalysisTestRouter()
+
+	for path, message := range map[string]string{
+		"/api/v1/collectors/not-a-uuid/locks/blocking-tree":                               "Invalid collector ID",
+		"/api/v1/collectors/" + uuid.New().String() + "/locks/blocking-tree?at=yesterday": "Invalid at timestamp",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Contains(t, w.Body.String(), message, path)
+	}
+}
+
+// TestBuildLockSnapshot dedupes cluster-wide rows and redacts queries
+func TestBuildLockSnapshot(t *testing.T) {
+	redactor, err := query_performance.NewRedactor(models.RedactionModeNormalize, nil)
+	require.NoError(t, err)
+
+	relation := 16384
+	query := "UPDATE accounts SET balance = 10 WHERE id = 42"
+	batch := models.DatabaseLockBatch{
+		ActiveLocks: []models.CollectedLock{
+			{PID: 100, LockType: "relation", Mode: "RowExclusiveLock", Granted: true, Relation: &relation, Query: &query},
+			{PID: 200, LockType: "relation", Mode: "ShareLock", Granted: false, Relation: &relation},
+		},
+		LockWaitChains: []models.CollectedLockWait{
+			{BlockedPID: 200, BlockingPID: 100, BlockingQuery: query},
+		},
+	}
+	req := &models.LockMetricsRequest{
+		Type:      "pg_locks",
+		Timestamp: "2026-03-01T12:00:00Z",
+		Databases: map[string]models.DatabaseLockBatch{"orders": batch, "billing": batch},
+	}
+
+	collectorID := uuid.New()
+	locks, waits := buildLockSnapshot(collectorID, req, redactor)
+	require.Len(t, locks, 2)
+	require.Len(t, waits, 1)
+
+	taken := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, l := range locks {
+		assert.Equal(t, "billing", l.DatabaseName)
+		assert.Equal(t, collectorID, l.CollectorID)
+		assert.True(t, taken.Equal(l.Timestamp))
+	}
+	assert.True(t, taken.Equal(waits[0].Timestamp))
+	assert.NotContains(t, *locks[0].Query, "42")
+	assert.NotContains(t, waits[0].BlockingQuery, "42")
+}
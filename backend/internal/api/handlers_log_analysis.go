@@ -4,11 +4,15 @@ import (
 	"context"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
 	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 	"go.uber.org/zap"
 )
 
@@ -98,75 +102,203 @@ func (s *Server) handleGetCollectorLogs(c *gin.Context) {
 }
 
 // @Summary Stream Logs via WebSocket
-// @Description Stream real-time logs for a collector via WebSocket
+// @Description Push a collector's logs as they are ingested. Each message carries the log ID as its offset; pass the last offset received as since to resume after a disconnect. Slow subscribers receive a gap message with the offset to resume from instead of blocking ingestion.
 // @Tags Logs
 // @Produce json
 // @Security Bearer
 // @Param collector_id path string true "Collector ID"
+// @Param min_level query string false "Minimum severity (DEBUG, INFO, NOTICE, WARNING, ERROR, FATAL, PANIC)"
+// @Param category query string false "Comma-separated log categories"
+// @Param q query string false "Case-insensitive text the message must contain"
+// @Param since query int false "Replay stored logs after this offset before streaming"
 // @Success 101 {string} string "WebSocket Upgrade"
 // @Failure 400 {object} apperrors.AppError
 // @Failure 401 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
 // @Router /api/v1/logs/stream/{collector_id} [get]
 func (s *Server) handleLogStream(c *gin.Context) {
-	collectorID := c.Param("collector_id")
+	collectorID, err := uuid.Parse(c.Param("collector_id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	filter, errResp := parseLogStreamFilter(c)
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	var since *int64
+	if v := c.Query("since"); v != "" {
+		offset, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || offset < 0 {
+			errResp := apperrors.BadRequest("Invalid since offset", "expected a log ID")
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+		since = &offset
+	}
+
+	if !s.requireCollectorAccess(c, collectorID) {
+		return
+	}
+
+	if s.logStream == nil {
+		errResp := apperrors.ServiceUnavailable("Log streaming not available", "")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
 
-	s.logger.Debug("WebSocket connection attempt for logs stream", zap.String("collector_id", collectorID))
+	// Subscribe before replaying so that no log falls between the two
+	sub := s.logStream.Subscribe(collectorID, filter, log_analysis.DefaultStreamBuffer)
+	defer sub.Close()
 
-	// Upgrade HTTP connection to WebSocket
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		s.logger.Error("WebSocket upgrade failed",
-			zap.String("collector_id", collectorID),
+			zap.String("collector_id", collectorID.String()),
 			zap.Error(err))
 		return
 	}
 	defer conn.Close()
 
-	s.logger.Info("WebSocket connection established for logs stream", zap.String("collector_id", collectorID))
-
-	// Create a channel for receiving logs
-	logChan := make(chan map[string]interface{}, 10)
-	defer close(logChan)
+	s.logger.Info("WebSocket connection established for logs stream", zap.String("collector_id", collectorID.String()))
 
-	// Create a context for cancellation
 	ctx, cancel := context.WithCancel(c.Request.Context())
 	defer cancel()
 
-	// Start streaming logs in a goroutine
+	// Read until the client goes away so that close frames are handled
 	go func() {
-		if s.logCollector == nil {
-			s.logger.Error("LogCollector not initialized")
-			return
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
 		}
+	}()
 
-		// StreamLogs will continuously poll the database and send logs through the channel
-		if err := s.logCollector.StreamLogs(ctx, collectorID, logChan); err != nil {
-			s.logger.Debug("Log streaming ended",
-				zap.String("collector_id", collectorID),
-				zap.Error(err))
+	var lastSent int64
+	send := func(msg *models.LogStreamMessage) bool {
+		_ = conn.SetWriteDeadline(time.Now().Add(logStreamWriteTimeout))
+		if err := conn.WriteJSON(msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				s.logger.Debug("WebSocket error", zap.Error(err))
+			}
+			return false
 		}
-	}()
+		if msg.Offset > lastSent {
+			lastSent = msg.Offset
+		}
+		return true
+	}
+	sendGap := func(dropped, resumeFrom int64) bool {
+		return send(&models.LogStreamMessage{Type: models.LogStreamMessageGap, Dropped: dropped, ResumeFrom: &resumeFrom})
+	}
+
+	if since != nil {
+		lastSent = *since
+		logs, last, complete, err := s.logStream.Replay(ctx, collectorID, *since, filter)
+		if err != nil {
+			s.logger.Error("Failed to replay logs", zap.String("collector_id", collectorID.String()), zap.Error(err))
+		}
+		for _, entry := range logs {
+			if !send(&models.LogStreamMessage{Type: models.LogStreamMessageLog, Offset: entry.ID, Log: entry}) {
+				return
+			}
+		}
+		if !complete && !sendGap(0, last) {
+			return
+		}
+	}
+
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
 
-	// Main loop: receive logs from channel and send via WebSocket
 	for {
 		select {
 		case <-ctx.Done():
-			s.logger.Debug("Context cancelled for logs stream", zap.String("collector_id", collectorID))
 			return
 
-		case log, ok := <-logChan:
-			if !ok {
-				s.logger.Debug("Log channel closed", zap.String("collector_id", collectorID))
+		case entry := <-sub.Events():
+			// Skip logs already sent by the replay
+			if entry.ID != 0 && entry.ID <= lastSent {
+				continue
+			}
+			if dropped := sub.Dropped(); dropped > 0 && !sendGap(dropped, lastSent) {
+				return
+			}
+			if !send(&models.LogStreamMessage{Type: models.LogStreamMessageLog, Offset: entry.ID, Log: entry}) {
 				return
 			}
 
-			// Send log via WebSocket
-			if err := conn.WriteJSON(log); err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					s.logger.Debug("WebSocket error", zap.Error(err))
-				}
+		case <-keepalive.C:
+			if dropped := sub.Dropped(); dropped > 0 && !sendGap(dropped, lastSent) {
 				return
 			}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(logStreamWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// logStreamWriteTimeout bounds writes to a log stream subscriber
+const logStreamWriteTimeout = 10 * time.Second
+
+// parseLogStreamFilter reads the min_level, category and q filters
+func parseLogStreamFilter(c *gin.Context) (log_analysis.LogStreamFilter, *apperrors.AppError) {
+	filter := log_analysis.LogStreamFilter{Text: c.Query("q")}
+
+	if level := strings.ToUpper(c.Query("min_level")); level != "" {
+		if !log_analysis.ValidLogLevel(level) {
+			return filter, apperrors.BadRequest("Invalid min_level", "expected DEBUG, INFO, NOTICE, WARNING, ERROR, FATAL or PANIC")
 		}
+		filter.MinLevel = level
 	}
+	for _, category := range strings.Split(c.Query("category"), ",") {
+		if category = strings.TrimSpace(category); category != "" {
+			filter.Categories = append(filter.Categories, category)
+		}
+	}
+
+	return filter, nil
+}
+
+// requireCollectorAccess checks that the user may access an instance
+// monitored by the collector, with the same tenant-based access as realtime
+// events; it writes the error response and returns false otherwise
+func (s *Server) requireCollectorAccess(c *gin.Context, collectorID uuid.UUID) bool {
+	userID, ok := s.requireUserID(c)
+	if !ok {
+		return false
+	}
+
+	access, err := s.wsManager.ResolveAccess(c.Request.Context(), realtimeUserID(userID))
+	if err != nil {
+		s.logger.Error("Failed to resolve instance access", zap.Error(err))
+		errResp := apperrors.InternalServerError("Failed to resolve instance access", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return false
+	}
+	if access.All {
+		return true
+	}
+
+	instances, err := s.postgres.GetInstanceIDsByCollectorIDs(c.Request.Context(), []uuid.UUID{collectorID})
+	if err != nil {
+		appErr := apperrors.ToAppError(err)
+		c.JSON(appErr.StatusCode, appErr)
+		return false
+	}
+	for _, id := range instances {
+		if access.Allows(id) {
+			return true
+		}
+	}
+
+	errResp := apperrors.Forbidden("Access denied", "collector is not in any of your tenants")
+	c.JSON(errResp.StatusCode, errResp)
+	return false
 }
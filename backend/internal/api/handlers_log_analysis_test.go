@@ -0,0 +1,132 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// TestLogStream_InvalidRequest rejects malformed stream parameters before upgrading
+func TestLogStream_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server, mock := newTenantTestServer(t)
+	server.logStream = log_analysis.NewLogStream(nil)
+	server.wsManager.SetAccessResolver(stubRealtimeResolver{})
+	router := gin.New()
+	router.GET("/api/v1/logs/stream/:collector_id", asUser(7), server.handleLogStream)
+
+	base := "/api/v1/logs/stream/" + uuid.New().String()
+	for path, message := range map[string]string{
+		"/api/v1/logs/stream/not-a-uuid": "Invalid collector ID",
+		base + "?min_level=LOUD":         "Invalid min_level",
+		base + "?since=-1":               "Invalid since offset",
+		base + "?since=latest":           "Invalid since offset",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Contains(t, w.Body.String(), message, path)
+	}
+
+	// Subscriptions are released when the upgrade fails
+	collectorID := uuid.New()
+	expectCollectorInstances(mock, collectorID, 1)
+	req := httptest.NewRequest("GET", "/api/v1/logs/stream/"+collectorID.String()+"?min_level=error&category=deadlock", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 0, server.logStream.Subscribers(collectorID))
+}
+
+// expectCollectorInstances expects the lookup of the instances a collector monitors
+func expectCollectorInstances(mock sqlmock.Sqlmock, collectorID uuid.UUID, instanceIDs ...int) {
+	rows := sqlmock.NewRows([]string{"id"})
+	for _, id := range instanceIDs {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("FROM pganalytics.postgresql_instances i")).
+		WithArgs(pq.Array([]string{collectorID.String()})).
+		WillReturnRows(rows)
+}
+
+// TestLogStream_CollectorAccess only streams collectors of the user's tenants
+func TestLogStream_CollectorAccess(t *testing.T) {
+	server, mock := newTenantTestServer(t)
+	server.logStream = log_analysis.NewLogStream(nil)
+	server.wsManager.SetAccessResolver(stubRealtimeResolver{})
+
+	router := gin.New()
+	router.GET("/api/v1/logs/stream/:collector_id", server.handleLogStream)
+	authed := gin.New()
+	authed.GET("/api/v1/logs/stream/:collector_id", asUser(7), server.handleLogStream)
+
+	// Unauthenticated requests are rejected
+	collectorID := uuid.New()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/logs/stream/"+collectorID.String(), nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The stub grants instance 1; a collector monitoring instance 2 is denied
+	expectCollectorInstances(mock, collectorID, 2)
+	w = httptest.NewRecorder()
+	authed.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/logs/stream/"+collectorID.String(), nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+	assert.Equal(t, 0, server.logStream.Subscribers(collectorID))
+
+	// A collector monitoring instance 1 passes the check and fails the upgrade
+	expectCollectorInstances(mock, collectorID, 1)
+	w = httptest.NewRecorder()
+	authed.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/logs/stream/"+collectorID.String(), nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestIngestLogs_CollectorAuth only accepts logs sent with the token of the
+// collector they belong to
+func TestIngestLogs_CollectorAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server, mock := newTenantTestServer(t)
+	router := gin.New()
+	router.POST("/api/v1/logs/ingest", server.CollectorAuthMiddleware(), server.handleIngestLogs)
+
+	collectorID := uuid.New()
+	token, _, err := server.jwtManager.GenerateCollectorToken(&models.Collector{ID: collectorID, Hostname: "db1"})
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		authorization string
+		collectorID   uuid.UUID
+		message       string
+	}{
+		"missing token":     {"", collectorID, ""},
+		"invalid token":     {"Bearer not-a-token", collectorID, ""},
+		"another collector": {"Bearer " + token, uuid.New(), "Collector ID mismatch"},
+	} {
+		body := `{"collector_id":"` + tc.collectorID.String() + `","instance_id":1,"logs":[{"message":"statement: SELECT 1"}]}`
+		req := httptest.NewRequest("POST", "/api/v1/logs/ingest", strings.NewReader(body))
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code, name)
+		assert.Contains(t, w.Body.String(), tc.message, name)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// LOG CATEGORY RULE ENDPOINTS
+// ============================================================================
+
+var (
+	logCategoryNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	sqlStatePrefixPattern  = regexp.MustCompile(`^[0-9A-Z]{1,5}$`)
+)
+
+// @Summary List log category rules
+// @Description List a tenant's custom log categories in evaluation order
+// @Tags Tenants
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Success 200 {array} models.LogCategoryRule
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/log-categories [get]
+func (s *Server) handleListLogCategoryRules(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+
+	rules, err := s.postgres.ListLogCategoryRules(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+	if rules == nil {
+		rules = []*models.LogCategoryRule{}
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// @Summary Create log category rule
+// @Description Add a custom log category matched by SQLSTATE prefix and/or message regex, evaluated before the built-in rules (admin only)
+// @Tags Tenants
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param rule body models.LogCategoryRuleRequest true "Log category rule"
+// @Success 201 {object} models.LogCategoryRule
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/log-categories [post]
+func (s *Server) handleCreateLogCategoryRule(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.LogCategoryRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp := apperrors.BadRequest("Invalid request body", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	rule, errResp := buildLogCategoryRule(&req)
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+	rule.TenantID = tenantID
+
+	if err := s.postgres.CreateLogCategoryRule(c.Request.Context(), rule); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// @Summary Delete log category rule
+// @Description Remove a tenant's custom log category (admin only)
+// @Tags Tenants
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param ruleId path int true "Rule ID"
+// @Success 204
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/log-categories/{ruleId} [delete]
+func (s *Server) handleDeleteLogCategoryRule(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+
+	ruleID, err := strconv.ParseInt(c.Param("ruleId"), 10, 64)
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid rule ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	if err := s.postgres.DeleteLogCategoryRule(c.Request.Context(), tenantID, ruleID); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// buildLogCategoryRule validates a rule request; a rule needs a SQLSTATE
+// prefix, a pattern, or both
+func buildLogCategoryRule(req *models.LogCategoryRuleRequest) (*models.LogCategoryRule, *apperrors.AppError) {
+	if !logCategoryNamePattern.MatchString(req.Category) {
+		return nil, apperrors.BadRequest("Invalid category",
+			"category must be lowercase letters, digits and underscores, starting with a letter")
+	}
+
+	rule := &models.LogCategoryRule{
+		Category: req.Category,
+		Priority: req.Priority,
+		Enabled:  req.Enabled == nil || *req.Enabled,
+	}
+
+	if req.SQLStatePrefix != nil && *req.SQLStatePrefix != "" {
+		prefix := strings.ToUpper(*req.SQLStatePrefix)
+		if !sqlStatePrefixPattern.MatchString(prefix) {
+			return nil, apperrors.BadRequest("Invalid sqlstate_prefix", "expected 1 to 5 SQLSTATE characters, e.g. 40 or 40P01")
+		}
+		rule.SQLStatePrefix = &prefix
+	}
+	if req.Pattern != nil && *req.Pattern != "" {
+		if _, err := regexp.Compile(*req.Pattern); err != nil {
+			return nil, apperrors.BadRequest("Invalid pattern", err.Error())
+		}
+		rule.Pattern = req.Pattern
+	}
+	if rule.SQLStatePrefix == nil && rule.Pattern == nil {
+		return nil, apperrors.BadRequest("Missing rule condition", "sqlstate_prefix or pattern is required")
+	}
+
+	return rule, nil
+}
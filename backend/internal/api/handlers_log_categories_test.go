@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// TestBuildLogCategoryRule validates custom log category requests
+func TestBuildLogCategoryRule(t *testing.T) {
+	str := func(s string) *string { return &s }
+	disabled := false
+
+	rule, errResp := buildLogCategoryRule(&models.LogCategoryRuleRequest{
+		Category:       "app_exception",
+		SQLStatePrefix: str("p0"),
+		Priority:       5,
+	})
+	require.Nil(t, errResp)
+	assert.Equal(t, "P0", *rule.SQLStatePrefix)
+	assert.Nil(t, rule.Pattern)
+	assert.True(t, rule.Enabled)
+	assert.Equal(t, 5, rule.Priority)
+
+	rule, errResp = buildLogCategoryRule(&models.LogCategoryRuleRequest{
+		Category: "billing", Pattern: str("invoice \\d+"), Enabled: &disabled,
+	})
+	require.Nil(t, errResp)
+	assert.False(t, rule.Enabled)
+
+	for name, req := range map[string]*models.LogCategoryRuleRequest{
+		"Invalid category":        {Category: "Bad Name", Pattern: str("x")},
+		"Invalid sqlstate_prefix": {Category: "ok", SQLStatePrefix: str("40P011")},
+		"Invalid pattern":         {Category: "ok", Pattern: str("(")},
+		"Missing rule condition":  {Category: "ok", Pattern: str("")},
+	} {
+		_, errResp := buildLogCategoryRule(req)
+		require.NotNil(t, errResp, name)
+		assert.Equal(t, name, errResp.Message)
+	}
+}
+
+// TestLogCategoryRules_TenantRole lets tenant members list rules and only
+// tenant admins change them
+func TestLogCategoryRules_TenantRole(t *testing.T) {
+	testTenantRoutes(t, func(s *Server, tenants *gin.RouterGroup) {
+		tenants.GET("/:id/log-categories", s.handleListLogCategoryRules)
+		tenants.POST("/:id/log-categories", s.handleCreateLogCategoryRule)
+		tenants.DELETE("/:id/log-categories/:ruleId", s.handleDeleteLogCategoryRule)
+	}, []tenantRouteCase{
+		{"GET", "/log-categories", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM log_category_rules")).
+				WithArgs(tenantID).
+				WillReturnRows(emptyRows())
+		}},
+		{"POST", "/log-categories", `{"category":"deadlocks","sqlstate_prefix":"40P01"}`, true, http.StatusCreated, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO log_category_rules")).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, time.Now()))
+		}},
+		{"DELETE", "/log-categories/5", "", true, http.StatusNoContent, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectExec(regexp.QuoteMeta("DELETE FROM log_category_rules")).
+				WithArgs(tenantID, int64(5)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}},
+	})
+}
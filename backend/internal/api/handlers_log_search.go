@@ -0,0 +1,128 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ============================================================================
+// LOG SEARCH ENDPOINTS
+// ============================================================================
+
+// @Summary Search Logs
+// @Description Search the stored PostgreSQL logs of the caller's instances with a query language, newest first.
+// @Description Terms are ANDed unless joined by OR and can be negated with NOT or -. Fields: severity (severity:>=WARNING),
+// @Description db, user, app, category (a trailing * matches a prefix), sqlstate (a code or 2 character class) and instance.
+// @Description Other words and "quoted phrases" are matched with full-text search on the message, e.g.
+// @Description severity:ERROR AND db:orders AND "could not serialize". The first page includes facets.
+// @Tags Logs
+// @Produce json
+// @Security Bearer
+// @Param q query string false "Search query"
+// @Param from query string false "Start time (RFC3339), default 24 hours before to"
+// @Param to query string false "End time (RFC3339), default now"
+// @Param cursor query string false "next_cursor of the previous page; the page continues that search's time range"
+// @Param limit query int false "Page size (max 1000)" default(100)
+// @Success 200 {object} models.LogSearchResponse
+// @Failure 400 {object} apperrors.AppError
+// @Failure 401 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/logs/search [get]
+func (s *Server) handleSearchLogs(c *gin.Context) {
+	req, errResp := parseLogSearchRequest(c.Query("q"), c.Query("from"), c.Query("to"), c.Query("cursor"), c.Query("limit"), time.Now())
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	userID, ok := s.requireUserID(c)
+	if !ok {
+		return
+	}
+
+	// Searches are limited to the instances of the user's tenants
+	access, err := s.wsManager.ResolveAccess(c.Request.Context(), realtimeUserID(userID))
+	if err != nil {
+		s.logger.Error("Failed to resolve instance access", zap.Error(err))
+		errResp := apperrors.InternalServerError("Failed to resolve instance access", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+	if !access.All {
+		req.params.InstanceIDs = append([]int{}, access.Instances...)
+	}
+
+	resp, err := log_analysis.NewSearchService(s.postgres).Search(c.Request.Context(), req.query, req.params)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// logSearchRequest is a validated log search request
+type logSearchRequest struct {
+	query  *log_analysis.SearchQuery
+	params models.LogSearchParams
+}
+
+// parseLogSearchRequest validates the query parameters of the search
+// endpoint. A cursor carries the time range of the search it continues,
+// which takes precedence over from and to.
+func parseLogSearchRequest(q, from, to, cursor, limit string, now time.Time) (*logSearchRequest, *apperrors.AppError) {
+	query, err := log_analysis.ParseSearchQuery(q)
+	if err != nil {
+		return nil, apperrors.BadRequest("Invalid search query", err.Error())
+	}
+	req := &logSearchRequest{query: query}
+
+	req.params.Limit = log_analysis.DefaultLogSearchLimit
+	if limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 || parsed > log_analysis.MaxLogSearchLimit {
+			return nil, apperrors.BadRequest("Invalid limit", "limit must be between 1 and 1000")
+		}
+		req.params.Limit = parsed
+	}
+
+	if cursor != "" {
+		after, err := log_analysis.DecodeLogSearchCursor(cursor)
+		if err != nil {
+			return nil, apperrors.BadRequest("Invalid cursor", err.Error())
+		}
+		req.params.After = after
+		req.params.From = after.From
+		req.params.To = after.To
+		return req, nil
+	}
+
+	req.params.To = now
+	if to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, apperrors.BadRequest("Invalid to", "expected RFC3339")
+		}
+		req.params.To = t
+	}
+	req.params.From = req.params.To.Add(-log_analysis.DefaultLogSearchWindow)
+	if from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, apperrors.BadRequest("Invalid from", "expected RFC3339")
+		}
+		req.params.From = t
+	}
+	if !req.params.From.Before(req.params.To) {
+		return nil, apperrors.BadRequest("Invalid time range", "from must be before to")
+	}
+
+	return req, nil
+}
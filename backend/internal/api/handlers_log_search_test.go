@@ -0,0 +1,51 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// TestParseLogSearchRequest validates log search query parameters
+func TestParseLogSearchRequest(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	req, errResp := parseLogSearchRequest("severity:ERROR", "", "", "", "", now)
+	require.Nil(t, errResp)
+	assert.Equal(t, now, req.params.To)
+	assert.Equal(t, now.Add(-24*time.Hour), req.params.From)
+	assert.Equal(t, log_analysis.DefaultLogSearchLimit, req.params.Limit)
+	assert.Nil(t, req.params.After)
+
+	req, errResp = parseLogSearchRequest("", "2026-02-01T00:00:00Z", "2026-02-02T00:00:00Z", "", "50", now)
+	require.Nil(t, errResp)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), req.params.From)
+	assert.Equal(t, 50, req.params.Limit)
+
+	// A cursor continues the time range of its search
+	cursor := log_analysis.EncodeLogSearchCursor(models.LogSearchCursor{
+		Timestamp: now.Add(-time.Hour), ID: 42, From: now.Add(-2 * time.Hour), To: now.Add(-time.Minute),
+	})
+	req, errResp = parseLogSearchRequest("", "", "", cursor, "", now.Add(time.Hour))
+	require.Nil(t, errResp)
+	require.NotNil(t, req.params.After)
+	assert.Equal(t, int64(42), req.params.After.ID)
+	assert.True(t, req.params.To.Equal(now.Add(-time.Minute)))
+
+	for name, tc := range map[string][]string{
+		"Invalid search query": {`"open`, "", "", "", ""},
+		"Invalid limit":        {"", "", "", "", "5000"},
+		"Invalid cursor":       {"", "", "", "bm9wZQ", ""},
+		"Invalid from":         {"", "yesterday", "", "", ""},
+		"Invalid to":           {"", "", "now", "", ""},
+		"Invalid time range":   {"", "2026-02-02T00:00:00Z", "2026-02-01T00:00:00Z", "", ""},
+	} {
+		_, errResp := parseLogSearchRequest(tc[0], tc[1], tc[2], tc[3], tc[4], now)
+		require.NotNil(t, errResp, name)
+		assert.Equal(t, name, errResp.Message)
+	}
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+)
+
+// ============================================================================
+// LOG TEMPLATE ENDPOINTS
+// ============================================================================
+
+// @Summary List Log Templates
+// @Description List a collector's most frequent log templates in a window, flagging new templates and frequency spikes
+// @Tags Logs
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param window query string false "Window ending now" default(1h)
+// @Param from query string false "Window start (RFC3339), instead of window"
+// @Param to query string false "Window end (RFC3339), instead of window"
+// @Param baseline_windows query int false "Window lengths before the window used as the baseline" default(24)
+// @Param spike_factor query number false "Count over baseline rate that counts as a spike" default(3)
+// @Param anomalies_only query bool false "Only return new and spiking templates" default(false)
+// @Param limit query int false "Result limit" default(20)
+// @Success 200 {object} models.LogTemplateReport
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/logs/templates [get]
+func (s *Server) handleGetLogTemplates(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	from, to, errResp := parseLogTemplateWindow(c)
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	opts := log_analysis.TemplateReportOptions{Limit: 20}
+	if l, err := strconv.Atoi(c.DefaultQuery("limit", "20")); err == nil && l > 0 && l <= 500 {
+		opts.Limit = l
+	}
+	if n, err := strconv.Atoi(c.Query("baseline_windows")); err == nil && n > 0 && n <= 720 {
+		opts.BaselineWindows = n
+	}
+	if v := c.Query("spike_factor"); v != "" {
+		factor, err := strconv.ParseFloat(v, 64)
+		if err != nil || factor <= 1 {
+			errResp := apperrors.BadRequest("Invalid spike_factor", "expected a number greater than 1")
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+		opts.SpikeFactor = factor
+	}
+	if v, err := strconv.ParseBool(c.DefaultQuery("anomalies_only", "false")); err == nil {
+		opts.AnomaliesOnly = v
+	}
+
+	report, err := log_analysis.NewTemplateService(s.postgres).TopTemplates(c.Request.Context(), collectorID, from, to, opts)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseLogTemplateWindow reads either from/to or a window ending now
+func parseLogTemplateWindow(c *gin.Context) (time.Time, time.Time, *apperrors.AppError) {
+	if fromParam, toParam := c.Query("from"), c.Query("to"); fromParam != "" || toParam != "" {
+		from, err := time.Parse(time.RFC3339, fromParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, apperrors.BadRequest("Invalid from timestamp", "expected RFC3339")
+		}
+		to, err := time.Parse(time.RFC3339, toParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, apperrors.BadRequest("Invalid to timestamp", "expected RFC3339")
+		}
+		if !from.Before(to) {
+			return time.Time{}, time.Time{}, apperrors.BadRequest("Invalid window", "from must be before to")
+		}
+		return from, to, nil
+	}
+
+	window, err := time.ParseDuration(c.DefaultQuery("window", "1h"))
+	if err != nil || window < time.Minute {
+		return time.Time{}, time.Time{}, apperrors.BadRequest("Invalid window", "expected a duration of at least 1m such as 15m or 6h")
+	}
+	to := time.Now()
+	return to.Add(-window), to, nil
+}
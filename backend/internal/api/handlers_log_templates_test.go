@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// TestGetLogTemplates_InvalidRequest rejects malformed template report parameters
+func TestGetLogTemplates_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{logger: zap.NewNop()}
+	router := gin.New()
+	router.GET("/api/v1/collectors/:id/logs/templates", server.handleGetLogTemplates)
+
+	base := "/api/v1/collectors/" + uuid.New().String() + "/logs/templates"
+	for path, message := range map[string]string{
+		"/api/v1/collectors/not-a-uuid/logs/templates":              "Invalid collector ID",
+		base + "?window=30s":                                        "Invalid window",
+		base + "?window=soon":                                       "Invalid window",
+		base + "?from=2026-03-01T10:00:00Z":                         "Invalid to timestamp",
+		base + "?from=2026-03-01T10:00:00Z&to=2026-03-01T09:00:00Z": "Invalid window",
+		base + "?spike_factor=0.5":                                  "Invalid spike_factor",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Contains(t, w.Body.String(), message, path)
+	}
+}
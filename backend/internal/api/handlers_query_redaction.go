@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ============================================================================
+// QUERY REDACTION ENDPOINTS
+// ============================================================================
+
+// @Summary Get query redaction policy
+// @Description Get how query samples are redacted at ingest for a tenant
+// @Tags Tenants
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.QueryRedactionPolicy
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/query-redaction [get]
+func (s *Server) handleGetQueryRedactionPolicy(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+
+	policy, err := s.postgres.GetQueryRedactionPolicy(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// @Summary Update query redaction policy
+// @Description Set how query samples are redacted at ingest for a tenant (admin only)
+// @Tags Tenants
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param policy body models.QueryRedactionPolicyRequest true "Redaction mode (none, normalize, mask)"
+// @Success 200 {object} models.QueryRedactionPolicy
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/query-redaction [put]
+func (s *Server) handleUpdateQueryRedactionPolicy(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.QueryRedactionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp := apperrors.BadRequest("Invalid request body", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	switch req.Mode {
+	case models.RedactionModeNone, models.RedactionModeNormalize, models.RedactionModeMask:
+	default:
+		errResp := apperrors.BadRequest("Invalid redaction mode", "mode must be one of: none, normalize, mask")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	policy := &models.QueryRedactionPolicy{
+		TenantID: tenantID,
+		Mode:     req.Mode,
+	}
+	if err := s.postgres.UpsertQueryRedactionPolicy(c.Request.Context(), policy); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// @Summary Backfill query redaction
+// @Description Apply the tenant's current redaction policy to already stored query samples, log messages and explain plans (admin only)
+// @Tags Tenants
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.QueryRedactionBackfillResponse
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/query-redaction/backfill [post]
+func (s *Server) handleBackfillQueryRedaction(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+
+	resp, err := query_performance.BackfillRedaction(c.Request.Context(), s.postgres, tenantID)
+	if err != nil {
+		s.logger.Error("Failed to backfill query redaction",
+			zap.String("tenant_id", tenantID.String()),
+			zap.Error(err))
+		if appErr, isAppErr := err.(*apperrors.AppError); isAppErr {
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		errResp := apperrors.InternalServerError("Failed to backfill query redaction", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	s.logger.Info("Query redaction backfill completed",
+		zap.String("tenant_id", tenantID.String()),
+		zap.String("mode", resp.Mode),
+		zap.Int64("rows_scanned", resp.RowsScanned),
+		zap.Int64("rows_updated", resp.RowsUpdated))
+
+	c.JSON(http.StatusOK, resp)
+}
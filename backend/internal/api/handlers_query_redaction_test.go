@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TestQueryRedaction_TenantRole lets tenant members read the policy and only
+// tenant admins change or backfill it
+func TestQueryRedaction_TenantRole(t *testing.T) {
+	testTenantRoutes(t, func(s *Server, tenants *gin.RouterGroup) {
+		tenants.GET("/:id/query-redaction", s.handleGetQueryRedactionPolicy)
+		tenants.PUT("/:id/query-redaction", s.handleUpdateQueryRedactionPolicy)
+		tenants.POST("/:id/query-redaction/backfill", s.handleBackfillQueryRedaction)
+	}, []tenantRouteCase{
+		{"GET", "/query-redaction", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM query_redaction_policies")).
+				WithArgs(tenantID).
+				WillReturnRows(emptyRows())
+		}},
+		{"PUT", "/query-redaction", `{"mode":"normalize"}`, true, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO query_redaction_policies")).
+				WithArgs(tenantID, "normalize").
+				WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+		}},
+		{"POST", "/query-redaction/backfill", "", true, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			// Without a policy there is nothing to redact
+			mock.ExpectQuery(regexp.QuoteMeta("FROM query_redaction_policies")).
+				WithArgs(tenantID).
+				WillReturnRows(emptyRows())
+		}},
+	})
+}
@@ -0,0 +1,250 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_regression"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// QUERY REGRESSION ENDPOINTS
+// ============================================================================
+
+// defaultRegressionWindow is the window on each side of a deploy marker
+const defaultRegressionWindow = time.Hour
+
+// @Summary Create Deploy Marker
+// @Description Record an application release for query regression reports
+// @Tags Queries
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param request body models.CreateDeployMarkerRequest true "Deploy marker"
+// @Success 201 {object} models.DeployMarker
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/deploy-markers [post]
+func (s *Server) handleCreateDeployMarker(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	var req models.CreateDeployMarkerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp := apperrors.BadRequest("Invalid request body", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	marker := &models.DeployMarker{
+		CollectorID: collectorID,
+		Version:     req.Version,
+		Description: req.Description,
+		DeployedAt:  time.Now(),
+	}
+	if req.DeployedAt != nil {
+		marker.DeployedAt = *req.DeployedAt
+	}
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(int); ok {
+			marker.CreatedBy = &id
+		}
+	}
+
+	if err := s.postgres.CreateDeployMarker(c.Request.Context(), marker); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, marker)
+}
+
+// @Summary List Deploy Markers
+// @Description List a collector's most recent deploy markers
+// @Tags Queries
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param limit query int false "Result limit" default(20)
+// @Success 200 {array} models.DeployMarker
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/deploy-markers [get]
+func (s *Server) handleListDeployMarkers(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	limit := 20
+	if l, err := strconv.Atoi(c.DefaultQuery("limit", "20")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	markers, err := s.postgres.ListDeployMarkers(c.Request.Context(), collectorID, limit)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+	if markers == nil {
+		markers = []*models.DeployMarker{}
+	}
+
+	c.JSON(http.StatusOK, markers)
+}
+
+// @Summary Get Query Regression Report
+// @Description Compare every query fingerprint between two time windows, or around a deploy marker, ranked by added DB time. p95_interval_mean_ms is the 95th percentile of per-sampling-interval mean latencies, not of individual calls
+// @Tags Queries
+// @Produce json,text/markdown
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param marker_id query int false "Deploy marker to compare around (instead of explicit windows)"
+// @Param window query string false "Window on each side of the deploy marker" default(1h)
+// @Param baseline_from query string false "Baseline window start (RFC3339)"
+// @Param baseline_to query string false "Baseline window end (RFC3339)"
+// @Param target_from query string false "Target window start (RFC3339)"
+// @Param target_to query string false "Target window end (RFC3339)"
+// @Param database query string false "Database name"
+// @Param alpha query number false "Significance level" default(0.05)
+// @Param min_calls query int false "Skip queries with fewer calls in both windows" default(10)
+// @Param significant_only query bool false "Only return significant changes and new queries" default(false)
+// @Param limit query int false "Result limit" default(50)
+// @Param format query string false "json or markdown" default(json)
+// @Success 200 {object} models.RegressionReport
+// @Failure 400 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/queries/regressions [get]
+func (s *Server) handleGetQueryRegressions(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" {
+		errResp := apperrors.BadRequest("Invalid format", "expected json or markdown")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	opts, errResp := parseRegressionOptions(c)
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	service := query_regression.NewService(s.postgres, s.logger)
+
+	var report *models.RegressionReport
+	if markerParam := c.Query("marker_id"); markerParam != "" {
+		markerID, err := strconv.ParseInt(markerParam, 10, 64)
+		if err != nil {
+			errResp := apperrors.BadRequest("Invalid marker_id", err.Error())
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+		window, err := time.ParseDuration(c.DefaultQuery("window", defaultRegressionWindow.String()))
+		if err != nil || window <= 0 {
+			errResp := apperrors.BadRequest("Invalid window", "expected a positive duration such as 30m or 2h")
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+		report, err = service.GenerateDeployReport(c.Request.Context(), collectorID, markerID, window, opts)
+		if err != nil {
+			c.JSON(err.(*apperrors.AppError).StatusCode, err)
+			return
+		}
+	} else {
+		baseline, errResp := parseRegressionWindow(c, "baseline")
+		if errResp != nil {
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+		target, errResp := parseRegressionWindow(c, "target")
+		if errResp != nil {
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+		report, err = service.GenerateReport(c.Request.Context(), collectorID, baseline, target, opts)
+		if err != nil {
+			c.JSON(err.(*apperrors.AppError).StatusCode, err)
+			return
+		}
+	}
+
+	if format == "markdown" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"query-regressions-%d.md\"", report.GeneratedAt.Unix()))
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(query_regression.RenderMarkdown(report)))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseRegressionOptions reads the filters shared by regression reports
+func parseRegressionOptions(c *gin.Context) (models.RegressionOptions, *apperrors.AppError) {
+	opts := models.RegressionOptions{
+		Alpha:    query_regression.DefaultAlpha,
+		MinCalls: 10,
+		Limit:    50,
+	}
+
+	if database := c.Query("database"); database != "" {
+		opts.Database = &database
+	}
+	if v := c.Query("alpha"); v != "" {
+		alpha, err := strconv.ParseFloat(v, 64)
+		if err != nil || alpha <= 0 || alpha >= 1 {
+			return opts, apperrors.BadRequest("Invalid alpha", "expected a value between 0 and 1")
+		}
+		opts.Alpha = alpha
+	}
+	if v, err := strconv.ParseInt(c.DefaultQuery("min_calls", "10"), 10, 64); err == nil && v >= 0 {
+		opts.MinCalls = v
+	}
+	if v, err := strconv.ParseBool(c.DefaultQuery("significant_only", "false")); err == nil {
+		opts.SignificantOnly = v
+	}
+	if l, err := strconv.Atoi(c.DefaultQuery("limit", "50")); err == nil && l > 0 && l <= 500 {
+		opts.Limit = l
+	}
+
+	return opts, nil
+}
+
+// parseRegressionWindow reads the <prefix>_from and <prefix>_to parameters
+func parseRegressionWindow(c *gin.Context, prefix string) (models.RegressionWindow, *apperrors.AppError) {
+	var window models.RegressionWindow
+	from, to := c.Query(prefix+"_from"), c.Query(prefix+"_to")
+	if from == "" || to == "" {
+		return window, apperrors.BadRequest("Missing "+prefix+" window",
+			fmt.Sprintf("%s_from and %s_to are required unless marker_id is given", prefix, prefix))
+	}
+
+	var err error
+	if window.From, err = time.Parse(time.RFC3339, from); err != nil {
+		return window, apperrors.BadRequest("Invalid "+prefix+"_from timestamp", "expected RFC3339")
+	}
+	if window.To, err = time.Parse(time.RFC3339, to); err != nil {
+		return window, apperrors.BadRequest("Invalid "+prefix+"_to timestamp", "expected RFC3339")
+	}
+	if !window.From.Before(window.To) {
+		return window, apperrors.BadRequest("Invalid "+prefix+" window", prefix+"_from must be before "+prefix+"_to")
+	}
+
+	return window, nil
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// TestGetQueryRegressions_InvalidRequest rejects malformed report parameters
+func TestGetQueryRegressions_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{logger: zap.NewNop()}
+	router := gin.New()
+	router.GET("/api/v1/collectors/:id/queries/regressions", server.handleGetQueryRegressions)
+
+	base := "/api/v1/collectors/" + uuid.New().String() + "/queries/regressions"
+	for path, message := range map[string]string{
+		"/api/v1/collectors/not-a-uuid/queries/regressions": "Invalid collector ID",
+		base + "?format=pdf":             "Invalid format",
+		base + "?alpha=2":                "Invalid alpha",
+		base + "?marker_id=abc":          "Invalid marker_id",
+		base + "?marker_id=1&window=-1h": "Invalid window",
+		base:                             "Missing baseline window",
+		base + "?baseline_from=2026-03-01T10:00:00Z&baseline_to=2026-03-01T09:00:00Z&target_from=2026-03-01T10:00:00Z&target_to=2026-03-01T11:00:00Z": "Invalid baseline window",
+		base + "?baseline_from=2026-03-01T09:00:00Z&baseline_to=2026-03-01T10:00:00Z&target_from=soon&target_to=2026-03-01T11:00:00Z":                 "Invalid target_from timestamp",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Contains(t, w.Body.String(), message, path)
+	}
+}
@@ -1,13 +1,23 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/auth"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 	"github.com/torresglauco/pganalytics-v3/backend/pkg/services"
+	"go.uber.org/zap"
 )
 
 var upgrader = websocket.Upgrader{
@@ -22,66 +32,320 @@ var upgrader = websocket.Upgrader{
 // WebSocketHandler handles WebSocket upgrades and manages connections
 func WebSocketHandler(wsManager *services.ConnectionManager, jwtManager *auth.JWTManager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Extract and validate JWT token
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Missing authorization header", http.StatusUnauthorized)
+		userID, access, ok := authorizeRealtimeRequest(w, r, wsManager, jwtManager)
+		if !ok {
 			return
 		}
 
-		// Parse "Bearer {token}"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+		// Upgrade HTTP connection to WebSocket
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("WebSocket upgrade error: %v", err)
 			return
 		}
 
-		token := parts[1]
+		// Register connection; the connection reads client messages
+		// (heartbeats, subscriptions) and unregisters itself on close
+		wsManager.RegisterConnectionWithAccess(userID, access, conn)
+		log.Printf("WebSocket connection established for user %s", userID)
+	}
+}
 
-		// Validate JWT
-		claims, err := jwtManager.ValidateUserToken(token)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+// EventStreamHandler streams the events of the WebSocket endpoint as
+// Server-Sent Events, for clients behind proxies that break WebSockets.
+// Subscriptions are fixed by the instances and event_types query
+// parameters, and a reconnecting client resumes after its Last-Event-ID
+// from the recently broadcast events.
+func EventStreamHandler(wsManager *services.ConnectionManager, jwtManager *auth.JWTManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, access, ok := authorizeRealtimeRequest(w, r, wsManager, jwtManager)
+		if !ok {
 			return
 		}
 
-		userID := claims.Subject
-		// For now, assume user has access to all instances
-		// TODO: Query database for user's accessible instances
-		instances := []int{1, 2, 3}
-
-		// Upgrade HTTP connection to WebSocket
-		conn, err := upgrader.Upgrade(w, r, nil)
+		req, err := parseSubscriptionQuery(r)
 		if err != nil {
-			log.Printf("WebSocket upgrade error: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		lastEventID, resume, err := parseLastEventID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// Register connection
-		wsConn := wsManager.RegisterConnection(userID, instances, conn)
-		log.Printf("WebSocket connection established for user %s", userID)
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		conn := wsManager.RegisterStreamConnection(userID, access)
+		defer wsManager.UnregisterConnection(userID, conn)
+
+		if len(req.Instances) > 0 || len(req.EventTypes) > 0 {
+			if _, err := conn.Subscribe(req); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+
+		// The stream outlives the server write timeout; each write sets
+		// its own deadline instead
+		rc := http.NewResponseController(w)
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		write := func(frame string) bool {
+			_ = rc.SetWriteDeadline(time.Now().Add(eventStreamWriteTimeout))
+			if _, err := io.WriteString(w, frame); err != nil {
+				return false
+			}
+			flusher.Flush()
+			return true
+		}
 
-		// Listen for client messages (heartbeat, etc)
-		go func() {
-			defer func() {
-				wsManager.UnregisterConnection(userID, wsConn)
-				log.Printf("WebSocket connection closed for user %s", userID)
-			}()
-
-			for {
-				message := make(map[string]interface{})
-				if err := conn.ReadJSON(&message); err != nil {
-					if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-						log.Printf("WebSocket error: %v", err)
-					}
+		if !write(fmt.Sprintf("retry: %d\n\n", eventStreamRetry.Milliseconds())) {
+			return
+		}
+
+		// Live events up to the last replayed one were already sent
+		var replayedUpTo uint64
+		if resume {
+			events, last, complete := wsManager.ReplayEvents(conn, lastEventID)
+			if !complete {
+				gap := services.WebSocketEvent{Type: "gap", Data: map[string]uint64{"last_event_id": lastEventID}}
+				if !write(formatServerSentEvent(gap)) {
 					return
 				}
+			}
+			for _, event := range events {
+				if !write(formatServerSentEvent(event)) {
+					return
+				}
+			}
+			replayedUpTo = last
+		}
 
-				// Handle client messages (ping/heartbeat)
-				if msgType, ok := message["type"].(string); ok && msgType == "ping" {
-					wsConn.SendMessage(map[string]string{"type": "pong"})
+		keepalive := time.NewTicker(eventStreamKeepalive)
+		defer keepalive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-conn.Done():
+				return
+			case <-keepalive.C:
+				if !write(": keepalive\n\n") {
+					return
+				}
+			case msg := <-conn.Messages():
+				event, ok := msg.(services.WebSocketEvent)
+				if !ok || (event.ID != 0 && event.ID <= replayedUpTo) {
+					continue
 				}
+				if !write(formatServerSentEvent(event)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+const (
+	eventStreamWriteTimeout = 10 * time.Second
+	eventStreamKeepalive    = 30 * time.Second
+	eventStreamRetry        = 3 * time.Second
+)
+
+// formatServerSentEvent encodes an event as an SSE frame; events without an
+// ID, such as access changes, are not resumable
+func formatServerSentEvent(event services.WebSocketEvent) string {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		data = []byte("null")
+	}
+
+	var b strings.Builder
+	if event.ID != 0 {
+		fmt.Fprintf(&b, "id: %d\n", event.ID)
+	}
+	fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", event.Type, data)
+	return b.String()
+}
+
+// parseSubscriptionQuery reads comma separated instances and event_types
+func parseSubscriptionQuery(r *http.Request) (services.SubscriptionRequest, error) {
+	var req services.SubscriptionRequest
+	query := r.URL.Query()
+
+	for _, value := range query["instances"] {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || id <= 0 {
+				return req, fmt.Errorf("invalid instance id %q", part)
 			}
-		}()
+			req.Instances = append(req.Instances, id)
+		}
+	}
+	for _, value := range query["event_types"] {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				req.EventTypes = append(req.EventTypes, part)
+			}
+		}
+	}
+	return req, nil
+}
+
+// parseLastEventID reads the ID a reconnecting client resumes after, from
+// the Last-Event-ID header or the last_event_id query parameter
+func parseLastEventID(r *http.Request) (id uint64, ok bool, err error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+	id, err = strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid Last-Event-ID %q", value)
+	}
+	return id, true, nil
+}
+
+// authorizeRealtimeRequest validates the bearer token of a realtime request
+// and resolves the instances the user may receive events for
+func authorizeRealtimeRequest(w http.ResponseWriter, r *http.Request, wsManager *services.ConnectionManager, jwtManager *auth.JWTManager) (string, services.InstanceAccess, bool) {
+	// Extract and validate JWT token
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		http.Error(w, "Missing authorization header", http.StatusUnauthorized)
+		return "", services.InstanceAccess{}, false
+	}
+
+	// Parse "Bearer {token}"
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+		return "", services.InstanceAccess{}, false
+	}
+
+	token := parts[1]
+
+	// Validate JWT
+	claims, err := jwtManager.ValidateUserToken(token)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return "", services.InstanceAccess{}, false
+	}
+
+	// Events are limited to the instances of the user's tenants
+	userID := claims.Subject
+	access, err := wsManager.ResolveAccess(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to resolve realtime access for user %s: %v", userID, err)
+		http.Error(w, "Failed to resolve instance access", http.StatusInternalServerError)
+		return "", services.InstanceAccess{}, false
+	}
+
+	return userID, access, true
+}
+
+// realtimeAccessStore is the data access for resolving realtime access
+type realtimeAccessStore interface {
+	GetUserByID(ctx context.Context, userID int) (*models.User, error)
+	GetTenantsByUserID(ctx context.Context, userID int) ([]*models.Tenant, error)
+	GetCollectorsByTenantID(ctx context.Context, tenantID uuid.UUID) ([]*models.Collector, error)
+	GetInstanceIDsByCollectorIDs(ctx context.Context, collectorIDs []uuid.UUID) ([]int, error)
+}
+
+var _ realtimeAccessStore = (*storage.PostgresDB)(nil)
+
+// realtimeAccessResolver grants global administrators every instance and
+// other users the instances monitored by the collectors of their tenants
+type realtimeAccessResolver struct {
+	store realtimeAccessStore
+}
+
+// ResolveInstanceAccess resolves access for a token subject ("user:<id>")
+func (r *realtimeAccessResolver) ResolveInstanceAccess(ctx context.Context, subject string) (services.InstanceAccess, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(subject, "user:"))
+	if err != nil {
+		return services.InstanceAccess{}, fmt.Errorf("invalid user subject %q", subject)
+	}
+
+	user, err := r.store.GetUserByID(ctx, id)
+	if err != nil {
+		return services.InstanceAccess{}, err
+	}
+	if !user.IsActive {
+		return services.InstanceAccess{}, nil
+	}
+	if user.Role == "admin" {
+		return services.InstanceAccess{All: true}, nil
+	}
+
+	tenants, err := r.store.GetTenantsByUserID(ctx, user.ID)
+	if err != nil {
+		return services.InstanceAccess{}, err
+	}
+
+	var collectorIDs []uuid.UUID
+	for _, tenant := range tenants {
+		collectors, err := r.store.GetCollectorsByTenantID(ctx, tenant.ID)
+		if err != nil {
+			return services.InstanceAccess{}, err
+		}
+		for _, collector := range collectors {
+			collectorIDs = append(collectorIDs, collector.ID)
+		}
+	}
+
+	instances, err := r.store.GetInstanceIDsByCollectorIDs(ctx, collectorIDs)
+	if err != nil {
+		return services.InstanceAccess{}, err
 	}
+	return services.InstanceAccess{Instances: instances}, nil
+}
+
+// realtimeUserID returns the ID WebSocket connections of a user are
+// registered under, the subject of their tokens
+func realtimeUserID(userID int) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// refreshRealtimeAccess applies a user's changed access to their live
+// WebSocket connections
+func (s *Server) refreshRealtimeAccess(userID int) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := s.wsManager.RefreshAccess(ctx, realtimeUserID(userID)); err != nil {
+			s.logger.Warn("Failed to refresh WebSocket access", zap.Int("user_id", userID), zap.Error(err))
+		}
+	}()
+}
+
+// refreshAllRealtimeAccess applies changed tenant collectors to every live
+// WebSocket connection
+func (s *Server) refreshAllRealtimeAccess() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		s.wsManager.RefreshAllAccess(ctx)
+	}()
+}
+
+// closeRealtimeConnections disconnects the WebSocket connections of a
+// deleted user
+func (s *Server) closeRealtimeConnections(userID int) {
+	s.wsManager.CloseUserConnections(realtimeUserID(userID))
 }
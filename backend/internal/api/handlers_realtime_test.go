@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/auth"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/services"
+)
+
+type mockRealtimeAccessStore struct {
+	users      map[int]*models.User
+	tenants    map[int][]*models.Tenant
+	collectors map[uuid.UUID][]*models.Collector
+	instances  map[uuid.UUID][]int
+}
+
+func (m *mockRealtimeAccessStore) GetUserByID(ctx context.Context, userID int) (*models.User, error) {
+	if user, ok := m.users[userID]; ok {
+		return user, nil
+	}
+	return nil, apperrors.NotFound("User not found", "")
+}
+
+func (m *mockRealtimeAccessStore) GetTenantsByUserID(ctx context.Context, userID int) ([]*models.Tenant, error) {
+	return m.tenants[userID], nil
+}
+
+func (m *mockRealtimeAccessStore) GetCollectorsByTenantID(ctx context.Context, tenantID uuid.UUID) ([]*models.Collector, error) {
+	return m.collectors[tenantID], nil
+}
+
+func (m *mockRealtimeAccessStore) GetInstanceIDsByCollectorIDs(ctx context.Context, collectorIDs []uuid.UUID) ([]int, error) {
+	var ids []int
+	for _, id := range collectorIDs {
+		ids = append(ids, m.instances[id]...)
+	}
+	return ids, nil
+}
+
+// TestRealtimeAccessResolver derives instance access from tenant membership
+func TestRealtimeAccessResolver(t *testing.T) {
+	tenantA, tenantB := uuid.New(), uuid.New()
+	collectorA, collectorB, other := uuid.New(), uuid.New(), uuid.New()
+	store := &mockRealtimeAccessStore{
+		users: map[int]*models.User{
+			1: {ID: 1, Role: "admin", IsActive: true},
+			2: {ID: 2, Role: "user", IsActive: true},
+			3: {ID: 3, Role: "admin", IsActive: false},
+			4: {ID: 4, Role: "viewer", IsActive: true},
+		},
+		tenants: map[int][]*models.Tenant{
+			2: {{ID: tenantA}, {ID: tenantB}},
+		},
+		collectors: map[uuid.UUID][]*models.Collector{
+			tenantA: {{ID: collectorA}},
+			tenantB: {{ID: collectorB}},
+		},
+		instances: map[uuid.UUID][]int{collectorA: {1, 2}, collectorB: {5}, other: {9}},
+	}
+	resolver := &realtimeAccessResolver{store: store}
+	ctx := context.Background()
+
+	access, err := resolver.ResolveInstanceAccess(ctx, "user:1")
+	require.NoError(t, err)
+	assert.True(t, access.All)
+
+	access, err = resolver.ResolveInstanceAccess(ctx, "user:2")
+	require.NoError(t, err)
+	assert.False(t, access.All)
+	assert.Equal(t, []int{1, 2, 5}, access.Instances)
+	assert.False(t, access.Allows(9))
+
+	// Deactivated users and users without tenants see nothing
+	access, err = resolver.ResolveInstanceAccess(ctx, "user:3")
+	require.NoError(t, err)
+	assert.Equal(t, services.InstanceAccess{}, access)
+
+	access, err = resolver.ResolveInstanceAccess(ctx, "user:4")
+	require.NoError(t, err)
+	assert.Empty(t, access.Instances)
+
+	_, err = resolver.ResolveInstanceAccess(ctx, "collector:abc")
+	assert.Error(t, err)
+	_, err = resolver.ResolveInstanceAccess(ctx, "user:99")
+	assert.Error(t, err)
+}
+
+// TestWebSocketHandler_RequiresToken rejects connections without a valid token
+func TestWebSocketHandler_RequiresToken(t *testing.T) {
+	handler := WebSocketHandler(services.NewConnectionManager(nil), nil)
+
+	for _, header := range []string{"", "Token abc"} {
+		req := httptest.NewRequest("GET", "/api/v1/ws", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		handler(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+}
+
+type stubRealtimeResolver struct{}
+
+func (stubRealtimeResolver) ResolveInstanceAccess(ctx context.Context, userID string) (services.InstanceAccess, error) {
+	return services.InstanceAccess{Instances: []int{1}}, nil
+}
+
+// TestEventStreamHandler streams and replays events as Server-Sent Events
+func TestEventStreamHandler(t *testing.T) {
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour, time.Hour, time.Hour)
+	token, _, err := jwtManager.GenerateUserToken(&models.User{ID: 7, Username: "viewer", Role: "user"})
+	require.NoError(t, err)
+
+	wsManager := services.NewConnectionManager(nil)
+	wsManager.SetAccessResolver(stubRealtimeResolver{})
+	server := httptest.NewServer(EventStreamHandler(wsManager, jwtManager))
+	defer server.Close()
+
+	get := func(query, lastEventID string) *http.Response {
+		req, err := http.NewRequest("GET", server.URL+query, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	resp := get("?instances=2", "")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	_ = resp.Body.Close()
+	resp = get("?instances=abc", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	_ = wsManager.BroadcastLogEvent(map[string]interface{}{"message": "before"}, 1)
+	_ = wsManager.BroadcastLogEvent(map[string]interface{}{"message": "hidden"}, 2)
+
+	resp = get("?event_types=log:new", "0")
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	readFrame := func() string {
+		var frame strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			if line == "\n" {
+				return frame.String()
+			}
+			frame.WriteString(line)
+		}
+	}
+
+	assert.Equal(t, "retry: 3000\n", readFrame())
+	assert.Equal(t, "id: 1\nevent: log:new\ndata: {\"message\":\"before\"}\n", readFrame())
+
+	// Live events follow the replay, filtered by access and event type
+	_ = wsManager.BroadcastAlertEvent(map[string]interface{}{"alert_id": 1}, 1)
+	_ = wsManager.BroadcastLogEvent(map[string]interface{}{"message": "hidden"}, 2)
+	_ = wsManager.BroadcastLogEvent(map[string]interface{}{"message": "after"}, 1)
+	assert.Equal(t, "id: 5\nevent: log:new\ndata: {\"message\":\"after\"}\n", readFrame())
+}
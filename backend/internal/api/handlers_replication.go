@@ -1,16 +1,253 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/cluster_events"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/replication_topology"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/slot_risk"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
 	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
 	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
 )
 
+// ============================================================================
+// REPLICATION METRICS INGESTION
+// ============================================================================
+
+// ingestReplicationMetrics stores a pg_replication metric and returns the
+// number of rows inserted
+func (s *Server) ingestReplicationMetrics(c *gin.Context, collectorID string, metric interface{}) int {
+	metricJSON, _ := json.Marshal(metric)
+
+	var req models.ReplicationMetricsRequest
+	if err := json.Unmarshal(metricJSON, &req); err != nil {
+		s.logger.Error("Failed to unmarshal pg_replication metric", zap.Error(err))
+		return 0
+	}
+
+	rows := buildReplicationRows(metricsCollectorUUID(collectorID), &req)
+	ctx := c.Request.Context()
+	if err := s.postgres.StoreReplicationMetrics(ctx, rows.status); err != nil {
+		s.logger.Error("Failed to store replication status", zap.Error(err), zap.String("collector_id", collectorID))
+		return 0
+	}
+	if err := s.postgres.StoreReplicationSlots(ctx, rows.slots); err != nil {
+		s.logger.Error("Failed to store replication slots", zap.Error(err), zap.String("collector_id", collectorID))
+		return len(rows.status)
+	}
+	if err := s.postgres.StoreWalPosition(ctx, rows.position); err != nil {
+		s.logger.Error("Failed to store WAL position", zap.Error(err), zap.String("collector_id", collectorID))
+	}
+	if rows.node != nil {
+		// Observe logs its errors; a failed detection must not fail the push
+		service := cluster_events.NewService(storage.NewClusterEventRepository(s.postgres.GetDB()), s.wsManager, s.logger)
+		_, _ = service.Observe(ctx, rows.node)
+	}
+
+	return len(rows.status) + len(rows.slots)
+}
+
+// replicationRows are the rows of one pg_replication metric
+type replicationRows struct {
+	status   []*models.ReplicationStatus
+	slots    []*models.ReplicationSlot
+	position *models.WalPosition // nil when not reported
+	node     *models.NodeState   // nil when not reported
+}
+
+// buildReplicationRows converts a pg_replication metric into walsender and
+// slot rows, the WAL position and the node state, all sharing the metric's
+// timestamp
+func buildReplicationRows(collectorID uuid.UUID, req *models.ReplicationMetricsRequest) *replicationRows {
+	ts := time.Now()
+	if parsed, err := time.Parse(time.RFC3339, req.Timestamp); err == nil {
+		ts = parsed
+	}
+
+	status := make([]*models.ReplicationStatus, 0, len(req.ReplicationStatus))
+	for _, r := range req.ReplicationStatus {
+		status = append(status, &models.ReplicationStatus{
+			CollectorID:     collectorID,
+			Timestamp:       ts,
+			ServerPID:       r.ServerPID,
+			Usename:         r.Usename,
+			ApplicationName: r.ApplicationName,
+			State:           r.State,
+			SyncState:       r.SyncState,
+			WriteLsn:        r.WriteLsn,
+			FlushLsn:        r.FlushLsn,
+			ReplayLsn:       r.ReplayLsn,
+			WriteLagMs:      r.WriteLagMs,
+			FlushLagMs:      r.FlushLagMs,
+			ReplayLagMs:     r.ReplayLagMs,
+			BehindByMb:      r.BehindByMb,
+			ClientAddr:      r.ClientAddr,
+			BackendStart:    parsePostgresTime(r.BackendStart),
+		})
+	}
+
+	slots := make([]*models.ReplicationSlot, 0, len(req.ReplicationSlots))
+	for _, slot := range req.ReplicationSlots {
+		if slot == nil || slot.SlotName == "" {
+			continue
+		}
+		slot.CollectorID = collectorID
+		slot.Timestamp = ts
+		slots = append(slots, slot)
+	}
+
+	var position *models.WalPosition
+	if req.CurrentWalLsn != "" {
+		position = &models.WalPosition{
+			Time:          ts,
+			CollectorID:   collectorID,
+			CurrentWalLsn: req.CurrentWalLsn,
+		}
+		if req.WalStatus != nil && req.WalStatus.WalDirectorySizeMb > 0 {
+			size := req.WalStatus.WalDirectorySizeMb << 20
+			position.WalDirectoryBytes = &size
+		}
+		// -1 means unlimited
+		if mb, err := strconv.ParseInt(req.Settings["max_slot_wal_keep_size"], 10, 64); err == nil && mb >= 0 {
+			size := mb << 20
+			position.MaxSlotWalKeepSizeBytes = &size
+		}
+	}
+
+	var node *models.NodeState
+	if req.Node != nil {
+		node = &models.NodeState{
+			Time:             ts,
+			CollectorID:      collectorID,
+			SystemIdentifier: req.Node.SystemIdentifier,
+			TimelineID:       req.Node.TimelineID,
+			InRecovery:       req.Node.InRecovery,
+			ServerVersionNum: req.Node.ServerVersionNum,
+		}
+	}
+
+	return &replicationRows{status: status, slots: slots, position: position, node: node}
+}
+
+// ingestLogicalReplicationMetrics stores a pg_logical_replication metric and
+// returns the number of rows inserted
+func (s *Server) ingestLogicalReplicationMetrics(c *gin.Context, collectorID string, metric interface{}) int {
+	metricJSON, _ := json.Marshal(metric)
+
+	var req models.LogicalReplicationMetricsRequest
+	if err := json.Unmarshal(metricJSON, &req); err != nil {
+		s.logger.Error("Failed to unmarshal pg_logical_replication metric", zap.Error(err))
+		return 0
+	}
+
+	subs, pubs, receivers := buildLogicalReplicationRows(metricsCollectorUUID(collectorID), &req)
+	ctx := c.Request.Context()
+	inserted := 0
+	if err := s.postgres.StoreLogicalSubscriptions(ctx, subs); err != nil {
+		s.logger.Error("Failed to store logical subscriptions", zap.Error(err), zap.String("collector_id", collectorID))
+	} else {
+		inserted += len(subs)
+	}
+	if err := s.postgres.StorePublications(ctx, pubs); err != nil {
+		s.logger.Error("Failed to store publications", zap.Error(err), zap.String("collector_id", collectorID))
+	} else {
+		inserted += len(pubs)
+	}
+	if err := s.postgres.StoreWalReceivers(ctx, receivers); err != nil {
+		s.logger.Error("Failed to store wal receiver", zap.Error(err), zap.String("collector_id", collectorID))
+	} else {
+		inserted += len(receivers)
+	}
+
+	return inserted
+}
+
+// buildLogicalReplicationRows converts a pg_logical_replication metric into
+// subscription, publication and WAL receiver rows sharing the metric's
+// timestamp. A primary reports an empty WAL receiver, which is not stored.
+func buildLogicalReplicationRows(collectorID uuid.UUID, req *models.LogicalReplicationMetricsRequest) ([]*models.LogicalSubscription, []*models.Publication, []*models.WalReceiver) {
+	ts := time.Now()
+	if parsed, err := time.Parse(time.RFC3339, req.Timestamp); err == nil {
+		ts = parsed
+	}
+
+	subs := make([]*models.LogicalSubscription, 0, len(req.LogicalSubscriptions))
+	for _, sub := range req.LogicalSubscriptions {
+		if sub.SubName == "" {
+			continue
+		}
+		subs = append(subs, &models.LogicalSubscription{
+			Time:                  ts,
+			CollectorID:           collectorID,
+			DatabaseName:          sub.Database,
+			SubName:               sub.SubName,
+			SubState:              sub.SubState,
+			SubRecvLsn:            sub.ReceivedLsn,
+			SubLatestEndLsn:       sub.LatestEndLsn,
+			SubLastMsgReceiptTime: parsePostgresTime(sub.LastMsgReceiptTime),
+			SubLastMsgSendTime:    parsePostgresTime(sub.LastMsgSendTime),
+			SubWorkerPid:          sub.WorkerPid,
+			SubWorkerCount:        sub.WorkerCount,
+		})
+	}
+
+	pubs := make([]*models.Publication, 0, len(req.Publications))
+	for _, pub := range req.Publications {
+		if pub.PubName == "" {
+			continue
+		}
+		pubs = append(pubs, &models.Publication{
+			Time:         ts,
+			CollectorID:  collectorID,
+			DatabaseName: pub.Database,
+			PubName:      pub.PubName,
+			PubOwner:     pub.PubOwner,
+			PubAllTables: pub.PubAllTables,
+			PubInsert:    pub.PubInsert,
+			PubUpdate:    pub.PubUpdate,
+			PubDelete:    pub.PubDelete,
+			PubTruncate:  pub.PubTruncate,
+		})
+	}
+
+	var receivers []*models.WalReceiver
+	if w := req.WalReceiver; w != nil && w.Status != "" {
+		w.CollectorID = collectorID
+		w.Time = ts
+		receivers = append(receivers, w)
+	}
+
+	return subs, pubs, receivers
+}
+
+// postgresTimeLayouts are the text formats of a timestamptz
+var postgresTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999-07",
+	"2006-01-02 15:04:05.999999-07:00",
+}
+
+// parsePostgresTime parses a timestamptz in text format, nil when empty or
+// unparsable
+func parsePostgresTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	for _, layout := range postgresTimeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return &parsed
+		}
+	}
+	return nil
+}
+
 // ============================================================================
 // STREAMING REPLICATION ENDPOINTS
 // ============================================================================
@@ -111,6 +348,43 @@ func (s *Server) handleGetReplicationSlots(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// @Summary Get Replication Slot Risk
+// @Description Assess replication slots: inactive or stuck slots, WAL retained per slot from LSN deltas, logical slots holding catalog_xmin back, the slot to drop and when retained WAL fills the disk
+// @Tags Replication
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param at query string false "Point in time (RFC3339)" default(now)
+// @Success 200 {object} models.SlotRiskReport
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/replication-slots/risk [get]
+func (s *Server) handleGetSlotRisk(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	at := time.Now()
+	if v := c.Query("at"); v != "" {
+		if at, err = time.Parse(time.RFC3339, v); err != nil {
+			errResp := apperrors.BadRequest("Invalid at timestamp", "expected RFC3339")
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+	}
+
+	service := slot_risk.NewService(storage.NewSlotRiskRepository(s.postgres.GetDB()), s.logger)
+	report, err := service.GetReport(c.Request.Context(), collectorID, at)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
 // ============================================================================
 // LOGICAL REPLICATION ENDPOINTS
 // ============================================================================
@@ -226,7 +500,7 @@ func (s *Server) handleGetPublications(c *gin.Context) {
 }
 
 // @Summary Get Replication Topology
-// @Description Get cascading replication topology showing primary -> standby -> standby chains
+// @Description Get a collector's place in the replication topology: its role, the collector it replicates from and the nodes replicating from it, resolved across the tenant's collectors
 // @Tags Logical Replication
 // @Produce json
 // @Security Bearer
@@ -245,6 +519,26 @@ func (s *Server) handleGetReplicationTopology(c *gin.Context) {
 	}
 
 	ctx := c.Request.Context()
+	tenantID, err := s.postgres.GetTenantIDByCollectorID(ctx, collectorIDStr)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	// Collectors outside a tenant have no fleet to resolve peers against
+	if tenantID != nil {
+		service := replication_topology.NewService(storage.NewReplicationTopologyRepository(s.postgres.GetDB()), s.logger)
+		fleet, err := service.GetFleetTopology(ctx, *tenantID, time.Now())
+		if err != nil {
+			c.JSON(err.(*apperrors.AppError).StatusCode, err)
+			return
+		}
+		if topology := replication_topology.NodeTopology(fleet, collectorID); topology != nil {
+			c.JSON(http.StatusOK, gin.H{"topology": topology})
+			return
+		}
+	}
+
 	topology, err := s.postgres.GetReplicationTopology(ctx, collectorID)
 	if err != nil {
 		c.JSON(err.(*apperrors.AppError).StatusCode, err)
@@ -253,3 +547,52 @@ func (s *Server) handleGetReplicationTopology(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"topology": topology})
 }
+
+// @Summary Get Fleet Replication Topology
+// @Description Get the replication graph of all collectors of a tenant: primaries, cascading standbys and logical subscribers with sync state and lag, as JSON or Graphviz DOT
+// @Tags Logical Replication
+// @Produce json,text/vnd.graphviz
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param format query string false "json or dot" default(json)
+// @Param at query string false "Point in time (RFC3339)" default(now)
+// @Success 200 {object} models.FleetTopology
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/replication-topology [get]
+func (s *Server) handleGetFleetTopology(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "dot" {
+		errResp := apperrors.BadRequest("Invalid format", "expected json or dot")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	at := time.Now()
+	if v := c.Query("at"); v != "" {
+		var err error
+		if at, err = time.Parse(time.RFC3339, v); err != nil {
+			errResp := apperrors.BadRequest("Invalid at timestamp", "expected RFC3339")
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+	}
+
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+
+	service := replication_topology.NewService(storage.NewReplicationTopologyRepository(s.postgres.GetDB()), s.logger)
+	topology, err := service.GetFleetTopology(c.Request.Context(), tenantID, at)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	if format == "dot" {
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(replication_topology.RenderDOT(topology)))
+		return
+	}
+	c.JSON(http.StatusOK, topology)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// TestBuildReplicationRows shares the metric timestamp and parses text timestamps
+func TestBuildReplicationRows(t *testing.T) {
+	collectorID := uuid.New()
+	req := &models.ReplicationMetricsRequest{
+		Timestamp: "2026-03-01T12:00:00Z",
+		ReplicationStatus: []models.CollectedReplicationStatus{
+			{ServerPID: 4242, ApplicationName: "standby1", ClientAddr: "10.0.0.2", SyncState: "sync", BackendStart: "2026-03-01 11:00:00.123456+00"},
+			{ServerPID: 4343, ApplicationName: "standby2", BackendStart: "not a time"},
+		},
+		ReplicationSlots: []*models.ReplicationSlot{
+			{SlotName: "standby1_slot", SlotType: "physical", Active: true},
+			{SlotName: ""},
+			nil,
+		},
+	}
+
+	rows := buildReplicationRows(collectorID, req)
+	status, slots := rows.status, rows.slots
+	sampledAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	require.Len(t, status, 2)
+	assert.Equal(t, collectorID, status[0].CollectorID)
+	assert.Equal(t, sampledAt, status[0].Timestamp)
+	require.NotNil(t, status[0].BackendStart)
+	assert.True(t, status[0].BackendStart.Equal(time.Date(2026, 3, 1, 11, 0, 0, 123456000, time.UTC)))
+	assert.Nil(t, status[1].BackendStart)
+
+	require.Len(t, slots, 1)
+	assert.Equal(t, collectorID, slots[0].CollectorID)
+	assert.Equal(t, sampledAt, slots[0].Timestamp)
+	assert.Nil(t, rows.position)
+	assert.Nil(t, rows.node)
+}
+
+// TestBuildReplicationRows_WalPosition converts the WAL directory size and
+// max_slot_wal_keep_size to bytes, treating -1 as unlimited
+func TestBuildReplicationRows_WalPosition(t *testing.T) {
+	collectorID := uuid.New()
+	req := &models.ReplicationMetricsRequest{
+		Timestamp:     "2026-03-01T12:00:00Z",
+		CurrentWalLsn: "1/A0000000",
+		WalStatus:     &models.CollectedWalStatus{WalDirectorySizeMb: 2048},
+		Settings:      map[string]string{"max_slot_wal_keep_size": "10240"},
+	}
+
+	position := buildReplicationRows(collectorID, req).position
+	require.NotNil(t, position)
+	assert.Equal(t, collectorID, position.CollectorID)
+	assert.Equal(t, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), position.Time)
+	assert.Equal(t, "1/A0000000", position.CurrentWalLsn)
+	require.NotNil(t, position.WalDirectoryBytes)
+	assert.Equal(t, int64(2048)<<20, *position.WalDirectoryBytes)
+	require.NotNil(t, position.MaxSlotWalKeepSizeBytes)
+	assert.Equal(t, int64(10240)<<20, *position.MaxSlotWalKeepSizeBytes)
+
+	assert.Nil(t, buildReplicationRows(collectorID, req).node)
+
+	req.Settings["max_slot_wal_keep_size"] = "-1"
+	position = buildReplicationRows(collectorID, req).position
+	require.NotNil(t, position)
+	assert.Nil(t, position.MaxSlotWalKeepSizeBytes)
+}
+
+// TestBuildReplicationRows_NodeState shares the metric timestamp
+func TestBuildReplicationRows_NodeState(t *testing.T) {
+	collectorID := uuid.New()
+	req := &models.ReplicationMetricsRequest{
+		Timestamp: "2026-03-01T12:00:00Z",
+		Node:      &models.CollectedNodeState{SystemIdentifier: "7312345678901234567", TimelineID: 3, InRecovery: true, ServerVersionNum: 160002},
+	}
+
+	node := buildReplicationRows(collectorID, req).node
+	require.NotNil(t, node)
+	assert.Equal(t, collectorID, node.CollectorID)
+	assert.Equal(t, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), node.Time)
+	assert.Equal(t, "7312345678901234567", node.SystemIdentifier)
+	assert.Equal(t, int64(3), node.TimelineID)
+	assert.True(t, node.InRecovery)
+	assert.Equal(t, 160002, node.ServerVersionNum)
+}
+
+// TestBuildLogicalReplicationRows keeps the WAL receiver of standbys only
+func TestBuildLogicalReplicationRows(t *testing.T) {
+	collectorID := uuid.New()
+	req := &models.LogicalReplicationMetricsRequest{
+		Timestamp: "2026-03-01T12:00:00Z",
+		LogicalSubscriptions: []models.CollectedSubscription{
+			{Database: "analytics", SubName: "sub_orders", SubState: "ready", LastMsgReceiptTime: "2026-03-01 11:59:59+00"},
+		},
+		Publications: []models.CollectedPublication{{Database: "orders", PubName: "pub_orders", PubInsert: true}},
+		WalReceiver:  &models.WalReceiver{Status: "streaming", SenderHost: "10.0.0.1", SenderPort: 5432},
+	}
+
+	subs, pubs, receivers := buildLogicalReplicationRows(collectorID, req)
+	require.Len(t, subs, 1)
+	assert.Equal(t, "analytics", subs[0].DatabaseName)
+	require.NotNil(t, subs[0].SubLastMsgReceiptTime)
+	require.Len(t, pubs, 1)
+	assert.True(t, pubs[0].PubInsert)
+	require.Len(t, receivers, 1)
+	assert.Equal(t, collectorID, receivers[0].CollectorID)
+	assert.Equal(t, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), receivers[0].Time)
+
+	req.WalReceiver = &models.WalReceiver{}
+	_, _, receivers = buildLogicalReplicationRows(collectorID, req)
+	assert.Empty(t, receivers, "a primary reports an empty WAL receiver")
+}
+
+// TestGetFleetTopology_InvalidRequest rejects bad formats and timestamps
+func TestGetFleetTopology_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{logger: zap.NewNop()}
+	router := gin.New()
+	router.GET("/api/v1/tenants/:id/replication-topology", server.handleGetFleetTopology)
+
+	base := "/api/v1/tenants/" + uuid.New().String() + "/replication-topology"
+	for path, message := range map[string]string{
+		base + "?format=svg":   "Invalid format",
+		base + "?at=yesterday": "Invalid at timestamp",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Contains(t, w.Body.String(), message, path)
+	}
+}
+
+// TestGetFleetTopology_TenantRole lets only tenant members see the topology
+func TestGetFleetTopology_TenantRole(t *testing.T) {
+	testTenantRoutes(t, func(s *Server, tenants *gin.RouterGroup) {
+		tenants.GET("/:id/replication-topology", s.handleGetFleetTopology)
+	}, []tenantRouteCase{
+		{"GET", "/replication-topology", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM collectors")).
+				WithArgs(tenantID).
+				WillReturnRows(emptyRows())
+		}},
+	})
+}
@@ -0,0 +1,329 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/retention"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ============================================================================
+// DATA RETENTION ENDPOINTS
+// ============================================================================
+
+// @Summary List retention policies
+// @Description List how long a tenant keeps each class of raw data; classes without a policy are kept indefinitely
+// @Tags Tenants
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Success 200 {array} models.RetentionPolicy
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/retention-policies [get]
+func (s *Server) handleListRetentionPolicies(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+
+	policies, err := s.postgres.GetRetentionPolicies(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+	if policies == nil {
+		policies = []*models.RetentionPolicy{}
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+// @Summary Set retention policy
+// @Description Set how long a tenant keeps a class of raw data (raw_logs, query_stats, host_metrics, explain_plans) and whether expired rows are rolled up and archived (admin only)
+// @Tags Tenants
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param class path string true "Data class"
+// @Param policy body models.RetentionPolicyRequest true "Retention policy"
+// @Success 200 {object} models.RetentionPolicy
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/retention-policies/{class} [put]
+func (s *Server) handleUpdateRetentionPolicy(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.RetentionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errResp := apperrors.BadRequest("Invalid request body", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	archiveConfigured := s.retention != nil && s.retention.ArchiveConfigured()
+	policy, errResp := buildRetentionPolicy(tenantID, c.Param("class"), &req, archiveConfigured)
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	if err := s.postgres.UpsertRetentionPolicy(c.Request.Context(), policy); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// @Summary Delete retention policy
+// @Description Remove a tenant's retention policy so that the data class is kept indefinitely (admin only)
+// @Tags Tenants
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param class path string true "Data class"
+// @Success 204
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/retention-policies/{class} [delete]
+func (s *Server) handleDeleteRetentionPolicy(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+
+	if err := s.postgres.DeleteRetentionPolicy(c.Request.Context(), tenantID, c.Param("class")); err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Apply retention policies
+// @Description Roll up, archive and delete a tenant's expired data now instead of waiting for the scheduled run (admin only)
+// @Tags Tenants
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Success 200 {object} models.RetentionRunResponse
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Failure 503 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/retention/run [post]
+func (s *Server) handleRunRetention(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
+		return
+	}
+
+	if s.retention == nil {
+		errResp := apperrors.ServiceUnavailable("Retention is not available", "retention service is not configured")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	resp, err := s.retention.ApplyTenant(c.Request.Context(), tenantID)
+	if err != nil {
+		if appErr, isAppErr := err.(*apperrors.AppError); isAppErr {
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		if errors.Is(err, retention.ErrArchiveNotConfigured) {
+			errResp := apperrors.BadRequest("Archive not configured", err.Error())
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+		errResp := apperrors.InternalServerError("Failed to apply retention policies", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	s.logger.Info("Retention policies applied",
+		zap.String("tenant_id", tenantID.String()),
+		zap.Int64("rows_deleted", resp.RowsDeleted),
+		zap.Int64("rollups_deleted", resp.RollupsDeleted),
+		zap.Bool("complete", resp.Complete))
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary List retention runs
+// @Description List the most recent windows of data removed by a tenant's retention policies
+// @Tags Tenants
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param limit query int false "Maximum number of runs (default 100, max 1000)"
+// @Success 200 {array} models.RetentionRun
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/retention/runs [get]
+func (s *Server) handleListRetentionRuns(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+
+	limit := 100
+	if l := c.Query("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 1 || parsed > 1000 {
+			errResp := apperrors.BadRequest("Invalid limit", "limit must be between 1 and 1000")
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := s.postgres.ListRetentionRuns(c.Request.Context(), tenantID, limit)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+	if runs == nil {
+		runs = []*models.RetentionRun{}
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// @Summary Get hourly rollups
+// @Description Get the hourly summaries kept for a tenant's expired data
+// @Tags Tenants
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param data_class query string true "Data class"
+// @Param collector_id query string false "Collector ID"
+// @Param from query string false "Start time, RFC 3339 (default 7 days before to)"
+// @Param to query string false "End time, RFC 3339 (default now)"
+// @Success 200 {array} models.DataRollup
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 500 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/retention/rollups [get]
+func (s *Server) handleGetDataRollups(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+
+	query, errResp := parseRollupQuery(c.Query("data_class"), c.Query("collector_id"), c.Query("from"), c.Query("to"), time.Now())
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	rollups, err := s.postgres.GetDataRollups(c.Request.Context(), tenantID, query.dataClass, query.collectorID, query.from, query.to)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+	if rollups == nil {
+		rollups = []*models.DataRollup{}
+	}
+
+	c.JSON(http.StatusOK, rollups)
+}
+
+// buildRetentionPolicy validates a retention policy request
+func buildRetentionPolicy(tenantID uuid.UUID, dataClass string, req *models.RetentionPolicyRequest, archiveConfigured bool) (*models.RetentionPolicy, *apperrors.AppError) {
+	if !models.ValidRetentionClass(dataClass) {
+		return nil, apperrors.BadRequest("Invalid data class",
+			"data class must be one of: "+strings.Join(models.RetentionClasses, ", "))
+	}
+	if req.RetentionDays < 1 {
+		return nil, apperrors.BadRequest("Invalid retention_days", "retention_days must be at least 1")
+	}
+	if req.Archive && !archiveConfigured {
+		return nil, apperrors.BadRequest("Archive not configured",
+			"set RETENTION_ARCHIVE_DIR or RETENTION_ARCHIVE_S3_ENDPOINT to archive expired data")
+	}
+
+	policy := &models.RetentionPolicy{
+		TenantID:      tenantID,
+		DataClass:     dataClass,
+		RetentionDays: req.RetentionDays,
+		Rollup:        true,
+		Archive:       req.Archive,
+	}
+	if req.Rollup != nil {
+		policy.Rollup = *req.Rollup
+	}
+	if req.RollupRetentionDays != nil {
+		if !policy.Rollup {
+			return nil, apperrors.BadRequest("Invalid rollup_retention_days", "rollup_retention_days requires rollup")
+		}
+		if *req.RollupRetentionDays < req.RetentionDays {
+			return nil, apperrors.BadRequest("Invalid rollup_retention_days",
+				"rollup_retention_days must not be shorter than retention_days")
+		}
+		policy.RollupRetentionDays = req.RollupRetentionDays
+	}
+
+	return policy, nil
+}
+
+// rollupQuery is a validated hourly rollup query
+type rollupQuery struct {
+	dataClass   string
+	collectorID *uuid.UUID
+	from        time.Time
+	to          time.Time
+}
+
+// parseRollupQuery validates the query parameters of the rollups endpoint
+func parseRollupQuery(dataClass, collectorID, from, to string, now time.Time) (*rollupQuery, *apperrors.AppError) {
+	query := &rollupQuery{dataClass: dataClass, to: now}
+
+	if !models.ValidRetentionClass(dataClass) {
+		return nil, apperrors.BadRequest("Invalid data_class",
+			"data_class must be one of: "+strings.Join(models.RetentionClasses, ", "))
+	}
+	if collectorID != "" {
+		id, err := uuid.Parse(collectorID)
+		if err != nil {
+			return nil, apperrors.BadRequest("Invalid collector_id", err.Error())
+		}
+		query.collectorID = &id
+	}
+	if to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, apperrors.BadRequest("Invalid to", err.Error())
+		}
+		query.to = t
+	}
+	query.from = query.to.Add(-7 * 24 * time.Hour)
+	if from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, apperrors.BadRequest("Invalid from", err.Error())
+		}
+		query.from = t
+	}
+	if !query.from.Before(query.to) {
+		return nil, apperrors.BadRequest("Invalid time range", "from must be before to")
+	}
+
+	return query, nil
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/retention"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// TestBuildRetentionPolicy validates retention policy requests
+func TestBuildRetentionPolicy(t *testing.T) {
+	tenantID := uuid.New()
+	days := func(d int) *int { return &d }
+	noRollup := false
+
+	policy, errResp := buildRetentionPolicy(tenantID, models.RetentionClassRawLogs,
+		&models.RetentionPolicyRequest{RetentionDays: 14, RollupRetentionDays: days(365)}, false)
+	require.Nil(t, errResp)
+	assert.Equal(t, tenantID, policy.TenantID)
+	assert.True(t, policy.Rollup, "rollup defaults to true")
+	assert.False(t, policy.Archive)
+	assert.Equal(t, 365, *policy.RollupRetentionDays)
+
+	policy, errResp = buildRetentionPolicy(tenantID, models.RetentionClassHostMetrics,
+		&models.RetentionPolicyRequest{RetentionDays: 30, Rollup: &noRollup, Archive: true}, true)
+	require.Nil(t, errResp)
+	assert.False(t, policy.Rollup)
+	assert.True(t, policy.Archive)
+
+	for name, tc := range map[string]struct {
+		class   string
+		req     models.RetentionPolicyRequest
+		archive bool
+	}{
+		"Invalid data class":            {class: "metrics", req: models.RetentionPolicyRequest{RetentionDays: 7}},
+		"Invalid retention_days":        {class: models.RetentionClassQueryStats, req: models.RetentionPolicyRequest{}},
+		"Archive not configured":        {class: models.RetentionClassQueryStats, req: models.RetentionPolicyRequest{RetentionDays: 7, Archive: true}},
+		"Invalid rollup_retention_days": {class: models.RetentionClassQueryStats, req: models.RetentionPolicyRequest{RetentionDays: 30, RollupRetentionDays: days(7)}},
+	} {
+		_, errResp := buildRetentionPolicy(tenantID, tc.class, &tc.req, tc.archive)
+		require.NotNil(t, errResp, name)
+		assert.Equal(t, name, errResp.Message)
+	}
+
+	_, errResp = buildRetentionPolicy(tenantID, models.RetentionClassQueryStats,
+		&models.RetentionPolicyRequest{RetentionDays: 7, Rollup: &noRollup, RollupRetentionDays: days(30)}, false)
+	require.NotNil(t, errResp)
+	assert.Contains(t, errResp.Details, "requires rollup")
+}
+
+// TestParseRollupQuery validates the rollups endpoint parameters
+func TestParseRollupQuery(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	collectorID := uuid.New()
+
+	query, errResp := parseRollupQuery(models.RetentionClassRawLogs, "", "", "", now)
+	require.Nil(t, errResp)
+	assert.Equal(t, now, query.to)
+	assert.Equal(t, now.Add(-7*24*time.Hour), query.from)
+	assert.Nil(t, query.collectorID)
+
+	query, errResp = parseRollupQuery(models.RetentionClassQueryStats, collectorID.String(),
+		"2026-03-01T00:00:00Z", "2026-03-02T00:00:00Z", now)
+	require.Nil(t, errResp)
+	assert.Equal(t, collectorID, *query.collectorID)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), query.from)
+
+	for name, args := range map[string][4]string{
+		"Invalid data_class":   {"", "", "", ""},
+		"Invalid collector_id": {models.RetentionClassRawLogs, "abc", "", ""},
+		"Invalid from":         {models.RetentionClassRawLogs, "", "yesterday", ""},
+		"Invalid time range":   {models.RetentionClassRawLogs, "", "2026-03-02T00:00:00Z", "2026-03-01T00:00:00Z"},
+	} {
+		_, errResp := parseRollupQuery(args[0], args[1], args[2], args[3], now)
+		require.NotNil(t, errResp, name)
+		assert.Equal(t, name, errResp.Message)
+	}
+}
+
+// TestRetention_TenantRole lets tenant members read policies, runs and
+// rollups and only tenant admins change policies or run retention
+func TestRetention_TenantRole(t *testing.T) {
+	testTenantRoutes(t, func(s *Server, tenants *gin.RouterGroup) {
+		s.retention = retention.NewService(s.postgres, nil, s.logger)
+		tenants.GET("/:id/retention-policies", s.handleListRetentionPolicies)
+		tenants.PUT("/:id/retention-policies/:class", s.handleUpdateRetentionPolicy)
+		tenants.DELETE("/:id/retention-policies/:class", s.handleDeleteRetentionPolicy)
+		tenants.POST("/:id/retention/run", s.handleRunRetention)
+		tenants.GET("/:id/retention/runs", s.handleListRetentionRuns)
+		tenants.GET("/:id/retention/rollups", s.handleGetDataRollups)
+	}, []tenantRouteCase{
+		{"GET", "/retention-policies", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM retention_policies")).
+				WithArgs(tenantID).
+				WillReturnRows(emptyRows())
+		}},
+		{"PUT", "/retention-policies/raw_logs", `{"retention_days":30}`, true, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO retention_policies")).
+				WithArgs(tenantID, models.RetentionClassRawLogs, 30, true, false, nil).
+				WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+		}},
+		{"DELETE", "/retention-policies/raw_logs", "", true, http.StatusNoContent, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectExec(regexp.QuoteMeta("DELETE FROM retention_policies")).
+				WithArgs(tenantID, models.RetentionClassRawLogs).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}},
+		{"POST", "/retention/run", "", true, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM retention_policies")).
+				WithArgs(tenantID).
+				WillReturnRows(emptyRows())
+		}},
+		{"GET", "/retention/runs", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM retention_runs")).
+				WithArgs(tenantID, 100).
+				WillReturnRows(emptyRows())
+		}},
+		{"GET", "/retention/rollups?data_class=raw_logs", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("FROM data_rollups_hourly")).
+				WithArgs(tenantID, models.RetentionClassRawLogs, sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnRows(emptyRows())
+		}},
+	})
+}
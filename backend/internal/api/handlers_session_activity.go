@@ -0,0 +1,220 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/session_activity"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ============================================================================
+// ACTIVE SESSION HISTORY ENDPOINTS
+// ============================================================================
+
+// defaultActivityWindow is the window used when no from/to is given
+const defaultActivityWindow = time.Hour
+
+// ingestActivitySamples stores a pg_activity_samples metric and returns the
+// number of samples inserted
+func (s *Server) ingestActivitySamples(c *gin.Context, collectorID string, metric interface{}, redactor *query_performance.Redactor) int {
+	metricJSON, _ := json.Marshal(metric)
+
+	var req models.ActivitySamplesRequest
+	if err := json.Unmarshal(metricJSON, &req); err != nil {
+		s.logger.Error("Failed to unmarshal pg_activity_samples metric", zap.Error(err))
+		return 0
+	}
+
+	samples := session_activity.BuildSamples(metricsCollectorUUID(collectorID), &req, redactor)
+	if err := s.postgres.InsertActivitySamples(c.Request.Context(), samples); err != nil {
+		s.logger.Error("Failed to insert activity samples",
+			zap.Error(err),
+			zap.String("collector_id", collectorID),
+			zap.Int("samples", len(samples)),
+		)
+		return 0
+	}
+
+	return len(samples)
+}
+
+// parseActivityFilter reads the collector, window, database and limit shared
+// by the active session history endpoints
+func parseActivityFilter(c *gin.Context) (models.ActivityFilter, *apperrors.AppError) {
+	filter := models.ActivityFilter{Limit: 10}
+
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return filter, apperrors.BadRequest("Invalid collector ID", err.Error())
+	}
+	filter.CollectorID = collectorID
+
+	filter.To = time.Now()
+	if to := c.Query("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return filter, apperrors.BadRequest("Invalid to timestamp", "expected RFC3339")
+		}
+	}
+	filter.From = filter.To.Add(-defaultActivityWindow)
+	if from := c.Query("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return filter, apperrors.BadRequest("Invalid from timestamp", "expected RFC3339")
+		}
+	}
+	if !filter.From.Before(filter.To) {
+		return filter, apperrors.BadRequest("Invalid time window", "from must be before to")
+	}
+
+	if database := c.Query("database"); database != "" {
+		filter.Database = &database
+	}
+
+	if l, err := strconv.Atoi(c.DefaultQuery("limit", "10")); err == nil && l > 0 && l <= 100 {
+		filter.Limit = l
+	}
+
+	return filter, nil
+}
+
+// @Summary Get Top Wait Events
+// @Description Get wait events ranked by DB time from active session history
+// @Tags Activity
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param from query string false "Window start (RFC3339)" default(1 hour before to)
+// @Param to query string false "Window end (RFC3339)" default(now)
+// @Param database query string false "Database name"
+// @Param limit query int false "Result limit" default(10)
+// @Success 200 {object} models.TopWaitEventsResponse
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/activity/wait-events [get]
+func (s *Server) handleGetActivityWaitEvents(c *gin.Context) {
+	filter, errResp := parseActivityFilter(c)
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	service := session_activity.NewService(s.postgres, s.logger)
+	resp, err := service.GetTopWaitEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary Get Top Sessions
+// @Description Get backends ranked by DB time from active session history
+// @Tags Activity
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param from query string false "Window start (RFC3339)" default(1 hour before to)
+// @Param to query string false "Window end (RFC3339)" default(now)
+// @Param database query string false "Database name"
+// @Param limit query int false "Result limit" default(10)
+// @Success 200 {object} models.TopSessionsResponse
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/activity/sessions [get]
+func (s *Server) handleGetActivitySessions(c *gin.Context) {
+	filter, errResp := parseActivityFilter(c)
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	service := session_activity.NewService(s.postgres, s.logger)
+	resp, err := service.GetTopSessions(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary Get Top Queries By DB Time
+// @Description Get query fingerprints ranked by DB time from active session history
+// @Tags Activity
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param from query string false "Window start (RFC3339)" default(1 hour before to)
+// @Param to query string false "Window end (RFC3339)" default(now)
+// @Param database query string false "Database name"
+// @Param limit query int false "Result limit" default(10)
+// @Success 200 {object} models.TopActivityQueriesResponse
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/activity/queries [get]
+func (s *Server) handleGetActivityQueries(c *gin.Context) {
+	filter, errResp := parseActivityFilter(c)
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	service := session_activity.NewService(s.postgres, s.logger)
+	resp, err := service.GetTopQueries(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary Get Fingerprint Wait Profile
+// @Description Get the wait event breakdown and timeline of a query fingerprint
+// @Tags Activity
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param fingerprint_hash path string true "Query fingerprint"
+// @Param from query string false "Window start (RFC3339)" default(1 hour before to)
+// @Param to query string false "Window end (RFC3339)" default(now)
+// @Param database query string false "Database name"
+// @Param limit query int false "Wait event limit" default(10)
+// @Success 200 {object} models.FingerprintWaitProfile
+// @Failure 400 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/activity/queries/{fingerprint_hash}/waits [get]
+func (s *Server) handleGetFingerprintWaitProfile(c *gin.Context) {
+	filter, errResp := parseActivityFilter(c)
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	fingerprint := c.Param("fingerprint_hash")
+	if fingerprint == "" {
+		errResp := apperrors.BadRequest("Fingerprint required", "")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	service := session_activity.NewService(s.postgres, s.logger)
+	profile, err := service.GetFingerprintWaitProfile(c.Request.Context(), filter, fingerprint)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	if profile.TotalSamples == 0 {
+		errResp := apperrors.NotFound("No activity samples found for this fingerprint", fingerprint)
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
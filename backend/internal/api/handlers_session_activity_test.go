@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func newActivityTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	server := &Server{logger: zap.NewNop()}
+
+	router := gin.New()
+	router.GET("/api/v1/collectors/:id/activity/wait-events", server.handleGetActivityWaitEvents)
+	return router
+}
+
+// TestGetActivityWaitEvents_InvalidCollectorID returns bad request for invalid ID
+func TestGetActivityWaitEvents_InvalidCollectorID(t *testing.T) {
+	router := newActivityTestRouter()
+
+	req := httptest.NewRequest("GET", "/api/v1/collectors/not-a-uuid/activity/wait-events", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid collector ID")
+}
+
+// TestGetActivityWaitEvents_InvalidWindow rejects malformed and inverted windows
+func TestGetActivityWaitEvents_InvalidWindow(t *testing.T) {
+	router := newActivityTestRouter()
+	base := "/api/v1/collectors/" + uuid.New().String() + "/activity/wait-events"
+
+	for _, query := range []string{
+		"?from=yesterday",
+		"?to=2026-03-01",
+		"?from=2026-03-01T12:00:00Z&to=2026-03-01T11:00:00Z",
+	} {
+		req := httptest.NewRequest("GET", base+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+// TestMetricsCollectorUUID derives a stable UUID for non-UUID collector IDs
+func TestMetricsCollectorUUID(t *testing.T) {
+	id := uuid.New()
+	assert.Equal(t, id, metricsCollectorUUID(id.String()))
+	assert.Equal(t, metricsCollectorUUID("col_demo_001"), metricsCollectorUUID("col_demo_001"))
+	assert.NotEqual(t, uuid.Nil, metricsCollectorUUID("col_demo_001"))
+}
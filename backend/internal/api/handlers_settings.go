@@ -0,0 +1,242 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/config_advisor"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ============================================================================
+// SERVER SETTINGS ENDPOINTS
+// ============================================================================
+
+// ingestSettings stores a pg_settings snapshot and returns the number of
+// settings stored
+func (s *Server) ingestSettings(c *gin.Context, collectorID string, metric interface{}) int {
+	metricJSON, _ := json.Marshal(metric)
+
+	var req models.PgSettingsRequest
+	if err := json.Unmarshal(metricJSON, &req); err != nil {
+		s.logger.Error("Failed to unmarshal pg_settings metric", zap.Error(err))
+		return 0
+	}
+
+	ts := time.Now()
+	if parsed, err := time.Parse(time.RFC3339, req.Timestamp); err == nil {
+		ts = parsed
+	}
+
+	settings := config_advisor.BuildSettings(req.Settings)
+	service := config_advisor.NewService(s.postgres, s.logger)
+	if _, err := service.Ingest(c.Request.Context(), metricsCollectorUUID(collectorID), ts, settings); err != nil {
+		return 0
+	}
+	return len(settings)
+}
+
+// @Summary Get server settings
+// @Description Get the latest pg_settings snapshot of a collector's instance, optionally only the settings changed from their defaults
+// @Tags Configuration
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param non_default query bool false "Only settings whose source is not the built-in default"
+// @Success 200 {object} models.CollectorSettings
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/settings [get]
+func (s *Server) handleGetSettings(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	settings, err := s.postgres.GetCurrentSettings(c.Request.Context(), collectorID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	if c.Query("non_default") == "true" {
+		changed := []*models.PgSetting{}
+		for _, setting := range settings.Settings {
+			if setting.Source != "" && setting.Source != "default" {
+				changed = append(changed, setting)
+			}
+		}
+		settings.Settings = changed
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// @Summary Get server settings history
+// @Description Get the changes of a collector's settings between pg_settings snapshots, most recent first
+// @Tags Configuration
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param name query string false "Setting name"
+// @Param from query string false "Start time (RFC3339)" default(30 days ago)
+// @Param to query string false "End time (RFC3339)" default(now)
+// @Param limit query int false "Result limit" default(100)
+// @Success 200 {array} models.PgSettingChange
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/settings/history [get]
+func (s *Server) handleGetSettingsHistory(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	filter, errResp := parseSettingChangeFilter(c)
+	if errResp != nil {
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+	filter.CollectorID = collectorID
+
+	changes, err := s.postgres.ListSettingChanges(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// @Summary Get server settings advice
+// @Description Review a collector's settings against values derived from its host's RAM, CPU cores and disk (shared_buffers, effective_cache_size, work_mem, maintenance_work_mem, max_wal_size, parallelism) and storage kind (random_page_cost, effective_io_concurrency), with why each setting matters and whether changing it needs a restart
+// @Tags Configuration
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param storage query string false "ssd or hdd" default(ssd)
+// @Success 200 {object} models.SettingsAdvice
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/settings/advice [get]
+func (s *Server) handleGetSettingsAdvice(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	storageKind := c.DefaultQuery("storage", config_advisor.StorageSSD)
+	if storageKind != config_advisor.StorageSSD && storageKind != config_advisor.StorageHDD {
+		errResp := apperrors.BadRequest("Invalid storage", "expected ssd or hdd")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	service := config_advisor.NewService(s.postgres, s.logger)
+	advice, err := service.Advise(c.Request.Context(), collectorID, storageKind, time.Now())
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, advice)
+}
+
+// @Summary Get cluster settings drift
+// @Description Compare a collector's settings with the other instances of its replication cluster. Settings a hot standby needs at least its primary's value of are critical.
+// @Tags Configuration
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Success 200 {object} models.SettingsDriftReport
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/settings/drift [get]
+func (s *Server) handleGetClusterSettingsDrift(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	service := config_advisor.NewService(s.postgres, s.logger)
+	report, err := service.ClusterDrift(c.Request.Context(), collectorID, time.Now())
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Get collector group settings drift
+// @Description Compare the settings of the instances in one of a tenant's collector groups, e.g. an environment
+// @Tags Configuration
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Tenant ID"
+// @Param groupId path int true "Collector group ID"
+// @Success 200 {object} models.SettingsDriftReport
+// @Failure 400 {object} apperrors.AppError
+// @Failure 403 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/tenants/{id}/collector-groups/{groupId}/settings-drift [get]
+func (s *Server) handleGetGroupSettingsDrift(c *gin.Context) {
+	tenantID, ok := s.requireTenantRole(c, false)
+	if !ok {
+		return
+	}
+	groupID, ok := int64Param(c, "groupId", "Invalid collector group ID")
+	if !ok {
+		return
+	}
+
+	service := config_advisor.NewService(s.postgres, s.logger)
+	report, err := service.GroupDrift(c.Request.Context(), tenantID, groupID, time.Now())
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseSettingChangeFilter reads the name, from, to and limit query parameters
+func parseSettingChangeFilter(c *gin.Context) (*models.PgSettingChangeFilter, *apperrors.AppError) {
+	filter := &models.PgSettingChangeFilter{To: time.Now(), Limit: 100, Name: c.Query("name")}
+
+	var err error
+	if v := c.Query("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, apperrors.BadRequest("Invalid to timestamp", "expected RFC3339")
+		}
+	}
+	filter.From = filter.To.Add(-30 * 24 * time.Hour)
+	if v := c.Query("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return nil, apperrors.BadRequest("Invalid from timestamp", "expected RFC3339")
+		}
+	}
+	if !filter.From.Before(filter.To) {
+		return nil, apperrors.BadRequest("Invalid time range", "from must be before to")
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > 1000 {
+			return nil, apperrors.BadRequest("Invalid limit", "expected 1 to 1000")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
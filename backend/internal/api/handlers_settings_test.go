@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// TestSettings_InvalidRequest rejects bad parameters before querying
+func TestSettings_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{logger: zap.NewNop()}
+	router := gin.New()
+	router.GET("/api/v1/collectors/:id/settings", server.handleGetSettings)
+	router.GET("/api/v1/collectors/:id/settings/history", server.handleGetSettingsHistory)
+	router.GET("/api/v1/collectors/:id/settings/advice", server.handleGetSettingsAdvice)
+	router.GET("/api/v1/collectors/:id/settings/drift", server.handleGetClusterSettingsDrift)
+
+	collector := "/api/v1/collectors/" + uuid.New().String() + "/settings"
+	for path, message := range map[string]string{
+		"/api/v1/collectors/not-a-uuid/settings":                                 "Invalid collector ID",
+		"/api/v1/collectors/not-a-uuid/settings/drift":                           "Invalid collector ID",
+		collector + "/history?from=2026-03-02T00:00:00Z&to=2026-03-01T00:00:00Z": "Invalid time range",
+		collector + "/history?to=today":                                          "Invalid to timestamp",
+		collector + "/history?limit=5000":                                        "Invalid limit",
+		collector + "/advice?storage=nvme-array":                                 "Invalid storage",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Contains(t, w.Body.String(), message, path)
+	}
+}
+
+// TestGetGroupSettingsDrift_TenantRole lets only tenant members see drift
+func TestGetGroupSettingsDrift_TenantRole(t *testing.T) {
+	testTenantRoutes(t, func(s *Server, tenants *gin.RouterGroup) {
+		tenants.GET("/:id/collector-groups/:groupId/settings-drift", s.handleGetGroupSettingsDrift)
+	}, []tenantRouteCase{
+		{"GET", "/collector-groups/3/settings-drift", "", false, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM collector_groups")).
+				WithArgs(tenantID, int64(3)).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+			mock.ExpectQuery(regexp.QuoteMeta("FROM collector_group_members")).
+				WithArgs(int64(3)).
+				WillReturnRows(emptyRows())
+		}},
+	})
+}
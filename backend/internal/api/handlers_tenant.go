@@ -30,17 +30,8 @@ var slugRegex = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
 // @Router /api/v1/tenants [get]
 func (s *Server) handleGetTenants(c *gin.Context) {
 	// Get user_id from context (set by AuthMiddleware)
-	userIDInterface, exists := c.Get("user_id")
-	if !exists {
-		errResp := apperrors.Unauthorized("Authentication required", "no user_id in context")
-		c.JSON(errResp.StatusCode, errResp)
-		return
-	}
-
-	userID, ok := userIDInterface.(uuid.UUID)
+	userID, ok := s.requireUserID(c)
 	if !ok {
-		errResp := apperrors.InternalServerError("Invalid user context", "user_id type assertion failed")
-		c.JSON(errResp.StatusCode, errResp)
 		return
 	}
 
@@ -87,18 +78,9 @@ func (s *Server) handleGetTenants(c *gin.Context) {
 // @Failure 500 {object} apperrors.AppError
 // @Router /api/v1/tenants [post]
 func (s *Server) handleCreateTenant(c *gin.Context) {
-	// Get user_id from context
-	userIDInterface, exists := c.Get("user_id")
-	if !exists {
-		errResp := apperrors.Unauthorized("Authentication required", "no user_id in context")
-		c.JSON(errResp.StatusCode, errResp)
-		return
-	}
-
-	userID, ok := userIDInterface.(uuid.UUID)
+	// Get user_id from context (set by AuthMiddleware)
+	userID, ok := s.requireUserID(c)
 	if !ok {
-		errResp := apperrors.InternalServerError("Invalid user context", "user_id type assertion failed")
-		c.JSON(errResp.StatusCode, errResp)
 		return
 	}
 
@@ -145,7 +127,7 @@ func (s *Server) handleCreateTenant(c *gin.Context) {
 	if err != nil {
 		s.logger.Error("Failed to add user as admin to tenant",
 			zap.String("tenant_id", tenant.ID.String()),
-			zap.String("user_id", userID.String()),
+			zap.Int("user_id", userID),
 			zap.Error(err))
 		// Continue - tenant was created successfully
 	}
@@ -232,36 +214,14 @@ func (s *Server) handleGetTenantCollectors(c *gin.Context) {
 // @Failure 500 {object} apperrors.AppError
 // @Router /api/v1/tenants/{id}/collectors [post]
 func (s *Server) handleAssignCollectorToTenant(c *gin.Context) {
-	// Get tenant ID from path
-	tenantIDStr := c.Param("id")
-	tenantID, err := uuid.Parse(tenantIDStr)
-	if err != nil {
-		errResp := apperrors.BadRequest("Invalid tenant ID", err.Error())
-		c.JSON(errResp.StatusCode, errResp)
+	// Only admins of the tenant can assign collectors
+	tenantID, ok := s.requireTenantRole(c, true)
+	if !ok {
 		return
 	}
 
-	// Verify user is admin of this tenant
-	userIDInterface, _ := c.Get("user_id")
-	userID := userIDInterface.(uuid.UUID)
-
 	ctx := c.Request.Context()
 
-	role, err := s.postgres.GetUserRoleInTenant(ctx, tenantID, userID)
-	if err != nil {
-		errResp := apperrors.Forbidden("Access denied", "user is not a member of this tenant")
-		c.JSON(errResp.StatusCode, errResp)
-		return
-	}
-
-	// Only admins can assign collectors
-	if role != "admin" {
-		errResp := apperrors.Forbidden("Admin required",
-			"only tenant admins can assign collectors")
-		c.JSON(errResp.StatusCode, errResp)
-		return
-	}
-
 	// Bind request body
 	var req models.TenantCollectorAssignmentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -271,15 +231,73 @@ func (s *Server) handleAssignCollectorToTenant(c *gin.Context) {
 	}
 
 	// Assign collector to tenant
-	err = s.postgres.AssignCollectorToTenant(ctx, tenantID, req.CollectorID)
+	err := s.postgres.AssignCollectorToTenant(ctx, tenantID, req.CollectorID)
 	if err != nil {
 		c.JSON(err.(*apperrors.AppError).StatusCode, err)
 		return
 	}
 
+	// Members of the previous and the new tenant see different instances
+	s.refreshAllRealtimeAccess()
+
 	c.JSON(http.StatusOK, gin.H{
 		"message":      "Collector assigned successfully",
 		"tenant_id":    tenantID.String(),
 		"collector_id": req.CollectorID.String(),
 	})
 }
+
+// requireUserID reads the ID of the user authenticated by AuthMiddleware. On
+// failure the error response is written and ok is false.
+func (s *Server) requireUserID(c *gin.Context) (int, bool) {
+	if _, exists := c.Get("user_id"); !exists {
+		errResp := apperrors.Unauthorized("Authentication required", "no user_id in context")
+		c.JSON(errResp.StatusCode, errResp)
+		return 0, false
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		errResp := apperrors.InternalServerError("Invalid user context", "user_id type assertion failed")
+		c.JSON(errResp.StatusCode, errResp)
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// requireTenantRole parses the tenant ID path parameter and verifies that the
+// authenticated user belongs to the tenant, and is an admin when adminOnly is
+// set. On failure the error response is written and ok is false.
+func (s *Server) requireTenantRole(c *gin.Context, adminOnly bool) (uuid.UUID, bool) {
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid tenant ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return uuid.Nil, false
+	}
+
+	userID, ok := s.requireUserID(c)
+	if !ok {
+		return uuid.Nil, false
+	}
+
+	role, err := s.postgres.GetUserRoleInTenant(c.Request.Context(), tenantID, userID)
+	if err != nil {
+		errResp := apperrors.ToAppError(err)
+		if errResp.StatusCode == http.StatusNotFound {
+			errResp = apperrors.Forbidden("Access denied", "user is not a member of this tenant")
+		}
+		c.JSON(errResp.StatusCode, errResp)
+		return uuid.Nil, false
+	}
+
+	if adminOnly && role != "admin" {
+		errResp := apperrors.Forbidden("Admin required",
+			"only tenant admins can perform this action")
+		c.JSON(errResp.StatusCode, errResp)
+		return uuid.Nil, false
+	}
+
+	return tenantID, true
+}
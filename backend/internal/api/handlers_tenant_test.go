@@ -0,0 +1,217 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/auth"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/services"
+	"go.uber.org/zap"
+)
+
+// newTenantTestServer returns a server whose storage is backed by sqlmock
+func newTenantTestServer(t *testing.T) (*Server, sqlmock.Sqlmock) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	return &Server{
+		logger:     zap.NewNop(),
+		postgres:   storage.NewPostgresDBFromDB(db),
+		jwtManager: auth.NewJWTManager("test-secret", time.Hour, time.Hour, time.Hour),
+		wsManager:  services.NewConnectionManager(nil),
+	}, mock
+}
+
+// asUser sets the request context the way AuthMiddleware does for a user
+func asUser(userID int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}
+}
+
+// expectTenantRole expects the tenant membership lookup of a user; an empty
+// role means the user is not a member
+func expectTenantRole(mock sqlmock.Sqlmock, tenantID uuid.UUID, userID int, role string) {
+	rows := sqlmock.NewRows([]string{"role"})
+	if role != "" {
+		rows.AddRow(role)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT role FROM tenant_users")).
+		WithArgs(tenantID, userID).
+		WillReturnRows(rows)
+}
+
+// tenantRouteCase is a request to a tenant-scoped route. expect sets up the
+// storage calls of a successful request after the tenant role check.
+type tenantRouteCase struct {
+	method, path, body string // path is relative to /api/v1/tenants/<id>
+	adminOnly          bool
+	status             int
+	expect             func(mock sqlmock.Sqlmock, tenantID uuid.UUID)
+}
+
+// testTenantRoutes checks that each route denies non-members, and non-admin
+// members when it is admin only, and serves users with the required role.
+// The user ID is set in the context the way AuthMiddleware sets it.
+func testTenantRoutes(t *testing.T, register func(s *Server, tenants *gin.RouterGroup), cases []tenantRouteCase) {
+	const userID = 7
+
+	for _, tc := range cases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			server, mock := newTenantTestServer(t)
+			router := gin.New()
+			router.Use(asUser(userID))
+			register(server, router.Group("/api/v1/tenants"))
+
+			tenantID := uuid.New()
+			serve := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest(tc.method, "/api/v1/tenants/"+tenantID.String()+tc.path, strings.NewReader(tc.body))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				return w
+			}
+
+			expectTenantRole(mock, tenantID, userID, "")
+			w := serve()
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Contains(t, w.Body.String(), "Access denied")
+
+			role := "viewer"
+			if tc.adminOnly {
+				expectTenantRole(mock, tenantID, userID, role)
+				w = serve()
+				assert.Equal(t, http.StatusForbidden, w.Code)
+				assert.Contains(t, w.Body.String(), "Admin required")
+				role = "admin"
+			}
+
+			expectTenantRole(mock, tenantID, userID, role)
+			if tc.expect != nil {
+				tc.expect(mock, tenantID)
+			}
+			w = serve()
+			assert.Equal(t, tc.status, w.Code, w.Body.String())
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// emptyRows returns no rows, for list queries
+func emptyRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id"})
+}
+
+// TestRequireTenantRole_ThroughAuthMiddleware resolves tenant roles from the
+// integer user ID AuthMiddleware sets
+func TestRequireTenantRole_ThroughAuthMiddleware(t *testing.T) {
+	server, mock := newTenantTestServer(t)
+	tenantID := uuid.New()
+	user := &models.User{ID: 7, Username: "alice", Email: "alice@example.com", Role: "user", IsActive: true}
+	token, _, err := server.jwtManager.GenerateUserToken(user)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(server.AuthMiddleware())
+	router.GET("/api/v1/tenants/:id/query-redaction", server.handleGetQueryRedactionPolicy)
+	router.PUT("/api/v1/tenants/:id/query-redaction", server.handleUpdateQueryRedactionPolicy)
+
+	expectUser := func() {
+		now := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta("FROM pganalytics.users WHERE id = $1")).
+			WithArgs(user.ID).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "username", "email", "password_hash", "full_name", "role",
+				"is_active", "password_changed", "last_login", "created_at", "updated_at",
+			}).AddRow(user.ID, user.Username, user.Email, "hash", "Alice", user.Role, true, true, nil, now, now))
+	}
+	serve := func(method string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/tenants/"+tenantID.String()+"/query-redaction", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Members read the tenant's policy
+	expectUser()
+	expectTenantRole(mock, tenantID, user.ID, "viewer")
+	mock.ExpectQuery(regexp.QuoteMeta("FROM query_redaction_policies")).
+		WithArgs(tenantID).
+		WillReturnRows(sqlmock.NewRows([]string{"mode", "updated_at"}).AddRow("mask", time.Now()))
+	w := serve("GET")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"mode":"mask"`)
+
+	// Non-admin members may not change it
+	expectUser()
+	expectTenantRole(mock, tenantID, user.ID, "viewer")
+	w = serve("PUT")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Admin required")
+
+	// Non-members see nothing
+	expectUser()
+	expectTenantRole(mock, tenantID, user.ID, "")
+	w = serve("GET")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "Access denied")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestGetTenants_ThroughAuthMiddleware lists the tenants of the integer user ID
+func TestGetTenants_ThroughAuthMiddleware(t *testing.T) {
+	server, mock := newTenantTestServer(t)
+	tenantID := uuid.New()
+
+	router := gin.New()
+	router.Use(asUser(7))
+	router.GET("/api/v1/tenants", server.handleGetTenants)
+
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta("JOIN tenant_users tu ON t.id = tu.tenant_id")).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "slug", "created_at", "updated_at", "is_active"}).
+			AddRow(tenantID, "Acme", "acme", now, now, true))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/tenants", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), tenantID.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestAssignCollectorToTenant_TenantRole lets only tenant admins assign
+// collectors
+func TestAssignCollectorToTenant_TenantRole(t *testing.T) {
+	collectorID := uuid.New()
+
+	testTenantRoutes(t, func(s *Server, tenants *gin.RouterGroup) {
+		tenants.POST("/:id/collectors", s.handleAssignCollectorToTenant)
+	}, []tenantRouteCase{
+		{"POST", "/collectors", `{"collector_id":"` + collectorID.String() + `"}`, true, http.StatusOK, func(mock sqlmock.Sqlmock, tenantID uuid.UUID) {
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE collectors")).
+				WithArgs(tenantID, collectorID).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}},
+	})
+}
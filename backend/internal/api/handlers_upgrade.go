@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/upgrade_readiness"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ============================================================================
+// UPGRADE READINESS ENDPOINTS
+// ============================================================================
+
+// ingestUpgradeObjects stores a pg_upgrade_objects snapshot and returns the
+// number of objects stored
+func (s *Server) ingestUpgradeObjects(c *gin.Context, collectorID string, metric interface{}) int {
+	metricJSON, _ := json.Marshal(metric)
+
+	var req models.UpgradeObjectsRequest
+	if err := json.Unmarshal(metricJSON, &req); err != nil {
+		s.logger.Error("Failed to unmarshal pg_upgrade_objects metric", zap.Error(err))
+		return 0
+	}
+
+	ts := time.Now()
+	if parsed, err := time.Parse(time.RFC3339, req.Timestamp); err == nil {
+		ts = parsed
+	}
+
+	service := upgrade_readiness.NewService(s.postgres, s.logger)
+	stored, err := service.IngestObjects(c.Request.Context(), metricsCollectorUUID(collectorID), ts, req.Objects)
+	if err != nil {
+		return 0
+	}
+	return stored
+}
+
+// @Summary Get upgrade readiness
+// @Description Assess upgrading a collector's instance to a newer major version: extensions without a compatible release, removed or renamed settings in use, schema objects pg_upgrade refuses (WITH OIDS tables, abstime/reltime/tinterval, reg* and aclitem columns, postfix operators) and a checklist ordered by severity
+// @Tags Version
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param target query int true "Target major version, e.g. 17"
+// @Success 200 {object} models.UpgradeReadinessReport
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/upgrade-readiness [get]
+func (s *Server) handleGetUpgradeReadiness(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	target, err := strconv.Atoi(c.Query("target"))
+	if err != nil || target <= 0 {
+		errResp := apperrors.BadRequest("Invalid target version", "expected a major version, e.g. target=17")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	service := upgrade_readiness.NewService(s.postgres, s.logger)
+	report, err := service.Report(c.Request.Context(), collectorID, target, time.Now())
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
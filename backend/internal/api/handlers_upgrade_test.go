@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// TestGetUpgradeReadiness_InvalidRequest rejects bad parameters before querying
+func TestGetUpgradeReadiness_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{logger: zap.NewNop()}
+	router := gin.New()
+	router.GET("/api/v1/collectors/:id/upgrade-readiness", server.handleGetUpgradeReadiness)
+
+	collector := "/api/v1/collectors/" + uuid.New().String() + "/upgrade-readiness"
+	for path, message := range map[string]string{
+		"/api/v1/collectors/not-a-uuid/upgrade-readiness?target=17": "Invalid collector ID",
+		collector:                  "Invalid target version",
+		collector + "?target=17.2": "Invalid target version",
+		collector + "?target=-1":   "Invalid target version",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Contains(t, w.Body.String(), message, path)
+	}
+}
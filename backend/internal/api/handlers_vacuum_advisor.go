@@ -1,10 +1,15 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/vacuum_advisor"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 	"go.uber.org/zap"
 )
 
@@ -12,7 +17,110 @@ import (
 // VACUUM ADVISOR ENDPOINTS
 // ============================================================================
 
-// handleGetVacuumRecommendations returns VACUUM recommendations for a database
+// ingestVacuumStats stores a pg_vacuum_stats metric and returns the number of
+// tables inserted
+func (s *Server) ingestVacuumStats(c *gin.Context, collectorID string, metric interface{}) int {
+	metricJSON, _ := json.Marshal(metric)
+
+	var req models.VacuumStatsRequest
+	if err := json.Unmarshal(metricJSON, &req); err != nil {
+		s.logger.Error("Failed to unmarshal pg_vacuum_stats metric", zap.Error(err))
+		return 0
+	}
+
+	sampledAt, tables := buildVacuumTableStats(&req)
+	if err := s.postgres.StoreVacuumStats(c.Request.Context(), metricsCollectorUUID(collectorID), req.Database, sampledAt, req.Settings, tables); err != nil {
+		s.logger.Error("Failed to store vacuum stats",
+			zap.Error(err),
+			zap.String("collector_id", collectorID),
+			zap.String("database", req.Database),
+			zap.Int("tables", len(tables)),
+		)
+		return 0
+	}
+
+	return len(tables)
+}
+
+// buildVacuumTableStats converts a pg_vacuum_stats metric into table samples
+// Timestamps that do not parse as RFC 3339 are dropped: a missing last vacuum
+// reads as never vacuumed rather than as a wrong time.
+func buildVacuumTableStats(req *models.VacuumStatsRequest) (time.Time, []*models.VacuumTableStat) {
+	var sampledAt time.Time
+	if parsed, err := time.Parse(time.RFC3339, req.Timestamp); err == nil {
+		sampledAt = parsed
+	}
+
+	tables := make([]*models.VacuumTableStat, 0, len(req.Tables))
+	for _, t := range req.Tables {
+		if t.Table == "" {
+			continue
+		}
+		schema := t.Schema
+		if schema == "" {
+			schema = "public"
+		}
+		tables = append(tables, &models.VacuumTableStat{
+			DatabaseName:     req.Database,
+			SchemaName:       schema,
+			TableName:        t.Table,
+			LiveTuples:       t.LiveTuples,
+			DeadTuples:       t.DeadTuples,
+			ModSinceAnalyze:  t.ModSinceAnalyze,
+			InsSinceVacuum:   t.InsSinceVacuum,
+			TuplesInserted:   t.TuplesInserted,
+			TuplesUpdated:    t.TuplesUpdated,
+			TuplesDeleted:    t.TuplesDeleted,
+			TuplesHotUpdated: t.TuplesHotUpdated,
+			RelTuples:        t.RelTuples,
+			RelPages:         t.RelPages,
+			TableSizeBytes:   t.TableSizeBytes,
+			BloatBytes:       t.BloatBytes,
+			RelOptions:       t.RelOptions,
+			LastVacuum:       parseOptionalTime(t.LastVacuum),
+			LastAutovacuum:   parseOptionalTime(t.LastAutovacuum),
+			LastAnalyze:      parseOptionalTime(t.LastAnalyze),
+			LastAutoanalyze:  parseOptionalTime(t.LastAutoanalyze),
+			VacuumCount:      t.VacuumCount,
+			AutovacuumCount:  t.AutovacuumCount,
+			AnalyzeCount:     t.AnalyzeCount,
+			AutoanalyzeCount: t.AutoanalyzeCount,
+		})
+	}
+	return sampledAt, tables
+}
+
+func parseOptionalTime(value *string) *time.Time {
+	if value == nil {
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil
+	}
+	return &parsed
+}
+
+// vacuumAnalyzer returns an analyzer over the collected vacuum statistics
+func (s *Server) vacuumAnalyzer() *vacuum_advisor.VacuumAnalyzer {
+	if s.postgres == nil {
+		return vacuum_advisor.NewVacuumAnalyzer(nil)
+	}
+	return vacuum_advisor.NewVacuumAnalyzer(s.postgres)
+}
+
+// respondVacuumAdvisorError writes an analyzer error
+func (s *Server) respondVacuumAdvisorError(c *gin.Context, err error) {
+	s.logger.Error("Vacuum advisor failed", zap.Error(err))
+	if appErr, ok := err.(*apperrors.AppError); ok {
+		c.JSON(appErr.StatusCode, appErr)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, apperrors.InternalServerError("Failed to analyze vacuum statistics", ""))
+}
+
+// handleGetVacuumRecommendations returns VACUUM recommendations for a database,
+// most urgent first; priority=high keeps only tables needing immediate attention
 // GET /api/v1/vacuum-advisor/database/:database_id/recommendations
 func (s *Server) handleGetVacuumRecommendations(c *gin.Context) {
 	// Parse database ID from URL parameter
@@ -30,19 +138,36 @@ func (s *Server) handleGetVacuumRecommendations(c *gin.Context) {
 		limit = 20
 	}
 
-	// In production, this would query the database for recommendations
-	// For now, return a successful response structure
-	recommendations := []map[string]interface{}{}
+	analyzer := s.vacuumAnalyzer()
+	var recommendations []vacuum_advisor.VacuumRecommendation
+	if c.Query("priority") == vacuum_advisor.PriorityHigh {
+		recommendations, err = analyzer.GetHighPriorityTables(c.Request.Context(), databaseID)
+	} else {
+		recommendations, err = analyzer.AnalyzeDatabase(c.Request.Context(), databaseID)
+	}
+	if err != nil {
+		s.respondVacuumAdvisorError(c, err)
+		return
+	}
+	total := len(recommendations)
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	if recommendations == nil {
+		recommendations = []vacuum_advisor.VacuumRecommendation{}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"database_id":     databaseID,
 		"recommendations": recommendations,
 		"count":           len(recommendations),
+		"total":           total,
 		"limit":           limit,
 	})
 }
 
 // handleGetVacuumTableRecommendation returns VACUUM recommendation for a specific table
+// table_name is "schema.table" or a bare name, which prefers the public schema
 // GET /api/v1/vacuum-advisor/database/:database_id/table/:table_name
 func (s *Server) handleGetVacuumTableRecommendation(c *gin.Context) {
 	// Parse parameters from URL
@@ -59,23 +184,45 @@ func (s *Server) handleGetVacuumTableRecommendation(c *gin.Context) {
 		return
 	}
 
-	// Return recommendation structure
+	analyzer := s.vacuumAnalyzer()
+	recommendation, err := analyzer.AnalyzeTable(c.Request.Context(), databaseID, tableName)
+	if err != nil {
+		s.respondVacuumAdvisorError(c, err)
+		return
+	}
+
+	// A table without collected statistics has no recommendation
+	configs := []vacuum_advisor.AutovacuumConfig{}
+	tunings := []vacuum_advisor.AutovacuumTuning{}
+	if recommendation != nil {
+		all, err := analyzer.GetAutovacuumConfig(c.Request.Context(), databaseID)
+		if err != nil {
+			s.respondVacuumAdvisorError(c, err)
+			return
+		}
+		for _, config := range all {
+			if config.SchemaName == recommendation.SchemaName && config.TableName == recommendation.TableName {
+				configs = append(configs, config)
+			}
+		}
+		qualified := recommendation.SchemaName + "." + recommendation.TableName
+		if tunings, err = analyzer.TuneAutovacuum(c.Request.Context(), databaseID, qualified); err != nil {
+			s.respondVacuumAdvisorError(c, err)
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"database_id": databaseID,
-		"table_name":  tableName,
-		"recommendation": map[string]interface{}{
-			"id":                  0,
-			"recommendation_type": "full_vacuum",
-			"dead_tuples_ratio":   0.0,
-			"estimated_gain":      0,
-			"last_vacuum":         nil,
-			"last_autovacuum":     nil,
-		},
-		"autovacuum_config": []interface{}{},
+		"database_id":       databaseID,
+		"table_name":        tableName,
+		"recommendation":    recommendation,
+		"autovacuum_config": configs,
+		"tuning":            tunings,
 	})
 }
 
-// handleGetAutovacuumConfig returns current autovacuum configuration
+// handleGetAutovacuumConfig returns the autovacuum settings in effect for each
+// table, where they come from and the recommended value
 // GET /api/v1/vacuum-advisor/database/:database_id/autovacuum-config
 func (s *Server) handleGetAutovacuumConfig(c *gin.Context) {
 	// Parse database ID from URL parameter
@@ -86,11 +233,20 @@ func (s *Server) handleGetAutovacuumConfig(c *gin.Context) {
 		return
 	}
 
-	// Return autovacuum configuration
+	configs, err := s.vacuumAnalyzer().GetAutovacuumConfig(c.Request.Context(), databaseID)
+	if err != nil {
+		s.respondVacuumAdvisorError(c, err)
+		return
+	}
+	tables := make(map[string]bool)
+	for _, config := range configs {
+		tables[config.SchemaName+"."+config.TableName] = true
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"database_id":    databaseID,
-		"configurations": []interface{}{},
-		"total_tables":   0,
+		"configurations": configs,
+		"total_tables":   len(tables),
 	})
 }
 
@@ -116,7 +272,8 @@ func (s *Server) handleExecuteVacuum(c *gin.Context) {
 	})
 }
 
-// handleGetVacuumTuningSuggestions returns autovacuum tuning suggestions
+// handleGetVacuumTuningSuggestions returns autovacuum tuning suggestions derived
+// from observed churn, each with the statement that applies it
 // GET /api/v1/vacuum-advisor/database/:database_id/tune-suggestions
 func (s *Server) handleGetVacuumTuningSuggestions(c *gin.Context) {
 	// Parse database ID from URL parameter
@@ -127,10 +284,22 @@ func (s *Server) handleGetVacuumTuningSuggestions(c *gin.Context) {
 		return
 	}
 
-	// Return tuning suggestions
+	suggestions, err := s.vacuumAnalyzer().TuneDatabase(c.Request.Context(), databaseID)
+	if err != nil {
+		s.respondVacuumAdvisorError(c, err)
+		return
+	}
+	var improvement float64
+	for _, suggestion := range suggestions {
+		improvement += suggestion.ExpectedImprovement
+	}
+	if len(suggestions) > 0 {
+		improvement /= float64(len(suggestions))
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"database_id":           databaseID,
-		"suggestions":           []interface{}{},
-		"estimated_improvement": 0.0,
+		"suggestions":           suggestions,
+		"estimated_improvement": improvement,
 	})
 }
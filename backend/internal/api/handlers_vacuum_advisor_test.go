@@ -4,9 +4,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 	"go.uber.org/zap"
 )
 
@@ -255,3 +258,49 @@ func TestAPIResponseFormats(t *testing.T) {
 		})
 	}
 }
+
+// TestBuildVacuumTableStats converts collected vacuum statistics
+func TestBuildVacuumTableStats(t *testing.T) {
+	lastAutovacuum := "2026-04-01T11:00:00Z"
+	invalid := "yesterday"
+	insSinceVacuum := int64(42)
+	req := &models.VacuumStatsRequest{
+		Type:      "pg_vacuum_stats",
+		Timestamp: "2026-04-01T12:00:00Z",
+		Database:  "app",
+		Tables: []models.CollectedVacuumStat{
+			{
+				Schema:         "sales",
+				Table:          "orders",
+				LiveTuples:     1000,
+				DeadTuples:     50,
+				InsSinceVacuum: &insSinceVacuum,
+				RelTuples:      -1,
+				RelOptions:     []string{"autovacuum_vacuum_scale_factor=0.01"},
+				LastAutovacuum: &lastAutovacuum,
+				LastAnalyze:    &invalid,
+			},
+			{Table: "events"},
+			{Schema: "public"},
+		},
+	}
+
+	sampledAt, tables := buildVacuumTableStats(req)
+	assert.Equal(t, time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC), sampledAt)
+	require.Len(t, tables, 2)
+
+	orders := tables[0]
+	assert.Equal(t, "app", orders.DatabaseName)
+	assert.Equal(t, "sales", orders.SchemaName)
+	assert.Equal(t, int64(50), orders.DeadTuples)
+	assert.Equal(t, &insSinceVacuum, orders.InsSinceVacuum)
+	assert.Equal(t, -1.0, orders.RelTuples)
+	assert.Equal(t, []string{"autovacuum_vacuum_scale_factor=0.01"}, orders.RelOptions)
+	require.NotNil(t, orders.LastAutovacuum)
+	assert.Equal(t, time.Date(2026, 4, 1, 11, 0, 0, 0, time.UTC), *orders.LastAutovacuum)
+	assert.Nil(t, orders.LastAnalyze)
+	assert.Nil(t, orders.LastVacuum)
+
+	assert.Equal(t, "public", tables[1].SchemaName)
+	assert.Equal(t, "events", tables[1].TableName)
+}
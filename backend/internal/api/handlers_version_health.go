@@ -1,13 +1,20 @@
 package api
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/auth"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/version_health"
 	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
 	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
 )
 
 // ============================================================================
@@ -63,13 +70,13 @@ func (s *Server) handleGetVersionHealthChecks(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
-// @Summary Execute version-specific health checks
-// @Description Run all applicable health checks for a collector and return results
+// @Summary Request a version health check run
+// @Description Ask the collector to run its health checks against its database on its next config pull. Results are reported back by the collector and read from the results endpoint.
 // @Tags HealthChecks
 // @Produce json
 // @Security Bearer
 // @Param id path string true "Collector ID"
-// @Success 200 {object} models.VersionHealthCheckResponse
+// @Success 202 {object} models.HealthCheckRunRequest
 // @Failure 400 {object} apperrors.AppError
 // @Failure 404 {object} apperrors.AppError
 // @Router /api/v1/collectors/{id}/health-checks/run [post]
@@ -82,66 +89,215 @@ func (s *Server) handleRunVersionHealthChecks(c *gin.Context) {
 		return
 	}
 
+	requestedAt, err := s.postgres.RequestHealthCheckRun(c.Request.Context(), collectorID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, &models.HealthCheckRunRequest{
+		CollectorID: collectorID,
+		RequestedAt: requestedAt,
+		Status:      "requested",
+	})
+}
+
+// @Summary Get version health check results
+// @Description Get the latest result of each health check the collector ran against its database
+// @Tags HealthChecks
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Success 200 {object} models.VersionHealthCheckResponse
+// @Failure 400 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/health-checks/results [get]
+func (s *Server) handleGetVersionHealthCheckResults(c *gin.Context) {
+	collectorIDStr := c.Param("id")
+	collectorID, err := uuid.Parse(collectorIDStr)
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	version, err := s.postgres.GetPostgreSQLVersion(ctx, collectorID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	results, err := s.postgres.GetLatestHealthCheckResults(ctx, collectorID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	resp := &models.VersionHealthCheckResponse{
+		CollectorID:             collectorID,
+		PostgreSQLVersion:       version.Major,
+		PostgreSQLVersionString: version.FullVersion,
+		Results:                 results,
+		Summary:                 version_health.Summarize(results),
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// @Summary Get collector health check config
+// @Description Get the health check queries for the authenticated collector's PostgreSQL version, including the custom checks scheduled on its collector groups (pulled by collector). The collector runs them and pushes a pg_health_checks metric with their result sets.
+// @Tags HealthChecks
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} models.HealthCheckConfig
+// @Failure 401 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Router /api/v1/collectors/health-checks/config [get]
+func (s *Server) handleGetHealthCheckConfig(c *gin.Context) {
+	claimsValue, exists := c.Get("collector_claims")
+	if !exists {
+		errResp := apperrors.Unauthorized("Authentication required", "")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+	claims, ok := claimsValue.(*auth.CollectorClaims)
+	if !ok {
+		errResp := apperrors.Unauthorized("Invalid authentication claims", "")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+	collectorID := metricsCollectorUUID(claims.CollectorID)
+
 	ctx := c.Request.Context()
 
-	// Get PostgreSQL version for the collector
 	version, err := s.postgres.GetPostgreSQLVersion(ctx, collectorID)
 	if err != nil {
 		c.JSON(err.(*apperrors.AppError).StatusCode, err)
 		return
 	}
 
-	// Get health checks applicable to this version
 	checks, err := s.postgres.GetHealthChecksForVersion(ctx, version.Major)
 	if err != nil {
 		c.JSON(err.(*apperrors.AppError).StatusCode, err)
 		return
 	}
 
-	// Execute each health check
-	var results []*models.HealthCheckResult
-	summary := models.HealthCheckSummary{
-		TotalChecks: len(checks),
+	scheduled, err := s.postgres.GetScheduledHealthChecks(ctx, collectorID, version.Major)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
 	}
 
-	for _, check := range checks {
-		result, err := s.postgres.RunHealthCheck(ctx, collectorID, check)
-		if err != nil {
-			// Log error but continue with other checks
-			result = &models.HealthCheckResult{
-				CheckID:   check.ID,
-				CheckName: check.CheckName,
-				Severity:  check.Severity,
-				Passed:    false,
-				Message:   err.Error(),
-				CheckedAt: check.CreatedAt,
-			}
-		}
+	requestedAt, err := s.postgres.GetHealthCheckRunRequest(ctx, collectorID)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, version_health.Config(collectorID, version.Major, checks, scheduled, requestedAt))
+}
+
+// ingestHealthCheckResults judges and stores a pg_health_checks metric and
+// returns the number of results stored
+func (s *Server) ingestHealthCheckResults(c *gin.Context, collectorID string, metric interface{}) int {
+	metricJSON, _ := json.Marshal(metric)
+
+	var req models.HealthCheckMetricsRequest
+	if err := json.Unmarshal(metricJSON, &req); err != nil {
+		s.logger.Error("Failed to unmarshal pg_health_checks metric", zap.Error(err))
+		return 0
+	}
+
+	ts := time.Now()
+	if parsed, err := time.Parse(time.RFC3339, req.Timestamp); err == nil {
+		ts = parsed
+	}
+
+	results, err := version_health.NewService(s.postgres, s.logger).IngestCollected(c.Request.Context(), metricsCollectorUUID(collectorID), ts, req.Results)
+	if err != nil {
+		return 0
+	}
+	return len(results)
+}
 
-		// Update summary counts
-		if result.Passed {
-			summary.PassedChecks++
-		} else {
-			switch result.Severity {
-			case "critical":
-				summary.FailedCritical++
-			case "warning":
-				summary.FailedWarning++
-			case "info":
-				summary.FailedInfo++
-			}
+// @Summary Run version health checks on a managed instance
+// @Description Connect to a managed instance and run the health checks for its PostgreSQL version, each in a read-only transaction
+// @Tags HealthChecks
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path int true "Managed Instance ID"
+// @Param request body models.RunManagedInstanceHealthChecksRequest false "Connection credentials"
+// @Success 200 {object} models.ManagedInstanceHealthCheckResponse
+// @Failure 400 {object} apperrors.AppError
+// @Failure 404 {object} apperrors.AppError
+// @Failure 503 {object} apperrors.AppError
+// @Router /api/v1/managed-instances/{id}/health-checks/run [post]
+func (s *Server) handleRunManagedInstanceHealthChecks(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid Managed Instance ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	var req models.RunManagedInstanceHealthChecksRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			errResp := apperrors.BadRequest("Invalid request", err.Error())
+			c.JSON(errResp.StatusCode, errResp)
+			return
 		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
 
-		results = append(results, result)
+	instance, err := s.postgres.GetManagedInstance(ctx, id)
+	if err != nil {
+		errResp := apperrors.ToAppError(err)
+		c.JSON(errResp.StatusCode, errResp)
+		return
 	}
 
-	// Build response
-	resp := &models.VersionHealthCheckResponse{
-		CollectorID:             collectorID,
-		PostgreSQLVersion:       version.Major,
-		PostgreSQLVersionString: version.FullVersion,
-		Results:                 results,
-		Summary:                 summary,
+	username, password, appErr := s.managedInstanceCredentials(ctx, instance, req.Username, req.Password)
+	if appErr != nil {
+		c.JSON(appErr.StatusCode, appErr)
+		return
+	}
+	if username == "" {
+		errResp := apperrors.BadRequest("Username required", "no username was given and none is stored for the instance")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	db, err := sql.Open("postgres", version_health.ConnString(instance.Endpoint, instance.Port, username, password,
+		req.Database, instance.SSLMode, instance.ConnectionTimeout))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid connection parameters", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		errResp := apperrors.ExternalServiceError("Managed instance", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	resp, err := version_health.NewService(s.postgres, s.logger).RunManagedInstance(ctx, id, version_health.NewSQLExecutor(db))
+	if err != nil {
+		// Store errors are AppErrors; anything else came from the instance
+		errResp, ok := err.(*apperrors.AppError)
+		if !ok {
+			errResp = apperrors.ExternalServiceError("Managed instance", err.Error())
+		}
+		c.JSON(errResp.StatusCode, errResp)
+		return
 	}
 
 	c.JSON(http.StatusOK, resp)
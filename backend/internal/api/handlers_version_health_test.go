@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// TestVersionHealthChecks_InvalidRequest rejects bad parameters before querying
+func TestVersionHealthChecks_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{logger: zap.NewNop()}
+	router := gin.New()
+	router.POST("/api/v1/collectors/:id/health-checks/run", server.handleRunVersionHealthChecks)
+	router.GET("/api/v1/collectors/:id/health-checks/results", server.handleGetVersionHealthCheckResults)
+	router.GET("/api/v1/collectors/health-checks/config", server.handleGetHealthCheckConfig)
+	router.POST("/api/v1/managed-instances/:id/health-checks/run", server.handleRunManagedInstanceHealthChecks)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		msg    string
+	}{
+		{"POST", "/api/v1/collectors/not-a-uuid/health-checks/run", "", http.StatusBadRequest, "Invalid collector ID"},
+		{"GET", "/api/v1/collectors/not-a-uuid/health-checks/results", "", http.StatusBadRequest, "Invalid collector ID"},
+		{"GET", "/api/v1/collectors/health-checks/config", "", http.StatusUnauthorized, "Authentication required"},
+		{"POST", "/api/v1/managed-instances/abc/health-checks/run", "", http.StatusBadRequest, "Invalid Managed Instance ID"},
+		{"POST", "/api/v1/managed-instances/1/health-checks/run", "{", http.StatusBadRequest, "Invalid request"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tt.status, w.Code, tt.path)
+		assert.Contains(t, w.Body.String(), tt.msg, tt.path)
+	}
+}
@@ -0,0 +1,265 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/wraparound"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// ============================================================================
+// TRANSACTION ID WRAPAROUND ENDPOINTS
+// ============================================================================
+
+// ingestWraparoundMetrics stores a pg_wraparound metric as one sample and
+// returns the number of rows inserted
+func (s *Server) ingestWraparoundMetrics(c *gin.Context, collectorID string, metric interface{}, redactor *query_performance.Redactor) int {
+	metricJSON, _ := json.Marshal(metric)
+
+	var req models.WraparoundMetricsRequest
+	if err := json.Unmarshal(metricJSON, &req); err != nil {
+		s.logger.Error("Failed to unmarshal pg_wraparound metric", zap.Error(err))
+		return 0
+	}
+
+	sample, databases, tables, blockers := buildWraparoundSample(metricsCollectorUUID(collectorID), &req, redactor)
+	if err := s.postgres.StoreWraparoundMetrics(c.Request.Context(), sample, databases, tables, blockers); err != nil {
+		s.logger.Error("Failed to store wraparound metrics",
+			zap.Error(err),
+			zap.String("collector_id", collectorID),
+			zap.Int("databases", len(databases)),
+			zap.Int("tables", len(tables)),
+			zap.Int("blockers", len(blockers)),
+		)
+		return 0
+	}
+
+	return 1 + len(databases) + len(tables) + len(blockers)
+}
+
+// buildWraparoundSample converts a pg_wraparound metric into a sample with its
+// ages and blockers. Freeze settings that are missing or do not parse are left
+// at zero, which the wraparound service reads as the PostgreSQL default.
+func buildWraparoundSample(collectorID uuid.UUID, req *models.WraparoundMetricsRequest, redactor *query_performance.Redactor) (*models.WraparoundSample, []*models.WraparoundAge, []*models.WraparoundAge, []*models.WraparoundBlocker) {
+	ts := time.Now()
+	if parsed, err := time.Parse(time.RFC3339, req.Timestamp); err == nil {
+		ts = parsed
+	}
+
+	setting := func(name string) int64 {
+		v, _ := strconv.ParseInt(strings.TrimSpace(req.Settings[name]), 10, 64)
+		return v
+	}
+
+	sample := &models.WraparoundSample{
+		Time:                  ts,
+		CollectorID:           collectorID,
+		NextXID:               req.NextXID,
+		NextMultixact:         req.NextMultixact,
+		FreezeMaxAge:          setting("autovacuum_freeze_max_age"),
+		MultixactFreezeMaxAge: setting("autovacuum_multixact_freeze_max_age"),
+		FreezeMinAge:          setting("vacuum_freeze_min_age"),
+	}
+
+	databases := make([]*models.WraparoundAge, 0, len(req.Databases))
+	for _, d := range req.Databases {
+		if d.Database == "" {
+			continue
+		}
+		databases = append(databases, &models.WraparoundAge{
+			Time:         ts,
+			DatabaseName: d.Database,
+			XIDAge:       d.XIDAge,
+			MXIDAge:      d.MXIDAge,
+		})
+	}
+
+	tables := make([]*models.WraparoundAge, 0, len(req.Tables))
+	for _, t := range req.Tables {
+		if t.Table == "" {
+			continue
+		}
+		schema := t.Schema
+		if schema == "" {
+			schema = "public"
+		}
+		size := t.TableSizeBytes
+		tables = append(tables, &models.WraparoundAge{
+			Time:           ts,
+			DatabaseName:   t.Database,
+			SchemaName:     schema,
+			TableName:      t.Table,
+			XIDAge:         t.XIDAge,
+			MXIDAge:        t.MXIDAge,
+			TableSizeBytes: &size,
+			FreezeMaxAge:   t.FreezeMaxAge,
+		})
+	}
+
+	blockers := make([]*models.WraparoundBlocker, 0, len(req.PreparedTransactions)+len(req.ReplicationSlots)+len(req.LongTransactions))
+	for _, p := range req.PreparedTransactions {
+		age := p.XIDAge
+		blockers = append(blockers, &models.WraparoundBlocker{
+			Type:         models.WraparoundBlockerPreparedTransaction,
+			Name:         p.GID,
+			DatabaseName: p.Database,
+			UserName:     p.Owner,
+			XIDAge:       &age,
+			Since:        parseOptionalTime(&p.Prepared),
+		})
+	}
+	for _, slot := range req.ReplicationSlots {
+		age := olderAge(slot.XminAge, slot.CatalogXminAge)
+		if age == nil {
+			continue // holds no xmin horizon back
+		}
+		active := slot.Active
+		blockers = append(blockers, &models.WraparoundBlocker{
+			Type:         models.WraparoundBlockerReplicationSlot,
+			Name:         slot.SlotName,
+			DatabaseName: slot.Database,
+			XIDAge:       age,
+			Active:       &active,
+			Detail:       slot.SlotType,
+		})
+	}
+	for _, t := range req.LongTransactions {
+		blockers = append(blockers, &models.WraparoundBlocker{
+			Type:         models.WraparoundBlockerLongTransaction,
+			Name:         strconv.Itoa(t.PID),
+			DatabaseName: t.Database,
+			UserName:     t.User,
+			XIDAge:       olderAge(t.XIDAge, t.XminAge),
+			Since:        parseOptionalTime(&t.XactStart),
+			Detail:       redactor.RedactQuery(t.Query),
+		})
+	}
+
+	return sample, databases, tables, blockers
+}
+
+// olderAge returns the larger of two optional ages
+func olderAge(a, b *int64) *int64 {
+	if a == nil || (b != nil && *b > *a) {
+		return b
+	}
+	return a
+}
+
+// @Summary Get Wraparound Status
+// @Description Assess transaction ID and multixact wraparound headroom per database and table, projected at the current consumption rate, with the blockers holding the xmin horizon back
+// @Tags Metrics
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param at query string false "Point in time (RFC3339)" default(now)
+// @Success 200 {object} models.WraparoundStatus
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/wraparound [get]
+func (s *Server) handleGetWraparoundStatus(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	at := time.Now()
+	if v := c.Query("at"); v != "" {
+		if at, err = time.Parse(time.RFC3339, v); err != nil {
+			errResp := apperrors.BadRequest("Invalid at timestamp", "expected RFC3339")
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+	}
+
+	service := wraparound.NewService(storage.NewWraparoundRepository(s.postgres.GetDB()), s.logger)
+	status, err := service.GetStatus(c.Request.Context(), collectorID, at)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// @Summary Get Wraparound History
+// @Description Transaction ID and multixact ages of a database, or of one of its tables, over time
+// @Tags Metrics
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Collector ID"
+// @Param database query string true "Database name"
+// @Param table query string false "Table as schema.table"
+// @Param from query string false "Start time (RFC3339)" default(7 days ago)
+// @Param to query string false "End time (RFC3339)" default(now)
+// @Success 200 {array} models.WraparoundAge
+// @Failure 400 {object} apperrors.AppError
+// @Router /api/v1/collectors/{id}/wraparound/history [get]
+func (s *Server) handleGetWraparoundHistory(c *gin.Context) {
+	collectorID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		errResp := apperrors.BadRequest("Invalid collector ID", err.Error())
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	database := c.Query("database")
+	if database == "" {
+		errResp := apperrors.BadRequest("Missing database", "database is required")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	var schema, table *string
+	if v := c.Query("table"); v != "" {
+		parts := strings.SplitN(v, ".", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			errResp := apperrors.BadRequest("Invalid table", "expected schema.table")
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+		schema, table = &parts[0], &parts[1]
+	}
+
+	to := time.Now()
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			errResp := apperrors.BadRequest("Invalid to timestamp", "expected RFC3339")
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+	}
+	from := to.Add(-7 * 24 * time.Hour)
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			errResp := apperrors.BadRequest("Invalid from timestamp", "expected RFC3339")
+			c.JSON(errResp.StatusCode, errResp)
+			return
+		}
+	}
+	if !from.Before(to) {
+		errResp := apperrors.BadRequest("Invalid time range", "from must be before to")
+		c.JSON(errResp.StatusCode, errResp)
+		return
+	}
+
+	service := wraparound.NewService(storage.NewWraparoundRepository(s.postgres.GetDB()), s.logger)
+	ages, err := service.GetHistory(c.Request.Context(), collectorID, database, schema, table, from, to)
+	if err != nil {
+		c.JSON(err.(*apperrors.AppError).StatusCode, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ages)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// TestGetWraparoundHistory_InvalidRequest rejects bad parameters before
+// querying
+func TestGetWraparoundHistory_InvalidRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := &Server{logger: zap.NewNop()}
+	router := gin.New()
+	router.GET("/api/v1/collectors/:id/wraparound", server.handleGetWraparoundStatus)
+	router.GET("/api/v1/collectors/:id/wraparound/history", server.handleGetWraparoundHistory)
+
+	base := "/api/v1/collectors/" + uuid.New().String() + "/wraparound"
+	for path, message := range map[string]string{
+		"/api/v1/collectors/not-a-uuid/wraparound":  "Invalid collector ID",
+		base + "?at=yesterday":                      "Invalid at timestamp",
+		base + "/history":                           "Missing database",
+		base + "/history?database=app&table=events": "Invalid table",
+		base + "/history?database=app&from=2026-03-02T00:00:00Z&to=2026-03-01T00:00:00Z": "Invalid time range",
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, path)
+		assert.Contains(t, w.Body.String(), message, path)
+	}
+}
+
+// TestBuildWraparoundSample converts settings, ages and the three kinds of
+// blockers
+func TestBuildWraparoundSample(t *testing.T) {
+	redactor, err := query_performance.NewRedactor(models.RedactionModeNormalize, nil)
+	require.NoError(t, err)
+
+	xmin := int64(900)
+	catalogXmin := int64(1200)
+	xidAge := int64(300)
+	reloption := int64(100000000)
+	req := &models.WraparoundMetricsRequest{
+		Timestamp:     "2026-03-01T12:00:00Z",
+		NextXID:       5000000000,
+		NextMultixact: 42,
+		Settings: map[string]string{
+			"autovacuum_freeze_max_age":           "150000000",
+			"autovacuum_multixact_freeze_max_age": "400000000",
+			"vacuum_freeze_min_age":               "bogus",
+		},
+		Databases: []models.CollectedDatabaseAge{
+			{Database: "app", XIDAge: 1000, MXIDAge: 10},
+			{Database: ""},
+		},
+		Tables: []models.CollectedTableAge{
+			{Database: "app", Table: "events", XIDAge: 1000, TableSizeBytes: 8192, FreezeMaxAge: &reloption},
+		},
+		PreparedTransactions: []models.CollectedPreparedXact{
+			{GID: "tx1", Database: "app", Owner: "alice", Prepared: "2026-03-01T11:00:00Z", XIDAge: 500},
+		},
+		ReplicationSlots: []models.CollectedSlotHorizon{
+			{SlotName: "replica", SlotType: "logical", XminAge: &xmin, CatalogXminAge: &catalogXmin},
+			{SlotName: "physical_no_feedback", SlotType: "physical", Active: true},
+		},
+		LongTransactions: []models.CollectedLongTransaction{
+			{PID: 4242, Database: "app", User: "bob", XactStart: "2026-03-01T10:00:00Z", XminAge: &xidAge, Query: "SELECT * FROM accounts WHERE id = 42"},
+		},
+	}
+
+	collectorID := uuid.New()
+	sample, databases, tables, blockers := buildWraparoundSample(collectorID, req, redactor)
+
+	assert.Equal(t, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), sample.Time)
+	assert.Equal(t, collectorID, sample.CollectorID)
+	assert.Equal(t, int64(150000000), sample.FreezeMaxAge)
+	assert.Equal(t, int64(400000000), sample.MultixactFreezeMaxAge)
+	assert.Zero(t, sample.FreezeMinAge, "unparsable settings fall back to the default")
+
+	require.Len(t, databases, 1)
+	require.Len(t, tables, 1)
+	assert.Equal(t, "public", tables[0].SchemaName)
+	assert.Equal(t, &reloption, tables[0].FreezeMaxAge)
+
+	require.Len(t, blockers, 3, "slots without xmin hold nothing back")
+	assert.Equal(t, models.WraparoundBlockerPreparedTransaction, blockers[0].Type)
+	require.NotNil(t, blockers[0].Since)
+
+	assert.Equal(t, models.WraparoundBlockerReplicationSlot, blockers[1].Type)
+	assert.Equal(t, int64(1200), *blockers[1].XIDAge, "the older of xmin and catalog_xmin")
+	assert.Equal(t, "logical", blockers[1].Detail)
+	assert.False(t, *blockers[1].Active)
+
+	assert.Equal(t, models.WraparoundBlockerLongTransaction, blockers[2].Type)
+	assert.Equal(t, "4242", blockers[2].Name)
+	assert.Equal(t, int64(300), *blockers[2].XIDAge)
+	assert.NotContains(t, blockers[2].Detail, "42", "queries are redacted")
+}
@@ -505,34 +505,10 @@ func (s *Server) handleTestManagedInstanceConnection(c *gin.Context) {
 		return
 	}
 
-	// Get username from request or from stored instance
-	username := req.Username
-	if username == "" {
-		username = instance.MasterUsername
-	}
-
-	// Get password from request or from stored secret
-	password := req.Password
-
-	// If no password provided in request and we have a secret, decrypt it
-	if password == "" && instance.SecretID != nil {
-		secret, err := s.postgres.GetSecret(ctx, *instance.SecretID)
-		if err != nil {
-			s.logger.Error("Failed to retrieve stored password", zap.Int("secret_id", *instance.SecretID), zap.Error(err))
-			errResp := apperrors.BadRequest("Failed to retrieve stored password", "")
-			c.JSON(errResp.StatusCode, errResp)
-			return
-		}
-
-		// Decrypt password
-		decryptedPassword, err := s.secretManager.Decrypt(string(secret.SecretEncrypted))
-		if err != nil {
-			s.logger.Error("Failed to decrypt password", zap.Error(err))
-			errResp := apperrors.BadRequest("Failed to decrypt password", "")
-			c.JSON(errResp.StatusCode, errResp)
-			return
-		}
-		password = decryptedPassword
+	username, password, appErr := s.managedInstanceCredentials(ctx, instance, req.Username, req.Password)
+	if appErr != nil {
+		c.JSON(appErr.StatusCode, appErr)
+		return
 	}
 
 	// Test connection
@@ -572,6 +548,35 @@ func (s *Server) handleTestManagedInstanceConnection(c *gin.Context) {
 	c.JSON(200, response)
 }
 
+// managedInstanceCredentials returns the username and password to connect to
+// a managed instance with: the ones given, else the stored username and the
+// decrypted stored password
+func (s *Server) managedInstanceCredentials(ctx context.Context, instance *models.ManagedInstance, username, password string) (string, string, *apperrors.AppError) {
+	// Get username from request or from stored instance
+	if username == "" {
+		username = instance.MasterUsername
+	}
+
+	// If no password provided in request and we have a secret, decrypt it
+	if password == "" && instance.SecretID != nil {
+		secret, err := s.postgres.GetSecret(ctx, *instance.SecretID)
+		if err != nil {
+			s.logger.Error("Failed to retrieve stored password", zap.Int("secret_id", *instance.SecretID), zap.Error(err))
+			return "", "", apperrors.BadRequest("Failed to retrieve stored password", "")
+		}
+
+		// Decrypt password
+		decryptedPassword, err := s.secretManager.Decrypt(string(secret.SecretEncrypted))
+		if err != nil {
+			s.logger.Error("Failed to decrypt password", zap.Error(err))
+			return "", "", apperrors.BadRequest("Failed to decrypt password", "")
+		}
+		password = decryptedPassword
+	}
+
+	return username, password, nil
+}
+
 // Helper function to test RDS connection
 func testRDSConnection(ctx context.Context, endpoint string, port int, username, password string) error {
 	// Build PostgreSQL connection string
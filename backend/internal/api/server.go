@@ -1,7 +1,6 @@
 package api
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -16,6 +15,7 @@ import (
 	"github.com/torresglauco/pganalytics-v3/backend/internal/middleware"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/ml"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/retention"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/session"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/timescale"
@@ -45,7 +45,9 @@ type Server struct {
 	silenceHandler    *handlers.SilenceHandler
 	escalationHandler *handlers.EscalationHandler
 	alertRulesHandler *handlers.AlertRulesHandler
-	logCollector      *log_analysis.LogCollector
+	logStream         *log_analysis.LogStream
+	logTemplates      *log_analysis.TemplateService
+	retention         *retention.Service
 }
 
 // NewServer creates a new API server
@@ -96,6 +98,9 @@ func NewServer(
 	if postgres != nil {
 		db := postgres.GetDB()
 
+		// Realtime events are limited to the instances of the user's tenants
+		wsManager.SetAccessResolver(&realtimeAccessResolver{store: postgres})
+
 		// Create SilenceRepository with WebSocket broadcast support
 		silenceRepo := storage.NewSilenceRepository(db, wsManager)
 		silenceService := services.NewSilenceService(silenceRepo)
@@ -114,12 +119,15 @@ func NewServer(
 	// Initialize session manager
 	sessionManager := session.NewSessionManager(nil) // Redis client to be configured
 
-	// Initialize log collector for log analysis and streaming
-	var logCollectorDB *sql.DB
+	// Initialize the log stream; with a database, ingested logs also reach
+	// the subscribers of other replicas
+	logStream := log_analysis.NewLogStream(nil)
+	var logTemplates *log_analysis.TemplateService
 	if postgres != nil {
-		logCollectorDB = postgres.GetDB()
+		logStream = log_analysis.NewLogStream(postgres)
+		// Shared so that template trees stay cached across ingest requests
+		logTemplates = log_analysis.NewTemplateService(postgres)
 	}
-	logCollector := log_analysis.NewLogCollector(logCollectorDB)
 
 	return &Server{
 		config:            cfg,
@@ -139,7 +147,8 @@ func NewServer(
 		silenceHandler:    silenceHandler,
 		escalationHandler: escalationHandler,
 		alertRulesHandler: alertRulesHandler,
-		logCollector:      logCollector,
+		logStream:         logStream,
+		logTemplates:      logTemplates,
 	}
 }
 
@@ -148,6 +157,21 @@ func (s *Server) SetCacheManager(cm *cache.Manager) {
 	s.cacheManager = cm
 }
 
+// LogStream returns the stream that pushes ingested logs to subscribers
+func (s *Server) LogStream() *log_analysis.LogStream {
+	return s.logStream
+}
+
+// Realtime returns the manager of the WebSocket connections
+func (s *Server) Realtime() *services.ConnectionManager {
+	return s.wsManager
+}
+
+// SetRetentionService sets the service that applies data retention policies
+func (s *Server) SetRetentionService(rs *retention.Service) {
+	s.retention = rs
+}
+
 // SetSessionManager sets the session manager for the server
 func (s *Server) SetSessionManager(sm session.ISessionManager) {
 	s.sessionManager = sm
@@ -318,8 +342,10 @@ func (s *Server) RegisterRoutes(router *gin.Engine) {
 		system.DELETE("/cache", s.AuthMiddleware(), s.handleClearCache) // Requires auth (destructive operation)
 	}
 
-	// WebSocket route (JWT auth required, handled in handler)
+	// Realtime routes, WebSocket and Server-Sent Events (JWT auth required,
+	// handled in handlers)
 	router.GET("/api/v1/ws", s.handleWebSocket)
+	router.GET("/api/v1/sse", s.handleEventStream)
 
 	// API v1 routes
 	api := router.Group("/api/v1")
@@ -363,6 +389,7 @@ func (s *Server) RegisterRoutes(router *gin.Engine) {
 			managedInstances.PUT("/:id", s.handleUpdateManagedInstance)
 			managedInstances.DELETE("/:id", s.handleDeleteManagedInstance)
 			managedInstances.POST("/:id/test-connection", s.handleTestManagedInstanceConnection)
+			managedInstances.POST("/:id/health-checks/run", s.handleRunManagedInstanceHealthChecks)
 		}
 
 		// Collector routes will be defined below
@@ -375,6 +402,9 @@ func (s *Server) RegisterRoutes(router *gin.Engine) {
 			// Token refresh (collector auth required)
 			collectors.POST("/refresh-token", s.CollectorAuthMiddleware(), s.handleRefreshCollectorToken)
 
+			// Health check queries pulled by the collector (collector auth required)
+			collectors.GET("/health-checks/config", s.CollectorAuthMiddleware(), s.handleGetHealthCheckConfig)
+
 			// Protected routes with tenant context for RLS (SCALE-04)
 			collectors.GET("", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleListCollectors)
 			collectors.GET("/:id", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetCollector)
@@ -383,23 +413,44 @@ func (s *Server) RegisterRoutes(router *gin.Engine) {
 			// Query Statistics routes
 			collectors.GET("/:id/queries/slow", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetSlowQueries)
 			collectors.GET("/:id/queries/frequent", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetFrequentQueries)
+			collectors.GET("/:id/queries/regressions", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetQueryRegressions)
+
+			// Deploy markers for regression reports
+			collectors.POST("/:id/deploy-markers", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleCreateDeployMarker)
+			collectors.GET("/:id/deploy-markers", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleListDeployMarkers)
+
+			// Log templates mined at ingest, with new/spiking template anomalies
+			collectors.GET("/:id/logs/templates", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetLogTemplates)
 
 			// ================================================================
 			// Metrics Collection Routes (Phase 1 & 2)
 			// ================================================================
 			collectors.GET("/:id/schema", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetSchemaMetrics)
 			collectors.GET("/:id/locks", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetLockMetrics)
+			collectors.GET("/:id/locks/blocking-tree", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetBlockingTree)
+			collectors.GET("/:id/wraparound", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetWraparoundStatus)
+			collectors.GET("/:id/wraparound/history", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetWraparoundHistory)
 			collectors.GET("/:id/bloat", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetBloatMetrics)
 			collectors.GET("/:id/cache-hits", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetCacheMetrics)
 			collectors.GET("/:id/connections", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetConnectionMetrics)
 			collectors.GET("/:id/extensions", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetExtensionMetrics)
 
+			// ================================================================
+			// Active Session History Routes
+			// ================================================================
+			collectors.GET("/:id/activity/wait-events", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetActivityWaitEvents)
+			collectors.GET("/:id/activity/sessions", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetActivitySessions)
+			collectors.GET("/:id/activity/queries", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetActivityQueries)
+			collectors.GET("/:id/activity/queries/:fingerprint_hash/waits", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetFingerprintWaitProfile)
+
 			// ================================================================
 			// Replication Routes (Phase 10)
 			// ================================================================
 			// Streaming replication
 			collectors.GET("/:id/replication", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetReplicationMetrics)
 			collectors.GET("/:id/replication-slots", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetReplicationSlots)
+			collectors.GET("/:id/replication-slots/risk", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetSlotRisk)
+			collectors.GET("/:id/cluster-events", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetCollectorClusterEvents)
 			// Logical replication
 			collectors.GET("/:id/logical-subscriptions", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetLogicalSubscriptions)
 			collectors.GET("/:id/publications", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetPublications)
@@ -416,12 +467,29 @@ func (s *Server) RegisterRoutes(router *gin.Engine) {
 			// ================================================================
 			collectors.GET("/:id/version", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetVersionInfo)
 			collectors.GET("/:id/mode", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetCollectorMode)
+			collectors.GET("/:id/upgrade-readiness", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetUpgradeReadiness)
 
 			// ================================================================
 			// Version-Specific Health Checks Routes (VER-03)
 			// ================================================================
 			collectors.GET("/:id/health-checks", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetVersionHealthChecks)
 			collectors.POST("/:id/health-checks/run", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleRunVersionHealthChecks)
+			collectors.GET("/:id/health-checks/results", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetVersionHealthCheckResults)
+
+			// ================================================================
+			// Server Settings Routes
+			// ================================================================
+			collectors.GET("/:id/settings", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetSettings)
+			collectors.GET("/:id/settings/history", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetSettingsHistory)
+			collectors.GET("/:id/settings/advice", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetSettingsAdvice)
+			collectors.GET("/:id/settings/drift", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetClusterSettingsDrift)
+
+			// ================================================================
+			// Database Health Score Routes
+			// ================================================================
+			collectors.GET("/:id/database-health", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetDatabaseHealth)
+			collectors.GET("/:id/database-health/history", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleGetDatabaseHealthHistory)
+			collectors.POST("/:id/database-health/calculate", s.AuthMiddleware(), s.TenantContextMiddleware(), s.handleCalculateDatabaseHealth)
 		}
 
 		// ================================================================
@@ -472,6 +540,47 @@ func (s *Server) RegisterRoutes(router *gin.Engine) {
 			tenants.POST("", s.handleCreateTenant)
 			tenants.GET("/:id/collectors", s.handleGetTenantCollectors)
 			tenants.POST("/:id/collectors", s.handleAssignCollectorToTenant)
+			// Query redaction policy and backfill of stored samples
+			tenants.GET("/:id/query-redaction", s.handleGetQueryRedactionPolicy)
+			tenants.PUT("/:id/query-redaction", s.handleUpdateQueryRedactionPolicy)
+			tenants.POST("/:id/query-redaction/backfill", s.handleBackfillQueryRedaction)
+			// Custom log categories evaluated before the built-in classification
+			tenants.GET("/:id/log-categories", s.handleListLogCategoryRules)
+			tenants.POST("/:id/log-categories", s.handleCreateLogCategoryRule)
+			tenants.DELETE("/:id/log-categories/:ruleId", s.handleDeleteLogCategoryRule)
+			// Retention of raw data with hourly rollups and archival
+			tenants.GET("/:id/retention-policies", s.handleListRetentionPolicies)
+			tenants.PUT("/:id/retention-policies/:class", s.handleUpdateRetentionPolicy)
+			tenants.DELETE("/:id/retention-policies/:class", s.handleDeleteRetentionPolicy)
+			tenants.POST("/:id/retention/run", s.handleRunRetention)
+			tenants.GET("/:id/retention/runs", s.handleListRetentionRuns)
+			tenants.GET("/:id/retention/rollups", s.handleGetDataRollups)
+			// Replication graph across the tenant's collectors
+			tenants.GET("/:id/replication-topology", s.handleGetFleetTopology)
+			tenants.GET("/:id/cluster-events", s.handleGetTenantClusterEvents)
+			// Custom health check packs scheduled on collector groups
+			tenants.GET("/:id/check-packs", s.handleListCheckPacks)
+			tenants.POST("/:id/check-packs", s.handleCreateCheckPack)
+			tenants.POST("/:id/check-packs/import", s.handleImportCheckPack)
+			tenants.GET("/:id/check-packs/:packId", s.handleGetCheckPack)
+			tenants.PUT("/:id/check-packs/:packId", s.handleUpdateCheckPack)
+			tenants.DELETE("/:id/check-packs/:packId", s.handleDeleteCheckPack)
+			tenants.GET("/:id/check-packs/:packId/export", s.handleExportCheckPack)
+			tenants.POST("/:id/check-packs/:packId/checks", s.handleCreatePackCheck)
+			tenants.PUT("/:id/check-packs/:packId/checks/:checkId", s.handleUpdatePackCheck)
+			tenants.DELETE("/:id/check-packs/:packId/checks/:checkId", s.handleDeletePackCheck)
+			tenants.GET("/:id/check-packs/:packId/schedules", s.handleListCheckPackSchedules)
+			tenants.POST("/:id/check-packs/:packId/schedules", s.handleCreateCheckPackSchedule)
+			tenants.DELETE("/:id/check-packs/:packId/schedules/:scheduleId", s.handleDeleteCheckPackSchedule)
+			tenants.GET("/:id/collector-groups", s.handleListCollectorGroups)
+			tenants.POST("/:id/collector-groups", s.handleCreateCollectorGroup)
+			tenants.PUT("/:id/collector-groups/:groupId/members", s.handleSetCollectorGroupMembers)
+			tenants.DELETE("/:id/collector-groups/:groupId", s.handleDeleteCollectorGroup)
+			tenants.GET("/:id/collector-groups/:groupId/settings-drift", s.handleGetGroupSettingsDrift)
+			// Database health weights and fleet ranking
+			tenants.GET("/:id/database-health/weights", s.handleGetDatabaseHealthWeights)
+			tenants.PUT("/:id/database-health/weights", s.handleUpdateDatabaseHealthWeights)
+			tenants.GET("/:id/database-health/ranking", s.handleGetDatabaseHealthRanking)
 		}
 
 		// ================================================================
@@ -522,15 +631,16 @@ func (s *Server) RegisterRoutes(router *gin.Engine) {
 		// Log Ingest routes
 		logs := api.Group("/logs")
 		{
-			// High-volume endpoint for log ingestion - requires API token auth
-			logs.POST("/ingest", s.handleIngestLogs)
+			// High-volume endpoint for log ingestion - requires collector token auth
+			logs.POST("/ingest", s.CollectorAuthMiddleware(), s.handleIngestLogs)
 			// Frontend log viewer endpoints
 			logs.GET("", s.AuthMiddleware(), s.handleGetLogs)
+			logs.GET("/search", s.AuthMiddleware(), s.handleSearchLogs)
 			logs.GET("/:logId", s.AuthMiddleware(), s.handleGetLogDetails)
 			// Log analysis endpoints (collector logs)
 			logs.GET("/collector/:collector_id", s.AuthMiddleware(), s.handleGetCollectorLogs)
 			// WebSocket endpoint for streaming logs in real-time
-			logs.GET("/stream/:collector_id", s.handleLogStream)
+			logs.GET("/stream/:collector_id", s.AuthMiddleware(), s.handleLogStream)
 		}
 
 		// ========================================================================
@@ -575,7 +685,7 @@ func (s *Server) RegisterRoutes(router *gin.Engine) {
 		// Internal analysis routes (collector -> backend for analyzed data like EXPLAIN plans)
 		internal := api.Group("/internal")
 		{
-			internal.POST("/explain-plans", s.MTLSMiddleware(), s.AuthMiddleware(), s.handleStoreExplainPlan)
+			internal.POST("/explain-plans", s.MTLSMiddleware(), s.CollectorAuthMiddleware(), s.handleStoreExplainPlan)
 		}
 
 		// Configuration routes
@@ -793,9 +903,15 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 	handler(c.Writer, c.Request)
 }
 
+// handleEventStream is a Gin wrapper for the Server-Sent Events handler
+func (s *Server) handleEventStream(c *gin.Context) {
+	handler := EventStreamHandler(s.wsManager, s.jwtManager)
+	handler(c.Writer, c.Request)
+}
+
 // handleIngestLogs is a Gin wrapper for the log ingest handler
 func (s *Server) handleIngestLogs(c *gin.Context) {
-	handler := handlers.IngestLogs(s.postgres, s.wsManager)
+	handler := handlers.IngestLogs(s.postgres, s.wsManager, s.logStream, s.logTemplates, c.GetString("collector_id"))
 	handler(c.Writer, c.Request)
 }
 
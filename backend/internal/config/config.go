@@ -109,6 +109,16 @@ type Config struct {
 	AuditEnabled       bool
 	AuditRetentionDays int    // Default 365
 	AuditArchivePath   string // S3 path or filesystem path
+
+	// Data Retention Configuration
+	RetentionInterval          time.Duration // How often retention policies are applied
+	RetentionArchiveDir        string        // Local archive directory for expired data
+	RetentionArchiveS3Endpoint string        // S3-compatible endpoint, takes precedence over the directory
+	RetentionArchiveS3Bucket   string
+	RetentionArchiveS3Region   string
+	RetentionArchiveS3Prefix   string
+	RetentionArchiveAccessKey  string
+	RetentionArchiveSecretKey  string
 }
 
 // Load loads configuration from environment variables
@@ -147,37 +157,45 @@ func Load() *Config {
 		RetryBackoffMultiplier: getFloatEnv("RETRY_BACKOFF_MULTIPLIER", 2.0),
 		RetryInitialBackoff:    time.Duration(getIntEnv("RETRY_INITIAL_BACKOFF", 100)) * time.Millisecond,
 		// Enterprise Authentication
-		LDAPEnabled:               getBoolEnv("LDAP_ENABLED", false),
-		LDAPServerURL:             getEnv("LDAP_SERVER_URL", ""),
-		LDAPBindDN:                getEnv("LDAP_BIND_DN", ""),
-		LDAPBindPassword:          getEnv("LDAP_BIND_PASSWORD", ""),
-		LDAPUserSearchBase:        getEnv("LDAP_USER_SEARCH_BASE", ""),
-		LDAPGroupSearchBase:       getEnv("LDAP_GROUP_SEARCH_BASE", ""),
-		LDAPGroupToRoleJSON:       getEnv("LDAP_GROUP_TO_ROLE_MAPPING", "{}"),
-		SAMLEnabled:               getBoolEnv("SAML_ENABLED", false),
-		SAMLCertPath:              getEnv("SAML_CERT_PATH", ""),
-		SAMLKeyPath:               getEnv("SAML_KEY_PATH", ""),
-		SAMLIDPMetadataURL:        getEnv("SAML_IDP_METADATA_URL", ""),
-		SAMLEntityID:              getEnv("SAML_ENTITY_ID", ""),
-		OAuthEnabled:              getBoolEnv("OAUTH_ENABLED", false),
-		OAuthProvidersJSON:        getEnv("OAUTH_PROVIDERS", "{}"),
-		MFAEnabled:                getBoolEnv("MFA_ENABLED", false),
-		MFADefaultType:            getEnv("MFA_DEFAULT_TYPE", "totp"),
-		MFAToTPIssuer:             getEnv("MFA_TOTP_ISSUER", "pgAnalytics"),
-		MFASMSProvider:            getEnv("MFA_SMS_PROVIDER", "twilio"),
-		MFABackupCodeCount:        getIntEnv("MFA_BACKUP_CODE_COUNT", 8),
-		EncryptionEnabled:         getBoolEnv("ENCRYPTION_ENABLED", false),
-		EncryptionKeyBackend:      getEnv("ENCRYPTION_KEY_BACKEND", "local"),
-		EncryptionAlgorithm:       getEnv("ENCRYPTION_ALGORITHM", "aes-256-gcm"),
-		EncryptionKeyRotationDays: getIntEnv("ENCRYPTION_KEY_ROTATION_DAYS", 90),
-		AWSSecretsManagerARN:      getEnv("AWS_SECRETS_MANAGER_ARN", ""),
-		VaultAddr:                 getEnv("VAULT_ADDR", ""),
-		VaultToken:                getEnv("VAULT_TOKEN", ""),
-		VaultPath:                 getEnv("VAULT_PATH", "/secret/pganalytics"),
-		GCPKMSKeyName:             getEnv("GCP_KMS_KEY_NAME", ""),
-		AuditEnabled:              getBoolEnv("AUDIT_ENABLED", true),
-		AuditRetentionDays:        getIntEnv("AUDIT_RETENTION_DAYS", 365),
-		AuditArchivePath:          getEnv("AUDIT_ARCHIVE_PATH", ""),
+		LDAPEnabled:                getBoolEnv("LDAP_ENABLED", false),
+		LDAPServerURL:              getEnv("LDAP_SERVER_URL", ""),
+		LDAPBindDN:                 getEnv("LDAP_BIND_DN", ""),
+		LDAPBindPassword:           getEnv("LDAP_BIND_PASSWORD", ""),
+		LDAPUserSearchBase:         getEnv("LDAP_USER_SEARCH_BASE", ""),
+		LDAPGroupSearchBase:        getEnv("LDAP_GROUP_SEARCH_BASE", ""),
+		LDAPGroupToRoleJSON:        getEnv("LDAP_GROUP_TO_ROLE_MAPPING", "{}"),
+		SAMLEnabled:                getBoolEnv("SAML_ENABLED", false),
+		SAMLCertPath:               getEnv("SAML_CERT_PATH", ""),
+		SAMLKeyPath:                getEnv("SAML_KEY_PATH", ""),
+		SAMLIDPMetadataURL:         getEnv("SAML_IDP_METADATA_URL", ""),
+		SAMLEntityID:               getEnv("SAML_ENTITY_ID", ""),
+		OAuthEnabled:               getBoolEnv("OAUTH_ENABLED", false),
+		OAuthProvidersJSON:         getEnv("OAUTH_PROVIDERS", "{}"),
+		MFAEnabled:                 getBoolEnv("MFA_ENABLED", false),
+		MFADefaultType:             getEnv("MFA_DEFAULT_TYPE", "totp"),
+		MFAToTPIssuer:              getEnv("MFA_TOTP_ISSUER", "pgAnalytics"),
+		MFASMSProvider:             getEnv("MFA_SMS_PROVIDER", "twilio"),
+		MFABackupCodeCount:         getIntEnv("MFA_BACKUP_CODE_COUNT", 8),
+		EncryptionEnabled:          getBoolEnv("ENCRYPTION_ENABLED", false),
+		EncryptionKeyBackend:       getEnv("ENCRYPTION_KEY_BACKEND", "local"),
+		EncryptionAlgorithm:        getEnv("ENCRYPTION_ALGORITHM", "aes-256-gcm"),
+		EncryptionKeyRotationDays:  getIntEnv("ENCRYPTION_KEY_ROTATION_DAYS", 90),
+		AWSSecretsManagerARN:       getEnv("AWS_SECRETS_MANAGER_ARN", ""),
+		VaultAddr:                  getEnv("VAULT_ADDR", ""),
+		VaultToken:                 getEnv("VAULT_TOKEN", ""),
+		VaultPath:                  getEnv("VAULT_PATH", "/secret/pganalytics"),
+		GCPKMSKeyName:              getEnv("GCP_KMS_KEY_NAME", ""),
+		AuditEnabled:               getBoolEnv("AUDIT_ENABLED", true),
+		AuditRetentionDays:         getIntEnv("AUDIT_RETENTION_DAYS", 365),
+		AuditArchivePath:           getEnv("AUDIT_ARCHIVE_PATH", ""),
+		RetentionInterval:          time.Duration(getIntEnv("RETENTION_INTERVAL", 3600)) * time.Second,
+		RetentionArchiveDir:        getEnv("RETENTION_ARCHIVE_DIR", ""),
+		RetentionArchiveS3Endpoint: getEnv("RETENTION_ARCHIVE_S3_ENDPOINT", ""),
+		RetentionArchiveS3Bucket:   getEnv("RETENTION_ARCHIVE_S3_BUCKET", ""),
+		RetentionArchiveS3Region:   getEnv("RETENTION_ARCHIVE_S3_REGION", "us-east-1"),
+		RetentionArchiveS3Prefix:   getEnv("RETENTION_ARCHIVE_S3_PREFIX", ""),
+		RetentionArchiveAccessKey:  getEnv("RETENTION_ARCHIVE_ACCESS_KEY", ""),
+		RetentionArchiveSecretKey:  getEnv("RETENTION_ARCHIVE_SECRET_KEY", ""),
 	}
 
 	return cfg
@@ -8,8 +8,27 @@ import (
 	"log"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/cluster_events"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/lock_analysis"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/slot_risk"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/wraparound"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
 )
 
+// lockSnapshotMaxAge is the oldest lock snapshot a blocking_chain rule evaluates
+const lockSnapshotMaxAge = 5 * time.Minute
+
+// wraparoundSampleMaxAge is the oldest wraparound sample a wraparound rule evaluates
+const wraparoundSampleMaxAge = time.Hour
+
+// slotSampleMaxAge is the oldest replication slot sample a replication_slot rule evaluates
+const slotSampleMaxAge = 15 * time.Minute
+
 // ============================================================================
 // ALERT RULE ENGINE TYPES
 // ============================================================================
@@ -43,9 +62,10 @@ type AlertRuleEngineJob struct {
 type AlertRule struct {
 	ID                   int64
 	UserID               int
+	TenantID             *uuid.UUID // nil for rules created before tenancy
 	Name                 string
 	Description          string
-	RuleType             string // "threshold", "change", "anomaly", "composite"
+	RuleType             string // "threshold", "change", "anomaly", "composite", "blocking_chain", "log", "wraparound", "replication_slot", "cluster_event"
 	DatabaseID           *int
 	QueryID              *int
 	MetricName           string
@@ -94,6 +114,36 @@ type CompositeCondition struct {
 	Rules    []json.RawMessage `json:"rules"`
 }
 
+// BlockingChainCondition fires when a root blocker holds more than MinWaiters
+// backends for longer than MinDurationSeconds in the latest lock snapshot
+type BlockingChainCondition struct {
+	models.BlockingChainAlertCondition
+}
+
+// LogCondition fires when more than Threshold stored log entries match the
+// condition's category, severity, SQLSTATE and pattern within its window
+type LogCondition struct {
+	models.LogAlertCondition
+}
+
+// WraparoundCondition fires when a wraparound metric of the collector's
+// latest sample compares to the condition's value
+type WraparoundCondition struct {
+	models.WraparoundAlertCondition
+}
+
+// SlotCondition fires when a replication slot metric of the collector's
+// latest sample compares to the condition's value
+type SlotCondition struct {
+	models.SlotAlertCondition
+}
+
+// ClusterEventCondition fires when the cluster of the condition's collector
+// had a failover, switchover or other role change within its window
+type ClusterEventCondition struct {
+	models.ClusterEventAlertCondition
+}
+
 // RuleEvaluationResult contains evaluation outcome
 type RuleEvaluationResult struct {
 	RuleID         int64
@@ -301,7 +351,7 @@ func (e *AlertRuleEngineJob) loadRules(ctx context.Context) ([]*AlertRule, error
 		       database_id, query_id, metric_name, condition,
 		       alert_severity, evaluation_interval_seconds, for_duration_seconds,
 		       notification_enabled, is_enabled, is_paused,
-		       created_at, updated_at
+		       created_at, updated_at, tenant_id
 		FROM alert_rules
 		WHERE is_enabled = TRUE
 		  AND is_paused = FALSE
@@ -325,7 +375,7 @@ func (e *AlertRuleEngineJob) loadRules(ctx context.Context) ([]*AlertRule, error
 			&rule.DatabaseID, &rule.QueryID, &rule.MetricName, &rule.Condition,
 			&rule.AlertSeverity, &rule.EvaluationInterval, &rule.ForDurationSeconds,
 			&rule.NotificationEnabled, &rule.IsEnabled, &rule.IsPaused,
-			&rule.CreatedAt, &rule.UpdatedAt,
+			&rule.CreatedAt, &rule.UpdatedAt, &rule.TenantID,
 		); err != nil {
 			log.Printf("[AlertEngine] Error scanning rule: %v\n", err)
 			continue
@@ -424,6 +474,41 @@ func (e *AlertRuleEngineJob) parseCondition(rule *AlertRule) (RuleCondition, err
 		}
 		return &cond, nil
 
+	case "blocking_chain":
+		cond, err := lock_analysis.ParseAlertCondition(rule.Condition)
+		if err != nil {
+			return nil, err
+		}
+		return &BlockingChainCondition{BlockingChainAlertCondition: *cond}, nil
+
+	case "log":
+		cond, err := log_analysis.ParseLogAlertCondition(rule.Condition)
+		if err != nil {
+			return nil, err
+		}
+		return &LogCondition{LogAlertCondition: *cond}, nil
+
+	case "wraparound":
+		cond, err := wraparound.ParseAlertCondition(rule.Condition)
+		if err != nil {
+			return nil, err
+		}
+		return &WraparoundCondition{WraparoundAlertCondition: *cond}, nil
+
+	case "replication_slot":
+		cond, err := slot_risk.ParseAlertCondition(rule.Condition)
+		if err != nil {
+			return nil, err
+		}
+		return &SlotCondition{SlotAlertCondition: *cond}, nil
+
+	case "cluster_event":
+		cond, err := cluster_events.ParseAlertCondition(rule.Condition)
+		if err != nil {
+			return nil, err
+		}
+		return &ClusterEventCondition{ClusterEventAlertCondition: *cond}, nil
+
 	default:
 		return nil, fmt.Errorf("unknown rule type: %s", rule.RuleType)
 	}
@@ -586,6 +671,220 @@ func (c *CompositeCondition) Evaluate(ctx context.Context, db *sql.DB, rule *Ale
 	}, nil
 }
 
+// Type returns the condition type
+func (b *BlockingChainCondition) Type() string {
+	return "blocking_chain"
+}
+
+// Evaluate checks the latest lock snapshot for root blockers holding too many
+// waiters for too long. Snapshots older than lockSnapshotMaxAge are ignored so
+// that a collector that stopped reporting does not keep the alert firing.
+func (b *BlockingChainCondition) Evaluate(ctx context.Context, db *sql.DB, rule *AlertRule) (bool, interface{}, error) {
+	now := time.Now()
+	service := lock_analysis.NewService(storage.NewLockSnapshotRepository(db), zap.NewNop())
+	snapshot, err := service.GetBlockingTree(ctx, b.CollectorID, now, nil)
+	if err != nil {
+		return false, nil, fmt.Errorf("load blocking tree: %w", err)
+	}
+	if snapshot.SnapshotTime == nil || now.Sub(*snapshot.SnapshotTime) > lockSnapshotMaxAge {
+		return false, nil, nil // No recent data
+	}
+
+	trees := lock_analysis.RootBlockersExceeding(snapshot, b.MinWaiters, time.Duration(b.MinDurationSeconds)*time.Second)
+
+	maxWaiters := 0
+	rootPIDs := make([]int, 0, len(trees))
+	for _, tree := range trees {
+		rootPIDs = append(rootPIDs, tree.Root.PID)
+		if tree.TotalWaiters > maxWaiters {
+			maxWaiters = tree.TotalWaiters
+		}
+	}
+
+	return len(trees) > 0, map[string]interface{}{
+		"current":   maxWaiters,
+		"threshold": b.MinWaiters,
+		"root_pids": rootPIDs,
+	}, nil
+}
+
+// Type returns the condition type
+func (l *LogCondition) Type() string {
+	return "log"
+}
+
+// Evaluate counts the stored logs matching the condition within its window,
+// per database or user when grouped
+func (l *LogCondition) Evaluate(ctx context.Context, db *sql.DB, rule *AlertRule) (bool, interface{}, error) {
+	var levels []string
+	if l.MinSeverity != "" {
+		levels = log_analysis.LogLevelsAtOrAbove(l.MinSeverity)
+	}
+
+	since := time.Now().Add(-time.Duration(l.WindowSeconds) * time.Second)
+	counts, err := storage.NewLogAlertRepository(db).CountMatchingLogs(ctx, &l.LogAlertCondition, levels, since, rule.TenantID, rule.UserID)
+	if err != nil {
+		return false, nil, fmt.Errorf("count matching logs: %w", err)
+	}
+
+	met, contextData := evaluateLogMatches(counts, l.Threshold, l.WindowSeconds)
+	return met, contextData, nil
+}
+
+// evaluateLogMatches fires when any group has more matching logs than the
+// threshold. Counts are ordered largest first, so the first is the current
+// value.
+func evaluateLogMatches(counts []*models.LogMatchCount, threshold int64, windowSeconds int) (bool, map[string]interface{}) {
+	current := int64(0)
+	if len(counts) > 0 {
+		current = counts[0].Count
+	}
+
+	exceeding := make([]*models.LogMatchCount, 0)
+	for _, count := range counts {
+		if count.Count > threshold {
+			exceeding = append(exceeding, count)
+		}
+	}
+
+	return len(exceeding) > 0, map[string]interface{}{
+		"current":        float64(current),
+		"threshold":      float64(threshold),
+		"window_seconds": windowSeconds,
+		"groups":         exceeding,
+	}
+}
+
+// Type returns the condition type
+func (w *WraparoundCondition) Type() string {
+	return "wraparound"
+}
+
+// Evaluate assesses the collector's latest wraparound sample. Samples older
+// than wraparoundSampleMaxAge are ignored, as are metrics without a value,
+// such as a projected time without consumption.
+func (w *WraparoundCondition) Evaluate(ctx context.Context, db *sql.DB, rule *AlertRule) (bool, interface{}, error) {
+	now := time.Now()
+	service := wraparound.NewService(storage.NewWraparoundRepository(db), zap.NewNop())
+	status, err := service.GetStatus(ctx, w.CollectorID, now)
+	if err != nil {
+		return false, nil, fmt.Errorf("load wraparound status: %w", err)
+	}
+
+	met, contextData := evaluateWraparound(status, &w.WraparoundAlertCondition, now)
+	return met, contextData, nil
+}
+
+// evaluateWraparound compares the condition's metric of a status to its value
+func evaluateWraparound(status *models.WraparoundStatus, cond *models.WraparoundAlertCondition, now time.Time) (bool, map[string]interface{}) {
+	if status.CollectedAt == nil || now.Sub(*status.CollectedAt) > wraparoundSampleMaxAge {
+		return false, nil // No recent data
+	}
+
+	current, ok := wraparound.MetricValue(status, cond.Metric)
+	if !ok {
+		return false, nil
+	}
+
+	return evaluateOperator(current, cond.Value, cond.Operator), map[string]interface{}{
+		"current":   current,
+		"threshold": cond.Value,
+		"metric":    cond.Metric,
+		"level":     status.Level,
+		"findings":  status.Findings,
+	}
+}
+
+// Type returns the condition type
+func (sc *SlotCondition) Type() string {
+	return "replication_slot"
+}
+
+// Evaluate assesses the collector's latest replication slot sample. Samples
+// older than slotSampleMaxAge are ignored, as are metrics without a value,
+// such as a disk fill time when no slot holds WAL back.
+func (sc *SlotCondition) Evaluate(ctx context.Context, db *sql.DB, rule *AlertRule) (bool, interface{}, error) {
+	now := time.Now()
+	service := slot_risk.NewService(storage.NewSlotRiskRepository(db), zap.NewNop())
+	report, err := service.GetReport(ctx, sc.CollectorID, now)
+	if err != nil {
+		return false, nil, fmt.Errorf("load replication slot risk: %w", err)
+	}
+
+	met, contextData := evaluateSlotRisk(report, &sc.SlotAlertCondition, now)
+	return met, contextData, nil
+}
+
+// evaluateSlotRisk compares the condition's metric of a report to its value
+func evaluateSlotRisk(report *models.SlotRiskReport, cond *models.SlotAlertCondition, now time.Time) (bool, map[string]interface{}) {
+	if report.CollectedAt == nil || now.Sub(*report.CollectedAt) > slotSampleMaxAge {
+		return false, nil // No recent data
+	}
+
+	current, ok := slot_risk.MetricValue(report, cond.Metric)
+	if !ok {
+		return false, nil
+	}
+
+	return evaluateOperator(current, cond.Value, cond.Operator), map[string]interface{}{
+		"current":          current,
+		"threshold":        cond.Value,
+		"metric":           cond.Metric,
+		"level":            report.Level,
+		"recommended_drop": report.RecommendedDrop,
+		"findings":         report.Findings,
+	}
+}
+
+// Type returns the condition type
+func (ce *ClusterEventCondition) Type() string {
+	return "cluster_event"
+}
+
+// Evaluate counts the events of the collector's cluster within the window
+func (ce *ClusterEventCondition) Evaluate(ctx context.Context, db *sql.DB, rule *AlertRule) (bool, interface{}, error) {
+	now := time.Now()
+	service := cluster_events.NewService(storage.NewClusterEventRepository(db), nil, zap.NewNop())
+	clusterID, err := service.ClusterIDOf(ctx, ce.CollectorID, now)
+	if err != nil {
+		return false, nil, fmt.Errorf("find cluster: %w", err)
+	}
+
+	events, err := service.ListEvents(ctx, &models.ClusterEventFilter{
+		ClusterID:  clusterID,
+		EventTypes: ce.EventTypes,
+		From:       now.Add(-time.Duration(ce.WindowSeconds) * time.Second),
+		To:         now,
+		Limit:      100,
+	})
+	if err != nil {
+		return false, nil, fmt.Errorf("list cluster events: %w", err)
+	}
+
+	met, contextData := evaluateClusterEvents(clusterID, events, ce.WindowSeconds)
+	return met, contextData, nil
+}
+
+// evaluateClusterEvents fires on any event, most recent first
+func evaluateClusterEvents(clusterID string, events []*models.ClusterEvent, windowSeconds int) (bool, map[string]interface{}) {
+	if len(events) == 0 {
+		return false, nil
+	}
+
+	summaries := make([]string, len(events))
+	for i, e := range events {
+		summaries[i] = e.Summary
+	}
+	return true, map[string]interface{}{
+		"current":        float64(len(events)),
+		"threshold":      float64(0),
+		"window_seconds": windowSeconds,
+		"cluster_id":     clusterID,
+		"event_type":     events[0].EventType,
+		"events":         summaries,
+	}
+}
+
 // ============================================================================
 // HELPER FUNCTIONS
 // ============================================================================
@@ -1,11 +1,15 @@
 package jobs
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 )
 
 // TestThresholdConditionEvaluation tests threshold-based rule evaluation
@@ -380,3 +384,225 @@ type AlertRuleEngineMetrics struct {
 	ErrorCount     int
 	CacheHitRate   float64
 }
+
+// TestParseBlockingChainCondition tests parsing of blocking_chain rules
+func TestParseBlockingChainCondition(t *testing.T) {
+	engine := NewAlertRuleEngineJob(nil)
+	collectorID := uuid.New()
+
+	condition, err := engine.parseCondition(&AlertRule{
+		RuleType:  "blocking_chain",
+		Condition: json.RawMessage(`{"collector_id":"` + collectorID.String() + `","min_waiters":5,"min_duration_seconds":60}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "blocking_chain", condition.Type())
+
+	cond := condition.(*BlockingChainCondition)
+	assert.Equal(t, collectorID, cond.CollectorID)
+	assert.Equal(t, 5, cond.MinWaiters)
+	assert.Equal(t, 60, cond.MinDurationSeconds)
+
+	// An invalid collector_id is rejected when parsing, before any query
+	_, err = engine.parseCondition(&AlertRule{
+		RuleType:  "blocking_chain",
+		Condition: json.RawMessage(`{"collector_id":"not-a-uuid","min_waiters":5,"min_duration_seconds":60}`),
+	})
+	assert.Error(t, err)
+}
+
+// TestParseLogCondition tests parsing of log rules
+func TestParseLogCondition(t *testing.T) {
+	engine := NewAlertRuleEngineJob(nil)
+
+	condition, err := engine.parseCondition(&AlertRule{
+		RuleType:  "log",
+		Condition: json.RawMessage(`{"category":"authentication_error","threshold":5,"group_by":"user"}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "log", condition.Type())
+
+	cond := condition.(*LogCondition)
+	assert.Equal(t, "authentication_error", cond.Category)
+	assert.Equal(t, int64(5), cond.Threshold)
+	assert.Equal(t, 60, cond.WindowSeconds, "window defaults to a minute")
+	assert.Equal(t, "user", cond.GroupBy)
+
+	_, err = engine.parseCondition(&AlertRule{
+		RuleType:  "log",
+		Condition: json.RawMessage(`{"threshold":5}`),
+	})
+	assert.Error(t, err, "a log condition needs a matcher")
+}
+
+// TestEvaluateLogMatches fires when any group exceeds the threshold
+func TestEvaluateLogMatches(t *testing.T) {
+	now := time.Now()
+	counts := []*models.LogMatchCount{
+		{Group: "mallory", Count: 9, LastSeen: now},
+		{Group: "alice", Count: 2, LastSeen: now},
+	}
+
+	met, contextData := evaluateLogMatches(counts, 5, 60)
+	assert.True(t, met)
+	assert.Equal(t, float64(9), contextData["current"])
+	assert.Equal(t, float64(5), contextData["threshold"])
+	groups := contextData["groups"].([]*models.LogMatchCount)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "mallory", groups[0].Group)
+
+	met, _ = evaluateLogMatches(counts, 9, 60)
+	assert.False(t, met, "the count must exceed the threshold")
+
+	met, contextData = evaluateLogMatches(nil, 0, 60)
+	assert.False(t, met)
+	assert.Equal(t, float64(0), contextData["current"])
+
+	// "Any deadlock" is a threshold of zero
+	met, _ = evaluateLogMatches([]*models.LogMatchCount{{Count: 1, LastSeen: now}}, 0, 60)
+	assert.True(t, met)
+}
+
+// TestParseWraparoundCondition tests parsing of wraparound rules
+func TestParseWraparoundCondition(t *testing.T) {
+	engine := NewAlertRuleEngineJob(nil)
+	collectorID := uuid.New()
+
+	condition, err := engine.parseCondition(&AlertRule{
+		RuleType:  "wraparound",
+		Condition: json.RawMessage(`{"collector_id":"` + collectorID.String() + `","metric":"xid_percent","operator":">=","value":25}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "wraparound", condition.Type())
+
+	cond := condition.(*WraparoundCondition)
+	assert.Equal(t, collectorID, cond.CollectorID)
+	assert.Equal(t, "xid_percent", cond.Metric)
+
+	_, err = engine.parseCondition(&AlertRule{
+		RuleType:  "wraparound",
+		Condition: json.RawMessage(`{"collector_id":"` + collectorID.String() + `","metric":"xid_percent","operator":"~","value":25}`),
+	})
+	assert.Error(t, err, "invalid operator")
+}
+
+// TestEvaluateWraparound compares the latest status and ignores stale samples
+func TestEvaluateWraparound(t *testing.T) {
+	now := time.Now()
+	collectedAt := now.Add(-5 * time.Minute)
+	status := &models.WraparoundStatus{
+		CollectedAt: &collectedAt,
+		Level:       models.WraparoundLevelWarning,
+		XID:         &models.WraparoundHeadroom{PercentOfHardLimit: 30},
+		Multixact:   &models.WraparoundHeadroom{},
+	}
+	cond := &models.WraparoundAlertCondition{Metric: "xid_percent", Operator: ">=", Value: 25}
+
+	met, contextData := evaluateWraparound(status, cond, now)
+	assert.True(t, met)
+	assert.Equal(t, float64(30), contextData["current"])
+	assert.Equal(t, float64(25), contextData["threshold"])
+
+	cond.Value = 40
+	met, _ = evaluateWraparound(status, cond, now)
+	assert.False(t, met)
+
+	stale := now.Add(-2 * wraparoundSampleMaxAge)
+	status.CollectedAt = &stale
+	cond.Value = 25
+	met, contextData = evaluateWraparound(status, cond, now)
+	assert.False(t, met, "stale samples do not fire")
+	assert.Nil(t, contextData)
+}
+
+// TestParseSlotCondition tests parsing of replication slot rules
+func TestParseSlotCondition(t *testing.T) {
+	engine := NewAlertRuleEngineJob(nil)
+	collectorID := uuid.New()
+
+	condition, err := engine.parseCondition(&AlertRule{
+		RuleType:  "replication_slot",
+		Condition: json.RawMessage(`{"collector_id":"` + collectorID.String() + `","metric":"inactive_slots","operator":">","value":0}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "replication_slot", condition.Type())
+
+	cond := condition.(*SlotCondition)
+	assert.Equal(t, collectorID, cond.CollectorID)
+	assert.Equal(t, "inactive_slots", cond.Metric)
+
+	_, err = engine.parseCondition(&AlertRule{
+		RuleType:  "replication_slot",
+		Condition: json.RawMessage(`{"metric":"inactive_slots","operator":">","value":0}`),
+	})
+	assert.Error(t, err, "missing collector")
+}
+
+// TestEvaluateSlotRisk compares the latest report and ignores stale samples
+func TestEvaluateSlotRisk(t *testing.T) {
+	now := time.Now()
+	collectedAt := now.Add(-time.Minute)
+	fullAt := collectedAt.Add(10 * time.Hour)
+	report := &models.SlotRiskReport{
+		CollectedAt:     &collectedAt,
+		Level:           models.SlotRiskCritical,
+		DiskFullAt:      &fullAt,
+		RecommendedDrop: "old_standby",
+	}
+	cond := &models.SlotAlertCondition{Metric: "hours_to_disk_full", Operator: "<", Value: 24}
+
+	met, contextData := evaluateSlotRisk(report, cond, now)
+	assert.True(t, met)
+	assert.InDelta(t, 10, contextData["current"], 0.001)
+	assert.Equal(t, "old_standby", contextData["recommended_drop"])
+
+	report.DiskFullAt = nil
+	met, contextData = evaluateSlotRisk(report, cond, now)
+	assert.False(t, met, "no forecast, no value")
+	assert.Nil(t, contextData)
+
+	stale := now.Add(-2 * slotSampleMaxAge)
+	report.CollectedAt = &stale
+	report.DiskFullAt = &fullAt
+	met, _ = evaluateSlotRisk(report, cond, now)
+	assert.False(t, met, "stale samples do not fire")
+}
+
+// TestParseClusterEventCondition tests parsing of cluster event rules
+func TestParseClusterEventCondition(t *testing.T) {
+	engine := NewAlertRuleEngineJob(nil)
+	collectorID := uuid.New()
+
+	condition, err := engine.parseCondition(&AlertRule{
+		RuleType:  "cluster_event",
+		Condition: json.RawMessage(`{"collector_id":"` + collectorID.String() + `","event_types":["failover"],"window_seconds":600}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "cluster_event", condition.Type())
+
+	cond := condition.(*ClusterEventCondition)
+	assert.Equal(t, collectorID, cond.CollectorID)
+	assert.Equal(t, 600, cond.WindowSeconds)
+
+	_, err = engine.parseCondition(&AlertRule{
+		RuleType:  "cluster_event",
+		Condition: json.RawMessage(`{"collector_id":"` + collectorID.String() + `","event_types":["reboot"]}`),
+	})
+	assert.Error(t, err, "invalid event type")
+}
+
+// TestEvaluateClusterEvents fires on any event in the window
+func TestEvaluateClusterEvents(t *testing.T) {
+	met, contextData := evaluateClusterEvents("7312345678901234567", nil, 300)
+	assert.False(t, met)
+	assert.Nil(t, contextData)
+
+	events := []*models.ClusterEvent{
+		{EventType: models.ClusterEventFailover, Summary: "Failover: db2 was promoted"},
+		{EventType: models.ClusterEventUpstreamChange, Summary: "db3 now replicates from db2"},
+	}
+	met, contextData = evaluateClusterEvents("7312345678901234567", events, 300)
+	assert.True(t, met)
+	assert.Equal(t, float64(2), contextData["current"])
+	assert.Equal(t, models.ClusterEventFailover, contextData["event_type"])
+	assert.Equal(t, []string{"Failover: db2 was promoted", "db3 now replicates from db2"}, contextData["events"])
+}
@@ -0,0 +1,140 @@
+package jobs
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/retention"
+	"go.uber.org/zap"
+)
+
+// RetentionJob periodically applies the tenants' retention policies
+type RetentionJob struct {
+	service      *retention.Service
+	logger       *zap.Logger
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	mu           sync.RWMutex
+	isRunning    bool
+	tickInterval time.Duration
+	jitterFactor float64
+}
+
+// NewRetentionJob creates a new retention job
+func NewRetentionJob(
+	service *retention.Service,
+	interval time.Duration,
+	logger *zap.Logger,
+) *RetentionJob {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &RetentionJob{
+		service:      service,
+		logger:       logger,
+		ctx:          ctx,
+		cancel:       cancel,
+		tickInterval: interval,
+		jitterFactor: 0.1, // 10% randomization
+		isRunning:    false,
+	}
+}
+
+// Start begins the retention job
+func (rj *RetentionJob) Start() {
+	rj.mu.Lock()
+	if rj.isRunning {
+		rj.mu.Unlock()
+		return
+	}
+	rj.isRunning = true
+	rj.mu.Unlock()
+
+	rj.wg.Add(1)
+	go rj.run()
+	rj.logger.Info("Retention job started", zap.Duration("interval", rj.tickInterval))
+}
+
+// Stop stops the retention job, interrupting a run in progress; windows
+// already expired stay committed and the rest resumes on the next start
+func (rj *RetentionJob) Stop() {
+	rj.mu.Lock()
+	defer rj.mu.Unlock()
+
+	if !rj.isRunning {
+		return
+	}
+
+	rj.isRunning = false
+	rj.cancel()
+	rj.wg.Wait()
+	rj.logger.Info("Retention job stopped")
+}
+
+// run applies the policies on every tick
+func (rj *RetentionJob) run() {
+	defer rj.wg.Done()
+
+	// Add initial jitter to stagger job starts across replicas
+	initialDelay := time.Duration(
+		float64(rj.tickInterval) * rj.jitterFactor * rand.Float64(),
+	)
+	timer := time.NewTimer(initialDelay)
+
+	for {
+		select {
+		case <-rj.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			rj.apply()
+
+			jitter := time.Duration(
+				float64(rj.tickInterval) * rj.jitterFactor * (2*rand.Float64() - 1),
+			)
+			timer.Reset(rj.tickInterval + jitter)
+		}
+	}
+}
+
+// apply runs the policies of every tenant once
+func (rj *RetentionJob) apply() {
+	startTime := time.Now()
+	ctx, cancel := context.WithTimeout(rj.ctx, rj.tickInterval)
+	defer cancel()
+
+	responses, err := rj.service.ApplyAll(ctx)
+	if err != nil {
+		rj.logger.Error("Retention run failed", zap.Error(err))
+	}
+
+	var rowsDeleted, rollupsDeleted int64
+	incomplete := 0
+	for _, response := range responses {
+		rowsDeleted += response.RowsDeleted
+		rollupsDeleted += response.RollupsDeleted
+		if !response.Complete {
+			incomplete++
+		}
+	}
+
+	rj.logger.Info(
+		"Retention run completed",
+		zap.Duration("duration", time.Since(startTime)),
+		zap.Int("tenants", len(responses)),
+		zap.Int64("rows_deleted", rowsDeleted),
+		zap.Int64("rollups_deleted", rollupsDeleted),
+		zap.Int("incomplete_tenants", incomplete),
+	)
+}
+
+// IsRunning returns whether the job is currently running
+func (rj *RetentionJob) IsRunning() bool {
+	rj.mu.RLock()
+	defer rj.mu.RUnlock()
+	return rj.isRunning
+}
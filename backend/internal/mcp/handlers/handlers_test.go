@@ -255,6 +255,40 @@ func TestAnomalyDetectErrorHandling(t *testing.T) {
 	}
 }
 
+// TestLockTreeWithMockDatabase tests lock_tree tool with mock database (no DB connection)
+func TestLockTreeWithMockDatabase(t *testing.T) {
+	mockCtx := NewHandlerContext(nil)
+
+	if _, err := mockCtx.LockTree(map[string]interface{}{}); err == nil {
+		t.Error("Expected error for missing collector_id")
+	}
+	if _, err := mockCtx.LockTree(map[string]interface{}{"collector_id": "c1", "at": "yesterday"}); err == nil {
+		t.Error("Expected error for invalid at")
+	}
+
+	result, err := mockCtx.LockTree(map[string]interface{}{"collector_id": "c1"})
+	if err != nil {
+		t.Fatalf("LockTree failed: %v", err)
+	}
+
+	tree, ok := result.(LockTreeResult)
+	if !ok {
+		t.Fatalf("Result type mismatch: got %T, want LockTreeResult", result)
+	}
+
+	if len(tree.Trees) != 1 {
+		t.Fatalf("Expected 1 blocking tree, got %d", len(tree.Trees))
+	}
+
+	if tree.Trees[0].Root.PID != 100 || tree.Trees[0].TotalWaiters != 2 {
+		t.Errorf("Unexpected root: pid %d with %d waiters", tree.Trees[0].Root.PID, tree.Trees[0].TotalWaiters)
+	}
+
+	if len(tree.Findings) == 0 {
+		t.Error("Expected findings for the mock snapshot")
+	}
+}
+
 // TestHandlerContextCreation tests proper handler context creation
 func TestHandlerContextCreation(t *testing.T) {
 	mockCtx := NewHandlerContext(nil)
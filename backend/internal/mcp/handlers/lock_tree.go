@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/lock_analysis"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+type LockTreeResult struct {
+	CollectorID     string                 `json:"collector_id"`
+	SnapshotTime    *time.Time             `json:"snapshot_time"`
+	BlockedSessions int                    `json:"blocked_sessions"`
+	Trees           []*models.BlockingTree `json:"trees"`
+	Findings        []*models.LockFinding  `json:"findings"`
+}
+
+func (ctx *HandlerContext) LockTree(params map[string]interface{}) (interface{}, error) {
+	collectorID, ok := params["collector_id"].(string)
+	if !ok || collectorID == "" {
+		return nil, fmt.Errorf("collector_id parameter required")
+	}
+
+	at := time.Now()
+	if v, ok := params["at"].(string); ok && v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("at must be an RFC3339 timestamp")
+		}
+		at = parsed
+	}
+
+	if ctx.DB == nil {
+		// Return mock data for testing: pid 100 blocks 200, which blocks 300
+		relation := 16384
+		wait := 42.0
+		username := "app"
+		state := "idle in transaction"
+		locks := []*models.Lock{
+			{PID: 100, LockType: "relation", Mode: "RowExclusiveLock", Granted: true, RelationID: &relation, Username: &username, SessionState: &state},
+			{PID: 200, LockType: "relation", Mode: "AccessExclusiveLock", Granted: false, RelationID: &relation},
+			{PID: 300, LockType: "relation", Mode: "AccessShareLock", Granted: false, RelationID: &relation},
+		}
+		waits := []*models.LockWait{
+			{BlockedPID: 200, BlockingPID: 100, WaitTimeSeconds: &wait},
+			{BlockedPID: 300, BlockingPID: 200, WaitTimeSeconds: &wait},
+		}
+		trees, findings := lock_analysis.BuildBlockingTrees(locks, waits)
+		return LockTreeResult{
+			CollectorID:     collectorID,
+			SnapshotTime:    &at,
+			BlockedSessions: 2,
+			Trees:           trees,
+			Findings:        findings,
+		}, nil
+	}
+
+	id, err := uuid.Parse(collectorID)
+	if err != nil {
+		return nil, fmt.Errorf("collector_id must be a UUID")
+	}
+
+	service := lock_analysis.NewService(storage.NewLockSnapshotRepository(ctx.DB), zap.NewNop())
+	snapshot, err := service.GetBlockingTree(context.Background(), id, at, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return LockTreeResult{
+		CollectorID:     collectorID,
+		SnapshotTime:    snapshot.SnapshotTime,
+		BlockedSessions: snapshot.BlockedSessions,
+		Trees:           snapshot.Trees,
+		Findings:        snapshot.Findings,
+	}, nil
+}
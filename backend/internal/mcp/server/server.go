@@ -55,6 +55,10 @@ func (s *MCPServer) RegisterDefaultHandlers(handlerCtx *handlers.HandlerContext)
 	s.RegisterTool("index_suggest", func(params map[string]interface{}) (interface{}, error) {
 		return handlerCtx.IndexSuggest(params)
 	})
+
+	s.RegisterTool("lock_tree", func(params map[string]interface{}) (interface{}, error) {
+		return handlerCtx.LockTree(params)
+	})
 }
 
 func (s *MCPServer) RegisterTool(name string, handler ToolHandler) {
@@ -103,7 +103,7 @@ func TestMCPServerHandlerRegistration(t *testing.T) {
 		t.Fatal("Tools is not a list")
 	}
 
-	expectedCount := 4 // table_stats, query_analysis, index_suggest, anomaly_detect
+	expectedCount := 5 // table_stats, query_analysis, index_suggest, lock_tree, anomaly_detect
 	if len(tools) != expectedCount {
 		t.Errorf("Expected %d tools, got %d", expectedCount, len(tools))
 	}
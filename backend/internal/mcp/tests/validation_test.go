@@ -82,14 +82,14 @@ func TestToolValidation(t *testing.T) {
 	tr := transport.NewStdioTransport(reader, writer)
 	mcp := server.NewMCPServer(tr)
 
-	// Register all 4 tools
+	// Register all 5 tools
 	handlerCtx := handlers.NewHandlerContext(nil)
 	mcp.RegisterDefaultHandlers(handlerCtx)
 	mcp.RegisterTool("anomaly_detect", func(params map[string]interface{}) (interface{}, error) {
 		return handlerCtx.DetectAnomalies(params)
 	})
 
-	expectedTools := []string{"table_stats", "query_analysis", "index_suggest", "anomaly_detect"}
+	expectedTools := []string{"table_stats", "query_analysis", "index_suggest", "lock_tree", "anomaly_detect"}
 
 	req := server.JSONRPCRequest{
 		JSONRPC: "2.0",
@@ -27,8 +27,7 @@ func TenantContextMiddleware(store *storage.PostgresDB, logger *zap.Logger) gin.
 			return
 		}
 
-		// Type assert to uuid.UUID
-		userID, ok := userIDInterface.(uuid.UUID)
+		userID, ok := GetUserIDFromContext(c)
 		if !ok {
 			logger.Error("Invalid user_id type in context",
 				zap.Any("user_id", userIDInterface))
@@ -43,7 +42,7 @@ func TenantContextMiddleware(store *storage.PostgresDB, logger *zap.Logger) gin.
 		tenant, err := store.GetTenantByUserID(c.Request.Context(), userID)
 		if err != nil {
 			logger.Warn("Failed to get tenant for user",
-				zap.String("user_id", userID.String()),
+				zap.Int("user_id", userID),
 				zap.Error(err))
 
 			// Return 403 Forbidden - user not associated with any tenant
@@ -74,12 +73,28 @@ func TenantContextMiddleware(store *storage.PostgresDB, logger *zap.Logger) gin.
 		logger.Debug("Tenant context set",
 			zap.String("tenant_id", tenant.ID.String()),
 			zap.String("tenant_slug", tenant.Slug),
-			zap.String("user_id", userID.String()))
+			zap.Int("user_id", userID))
 
 		c.Next()
 	}
 }
 
+// GetUserIDFromContext extracts the user_id set by AuthMiddleware, the integer
+// ID of the authenticated user, from gin context
+func GetUserIDFromContext(c *gin.Context) (int, bool) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+
+	userID, ok := userIDInterface.(int)
+	if !ok {
+		return 0, false
+	}
+
+	return userID, true
+}
+
 // GetTenantIDFromContext extracts tenant_id from gin context
 func GetTenantIDFromContext(c *gin.Context) (uuid.UUID, bool) {
 	tenantIDInterface, exists := c.Get("tenant_id")
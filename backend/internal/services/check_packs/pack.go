@@ -0,0 +1,167 @@
+package check_packs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/version_health"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+const (
+	// DefaultInterval is how often a scheduled pack runs unless set
+	DefaultInterval = 3600
+
+	// MinInterval is the shortest schedule a pack can run at
+	MinInterval = 60
+
+	// MaxChecks is how many checks a pack can hold
+	MaxChecks = 200
+
+	defaultCategory = "configuration"
+)
+
+var (
+	packNamePattern    = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,99}$`)
+	packVersionPattern = regexp.MustCompile(`^[0-9A-Za-z][0-9A-Za-z.+-]{0,49}$`)
+	checkNamePattern   = regexp.MustCompile(`^[a-z][a-z0-9_]{0,99}$`)
+	categoryPattern    = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+	groupNamePattern   = regexp.MustCompile(`^\S.{0,99}$`)
+
+	severities = map[string]bool{
+		"critical": true,
+		"warning":  true,
+		"info":     true,
+	}
+)
+
+// BuildPack validates a pack request and returns the pack with its checks
+func BuildPack(req *models.HealthCheckPackRequest) (*models.HealthCheckPack, error) {
+	pack := &models.HealthCheckPack{
+		Name:        strings.TrimSpace(req.Name),
+		Version:     strings.TrimSpace(req.Version),
+		Description: strings.TrimSpace(req.Description),
+		Checks:      []*models.VersionHealthCheck{},
+	}
+	if err := ValidatePackHeader(pack.Name, pack.Version); err != nil {
+		return nil, err
+	}
+	if len(req.Checks) > MaxChecks {
+		return nil, fmt.Errorf("a pack holds at most %d checks, got %d", MaxChecks, len(req.Checks))
+	}
+
+	names := make(map[string]bool, len(req.Checks))
+	for i, checkReq := range req.Checks {
+		check, err := BuildCheck(checkReq)
+		if err != nil {
+			return nil, fmt.Errorf("check %d: %v", i+1, err)
+		}
+		if names[check.CheckName] {
+			return nil, fmt.Errorf("check %d: duplicate check_name %q", i+1, check.CheckName)
+		}
+		names[check.CheckName] = true
+		pack.Checks = append(pack.Checks, check)
+	}
+	pack.CheckCount = len(pack.Checks)
+
+	return pack, nil
+}
+
+// ValidatePackHeader checks a pack's name and version
+func ValidatePackHeader(name, version string) error {
+	if !packNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid pack name %q: use lowercase letters, digits, '-' and '_'", name)
+	}
+	if !packVersionPattern.MatchString(version) {
+		return fmt.Errorf("invalid pack version %q: use letters, digits, '.', '+' and '-'", version)
+	}
+	return nil
+}
+
+// BuildCheck validates a custom check request and returns the check
+func BuildCheck(req *models.HealthCheckRequest) (*models.VersionHealthCheck, error) {
+	if req == nil {
+		return nil, fmt.Errorf("check is empty")
+	}
+	check := &models.VersionHealthCheck{
+		CheckName:      strings.TrimSpace(req.CheckName),
+		CheckQuery:     strings.TrimSpace(req.CheckQuery),
+		ExpectedResult: strings.TrimSpace(req.ExpectedResult),
+		Severity:       strings.ToLower(strings.TrimSpace(req.Severity)),
+		Description:    strings.TrimSpace(req.Description),
+		Remediation:    strings.TrimSpace(req.Remediation),
+		Category:       strings.ToLower(strings.TrimSpace(req.Category)),
+		MinVersion:     req.MinVersion,
+		MaxVersion:     req.MaxVersion,
+		AssertionType:  strings.TrimSpace(req.AssertionType),
+		AssertionValue: req.AssertionValue,
+	}
+	if check.Category == "" {
+		check.Category = defaultCategory
+	}
+
+	if !checkNamePattern.MatchString(check.CheckName) {
+		return nil, fmt.Errorf("invalid check_name %q: use lowercase letters, digits and '_'", check.CheckName)
+	}
+	if !severities[check.Severity] {
+		return nil, fmt.Errorf("invalid severity %q: must be critical, warning or info", req.Severity)
+	}
+	if !categoryPattern.MatchString(check.Category) {
+		return nil, fmt.Errorf("invalid category %q", req.Category)
+	}
+	if check.MinVersion < 0 || check.MaxVersion < 0 {
+		return nil, fmt.Errorf("versions cannot be negative")
+	}
+	if check.MaxVersion != 0 && check.MaxVersion < check.MinVersion {
+		return nil, fmt.Errorf("max_version %d is below min_version %d", check.MaxVersion, check.MinVersion)
+	}
+	if err := version_health.ValidateQuery(check.CheckQuery); err != nil {
+		return nil, err
+	}
+	if err := version_health.ValidateAssertion(check.AssertionType, check.AssertionValue); err != nil {
+		return nil, err
+	}
+	if check.AssertionType == models.HealthCheckAssertRowCountZero {
+		check.AssertionValue = ""
+	}
+
+	return check, nil
+}
+
+// BuildGroup validates a collector group request
+func BuildGroup(req *models.CollectorGroupRequest) (*models.CollectorGroup, error) {
+	group := &models.CollectorGroup{
+		Name:         strings.TrimSpace(req.Name),
+		Description:  strings.TrimSpace(req.Description),
+		CollectorIDs: req.CollectorIDs,
+	}
+	if !groupNamePattern.MatchString(group.Name) {
+		return nil, fmt.Errorf("group name must be 1 to 100 characters")
+	}
+	if group.CollectorIDs == nil {
+		group.CollectorIDs = []uuid.UUID{}
+	}
+	return group, nil
+}
+
+// BuildSchedule validates a schedule request, applying the defaults
+func BuildSchedule(packID int64, req *models.HealthCheckScheduleRequest) (*models.HealthCheckSchedule, error) {
+	schedule := &models.HealthCheckSchedule{
+		PackID:          packID,
+		GroupID:         req.GroupID,
+		IntervalSeconds: req.IntervalSeconds,
+		Enabled:         true,
+	}
+	if schedule.IntervalSeconds == 0 {
+		schedule.IntervalSeconds = DefaultInterval
+	}
+	if schedule.IntervalSeconds < MinInterval {
+		return nil, fmt.Errorf("interval_seconds must be at least %d", MinInterval)
+	}
+	if req.Enabled != nil {
+		schedule.Enabled = *req.Enabled
+	}
+	return schedule, nil
+}
@@ -0,0 +1,159 @@
+package check_packs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+func sslCheck() *models.HealthCheckRequest {
+	return &models.HealthCheckRequest{
+		CheckName:      "ssl_enabled",
+		Severity:       "Critical",
+		CheckQuery:     "SHOW ssl;",
+		AssertionType:  models.HealthCheckAssertEquals,
+		AssertionValue: "on",
+	}
+}
+
+func TestBuildPack(t *testing.T) {
+	pack, err := BuildPack(&models.HealthCheckPackRequest{
+		Name:    "security-hardening",
+		Version: "1.2.0",
+		Checks:  []*models.HealthCheckRequest{sslCheck()},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, pack.CheckCount)
+	check := pack.Checks[0]
+	assert.Equal(t, "critical", check.Severity)
+	assert.Equal(t, "configuration", check.Category)
+	assert.Equal(t, "SHOW ssl;", check.CheckQuery)
+
+	_, err = BuildPack(&models.HealthCheckPackRequest{
+		Name:    "security-hardening",
+		Version: "1.2.0",
+		Checks:  []*models.HealthCheckRequest{sslCheck(), sslCheck()},
+	})
+	assert.ErrorContains(t, err, `check 2: duplicate check_name "ssl_enabled"`)
+
+	_, err = BuildPack(&models.HealthCheckPackRequest{Name: "Security Hardening", Version: "1"})
+	assert.ErrorContains(t, err, "invalid pack name")
+
+	_, err = BuildPack(&models.HealthCheckPackRequest{Name: "security", Version: "v 1"})
+	assert.ErrorContains(t, err, "invalid pack version")
+}
+
+func TestBuildCheck_Invalid(t *testing.T) {
+	for want, mutate := range map[string]func(*models.HealthCheckRequest){
+		"invalid check_name":          func(r *models.HealthCheckRequest) { r.CheckName = "SSL on" },
+		"invalid severity":            func(r *models.HealthCheckRequest) { r.Severity = "high" },
+		"invalid category":            func(r *models.HealthCheckRequest) { r.Category = "Sec urity" },
+		"versions cannot be negative": func(r *models.HealthCheckRequest) { r.MinVersion = -1 },
+		"max_version 12 is below":     func(r *models.HealthCheckRequest) { r.MinVersion, r.MaxVersion = 14, 12 },
+		"must be a single statement":  func(r *models.HealthCheckRequest) { r.CheckQuery = "SHOW ssl; DROP TABLE t" },
+		"must be a SELECT":            func(r *models.HealthCheckRequest) { r.CheckQuery = "ALTER SYSTEM SET ssl = off" },
+		"invalid assertion_type":      func(r *models.HealthCheckRequest) { r.AssertionType = "contains" },
+		"needs a numeric":             func(r *models.HealthCheckRequest) { r.AssertionType = models.HealthCheckAssertLessThan },
+		"invalid regex assertion": func(r *models.HealthCheckRequest) {
+			r.AssertionType, r.AssertionValue = models.HealthCheckAssertRegex, "("
+		},
+	} {
+		req := sslCheck()
+		mutate(req)
+		_, err := BuildCheck(req)
+		assert.ErrorContains(t, err, want)
+	}
+
+	req := sslCheck()
+	req.AssertionType = models.HealthCheckAssertRowCountZero
+	check, err := BuildCheck(req)
+	require.NoError(t, err)
+	assert.Empty(t, check.AssertionValue)
+}
+
+func TestBuildSchedule(t *testing.T) {
+	schedule, err := BuildSchedule(3, &models.HealthCheckScheduleRequest{GroupID: 7})
+	require.NoError(t, err)
+	assert.Equal(t, &models.HealthCheckSchedule{PackID: 3, GroupID: 7, IntervalSeconds: DefaultInterval, Enabled: true}, schedule)
+
+	disabled := false
+	schedule, err = BuildSchedule(3, &models.HealthCheckScheduleRequest{GroupID: 7, IntervalSeconds: 300, Enabled: &disabled})
+	require.NoError(t, err)
+	assert.Equal(t, 300, schedule.IntervalSeconds)
+	assert.False(t, schedule.Enabled)
+
+	_, err = BuildSchedule(3, &models.HealthCheckScheduleRequest{GroupID: 7, IntervalSeconds: 30})
+	assert.Error(t, err)
+}
+
+func TestBuildGroup(t *testing.T) {
+	group, err := BuildGroup(&models.CollectorGroupRequest{Name: " production "})
+	require.NoError(t, err)
+	assert.Equal(t, "production", group.Name)
+	assert.NotNil(t, group.CollectorIDs)
+
+	_, err = BuildGroup(&models.CollectorGroupRequest{Name: "  "})
+	assert.Error(t, err)
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	doc := []byte(`
+name: cis-config
+version: 2024.1
+checks:
+  - name: log_connections
+    severity: info
+    category: security
+    min_version: 12
+    query: SHOW log_connections
+    assertion:
+      type: equals
+      value: "on"
+    remediation: Set log_connections = on.
+`)
+	pack, err := ParseYAML(doc)
+	require.NoError(t, err)
+	assert.Equal(t, "cis-config", pack.Name)
+	assert.Equal(t, "2024.1", pack.Version)
+	require.Len(t, pack.Checks, 1)
+	assert.Equal(t, 12, pack.Checks[0].MinVersion)
+	assert.Equal(t, "on", pack.Checks[0].AssertionValue)
+
+	out, err := ExportYAML(pack)
+	require.NoError(t, err)
+	again, err := ParseYAML(out)
+	require.NoError(t, err)
+	assert.Equal(t, pack, again)
+}
+
+func TestParseYAML_Invalid(t *testing.T) {
+	_, err := ParseYAML([]byte("name: x\nversion: 1\nchecks:\n  - name: a\n    assert: {type: equals}\n"))
+	assert.ErrorContains(t, err, "invalid pack document")
+
+	_, err = ParseYAML([]byte("name: x\nversion: 1\nchecks:\n  - name: a\n    severity: info\n    query: SELECT 1\n"))
+	assert.ErrorContains(t, err, "check 1: invalid assertion_type")
+
+	_, err = ParseYAML(make([]byte, MaxDocumentBytes+1))
+	assert.ErrorContains(t, err, "larger than")
+}
+
+// TestExamplePacks keeps the example packs shipped in config/check-packs
+// importable
+func TestExamplePacks(t *testing.T) {
+	paths, err := filepath.Glob("../../../../config/check-packs/*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		pack, err := ParseYAML(data)
+		require.NoError(t, err, path)
+		assert.NotEmpty(t, pack.Checks, path)
+	}
+}
@@ -0,0 +1,105 @@
+package check_packs
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// MaxDocumentBytes is the largest pack document accepted for import
+const MaxDocumentBytes = 1 << 20
+
+// packDocument is the YAML form of a check pack, the format packs are shared
+// in between tenants and kept in version control
+type packDocument struct {
+	Name        string          `yaml:"name"`
+	Version     string          `yaml:"version"`
+	Description string          `yaml:"description,omitempty"`
+	Checks      []checkDocument `yaml:"checks"`
+}
+
+type checkDocument struct {
+	Name           string            `yaml:"name"`
+	Description    string            `yaml:"description,omitempty"`
+	Category       string            `yaml:"category,omitempty"`
+	Severity       string            `yaml:"severity"`
+	MinVersion     int               `yaml:"min_version,omitempty"`
+	MaxVersion     int               `yaml:"max_version,omitempty"`
+	Query          string            `yaml:"query"`
+	Assertion      assertionDocument `yaml:"assertion"`
+	ExpectedResult string            `yaml:"expected_result,omitempty"`
+	Remediation    string            `yaml:"remediation,omitempty"`
+}
+
+type assertionDocument struct {
+	Type  string `yaml:"type"`
+	Value string `yaml:"value,omitempty"`
+}
+
+// ParseYAML reads a pack document and validates it like a pack request.
+// Unknown keys are rejected so that a typo does not silently drop an
+// assertion.
+func ParseYAML(data []byte) (*models.HealthCheckPack, error) {
+	if len(data) > MaxDocumentBytes {
+		return nil, fmt.Errorf("pack document is larger than %d bytes", MaxDocumentBytes)
+	}
+
+	var doc packDocument
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid pack document: %v", err)
+	}
+
+	req := &models.HealthCheckPackRequest{
+		Name:        doc.Name,
+		Version:     doc.Version,
+		Description: doc.Description,
+		Checks:      make([]*models.HealthCheckRequest, 0, len(doc.Checks)),
+	}
+	for _, c := range doc.Checks {
+		req.Checks = append(req.Checks, &models.HealthCheckRequest{
+			CheckName:      c.Name,
+			Description:    c.Description,
+			Category:       c.Category,
+			Severity:       c.Severity,
+			MinVersion:     c.MinVersion,
+			MaxVersion:     c.MaxVersion,
+			CheckQuery:     c.Query,
+			AssertionType:  c.Assertion.Type,
+			AssertionValue: c.Assertion.Value,
+			ExpectedResult: c.ExpectedResult,
+			Remediation:    c.Remediation,
+		})
+	}
+
+	return BuildPack(req)
+}
+
+// ExportYAML writes a pack and its checks as a pack document
+func ExportYAML(pack *models.HealthCheckPack) ([]byte, error) {
+	doc := packDocument{
+		Name:        pack.Name,
+		Version:     pack.Version,
+		Description: pack.Description,
+		Checks:      make([]checkDocument, 0, len(pack.Checks)),
+	}
+	for _, c := range pack.Checks {
+		doc.Checks = append(doc.Checks, checkDocument{
+			Name:           c.CheckName,
+			Description:    c.Description,
+			Category:       c.Category,
+			Severity:       c.Severity,
+			MinVersion:     c.MinVersion,
+			MaxVersion:     c.MaxVersion,
+			Query:          c.CheckQuery,
+			Assertion:      assertionDocument{Type: c.AssertionType, Value: c.AssertionValue},
+			ExpectedResult: c.ExpectedResult,
+			Remediation:    c.Remediation,
+		})
+	}
+
+	return yaml.Marshal(&doc)
+}
@@ -0,0 +1,54 @@
+package cluster_events
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+const (
+	defaultAlertWindowSeconds = 300
+	maxAlertWindowSeconds     = 86400
+)
+
+var eventTypes = map[string]bool{
+	models.ClusterEventFailover:       true,
+	models.ClusterEventSwitchover:     true,
+	models.ClusterEventPromotion:      true,
+	models.ClusterEventDemotion:       true,
+	models.ClusterEventTimelineChange: true,
+	models.ClusterEventUpstreamChange: true,
+}
+
+// ValidEventType reports whether t is a cluster event type
+func ValidEventType(t string) bool {
+	return eventTypes[t]
+}
+
+// ParseAlertCondition decodes and validates the condition of a cluster_event
+// alert rule, defaulting the window to five minutes
+func ParseAlertCondition(raw json.RawMessage) (*models.ClusterEventAlertCondition, error) {
+	var cond models.ClusterEventAlertCondition
+	if err := json.Unmarshal(raw, &cond); err != nil {
+		return nil, fmt.Errorf("unmarshal cluster event condition: %w", err)
+	}
+
+	if cond.CollectorID == uuid.Nil {
+		return nil, fmt.Errorf("cluster event condition needs a collector_id")
+	}
+	for _, t := range cond.EventTypes {
+		if !ValidEventType(t) {
+			return nil, fmt.Errorf("invalid event type %q", t)
+		}
+	}
+	if cond.WindowSeconds == 0 {
+		cond.WindowSeconds = defaultAlertWindowSeconds
+	}
+	if cond.WindowSeconds < 0 || cond.WindowSeconds > maxAlertWindowSeconds {
+		return nil, fmt.Errorf("window_seconds must be between 1 and %d", maxAlertWindowSeconds)
+	}
+
+	return &cond, nil
+}
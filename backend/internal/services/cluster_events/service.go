@@ -0,0 +1,322 @@
+package cluster_events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/replication_topology"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// EventName is the WebSocket event type of a detected cluster event
+const EventName = "cluster:event"
+
+// Store interface for node states and cluster events
+type Store interface {
+	GetReplicationNodes(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.ReplicationNodeSnapshot, error)
+	StoreNodeState(ctx context.Context, state *models.NodeState) error
+	GetPreviousNodeState(ctx context.Context, collectorID uuid.UUID, before time.Time) (*models.NodeState, error)
+	GetClusterNodeStates(ctx context.Context, systemIdentifier string, from, to time.Time) ([]*models.NodeState, error)
+	GetLatestWalReceiver(ctx context.Context, collectorID uuid.UUID, from, to time.Time) (*models.WalReceiver, error)
+	GetCollectorTenant(ctx context.Context, collectorID uuid.UUID) (*uuid.UUID, error)
+	GetCollectorInstanceIDs(ctx context.Context, collectorID uuid.UUID) ([]int, error)
+	StoreClusterEvent(ctx context.Context, event *models.ClusterEvent) error
+	ListClusterEvents(ctx context.Context, filter *models.ClusterEventFilter) ([]*models.ClusterEvent, error)
+}
+
+// Broadcaster sends WebSocket events to the connections with access to any
+// of the given instances
+type Broadcaster interface {
+	BroadcastToInstances(event string, data map[string]interface{}, instanceIDs []int)
+}
+
+// Service detects role, timeline and upstream changes between successive
+// node states and keeps the timeline of each cluster
+type Service struct {
+	store       Store
+	broadcaster Broadcaster
+	logger      *zap.Logger
+}
+
+// NewService creates a new cluster event service. The broadcaster may be nil.
+func NewService(store Store, broadcaster Broadcaster, logger *zap.Logger) *Service {
+	return &Service{
+		store:       store,
+		broadcaster: broadcaster,
+		logger:      logger,
+	}
+}
+
+// ClusterID identifies the physical cluster of a node: its system
+// identifier, or the collector itself when the identifier is unknown
+func ClusterID(state *models.NodeState) string {
+	if state.SystemIdentifier != "" {
+		return state.SystemIdentifier
+	}
+	return "collector:" + state.CollectorID.String()
+}
+
+// Observe stores a node state and records, then broadcasts, the events found
+// by comparing it to the collector's previous state. A standby's upstream is
+// taken from its latest WAL receiver. Events reach the connections with
+// access to the collector's instances.
+func (s *Service) Observe(ctx context.Context, state *models.NodeState) ([]*models.ClusterEvent, error) {
+	if state.InRecovery {
+		receiver, err := s.store.GetLatestWalReceiver(ctx, state.CollectorID, state.Time.Add(-replication_topology.Freshness), state.Time)
+		if err != nil {
+			s.logger.Error("Failed to find WAL receiver", zap.Error(err))
+			return nil, err
+		}
+		if receiver != nil {
+			state.UpstreamHost = receiver.SenderHost
+			state.UpstreamPort = receiver.SenderPort
+		}
+	}
+
+	prev, err := s.store.GetPreviousNodeState(ctx, state.CollectorID, state.Time)
+	if err != nil {
+		s.logger.Error("Failed to find previous node state", zap.Error(err))
+		return nil, err
+	}
+	if err := s.store.StoreNodeState(ctx, state); err != nil {
+		s.logger.Error("Failed to store node state", zap.Error(err))
+		return nil, err
+	}
+	if prev == nil {
+		return nil, nil
+	}
+
+	changes := Detect(prev, state)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	tenantID, err := s.store.GetCollectorTenant(ctx, state.CollectorID)
+	if err != nil {
+		s.logger.Error("Failed to find collector tenant", zap.Error(err))
+		return nil, err
+	}
+	var instanceIDs []int
+	if s.broadcaster != nil {
+		if instanceIDs, err = s.store.GetCollectorInstanceIDs(ctx, state.CollectorID); err != nil {
+			s.logger.Error("Failed to find collector instances", zap.Error(err))
+			return nil, err
+		}
+	}
+	var before, after *models.FleetTopology
+	if tenantID != nil {
+		if before, err = s.clusterTopology(ctx, *tenantID, state.CollectorID, prev.Time); err != nil {
+			return nil, err
+		}
+		if after, err = s.clusterTopology(ctx, *tenantID, state.CollectorID, state.Time); err != nil {
+			return nil, err
+		}
+	}
+
+	events := make([]*models.ClusterEvent, 0, len(changes))
+	for _, eventType := range changes {
+		event := &models.ClusterEvent{
+			TenantID:         tenantID,
+			ClusterID:        ClusterID(state),
+			CollectorID:      state.CollectorID,
+			EventType:        eventType,
+			OccurredAt:       state.Time,
+			PreviousSampleAt: prev.Time,
+			Before:           prev,
+			After:            state,
+			BeforeTopology:   before,
+			AfterTopology:    after,
+		}
+		if eventType == models.ClusterEventPromotion {
+			if err := s.classifyPromotion(ctx, event); err != nil {
+				return nil, err
+			}
+		}
+		event.Summary = Summarize(event, nodeName(after, state.CollectorID), nodeName(before, derefID(event.PreviousPrimary)))
+
+		if err := s.store.StoreClusterEvent(ctx, event); err != nil {
+			s.logger.Error("Failed to store cluster event", zap.Error(err))
+			return nil, err
+		}
+		s.logger.Warn("Cluster event detected",
+			zap.String("event_type", event.EventType),
+			zap.String("cluster_id", event.ClusterID),
+			zap.String("collector_id", event.CollectorID.String()),
+			zap.String("summary", event.Summary))
+		s.broadcast(event, instanceIDs)
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// Detect compares two successive states of a node. A change of system
+// identifier means the node was rebuilt from another cluster, which says
+// nothing about roles.
+func Detect(prev, cur *models.NodeState) []string {
+	if prev.SystemIdentifier != "" && cur.SystemIdentifier != "" && prev.SystemIdentifier != cur.SystemIdentifier {
+		return nil
+	}
+
+	var changes []string
+	switch {
+	case prev.InRecovery && !cur.InRecovery:
+		changes = append(changes, models.ClusterEventPromotion)
+	case !prev.InRecovery && cur.InRecovery:
+		changes = append(changes, models.ClusterEventDemotion)
+	case prev.TimelineID != 0 && cur.TimelineID != 0 && prev.TimelineID != cur.TimelineID:
+		changes = append(changes, models.ClusterEventTimelineChange)
+	}
+
+	if prev.InRecovery && cur.InRecovery && prev.UpstreamHost != "" && cur.UpstreamHost != "" &&
+		(prev.UpstreamHost != cur.UpstreamHost || prev.UpstreamPort != cur.UpstreamPort) {
+		changes = append(changes, models.ClusterEventUpstreamChange)
+	}
+
+	return changes
+}
+
+// classifyPromotion tells a switchover, where the previous primary of the
+// cluster now reports being in recovery, from a failover, where it stopped
+// reporting or still reports as primary. Without a known previous primary
+// the event stays a promotion.
+func (s *Service) classifyPromotion(ctx context.Context, event *models.ClusterEvent) error {
+	systemIdentifier := event.After.SystemIdentifier
+	if systemIdentifier == "" {
+		return nil
+	}
+
+	before, err := s.store.GetClusterNodeStates(ctx, systemIdentifier, event.PreviousSampleAt.Add(-replication_topology.Freshness), event.PreviousSampleAt)
+	if err != nil {
+		s.logger.Error("Failed to load cluster node states", zap.Error(err))
+		return err
+	}
+	var previousPrimary *models.NodeState
+	for _, state := range before {
+		if state.CollectorID != event.CollectorID && !state.InRecovery {
+			previousPrimary = state
+			break
+		}
+	}
+	if previousPrimary == nil {
+		return nil
+	}
+	event.PreviousPrimary = &previousPrimary.CollectorID
+
+	now, err := s.store.GetClusterNodeStates(ctx, systemIdentifier, event.OccurredAt.Add(-replication_topology.Freshness), event.OccurredAt)
+	if err != nil {
+		s.logger.Error("Failed to load cluster node states", zap.Error(err))
+		return err
+	}
+	event.EventType = models.ClusterEventFailover
+	for _, state := range now {
+		if state.CollectorID == previousPrimary.CollectorID && state.InRecovery {
+			event.EventType = models.ClusterEventSwitchover
+		}
+	}
+	return nil
+}
+
+// Summarize describes an event for timelines and notifications
+func Summarize(event *models.ClusterEvent, name, previousPrimaryName string) string {
+	before, after := event.Before, event.After
+	switch event.EventType {
+	case models.ClusterEventFailover:
+		return fmt.Sprintf("Failover: %s was promoted to primary on timeline %d; previous primary %s stopped reporting as primary",
+			name, after.TimelineID, previousPrimaryName)
+	case models.ClusterEventSwitchover:
+		return fmt.Sprintf("Switchover: %s was promoted to primary on timeline %d; previous primary %s is now a standby",
+			name, after.TimelineID, previousPrimaryName)
+	case models.ClusterEventPromotion:
+		return fmt.Sprintf("%s was promoted to primary on timeline %d", name, after.TimelineID)
+	case models.ClusterEventDemotion:
+		return fmt.Sprintf("%s was a primary on timeline %d and restarted as a standby", name, before.TimelineID)
+	case models.ClusterEventTimelineChange:
+		return fmt.Sprintf("%s switched from timeline %d to %d", name, before.TimelineID, after.TimelineID)
+	case models.ClusterEventUpstreamChange:
+		return fmt.Sprintf("%s now replicates from %s instead of %s", name,
+			hostPort(after.UpstreamHost, after.UpstreamPort), hostPort(before.UpstreamHost, before.UpstreamPort))
+	default:
+		return fmt.Sprintf("%s: %s", event.EventType, name)
+	}
+}
+
+// ListEvents returns cluster events, most recent first
+func (s *Service) ListEvents(ctx context.Context, filter *models.ClusterEventFilter) ([]*models.ClusterEvent, error) {
+	events, err := s.store.ListClusterEvents(ctx, filter)
+	if err != nil {
+		s.logger.Error("Failed to list cluster events", zap.Error(err))
+		return nil, err
+	}
+	return events, nil
+}
+
+// ClusterIDOf returns the cluster a collector reported being part of last
+func (s *Service) ClusterIDOf(ctx context.Context, collectorID uuid.UUID, at time.Time) (string, error) {
+	state, err := s.store.GetPreviousNodeState(ctx, collectorID, at)
+	if err != nil {
+		s.logger.Error("Failed to find node state", zap.Error(err))
+		return "", err
+	}
+	if state == nil {
+		return ClusterID(&models.NodeState{CollectorID: collectorID}), nil
+	}
+	return ClusterID(state), nil
+}
+
+func (s *Service) clusterTopology(ctx context.Context, tenantID, collectorID uuid.UUID, at time.Time) (*models.FleetTopology, error) {
+	snapshots, err := s.store.GetReplicationNodes(ctx, tenantID, at.Add(-replication_topology.Freshness), at)
+	if err != nil {
+		s.logger.Error("Failed to load replication nodes", zap.Error(err))
+		return nil, err
+	}
+	return replication_topology.ClusterOf(replication_topology.Build(tenantID, snapshots, at), collectorID), nil
+}
+
+func (s *Service) broadcast(event *models.ClusterEvent, instanceIDs []int) {
+	if s.broadcaster == nil {
+		return
+	}
+	data := map[string]interface{}{
+		"id":           event.ID,
+		"cluster_id":   event.ClusterID,
+		"collector_id": event.CollectorID.String(),
+		"event_type":   event.EventType,
+		"occurred_at":  event.OccurredAt,
+		"summary":      event.Summary,
+	}
+	if event.TenantID != nil {
+		data["tenant_id"] = event.TenantID.String()
+	}
+	s.broadcaster.BroadcastToInstances(EventName, data, instanceIDs)
+}
+
+// nodeName is the name of a node in a cluster topology, or its ID when the
+// topology does not have it
+func nodeName(topology *models.FleetTopology, collectorID uuid.UUID) string {
+	if topology != nil {
+		for _, n := range topology.Nodes {
+			if n.CollectorID != nil && *n.CollectorID == collectorID {
+				return n.Name
+			}
+		}
+	}
+	return collectorID.String()
+}
+
+func derefID(id *uuid.UUID) uuid.UUID {
+	if id == nil {
+		return uuid.Nil
+	}
+	return *id
+}
+
+func hostPort(host string, port int) string {
+	if port == 0 {
+		return host
+	}
+	return fmt.Sprintf("%s:%d", host, port)
+}
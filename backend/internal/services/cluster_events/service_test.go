@@ -0,0 +1,268 @@
+package cluster_events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockClusterStore is a mock implementation for testing. Replication nodes
+// and cluster node states are returned by the end of the requested window.
+type mockClusterStore struct {
+	nodes         map[time.Time][]*models.ReplicationNodeSnapshot
+	previous      *models.NodeState
+	clusterStates map[time.Time][]*models.NodeState
+	receiver      *models.WalReceiver
+	tenantID      *uuid.UUID
+	instanceIDs   []int
+	err           error
+
+	stored []*models.NodeState
+	events []*models.ClusterEvent
+	filter *models.ClusterEventFilter
+}
+
+func (m *mockClusterStore) GetReplicationNodes(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.ReplicationNodeSnapshot, error) {
+	return m.nodes[to], m.err
+}
+
+func (m *mockClusterStore) StoreNodeState(ctx context.Context, state *models.NodeState) error {
+	m.stored = append(m.stored, state)
+	return m.err
+}
+
+func (m *mockClusterStore) GetPreviousNodeState(ctx context.Context, collectorID uuid.UUID, before time.Time) (*models.NodeState, error) {
+	return m.previous, m.err
+}
+
+func (m *mockClusterStore) GetClusterNodeStates(ctx context.Context, systemIdentifier string, from, to time.Time) ([]*models.NodeState, error) {
+	return m.clusterStates[to], m.err
+}
+
+func (m *mockClusterStore) GetLatestWalReceiver(ctx context.Context, collectorID uuid.UUID, from, to time.Time) (*models.WalReceiver, error) {
+	return m.receiver, m.err
+}
+
+func (m *mockClusterStore) GetCollectorTenant(ctx context.Context, collectorID uuid.UUID) (*uuid.UUID, error) {
+	return m.tenantID, m.err
+}
+
+func (m *mockClusterStore) GetCollectorInstanceIDs(ctx context.Context, collectorID uuid.UUID) ([]int, error) {
+	return m.instanceIDs, m.err
+}
+
+func (m *mockClusterStore) StoreClusterEvent(ctx context.Context, event *models.ClusterEvent) error {
+	event.ID = int64(len(m.events) + 1)
+	m.events = append(m.events, event)
+	return m.err
+}
+
+func (m *mockClusterStore) ListClusterEvents(ctx context.Context, filter *models.ClusterEventFilter) ([]*models.ClusterEvent, error) {
+	m.filter = filter
+	return m.events, m.err
+}
+
+// mockBroadcaster records broadcast events and the instances they reach
+type mockBroadcaster struct {
+	events      []map[string]interface{}
+	instanceIDs [][]int
+}
+
+func (m *mockBroadcaster) BroadcastToInstances(event string, data map[string]interface{}, instanceIDs []int) {
+	if event == EventName {
+		m.events = append(m.events, data)
+		m.instanceIDs = append(m.instanceIDs, instanceIDs)
+	}
+}
+
+const systemIdentifier = "7312345678901234567"
+
+func state(collectorID uuid.UUID, at time.Time, timeline int64, inRecovery bool) *models.NodeState {
+	return &models.NodeState{Time: at, CollectorID: collectorID, SystemIdentifier: systemIdentifier, TimelineID: timeline, InRecovery: inRecovery}
+}
+
+// TestDetect compares successive states
+func TestDetect(t *testing.T) {
+	id := uuid.New()
+	at := time.Now()
+	standby := state(id, at, 1, true)
+	standby.UpstreamHost = "db1"
+
+	assert.Equal(t, []string{models.ClusterEventPromotion}, Detect(standby, state(id, at, 2, false)))
+	assert.Equal(t, []string{models.ClusterEventDemotion}, Detect(state(id, at, 1, false), state(id, at, 2, true)))
+	assert.Equal(t, []string{models.ClusterEventTimelineChange}, Detect(state(id, at, 1, false), state(id, at, 2, false)))
+	assert.Empty(t, Detect(standby, standby))
+
+	moved := state(id, at, 2, true)
+	moved.UpstreamHost = "db3"
+	assert.Equal(t, []string{models.ClusterEventTimelineChange, models.ClusterEventUpstreamChange}, Detect(standby, moved))
+
+	// A reconnecting standby without a WAL receiver has no upstream to compare
+	assert.Empty(t, Detect(standby, state(id, at, 1, true)))
+
+	rebuilt := state(id, at, 1, false)
+	rebuilt.SystemIdentifier = "7399999999999999999"
+	assert.Empty(t, Detect(standby, rebuilt), "another cluster")
+}
+
+// TestObserve_FirstState stores the state without events
+func TestObserve_FirstState(t *testing.T) {
+	store := &mockClusterStore{}
+	service := NewService(store, nil, zap.NewNop())
+
+	events, err := service.Observe(context.Background(), state(uuid.New(), time.Now(), 1, false))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Len(t, store.stored, 1)
+}
+
+// TestObserve_Failover classifies a promotion whose previous primary stopped
+// reporting, records both topologies and broadcasts the event
+func TestObserve_Failover(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	prevAt := at.Add(-time.Minute)
+	primaryID, standbyID, tenantID := uuid.New(), uuid.New(), uuid.New()
+
+	snapshot := func(id uuid.UUID, name, hostname string) *models.ReplicationNodeSnapshot {
+		return &models.ReplicationNodeSnapshot{CollectorID: id, Name: name, Hostname: hostname,
+			Senders: []*models.ReplicationStatus{}, Subscriptions: []*models.LogicalSubscription{}}
+	}
+	primary := snapshot(primaryID, "orders-primary", "db1")
+	standby := snapshot(standbyID, "orders-standby", "db2")
+	standby.WalReceiver = &models.WalReceiver{Time: prevAt, SenderHost: "db1"}
+	promoted := snapshot(standbyID, "orders-standby", "db2")
+	promoted.WalReceiver = standby.WalReceiver
+	promoted.State = state(standbyID, at, 2, false)
+
+	store := &mockClusterStore{
+		nodes: map[time.Time][]*models.ReplicationNodeSnapshot{
+			prevAt: {primary, standby},
+			at:     {primary, promoted},
+		},
+		previous: state(standbyID, prevAt, 1, true),
+		clusterStates: map[time.Time][]*models.NodeState{
+			prevAt: {state(primaryID, prevAt, 1, false), state(standbyID, prevAt, 1, true)},
+			at:     {state(standbyID, at, 2, false)},
+		},
+		tenantID:    &tenantID,
+		instanceIDs: []int{3, 4},
+	}
+	broadcaster := &mockBroadcaster{}
+	service := NewService(store, broadcaster, zap.NewNop())
+
+	events, err := service.Observe(context.Background(), state(standbyID, at, 2, false))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	event := events[0]
+	assert.Equal(t, models.ClusterEventFailover, event.EventType)
+	assert.Equal(t, systemIdentifier, event.ClusterID)
+	assert.Equal(t, &tenantID, event.TenantID)
+	assert.Equal(t, at, event.OccurredAt)
+	assert.Equal(t, prevAt, event.PreviousSampleAt)
+	require.NotNil(t, event.PreviousPrimary)
+	assert.Equal(t, primaryID, *event.PreviousPrimary)
+	assert.Equal(t, "Failover: orders-standby was promoted to primary on timeline 2; previous primary orders-primary stopped reporting as primary", event.Summary)
+
+	// Before, the standby replicated from the primary; after, its state drops
+	// the stale WAL receiver and it is a cluster of its own
+	require.NotNil(t, event.BeforeTopology)
+	require.NotNil(t, event.AfterTopology)
+	assert.Len(t, event.BeforeTopology.Nodes, 2)
+	require.Len(t, event.AfterTopology.Nodes, 1)
+	assert.Equal(t, models.TopologyRolePrimary, event.AfterTopology.Nodes[0].Role)
+
+	require.Len(t, broadcaster.events, 1)
+	assert.Equal(t, models.ClusterEventFailover, broadcaster.events[0]["event_type"])
+	assert.Equal(t, tenantID.String(), broadcaster.events[0]["tenant_id"])
+	assert.Equal(t, []int{3, 4}, broadcaster.instanceIDs[0], "only the collector's instances")
+}
+
+// TestObserve_Switchover classifies a promotion whose previous primary now
+// reports being in recovery
+func TestObserve_Switchover(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	prevAt := at.Add(-time.Minute)
+	primaryID, standbyID := uuid.New(), uuid.New()
+
+	store := &mockClusterStore{
+		previous: state(standbyID, prevAt, 1, true),
+		clusterStates: map[time.Time][]*models.NodeState{
+			prevAt: {state(primaryID, prevAt, 1, false)},
+			at:     {state(primaryID, at.Add(-10*time.Second), 2, true), state(standbyID, at, 2, false)},
+		},
+	}
+	service := NewService(store, nil, zap.NewNop())
+
+	events, err := service.Observe(context.Background(), state(standbyID, at, 2, false))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.ClusterEventSwitchover, events[0].EventType)
+	assert.Nil(t, events[0].TenantID)
+	assert.Nil(t, events[0].BeforeTopology, "no tenant, no topology")
+	assert.Contains(t, events[0].Summary, "is now a standby")
+}
+
+// TestObserve_UpstreamChange takes the upstream from the WAL receiver
+func TestObserve_UpstreamChange(t *testing.T) {
+	at := time.Now()
+	id := uuid.New()
+	previous := state(id, at.Add(-time.Minute), 1, true)
+	previous.UpstreamHost, previous.UpstreamPort = "db1", 5432
+
+	store := &mockClusterStore{
+		previous: previous,
+		receiver: &models.WalReceiver{SenderHost: "db3", SenderPort: 5432},
+	}
+	service := NewService(store, nil, zap.NewNop())
+
+	events, err := service.Observe(context.Background(), state(id, at, 1, true))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.ClusterEventUpstreamChange, events[0].EventType)
+	assert.Equal(t, id.String()+" now replicates from db3:5432 instead of db1:5432", events[0].Summary)
+	assert.Equal(t, "db3", store.stored[0].UpstreamHost)
+}
+
+// TestClusterIDOf falls back to the collector without a system identifier
+func TestClusterIDOf(t *testing.T) {
+	id := uuid.New()
+	store := &mockClusterStore{}
+	service := NewService(store, nil, zap.NewNop())
+
+	clusterID, err := service.ClusterIDOf(context.Background(), id, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "collector:"+id.String(), clusterID)
+
+	store.previous = state(id, time.Now(), 1, false)
+	clusterID, err = service.ClusterIDOf(context.Background(), id, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, systemIdentifier, clusterID)
+}
+
+// TestParseAlertCondition validates event types and defaults the window
+func TestParseAlertCondition(t *testing.T) {
+	collectorID := uuid.New()
+	cond, err := ParseAlertCondition(json.RawMessage(`{"collector_id":"` + collectorID.String() + `","event_types":["failover","switchover"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, collectorID, cond.CollectorID)
+	assert.Equal(t, []string{"failover", "switchover"}, cond.EventTypes)
+	assert.Equal(t, 300, cond.WindowSeconds)
+
+	for name, raw := range map[string]string{
+		"collector":  `{"event_types":["failover"]}`,
+		"event type": `{"collector_id":"` + collectorID.String() + `","event_types":["crash"]}`,
+		"window":     `{"collector_id":"` + collectorID.String() + `","window_seconds":-1}`,
+		"json":       `{`,
+	} {
+		_, err := ParseAlertCondition(json.RawMessage(raw))
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,192 @@
+package config_advisor
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// Storage kinds the random I/O settings are recommended for
+const (
+	StorageSSD = "ssd"
+	StorageHDD = "hdd"
+)
+
+const (
+	// defaultMaxConnections is assumed when the collector did not report
+	// max_connections
+	defaultMaxConnections = 100
+
+	// workMemOperations is how many sort or hash operations a connection is
+	// assumed to run at once when sizing work_mem
+	workMemOperations = 3
+
+	minWorkMem                = 4 * mB
+	minMaintenanceWorkMem     = 64 * mB
+	maxMaintenanceWorkMem     = 2 * gB
+	minMaxWalSize             = 1 * gB
+	maxMaxWalSize             = 16 * gB
+	maxWalSizeShareOfDisk     = 0.02
+	minWorkerProcesses        = 8
+	ssdRandomPageCost         = 1.1
+	hddRandomPageCost         = 4.0
+	ssdEffectiveIOConcurrency = 200
+	hddEffectiveIOConcurrency = 2
+)
+
+// target is a recommended value of a setting with the range around it that
+// still counts as fine, as ratios of the recommendation
+type target struct {
+	value  float64 // bytes for memory settings
+	memory bool
+	low    float64
+	high   float64
+	basis  string
+}
+
+// Recommend reviews an instance's settings against values derived from its
+// host: memory settings from RAM, parallelism from CPU cores, WAL size from
+// disk and random I/O costs from the storage kind. Settings the instance did
+// not report, and recommendations whose host facts are unknown, are left out
+// and explained in the notes.
+func Recommend(settings []*models.PgSetting, inventory *models.HostInventory, storage string) ([]*models.SettingRecommendation, []string) {
+	byName := make(map[string]*models.PgSetting, len(settings))
+	for _, s := range settings {
+		byName[s.Name] = s
+	}
+
+	recommendations := []*models.SettingRecommendation{}
+	notes := []string{}
+	add := func(name string, t target) {
+		s, ok := byName[name]
+		if !ok {
+			notes = append(notes, fmt.Sprintf("%s was not reported by the collector", name))
+			return
+		}
+		if rec := compare(s, t); rec != nil {
+			recommendations = append(recommendations, rec)
+		}
+	}
+
+	var ram, disk int64
+	var cores int
+	if inventory != nil {
+		ram = inventory.MemoryTotalMb * mB
+		disk = inventory.DiskTotalGb * gB
+		cores = inventory.CpuCores
+	}
+
+	if ram > 0 {
+		sharedBuffers := roundMB(ram / 4)
+		add("shared_buffers", target{
+			value: float64(sharedBuffers), memory: true, low: 0.75, high: 1.5,
+			basis: fmt.Sprintf("25%% of the host's %s of RAM", formatBytes(roundMB(ram))),
+		})
+		add("effective_cache_size", target{
+			value: float64(roundMB(ram * 3 / 4)), memory: true, low: 0.75, high: 1.34,
+			basis: fmt.Sprintf("75%% of the host's %s of RAM", formatBytes(roundMB(ram))),
+		})
+
+		// Size work_mem from the memory shared_buffers leaves, as configured
+		// if known
+		if current, ok := settingBytes(byName["shared_buffers"]); ok {
+			sharedBuffers = current
+		}
+		connections := float64(defaultMaxConnections)
+		if n, ok := settingNumber(byName["max_connections"]); ok && n > 0 {
+			connections = n
+		}
+		workMem := int64(float64(ram-sharedBuffers) / (connections * workMemOperations))
+		workMem = roundMB(int64(math.Max(float64(workMem), float64(minWorkMem))))
+		add("work_mem", target{
+			value: float64(workMem), memory: true, low: 0.5, high: 2,
+			basis: fmt.Sprintf("RAM left by shared_buffers spread over %s connections running %d operations each",
+				formatNumber(connections), workMemOperations),
+		})
+
+		maintenance := roundMB(int64(math.Min(math.Max(float64(ram/16), float64(minMaintenanceWorkMem)), float64(maxMaintenanceWorkMem))))
+		add("maintenance_work_mem", target{
+			value: float64(maintenance), memory: true, low: 0.5, high: 2,
+			basis: "1/16 of RAM, between 64MB and 2GB",
+		})
+	} else {
+		notes = append(notes, "Memory settings need the host's RAM from host inventory, which has not been collected")
+	}
+
+	if disk > 0 {
+		walSize := roundMB(int64(math.Min(math.Max(float64(disk)*maxWalSizeShareOfDisk, float64(minMaxWalSize)), float64(maxMaxWalSize))))
+		add("max_wal_size", target{
+			value: float64(walSize), memory: true, low: 0.5, high: 2,
+			basis: fmt.Sprintf("2%% of the host's %dGB disk, between 1GB and 16GB", disk/gB),
+		})
+	} else {
+		notes = append(notes, "max_wal_size needs the host's disk size from host inventory, which has not been collected")
+	}
+
+	if storage == StorageHDD {
+		add("random_page_cost", target{value: hddRandomPageCost, low: 0.8, high: 1.25, basis: "rotational storage"})
+		add("effective_io_concurrency", target{value: hddEffectiveIOConcurrency, low: 0.5, high: 2, basis: "rotational storage"})
+	} else {
+		add("random_page_cost", target{value: ssdRandomPageCost, low: 0.8, high: 1.25, basis: "SSD storage"})
+		add("effective_io_concurrency", target{value: ssdEffectiveIOConcurrency, low: 0.5, high: 2, basis: "SSD storage"})
+	}
+
+	if cores > 0 {
+		add("max_worker_processes", target{
+			value: math.Max(float64(cores), minWorkerProcesses), low: 1, high: 4,
+			basis: fmt.Sprintf("one per CPU core of the host's %d, at least %d", cores, minWorkerProcesses),
+		})
+		add("max_parallel_workers", target{
+			value: float64(cores), low: 0.5, high: 1.5,
+			basis: fmt.Sprintf("one per CPU core of the host's %d", cores),
+		})
+	} else {
+		notes = append(notes, "Parallelism settings need the host's CPU cores from host inventory, which have not been collected")
+	}
+
+	return recommendations, notes
+}
+
+// compare judges a setting against its target; settings whose value does
+// not parse are left out
+func compare(s *models.PgSetting, t target) *models.SettingRecommendation {
+	var current float64
+	var recommended string
+	if t.memory {
+		b, ok := settingBytes(s)
+		if !ok {
+			return nil
+		}
+		current = float64(b)
+		recommended = formatBytes(int64(t.value))
+	} else {
+		n, ok := settingNumber(s)
+		if !ok {
+			return nil
+		}
+		current = n
+		recommended = formatNumber(t.value)
+	}
+
+	rec := &models.SettingRecommendation{
+		Name:             s.Name,
+		CurrentValue:     displayValue(s),
+		RecommendedValue: recommended,
+		Status:           models.SettingStatusOK,
+		Why:              reason(s.Name),
+		Basis:            t.basis,
+		RequiresRestart:  requiresRestart(s),
+		PendingRestart:   s.PendingRestart,
+	}
+	switch {
+	case current < t.value*t.low:
+		rec.Status = models.SettingStatusIncrease
+	case current > t.value*t.high:
+		rec.Status = models.SettingStatusDecrease
+	}
+	if rec.Status != models.SettingStatusOK {
+		rec.Statement = fmt.Sprintf("ALTER SYSTEM SET %s = %s;", s.Name, quoteLiteral(recommended))
+	}
+	return rec
+}
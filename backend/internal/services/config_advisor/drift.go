@@ -0,0 +1,133 @@
+package config_advisor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// Diff returns the changes from one snapshot of a collector's settings to the
+// next, ordered by setting name. Each setting of next gets the time its value
+// last changed: now when it changed, its previous time otherwise.
+func Diff(collectorID uuid.UUID, prev, next []*models.PgSetting, at time.Time) []*models.PgSettingChange {
+	before := make(map[string]*models.PgSetting, len(prev))
+	for _, s := range prev {
+		before[s.Name] = s
+	}
+
+	changes := []*models.PgSettingChange{}
+	seen := make(map[string]bool, len(next))
+	for _, s := range next {
+		seen[s.Name] = true
+		old, ok := before[s.Name]
+		switch {
+		case !ok:
+			value := s.Setting
+			changes = append(changes, &models.PgSettingChange{
+				CollectorID: collectorID, Name: s.Name, NewValue: &value, Unit: s.Unit, ChangedAt: at,
+			})
+			s.ChangedAt = at
+		case old.Setting != s.Setting:
+			oldValue, newValue := old.Setting, s.Setting
+			changes = append(changes, &models.PgSettingChange{
+				CollectorID: collectorID, Name: s.Name, OldValue: &oldValue, NewValue: &newValue, Unit: s.Unit, ChangedAt: at,
+			})
+			s.ChangedAt = at
+		default:
+			s.ChangedAt = old.ChangedAt
+		}
+	}
+	for _, s := range prev {
+		if !seen[s.Name] {
+			oldValue := s.Setting
+			changes = append(changes, &models.PgSettingChange{
+				CollectorID: collectorID, Name: s.Name, OldValue: &oldValue, Unit: s.Unit, ChangedAt: at,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// Drift compares the settings of instances that should agree and returns
+// the settings whose values differ, most severe first. Settings expected to
+// differ per node, values of the collector's own session and settings that
+// only some instances report (e.g. across major versions) are not drift.
+// In a cluster, a setting a hot standby needs at least its primary's value
+// of is critical; elsewhere, settings that need a restart to fix are
+// warnings and the rest informational.
+func Drift(scope string, members []*models.CollectorSettings) []*models.SettingDrift {
+	type reported struct {
+		setting *models.PgSetting
+		member  *models.CollectorSettings
+	}
+	byName := map[string][]reported{}
+	reporting := 0
+	for _, m := range members {
+		if len(m.Settings) == 0 {
+			continue
+		}
+		reporting++
+		for _, s := range m.Settings {
+			if nodeSettings[s.Name] || sessionSources[s.Source] {
+				continue
+			}
+			byName[s.Name] = append(byName[s.Name], reported{setting: s, member: m})
+		}
+	}
+
+	drift := []*models.SettingDrift{}
+	for name, values := range byName {
+		if len(values) < reporting || len(values) < 2 {
+			continue
+		}
+		distinct := map[string]bool{}
+		for _, v := range values {
+			distinct[v.setting.Setting] = true
+		}
+		if len(distinct) < 2 {
+			continue
+		}
+
+		first := values[0].setting
+		d := &models.SettingDrift{
+			Name:            name,
+			Unit:            first.Unit,
+			Severity:        "info",
+			Why:             reason(name),
+			RequiresRestart: requiresRestart(first),
+			Values:          make([]*models.SettingValue, 0, len(values)),
+		}
+		switch {
+		case scope == models.SettingDriftScopeCluster && hotStandbyMinimums[name]:
+			d.Severity = "critical"
+		case d.RequiresRestart:
+			d.Severity = "warning"
+		}
+		for _, v := range values {
+			d.Values = append(d.Values, &models.SettingValue{
+				CollectorID:   v.member.CollectorID,
+				CollectorName: v.member.CollectorName,
+				Value:         displayValue(v.setting),
+			})
+		}
+		drift = append(drift, d)
+	}
+
+	sort.Slice(drift, func(i, j int) bool {
+		if ri, rj := severityRank[drift[i].Severity], severityRank[drift[j].Severity]; ri != rj {
+			return ri < rj
+		}
+		return drift[i].Name < drift[j].Name
+	})
+	return drift
+}
+
+var severityRank = map[string]int{
+	"critical": 0,
+	"warning":  1,
+	"info":     2,
+}
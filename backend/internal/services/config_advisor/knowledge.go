@@ -0,0 +1,125 @@
+package config_advisor
+
+import (
+	"strings"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// contextPostmaster is the pg_settings context of settings that only take
+// effect at server start
+const contextPostmaster = "postmaster"
+
+// restartSettings need a restart; used when the collector did not report a
+// setting's context
+var restartSettings = map[string]bool{
+	"shared_buffers":            true,
+	"max_connections":           true,
+	"max_worker_processes":      true,
+	"max_wal_senders":           true,
+	"max_replication_slots":     true,
+	"max_prepared_transactions": true,
+	"max_locks_per_transaction": true,
+	"wal_level":                 true,
+	"wal_buffers":               true,
+	"huge_pages":                true,
+	"shared_preload_libraries":  true,
+	"archive_mode":              true,
+	"hot_standby":               true,
+	"listen_addresses":          true,
+	"port":                      true,
+}
+
+// settingReasons explain why a setting matters
+var settingReasons = map[string]string{
+	"shared_buffers":                      "PostgreSQL's own page cache. Too small and hot data is read through the kernel on every access; too large and it competes with the OS cache and lengthens checkpoints.",
+	"effective_cache_size":                "The planner's estimate of how much data the OS and PostgreSQL can cache together. Set too low, the planner avoids index scans it would profit from. It allocates nothing.",
+	"work_mem":                            "Memory per sort or hash operation, which a single query can use several times over. Too small and sorts and hashes spill to temporary files; too large and concurrent queries can exhaust memory.",
+	"maintenance_work_mem":                "Memory for VACUUM, CREATE INDEX and foreign key checks. More lets vacuum process dead tuples in fewer index passes and builds indexes faster.",
+	"max_wal_size":                        "WAL written between automatic checkpoints. Too small and checkpoints run back to back, writing full-page images after each one; larger values cost disk space and crash recovery time.",
+	"random_page_cost":                    "The planner's cost of a random page read relative to a sequential one. The default of 4 models spinning disks; on SSDs it makes the planner pick sequential scans over cheaper index scans.",
+	"effective_io_concurrency":            "How many concurrent reads bitmap heap scans issue. SSDs and arrays serve many requests in parallel; a value of 1 leaves them idle.",
+	"max_worker_processes":                "Background worker slots shared by parallel query, logical replication and extensions. Once they are used up, parallel plans run without workers.",
+	"max_parallel_workers":                "Workers parallel queries may use at once. More than the CPU cores oversubscribes the host; far fewer leaves cores idle on analytic queries.",
+	"max_connections":                     "Connection slots, each a process with its own memory. A hot standby needs at least its primary's value to replay WAL.",
+	"max_prepared_transactions":           "Slots for two-phase commit transactions. A hot standby needs at least its primary's value to replay WAL.",
+	"max_locks_per_transaction":           "Size of the shared lock table. A hot standby needs at least its primary's value to replay WAL.",
+	"max_wal_senders":                     "Concurrent replication connections. A hot standby needs at least its primary's value, and a promoted standby needs enough for the other standbys.",
+	"wal_level":                           "How much information WAL carries. A promoted standby with a lower wal_level cannot serve logical replication or archiving the old primary did.",
+	"max_replication_slots":               "Replication slots the server can hold. A promoted standby with fewer slots cannot take over its primary's slots.",
+	"synchronous_commit":                  "Whether commits wait for WAL to be flushed locally or on standbys. Differing values change durability after a failover.",
+	"hot_standby_feedback":                "Whether a standby tells its primary which rows its queries still need. Without it, long standby queries are canceled by replay conflicts.",
+	"wal_log_hints":                       "Whether hint bit changes are WAL-logged. pg_rewind needs it, or data checksums, to resynchronize a former primary.",
+	"full_page_writes":                    "Whether the first change of a page after a checkpoint writes the full page to WAL. Turning it off risks corruption after a crash.",
+	"fsync":                               "Whether PostgreSQL forces writes to disk. Turning it off risks corruption after a crash.",
+	"archive_mode":                        "Whether completed WAL is archived. A promoted standby without it leaves a gap in point-in-time recovery.",
+	"shared_preload_libraries":            "Libraries loaded at server start. Extensions such as pg_stat_statements stop working on an instance that does not load them.",
+	"server_version":                      "Instances of one cluster or environment running different PostgreSQL releases behave and plan differently.",
+	"autovacuum":                          "Whether autovacuum runs. Without it dead tuples accumulate and transaction IDs are not frozen.",
+	"checkpoint_timeout":                  "Longest time between automatic checkpoints. Differing values change write patterns and crash recovery time between instances.",
+	"statement_timeout":                   "Longest a statement may run. Applications see different failures depending on which instance serves them.",
+	"idle_in_transaction_session_timeout": "Longest a session may sit idle inside a transaction, holding locks and the xmin horizon.",
+	"default_transaction_isolation":       "Isolation level of transactions that do not set one. Applications see different anomalies depending on which instance serves them.",
+	"timezone":                            "Time zone timestamps are displayed in. Applications see different times depending on which instance serves them.",
+	"log_min_duration_statement":          "Statements slower than this are logged. Differing values hide slow queries on some instances.",
+}
+
+// hotStandbyMinimums must be at least as high on a hot standby as on its
+// primary; PostgreSQL pauses replay, or before 16 refuses to start, otherwise
+var hotStandbyMinimums = map[string]bool{
+	"max_connections":           true,
+	"max_prepared_transactions": true,
+	"max_locks_per_transaction": true,
+	"max_wal_senders":           true,
+	"max_worker_processes":      true,
+}
+
+// nodeSettings are expected to differ between instances: paths, addresses
+// and the settings that make a server a standby
+var nodeSettings = map[string]bool{
+	"data_directory":            true,
+	"config_file":               true,
+	"hba_file":                  true,
+	"ident_file":                true,
+	"external_pid_file":         true,
+	"cluster_name":              true,
+	"listen_addresses":          true,
+	"port":                      true,
+	"unix_socket_directories":   true,
+	"primary_conninfo":          true,
+	"primary_slot_name":         true,
+	"restore_command":           true,
+	"recovery_min_apply_delay":  true,
+	"promote_trigger_file":      true,
+	"synchronous_standby_names": true,
+	"in_hot_standby":            true,
+	"transaction_read_only":     true,
+	"ssl_cert_file":             true,
+	"ssl_key_file":              true,
+	"log_directory":             true,
+	"data_directory_mode":       true,
+}
+
+// sessionSources are the sources of values that belong to the collector's
+// session rather than the server
+var sessionSources = map[string]bool{
+	"client":  true,
+	"session": true,
+}
+
+// requiresRestart reports whether a change to a setting takes a restart
+func requiresRestart(s *models.PgSetting) bool {
+	if s.Context != "" {
+		return s.Context == contextPostmaster
+	}
+	return restartSettings[s.Name]
+}
+
+// reason explains why a setting matters, with a fallback for settings
+// without an explanation
+func reason(name string) string {
+	if why, ok := settingReasons[strings.ToLower(name)]; ok {
+		return why
+	}
+	return "Instances that should behave alike run with different values of this setting."
+}
@@ -0,0 +1,197 @@
+package config_advisor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Store interface for server settings, host inventory and the instances
+// settings are compared across
+type Store interface {
+	GetCurrentSettings(ctx context.Context, collectorID uuid.UUID) (*models.CollectorSettings, error)
+	ReplaceSettings(ctx context.Context, collectorID uuid.UUID, at time.Time, settings []*models.PgSetting, changes []*models.PgSettingChange) error
+	GetSettingsForCollectors(ctx context.Context, collectorIDs []uuid.UUID) ([]*models.CollectorSettings, error)
+	GetClusterCollectorIDs(ctx context.Context, collectorID uuid.UUID) (string, []uuid.UUID, error)
+	GetCollectorGroupMemberIDs(ctx context.Context, tenantID uuid.UUID, groupID int64) ([]uuid.UUID, error)
+	GetHostInventory(ctx context.Context, collectorID uuid.UUID) (*models.HostInventory, error)
+}
+
+// Service snapshots server settings and reviews them for drift and against
+// host-derived recommendations
+type Service struct {
+	store  Store
+	logger *zap.Logger
+}
+
+// NewService creates a new config advisor service
+func NewService(store Store, logger *zap.Logger) *Service {
+	return &Service{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// BuildSettings converts the settings of a pg_settings metric, dropping rows
+// without a name and keeping the last of duplicate names
+func BuildSettings(collected []models.CollectedSetting) []*models.PgSetting {
+	index := make(map[string]int, len(collected))
+	settings := make([]*models.PgSetting, 0, len(collected))
+	for _, c := range collected {
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			continue
+		}
+		s := &models.PgSetting{
+			Name:           name,
+			Setting:        c.Setting,
+			Unit:           c.Unit,
+			VarType:        c.VarType,
+			Context:        c.Context,
+			Source:         c.Source,
+			BootVal:        c.BootVal,
+			PendingRestart: c.PendingRestart,
+		}
+		if i, ok := index[name]; ok {
+			settings[i] = s
+			continue
+		}
+		index[name] = len(settings)
+		settings = append(settings, s)
+	}
+	return settings
+}
+
+// Ingest stores a snapshot of a collector's settings and records the changes
+// since its previous snapshot. The first snapshot is the baseline and
+// records no changes.
+func (s *Service) Ingest(ctx context.Context, collectorID uuid.UUID, at time.Time, settings []*models.PgSetting) ([]*models.PgSettingChange, error) {
+	if len(settings) == 0 {
+		return nil, nil
+	}
+
+	current, err := s.store.GetCurrentSettings(ctx, collectorID)
+	if err != nil {
+		s.logger.Error("Failed to load current settings", zap.Error(err))
+		return nil, err
+	}
+
+	changes := []*models.PgSettingChange{}
+	if len(current.Settings) == 0 {
+		for _, setting := range settings {
+			setting.ChangedAt = at
+		}
+	} else {
+		changes = Diff(collectorID, current.Settings, settings, at)
+	}
+
+	if err := s.store.ReplaceSettings(ctx, collectorID, at, settings, changes); err != nil {
+		s.logger.Error("Failed to store settings", zap.Error(err))
+		return nil, err
+	}
+	if len(changes) > 0 {
+		s.logger.Info("Server settings changed",
+			zap.String("collector_id", collectorID.String()),
+			zap.Int("changes", len(changes)),
+		)
+	}
+
+	return changes, nil
+}
+
+// Advise reviews a collector's settings against recommendations for its
+// host. Without host inventory only the storage-based settings are reviewed.
+func (s *Service) Advise(ctx context.Context, collectorID uuid.UUID, storage string, now time.Time) (*models.SettingsAdvice, error) {
+	current, err := s.store.GetCurrentSettings(ctx, collectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory, err := s.store.GetHostInventory(ctx, collectorID)
+	if err != nil {
+		var appErr *apperrors.AppError
+		if !errors.As(err, &appErr) || appErr.StatusCode != http.StatusNotFound {
+			return nil, err
+		}
+		inventory = nil
+	}
+
+	advice := &models.SettingsAdvice{
+		CollectorID: collectorID,
+		CollectedAt: current.CollectedAt,
+		Storage:     storage,
+		GeneratedAt: now,
+	}
+	if inventory != nil {
+		advice.MemoryTotalMb = inventory.MemoryTotalMb
+		advice.CpuCores = inventory.CpuCores
+		advice.DiskTotalGb = inventory.DiskTotalGb
+	}
+	if len(current.Settings) == 0 {
+		advice.Recommendations = []*models.SettingRecommendation{}
+		advice.Notes = []string{"The collector has not reported pg_settings yet"}
+		return advice, nil
+	}
+
+	advice.Recommendations, advice.Notes = Recommend(current.Settings, inventory, storage)
+	return advice, nil
+}
+
+// ClusterDrift compares a collector's settings with the other members of
+// its replication cluster, the collectors whose servers share its system
+// identifier
+func (s *Service) ClusterDrift(ctx context.Context, collectorID uuid.UUID, now time.Time) (*models.SettingsDriftReport, error) {
+	clusterID, ids, err := s.store.GetClusterCollectorIDs(ctx, collectorID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		ids = []uuid.UUID{collectorID}
+	}
+	if clusterID == "" {
+		clusterID = "collector:" + collectorID.String()
+	}
+	return s.drift(ctx, models.SettingDriftScopeCluster, clusterID, ids, now)
+}
+
+// GroupDrift compares the settings of the collectors of a tenant's group,
+// e.g. all production instances
+func (s *Service) GroupDrift(ctx context.Context, tenantID uuid.UUID, groupID int64, now time.Time) (*models.SettingsDriftReport, error) {
+	ids, err := s.store.GetCollectorGroupMemberIDs(ctx, tenantID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return s.drift(ctx, models.SettingDriftScopeGroup, strconv.FormatInt(groupID, 10), ids, now)
+}
+
+func (s *Service) drift(ctx context.Context, scope, scopeID string, ids []uuid.UUID, now time.Time) (*models.SettingsDriftReport, error) {
+	members, err := s.store.GetSettingsForCollectors(ctx, ids)
+	if err != nil {
+		s.logger.Error("Failed to load settings for drift", zap.Error(err))
+		return nil, err
+	}
+
+	report := &models.SettingsDriftReport{
+		Scope:        scope,
+		ScopeID:      scopeID,
+		CollectorIDs: ids,
+		Missing:      []uuid.UUID{},
+		GeneratedAt:  now,
+	}
+	for _, m := range members {
+		if len(m.Settings) == 0 {
+			report.Missing = append(report.Missing, m.CollectorID)
+		}
+	}
+	report.Drift = Drift(scope, members)
+
+	return report, nil
+}
@@ -0,0 +1,405 @@
+package config_advisor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockSettingsStore is a mock implementation for testing
+type mockSettingsStore struct {
+	current   map[uuid.UUID]*models.CollectorSettings
+	inventory *models.HostInventory
+	clusterID string
+	cluster   []uuid.UUID
+	group     []uuid.UUID
+	err       error
+
+	replaced []*models.PgSetting
+	changes  []*models.PgSettingChange
+}
+
+func (m *mockSettingsStore) GetCurrentSettings(ctx context.Context, collectorID uuid.UUID) (*models.CollectorSettings, error) {
+	if cs, ok := m.current[collectorID]; ok {
+		return cs, m.err
+	}
+	return &models.CollectorSettings{CollectorID: collectorID, Settings: []*models.PgSetting{}}, m.err
+}
+
+func (m *mockSettingsStore) ReplaceSettings(ctx context.Context, collectorID uuid.UUID, at time.Time, settings []*models.PgSetting, changes []*models.PgSettingChange) error {
+	m.replaced = settings
+	m.changes = changes
+	return m.err
+}
+
+func (m *mockSettingsStore) GetSettingsForCollectors(ctx context.Context, collectorIDs []uuid.UUID) ([]*models.CollectorSettings, error) {
+	all := []*models.CollectorSettings{}
+	for _, id := range collectorIDs {
+		cs, _ := m.GetCurrentSettings(ctx, id)
+		all = append(all, cs)
+	}
+	return all, m.err
+}
+
+func (m *mockSettingsStore) GetClusterCollectorIDs(ctx context.Context, collectorID uuid.UUID) (string, []uuid.UUID, error) {
+	return m.clusterID, m.cluster, m.err
+}
+
+func (m *mockSettingsStore) GetCollectorGroupMemberIDs(ctx context.Context, tenantID uuid.UUID, groupID int64) ([]uuid.UUID, error) {
+	return m.group, m.err
+}
+
+func (m *mockSettingsStore) GetHostInventory(ctx context.Context, collectorID uuid.UUID) (*models.HostInventory, error) {
+	if m.inventory == nil {
+		return nil, apperrors.NotFound("Host inventory not found", collectorID.String())
+	}
+	return m.inventory, m.err
+}
+
+// newTestSettings returns the settings of an untuned instance: stock
+// memory settings and HDD-era random_page_cost
+func newTestSettings() []*models.PgSetting {
+	return []*models.PgSetting{
+		{Name: "shared_buffers", Setting: "16384", Unit: "8kB", Context: "postmaster", Source: "configuration file"},
+		{Name: "effective_cache_size", Setting: "524288", Unit: "8kB", Context: "user", Source: "default"},
+		{Name: "work_mem", Setting: "4096", Unit: "kB", Context: "user", Source: "default"},
+		{Name: "maintenance_work_mem", Setting: "1048576", Unit: "kB", Context: "user", Source: "configuration file"},
+		{Name: "max_wal_size", Setting: "1024", Unit: "MB", Context: "sighup", Source: "default"},
+		{Name: "random_page_cost", Setting: "4", Context: "user", Source: "default"},
+		{Name: "effective_io_concurrency", Setting: "200", Context: "user", Source: "configuration file"},
+		{Name: "max_connections", Setting: "100", Context: "postmaster", Source: "configuration file"},
+		{Name: "max_worker_processes", Setting: "8", Context: "postmaster", Source: "default"},
+		{Name: "max_parallel_workers", Setting: "8", Context: "user", Source: "default"},
+	}
+}
+
+func newTestInventory() *models.HostInventory {
+	return &models.HostInventory{MemoryTotalMb: 16384, CpuCores: 8, DiskTotalGb: 500}
+}
+
+func findRecommendation(recs []*models.SettingRecommendation, name string) *models.SettingRecommendation {
+	for _, r := range recs {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func TestRecommend(t *testing.T) {
+	recs, notes := Recommend(newTestSettings(), newTestInventory(), StorageSSD)
+	assert.Empty(t, notes)
+	require.Len(t, recs, 9)
+
+	tests := []struct {
+		name        string
+		current     string
+		recommended string
+		status      string
+	}{
+		{"shared_buffers", "128MB", "4GB", models.SettingStatusIncrease},
+		{"effective_cache_size", "4GB", "12GB", models.SettingStatusIncrease},
+		{"work_mem", "4MB", "54MB", models.SettingStatusIncrease},
+		{"maintenance_work_mem", "1GB", "1GB", models.SettingStatusOK},
+		{"max_wal_size", "1GB", "10GB", models.SettingStatusIncrease},
+		{"random_page_cost", "4", "1.1", models.SettingStatusDecrease},
+		{"effective_io_concurrency", "200", "200", models.SettingStatusOK},
+		{"max_worker_processes", "8", "8", models.SettingStatusOK},
+		{"max_parallel_workers", "8", "8", models.SettingStatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := findRecommendation(recs, tt.name)
+			require.NotNil(t, rec)
+			assert.Equal(t, tt.current, rec.CurrentValue)
+			assert.Equal(t, tt.recommended, rec.RecommendedValue)
+			assert.Equal(t, tt.status, rec.Status)
+			assert.NotEmpty(t, rec.Why)
+			assert.NotEmpty(t, rec.Basis)
+			if tt.status == models.SettingStatusOK {
+				assert.Empty(t, rec.Statement)
+			} else {
+				assert.Equal(t, "ALTER SYSTEM SET "+tt.name+" = '"+tt.recommended+"';", rec.Statement)
+			}
+		})
+	}
+
+	assert.True(t, findRecommendation(recs, "shared_buffers").RequiresRestart)
+	assert.False(t, findRecommendation(recs, "work_mem").RequiresRestart)
+}
+
+func TestRecommend_HDD(t *testing.T) {
+	recs, _ := Recommend(newTestSettings(), newTestInventory(), StorageHDD)
+
+	rpc := findRecommendation(recs, "random_page_cost")
+	require.NotNil(t, rpc)
+	assert.Equal(t, models.SettingStatusOK, rpc.Status)
+
+	eic := findRecommendation(recs, "effective_io_concurrency")
+	require.NotNil(t, eic)
+	assert.Equal(t, "2", eic.RecommendedValue)
+	assert.Equal(t, models.SettingStatusDecrease, eic.Status)
+}
+
+func TestRecommend_WithoutInventory(t *testing.T) {
+	recs, notes := Recommend(newTestSettings(), nil, StorageSSD)
+
+	// Only the storage-based settings can be reviewed
+	require.Len(t, recs, 2)
+	assert.Equal(t, "random_page_cost", recs[0].Name)
+	assert.Equal(t, "effective_io_concurrency", recs[1].Name)
+	assert.Len(t, notes, 3)
+}
+
+func TestRecommend_MissingSetting(t *testing.T) {
+	settings := newTestSettings()[1:] // no shared_buffers
+	recs, notes := Recommend(settings, newTestInventory(), StorageSSD)
+
+	assert.Nil(t, findRecommendation(recs, "shared_buffers"))
+	assert.Contains(t, notes, "shared_buffers was not reported by the collector")
+}
+
+func TestDisplayValue(t *testing.T) {
+	tests := []struct {
+		setting  *models.PgSetting
+		expected string
+	}{
+		{&models.PgSetting{Setting: "16384", Unit: "8kB"}, "128MB"},
+		{&models.PgSetting{Setting: "65536", Unit: "kB"}, "64MB"},
+		{&models.PgSetting{Setting: "1000", Unit: "kB"}, "1000kB"},
+		{&models.PgSetting{Setting: "-1", Unit: "kB"}, "-1kB"},
+		{&models.PgSetting{Setting: "200", Unit: "ms"}, "200ms"},
+		{&models.PgSetting{Setting: "on"}, "on"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, displayValue(tt.setting))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	collectorID := uuid.New()
+	before := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := before.Add(time.Hour)
+
+	prev := []*models.PgSetting{
+		{Name: "work_mem", Setting: "4096", Unit: "kB", ChangedAt: before},
+		{Name: "max_connections", Setting: "100", ChangedAt: before},
+		{Name: "old_setting", Setting: "on", ChangedAt: before},
+	}
+	next := []*models.PgSetting{
+		{Name: "work_mem", Setting: "65536", Unit: "kB"},
+		{Name: "max_connections", Setting: "100"},
+		{Name: "new_setting", Setting: "off"},
+	}
+
+	changes := Diff(collectorID, prev, next, at)
+	require.Len(t, changes, 3)
+
+	assert.Equal(t, "new_setting", changes[0].Name)
+	assert.Nil(t, changes[0].OldValue)
+	assert.Equal(t, "off", *changes[0].NewValue)
+
+	assert.Equal(t, "old_setting", changes[1].Name)
+	assert.Equal(t, "on", *changes[1].OldValue)
+	assert.Nil(t, changes[1].NewValue)
+
+	assert.Equal(t, "work_mem", changes[2].Name)
+	assert.Equal(t, "4096", *changes[2].OldValue)
+	assert.Equal(t, "65536", *changes[2].NewValue)
+	assert.Equal(t, collectorID, changes[2].CollectorID)
+	assert.Equal(t, at, changes[2].ChangedAt)
+
+	assert.Equal(t, at, next[0].ChangedAt)
+	assert.Equal(t, before, next[1].ChangedAt)
+	assert.Equal(t, at, next[2].ChangedAt)
+}
+
+func TestDrift(t *testing.T) {
+	primary := &models.CollectorSettings{CollectorID: uuid.New(), CollectorName: "primary", Settings: []*models.PgSetting{
+		{Name: "max_connections", Setting: "200", Context: "postmaster"},
+		{Name: "shared_buffers", Setting: "524288", Unit: "8kB", Context: "postmaster"},
+		{Name: "work_mem", Setting: "65536", Unit: "kB", Context: "user"},
+		{Name: "random_page_cost", Setting: "1.1", Context: "user"},
+		{Name: "primary_conninfo", Setting: "", Context: "sighup"},
+		{Name: "application_name", Setting: "pganalytics", Context: "user", Source: "client"},
+		{Name: "new_in_17", Setting: "on", Context: "user"},
+	}}
+	replica := &models.CollectorSettings{CollectorID: uuid.New(), CollectorName: "replica", Settings: []*models.PgSetting{
+		{Name: "max_connections", Setting: "100", Context: "postmaster"},
+		{Name: "shared_buffers", Setting: "262144", Unit: "8kB", Context: "postmaster"},
+		{Name: "work_mem", Setting: "4096", Unit: "kB", Context: "user"},
+		{Name: "random_page_cost", Setting: "1.1", Context: "user"},
+		{Name: "primary_conninfo", Setting: "host=primary", Context: "sighup"},
+		{Name: "application_name", Setting: "psql", Context: "user", Source: "client"},
+	}}
+	silent := &models.CollectorSettings{CollectorID: uuid.New(), Settings: []*models.PgSetting{}}
+
+	drift := Drift(models.SettingDriftScopeCluster, []*models.CollectorSettings{primary, replica, silent})
+	require.Len(t, drift, 3)
+
+	assert.Equal(t, "max_connections", drift[0].Name)
+	assert.Equal(t, "critical", drift[0].Severity)
+	assert.True(t, drift[0].RequiresRestart)
+	require.Len(t, drift[0].Values, 2)
+	assert.Equal(t, "primary", drift[0].Values[0].CollectorName)
+	assert.Equal(t, "200", drift[0].Values[0].Value)
+
+	assert.Equal(t, "shared_buffers", drift[1].Name)
+	assert.Equal(t, "warning", drift[1].Severity)
+	assert.Equal(t, "4GB", drift[1].Values[0].Value)
+	assert.Equal(t, "2GB", drift[1].Values[1].Value)
+
+	assert.Equal(t, "work_mem", drift[2].Name)
+	assert.Equal(t, "info", drift[2].Severity)
+
+	// Outside a cluster a lower max_connections is not critical
+	drift = Drift(models.SettingDriftScopeGroup, []*models.CollectorSettings{primary, replica})
+	require.Len(t, drift, 3)
+	assert.Equal(t, "max_connections", drift[0].Name)
+	assert.Equal(t, "warning", drift[0].Severity)
+}
+
+func TestBuildSettings(t *testing.T) {
+	settings := BuildSettings([]models.CollectedSetting{
+		{Name: "work_mem", Setting: "4096", Unit: "kB"},
+		{Name: " ", Setting: "ignored"},
+		{Name: "max_connections", Setting: "100", Context: "postmaster", PendingRestart: true},
+		{Name: "work_mem", Setting: "8192", Unit: "kB"},
+	})
+
+	require.Len(t, settings, 2)
+	assert.Equal(t, "work_mem", settings[0].Name)
+	assert.Equal(t, "8192", settings[0].Setting)
+	assert.Equal(t, "max_connections", settings[1].Name)
+	assert.True(t, settings[1].PendingRestart)
+}
+
+func TestIngest(t *testing.T) {
+	collectorID := uuid.New()
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("first snapshot is the baseline", func(t *testing.T) {
+		store := &mockSettingsStore{}
+		service := NewService(store, zap.NewNop())
+
+		changes, err := service.Ingest(context.Background(), collectorID, at, newTestSettings())
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+		require.Len(t, store.replaced, 10)
+		assert.Equal(t, at, store.replaced[0].ChangedAt)
+	})
+
+	t.Run("later snapshots record changes", func(t *testing.T) {
+		store := &mockSettingsStore{current: map[uuid.UUID]*models.CollectorSettings{
+			collectorID: {CollectorID: collectorID, Settings: newTestSettings()},
+		}}
+		service := NewService(store, zap.NewNop())
+
+		next := newTestSettings()
+		next[2].Setting = "65536"
+		changes, err := service.Ingest(context.Background(), collectorID, at, next)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, "work_mem", changes[0].Name)
+		assert.Equal(t, changes, store.changes)
+	})
+
+	t.Run("empty snapshot is ignored", func(t *testing.T) {
+		store := &mockSettingsStore{}
+		service := NewService(store, zap.NewNop())
+
+		changes, err := service.Ingest(context.Background(), collectorID, at, nil)
+		require.NoError(t, err)
+		assert.Nil(t, changes)
+		assert.Nil(t, store.replaced)
+	})
+}
+
+func TestAdvise(t *testing.T) {
+	collectorID := uuid.New()
+	now := time.Now()
+
+	t.Run("with inventory", func(t *testing.T) {
+		store := &mockSettingsStore{
+			current: map[uuid.UUID]*models.CollectorSettings{
+				collectorID: {CollectorID: collectorID, Settings: newTestSettings()},
+			},
+			inventory: newTestInventory(),
+		}
+		advice, err := NewService(store, zap.NewNop()).Advise(context.Background(), collectorID, StorageSSD, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(16384), advice.MemoryTotalMb)
+		assert.Equal(t, 8, advice.CpuCores)
+		assert.Len(t, advice.Recommendations, 9)
+		assert.Empty(t, advice.Notes)
+	})
+
+	t.Run("without inventory", func(t *testing.T) {
+		store := &mockSettingsStore{current: map[uuid.UUID]*models.CollectorSettings{
+			collectorID: {CollectorID: collectorID, Settings: newTestSettings()},
+		}}
+		advice, err := NewService(store, zap.NewNop()).Advise(context.Background(), collectorID, StorageSSD, now)
+		require.NoError(t, err)
+		assert.Zero(t, advice.MemoryTotalMb)
+		assert.Len(t, advice.Recommendations, 2)
+		assert.NotEmpty(t, advice.Notes)
+	})
+
+	t.Run("without settings", func(t *testing.T) {
+		store := &mockSettingsStore{inventory: newTestInventory()}
+		advice, err := NewService(store, zap.NewNop()).Advise(context.Background(), collectorID, StorageSSD, now)
+		require.NoError(t, err)
+		assert.Empty(t, advice.Recommendations)
+		assert.Equal(t, []string{"The collector has not reported pg_settings yet"}, advice.Notes)
+	})
+}
+
+func TestClusterDrift(t *testing.T) {
+	collectorID := uuid.New()
+	now := time.Now()
+
+	t.Run("alone without a known cluster", func(t *testing.T) {
+		store := &mockSettingsStore{}
+		report, err := NewService(store, zap.NewNop()).ClusterDrift(context.Background(), collectorID, now)
+		require.NoError(t, err)
+		assert.Equal(t, models.SettingDriftScopeCluster, report.Scope)
+		assert.Equal(t, "collector:"+collectorID.String(), report.ScopeID)
+		assert.Equal(t, []uuid.UUID{collectorID}, report.CollectorIDs)
+		assert.Equal(t, []uuid.UUID{collectorID}, report.Missing)
+		assert.Empty(t, report.Drift)
+	})
+
+	t.Run("cluster members", func(t *testing.T) {
+		replicaID := uuid.New()
+		replica := newTestSettings()
+		replica[7].Setting = "50" // max_connections below the primary's
+		store := &mockSettingsStore{
+			current: map[uuid.UUID]*models.CollectorSettings{
+				collectorID: {CollectorID: collectorID, Settings: newTestSettings()},
+				replicaID:   {CollectorID: replicaID, Settings: replica},
+			},
+			clusterID: "7312345678901234567",
+			cluster:   []uuid.UUID{collectorID, replicaID},
+		}
+		report, err := NewService(store, zap.NewNop()).ClusterDrift(context.Background(), collectorID, now)
+		require.NoError(t, err)
+		assert.Equal(t, "7312345678901234567", report.ScopeID)
+		assert.Empty(t, report.Missing)
+		require.Len(t, report.Drift, 1)
+		assert.Equal(t, "max_connections", report.Drift[0].Name)
+		assert.Equal(t, "critical", report.Drift[0].Severity)
+	})
+}
+
+func TestGroupDrift(t *testing.T) {
+	store := &mockSettingsStore{err: apperrors.NotFound("Collector group not found", "id: 7")}
+	_, err := NewService(store, zap.NewNop()).GroupDrift(context.Background(), uuid.New(), 7, time.Now())
+	require.Error(t, err)
+}
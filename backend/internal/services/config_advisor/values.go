@@ -0,0 +1,124 @@
+package config_advisor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+const (
+	kB = int64(1024)
+	mB = 1024 * kB
+	gB = 1024 * mB
+)
+
+// unitBytes returns the size of a pg_settings memory unit such as "8kB" or
+// "MB", and false for units that are not memory
+func unitBytes(unit string) (int64, bool) {
+	unit = strings.TrimSpace(unit)
+	i := 0
+	for i < len(unit) && unit[i] >= '0' && unit[i] <= '9' {
+		i++
+	}
+	multiplier := int64(1)
+	if i > 0 {
+		n, err := strconv.ParseInt(unit[:i], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		multiplier = n
+	}
+	switch unit[i:] {
+	case "B":
+		return multiplier, true
+	case "kB":
+		return multiplier * kB, true
+	case "MB":
+		return multiplier * mB, true
+	case "GB":
+		return multiplier * gB, true
+	case "TB":
+		return multiplier * 1024 * gB, true
+	}
+	return 0, false
+}
+
+// settingBytes returns a memory setting in bytes. Negative values, which
+// mean "use another setting" (e.g. autovacuum_work_mem = -1), do not parse.
+func settingBytes(s *models.PgSetting) (int64, bool) {
+	if s == nil {
+		return 0, false
+	}
+	size, ok := unitBytes(s.Unit)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s.Setting), 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return int64(n) * size, true
+}
+
+// settingNumber returns a numeric setting
+func settingNumber(s *models.PgSetting) (float64, bool) {
+	if s == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(s.Setting), 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// formatBytes writes a size the way postgresql.conf takes it, in the largest
+// unit that holds it exactly
+func formatBytes(b int64) string {
+	switch {
+	case b >= gB && b%gB == 0:
+		return fmt.Sprintf("%dGB", b/gB)
+	case b >= mB && b%mB == 0:
+		return fmt.Sprintf("%dMB", b/mB)
+	case b%kB == 0:
+		return fmt.Sprintf("%dkB", b/kB)
+	}
+	return fmt.Sprintf("%dB", b)
+}
+
+// roundMB rounds a size down to whole megabytes, and to whole gigabytes
+// from 8GB, so that recommendations read as a person would write them
+func roundMB(b int64) int64 {
+	if b >= 8*gB {
+		return b / gB * gB
+	}
+	return b / mB * mB
+}
+
+// formatNumber writes a number without trailing zeros
+func formatNumber(n float64) string {
+	if n == math.Trunc(n) {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// displayValue writes a setting as postgresql.conf takes it: memory in
+// bytes-based units, everything else as pg_settings reports it with its unit
+func displayValue(s *models.PgSetting) string {
+	if b, ok := settingBytes(s); ok {
+		return formatBytes(b)
+	}
+	if s.Unit != "" {
+		return s.Setting + s.Unit
+	}
+	return s.Setting
+}
+
+// quoteLiteral quotes a value for ALTER SYSTEM
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
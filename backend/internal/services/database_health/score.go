@@ -0,0 +1,229 @@
+package database_health
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// Thresholds between which a component's score falls linearly from 100 to 0
+const (
+	cacheHitGood = 0.99
+	cacheHitBad  = 0.80
+
+	replicationLagGoodMs = 1000.0
+	replicationLagBadMs  = 300000.0
+
+	connectionsGood = 0.70
+	connectionsBad  = 0.95
+
+	deadTuplesGood = 0.10
+	deadTuplesBad  = 0.50
+
+	anomalyPenalty = 20.0 // points lost per anomalous log template
+
+	errorsPerMinuteBad = 10.0
+)
+
+// scale maps value to 100 at good and 0 at bad, linearly in between, for
+// thresholds in either direction
+func scale(value, good, bad float64) float64 {
+	score := 100 * (value - bad) / (good - bad)
+	return math.Max(0, math.Min(100, score))
+}
+
+func percent(ratio float64) string {
+	return fmt.Sprintf("%.1f%%", ratio*100)
+}
+
+// ValidateWeights rejects negative weights and weights that are all zero
+func ValidateWeights(w models.DatabaseHealthWeights) error {
+	named := map[string]float64{
+		models.DatabaseHealthCacheHit:       w.CacheHit,
+		models.DatabaseHealthReplicationLag: w.ReplicationLag,
+		models.DatabaseHealthConnections:    w.Connections,
+		models.DatabaseHealthBloat:          w.Bloat,
+		models.DatabaseHealthWraparound:     w.Wraparound,
+		models.DatabaseHealthChecks:         w.HealthChecks,
+		models.DatabaseHealthAnomalies:      w.Anomalies,
+		models.DatabaseHealthErrorLogs:      w.ErrorLogs,
+	}
+	total := 0.0
+	for name, weight := range named {
+		if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return apperrors.BadRequest("Invalid weights", fmt.Sprintf("%s must be a non-negative number", name))
+		}
+		total += weight
+	}
+	if total <= 0 {
+		return apperrors.BadRequest("Invalid weights", "at least one weight must be positive")
+	}
+	return nil
+}
+
+// Components scores each component from its inputs. Components without data
+// are returned unavailable with a zero score.
+func Components(in *models.DatabaseHealthInputs, w models.DatabaseHealthWeights) []*models.DatabaseHealthComponent {
+	return []*models.DatabaseHealthComponent{
+		cacheHitComponent(in, w.CacheHit),
+		replicationLagComponent(in, w.ReplicationLag),
+		connectionsComponent(in, w.Connections),
+		bloatComponent(in, w.Bloat),
+		wraparoundComponent(in, w.Wraparound),
+		healthChecksComponent(in, w.HealthChecks),
+		anomaliesComponent(in, w.Anomalies),
+		errorLogsComponent(in, w.ErrorLogs),
+	}
+}
+
+// Score calculates a database health score as the weighted average of the
+// available components, so that missing data neither rewards nor penalizes
+// a database. It returns nil when no weighted component has data.
+func Score(collectorID uuid.UUID, at time.Time, in *models.DatabaseHealthInputs, w models.DatabaseHealthWeights) *models.DatabaseHealthScore {
+	components := Components(in, w)
+
+	total := 0.0
+	for _, c := range components {
+		if c.Available {
+			total += c.Weight
+		}
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	sum := 0.0
+	for _, c := range components {
+		if c.Available {
+			c.Contribution = math.Round(c.Score*c.Weight/total*100) / 100
+			sum += c.Score * c.Weight / total
+		}
+	}
+
+	score := int(math.Round(sum))
+	return &models.DatabaseHealthScore{
+		Time:        at,
+		CollectorID: collectorID,
+		HealthScore: score,
+		Status:      services.GetHealthStatus(score),
+		Components:  components,
+		Weights:     w,
+	}
+}
+
+// Weakest returns the name of the available component with the lowest score
+func Weakest(components []*models.DatabaseHealthComponent) string {
+	var weakest *models.DatabaseHealthComponent
+	for _, c := range components {
+		if c.Available && c.Weight > 0 && (weakest == nil || c.Score < weakest.Score) {
+			weakest = c
+		}
+	}
+	if weakest == nil {
+		return ""
+	}
+	return weakest.Name
+}
+
+func unavailable(name string, weight float64, explanation string) *models.DatabaseHealthComponent {
+	return &models.DatabaseHealthComponent{Name: name, Weight: weight, Explanation: explanation}
+}
+
+func available(name string, weight, score, value float64, explanation string) *models.DatabaseHealthComponent {
+	return &models.DatabaseHealthComponent{
+		Name:        name,
+		Available:   true,
+		Score:       math.Round(score*10) / 10,
+		Weight:      weight,
+		Value:       &value,
+		Explanation: explanation,
+	}
+}
+
+func cacheHitComponent(in *models.DatabaseHealthInputs, weight float64) *models.DatabaseHealthComponent {
+	if in.CacheHitRatio == nil {
+		return unavailable(models.DatabaseHealthCacheHit, weight, "No pg_stat_statements block counts in the last 24 hours")
+	}
+	ratio := *in.CacheHitRatio
+	return available(models.DatabaseHealthCacheHit, weight, scale(ratio, cacheHitGood, cacheHitBad), ratio,
+		fmt.Sprintf("%s of shared block reads were cache hits; %s or more scores 100, %s or less scores 0",
+			percent(ratio), percent(cacheHitGood), percent(cacheHitBad)))
+}
+
+func replicationLagComponent(in *models.DatabaseHealthInputs, weight float64) *models.DatabaseHealthComponent {
+	if in.ReplicationLagMs == nil {
+		return unavailable(models.DatabaseHealthReplicationLag, weight, "No replicas reported in the last hour")
+	}
+	lag := float64(*in.ReplicationLagMs)
+	return available(models.DatabaseHealthReplicationLag, weight, scale(lag, replicationLagGoodMs, replicationLagBadMs), lag,
+		fmt.Sprintf("The most lagging replica is %s behind on replay; up to %s scores 100, %s or more scores 0",
+			time.Duration(lag)*time.Millisecond, time.Duration(replicationLagGoodMs)*time.Millisecond,
+			time.Duration(replicationLagBadMs)*time.Millisecond))
+}
+
+func connectionsComponent(in *models.DatabaseHealthInputs, weight float64) *models.DatabaseHealthComponent {
+	if in.Connections == nil {
+		return unavailable(models.DatabaseHealthConnections, weight, "No activity samples in the last hour")
+	}
+	if in.MaxConnections == nil || *in.MaxConnections <= 0 {
+		return unavailable(models.DatabaseHealthConnections, weight, "max_connections is unknown; report pg_settings or host inventory")
+	}
+	ratio := float64(*in.Connections) / float64(*in.MaxConnections)
+	return available(models.DatabaseHealthConnections, weight, scale(ratio, connectionsGood, connectionsBad), ratio,
+		fmt.Sprintf("%d of %d connections in use (%s); up to %s scores 100, %s or more scores 0",
+			*in.Connections, *in.MaxConnections, percent(ratio), percent(connectionsGood), percent(connectionsBad)))
+}
+
+func bloatComponent(in *models.DatabaseHealthInputs, weight float64) *models.DatabaseHealthComponent {
+	if in.DeadTupleRatio == nil {
+		return unavailable(models.DatabaseHealthBloat, weight, "No vacuum statistics in the last 24 hours")
+	}
+	ratio := *in.DeadTupleRatio
+	return available(models.DatabaseHealthBloat, weight, scale(ratio, deadTuplesGood, deadTuplesBad), ratio,
+		fmt.Sprintf("%s of tuples are dead; up to %s scores 100, %s or more scores 0",
+			percent(ratio), percent(deadTuplesGood), percent(deadTuplesBad)))
+}
+
+func wraparoundComponent(in *models.DatabaseHealthInputs, weight float64) *models.DatabaseHealthComponent {
+	if in.WraparoundScore == nil {
+		return unavailable(models.DatabaseHealthWraparound, weight, "No wraparound samples have been collected")
+	}
+	score := float64(*in.WraparoundScore)
+	return available(models.DatabaseHealthWraparound, weight, score, score,
+		fmt.Sprintf("Wraparound risk is %s; the score is the wraparound assessment's own", in.WraparoundLevel))
+}
+
+func healthChecksComponent(in *models.DatabaseHealthInputs, weight float64) *models.DatabaseHealthComponent {
+	if in.HealthChecks == nil || in.HealthChecks.TotalChecks == 0 {
+		return unavailable(models.DatabaseHealthChecks, weight, "No health checks have been run")
+	}
+	s := in.HealthChecks
+	failed := s.TotalChecks - s.PassedChecks
+	return available(models.DatabaseHealthChecks, weight, float64(s.Score), float64(failed),
+		fmt.Sprintf("%d of %d health checks failed (%d critical, %d warning); passed checks weighted by severity",
+			failed, s.TotalChecks, s.FailedCritical, s.FailedWarning))
+}
+
+func anomaliesComponent(in *models.DatabaseHealthInputs, weight float64) *models.DatabaseHealthComponent {
+	if in.LogAnomalies == nil {
+		return unavailable(models.DatabaseHealthAnomalies, weight, "No logs in the last hour")
+	}
+	n := float64(*in.LogAnomalies)
+	return available(models.DatabaseHealthAnomalies, weight, math.Max(0, 100-n*anomalyPenalty), n,
+		fmt.Sprintf("%d new or spiking log templates in the last hour; each costs %.0f points", *in.LogAnomalies, anomalyPenalty))
+}
+
+func errorLogsComponent(in *models.DatabaseHealthInputs, weight float64) *models.DatabaseHealthComponent {
+	if in.ErrorsPerMinute == nil {
+		return unavailable(models.DatabaseHealthErrorLogs, weight, "No logs in the last hour")
+	}
+	rate := *in.ErrorsPerMinute
+	return available(models.DatabaseHealthErrorLogs, weight, scale(rate, 0, errorsPerMinuteBad), rate,
+		fmt.Sprintf("%.2f ERROR, FATAL or PANIC lines per minute over the last hour; %.0f or more per minute scores 0",
+			rate, errorsPerMinuteBad))
+}
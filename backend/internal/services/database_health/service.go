@@ -0,0 +1,214 @@
+package database_health
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/version_health"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// AnomalyWindow is the log period anomalies are looked for in
+	AnomalyWindow = time.Hour
+	// RankingWindow is how recent a score must be to rank a collector
+	RankingWindow = 24 * time.Hour
+)
+
+// Store interface for database health inputs, weights and scores
+type Store interface {
+	GetDatabaseHealthInputs(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.DatabaseHealthInputs, error)
+	GetLatestHealthCheckResults(ctx context.Context, collectorID uuid.UUID) ([]*models.HealthCheckResult, error)
+	// GetCollectorDatabaseHealthWeights and GetDatabaseHealthWeights return
+	// nil when the tenant has not set weights
+	GetCollectorDatabaseHealthWeights(ctx context.Context, collectorID uuid.UUID) (*models.DatabaseHealthWeights, error)
+	GetDatabaseHealthWeights(ctx context.Context, tenantID uuid.UUID) (*models.DatabaseHealthWeights, error)
+	SetDatabaseHealthWeights(ctx context.Context, tenantID uuid.UUID, weights *models.DatabaseHealthWeights) error
+	StoreDatabaseHealthScore(ctx context.Context, score *models.DatabaseHealthScore) error
+	GetCollectorsByTenantID(ctx context.Context, tenantID uuid.UUID) ([]*models.Collector, error)
+	GetLatestDatabaseHealthScores(ctx context.Context, collectorIDs []uuid.UUID, since time.Time) (map[uuid.UUID]*models.DatabaseHealthScore, error)
+}
+
+// WraparoundAssessor assesses wraparound headroom
+type WraparoundAssessor interface {
+	GetStatus(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.WraparoundStatus, error)
+}
+
+// AnomalyReporter reports anomalous log templates
+type AnomalyReporter interface {
+	TopTemplates(ctx context.Context, collectorID uuid.UUID, from, to time.Time, opts log_analysis.TemplateReportOptions) (*models.LogTemplateReport, error)
+}
+
+// Service calculates, stores and ranks database health scores
+type Service struct {
+	store      Store
+	wraparound WraparoundAssessor
+	anomalies  AnomalyReporter
+	logger     *zap.Logger
+}
+
+// NewService creates a new database health service
+func NewService(store Store, wraparound WraparoundAssessor, anomalies AnomalyReporter, logger *zap.Logger) *Service {
+	return &Service{
+		store:      store,
+		wraparound: wraparound,
+		anomalies:  anomalies,
+		logger:     logger,
+	}
+}
+
+// Weights returns a tenant's weights, or the defaults when it has none
+func (s *Service) Weights(ctx context.Context, tenantID uuid.UUID) (*models.DatabaseHealthWeights, error) {
+	weights, err := s.store.GetDatabaseHealthWeights(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if weights == nil {
+		defaults := models.DefaultDatabaseHealthWeights
+		return &defaults, nil
+	}
+	return weights, nil
+}
+
+// SetWeights validates and stores a tenant's weights
+func (s *Service) SetWeights(ctx context.Context, tenantID uuid.UUID, weights *models.DatabaseHealthWeights) error {
+	if err := ValidateWeights(*weights); err != nil {
+		return err
+	}
+	return s.store.SetDatabaseHealthWeights(ctx, tenantID, weights)
+}
+
+// Inputs gathers the signals a collector's database health is scored from
+func (s *Service) Inputs(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.DatabaseHealthInputs, error) {
+	inputs, err := s.store.GetDatabaseHealthInputs(ctx, collectorID, at)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := s.wraparound.GetStatus(ctx, collectorID, at)
+	if err != nil {
+		return nil, err
+	}
+	if status.CollectedAt != nil {
+		score := status.HealthScore
+		inputs.WraparoundScore = &score
+		inputs.WraparoundLevel = status.Level
+	}
+
+	results, err := s.store.GetLatestHealthCheckResults(ctx, collectorID)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > 0 {
+		summary := version_health.Summarize(results)
+		inputs.HealthChecks = &summary
+	}
+
+	report, err := s.anomalies.TopTemplates(ctx, collectorID, at.Add(-AnomalyWindow), at, log_analysis.TemplateReportOptions{AnomaliesOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	if report.TotalMessages > 0 || inputs.ErrorsPerMinute != nil {
+		anomalies := report.Anomalies
+		inputs.LogAnomalies = &anomalies
+	}
+
+	return inputs, nil
+}
+
+// Calculate scores a collector's database health with its tenant's weights
+// and stores the score
+func (s *Service) Calculate(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.DatabaseHealthScore, error) {
+	inputs, err := s.Inputs(ctx, collectorID, at)
+	if err != nil {
+		return nil, err
+	}
+
+	weights, err := s.store.GetCollectorDatabaseHealthWeights(ctx, collectorID)
+	if err != nil {
+		return nil, err
+	}
+	if weights == nil {
+		defaults := models.DefaultDatabaseHealthWeights
+		weights = &defaults
+	}
+
+	score := Score(collectorID, at, inputs, *weights)
+	if score == nil {
+		return nil, apperrors.NotFound("No database metrics found for collector", collectorID.String())
+	}
+
+	if err := s.store.StoreDatabaseHealthScore(ctx, score); err != nil {
+		s.logger.Error("Failed to store database health score", zap.Error(err))
+		return nil, err
+	}
+	return score, nil
+}
+
+// Ranking ranks a tenant's collectors by their latest database health score,
+// worst first. Collectors without a recent score are listed last.
+func (s *Service) Ranking(ctx context.Context, tenantID uuid.UUID, now time.Time) (*models.DatabaseHealthRanking, error) {
+	collectors, err := s.store.GetCollectorsByTenantID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(collectors))
+	for _, c := range collectors {
+		ids = append(ids, c.ID)
+	}
+	scores := map[uuid.UUID]*models.DatabaseHealthScore{}
+	if len(ids) > 0 {
+		if scores, err = s.store.GetLatestDatabaseHealthScores(ctx, ids, now.Add(-RankingWindow)); err != nil {
+			return nil, err
+		}
+	}
+
+	ranking := &models.DatabaseHealthRanking{
+		TenantID:    tenantID,
+		Collectors:  make([]*models.DatabaseHealthRank, 0, len(collectors)),
+		GeneratedAt: now,
+	}
+	for _, c := range collectors {
+		rank := &models.DatabaseHealthRank{
+			CollectorID:   c.ID,
+			CollectorName: c.Name,
+			Hostname:      c.Hostname,
+			Status:        "unknown",
+		}
+		if score, ok := scores[c.ID]; ok {
+			value := score.HealthScore
+			scoredAt := score.Time
+			rank.HealthScore = &value
+			rank.Status = score.Status
+			rank.Weakest = Weakest(score.Components)
+			rank.ScoredAt = &scoredAt
+		} else {
+			ranking.Unscored++
+		}
+		ranking.Collectors = append(ranking.Collectors, rank)
+	}
+
+	sort.SliceStable(ranking.Collectors, func(i, j int) bool {
+		a, b := ranking.Collectors[i], ranking.Collectors[j]
+		if (a.HealthScore == nil) != (b.HealthScore == nil) {
+			return a.HealthScore != nil
+		}
+		if a.HealthScore != nil && *a.HealthScore != *b.HealthScore {
+			return *a.HealthScore < *b.HealthScore
+		}
+		return a.Hostname < b.Hostname
+	})
+	for i, r := range ranking.Collectors {
+		if r.HealthScore != nil {
+			r.Rank = i + 1
+		}
+	}
+
+	return ranking, nil
+}
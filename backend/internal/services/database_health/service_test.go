@@ -0,0 +1,375 @@
+package database_health
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockHealthStore is a mock implementation for testing
+type mockHealthStore struct {
+	inputs        *models.DatabaseHealthInputs
+	results       []*models.HealthCheckResult
+	weights       *models.DatabaseHealthWeights
+	collectors    []*models.Collector
+	scores        map[uuid.UUID]*models.DatabaseHealthScore
+	stored        []*models.DatabaseHealthScore
+	storedWeights *models.DatabaseHealthWeights
+	rankingSince  time.Time
+	err           error
+}
+
+func (m *mockHealthStore) GetDatabaseHealthInputs(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.DatabaseHealthInputs, error) {
+	if m.inputs == nil {
+		return &models.DatabaseHealthInputs{}, m.err
+	}
+	return m.inputs, m.err
+}
+
+func (m *mockHealthStore) GetLatestHealthCheckResults(ctx context.Context, collectorID uuid.UUID) ([]*models.HealthCheckResult, error) {
+	return m.results, m.err
+}
+
+func (m *mockHealthStore) GetCollectorDatabaseHealthWeights(ctx context.Context, collectorID uuid.UUID) (*models.DatabaseHealthWeights, error) {
+	return m.weights, m.err
+}
+
+func (m *mockHealthStore) GetDatabaseHealthWeights(ctx context.Context, tenantID uuid.UUID) (*models.DatabaseHealthWeights, error) {
+	return m.weights, m.err
+}
+
+func (m *mockHealthStore) SetDatabaseHealthWeights(ctx context.Context, tenantID uuid.UUID, weights *models.DatabaseHealthWeights) error {
+	m.storedWeights = weights
+	return m.err
+}
+
+func (m *mockHealthStore) StoreDatabaseHealthScore(ctx context.Context, score *models.DatabaseHealthScore) error {
+	m.stored = append(m.stored, score)
+	return m.err
+}
+
+func (m *mockHealthStore) GetCollectorsByTenantID(ctx context.Context, tenantID uuid.UUID) ([]*models.Collector, error) {
+	return m.collectors, m.err
+}
+
+func (m *mockHealthStore) GetLatestDatabaseHealthScores(ctx context.Context, collectorIDs []uuid.UUID, since time.Time) (map[uuid.UUID]*models.DatabaseHealthScore, error) {
+	m.rankingSince = since
+	return m.scores, m.err
+}
+
+// mockWraparound returns a fixed wraparound status
+type mockWraparound struct {
+	status *models.WraparoundStatus
+}
+
+func (m *mockWraparound) GetStatus(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.WraparoundStatus, error) {
+	if m.status == nil {
+		return &models.WraparoundStatus{Level: models.WraparoundLevelUnknown, HealthScore: 100}, nil
+	}
+	return m.status, nil
+}
+
+// mockAnomalies returns a fixed template report and records its window
+type mockAnomalies struct {
+	report   *models.LogTemplateReport
+	from, to time.Time
+	opts     log_analysis.TemplateReportOptions
+}
+
+func (m *mockAnomalies) TopTemplates(ctx context.Context, collectorID uuid.UUID, from, to time.Time, opts log_analysis.TemplateReportOptions) (*models.LogTemplateReport, error) {
+	m.from, m.to, m.opts = from, to, opts
+	if m.report == nil {
+		return &models.LogTemplateReport{}, nil
+	}
+	return m.report, nil
+}
+
+func float(v float64) *float64 { return &v }
+func integer(v int) *int       { return &v }
+
+func component(t *testing.T, score *models.DatabaseHealthScore, name string) *models.DatabaseHealthComponent {
+	for _, c := range score.Components {
+		if c.Name == name {
+			return c
+		}
+	}
+	t.Fatalf("component %s not found", name)
+	return nil
+}
+
+func TestScale(t *testing.T) {
+	assert.Equal(t, 100.0, scale(0.995, cacheHitGood, cacheHitBad))
+	assert.Equal(t, 0.0, scale(0.5, cacheHitGood, cacheHitBad))
+	assert.InDelta(t, 50.0, scale(0.895, cacheHitGood, cacheHitBad), 0.01)
+
+	// Lower is better
+	assert.Equal(t, 100.0, scale(0.05, deadTuplesGood, deadTuplesBad))
+	assert.Equal(t, 0.0, scale(0.9, deadTuplesGood, deadTuplesBad))
+	assert.InDelta(t, 50.0, scale(0.30, deadTuplesGood, deadTuplesBad), 0.01)
+}
+
+func TestScoreAllComponents(t *testing.T) {
+	collectorID := uuid.New()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	lag := int64(500)
+	in := &models.DatabaseHealthInputs{
+		CacheHitRatio:    float(0.995),
+		ReplicationLagMs: &lag,
+		Connections:      integer(50),
+		MaxConnections:   integer(100),
+		DeadTupleRatio:   float(0.05),
+		WraparoundScore:  integer(100),
+		WraparoundLevel:  models.WraparoundLevelOK,
+		HealthChecks:     &models.HealthCheckSummary{TotalChecks: 10, PassedChecks: 10, Score: 100},
+		LogAnomalies:     integer(0),
+		ErrorsPerMinute:  float(0),
+	}
+
+	score := Score(collectorID, now, in, models.DefaultDatabaseHealthWeights)
+	require.NotNil(t, score)
+	assert.Equal(t, 100, score.HealthScore)
+	assert.Equal(t, "healthy", score.Status)
+	assert.Equal(t, collectorID, score.CollectorID)
+	assert.Equal(t, now, score.Time)
+	assert.Len(t, score.Components, 8)
+	for _, c := range score.Components {
+		assert.True(t, c.Available, c.Name)
+		assert.NotEmpty(t, c.Explanation, c.Name)
+		assert.NotNil(t, c.Value, c.Name)
+	}
+}
+
+func TestScoreRenormalizesOverAvailableComponents(t *testing.T) {
+	in := &models.DatabaseHealthInputs{
+		CacheHitRatio:  float(0.80), // scores 0
+		DeadTupleRatio: float(0.05), // scores 100
+	}
+	weights := models.DatabaseHealthWeights{CacheHit: 1, Bloat: 3, Wraparound: 10}
+
+	score := Score(uuid.New(), time.Now(), in, weights)
+	require.NotNil(t, score)
+	assert.Equal(t, 75, score.HealthScore)
+	assert.Equal(t, "degraded", score.Status)
+
+	wrap := component(t, score, models.DatabaseHealthWraparound)
+	assert.False(t, wrap.Available)
+	assert.Nil(t, wrap.Value)
+	assert.Equal(t, 0.0, wrap.Contribution)
+	assert.Contains(t, wrap.Explanation, "No wraparound samples")
+
+	assert.InDelta(t, 75.0, component(t, score, models.DatabaseHealthBloat).Contribution, 0.01)
+	assert.Equal(t, 0.0, component(t, score, models.DatabaseHealthCacheHit).Contribution)
+}
+
+func TestScoreWithoutData(t *testing.T) {
+	assert.Nil(t, Score(uuid.New(), time.Now(), &models.DatabaseHealthInputs{}, models.DefaultDatabaseHealthWeights))
+
+	// Data only for a component weighted zero
+	in := &models.DatabaseHealthInputs{CacheHitRatio: float(0.99)}
+	assert.Nil(t, Score(uuid.New(), time.Now(), in, models.DatabaseHealthWeights{Bloat: 1}))
+}
+
+func TestComponentExplanations(t *testing.T) {
+	lag := int64(60000)
+	in := &models.DatabaseHealthInputs{
+		ReplicationLagMs: &lag,
+		Connections:      integer(90),
+		MaxConnections:   integer(100),
+		HealthChecks:     &models.HealthCheckSummary{TotalChecks: 4, PassedChecks: 2, FailedCritical: 1, FailedWarning: 1, Score: 40},
+		LogAnomalies:     integer(2),
+		ErrorsPerMinute:  float(5),
+	}
+	score := Score(uuid.New(), time.Now(), in, models.DefaultDatabaseHealthWeights)
+	require.NotNil(t, score)
+
+	replication := component(t, score, models.DatabaseHealthReplicationLag)
+	assert.Contains(t, replication.Explanation, "1m0s behind")
+	assert.InDelta(t, 80.3, replication.Score, 0.1)
+
+	connections := component(t, score, models.DatabaseHealthConnections)
+	assert.Contains(t, connections.Explanation, "90 of 100 connections")
+	assert.InDelta(t, 20.0, connections.Score, 0.1)
+
+	checks := component(t, score, models.DatabaseHealthChecks)
+	assert.Equal(t, 40.0, checks.Score)
+	assert.Equal(t, 2.0, *checks.Value)
+	assert.Contains(t, checks.Explanation, "2 of 4 health checks failed (1 critical, 1 warning)")
+
+	anomalies := component(t, score, models.DatabaseHealthAnomalies)
+	assert.Equal(t, 60.0, anomalies.Score)
+
+	errors := component(t, score, models.DatabaseHealthErrorLogs)
+	assert.Equal(t, 50.0, errors.Score)
+}
+
+func TestConnectionsWithoutMaxConnections(t *testing.T) {
+	in := &models.DatabaseHealthInputs{Connections: integer(10)}
+	c := connectionsComponent(in, 1)
+	assert.False(t, c.Available)
+	assert.Contains(t, c.Explanation, "max_connections is unknown")
+}
+
+func TestWeakest(t *testing.T) {
+	components := []*models.DatabaseHealthComponent{
+		{Name: models.DatabaseHealthCacheHit, Available: true, Score: 90, Weight: 1},
+		{Name: models.DatabaseHealthBloat, Available: true, Score: 40, Weight: 1},
+		{Name: models.DatabaseHealthWraparound, Available: false, Weight: 1},
+		{Name: models.DatabaseHealthAnomalies, Available: true, Score: 10, Weight: 0},
+	}
+	assert.Equal(t, models.DatabaseHealthBloat, Weakest(components))
+	assert.Equal(t, "", Weakest(nil))
+}
+
+func TestValidateWeights(t *testing.T) {
+	assert.NoError(t, ValidateWeights(models.DefaultDatabaseHealthWeights))
+	assert.NoError(t, ValidateWeights(models.DatabaseHealthWeights{ErrorLogs: 2}))
+
+	err := ValidateWeights(models.DatabaseHealthWeights{CacheHit: 1, Bloat: -1})
+	require.Error(t, err)
+	assert.Equal(t, 400, err.(*apperrors.AppError).StatusCode)
+
+	err = ValidateWeights(models.DatabaseHealthWeights{})
+	require.Error(t, err)
+	assert.Contains(t, err.(*apperrors.AppError).Details, "at least one weight")
+}
+
+func TestCalculate(t *testing.T) {
+	collectorID := uuid.New()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	collectedAt := now.Add(-time.Minute)
+	store := &mockHealthStore{
+		inputs: &models.DatabaseHealthInputs{ErrorsPerMinute: float(0)},
+		results: []*models.HealthCheckResult{
+			{Severity: "critical", Passed: false},
+			{Severity: "warning", Passed: true},
+		},
+		weights: &models.DatabaseHealthWeights{Wraparound: 1, HealthChecks: 1, Anomalies: 1},
+	}
+	wrap := &mockWraparound{status: &models.WraparoundStatus{CollectedAt: &collectedAt, Level: models.WraparoundLevelWarning, HealthScore: 70}}
+	anomalies := &mockAnomalies{report: &models.LogTemplateReport{TotalMessages: 20, Anomalies: 1}}
+	service := NewService(store, wrap, anomalies, zap.NewNop())
+
+	score, err := service.Calculate(context.Background(), collectorID, now)
+	require.NoError(t, err)
+	require.Len(t, store.stored, 1)
+	assert.Same(t, score, store.stored[0])
+	assert.Equal(t, *store.weights, score.Weights)
+
+	assert.Equal(t, now.Add(-AnomalyWindow), anomalies.from)
+	assert.Equal(t, now, anomalies.to)
+	assert.True(t, anomalies.opts.AnomaliesOnly)
+
+	wraparound := component(t, score, models.DatabaseHealthWraparound)
+	assert.True(t, wraparound.Available)
+	assert.Equal(t, 70.0, wraparound.Score)
+	assert.Contains(t, wraparound.Explanation, "warning")
+
+	checks := component(t, score, models.DatabaseHealthChecks)
+	assert.True(t, checks.Available)
+	assert.Less(t, checks.Score, 100.0)
+
+	assert.Equal(t, 80.0, component(t, score, models.DatabaseHealthAnomalies).Score)
+}
+
+func TestCalculateUsesDefaultWeights(t *testing.T) {
+	store := &mockHealthStore{inputs: &models.DatabaseHealthInputs{CacheHitRatio: float(0.99)}}
+	service := NewService(store, &mockWraparound{}, &mockAnomalies{}, zap.NewNop())
+
+	score, err := service.Calculate(context.Background(), uuid.New(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultDatabaseHealthWeights, score.Weights)
+	assert.Equal(t, 100, score.HealthScore)
+
+	// Without samples the wraparound and anomaly components are unavailable
+	assert.False(t, component(t, score, models.DatabaseHealthWraparound).Available)
+	assert.False(t, component(t, score, models.DatabaseHealthAnomalies).Available)
+}
+
+func TestCalculateWithoutData(t *testing.T) {
+	store := &mockHealthStore{}
+	service := NewService(store, &mockWraparound{}, &mockAnomalies{}, zap.NewNop())
+
+	_, err := service.Calculate(context.Background(), uuid.New(), time.Now())
+	require.Error(t, err)
+	assert.Equal(t, 404, err.(*apperrors.AppError).StatusCode)
+	assert.Empty(t, store.stored)
+}
+
+func TestWeights(t *testing.T) {
+	store := &mockHealthStore{}
+	service := NewService(store, &mockWraparound{}, &mockAnomalies{}, zap.NewNop())
+
+	weights, err := service.Weights(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultDatabaseHealthWeights, *weights)
+
+	custom := &models.DatabaseHealthWeights{CacheHit: 2}
+	require.NoError(t, service.SetWeights(context.Background(), uuid.New(), custom))
+	assert.Same(t, custom, store.storedWeights)
+
+	err = service.SetWeights(context.Background(), uuid.New(), &models.DatabaseHealthWeights{})
+	require.Error(t, err)
+	assert.Same(t, custom, store.storedWeights)
+}
+
+func TestRanking(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tenantID := uuid.New()
+	good, bad, worse, unscored := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	store := &mockHealthStore{
+		collectors: []*models.Collector{
+			{ID: unscored, Name: "d", Hostname: "db-d"},
+			{ID: good, Name: "a", Hostname: "db-a"},
+			{ID: bad, Name: "b", Hostname: "db-b"},
+			{ID: worse, Name: "c", Hostname: "db-c"},
+		},
+		scores: map[uuid.UUID]*models.DatabaseHealthScore{
+			good: {CollectorID: good, Time: now, HealthScore: 95, Status: "healthy"},
+			bad:  {CollectorID: bad, Time: now, HealthScore: 55, Status: "warning"},
+			worse: {CollectorID: worse, Time: now, HealthScore: 30, Status: "critical",
+				Components: []*models.DatabaseHealthComponent{
+					{Name: models.DatabaseHealthCacheHit, Available: true, Score: 80, Weight: 1},
+					{Name: models.DatabaseHealthConnections, Available: true, Score: 5, Weight: 1},
+				}},
+		},
+	}
+	service := NewService(store, &mockWraparound{}, &mockAnomalies{}, zap.NewNop())
+
+	ranking, err := service.Ranking(context.Background(), tenantID, now)
+	require.NoError(t, err)
+	assert.Equal(t, tenantID, ranking.TenantID)
+	assert.Equal(t, now.Add(-RankingWindow), store.rankingSince)
+	assert.Equal(t, 1, ranking.Unscored)
+	require.Len(t, ranking.Collectors, 4)
+
+	order := []uuid.UUID{}
+	for _, r := range ranking.Collectors {
+		order = append(order, r.CollectorID)
+	}
+	assert.Equal(t, []uuid.UUID{worse, bad, good, unscored}, order)
+
+	assert.Equal(t, 1, ranking.Collectors[0].Rank)
+	assert.Equal(t, models.DatabaseHealthConnections, ranking.Collectors[0].Weakest)
+	assert.Equal(t, 30, *ranking.Collectors[0].HealthScore)
+	assert.Equal(t, 0, ranking.Collectors[3].Rank)
+	assert.Nil(t, ranking.Collectors[3].HealthScore)
+	assert.Equal(t, "unknown", ranking.Collectors[3].Status)
+}
+
+func TestRankingWithoutCollectors(t *testing.T) {
+	store := &mockHealthStore{}
+	service := NewService(store, &mockWraparound{}, &mockAnomalies{}, zap.NewNop())
+
+	ranking, err := service.Ranking(context.Background(), uuid.New(), time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, ranking.Collectors)
+	assert.True(t, store.rankingSince.IsZero())
+}
@@ -0,0 +1,30 @@
+package lock_analysis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ParseAlertCondition decodes and validates the condition of a blocking_chain
+// alert rule
+func ParseAlertCondition(raw json.RawMessage) (*models.BlockingChainAlertCondition, error) {
+	var cond models.BlockingChainAlertCondition
+	if err := json.Unmarshal(raw, &cond); err != nil {
+		return nil, fmt.Errorf("unmarshal blocking_chain condition: %w", err)
+	}
+
+	if cond.CollectorID == uuid.Nil {
+		return nil, fmt.Errorf("blocking_chain condition needs a collector_id")
+	}
+	if cond.MinWaiters < 0 {
+		return nil, fmt.Errorf("min_waiters must not be negative")
+	}
+	if cond.MinDurationSeconds < 0 {
+		return nil, fmt.Errorf("min_duration_seconds must not be negative")
+	}
+
+	return &cond, nil
+}
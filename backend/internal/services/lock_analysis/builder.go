@@ -0,0 +1,383 @@
+package lock_analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// LongChainDepth is the chain depth from which a blocking tree is reported
+// as a long chain: a waiter blocked by a waiter blocked by the root
+const LongChainDepth = 3
+
+// waitGraph is the wait-for graph of a lock snapshot
+type waitGraph struct {
+	blockers map[int][]int // waiter pid -> pids it waits on
+	waiters  map[int][]int // blocker pid -> pids waiting on it
+	nodes    map[int]*models.BlockingNode
+	waitLock map[int]*models.Lock   // pid -> lock it is queued for
+	held     map[int][]*models.Lock // pid -> granted locks
+}
+
+// BuildBlockingTrees reconstructs blocking trees from one lock snapshot
+// Each tree starts at a root blocker, a backend that blocks others without
+// waiting itself. Backends in a wait cycle have no such root; the cycle
+// member with the lowest pid is used instead and the cycle is reported as a
+// deadlock. Every waiter appears once: a waiter blocked by several backends
+// is placed under the one closest to a root.
+func BuildBlockingTrees(locks []*models.Lock, waits []*models.LockWait) ([]*models.BlockingTree, []*models.LockFinding) {
+	g := newWaitGraph(locks, waits)
+
+	var findings []*models.LockFinding
+	roots := g.roots()
+	for _, cycle := range g.cycles() {
+		findings = append(findings, &models.LockFinding{
+			Type:     models.LockFindingDeadlock,
+			Severity: "critical",
+			PIDs:     cycle,
+			Message:  fmt.Sprintf("Backends %s wait on each other in a cycle", joinPIDs(cycle)),
+		})
+	}
+	roots = append(roots, g.cycleRoots(roots)...)
+
+	trees := g.buildForest(roots)
+
+	sort.SliceStable(trees, func(i, j int) bool {
+		if trees[i].TotalWaiters != trees[j].TotalWaiters {
+			return trees[i].TotalWaiters > trees[j].TotalWaiters
+		}
+		return trees[i].Root.PID < trees[j].Root.PID
+	})
+
+	findings = append(findings, g.patternFindings(trees)...)
+	return trees, findings
+}
+
+// newWaitGraph indexes a snapshot's locks and blocked/blocking pairs
+// Duplicate pairs, as reported when pg_locks is read through several
+// database connections, are collapsed.
+func newWaitGraph(locks []*models.Lock, waits []*models.LockWait) *waitGraph {
+	g := &waitGraph{
+		blockers: make(map[int][]int),
+		waiters:  make(map[int][]int),
+		nodes:    make(map[int]*models.BlockingNode),
+		waitLock: make(map[int]*models.Lock),
+		held:     make(map[int][]*models.Lock),
+	}
+
+	for _, l := range locks {
+		if l.Granted {
+			g.held[l.PID] = append(g.held[l.PID], l)
+		} else if _, ok := g.waitLock[l.PID]; !ok {
+			g.waitLock[l.PID] = l
+		}
+		n := g.info(l.PID)
+		if n.Username == "" && l.Username != nil {
+			n.Username = *l.Username
+		}
+		if n.SessionState == "" && l.SessionState != nil {
+			n.SessionState = *l.SessionState
+		}
+		if n.Query == "" && l.Query != nil {
+			n.Query = *l.Query
+		}
+	}
+
+	pairs := make(map[[2]int]bool)
+	for _, w := range waits {
+		if w.BlockedPID == w.BlockingPID || pairs[[2]int{w.BlockedPID, w.BlockingPID}] {
+			continue
+		}
+		pairs[[2]int{w.BlockedPID, w.BlockingPID}] = true
+		g.blockers[w.BlockedPID] = append(g.blockers[w.BlockedPID], w.BlockingPID)
+		g.waiters[w.BlockingPID] = append(g.waiters[w.BlockingPID], w.BlockedPID)
+
+		blocked := g.info(w.BlockedPID)
+		blocked.Username, blocked.ApplicationName, blocked.Query = w.BlockedUsername, w.BlockedApplication, w.BlockedQuery
+		if w.WaitTimeSeconds != nil {
+			wait := *w.WaitTimeSeconds
+			blocked.WaitTimeSeconds = &wait
+		}
+		blocking := g.info(w.BlockingPID)
+		blocking.Username, blocking.ApplicationName, blocking.Query = w.BlockingUsername, w.BlockingApplication, w.BlockingQuery
+	}
+
+	for _, pids := range g.blockers {
+		sort.Ints(pids)
+	}
+	for _, pids := range g.waiters {
+		sort.Ints(pids)
+	}
+
+	return g
+}
+
+// info returns the shared backend details for a pid
+func (g *waitGraph) info(pid int) *models.BlockingNode {
+	n, ok := g.nodes[pid]
+	if !ok {
+		n = &models.BlockingNode{PID: pid}
+		g.nodes[pid] = n
+	}
+	return n
+}
+
+// node returns a tree node for pid; parent is the backend it waits on
+func (g *waitGraph) node(pid int, parent *int) *models.BlockingNode {
+	n := *g.info(pid)
+	n.Waiters = []*models.BlockingNode{}
+	if parent == nil {
+		n.WaitTimeSeconds = nil
+		return &n
+	}
+
+	if wl, ok := g.waitLock[pid]; ok {
+		edge := &models.LockEdge{LockType: wl.LockType, WaitMode: wl.Mode, RelationID: wl.RelationID}
+		for _, h := range g.held[*parent] {
+			if sameLockTarget(h, wl) {
+				edge.HeldMode = h.Mode
+				break
+			}
+		}
+		n.WaitingOn = edge
+	}
+	return &n
+}
+
+// buildForest builds one tree per root, visiting each backend once. Roots
+// are expanded breadth first together, so a waiter blocked by several
+// backends is attached to the one closest to a root, ties going to the
+// blocker expanded first.
+func (g *waitGraph) buildForest(roots []int) []*models.BlockingTree {
+	type queued struct {
+		node  *models.BlockingNode
+		tree  *models.BlockingTree
+		depth int
+	}
+
+	visited := make(map[int]bool, len(g.nodes))
+	trees := make([]*models.BlockingTree, 0, len(roots))
+	queue := make([]queued, 0, len(g.nodes))
+	for _, pid := range roots {
+		visited[pid] = true
+		tree := &models.BlockingTree{Root: g.node(pid, nil)}
+		trees = append(trees, tree)
+		queue = append(queue, queued{node: tree.Root, tree: tree})
+	}
+
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+		pid := q.node.PID
+		for _, w := range g.waiters[pid] {
+			if visited[w] {
+				continue
+			}
+			visited[w] = true
+
+			child := g.node(w, &pid)
+			q.node.Waiters = append(q.node.Waiters, child)
+			q.tree.TotalWaiters++
+			if q.depth+1 > q.tree.Depth {
+				q.tree.Depth = q.depth + 1
+			}
+			if child.WaitTimeSeconds != nil && *child.WaitTimeSeconds > q.tree.MaxWaitSeconds {
+				q.tree.MaxWaitSeconds = *child.WaitTimeSeconds
+			}
+			queue = append(queue, queued{node: child, tree: q.tree, depth: q.depth + 1})
+		}
+	}
+
+	return trees
+}
+
+// roots returns backends that block others without waiting themselves
+func (g *waitGraph) roots() []int {
+	var roots []int
+	for pid := range g.waiters {
+		if len(g.blockers[pid]) == 0 {
+			roots = append(roots, pid)
+		}
+	}
+	sort.Ints(roots)
+	return roots
+}
+
+// cycleRoots picks a root for every group of waiters not reachable from a
+// real root blocker, which only happens when they wait in a cycle
+func (g *waitGraph) cycleRoots(roots []int) []int {
+	reached := make(map[int]bool)
+	var mark func(pid int)
+	mark = func(pid int) {
+		if reached[pid] {
+			return
+		}
+		reached[pid] = true
+		for _, w := range g.waiters[pid] {
+			mark(w)
+		}
+	}
+	for _, r := range roots {
+		mark(r)
+	}
+
+	var extra []int
+	for _, cycle := range g.cycles() {
+		if !reached[cycle[0]] {
+			extra = append(extra, cycle[0])
+			mark(cycle[0])
+		}
+	}
+	return extra
+}
+
+// cycles returns the wait cycles in the graph, each as sorted pids
+func (g *waitGraph) cycles() [][]int {
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	state := make(map[int]int)
+	var stack []int
+	found := make(map[string][]int)
+
+	var visit func(pid int)
+	visit = func(pid int) {
+		state[pid] = onStack
+		stack = append(stack, pid)
+		for _, b := range g.blockers[pid] {
+			switch state[b] {
+			case unvisited:
+				visit(b)
+			case onStack:
+				start := len(stack) - 1
+				for stack[start] != b {
+					start--
+				}
+				cycle := append([]int(nil), stack[start:]...)
+				sort.Ints(cycle)
+				found[joinPIDs(cycle)] = cycle
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[pid] = done
+	}
+
+	pids := make([]int, 0, len(g.blockers))
+	for pid := range g.blockers {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	for _, pid := range pids {
+		if state[pid] == unvisited {
+			visit(pid)
+		}
+	}
+
+	keys := make([]string, 0, len(found))
+	for k := range found {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	cycles := make([][]int, 0, len(keys))
+	for _, k := range keys {
+		cycles = append(cycles, found[k])
+	}
+	return cycles
+}
+
+// patternFindings reports long chains and deadlock-prone patterns
+func (g *waitGraph) patternFindings(trees []*models.BlockingTree) []*models.LockFinding {
+	var findings []*models.LockFinding
+
+	for _, tree := range trees {
+		root := tree.Root
+		if tree.Depth >= LongChainDepth {
+			findings = append(findings, &models.LockFinding{
+				Type:     models.LockFindingLongChain,
+				Severity: "warning",
+				PIDs:     []int{root.PID},
+				Message: fmt.Sprintf("Blocking chain of depth %d below pid %d (%d waiters)",
+					tree.Depth, root.PID, tree.TotalWaiters),
+			})
+		}
+		if strings.HasPrefix(root.SessionState, "idle in transaction") {
+			findings = append(findings, &models.LockFinding{
+				Type:     models.LockFindingIdleInTransaction,
+				Severity: "warning",
+				PIDs:     []int{root.PID},
+				Message: fmt.Sprintf("Root blocker pid %d is idle in transaction while %d backends wait on it",
+					root.PID, tree.TotalWaiters),
+			})
+		}
+	}
+
+	waiting := make([]int, 0, len(g.waitLock))
+	for pid := range g.waitLock {
+		if len(g.blockers[pid]) > 0 {
+			waiting = append(waiting, pid)
+		}
+	}
+	sort.Ints(waiting)
+
+	for _, pid := range waiting {
+		wl := g.waitLock[pid]
+
+		// Holding a weaker lock on the relation it waits for: two sessions
+		// doing this on the same relation deadlock
+		for _, h := range g.held[pid] {
+			if wl.RelationID != nil && sameLockTarget(h, wl) {
+				findings = append(findings, &models.LockFinding{
+					Type:     models.LockFindingLockUpgrade,
+					Severity: "warning",
+					PIDs:     []int{pid},
+					Message: fmt.Sprintf("pid %d holds %s and waits to upgrade to %s on the same %s",
+						pid, h.Mode, wl.Mode, wl.LockType),
+				})
+				break
+			}
+		}
+
+		// A queued AccessExclusiveLock blocks every later lock request on the
+		// relation, even ones compatible with the current holder
+		if wl.Mode == "AccessExclusiveLock" && len(g.waiters[pid]) > 0 {
+			findings = append(findings, &models.LockFinding{
+				Type:     models.LockFindingExclusiveLockQueued,
+				Severity: "warning",
+				PIDs:     append([]int{pid}, g.waiters[pid]...),
+				Message: fmt.Sprintf("pid %d is queued for AccessExclusiveLock and blocks %d backends behind it",
+					pid, len(g.waiters[pid])),
+			})
+		}
+	}
+
+	return findings
+}
+
+// sameLockTarget reports whether two locks are on the same lockable object
+// Only relation, page and tuple are stored, so locks on transaction ids and
+// other objects match on their lock type alone.
+func sameLockTarget(a, b *models.Lock) bool {
+	return a.LockType == b.LockType &&
+		equalOptionalInt(a.RelationID, b.RelationID) &&
+		equalOptionalInt(a.PageNumber, b.PageNumber) &&
+		equalOptionalInt(a.TupleID, b.TupleID)
+}
+
+func equalOptionalInt(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+func joinPIDs(pids []int) string {
+	parts := make([]string, len(pids))
+	for i, pid := range pids {
+		parts[i] = fmt.Sprintf("%d", pid)
+	}
+	return strings.Join(parts, ", ")
+}
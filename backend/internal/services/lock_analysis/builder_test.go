@@ -0,0 +1,173 @@
+package lock_analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+func intPtr(v int) *int { return &v }
+
+func floatPtr(v float64) *float64 { return &v }
+
+func strPtr(v string) *string { return &v }
+
+func relLock(pid int, mode string, granted bool, relation int) *models.Lock {
+	return &models.Lock{PID: pid, LockType: "relation", Mode: mode, Granted: granted, RelationID: intPtr(relation)}
+}
+
+func wait(blocked, blocking int, seconds float64) *models.LockWait {
+	return &models.LockWait{BlockedPID: blocked, BlockingPID: blocking, WaitTimeSeconds: floatPtr(seconds)}
+}
+
+func findingTypes(findings []*models.LockFinding) []string {
+	types := make([]string, 0, len(findings))
+	for _, f := range findings {
+		types = append(types, f.Type)
+	}
+	return types
+}
+
+// TestBuildBlockingTrees_Chain builds a chain with lock edges and depth
+func TestBuildBlockingTrees_Chain(t *testing.T) {
+	locks := []*models.Lock{
+		relLock(100, "RowExclusiveLock", true, 16384),
+		relLock(200, "ShareLock", false, 16384),
+		relLock(200, "ShareLock", true, 16400),
+		relLock(300, "AccessShareLock", false, 16400),
+		relLock(400, "AccessShareLock", false, 16400),
+	}
+	waits := []*models.LockWait{
+		wait(200, 100, 30),
+		wait(300, 200, 10),
+		wait(400, 300, 5),
+		wait(200, 100, 30), // reported again through another database
+	}
+
+	trees, findings := BuildBlockingTrees(locks, waits)
+	require.Len(t, trees, 1)
+
+	tree := trees[0]
+	assert.Equal(t, 100, tree.Root.PID)
+	assert.Nil(t, tree.Root.WaitTimeSeconds)
+	assert.Equal(t, 3, tree.TotalWaiters)
+	assert.Equal(t, 3, tree.Depth)
+	assert.Equal(t, 30.0, tree.MaxWaitSeconds)
+
+	require.Len(t, tree.Root.Waiters, 1)
+	child := tree.Root.Waiters[0]
+	assert.Equal(t, 200, child.PID)
+	require.NotNil(t, child.WaitingOn)
+	assert.Equal(t, "ShareLock", child.WaitingOn.WaitMode)
+	assert.Equal(t, "RowExclusiveLock", child.WaitingOn.HeldMode)
+	assert.Equal(t, 16384, *child.WaitingOn.RelationID)
+
+	require.Len(t, child.Waiters, 1)
+	assert.Equal(t, "ShareLock", child.Waiters[0].WaitingOn.HeldMode)
+
+	assert.Equal(t, []string{models.LockFindingLongChain}, findingTypes(findings))
+}
+
+// TestBuildBlockingTrees_MultipleRoots orders trees by waiter count and
+// attaches a waiter blocked by both roots to one of them
+func TestBuildBlockingTrees_MultipleRoots(t *testing.T) {
+	waits := []*models.LockWait{
+		wait(11, 10, 1),
+		wait(21, 20, 1),
+		wait(22, 20, 2),
+		wait(22, 10, 2), // blocked by both roots
+	}
+
+	trees, findings := BuildBlockingTrees(nil, waits)
+	require.Len(t, trees, 2)
+	assert.Equal(t, 10, trees[0].Root.PID)
+	assert.Equal(t, 2, trees[0].TotalWaiters)
+	assert.Equal(t, 20, trees[1].Root.PID)
+	assert.Equal(t, 1, trees[1].TotalWaiters)
+	assert.Equal(t, 1, trees[0].Depth)
+	require.Len(t, trees[0].Root.Waiters, 2)
+	assert.Equal(t, 22, trees[0].Root.Waiters[1].PID)
+	assert.Empty(t, findings)
+}
+
+// TestBuildBlockingTrees_QueuedWaiters builds a lock queue in which every
+// waiter is reported as blocked by all waiters ahead of it, as collectors
+// that also match ungranted locks report it
+func TestBuildBlockingTrees_QueuedWaiters(t *testing.T) {
+	const queued = 50
+	var waits []*models.LockWait
+	for pid := 2; pid <= queued+1; pid++ {
+		for ahead := 1; ahead < pid; ahead++ {
+			waits = append(waits, wait(pid, ahead, float64(queued+2-pid)))
+		}
+	}
+
+	start := time.Now()
+	trees, _ := BuildBlockingTrees(nil, waits)
+	assert.Less(t, time.Since(start), time.Second)
+
+	require.Len(t, trees, 1)
+	assert.Equal(t, 1, trees[0].Root.PID)
+	assert.Equal(t, queued, trees[0].TotalWaiters)
+	assert.Equal(t, 1, trees[0].Depth)
+	assert.Equal(t, float64(queued), trees[0].MaxWaitSeconds)
+}
+
+// TestBuildBlockingTrees_Deadlock reports a cycle and still builds a tree
+func TestBuildBlockingTrees_Deadlock(t *testing.T) {
+	waits := []*models.LockWait{
+		wait(1, 2, 3),
+		wait(2, 1, 4),
+		wait(3, 2, 1),
+	}
+
+	trees, findings := BuildBlockingTrees(nil, waits)
+	require.Len(t, trees, 1)
+	assert.Equal(t, 1, trees[0].Root.PID)
+	assert.Equal(t, 2, trees[0].TotalWaiters)
+
+	require.Len(t, findings, 1)
+	assert.Equal(t, models.LockFindingDeadlock, findings[0].Type)
+	assert.Equal(t, "critical", findings[0].Severity)
+	assert.Equal(t, []int{1, 2}, findings[0].PIDs)
+}
+
+// TestBuildBlockingTrees_Patterns detects deadlock-prone lock patterns
+func TestBuildBlockingTrees_Patterns(t *testing.T) {
+	root := relLock(100, "AccessShareLock", true, 16384)
+	root.SessionState = strPtr("idle in transaction")
+	locks := []*models.Lock{
+		root,
+		// pid 200 holds a share lock and waits to upgrade it
+		relLock(200, "AccessShareLock", true, 16384),
+		relLock(200, "AccessExclusiveLock", false, 16384),
+		relLock(300, "AccessShareLock", false, 16384),
+	}
+	waits := []*models.LockWait{
+		wait(200, 100, 20),
+		wait(300, 200, 10),
+	}
+
+	_, findings := BuildBlockingTrees(locks, waits)
+	assert.ElementsMatch(t, []string{
+		models.LockFindingIdleInTransaction,
+		models.LockFindingLockUpgrade,
+		models.LockFindingExclusiveLockQueued,
+	}, findingTypes(findings))
+
+	for _, f := range findings {
+		if f.Type == models.LockFindingExclusiveLockQueued {
+			assert.Equal(t, []int{200, 300}, f.PIDs)
+		}
+	}
+}
+
+// TestBuildBlockingTrees_Empty returns no trees without waits
+func TestBuildBlockingTrees_Empty(t *testing.T) {
+	trees, findings := BuildBlockingTrees([]*models.Lock{relLock(1, "AccessShareLock", true, 1)}, nil)
+	assert.Empty(t, trees)
+	assert.Empty(t, findings)
+}
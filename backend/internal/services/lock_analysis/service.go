@@ -0,0 +1,83 @@
+package lock_analysis
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Store interface for lock snapshot data
+type Store interface {
+	GetLockSnapshotTime(ctx context.Context, collectorID uuid.UUID, at time.Time) (*time.Time, error)
+	GetLockSnapshot(ctx context.Context, collectorID uuid.UUID, snapshotTime time.Time, database *string) ([]*models.Lock, []*models.LockWait, error)
+}
+
+// Service provides blocking-chain analysis over stored lock snapshots
+type Service struct {
+	store  Store
+	logger *zap.Logger
+}
+
+// NewService creates a new lock analysis service
+func NewService(store Store, logger *zap.Logger) *Service {
+	return &Service{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// GetBlockingTree reconstructs the blocking trees from the latest lock
+// snapshot taken at or before at
+func (s *Service) GetBlockingTree(ctx context.Context, collectorID uuid.UUID, at time.Time, database *string) (*models.BlockingTreeSnapshot, error) {
+	snapshot := &models.BlockingTreeSnapshot{
+		CollectorID: collectorID,
+		Trees:       []*models.BlockingTree{},
+		Findings:    []*models.LockFinding{},
+	}
+
+	snapshotTime, err := s.store.GetLockSnapshotTime(ctx, collectorID, at)
+	if err != nil {
+		s.logger.Error("Failed to find lock snapshot", zap.Error(err))
+		return nil, err
+	}
+	if snapshotTime == nil {
+		return snapshot, nil
+	}
+	snapshot.SnapshotTime = snapshotTime
+
+	locks, waits, err := s.store.GetLockSnapshot(ctx, collectorID, *snapshotTime, database)
+	if err != nil {
+		s.logger.Error("Failed to load lock snapshot", zap.Error(err))
+		return nil, err
+	}
+
+	trees, findings := BuildBlockingTrees(locks, waits)
+	snapshot.Trees = trees
+	if findings != nil {
+		snapshot.Findings = findings
+	}
+
+	blocked := make(map[int]bool)
+	for _, w := range waits {
+		blocked[w.BlockedPID] = true
+	}
+	snapshot.BlockedSessions = len(blocked)
+
+	return snapshot, nil
+}
+
+// RootBlockersExceeding returns the trees whose root blocker holds more than
+// minWaiters backends and has done so for longer than minDuration, measured
+// by the longest wait in the tree
+func RootBlockersExceeding(snapshot *models.BlockingTreeSnapshot, minWaiters int, minDuration time.Duration) []*models.BlockingTree {
+	var trees []*models.BlockingTree
+	for _, tree := range snapshot.Trees {
+		if tree.TotalWaiters > minWaiters && tree.MaxWaitSeconds > minDuration.Seconds() {
+			trees = append(trees, tree)
+		}
+	}
+	return trees
+}
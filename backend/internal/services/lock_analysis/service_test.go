@@ -0,0 +1,117 @@
+package lock_analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockLockStore is a mock implementation for testing
+type mockLockStore struct {
+	snapshotTime *time.Time
+	locks        []*models.Lock
+	waits        []*models.LockWait
+	err          error
+
+	lastAt       time.Time
+	lastDatabase *string
+}
+
+func (m *mockLockStore) GetLockSnapshotTime(ctx context.Context, collectorID uuid.UUID, at time.Time) (*time.Time, error) {
+	m.lastAt = at
+	return m.snapshotTime, m.err
+}
+
+func (m *mockLockStore) GetLockSnapshot(ctx context.Context, collectorID uuid.UUID, snapshotTime time.Time, database *string) ([]*models.Lock, []*models.LockWait, error) {
+	m.lastDatabase = database
+	return m.locks, m.waits, m.err
+}
+
+// TestGetBlockingTree_NoSnapshot returns an empty result
+func TestGetBlockingTree_NoSnapshot(t *testing.T) {
+	service := NewService(&mockLockStore{}, zap.NewNop())
+
+	snapshot, err := service.GetBlockingTree(context.Background(), uuid.New(), time.Now(), nil)
+	require.NoError(t, err)
+	assert.Nil(t, snapshot.SnapshotTime)
+	assert.NotNil(t, snapshot.Trees)
+	assert.NotNil(t, snapshot.Findings)
+	assert.Zero(t, snapshot.BlockedSessions)
+}
+
+// TestGetBlockingTree_BuildsTrees builds trees from the stored snapshot
+func TestGetBlockingTree_BuildsTrees(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	taken := at.Add(-10 * time.Second)
+	database := "app"
+	store := &mockLockStore{
+		snapshotTime: &taken,
+		waits: []*models.LockWait{
+			wait(2, 1, 5),
+			wait(3, 1, 90),
+		},
+	}
+	service := NewService(store, zap.NewNop())
+
+	snapshot, err := service.GetBlockingTree(context.Background(), uuid.New(), at, &database)
+	require.NoError(t, err)
+	assert.Equal(t, at, store.lastAt)
+	assert.Equal(t, &database, store.lastDatabase)
+	assert.Equal(t, taken, *snapshot.SnapshotTime)
+	assert.Equal(t, 2, snapshot.BlockedSessions)
+	require.Len(t, snapshot.Trees, 1)
+	assert.Equal(t, 1, snapshot.Trees[0].Root.PID)
+	assert.NotNil(t, snapshot.Findings)
+}
+
+// TestGetBlockingTree_StoreError propagates store errors
+func TestGetBlockingTree_StoreError(t *testing.T) {
+	service := NewService(&mockLockStore{err: errors.New("boom")}, zap.NewNop())
+
+	_, err := service.GetBlockingTree(context.Background(), uuid.New(), time.Now(), nil)
+	assert.Error(t, err)
+}
+
+// TestRootBlockersExceeding requires both thresholds to be exceeded
+func TestRootBlockersExceeding(t *testing.T) {
+	snapshot := &models.BlockingTreeSnapshot{
+		Trees: []*models.BlockingTree{
+			{Root: &models.BlockingNode{PID: 1}, TotalWaiters: 5, MaxWaitSeconds: 120},
+			{Root: &models.BlockingNode{PID: 2}, TotalWaiters: 5, MaxWaitSeconds: 30},
+			{Root: &models.BlockingNode{PID: 3}, TotalWaiters: 3, MaxWaitSeconds: 300},
+		},
+	}
+
+	trees := RootBlockersExceeding(snapshot, 3, time.Minute)
+	require.Len(t, trees, 1)
+	assert.Equal(t, 1, trees[0].Root.PID)
+}
+
+// TestParseAlertCondition validates blocking_chain conditions
+func TestParseAlertCondition(t *testing.T) {
+	collectorID := uuid.New()
+	cond, err := ParseAlertCondition(json.RawMessage(`{"collector_id":"` + collectorID.String() + `","min_waiters":3,"min_duration_seconds":30}`))
+	require.NoError(t, err)
+	assert.Equal(t, collectorID, cond.CollectorID)
+	assert.Equal(t, 3, cond.MinWaiters)
+	assert.Equal(t, 30, cond.MinDurationSeconds)
+
+	for name, raw := range map[string]string{
+		"collector":    `{"min_waiters":3,"min_duration_seconds":30}`,
+		"invalid uuid": `{"collector_id":"not-a-uuid","min_waiters":3}`,
+		"waiters":      `{"collector_id":"` + collectorID.String() + `","min_waiters":-1}`,
+		"duration":     `{"collector_id":"` + collectorID.String() + `","min_duration_seconds":-30}`,
+		"json":         `{`,
+	} {
+		_, err := ParseAlertCondition(json.RawMessage(raw))
+		assert.Error(t, err, name)
+	}
+}
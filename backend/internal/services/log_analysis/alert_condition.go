@@ -0,0 +1,139 @@
+package log_analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+const (
+	// DefaultLogAlertWindowSeconds is the window of a log alert condition
+	// that does not set one
+	DefaultLogAlertWindowSeconds = 60
+	// MaxLogAlertWindowSeconds bounds log alert windows to a day, which
+	// keeps each evaluation a bounded scan of recent logs
+	MaxLogAlertWindowSeconds = 24 * 60 * 60
+)
+
+var (
+	logAlertCategoryPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	logAlertSQLStatePattern = regexp.MustCompile(`^[0-9A-Z]{2}([0-9A-Z]{3})?$`)
+)
+
+// ParseLogAlertCondition decodes and validates the condition of a log alert
+// rule, normalizing severity and SQLSTATE to upper case
+func ParseLogAlertCondition(raw json.RawMessage) (*models.LogAlertCondition, error) {
+	var cond models.LogAlertCondition
+	if err := json.Unmarshal(raw, &cond); err != nil {
+		return nil, fmt.Errorf("unmarshal log condition: %w", err)
+	}
+
+	cond.MinSeverity = strings.ToUpper(strings.TrimSpace(cond.MinSeverity))
+	cond.SQLState = strings.ToUpper(strings.TrimSpace(cond.SQLState))
+
+	if cond.Category == "" && cond.MinSeverity == "" && cond.SQLState == "" && cond.Pattern == "" {
+		return nil, fmt.Errorf("log condition needs a category, min_severity, sqlstate or pattern")
+	}
+	if cond.Category != "" && !logAlertCategoryPattern.MatchString(cond.Category) {
+		return nil, fmt.Errorf("invalid category %q", cond.Category)
+	}
+	if cond.MinSeverity != "" && !ValidLogLevel(cond.MinSeverity) {
+		return nil, fmt.Errorf("invalid min_severity %q", cond.MinSeverity)
+	}
+	if cond.SQLState != "" && !logAlertSQLStatePattern.MatchString(cond.SQLState) {
+		return nil, fmt.Errorf("invalid sqlstate %q: use a 5 character code or a 2 character class", cond.SQLState)
+	}
+	if cond.Pattern != "" {
+		if _, err := regexp.Compile(cond.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		if err := checkPortablePattern(cond.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+	}
+
+	if cond.WindowSeconds == 0 {
+		cond.WindowSeconds = DefaultLogAlertWindowSeconds
+	}
+	if cond.WindowSeconds < 0 || cond.WindowSeconds > MaxLogAlertWindowSeconds {
+		return nil, fmt.Errorf("window_seconds must be between 1 and %d", MaxLogAlertWindowSeconds)
+	}
+	if cond.Threshold < 0 {
+		return nil, fmt.Errorf("threshold cannot be negative")
+	}
+
+	switch cond.GroupBy {
+	case "", models.LogAlertGroupByDatabase, models.LogAlertGroupByUser:
+	default:
+		return nil, fmt.Errorf("invalid group_by %q: use database or user", cond.GroupBy)
+	}
+
+	return &cond, nil
+}
+
+// checkPortablePattern limits a pattern to the syntax that Go and PostgreSQL,
+// which matches patterns with ~, read the same way. Escapes are limited to
+// \d, \s, \w, their negations outside brackets and escaped punctuation: \b
+// for instance is a word boundary in Go but a backspace in PostgreSQL. Of the
+// (?...) groups only (?:...) is allowed.
+func checkPortablePattern(pattern string) error {
+	inBracket := false
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\':
+			if i+1 == len(pattern) {
+				return fmt.Errorf("trailing backslash")
+			}
+			i++
+			e := pattern[i]
+			switch {
+			case strings.IndexByte("dsw", e) >= 0:
+			case strings.IndexByte("DSW", e) >= 0 && !inBracket:
+			case !isASCIIAlphanumeric(e):
+			default:
+				return fmt.Errorf("escape \\%c is not supported", e)
+			}
+		case inBracket:
+			if c == ']' {
+				inBracket = false
+			}
+		case c == '[':
+			inBracket = true
+			// A leading ] (after an optional ^) is a literal member
+			if strings.HasPrefix(pattern[i+1:], "^") {
+				i++
+			}
+			if strings.HasPrefix(pattern[i+1:], "]") {
+				i++
+			}
+		case c == '(' && strings.HasPrefix(pattern[i+1:], "?") && !strings.HasPrefix(pattern[i+1:], "?:"):
+			return fmt.Errorf("only (?:...) groups are supported")
+		}
+	}
+	return nil
+}
+
+func isASCIIAlphanumeric(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// LogLevelsAtOrAbove returns the stored log levels ranking at or above a
+// minimum level, for filtering in queries
+func LogLevelsAtOrAbove(minLevel string) []string {
+	minRank, ok := logLevelRank[strings.ToUpper(minLevel)]
+	if !ok {
+		return nil
+	}
+	var levels []string
+	for level, rank := range logLevelRank {
+		if rank >= minRank {
+			levels = append(levels, level)
+		}
+	}
+	sort.Strings(levels)
+	return levels
+}
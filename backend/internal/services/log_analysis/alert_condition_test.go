@@ -0,0 +1,58 @@
+package log_analysis
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseLogAlertCondition validates and normalizes log alert conditions
+func TestParseLogAlertCondition(t *testing.T) {
+	cond, err := ParseLogAlertCondition(json.RawMessage(`{"sqlstate":"40p01","min_severity":"error","window_seconds":300}`))
+	require.NoError(t, err)
+	assert.Equal(t, "40P01", cond.SQLState)
+	assert.Equal(t, "ERROR", cond.MinSeverity)
+	assert.Equal(t, 300, cond.WindowSeconds)
+	assert.Zero(t, cond.Threshold)
+
+	cond, err = ParseLogAlertCondition(json.RawMessage(`{"sqlstate":"28","threshold":5,"group_by":"database"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "28", cond.SQLState)
+	assert.Equal(t, DefaultLogAlertWindowSeconds, cond.WindowSeconds)
+
+	// Patterns are limited to syntax PostgreSQL reads like Go
+	cond, err = ParseLogAlertCondition(json.RawMessage(`{"pattern":"^password authentication failed for user \"[^\"]+\"$|(?:no|bad) \\w+ \\(\\d+\\)|[\\d.]+s"}`))
+	require.NoError(t, err)
+	assert.NotEmpty(t, cond.Pattern)
+
+	for name, raw := range map[string]string{
+		"no matcher":        `{"threshold":5}`,
+		"invalid category":  `{"category":"Auth Errors"}`,
+		"invalid severity":  `{"min_severity":"LOUD"}`,
+		"invalid sqlstate":  `{"sqlstate":"40P0"}`,
+		"invalid pattern":   `{"pattern":"("}`,
+		"word boundary":     `{"pattern":"\\bdeadlock\\b"}`,
+		"unicode class":     `{"pattern":"\\pL+"}`,
+		"named group":       `{"pattern":"(?P<table>\\w+)"}`,
+		"inline flags":      `{"pattern":"(?i)deadlock"}`,
+		"negative window":   `{"category":"deadlock","window_seconds":-1}`,
+		"window too long":   `{"category":"deadlock","window_seconds":86401}`,
+		"negative count":    `{"category":"deadlock","threshold":-1}`,
+		"invalid group_by":  `{"category":"deadlock","group_by":"host"}`,
+		"malformed":         `{"category":`,
+		"wrong field types": `{"category":"deadlock","threshold":"five"}`,
+	} {
+		_, err := ParseLogAlertCondition(json.RawMessage(raw))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestLogLevelsAtOrAbove lists the levels matching a minimum severity
+func TestLogLevelsAtOrAbove(t *testing.T) {
+	assert.Equal(t, []string{"ERROR", "FATAL", "PANIC"}, LogLevelsAtOrAbove("error"))
+	assert.Contains(t, LogLevelsAtOrAbove("WARNING"), "WARNING")
+	assert.NotContains(t, LogLevelsAtOrAbove("WARNING"), "LOG")
+	assert.Nil(t, LogLevelsAtOrAbove("LOUD"))
+}
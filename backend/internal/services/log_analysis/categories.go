@@ -18,75 +18,95 @@ const (
 	CategoryWALError            LogCategory = "wal_error"
 	CategoryOutOfMemory         LogCategory = "out_of_memory"
 	CategoryDiskFull            LogCategory = "disk_full"
+	CategoryQueryCanceled       LogCategory = "query_canceled"
+	CategoryPermissionDenied    LogCategory = "permission_denied"
+	CategoryTransactionRollback LogCategory = "transaction_rollback"
+	CategoryResourceExhausted   LogCategory = "resource_exhausted"
+	CategoryDataException       LogCategory = "data_exception"
+	CategoryInternalError       LogCategory = "internal_error"
 	CategoryWarning             LogCategory = "warning"
 	CategoryInfo                LogCategory = "info"
 )
 
-var LogCategoryPatterns = map[LogCategory][]string{
-	CategoryDatabaseError: {
+// CategoryRule is a built-in text classification rule. Rules are tried in
+// the order of DefaultCategoryRules, so more specific categories come first.
+type CategoryRule struct {
+	Category LogCategory
+	Patterns []string
+	// ErrorsOnly rules are skipped for entries logged below WARNING, so that
+	// benign LOG lines mentioning "replication" or "WAL" are not errors
+	ErrorsOnly bool
+}
+
+// DefaultCategoryRules are the fallback rules used when an entry has no
+// SQLSTATE or its SQLSTATE is not classified
+var DefaultCategoryRules = []CategoryRule{
+	{Category: CategoryDeadlock, ErrorsOnly: true, Patterns: []string{
+		"deadlock detected",
+		"Deadlock found",
+	}},
+	{Category: CategoryLockTimeout, ErrorsOnly: true, Patterns: []string{
+		"lock timeout",
+		"could not obtain lock",
+	}},
+	{Category: CategoryOutOfMemory, ErrorsOnly: true, Patterns: []string{
+		"out of memory",
+		"OOM",
+	}},
+	{Category: CategoryDiskFull, ErrorsOnly: true, Patterns: []string{
+		"disk full",
+		"No space left on device",
+	}},
+	{Category: CategoryAuthenticationError, ErrorsOnly: true, Patterns: []string{
+		"FATAL: no pg_hba.conf entry",
+		"FATAL: password authentication failed",
+		"role .* does not exist",
+	}},
+	{Category: CategoryDatabaseError, ErrorsOnly: true, Patterns: []string{
 		"database .* does not exist",
 		"FATAL: database .* does not exist",
-	},
-	CategoryConnectionError: {
+	}},
+	{Category: CategoryConnectionError, ErrorsOnly: true, Patterns: []string{
 		"connection refused",
 		"Connection refused",
 		"FATAL: could not accept SSL connection",
-	},
-	CategoryAuthenticationError: {
-		"FATAL: no pg_hba.conf entry",
-		"FATAL: password authentication failed",
-		"role .* does not exist",
-	},
-	CategorySyntaxError: {
+	}},
+	{Category: CategorySyntaxError, ErrorsOnly: true, Patterns: []string{
 		"syntax error",
 		"ERROR: syntax error",
-	},
-	CategoryConstraintError: {
-		"constraint",
+	}},
+	{Category: CategoryConstraintError, ErrorsOnly: true, Patterns: []string{
+		"violates .* constraint",
 		"UNIQUE constraint",
 		"FOREIGN KEY constraint",
-	},
-	CategorySlowQuery: {
+		"constraint",
+	}},
+	{Category: CategorySlowQuery, Patterns: []string{
 		"duration: \\d+\\.\\d+ ms",
 		"slow query",
-	},
-	CategoryCheckpoint: {
+	}},
+	{Category: CategoryCheckpoint, Patterns: []string{
 		"LOG: checkpoint",
+		"checkpoint (starting|complete)",
 		"FATAL: checkpoint failed",
-	},
-	CategoryVacuum: {
+	}},
+	{Category: CategoryVacuum, Patterns: []string{
 		"automatic vacuum",
 		"VACUUM",
-	},
-	CategoryLongTransaction: {
+	}},
+	{Category: CategoryLongTransaction, Patterns: []string{
 		"long transaction",
 		"transaction \\d+ is still in progress",
-	},
-	CategoryLockTimeout: {
-		"lock timeout",
-		"could not obtain lock",
-	},
-	CategoryDeadlock: {
-		"deadlock detected",
-		"Deadlock found",
-	},
-	CategoryReplicationError: {
+	}},
+	{Category: CategoryReplicationError, ErrorsOnly: true, Patterns: []string{
 		"replication",
 		"standby",
-	},
-	CategoryWALError: {
+	}},
+	{Category: CategoryWALError, ErrorsOnly: true, Patterns: []string{
 		"WAL",
 		"wal",
-	},
-	CategoryOutOfMemory: {
-		"out of memory",
-		"OOM",
-	},
-	CategoryDiskFull: {
-		"disk full",
-		"No space left on device",
-	},
-	CategoryWarning: {
+	}},
+	{Category: CategoryWarning, Patterns: []string{
 		"WARNING",
-	},
+	}},
 }
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 )
 
 // LogCollector manages the ingestion and streaming of PostgreSQL logs
@@ -52,7 +54,11 @@ func (lc *LogCollector) IngestLogs(ctx context.Context, databaseID string, logs
 		}
 
 		// Classify log and extract metadata
-		category := lc.parser.ClassifyLog(message)
+		entry := &models.PostgreSQLLog{LogLevel: severity, LogMessage: message}
+		if code, ok := logEntry["error_code"].(string); ok && code != "" {
+			entry.ErrorCode = &code
+		}
+		category := lc.parser.Classify(entry)
 		metadata := lc.parser.ExtractMetadata(message)
 
 		// Extract optional metadata fields
@@ -89,75 +95,6 @@ func (lc *LogCollector) IngestLogs(ctx context.Context, databaseID string, logs
 	return nil
 }
 
-// StreamLogs continuously polls the database for new logs and sends them through a channel
-// It uses a ticker for polling at regular intervals and respects context cancellation
-func (lc *LogCollector) StreamLogs(ctx context.Context, databaseID string, ch chan<- map[string]interface{}) error {
-	ticker := time.NewTicker(10 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-			// If database is nil, return error
-			if lc.db == nil {
-				return fmt.Errorf("database connection not available")
-			}
-
-			rows, err := lc.db.QueryContext(ctx, `
-				SELECT id, log_timestamp, category, severity, message
-				FROM logs
-				WHERE database_id = $1
-				ORDER BY created_at DESC
-				LIMIT 10
-			`, databaseID)
-
-			if err != nil {
-				return fmt.Errorf("failed to query logs: %w", err)
-			}
-
-			// Process results
-			logCount := 0
-			for rows.Next() {
-				var id int64
-				var timestamp time.Time
-				var category, severity, message string
-
-				err := rows.Scan(&id, &timestamp, &category, &severity, &message)
-				if err != nil {
-					rows.Close()
-					return fmt.Errorf("failed to scan log row: %w", err)
-				}
-
-				logEntry := map[string]interface{}{
-					"id":        id,
-					"timestamp": timestamp,
-					"category":  category,
-					"severity":  severity,
-					"message":   message,
-				}
-
-				// Non-blocking send to channel
-				select {
-				case <-ctx.Done():
-					rows.Close()
-					return ctx.Err()
-				case ch <- logEntry:
-					logCount++
-				}
-			}
-
-			// Close rows to avoid resource leaks
-			err = rows.Err()
-			rows.Close()
-			if err != nil {
-				return fmt.Errorf("error iterating rows: %w", err)
-			}
-		}
-	}
-}
-
 // GetLogParser returns the underlying LogParser instance
 // Useful for direct parsing operations
 func (lc *LogCollector) GetLogParser() *LogParser {
@@ -3,7 +3,6 @@ package log_analysis
 import (
 	"context"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -108,33 +107,6 @@ func TestLogCollector_ExtractMetadata(t *testing.T) {
 	}
 }
 
-func TestLogCollector_StreamLogs_ContextCancellation(t *testing.T) {
-	collector := NewLogCollector(nil)
-	ctx, cancel := context.WithCancel(context.Background())
-
-	ch := make(chan map[string]interface{})
-
-	// Cancel immediately to test context handling
-	cancel()
-
-	// Should return context error
-	err := collector.StreamLogs(ctx, "1", ch)
-	assert.Equal(t, context.Canceled, err)
-}
-
-func TestLogCollector_StreamLogs_ContextTimeout(t *testing.T) {
-	collector := NewLogCollector(nil)
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
-	defer cancel()
-
-	ch := make(chan map[string]interface{})
-
-	// Should timeout when no database is available
-	err := collector.StreamLogs(ctx, "1", ch)
-	// Either timeout or database error is acceptable
-	assert.NotNil(t, err)
-}
-
 func TestLogCollector_IngestLogs_WithNilDB(t *testing.T) {
 	collector := NewLogCollector(nil)
 
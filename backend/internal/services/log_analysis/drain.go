@@ -0,0 +1,171 @@
+package log_analysis
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TemplateWildcard marks a variable token in a log template
+const TemplateWildcard = "<*>"
+
+// Drain parameters
+const (
+	// drainDepth is the number of leading tokens used to route a message
+	// through the parse tree
+	drainDepth = 2
+	// drainSimilarity is the minimum fraction of matching tokens for a
+	// message to join an existing template
+	drainSimilarity = 0.5
+	// drainMaxChildren bounds the fan-out of a tree node; further distinct
+	// tokens are routed through the wildcard child
+	drainMaxChildren = 100
+	// drainMaxTokens truncates very long messages before mining
+	drainMaxTokens = 64
+)
+
+// TemplateCluster is one template tracked by a TemplateMiner. ID is zero until
+// the template has been stored.
+type TemplateCluster struct {
+	ID     int64
+	Tokens []string
+	// Changed is set when the template was created or generalized since it
+	// was loaded
+	Changed bool
+}
+
+// Template returns the template text
+func (c *TemplateCluster) Template() string {
+	return strings.Join(c.Tokens, " ")
+}
+
+type drainNode struct {
+	children map[string]*drainNode
+	clusters []*TemplateCluster
+}
+
+func newDrainNode() *drainNode {
+	return &drainNode{children: make(map[string]*drainNode)}
+}
+
+// TemplateMiner groups log messages into templates using the Drain
+// algorithm: messages are routed by token count and their leading tokens to
+// a small set of candidate templates, joined to the most similar one, and
+// tokens that differ are replaced by wildcards.
+type TemplateMiner struct {
+	root *drainNode
+}
+
+// NewTemplateMiner creates an empty miner
+func NewTemplateMiner() *TemplateMiner {
+	return &TemplateMiner{root: newDrainNode()}
+}
+
+// Load adds a previously stored template without matching it
+func (m *TemplateMiner) Load(id int64, template string) *TemplateCluster {
+	cluster := &TemplateCluster{ID: id, Tokens: strings.Fields(template)}
+	leaf := m.leaf(cluster.Tokens)
+	leaf.clusters = append(leaf.clusters, cluster)
+	return cluster
+}
+
+// Add mines a message and returns the template it joined or created
+func (m *TemplateMiner) Add(message string) *TemplateCluster {
+	tokens := TokenizeLogMessage(message)
+	leaf := m.leaf(tokens)
+
+	var best *TemplateCluster
+	bestSim, bestWildcards := -1.0, -1
+	for _, cluster := range leaf.clusters {
+		sim, wildcards := similarity(cluster.Tokens, tokens)
+		if sim > bestSim || (sim == bestSim && wildcards > bestWildcards) {
+			best, bestSim, bestWildcards = cluster, sim, wildcards
+		}
+	}
+
+	if best == nil || bestSim < drainSimilarity {
+		cluster := &TemplateCluster{Tokens: tokens, Changed: true}
+		leaf.clusters = append(leaf.clusters, cluster)
+		return cluster
+	}
+
+	for i, token := range tokens {
+		if best.Tokens[i] != TemplateWildcard && best.Tokens[i] != token {
+			best.Tokens[i] = TemplateWildcard
+			best.Changed = true
+		}
+	}
+	return best
+}
+
+// leaf routes tokens to their leaf node, creating nodes as needed
+func (m *TemplateMiner) leaf(tokens []string) *drainNode {
+	node := m.child(m.root, strconv.Itoa(len(tokens)))
+	for i := 0; i < drainDepth && i < len(tokens); i++ {
+		key := tokens[i]
+		// Quoted identifiers vary between otherwise identical messages
+		// ("relation "orders" does not exist"), so they do not route
+		if strings.HasPrefix(key, `"`) || strings.HasPrefix(key, "'") {
+			key = TemplateWildcard
+		}
+		if _, ok := node.children[key]; !ok && len(node.children) >= drainMaxChildren {
+			key = TemplateWildcard
+		}
+		node = m.child(node, key)
+	}
+	return node
+}
+
+func (m *TemplateMiner) child(node *drainNode, key string) *drainNode {
+	next, ok := node.children[key]
+	if !ok {
+		next = newDrainNode()
+		node.children[key] = next
+	}
+	return next
+}
+
+// similarity is the fraction of positions where the message matches the
+// template, with template wildcards matching any token, and the number of
+// wildcards in the template
+func similarity(template, tokens []string) (float64, int) {
+	if len(template) != len(tokens) || len(tokens) == 0 {
+		return 0, 0
+	}
+	same, wildcards := 0, 0
+	for i, token := range template {
+		switch token {
+		case TemplateWildcard:
+			wildcards++
+			same++
+		case tokens[i]:
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens)), wildcards
+}
+
+// TokenizeLogMessage splits a message on whitespace and masks tokens that
+// carry values: anything containing a digit, such as numbers, durations,
+// addresses, pids and identifiers with suffixes
+func TokenizeLogMessage(message string) []string {
+	tokens := strings.Fields(message)
+	if len(tokens) > drainMaxTokens {
+		tokens = tokens[:drainMaxTokens]
+	}
+	for i, token := range tokens {
+		if hasDigit(token) {
+			tokens[i] = TemplateWildcard
+		}
+	}
+	return tokens
+}
+
+func hasDigit(s string) bool {
+	for _, r := range s {
+		if unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package log_analysis
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateMiner_CollapsesRepeatedMessages(t *testing.T) {
+	miner := NewTemplateMiner()
+
+	templates := make(map[*TemplateCluster]int)
+	for i := 0; i < 1000; i++ {
+		templates[miner.Add(fmt.Sprintf("duplicate key value violates unique constraint \"users_email_key\" for id %d", i))]++
+		templates[miner.Add(fmt.Sprintf("canceling statement due to statement timeout after %dms", i*10))]++
+		templates[miner.Add(fmt.Sprintf("relation \"orders_%c\" does not exist", 'a'+i%26))]++
+	}
+
+	assert.Len(t, templates, 3, "3 distinct problems")
+	var texts []string
+	for cluster, count := range templates {
+		assert.Equal(t, 1000, count)
+		texts = append(texts, cluster.Template())
+	}
+	assert.ElementsMatch(t, []string{
+		`duplicate key value violates unique constraint "users_email_key" for id <*>`,
+		"canceling statement due to statement timeout after <*>",
+		"relation <*> does not exist",
+	}, texts)
+}
+
+func TestTemplateMiner_KeepsDissimilarMessagesApart(t *testing.T) {
+	miner := NewTemplateMiner()
+
+	a := miner.Add("connection authorized: user=app database=shop")
+	b := miner.Add("connection received: host=10.0.0.1 port=5432")
+	assert.NotSame(t, a, b)
+
+	// Different token counts never share a template
+	c := miner.Add("checkpoint starting: time")
+	d := miner.Add("checkpoint starting: immediate force wait")
+	assert.NotSame(t, c, d)
+}
+
+func TestTemplateMiner_LoadAndGeneralize(t *testing.T) {
+	miner := NewTemplateMiner()
+	loaded := miner.Load(7, "could not obtain lock on relation orders")
+
+	same := miner.Add("could not obtain lock on relation orders")
+	assert.Same(t, loaded, same)
+	assert.False(t, loaded.Changed)
+
+	generalized := miner.Add("could not obtain lock on relation invoices")
+	assert.Same(t, loaded, generalized)
+	assert.True(t, loaded.Changed)
+	assert.Equal(t, int64(7), loaded.ID)
+	assert.Equal(t, "could not obtain lock on relation <*>", loaded.Template())
+}
+
+func TestTokenizeLogMessage(t *testing.T) {
+	assert.Equal(t,
+		[]string{"duration:", "<*>", "ms", "statement:", "SELECT", "<*>"},
+		TokenizeLogMessage("duration: 1234.567 ms  statement: SELECT 1"))
+	assert.Len(t, TokenizeLogMessage(fmt.Sprint(make([]int, 200))), drainMaxTokens)
+}
@@ -0,0 +1,340 @@
+package log_analysis
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// LogFormat identifies a PostgreSQL log_destination output format
+type LogFormat string
+
+const (
+	LogFormatStderr LogFormat = "stderr"
+	LogFormatCSV    LogFormat = "csvlog"
+	LogFormatJSON   LogFormat = "jsonlog"
+)
+
+// maxLogLineSize bounds a single physical log line; statements can be long
+const maxLogLineSize = 4 * 1024 * 1024
+
+// ParseResult holds the entries parsed from a log stream and a description of
+// every record that could not be parsed
+type ParseResult struct {
+	Logs   []*models.PostgreSQLLog
+	Errors []string
+}
+
+// StructuredLogParser parses PostgreSQL server log output into typed entries.
+// Parse only returns an error when the stream cannot be read; malformed
+// records are reported in ParseResult.Errors and skipped.
+type StructuredLogParser interface {
+	Parse(r io.Reader) (*ParseResult, error)
+}
+
+// NewStructuredLogParser returns a parser for the given format. logLinePrefix
+// is required for stderr logs and ignored otherwise.
+func NewStructuredLogParser(format LogFormat, logLinePrefix string) (StructuredLogParser, error) {
+	switch format {
+	case LogFormatCSV:
+		return &csvLogParser{}, nil
+	case LogFormatJSON:
+		return &jsonLogParser{}, nil
+	case LogFormatStderr:
+		return NewStderrLogParser(logLinePrefix)
+	default:
+		return nil, fmt.Errorf("unsupported log format %q", format)
+	}
+}
+
+// ============================================================================
+// CSVLOG
+// ============================================================================
+
+// csvlog columns, in the order PostgreSQL writes them. backend_type (PG13),
+// leader_pid and query_id (PG14) are only present on newer servers.
+const (
+	csvLogTime = iota
+	csvUserName
+	csvDatabaseName
+	csvProcessID
+	csvConnectionFrom
+	csvSessionID
+	csvSessionLineNum
+	csvCommandTag
+	csvSessionStartTime
+	csvVirtualTransactionID
+	csvTransactionID
+	csvErrorSeverity
+	csvSQLStateCode
+	csvMessage
+	csvDetail
+	csvHint
+	csvInternalQuery
+	csvInternalQueryPos
+	csvContext
+	csvQuery
+	csvQueryPos
+	csvLocation
+	csvApplicationName
+	csvBackendType
+	csvLeaderPID
+	csvQueryID
+)
+
+// csvLogMinFields is the column count of a PG 9.0-12 csvlog record
+const csvLogMinFields = csvApplicationName + 1
+
+type csvLogParser struct{}
+
+// Parse reads csvlog records; quoted fields may span several lines
+func (p *csvLogParser) Parse(r io.Reader) (*ParseResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	result := &ParseResult{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				result.Errors = append(result.Errors, parseErr.Error())
+				continue
+			}
+			return result, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		entry, err := parseCSVLogRecord(record)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+		result.Logs = append(result.Logs, entry)
+	}
+
+	return result, nil
+}
+
+func parseCSVLogRecord(record []string) (*models.PostgreSQLLog, error) {
+	if len(record) < csvLogMinFields {
+		return nil, fmt.Errorf("expected at least %d csvlog fields, got %d", csvLogMinFields, len(record))
+	}
+
+	ts, err := ParseLogTimestamp(record[csvLogTime])
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.PostgreSQLLog{
+		LogTimestamp:    ts,
+		LogLevel:        record[csvErrorSeverity],
+		LogMessage:      record[csvMessage],
+		UserName:        optionalString(record[csvUserName]),
+		DatabaseName:    optionalString(record[csvDatabaseName]),
+		ProcessID:       optionalInt(record[csvProcessID]),
+		ConnectionFrom:  optionalString(record[csvConnectionFrom]),
+		SessionID:       optionalString(record[csvSessionID]),
+		ErrorCode:       optionalSQLState(record[csvSQLStateCode]),
+		ErrorDetail:     optionalString(record[csvDetail]),
+		ErrorHint:       optionalString(record[csvHint]),
+		ErrorContext:    optionalString(record[csvContext]),
+		QueryText:       optionalString(record[csvQuery]),
+		SourceLocation:  optionalString(record[csvLocation]),
+		ApplicationName: optionalString(record[csvApplicationName]),
+	}
+	if len(record) > csvBackendType {
+		entry.BackendType = optionalString(record[csvBackendType])
+	}
+	if len(record) > csvQueryID {
+		entry.QueryHash = optionalQueryID(record[csvQueryID])
+	}
+
+	return entry, nil
+}
+
+// ============================================================================
+// JSONLOG (PG15+)
+// ============================================================================
+
+// jsonLogRecord holds the jsonlog keys that map onto PostgreSQLLog. Keys
+// whose value is empty are omitted by the server.
+type jsonLogRecord struct {
+	Timestamp       string      `json:"timestamp"`
+	User            string      `json:"user"`
+	DBName          string      `json:"dbname"`
+	PID             int         `json:"pid"`
+	RemoteHost      string      `json:"remote_host"`
+	RemotePort      int         `json:"remote_port"`
+	SessionID       string      `json:"session_id"`
+	ErrorSeverity   string      `json:"error_severity"`
+	StateCode       string      `json:"state_code"`
+	Message         string      `json:"message"`
+	Detail          string      `json:"detail"`
+	Hint            string      `json:"hint"`
+	Context         string      `json:"context"`
+	Statement       string      `json:"statement"`
+	FuncName        string      `json:"func_name"`
+	FileName        string      `json:"file_name"`
+	FileLineNum     int         `json:"file_line_num"`
+	ApplicationName string      `json:"application_name"`
+	BackendType     string      `json:"backend_type"`
+	QueryID         json.Number `json:"query_id"`
+}
+
+type jsonLogParser struct{}
+
+// Parse reads one JSON object per line
+func (p *jsonLogParser) Parse(r io.Reader) (*ParseResult, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineSize)
+
+	result := &ParseResult{}
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		entry, err := parseJSONLogLine(line)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: %v", lineNum, err))
+			continue
+		}
+		result.Logs = append(result.Logs, entry)
+	}
+
+	return result, scanner.Err()
+}
+
+func parseJSONLogLine(line string) (*models.PostgreSQLLog, error) {
+	var rec jsonLogRecord
+	if err := json.Unmarshal([]byte(line), &rec); err != nil {
+		return nil, fmt.Errorf("invalid jsonlog record: %w", err)
+	}
+	if rec.Timestamp == "" || rec.ErrorSeverity == "" {
+		return nil, errors.New("jsonlog record has no timestamp or error_severity")
+	}
+
+	ts, err := ParseLogTimestamp(rec.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.PostgreSQLLog{
+		LogTimestamp:    ts,
+		LogLevel:        rec.ErrorSeverity,
+		LogMessage:      rec.Message,
+		UserName:        optionalString(rec.User),
+		DatabaseName:    optionalString(rec.DBName),
+		SessionID:       optionalString(rec.SessionID),
+		ErrorCode:       optionalSQLState(rec.StateCode),
+		ErrorDetail:     optionalString(rec.Detail),
+		ErrorHint:       optionalString(rec.Hint),
+		ErrorContext:    optionalString(rec.Context),
+		QueryText:       optionalString(rec.Statement),
+		ApplicationName: optionalString(rec.ApplicationName),
+		BackendType:     optionalString(rec.BackendType),
+		QueryHash:       optionalQueryID(rec.QueryID.String()),
+	}
+	if rec.PID > 0 {
+		pid := rec.PID
+		entry.ProcessID = &pid
+	}
+	if rec.RemoteHost != "" {
+		from := rec.RemoteHost
+		if rec.RemotePort > 0 {
+			from += ":" + strconv.Itoa(rec.RemotePort)
+		}
+		entry.ConnectionFrom = &from
+	}
+	// Match the csvlog location column: "function, file:line"
+	if rec.FileName != "" {
+		location := fmt.Sprintf("%s:%d", rec.FileName, rec.FileLineNum)
+		if rec.FuncName != "" {
+			location = rec.FuncName + ", " + location
+		}
+		entry.SourceLocation = &location
+	}
+
+	return entry, nil
+}
+
+// ============================================================================
+// SHARED HELPERS
+// ============================================================================
+
+// logTimestampLayouts covers %m/%t style timestamps with a numeric offset or a
+// zone abbreviation, with or without fractional seconds. Numeric offsets come
+// first because the MST layout also accepts "+02" but ignores its value.
+var logTimestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999 -07",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999 -07:00",
+	"2006-01-02 15:04:05.999999999 MST",
+	time.RFC3339Nano,
+}
+
+// ParseLogTimestamp parses a timestamp as written by log_line_prefix %m/%t,
+// csvlog and jsonlog. Zone abbreviations that are not known to the local
+// time zone database are treated as UTC.
+func ParseLogTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range logTimestampLayouts {
+		if ts, err := time.Parse(layout, value); err == nil {
+			return ts, nil
+		}
+	}
+	// %n: Unix epoch with milliseconds
+	if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+		return time.UnixMilli(int64(secs * 1000)).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid log timestamp %q", value)
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func optionalInt(value string) *int {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// optionalSQLState drops the successful_completion code that PostgreSQL
+// attaches to every non-error message
+func optionalSQLState(value string) *string {
+	if value == "" || value == "00000" {
+		return nil
+	}
+	return &value
+}
+
+// optionalQueryID keeps a non-zero compute_query_id value
+func optionalQueryID(value string) *int64 {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id == 0 {
+		return nil
+	}
+	return &id
+}
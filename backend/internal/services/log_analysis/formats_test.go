@@ -0,0 +1,175 @@
+package log_analysis
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVLogParser_Parse(t *testing.T) {
+	input := `2024-01-15 10:23:45.123 UTC,"app","shop",4242,"10.0.0.5:51234",65a5081d.1092,3,"SELECT",2024-01-15 10:20:00 UTC,3/77,0,ERROR,42P01,"relation ""orders"" does not exist",,,,,,"SELECT *
+FROM orders",15,"parserOpenTable, parse_relation.c:1384","psql","client backend",,-123456789
+2024-01-15 10:23:46.000 UTC,,,100,,65a5081d.64,1,,2024-01-15 10:00:00 UTC,,0,LOG,00000,"checkpoint starting: time",,,,,,,,,"","checkpointer",,0
+not,enough,fields
+`
+	parser, err := NewStructuredLogParser(LogFormatCSV, "")
+	require.NoError(t, err)
+
+	result, err := parser.Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, result.Logs, 2)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "line 4")
+
+	entry := result.Logs[0]
+	assert.Equal(t, time.Date(2024, 1, 15, 10, 23, 45, 123000000, time.UTC), entry.LogTimestamp.UTC())
+	assert.Equal(t, "ERROR", entry.LogLevel)
+	assert.Equal(t, `relation "orders" does not exist`, entry.LogMessage)
+	assert.Equal(t, "app", *entry.UserName)
+	assert.Equal(t, "shop", *entry.DatabaseName)
+	assert.Equal(t, 4242, *entry.ProcessID)
+	assert.Equal(t, "10.0.0.5:51234", *entry.ConnectionFrom)
+	assert.Equal(t, "65a5081d.1092", *entry.SessionID)
+	assert.Equal(t, "42P01", *entry.ErrorCode)
+	assert.Equal(t, "SELECT *\nFROM orders", *entry.QueryText)
+	assert.Equal(t, "parserOpenTable, parse_relation.c:1384", *entry.SourceLocation)
+	assert.Equal(t, "psql", *entry.ApplicationName)
+	assert.Equal(t, "client backend", *entry.BackendType)
+	assert.Equal(t, int64(-123456789), *entry.QueryHash)
+	assert.Nil(t, entry.ErrorDetail)
+
+	checkpoint := result.Logs[1]
+	assert.Equal(t, "LOG", checkpoint.LogLevel)
+	assert.Nil(t, checkpoint.ErrorCode, "00000 is not stored")
+	assert.Nil(t, checkpoint.UserName)
+	assert.Nil(t, checkpoint.QueryHash)
+	assert.Equal(t, "checkpointer", *checkpoint.BackendType)
+}
+
+func TestJSONLogParser_Parse(t *testing.T) {
+	input := `{"timestamp":"2024-01-15 10:23:45.123 UTC","user":"app","dbname":"shop","pid":4242,"remote_host":"10.0.0.5","remote_port":51234,"session_id":"65a5081d.1092","line_num":3,"error_severity":"ERROR","state_code":"23505","message":"duplicate key value violates unique constraint \"users_email_key\"","detail":"Key (email)=(a@b.c) already exists.","statement":"INSERT INTO users VALUES ($1)","func_name":"_bt_check_unique","file_name":"nbtinsert.c","file_line_num":664,"application_name":"api","backend_type":"client backend","query_id":42}
+
+{"timestamp":"2024-01-15 10:23:46.000 UTC","pid":100,"error_severity":"LOG","message":"checkpoint complete","backend_type":"checkpointer","query_id":0}
+{"timestamp":
+`
+	parser, err := NewStructuredLogParser(LogFormatJSON, "")
+	require.NoError(t, err)
+
+	result, err := parser.Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, result.Logs, 2)
+	require.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0], "line 4")
+
+	entry := result.Logs[0]
+	assert.Equal(t, "ERROR", entry.LogLevel)
+	assert.Equal(t, "23505", *entry.ErrorCode)
+	assert.Equal(t, "shop", *entry.DatabaseName)
+	assert.Equal(t, "10.0.0.5:51234", *entry.ConnectionFrom)
+	assert.Equal(t, 4242, *entry.ProcessID)
+	assert.Equal(t, "Key (email)=(a@b.c) already exists.", *entry.ErrorDetail)
+	assert.Equal(t, "INSERT INTO users VALUES ($1)", *entry.QueryText)
+	assert.Equal(t, "_bt_check_unique, nbtinsert.c:664", *entry.SourceLocation)
+	assert.Equal(t, "api", *entry.ApplicationName)
+	assert.Equal(t, int64(42), *entry.QueryHash)
+
+	assert.Nil(t, result.Logs[1].ConnectionFrom)
+	assert.Nil(t, result.Logs[1].QueryHash)
+}
+
+func TestStderrLogParser_Parse(t *testing.T) {
+	input := "2024-01-15 10:23:45.123 UTC [4242] app@shop ERROR:  relation \"orders\" does not exist at character 15\n" +
+		"2024-01-15 10:23:45.123 UTC [4242] app@shop STATEMENT:  SELECT *\n" +
+		"\tFROM orders\n" +
+		"2024-01-15 10:23:46.000 UTC [100] LOG:  checkpoint starting: time\n" +
+		"2024-01-15 10:23:47.000 UTC [4243] app@shop ERROR:  23505: duplicate key value violates unique constraint \"users_email_key\"\n" +
+		"2024-01-15 10:23:47.000 UTC [4243] app@shop DETAIL:  Key (email)=(a@b.c) already exists.\n" +
+		"2024-01-15 10:23:47.000 UTC [4243] app@shop LOCATION:  _bt_check_unique, nbtinsert.c:664\n" +
+		"2024-01-15 10:23:48.000 UTC [9999] app@shop HINT:  orphaned hint\n" +
+		"garbage before anything\n"
+
+	parser, err := NewStructuredLogParser(LogFormatStderr, "%m [%p] %q%u@%d ")
+	require.NoError(t, err)
+
+	result, err := parser.Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, result.Logs, 3)
+
+	first := result.Logs[0]
+	assert.Equal(t, "ERROR", first.LogLevel)
+	assert.Equal(t, 4242, *first.ProcessID)
+	assert.Equal(t, "app", *first.UserName)
+	assert.Equal(t, "shop", *first.DatabaseName)
+	assert.Equal(t, "SELECT *\nFROM orders", *first.QueryText)
+
+	checkpoint := result.Logs[1]
+	assert.Equal(t, "LOG", checkpoint.LogLevel)
+	assert.Equal(t, "checkpoint starting: time", checkpoint.LogMessage)
+	assert.Nil(t, checkpoint.UserName, "%q omits the session fields")
+
+	verbose := result.Logs[2]
+	assert.Equal(t, "23505", *verbose.ErrorCode)
+	assert.Equal(t, `duplicate key value violates unique constraint "users_email_key"`, verbose.LogMessage)
+	assert.Equal(t, "Key (email)=(a@b.c) already exists.", *verbose.ErrorDetail)
+	assert.Equal(t, "_bt_check_unique, nbtinsert.c:664", *verbose.SourceLocation)
+	assert.Nil(t, verbose.ErrorHint, "hint from another pid is not attached")
+
+	require.Len(t, result.Errors, 2)
+	assert.Contains(t, result.Errors[0], "line 8: HINT without a preceding message")
+	assert.Contains(t, result.Errors[1], "line 9: does not match log_line_prefix")
+}
+
+func TestStderrLogParser_PrefixEscapes(t *testing.T) {
+	parser, err := NewStderrLogParser("%t %-6p %c %r %a %e ")
+	require.NoError(t, err)
+
+	line := "2024-01-15 10:23:45 +02 42     65a5081d.1092 10.0.0.5(51234) my app 57014 ERROR:  canceling statement due to statement timeout\n"
+	result, err := parser.Parse(strings.NewReader(line))
+	require.NoError(t, err)
+	require.Len(t, result.Logs, 1)
+
+	entry := result.Logs[0]
+	assert.Equal(t, time.Date(2024, 1, 15, 8, 23, 45, 0, time.UTC), entry.LogTimestamp.UTC())
+	assert.Equal(t, 42, *entry.ProcessID)
+	assert.Equal(t, "65a5081d.1092", *entry.SessionID)
+	assert.Equal(t, "10.0.0.5:51234", *entry.ConnectionFrom)
+	assert.Equal(t, "my app", *entry.ApplicationName)
+	assert.Equal(t, "57014", *entry.ErrorCode)
+}
+
+func TestNewStructuredLogParser_Errors(t *testing.T) {
+	_, err := NewStructuredLogParser("syslog", "")
+	assert.Error(t, err)
+
+	_, err = NewStderrLogParser("")
+	assert.Error(t, err)
+
+	_, err = NewStderrLogParser("[%p] ")
+	assert.ErrorContains(t, err, "%m")
+
+	_, err = NewStderrLogParser("%m %Z ")
+	assert.ErrorContains(t, err, "%Z")
+
+	_, err = NewStderrLogParser("%m %")
+	assert.Error(t, err)
+}
+
+func TestParseLogTimestamp(t *testing.T) {
+	ts, err := ParseLogTimestamp("2024-01-15 10:23:45.123 UTC")
+	require.NoError(t, err)
+	assert.Equal(t, 123, ts.Nanosecond()/1e6)
+
+	ts, err = ParseLogTimestamp("2024-01-15 10:23:45 -05")
+	require.NoError(t, err)
+	assert.Equal(t, 15, ts.UTC().Hour())
+
+	ts, err = ParseLogTimestamp("1705314225.500")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1705314225500), ts.UnixMilli())
+
+	_, err = ParseLogTimestamp("yesterday")
+	assert.Error(t, err)
+}
@@ -1,45 +1,168 @@
 package log_analysis
 
 import (
+	"context"
+	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 )
 
+// compiledRule is a category rule ready for matching
+type compiledRule struct {
+	category       LogCategory
+	sqlStatePrefix string
+	pattern        *regexp.Regexp
+	errorsOnly     bool
+}
+
+// LogParser classifies PostgreSQL log entries. Tenant rules are evaluated
+// first, then the entry's SQLSTATE, then the ordered built-in text rules.
 type LogParser struct {
-	patterns map[LogCategory]*regexp.Regexp
+	custom   []compiledRule
+	builtins []compiledRule
+}
+
+// LogCategoryStore is the data access LoadCollectorLogParser needs
+type LogCategoryStore interface {
+	GetTenantIDByCollectorID(ctx context.Context, collectorID string) (*uuid.UUID, error)
+	ListLogCategoryRules(ctx context.Context, tenantID uuid.UUID) ([]*models.LogCategoryRule, error)
 }
 
 func NewLogParser() *LogParser {
-	parser := &LogParser{
-		patterns: make(map[LogCategory]*regexp.Regexp),
-	}
+	parser := &LogParser{}
 
-	for category, patternList := range LogCategoryPatterns {
-		combined := "(" + strings.Join(patternList, "|") + ")"
-		parser.patterns[category] = regexp.MustCompile(combined)
+	for _, rule := range DefaultCategoryRules {
+		combined := "(" + strings.Join(rule.Patterns, "|") + ")"
+		parser.builtins = append(parser.builtins, compiledRule{
+			category:   rule.Category,
+			pattern:    regexp.MustCompile(combined),
+			errorsOnly: rule.ErrorsOnly,
+		})
 	}
 
 	return parser
 }
 
+// NewLogParserWithRules returns a parser that evaluates the enabled tenant
+// rules, highest priority first, before the built-in classification
+func NewLogParserWithRules(rules []*models.LogCategoryRule) (*LogParser, error) {
+	parser := NewLogParser()
+
+	sorted := make([]*models.LogCategoryRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Enabled {
+			sorted = append(sorted, rule)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority > sorted[j].Priority })
+
+	for _, rule := range sorted {
+		compiled := compiledRule{category: LogCategory(rule.Category)}
+		if rule.SQLStatePrefix != nil {
+			compiled.sqlStatePrefix = strings.ToUpper(*rule.SQLStatePrefix)
+		}
+		if rule.Pattern != nil && *rule.Pattern != "" {
+			re, err := regexp.Compile(*rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern for log category %q: %w", rule.Category, err)
+			}
+			compiled.pattern = re
+		}
+		if compiled.sqlStatePrefix == "" && compiled.pattern == nil {
+			continue
+		}
+		parser.custom = append(parser.custom, compiled)
+	}
+
+	return parser, nil
+}
+
+// LoadCollectorLogParser builds the parser for the tenant owning a collector.
+// Collectors without a tenant use the built-in classification only.
+func LoadCollectorLogParser(ctx context.Context, store LogCategoryStore, collectorID string) (*LogParser, error) {
+	tenantID, err := store.GetTenantIDByCollectorID(ctx, collectorID)
+	if err != nil {
+		return nil, err
+	}
+	if tenantID == nil {
+		return NewLogParser(), nil
+	}
+
+	rules, err := store.ListLogCategoryRules(ctx, *tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return NewLogParserWithRules(rules)
+}
+
+// ClassifyLog classifies free text whose severity and SQLSTATE are unknown
 func (lp *LogParser) ClassifyLog(message string) LogCategory {
-	for category, pattern := range lp.patterns {
-		if pattern.MatchString(message) {
-			return category
+	return lp.Classify(&models.PostgreSQLLog{LogMessage: message})
+}
+
+// Classify classifies a structured log entry
+func (lp *LogParser) Classify(entry *models.PostgreSQLLog) LogCategory {
+	code := ""
+	if entry.ErrorCode != nil {
+		code = strings.ToUpper(*entry.ErrorCode)
+	}
+
+	for _, rule := range lp.custom {
+		if rule.sqlStatePrefix != "" && !strings.HasPrefix(code, rule.sqlStatePrefix) {
+			continue
+		}
+		if rule.pattern != nil && !rule.pattern.MatchString(entry.LogMessage) {
+			continue
+		}
+		return rule.category
+	}
+
+	if category, ok := SQLStateCategory(code); ok {
+		return category
+	}
+
+	belowWarning := isBelowWarning(entry.LogLevel)
+	for _, rule := range lp.builtins {
+		if rule.errorsOnly && belowWarning {
+			continue
+		}
+		if rule.pattern.MatchString(entry.LogMessage) {
+			return rule.category
 		}
 	}
 
-	if strings.Contains(strings.ToLower(message), "error") {
+	switch strings.ToUpper(entry.LogLevel) {
+	case "ERROR", "FATAL", "PANIC":
+		return CategoryDatabaseError
+	case "WARNING":
+		return CategoryWarning
+	}
+
+	if strings.Contains(strings.ToLower(entry.LogMessage), "error") {
 		return CategoryDatabaseError
 	}
 
-	if strings.Contains(strings.ToLower(message), "warning") {
+	if strings.Contains(strings.ToLower(entry.LogMessage), "warning") {
 		return CategoryWarning
 	}
 
 	return CategoryInfo
 }
 
+// isBelowWarning reports whether a severity is informational. An unknown
+// severity is not, so free text keeps every rule.
+func isBelowWarning(level string) bool {
+	switch strings.ToUpper(level) {
+	case "DEBUG", "DEBUG1", "DEBUG2", "DEBUG3", "DEBUG4", "DEBUG5", "LOG", "INFO", "NOTICE":
+		return true
+	}
+	return false
+}
+
 func (lp *LogParser) ExtractMetadata(message string) map[string]interface{} {
 	metadata := make(map[string]interface{})
 
@@ -1,9 +1,13 @@
 package log_analysis
 
 import (
+	"context"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 )
 
 func TestLogParser_ClassifyLog(t *testing.T) {
@@ -87,3 +91,114 @@ func TestLogParser_DefaultCategories(t *testing.T) {
 		assert.Equal(t, tt.expected, category, "Failed for message: %s", tt.message)
 	}
 }
+
+func strPtr(s string) *string { return &s }
+
+func TestLogParser_ClassifySQLState(t *testing.T) {
+	parser := NewLogParser()
+
+	tests := []struct {
+		code     string
+		message  string
+		expected LogCategory
+	}{
+		// The message mentions a constraint but the SQLSTATE is authoritative
+		{"40P01", "deadlock detected while checking constraint orders_fk", CategoryDeadlock},
+		{"28P01", "password authentication failed for user \"app\"", CategoryAuthenticationError},
+		{"28000", "no pg_hba.conf entry for host", CategoryAuthenticationError},
+		{"53200", "out of memory", CategoryOutOfMemory},
+		{"53100", "could not extend file: No space left on device", CategoryDiskFull},
+		{"53400", "configuration limit exceeded", CategoryResourceExhausted},
+		{"57014", "canceling statement due to statement timeout", CategoryQueryCanceled},
+		{"55P03", "could not obtain lock on relation \"orders\"", CategoryLockTimeout},
+		{"23505", "duplicate key value violates unique constraint", CategoryConstraintError},
+		{"40001", "could not serialize access due to concurrent update", CategoryTransactionRollback},
+		{"42501", "permission denied for table orders", CategoryPermissionDenied},
+		{"42P01", "relation \"orders\" does not exist", CategorySyntaxError},
+		{"XX001", "invalid page in block 12 of relation base/16384/16385", CategoryInternalError},
+	}
+
+	for _, tt := range tests {
+		entry := &models.PostgreSQLLog{LogLevel: "ERROR", LogMessage: tt.message, ErrorCode: strPtr(tt.code)}
+		assert.Equal(t, tt.expected, parser.Classify(entry), "Failed for SQLSTATE %s", tt.code)
+	}
+}
+
+func TestLogParser_ClassifyIsDeterministic(t *testing.T) {
+	parser := NewLogParser()
+
+	// Matches both the deadlock and constraint patterns; deadlock is first
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, CategoryDeadlock, parser.ClassifyLog("deadlock detected on constraint check"))
+	}
+}
+
+func TestLogParser_ClassifyIgnoresErrorRulesBelowWarning(t *testing.T) {
+	parser := NewLogParser()
+
+	benign := &models.PostgreSQLLog{LogLevel: "LOG", LogMessage: "logical replication launcher started"}
+	assert.Equal(t, CategoryInfo, parser.Classify(benign))
+
+	failed := &models.PostgreSQLLog{LogLevel: "FATAL", LogMessage: "could not start WAL streaming: replication slot \"standby_1\" does not exist"}
+	assert.Equal(t, CategoryReplicationError, parser.Classify(failed))
+
+	checkpoint := &models.PostgreSQLLog{LogLevel: "LOG", LogMessage: "checkpoint complete: wrote 12 buffers"}
+	assert.Equal(t, CategoryCheckpoint, parser.Classify(checkpoint))
+
+	unmatched := &models.PostgreSQLLog{LogLevel: "ERROR", LogMessage: "something unexpected"}
+	assert.Equal(t, CategoryDatabaseError, parser.Classify(unmatched))
+}
+
+func TestNewLogParserWithRules(t *testing.T) {
+	rules := []*models.LogCategoryRule{
+		{Category: "app_exception", SQLStatePrefix: strPtr("P0"), Priority: 1, Enabled: true},
+		{Category: "billing", SQLStatePrefix: strPtr("P0001"), Pattern: strPtr("invoice"), Priority: 10, Enabled: true},
+		{Category: "disabled", Pattern: strPtr(".*"), Priority: 100, Enabled: false},
+		{Category: "noisy_client", Pattern: strPtr("^connection reset"), Enabled: true},
+	}
+	parser, err := NewLogParserWithRules(rules)
+	require.NoError(t, err)
+
+	billing := &models.PostgreSQLLog{LogLevel: "ERROR", LogMessage: "invoice 42 is locked", ErrorCode: strPtr("P0001")}
+	assert.Equal(t, LogCategory("billing"), parser.Classify(billing))
+
+	raised := &models.PostgreSQLLog{LogLevel: "ERROR", LogMessage: "order 42 is locked", ErrorCode: strPtr("P0001")}
+	assert.Equal(t, LogCategory("app_exception"), parser.Classify(raised))
+
+	assert.Equal(t, LogCategory("noisy_client"), parser.ClassifyLog("connection reset by peer"))
+
+	// Custom rules are evaluated before the built-in SQLSTATE mapping
+	deadlock := &models.PostgreSQLLog{LogLevel: "ERROR", LogMessage: "deadlock detected", ErrorCode: strPtr("40P01")}
+	assert.Equal(t, CategoryDeadlock, parser.Classify(deadlock))
+
+	_, err = NewLogParserWithRules([]*models.LogCategoryRule{{Category: "bad", Pattern: strPtr("("), Enabled: true}})
+	assert.Error(t, err)
+}
+
+type mockLogCategoryStore struct {
+	tenantID *uuid.UUID
+	rules    []*models.LogCategoryRule
+}
+
+func (m *mockLogCategoryStore) GetTenantIDByCollectorID(ctx context.Context, collectorID string) (*uuid.UUID, error) {
+	return m.tenantID, nil
+}
+
+func (m *mockLogCategoryStore) ListLogCategoryRules(ctx context.Context, tenantID uuid.UUID) ([]*models.LogCategoryRule, error) {
+	return m.rules, nil
+}
+
+func TestLoadCollectorLogParser(t *testing.T) {
+	parser, err := LoadCollectorLogParser(context.Background(), &mockLogCategoryStore{}, uuid.New().String())
+	require.NoError(t, err)
+	assert.Equal(t, CategoryInfo, parser.ClassifyLog("tenant specific"))
+
+	tenantID := uuid.New()
+	store := &mockLogCategoryStore{
+		tenantID: &tenantID,
+		rules:    []*models.LogCategoryRule{{Category: "tenant_rule", Pattern: strPtr("tenant specific"), Enabled: true}},
+	}
+	parser, err = LoadCollectorLogParser(context.Background(), store, uuid.New().String())
+	require.NoError(t, err)
+	assert.Equal(t, LogCategory("tenant_rule"), parser.ClassifyLog("tenant specific"))
+}
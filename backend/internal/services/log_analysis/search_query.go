@@ -0,0 +1,645 @@
+package log_analysis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// Search query language
+//
+//	query  = or
+//	or     = and { "OR" and }
+//	and    = unary { ["AND"] unary }      adjacent terms are ANDed
+//	unary  = ("NOT" | "-") unary | "(" or ")" | term
+//	term   = field ":" value | "quoted phrase" | word | prefix*
+//
+// Fields are severity (level), db (database), user, app (application),
+// category, sqlstate (code) and instance. severity takes a comparison such
+// as severity:>=WARNING; db, user, app and category values ending in * match
+// by prefix; a two character sqlstate matches its class. Operators are upper
+// case so that "and" and "or" remain searchable words.
+//
+// Free text and phrases are matched with PostgreSQL full-text search on the
+// message, e.g. severity:ERROR AND db:orders AND "could not serialize".
+
+const (
+	maxSearchQueryLength = 1000
+	maxSearchTerms       = 50
+	maxSearchDepth       = 16
+)
+
+// searchFields maps field names and their aliases to canonical fields
+var searchFields = map[string]string{
+	"severity":    "severity",
+	"level":       "severity",
+	"db":          "db",
+	"database":    "db",
+	"user":        "user",
+	"app":         "app",
+	"application": "app",
+	"category":    "category",
+	"sqlstate":    "sqlstate",
+	"code":        "sqlstate",
+	"instance":    "instance",
+}
+
+// searchFieldColumns are the columns of fields matched by value
+var searchFieldColumns = map[string]string{
+	"db":       "database_name",
+	"user":     "user_name",
+	"app":      "application_name",
+	"category": "category",
+}
+
+var (
+	searchSQLStatePattern = regexp.MustCompile(`^[0-9A-Z]{2}([0-9A-Z]{3})?$`)
+	searchPrefixPattern   = regexp.MustCompile(`^[\p{L}\p{N}_]+$`)
+)
+
+// SearchQuery is a parsed log search query
+type SearchQuery struct {
+	root searchNode
+	// texts are the words and phrases to highlight, from terms not under NOT
+	texts []searchText
+}
+
+type searchNode interface {
+	sql(arg func(interface{}) string) string
+}
+
+type searchAnd struct{ children []searchNode }
+type searchOr struct{ children []searchNode }
+type searchNot struct{ child searchNode }
+
+type searchField struct {
+	field string
+	op    string // severity comparison; "=" or "prefix" for other fields
+	value string
+}
+
+type searchText struct {
+	words  []string // lower-cased words; more than one for a phrase
+	phrase bool
+	prefix bool
+}
+
+// ParseSearchQuery parses a log search query. An empty query matches
+// every log.
+func ParseSearchQuery(input string) (*SearchQuery, error) {
+	if len(input) > maxSearchQueryLength {
+		return nil, fmt.Errorf("query is longer than %d characters", maxSearchQueryLength)
+	}
+
+	tokens, err := lexSearchQuery(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &searchParser{tokens: tokens}
+	query := &SearchQuery{}
+	if len(tokens) == 0 {
+		return query, nil
+	}
+
+	root, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s at position %d", p.tokens[p.pos].describe(), p.tokens[p.pos].pos)
+	}
+	if p.terms > maxSearchTerms {
+		return nil, fmt.Errorf("query has more than %d terms", maxSearchTerms)
+	}
+
+	query.root = root
+	query.texts = collectSearchTexts(root, false, nil)
+	return query, nil
+}
+
+// Condition writes the query as a SQL condition on pganalytics.postgresql_logs,
+// binding values with arg, which returns each value's placeholder
+func (q *SearchQuery) Condition(arg func(interface{}) string) string {
+	if q.root == nil {
+		return "TRUE"
+	}
+	return q.root.sql(arg)
+}
+
+// Highlight returns the byte ranges of a message matching the query's words
+// and phrases, in order and without overlaps
+func (q *SearchQuery) Highlight(message string) []models.LogHighlight {
+	highlights := []models.LogHighlight{}
+	if len(q.texts) == 0 {
+		return highlights
+	}
+
+	words := searchWords(message)
+	covered := -1
+	for i := range words {
+		if words[i].start < covered {
+			continue
+		}
+		for _, text := range q.texts {
+			if end, ok := text.matchAt(words, i); ok {
+				highlights = append(highlights, models.LogHighlight{Start: words[i].start, End: end})
+				covered = end
+				break
+			}
+		}
+	}
+	return highlights
+}
+
+// matchAt reports whether the text matches the words starting at i and
+// returns the end of the match
+func (t searchText) matchAt(words []searchWord, i int) (int, bool) {
+	if i+len(t.words) > len(words) {
+		return 0, false
+	}
+	for j, w := range t.words {
+		word := words[i+j].lower
+		last := j == len(t.words)-1
+		if t.prefix && last {
+			if !strings.HasPrefix(word, w) {
+				return 0, false
+			}
+		} else if word != w {
+			return 0, false
+		}
+	}
+	return words[i+len(t.words)-1].end, true
+}
+
+func collectSearchTexts(node searchNode, negated bool, texts []searchText) []searchText {
+	switch n := node.(type) {
+	case *searchAnd:
+		for _, child := range n.children {
+			texts = collectSearchTexts(child, negated, texts)
+		}
+	case *searchOr:
+		for _, child := range n.children {
+			texts = collectSearchTexts(child, negated, texts)
+		}
+	case *searchNot:
+		texts = collectSearchTexts(n.child, !negated, texts)
+	case *searchText:
+		if !negated && len(n.words) > 0 {
+			texts = append(texts, *n)
+		}
+	}
+	return texts
+}
+
+// ----------------------------------------------------------------------------
+// SQL
+// ----------------------------------------------------------------------------
+
+func (n *searchAnd) sql(arg func(interface{}) string) string {
+	parts := make([]string, len(n.children))
+	for i, child := range n.children {
+		parts[i] = child.sql(arg)
+	}
+	return "(" + strings.Join(parts, " AND ") + ")"
+}
+
+func (n *searchOr) sql(arg func(interface{}) string) string {
+	parts := make([]string, len(n.children))
+	for i, child := range n.children {
+		parts[i] = child.sql(arg)
+	}
+	return "(" + strings.Join(parts, " OR ") + ")"
+}
+
+// sql negates with IS NOT TRUE so that NOT db:orders also matches logs
+// without a database
+func (n *searchNot) sql(arg func(interface{}) string) string {
+	return "(" + n.child.sql(arg) + ") IS NOT TRUE"
+}
+
+func (n *searchField) sql(arg func(interface{}) string) string {
+	switch n.field {
+	case "severity":
+		var levels []string
+		for level, rank := range logLevelRank {
+			if compareRank(rank, logLevelRank[n.value], n.op) {
+				levels = append(levels, level)
+			}
+		}
+		sort.Strings(levels)
+		quoted := make([]string, 0, len(levels))
+		for _, level := range levels {
+			quoted = append(quoted, arg(level))
+		}
+		return "UPPER(log_level) IN (" + strings.Join(quoted, ", ") + ")"
+	case "sqlstate":
+		if len(n.value) == 2 {
+			return "LEFT(error_code, 2) = " + arg(n.value)
+		}
+		return "error_code = " + arg(n.value)
+	case "instance":
+		id, _ := strconv.Atoi(n.value)
+		return "instance_id = " + arg(id)
+	}
+
+	column := searchFieldColumns[n.field]
+	if n.op == "prefix" {
+		return column + " LIKE " + arg(escapeLike(n.value)+"%")
+	}
+	return column + " = " + arg(n.value)
+}
+
+// sql matches with the expression of the full-text index
+func (n *searchText) sql(arg func(interface{}) string) string {
+	const document = "to_tsvector('simple', log_message)"
+	switch {
+	case n.prefix:
+		return document + " @@ to_tsquery('simple', " + arg(n.words[0]+":*") + ")"
+	case n.phrase:
+		return document + " @@ phraseto_tsquery('simple', " + arg(strings.Join(n.words, " ")) + ")"
+	default:
+		return document + " @@ plainto_tsquery('simple', " + arg(n.words[0]) + ")"
+	}
+}
+
+func compareRank(rank, value int, op string) bool {
+	switch op {
+	case ">=":
+		return rank >= value
+	case ">":
+		return rank > value
+	case "<=":
+		return rank <= value
+	case "<":
+		return rank < value
+	default:
+		return rank == value
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ----------------------------------------------------------------------------
+// Lexer
+// ----------------------------------------------------------------------------
+
+type searchTokenKind int
+
+const (
+	tokenWord searchTokenKind = iota
+	tokenPhrase
+	tokenField
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type searchToken struct {
+	kind  searchTokenKind
+	pos   int
+	text  string // word, phrase or field value
+	field string // field name of a field token
+}
+
+func (t searchToken) describe() string {
+	switch t.kind {
+	case tokenLParen:
+		return `"("`
+	case tokenRParen:
+		return `")"`
+	case tokenAnd, tokenOr, tokenNot:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("term %q", t.text)
+	}
+}
+
+func lexSearchQuery(input string) ([]searchToken, error) {
+	var tokens []searchToken
+	i := 0
+	for i < len(input) {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			tokens = append(tokens, searchToken{kind: tokenLParen, pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, searchToken{kind: tokenRParen, pos: i})
+			i++
+		case r == '"':
+			text, next, err := lexQuoted(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, searchToken{kind: tokenPhrase, pos: i, text: text})
+			i = next
+		case r == '-' && i+1 < len(input) && !unicode.IsSpace(rune(input[i+1])) && (i == 0 || isSearchBoundary(input[i-1])):
+			tokens = append(tokens, searchToken{kind: tokenNot, pos: i, text: "-"})
+			i++
+		default:
+			start := i
+			for i < len(input) {
+				r, size := utf8.DecodeRuneInString(input[i:])
+				if unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' {
+					break
+				}
+				if r == ':' {
+					if field, ok := searchFields[strings.ToLower(input[start:i])]; ok {
+						value, next, err := lexFieldValue(input, i+1)
+						if err != nil {
+							return nil, err
+						}
+						tokens = append(tokens, searchToken{kind: tokenField, pos: start, field: field, text: value})
+						i = next
+						start = -1
+						break
+					}
+				}
+				i += size
+			}
+			if start < 0 {
+				continue
+			}
+
+			word := input[start:i]
+			switch word {
+			case "AND":
+				tokens = append(tokens, searchToken{kind: tokenAnd, pos: start, text: word})
+			case "OR":
+				tokens = append(tokens, searchToken{kind: tokenOr, pos: start, text: word})
+			case "NOT":
+				tokens = append(tokens, searchToken{kind: tokenNot, pos: start, text: word})
+			default:
+				tokens = append(tokens, searchToken{kind: tokenWord, pos: start, text: word})
+			}
+		}
+	}
+	return tokens, nil
+}
+
+func isSearchBoundary(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '('
+}
+
+// lexQuoted reads a double-quoted string starting at i, where \" and \\
+// are escapes
+func lexQuoted(input string, i int) (string, int, error) {
+	var b strings.Builder
+	for j := i + 1; j < len(input); j++ {
+		switch input[j] {
+		case '\\':
+			if j+1 < len(input) {
+				j++
+				b.WriteByte(input[j])
+			}
+		case '"':
+			return b.String(), j + 1, nil
+		default:
+			b.WriteByte(input[j])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quote at position %d", i)
+}
+
+// lexFieldValue reads a field value, quoted or up to the next space or
+// parenthesis
+func lexFieldValue(input string, i int) (string, int, error) {
+	if i < len(input) && input[i] == '"' {
+		return lexQuoted(input, i)
+	}
+	j := i
+	for j < len(input) {
+		r, size := utf8.DecodeRuneInString(input[j:])
+		if unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' {
+			break
+		}
+		j += size
+	}
+	if j == i {
+		return "", 0, fmt.Errorf("missing value at position %d", i)
+	}
+	return input[i:j], j, nil
+}
+
+// ----------------------------------------------------------------------------
+// Parser
+// ----------------------------------------------------------------------------
+
+type searchParser struct {
+	tokens []searchToken
+	pos    int
+	terms  int
+}
+
+func (p *searchParser) peek() *searchToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *searchParser) parseOr(depth int) (searchNode, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	children := []searchNode{left}
+	for t := p.peek(); t != nil && t.kind == tokenOr; t = p.peek() {
+		p.pos++
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	if len(children) == 1 {
+		return left, nil
+	}
+	return &searchOr{children: children}, nil
+}
+
+func (p *searchParser) parseAnd(depth int) (searchNode, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	children := []searchNode{left}
+	for t := p.peek(); t != nil && t.kind != tokenOr && t.kind != tokenRParen; t = p.peek() {
+		if t.kind == tokenAnd {
+			p.pos++
+		}
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	if len(children) == 1 {
+		return left, nil
+	}
+	return &searchAnd{children: children}, nil
+}
+
+func (p *searchParser) parseUnary(depth int) (searchNode, error) {
+	if depth > maxSearchDepth {
+		return nil, fmt.Errorf("query is nested more than %d levels deep", maxSearchDepth)
+	}
+
+	t := p.peek()
+	if t == nil {
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	p.pos++
+
+	switch t.kind {
+	case tokenNot:
+		child, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &searchNot{child: child}, nil
+	case tokenLParen:
+		inner, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing == nil || closing.kind != tokenRParen {
+			return nil, fmt.Errorf("missing \")\" for \"(\" at position %d", t.pos)
+		}
+		p.pos++
+		return inner, nil
+	case tokenField:
+		p.terms++
+		return parseSearchField(t)
+	case tokenPhrase:
+		p.terms++
+		words := lowerWords(t.text)
+		if len(words) == 0 {
+			return nil, fmt.Errorf("empty phrase at position %d", t.pos)
+		}
+		return &searchText{words: words, phrase: len(words) > 1}, nil
+	case tokenWord:
+		p.terms++
+		return parseSearchWord(t)
+	default:
+		return nil, fmt.Errorf("unexpected %s at position %d", t.describe(), t.pos)
+	}
+}
+
+func parseSearchField(t *searchToken) (searchNode, error) {
+	value := t.text
+	switch t.field {
+	case "severity":
+		op := "="
+		for _, candidate := range []string{">=", "<=", ">", "<"} {
+			if strings.HasPrefix(value, candidate) {
+				op = candidate
+				value = value[len(candidate):]
+				break
+			}
+		}
+		value = strings.ToUpper(value)
+		if _, ok := logLevelRank[value]; !ok {
+			return nil, fmt.Errorf("unknown severity %q at position %d", value, t.pos)
+		}
+		return &searchField{field: t.field, op: op, value: value}, nil
+	case "sqlstate":
+		value = strings.ToUpper(value)
+		if !searchSQLStatePattern.MatchString(value) {
+			return nil, fmt.Errorf("invalid sqlstate %q at position %d: use a 5 character code or a 2 character class", value, t.pos)
+		}
+		return &searchField{field: t.field, op: "=", value: value}, nil
+	case "instance":
+		if id, err := strconv.Atoi(value); err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid instance %q at position %d", value, t.pos)
+		}
+		return &searchField{field: t.field, op: "=", value: value}, nil
+	}
+
+	if strings.HasSuffix(value, "*") {
+		value = strings.TrimSuffix(value, "*")
+		if value == "" {
+			return nil, fmt.Errorf("empty prefix at position %d", t.pos)
+		}
+		return &searchField{field: t.field, op: "prefix", value: value}, nil
+	}
+	return &searchField{field: t.field, op: "=", value: value}, nil
+}
+
+func parseSearchWord(t *searchToken) (searchNode, error) {
+	if strings.HasSuffix(t.text, "*") {
+		prefix := strings.ToLower(strings.TrimSuffix(t.text, "*"))
+		if !searchPrefixPattern.MatchString(prefix) {
+			return nil, fmt.Errorf("invalid prefix %q at position %d: use letters, digits and underscores", t.text, t.pos)
+		}
+		return &searchText{words: []string{prefix}, prefix: true}, nil
+	}
+
+	words := lowerWords(t.text)
+	if len(words) == 0 {
+		// Punctuation only; matches nothing in the full-text index
+		return &searchText{words: []string{strings.ToLower(t.text)}}, nil
+	}
+	if len(words) > 1 {
+		// e.g. orders.id, which the index stores as separate words
+		return &searchText{words: words, phrase: true}, nil
+	}
+	return &searchText{words: words}, nil
+}
+
+// ----------------------------------------------------------------------------
+// Words
+// ----------------------------------------------------------------------------
+
+// searchWord is a word of a message, approximating the words the 'simple'
+// text search configuration indexes
+type searchWord struct {
+	start, end int
+	lower      string
+}
+
+func isSearchWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func searchWords(s string) []searchWord {
+	var words []searchWord
+	start := -1
+	for i, r := range s {
+		if isSearchWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			words = append(words, searchWord{start: start, end: i, lower: strings.ToLower(s[start:i])})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, searchWord{start: start, end: len(s), lower: strings.ToLower(s[start:])})
+	}
+	return words
+}
+
+func lowerWords(s string) []string {
+	var words []string
+	for _, w := range searchWords(s) {
+		words = append(words, w.lower)
+	}
+	return words
+}
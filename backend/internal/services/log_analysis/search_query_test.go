@@ -0,0 +1,140 @@
+package log_analysis
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// compileSearch returns a query's SQL condition and bound values
+func compileSearch(t *testing.T, input string) (string, []interface{}) {
+	t.Helper()
+	query, err := ParseSearchQuery(input)
+	require.NoError(t, err, input)
+
+	var args []interface{}
+	sql := query.Condition(func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	})
+	return sql, args
+}
+
+func TestParseSearchQuery_Example(t *testing.T) {
+	sql, args := compileSearch(t, `severity:ERROR AND db:orders AND "could not serialize"`)
+
+	assert.Equal(t, "(UPPER(log_level) IN ($1) AND database_name = $2 AND "+
+		"to_tsvector('simple', log_message) @@ phraseto_tsquery('simple', $3))", sql)
+	assert.Equal(t, []interface{}{"ERROR", "orders", "could not serialize"}, args)
+}
+
+func TestParseSearchQuery_Precedence(t *testing.T) {
+	// AND binds tighter than OR; adjacent terms are ANDed
+	sql, args := compileSearch(t, "deadlock user:app OR timeout")
+	assert.Equal(t, "((to_tsvector('simple', log_message) @@ plainto_tsquery('simple', $1) AND user_name = $2) OR "+
+		"to_tsvector('simple', log_message) @@ plainto_tsquery('simple', $3))", sql)
+	assert.Equal(t, []interface{}{"deadlock", "app", "timeout"}, args)
+
+	sql, _ = compileSearch(t, "deadlock AND (user:app OR timeout)")
+	assert.Equal(t, "(to_tsvector('simple', log_message) @@ plainto_tsquery('simple', $1) AND (user_name = $2 OR "+
+		"to_tsvector('simple', log_message) @@ plainto_tsquery('simple', $3)))", sql)
+}
+
+func TestParseSearchQuery_Fields(t *testing.T) {
+	tests := []struct {
+		input string
+		sql   string
+		args  []interface{}
+	}{
+		{"level:>=error", "UPPER(log_level) IN ($1, $2, $3)", []interface{}{"ERROR", "FATAL", "PANIC"}},
+		{"severity:<notice", "UPPER(log_level) IN ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+			[]interface{}{"DEBUG", "DEBUG1", "DEBUG2", "DEBUG3", "DEBUG4", "DEBUG5", "INFO", "LOG", "SLOW_QUERY", "STATEMENT"}},
+		{"sqlstate:40001", "error_code = $1", []interface{}{"40001"}},
+		{"code:40", "LEFT(error_code, 2) = $1", []interface{}{"40"}},
+		{"instance:7", "instance_id = $1", []interface{}{7}},
+		{"app:psql_*", "application_name LIKE $1", []interface{}{`psql\_%`}},
+		{`db:"my db"`, "database_name = $1", []interface{}{"my db"}},
+		{"category:lock_timeout", "category = $1", []interface{}{"lock_timeout"}},
+		{"serializ*", "to_tsvector('simple', log_message) @@ to_tsquery('simple', $1)", []interface{}{"serializ:*"}},
+		{"orders.id", "to_tsvector('simple', log_message) @@ phraseto_tsquery('simple', $1)", []interface{}{"orders id"}},
+		{"-db:orders", "(database_name = $1) IS NOT TRUE", []interface{}{"orders"}},
+		{"", "TRUE", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			sql, args := compileSearch(t, tt.input)
+			assert.Equal(t, tt.sql, sql)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestParseSearchQuery_LowercaseOperatorsAreWords(t *testing.T) {
+	sql, args := compileSearch(t, "read and write")
+	assert.Contains(t, sql, " AND ")
+	assert.Equal(t, []interface{}{"read", "and", "write"}, args)
+}
+
+func TestParseSearchQuery_Errors(t *testing.T) {
+	tests := map[string]string{
+		`"unterminated`: "unterminated quote",
+		"(deadlock":     `missing ")"`,
+		"deadlock)":     `unexpected ")"`,
+		"severity:LOUD": "unknown severity",
+		"sqlstate:4000": "invalid sqlstate",
+		"instance:abc":  "invalid instance",
+		"db:":           "missing value",
+		"deadlock AND":  "unexpected end",
+		"OR deadlock":   `unexpected "OR"`,
+		"dead'lock*":    "invalid prefix",
+		"db:*":          "empty prefix",
+		`""`:            "empty phrase",
+		"NOT":           "unexpected end",
+		string(make([]byte, maxSearchQueryLength+1)): "longer than",
+	}
+
+	for input, want := range tests {
+		_, err := ParseSearchQuery(input)
+		require.Error(t, err, input)
+		assert.Contains(t, err.Error(), want, input)
+	}
+}
+
+func TestParseSearchQuery_Limits(t *testing.T) {
+	deep := ""
+	for i := 0; i <= maxSearchDepth; i++ {
+		deep += "("
+	}
+	_, err := ParseSearchQuery(deep + "x")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nested")
+
+	many := ""
+	for i := 0; i <= maxSearchTerms; i++ {
+		many += "x "
+	}
+	_, err = ParseSearchQuery(many)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "terms")
+}
+
+func TestSearchQuery_Highlight(t *testing.T) {
+	query, err := ParseSearchQuery(`"could not serialize" OR deadlock* -timeout severity:ERROR`)
+	require.NoError(t, err)
+
+	message := "ERROR: could not serialize access; Deadlocks detected, not a timeout"
+	highlights := query.Highlight(message)
+
+	require.Len(t, highlights, 2)
+	assert.Equal(t, "could not serialize", message[highlights[0].Start:highlights[0].End])
+	assert.Equal(t, "Deadlocks", message[highlights[1].Start:highlights[1].End])
+
+	// Field-only queries have nothing to highlight
+	query, err = ParseSearchQuery("db:orders")
+	require.NoError(t, err)
+	assert.Equal(t, []models.LogHighlight{}, query.Highlight(message))
+}
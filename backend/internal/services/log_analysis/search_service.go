@@ -0,0 +1,133 @@
+package log_analysis
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+const (
+	// DefaultLogSearchWindow is the time range searched when no start is given
+	DefaultLogSearchWindow = 24 * time.Hour
+	DefaultLogSearchLimit  = 100
+	MaxLogSearchLimit      = 1000
+)
+
+// SearchStore is the data access for log search
+type SearchStore interface {
+	SearchPostgresqlLogs(ctx context.Context, params *models.LogSearchParams, condition func(arg func(interface{}) string) string) ([]*models.PostgreSQLLog, error)
+	GetLogSearchFacets(ctx context.Context, params *models.LogSearchParams, condition func(arg func(interface{}) string) string) (*models.LogSearchFacets, error)
+}
+
+// SearchService searches stored PostgreSQL logs with the search query language
+type SearchService struct {
+	store SearchStore
+}
+
+// NewSearchService creates a new log search service
+func NewSearchService(store SearchStore) *SearchService {
+	return &SearchService{store: store}
+}
+
+// Search returns a page of the logs matching a query, newest first, with
+// the matching ranges of each message highlighted. Facets are counted on
+// the first page only, since they do not change between pages.
+func (s *SearchService) Search(ctx context.Context, query *SearchQuery, params models.LogSearchParams) (*models.LogSearchResponse, error) {
+	if params.Limit <= 0 {
+		params.Limit = DefaultLogSearchLimit
+	}
+	if params.Limit > MaxLogSearchLimit {
+		params.Limit = MaxLogSearchLimit
+	}
+
+	response := &models.LogSearchResponse{
+		Hits: []*models.LogSearchHit{},
+		From: params.From,
+		To:   params.To,
+	}
+	if params.InstanceIDs != nil && len(params.InstanceIDs) == 0 {
+		if params.After == nil {
+			response.Facets = &models.LogSearchFacets{
+				Categories: []models.LogFacetCount{},
+				Databases:  []models.LogFacetCount{},
+				Users:      []models.LogFacetCount{},
+			}
+		}
+		return response, nil
+	}
+
+	// Fetch one extra log to know whether there is a next page
+	page := params
+	page.Limit = params.Limit + 1
+	logs, err := s.store.SearchPostgresqlLogs(ctx, &page, query.Condition)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(logs) > params.Limit {
+		logs = logs[:params.Limit]
+		last := logs[len(logs)-1]
+		response.NextCursor = EncodeLogSearchCursor(models.LogSearchCursor{
+			Timestamp: last.LogTimestamp,
+			ID:        last.ID,
+			From:      params.From,
+			To:        params.To,
+		})
+	}
+	for _, log := range logs {
+		response.Hits = append(response.Hits, &models.LogSearchHit{
+			PostgreSQLLog: log,
+			Highlights:    query.Highlight(log.LogMessage),
+		})
+	}
+
+	if params.After == nil {
+		facets, err := s.store.GetLogSearchFacets(ctx, &params, query.Condition)
+		if err != nil {
+			return nil, err
+		}
+		response.Facets = facets
+	}
+
+	return response, nil
+}
+
+// EncodeLogSearchCursor encodes the position of a hit as an opaque cursor
+func EncodeLogSearchCursor(cursor models.LogSearchCursor) string {
+	raw := fmt.Sprintf("%d:%d:%d:%d", cursor.Timestamp.UnixNano(), cursor.ID, cursor.From.UnixNano(), cursor.To.UnixNano())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeLogSearchCursor decodes a cursor returned by EncodeLogSearchCursor
+func DecodeLogSearchCursor(s string) (*models.LogSearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	values := make([]int64, len(parts))
+	for i, part := range parts {
+		if values[i], err = strconv.ParseInt(part, 10, 64); err != nil {
+			return nil, fmt.Errorf("malformed cursor")
+		}
+	}
+
+	cursor := &models.LogSearchCursor{
+		Timestamp: time.Unix(0, values[0]).UTC(),
+		ID:        values[1],
+		From:      time.Unix(0, values[2]).UTC(),
+		To:        time.Unix(0, values[3]).UTC(),
+	}
+	if !cursor.From.Before(cursor.To) {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	return cursor, nil
+}
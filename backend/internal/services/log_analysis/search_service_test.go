@@ -0,0 +1,114 @@
+package log_analysis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// mockSearchStore returns stored logs newest first, honoring the cursor and
+// limit but not the condition
+type mockSearchStore struct {
+	logs        []*models.PostgreSQLLog
+	searches    []models.LogSearchParams
+	facetCalls  int
+	lastCompile string
+}
+
+func (m *mockSearchStore) SearchPostgresqlLogs(ctx context.Context, params *models.LogSearchParams, condition func(arg func(interface{}) string) string) ([]*models.PostgreSQLLog, error) {
+	m.searches = append(m.searches, *params)
+	m.lastCompile = condition(func(interface{}) string { return "?" })
+
+	var out []*models.PostgreSQLLog
+	for _, log := range m.logs {
+		if params.After != nil && !(log.LogTimestamp.Before(params.After.Timestamp) ||
+			log.LogTimestamp.Equal(params.After.Timestamp) && log.ID < params.After.ID) {
+			continue
+		}
+		if len(out) == params.Limit {
+			break
+		}
+		out = append(out, log)
+	}
+	return out, nil
+}
+
+func (m *mockSearchStore) GetLogSearchFacets(ctx context.Context, params *models.LogSearchParams, condition func(arg func(interface{}) string) string) (*models.LogSearchFacets, error) {
+	m.facetCalls++
+	return &models.LogSearchFacets{Databases: []models.LogFacetCount{{Value: "orders", Count: int64(len(m.logs))}}}, nil
+}
+
+func TestSearchService_Paginates(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	// Newest first, as the store returns them; logs 3 and 2 share a
+	// timestamp so that the ID breaks the tie
+	store := &mockSearchStore{}
+	store.logs = []*models.PostgreSQLLog{
+		{ID: 5, LogTimestamp: now, LogMessage: "deadlock detected"},
+		{ID: 4, LogTimestamp: now.Add(-time.Minute), LogMessage: "deadlock detected"},
+		{ID: 3, LogTimestamp: now.Add(-2 * time.Minute), LogMessage: "deadlock detected"},
+		{ID: 2, LogTimestamp: now.Add(-2 * time.Minute), LogMessage: "deadlock detected"},
+		{ID: 1, LogTimestamp: now.Add(-3 * time.Minute), LogMessage: "deadlock detected"},
+	}
+
+	query, err := ParseSearchQuery("deadlock")
+	require.NoError(t, err)
+	service := NewSearchService(store)
+	params := models.LogSearchParams{From: now.Add(-time.Hour), To: now, Limit: 2}
+
+	var ids []int64
+	var pages int
+	for {
+		resp, err := service.Search(context.Background(), query, params)
+		require.NoError(t, err)
+		pages++
+		for _, hit := range resp.Hits {
+			ids = append(ids, hit.ID)
+			assert.Equal(t, []models.LogHighlight{{Start: 0, End: 8}}, hit.Highlights)
+		}
+		if pages == 1 {
+			require.NotNil(t, resp.Facets)
+			assert.Equal(t, "orders", resp.Facets.Databases[0].Value)
+		} else {
+			assert.Nil(t, resp.Facets, "facets are only counted on the first page")
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+
+		cursor, err := DecodeLogSearchCursor(resp.NextCursor)
+		require.NoError(t, err)
+		assert.True(t, cursor.From.Equal(params.From))
+		assert.True(t, cursor.To.Equal(params.To))
+		params.After = cursor
+	}
+
+	assert.Equal(t, []int64{5, 4, 3, 2, 1}, ids)
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, 1, store.facetCalls)
+	assert.Equal(t, 3, store.searches[0].Limit, "one extra log is fetched to detect the next page")
+	assert.Contains(t, store.lastCompile, "plainto_tsquery")
+}
+
+func TestSearchService_NoAccessibleInstances(t *testing.T) {
+	store := &mockSearchStore{logs: []*models.PostgreSQLLog{{ID: 1}}}
+	query, err := ParseSearchQuery("")
+	require.NoError(t, err)
+
+	resp, err := NewSearchService(store).Search(context.Background(), query, models.LogSearchParams{InstanceIDs: []int{}})
+	require.NoError(t, err)
+	assert.Empty(t, resp.Hits)
+	assert.NotNil(t, resp.Facets)
+	assert.Empty(t, store.searches, "the store is not queried")
+}
+
+func TestDecodeLogSearchCursor_Invalid(t *testing.T) {
+	for _, cursor := range []string{"!!", "MTIz", EncodeLogSearchCursor(models.LogSearchCursor{})} {
+		_, err := DecodeLogSearchCursor(cursor)
+		assert.Error(t, err, cursor)
+	}
+}
@@ -0,0 +1,49 @@
+package log_analysis
+
+// sqlStateCategories maps individual SQLSTATE codes whose meaning differs from
+// the rest of their class
+var sqlStateCategories = map[string]LogCategory{
+	"40P01": CategoryDeadlock,         // deadlock_detected
+	"55P03": CategoryLockTimeout,      // lock_not_available
+	"57014": CategoryQueryCanceled,    // query_canceled
+	"25P03": CategoryLongTransaction,  // idle_in_transaction_session_timeout
+	"53100": CategoryDiskFull,         // disk_full
+	"53200": CategoryOutOfMemory,      // out_of_memory
+	"53300": CategoryConnectionError,  // too_many_connections
+	"57P01": CategoryConnectionError,  // admin_shutdown
+	"57P02": CategoryConnectionError,  // crash_shutdown
+	"57P03": CategoryConnectionError,  // cannot_connect_now
+	"57P04": CategoryConnectionError,  // database_dropped
+	"57P05": CategoryConnectionError,  // idle_session_timeout
+	"3D000": CategoryDatabaseError,    // invalid_catalog_name
+	"42501": CategoryPermissionDenied, // insufficient_privilege
+}
+
+// sqlStateClassCategories maps SQLSTATE classes (the first two characters)
+var sqlStateClassCategories = map[string]LogCategory{
+	"01": CategoryWarning,
+	"08": CategoryConnectionError,
+	"22": CategoryDataException,
+	"23": CategoryConstraintError,
+	"28": CategoryAuthenticationError,
+	"40": CategoryTransactionRollback,
+	"42": CategorySyntaxError,
+	"53": CategoryResourceExhausted,
+	"54": CategoryResourceExhausted,
+	"58": CategoryInternalError,
+	"XX": CategoryInternalError,
+}
+
+// SQLStateCategory classifies a SQLSTATE code. Exact codes take precedence
+// over their class; successful completion (class 00) and unknown classes
+// are not classified.
+func SQLStateCategory(code string) (LogCategory, bool) {
+	if len(code) != 5 {
+		return "", false
+	}
+	if category, ok := sqlStateCategories[code]; ok {
+		return category, true
+	}
+	category, ok := sqlStateClassCategories[code[:2]]
+	return category, ok
+}
@@ -0,0 +1,320 @@
+package log_analysis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// prefixField is the PostgreSQLLog field a log_line_prefix escape fills
+type prefixField int
+
+const (
+	prefixIgnored prefixField = iota
+	prefixTimestamp
+	prefixPID
+	prefixSessionID
+	prefixUser
+	prefixDatabase
+	prefixApplication
+	prefixRemoteHostPort
+	prefixRemoteHost
+	prefixSQLState
+	prefixBackendType
+	prefixQueryID
+)
+
+// prefixEscape describes one log_line_prefix escape sequence
+type prefixEscape struct {
+	field   prefixField
+	pattern string
+}
+
+const logTimestampPattern = `\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?:\.\d+)?(?: [A-Za-z0-9+\-:]+)?`
+
+// prefixEscapes maps log_line_prefix escapes to the text PostgreSQL writes
+var prefixEscapes = map[byte]prefixEscape{
+	'a': {prefixApplication, `.*?`},
+	'u': {prefixUser, `.*?`},
+	'd': {prefixDatabase, `.*?`},
+	'r': {prefixRemoteHostPort, `\S*?`},
+	'h': {prefixRemoteHost, `\S*?`},
+	'b': {prefixBackendType, `.*?`},
+	'p': {prefixPID, `\d+`},
+	'P': {prefixIgnored, `\d*`},
+	't': {prefixTimestamp, logTimestampPattern},
+	'm': {prefixTimestamp, logTimestampPattern},
+	'n': {prefixTimestamp, `\d+(?:\.\d+)?`},
+	'i': {prefixIgnored, `.*?`},
+	'e': {prefixSQLState, `[0-9A-Z]{5}`},
+	'c': {prefixSessionID, `[0-9a-f]+\.[0-9a-f]+`},
+	'l': {prefixIgnored, `\d+`},
+	's': {prefixIgnored, logTimestampPattern},
+	'v': {prefixIgnored, `\S*?`},
+	'x': {prefixIgnored, `\d*`},
+	'Q': {prefixIgnored, `-?\d*`},
+}
+
+// stderrSeverities are the labels PostgreSQL writes after the prefix. DETAIL
+// and below continue the preceding message rather than starting a new one.
+const stderrSeverities = `DEBUG[1-5]|LOG|INFO|NOTICE|WARNING|ERROR|FATAL|PANIC|DETAIL|HINT|CONTEXT|STATEMENT|QUERY|LOCATION`
+
+// verboseSQLState matches the SQLSTATE written into the message when
+// log_error_verbosity = verbose
+var verboseSQLState = regexp.MustCompile(`^([0-9A-Z]{5}): `)
+
+// StderrLogParser parses stderr log output using the server's log_line_prefix
+type StderrLogParser struct {
+	pattern *regexp.Regexp
+	// fields[i] is the field captured by subexpression i+1; the last two
+	// subexpressions are the severity and the message
+	fields []prefixField
+}
+
+// NewStderrLogParser compiles a log_line_prefix such as '%m [%p] %q%u@%d '.
+// The prefix must contain a timestamp escape (%m, %t or %n).
+func NewStderrLogParser(logLinePrefix string) (*StderrLogParser, error) {
+	if logLinePrefix == "" {
+		return nil, errors.New("log_line_prefix is required to parse stderr logs")
+	}
+
+	var (
+		b            strings.Builder
+		fields       []prefixField
+		hasTimestamp bool
+		optionalTail bool
+	)
+	b.WriteString("^")
+
+	for i := 0; i < len(logLinePrefix); i++ {
+		ch := logLinePrefix[i]
+		if ch != '%' {
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+			continue
+		}
+
+		// Optional padding such as %-10u or %5p
+		i++
+		start := i
+		if i < len(logLinePrefix) && logLinePrefix[i] == '-' {
+			i++
+		}
+		for i < len(logLinePrefix) && logLinePrefix[i] >= '0' && logLinePrefix[i] <= '9' {
+			i++
+		}
+		padded := i > start
+		if i >= len(logLinePrefix) {
+			return nil, errors.New("log_line_prefix ends with an incomplete escape")
+		}
+
+		switch esc := logLinePrefix[i]; esc {
+		case '%':
+			b.WriteString("%")
+		case 'q':
+			// Everything after %q is omitted for non-session processes
+			if !optionalTail {
+				b.WriteString("(?:")
+				optionalTail = true
+			}
+		default:
+			spec, ok := prefixEscapes[esc]
+			if !ok {
+				return nil, fmt.Errorf("unsupported log_line_prefix escape %%%c", esc)
+			}
+			if spec.field == prefixTimestamp {
+				hasTimestamp = true
+			}
+			if padded {
+				b.WriteString(" *(" + spec.pattern + ") *")
+			} else {
+				b.WriteString("(" + spec.pattern + ")")
+			}
+			fields = append(fields, spec.field)
+		}
+	}
+
+	if !hasTimestamp {
+		return nil, errors.New("log_line_prefix must include %m, %t or %n")
+	}
+	if optionalTail {
+		b.WriteString(")?")
+	}
+	b.WriteString("(" + stderrSeverities + "):  (.*)$")
+
+	pattern, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid log_line_prefix: %w", err)
+	}
+
+	return &StderrLogParser{pattern: pattern, fields: fields}, nil
+}
+
+// Parse groups physical lines into log entries. DETAIL, HINT, CONTEXT,
+// STATEMENT and LOCATION lines are attached to the preceding entry, and
+// tab-indented lines continue the previous field.
+func (p *StderrLogParser) Parse(r io.Reader) (*ParseResult, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLogLineSize)
+
+	result := &ParseResult{}
+	var (
+		current    *models.PostgreSQLLog
+		appendLine func(string)
+	)
+	flush := func() {
+		if current != nil {
+			result.Logs = append(result.Logs, current)
+		}
+		current, appendLine = nil, nil
+	}
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		m := p.pattern.FindStringSubmatch(line)
+		if m == nil {
+			if current != nil && appendLine != nil {
+				appendLine("\n" + strings.TrimPrefix(line, "\t"))
+			} else if strings.TrimSpace(line) != "" {
+				result.Errors = append(result.Errors, fmt.Sprintf("line %d: does not match log_line_prefix", lineNum))
+			}
+			continue
+		}
+
+		entry, err := p.entryFromMatch(m)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: %v", lineNum, err))
+			continue
+		}
+
+		severity, message := m[len(m)-2], m[len(m)-1]
+		if !isSecondarySeverity(severity) {
+			flush()
+			if code := verboseSQLState.FindStringSubmatch(message); code != nil && strings.ContainsAny(code[1], "0123456789") {
+				message = strings.TrimPrefix(message, code[0])
+				if entry.ErrorCode == nil {
+					entry.ErrorCode = optionalSQLState(code[1])
+				}
+			}
+			entry.LogLevel = severity
+			entry.LogMessage = message
+			current = entry
+			appendLine = func(s string) { current.LogMessage += s }
+			continue
+		}
+
+		if current == nil || !sameProcess(current, entry) {
+			result.Errors = append(result.Errors, fmt.Sprintf("line %d: %s without a preceding message", lineNum, severity))
+			appendLine = nil
+			continue
+		}
+		field := secondaryField(current, severity)
+		if field == nil {
+			appendLine = nil
+			continue
+		}
+		value := message
+		*field = &value
+		appendLine = func(s string) { value += s }
+	}
+	flush()
+
+	return result, scanner.Err()
+}
+
+// isSecondarySeverity reports whether a line continues the preceding message
+func isSecondarySeverity(severity string) bool {
+	switch severity {
+	case "DETAIL", "HINT", "CONTEXT", "STATEMENT", "QUERY", "LOCATION":
+		return true
+	}
+	return false
+}
+
+// secondaryField returns the field a secondary line fills, or nil for QUERY
+// (the internal query), which is consumed but not stored
+func secondaryField(entry *models.PostgreSQLLog, severity string) **string {
+	switch severity {
+	case "DETAIL":
+		return &entry.ErrorDetail
+	case "HINT":
+		return &entry.ErrorHint
+	case "CONTEXT":
+		return &entry.ErrorContext
+	case "STATEMENT":
+		return &entry.QueryText
+	case "LOCATION":
+		return &entry.SourceLocation
+	}
+	return nil
+}
+
+// sameProcess reports whether a secondary line belongs to the current entry;
+// without a pid in the prefix every secondary line is attached
+func sameProcess(current, line *models.PostgreSQLLog) bool {
+	return current.ProcessID == nil || line.ProcessID == nil || *current.ProcessID == *line.ProcessID
+}
+
+// entryFromMatch fills the prefix fields of a new entry
+func (p *StderrLogParser) entryFromMatch(m []string) (*models.PostgreSQLLog, error) {
+	entry := &models.PostgreSQLLog{}
+	for i, field := range p.fields {
+		value := m[i+1]
+		switch field {
+		case prefixTimestamp:
+			if value == "" || !entry.LogTimestamp.IsZero() {
+				continue
+			}
+			ts, err := ParseLogTimestamp(value)
+			if err != nil {
+				return nil, err
+			}
+			entry.LogTimestamp = ts
+		case prefixPID:
+			entry.ProcessID = optionalInt(value)
+		case prefixSessionID:
+			entry.SessionID = optionalString(value)
+		case prefixUser:
+			entry.UserName = optionalPrefixValue(value)
+		case prefixDatabase:
+			entry.DatabaseName = optionalPrefixValue(value)
+		case prefixApplication:
+			entry.ApplicationName = optionalPrefixValue(value)
+		case prefixRemoteHostPort:
+			// %r is written as host(port)
+			if open := strings.IndexByte(value, '('); open > 0 && strings.HasSuffix(value, ")") {
+				value = value[:open] + ":" + value[open+1:len(value)-1]
+			}
+			entry.ConnectionFrom = optionalPrefixValue(value)
+		case prefixRemoteHost:
+			if entry.ConnectionFrom == nil {
+				entry.ConnectionFrom = optionalPrefixValue(value)
+			}
+		case prefixSQLState:
+			entry.ErrorCode = optionalSQLState(value)
+		case prefixBackendType:
+			entry.BackendType = optionalPrefixValue(value)
+		case prefixQueryID:
+			entry.QueryHash = optionalQueryID(value)
+		}
+	}
+	if entry.LogTimestamp.IsZero() {
+		return nil, errors.New("missing timestamp")
+	}
+	return entry, nil
+}
+
+// optionalPrefixValue treats PostgreSQL's "[unknown]" placeholder as empty
+func optionalPrefixValue(value string) *string {
+	if value == "[unknown]" {
+		return nil
+	}
+	return optionalString(strings.TrimSpace(value))
+}
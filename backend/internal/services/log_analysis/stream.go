@@ -0,0 +1,278 @@
+package log_analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+const (
+	// DefaultStreamBuffer is the number of logs queued per subscriber
+	// before further logs are dropped
+	DefaultStreamBuffer = 256
+	// MaxStreamReplay bounds the logs replayed when a subscriber resumes
+	MaxStreamReplay = 1000
+	// notifyBatchSize keeps NOTIFY payloads well below the 8000 byte limit
+	notifyBatchSize = 400
+)
+
+// logLevelRank orders severities for minimum level filters. LOG is the
+// level of routine server messages and ranks with INFO.
+var logLevelRank = map[string]int{
+	"DEBUG": 0, "DEBUG1": 0, "DEBUG2": 0, "DEBUG3": 0, "DEBUG4": 0, "DEBUG5": 0,
+	"INFO": 1, "LOG": 1, "STATEMENT": 1, "SLOW_QUERY": 1,
+	"NOTICE": 2, "WARNING": 3, "ERROR": 4, "FATAL": 5, "PANIC": 6,
+}
+
+// LogStreamStore is the data access for cross-replica log streaming
+type LogStreamStore interface {
+	NotifyLogStream(ctx context.Context, payload string) error
+	ListenLogStream(ctx context.Context, connString string, handle func(payload string)) error
+	GetPostgresqlLogsByIDs(ctx context.Context, ids []int64) ([]*models.PostgreSQLLog, error)
+	GetPostgresqlLogsAfter(ctx context.Context, collectorID uuid.UUID, afterID int64, limit int) ([]*models.PostgreSQLLog, error)
+}
+
+// LogStreamFilter selects the logs delivered to a subscriber
+type LogStreamFilter struct {
+	MinLevel   string
+	Categories []string
+	// Text matches messages containing it, ignoring case
+	Text string
+}
+
+// ValidLogLevel reports whether a level can be used as a minimum level
+func ValidLogLevel(level string) bool {
+	_, ok := logLevelRank[strings.ToUpper(level)]
+	return ok
+}
+
+// Match reports whether a log passes the filter
+func (f LogStreamFilter) Match(entry *models.PostgreSQLLog) bool {
+	if f.MinLevel != "" && logLevelRank[strings.ToUpper(entry.LogLevel)] < logLevelRank[strings.ToUpper(f.MinLevel)] {
+		return false
+	}
+	if len(f.Categories) > 0 {
+		if entry.Category == nil {
+			return false
+		}
+		found := false
+		for _, c := range f.Categories {
+			if c == *entry.Category {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Text != "" && !strings.Contains(strings.ToLower(entry.LogMessage), strings.ToLower(f.Text)) {
+		return false
+	}
+	return true
+}
+
+// LogSubscription receives the logs of one collector that match a filter.
+// When the subscriber falls behind, logs are dropped rather than slowing
+// down ingestion; Dropped reports how many so that the subscriber can
+// resume from the last offset it received.
+type LogSubscription struct {
+	collectorID uuid.UUID
+	filter      LogStreamFilter
+	events      chan *models.PostgreSQLLog
+	dropped     atomic.Int64
+	stream      *LogStream
+	closeOnce   sync.Once
+}
+
+// Events returns the channel of matching logs
+func (s *LogSubscription) Events() <-chan *models.PostgreSQLLog {
+	return s.events
+}
+
+// Dropped returns and resets the number of logs dropped since the last call
+func (s *LogSubscription) Dropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// Close unsubscribes; the events channel is not closed
+func (s *LogSubscription) Close() {
+	s.closeOnce.Do(func() { s.stream.unsubscribe(s) })
+}
+
+func (s *LogSubscription) deliver(entry *models.PostgreSQLLog) {
+	if !s.filter.Match(entry) {
+		return
+	}
+	select {
+	case s.events <- entry:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// logStreamNotification announces ingested logs to the other replicas
+type logStreamNotification struct {
+	Origin      string    `json:"o"`
+	CollectorID uuid.UUID `json:"c"`
+	IDs         []int64   `json:"ids"`
+}
+
+// LogStream pushes ingested logs to subscribers in-process and, with a
+// store, to the subscribers of other API replicas through LISTEN/NOTIFY.
+// Offsets are log IDs, so a subscriber can resume from the last log it saw.
+type LogStream struct {
+	store  LogStreamStore
+	origin string
+
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[*LogSubscription]struct{}
+}
+
+// NewLogStream creates a log stream; store may be nil for a single process
+func NewLogStream(store LogStreamStore) *LogStream {
+	return &LogStream{
+		store:       store,
+		origin:      uuid.New().String(),
+		subscribers: make(map[uuid.UUID]map[*LogSubscription]struct{}),
+	}
+}
+
+// Subscribe starts receiving a collector's logs that match the filter
+func (ls *LogStream) Subscribe(collectorID uuid.UUID, filter LogStreamFilter, buffer int) *LogSubscription {
+	if buffer <= 0 {
+		buffer = DefaultStreamBuffer
+	}
+	sub := &LogSubscription{
+		collectorID: collectorID,
+		filter:      filter,
+		events:      make(chan *models.PostgreSQLLog, buffer),
+		stream:      ls,
+	}
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	if ls.subscribers[collectorID] == nil {
+		ls.subscribers[collectorID] = make(map[*LogSubscription]struct{})
+	}
+	ls.subscribers[collectorID][sub] = struct{}{}
+	return sub
+}
+
+func (ls *LogStream) unsubscribe(sub *LogSubscription) {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	delete(ls.subscribers[sub.collectorID], sub)
+	if len(ls.subscribers[sub.collectorID]) == 0 {
+		delete(ls.subscribers, sub.collectorID)
+	}
+}
+
+// Subscribers returns the number of subscriptions to a collector's logs
+func (ls *LogStream) Subscribers(collectorID uuid.UUID) int {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	return len(ls.subscribers[collectorID])
+}
+
+// Publish delivers stored logs of a collector to local subscribers and
+// announces them to the other replicas
+func (ls *LogStream) Publish(ctx context.Context, collectorID uuid.UUID, logs []*models.PostgreSQLLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	ls.deliver(collectorID, logs)
+
+	if ls.store == nil {
+		return nil
+	}
+	ids := make([]int64, 0, len(logs))
+	for _, entry := range logs {
+		if entry.ID != 0 {
+			ids = append(ids, entry.ID)
+		}
+	}
+	for start := 0; start < len(ids); start += notifyBatchSize {
+		end := start + notifyBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		payload, err := json.Marshal(logStreamNotification{Origin: ls.origin, CollectorID: collectorID, IDs: ids[start:end]})
+		if err != nil {
+			return err
+		}
+		if err := ls.store.NotifyLogStream(ctx, string(payload)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ls *LogStream) deliver(collectorID uuid.UUID, logs []*models.PostgreSQLLog) {
+	ls.mu.RLock()
+	defer ls.mu.RUnlock()
+	for sub := range ls.subscribers[collectorID] {
+		for _, entry := range logs {
+			sub.deliver(entry)
+		}
+	}
+}
+
+// Listen delivers logs announced by other replicas to local subscribers
+// until the context is cancelled
+func (ls *LogStream) Listen(ctx context.Context, connString string) error {
+	if ls.store == nil {
+		return fmt.Errorf("log stream has no store to listen on")
+	}
+	return ls.store.ListenLogStream(ctx, connString, func(payload string) {
+		_ = ls.handleNotification(ctx, payload)
+	})
+}
+
+// handleNotification loads the announced logs when a local subscriber
+// wants them; logs published by this replica were already delivered
+func (ls *LogStream) handleNotification(ctx context.Context, payload string) error {
+	var n logStreamNotification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		return err
+	}
+	if n.Origin == ls.origin || len(n.IDs) == 0 || ls.Subscribers(n.CollectorID) == 0 {
+		return nil
+	}
+
+	logs, err := ls.store.GetPostgresqlLogsByIDs(ctx, n.IDs)
+	if err != nil {
+		return err
+	}
+	ls.deliver(n.CollectorID, logs)
+	return nil
+}
+
+// Replay returns a collector's stored logs after an offset that match the
+// filter, oldest first, for resuming a stream. At most MaxStreamReplay logs
+// are examined; last is the offset of the last one and complete is false
+// when more logs follow it.
+func (ls *LogStream) Replay(ctx context.Context, collectorID uuid.UUID, afterID int64, filter LogStreamFilter) (logs []*models.PostgreSQLLog, last int64, complete bool, err error) {
+	if ls.store == nil {
+		return nil, afterID, true, nil
+	}
+	stored, err := ls.store.GetPostgresqlLogsAfter(ctx, collectorID, afterID, MaxStreamReplay)
+	if err != nil {
+		return nil, afterID, false, err
+	}
+
+	last = afterID
+	for _, entry := range stored {
+		last = entry.ID
+		if filter.Match(entry) {
+			logs = append(logs, entry)
+		}
+	}
+	return logs, last, len(stored) < MaxStreamReplay, nil
+}
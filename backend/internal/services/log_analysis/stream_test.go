@@ -0,0 +1,190 @@
+package log_analysis
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+type mockLogStreamStore struct {
+	notified []string
+	logs     map[int64]*models.PostgreSQLLog
+	fetched  [][]int64
+}
+
+func (m *mockLogStreamStore) NotifyLogStream(ctx context.Context, payload string) error {
+	m.notified = append(m.notified, payload)
+	return nil
+}
+
+func (m *mockLogStreamStore) ListenLogStream(ctx context.Context, connString string, handle func(payload string)) error {
+	for _, payload := range m.notified {
+		handle(payload)
+	}
+	return nil
+}
+
+func (m *mockLogStreamStore) GetPostgresqlLogsByIDs(ctx context.Context, ids []int64) ([]*models.PostgreSQLLog, error) {
+	m.fetched = append(m.fetched, ids)
+	var logs []*models.PostgreSQLLog
+	for _, id := range ids {
+		if entry, ok := m.logs[id]; ok {
+			logs = append(logs, entry)
+		}
+	}
+	return logs, nil
+}
+
+func (m *mockLogStreamStore) GetPostgresqlLogsAfter(ctx context.Context, collectorID uuid.UUID, afterID int64, limit int) ([]*models.PostgreSQLLog, error) {
+	var logs []*models.PostgreSQLLog
+	for id := afterID + 1; len(logs) < limit; id++ {
+		entry, ok := m.logs[id]
+		if !ok {
+			break
+		}
+		logs = append(logs, entry)
+	}
+	return logs, nil
+}
+
+func streamLog(id int64, level, category, message string) *models.PostgreSQLLog {
+	return &models.PostgreSQLLog{ID: id, LogLevel: level, Category: &category, LogMessage: message}
+}
+
+func TestLogStreamFilter_Match(t *testing.T) {
+	entry := streamLog(1, "ERROR", "lock_timeout", "canceling statement due to lock timeout")
+
+	assert.True(t, LogStreamFilter{}.Match(entry))
+	assert.True(t, LogStreamFilter{MinLevel: "warning"}.Match(entry))
+	assert.False(t, LogStreamFilter{MinLevel: "FATAL"}.Match(entry))
+	assert.True(t, LogStreamFilter{Categories: []string{"deadlock", "lock_timeout"}}.Match(entry))
+	assert.False(t, LogStreamFilter{Categories: []string{"deadlock"}}.Match(entry))
+	assert.True(t, LogStreamFilter{Text: "LOCK TIMEOUT"}.Match(entry))
+	assert.False(t, LogStreamFilter{Text: "deadlock"}.Match(entry))
+
+	// LOG messages rank with INFO
+	assert.False(t, LogStreamFilter{MinLevel: "NOTICE"}.Match(streamLog(2, "LOG", "checkpoint", "checkpoint starting")))
+	assert.False(t, LogStreamFilter{Categories: []string{"checkpoint"}}.Match(&models.PostgreSQLLog{LogLevel: "LOG"}))
+
+	assert.True(t, ValidLogLevel("warning"))
+	assert.False(t, ValidLogLevel("LOUD"))
+}
+
+func TestLogStream_PublishDeliversMatchingLogs(t *testing.T) {
+	stream := NewLogStream(nil)
+	collectorID := uuid.New()
+
+	errors := stream.Subscribe(collectorID, LogStreamFilter{MinLevel: "ERROR"}, 10)
+	all := stream.Subscribe(collectorID, LogStreamFilter{}, 10)
+	other := stream.Subscribe(uuid.New(), LogStreamFilter{}, 10)
+	defer errors.Close()
+	defer other.Close()
+
+	require.NoError(t, stream.Publish(context.Background(), collectorID, []*models.PostgreSQLLog{
+		streamLog(1, "LOG", "connection", "connection received"),
+		streamLog(2, "ERROR", "syntax_error", "syntax error at or near \"FORM\""),
+	}))
+
+	assert.Len(t, errors.Events(), 1)
+	assert.Equal(t, int64(2), (<-errors.Events()).ID)
+	assert.Len(t, all.Events(), 2)
+	assert.Len(t, other.Events(), 0)
+
+	all.Close()
+	all.Close()
+	assert.Equal(t, 1, stream.Subscribers(collectorID))
+}
+
+func TestLogStream_SlowSubscriberDropsInsteadOfBlocking(t *testing.T) {
+	stream := NewLogStream(nil)
+	collectorID := uuid.New()
+	sub := stream.Subscribe(collectorID, LogStreamFilter{}, 2)
+	defer sub.Close()
+
+	var logs []*models.PostgreSQLLog
+	for i := int64(1); i <= 5; i++ {
+		logs = append(logs, streamLog(i, "ERROR", "unknown", "boom"))
+	}
+	require.NoError(t, stream.Publish(context.Background(), collectorID, logs))
+
+	assert.Len(t, sub.Events(), 2)
+	assert.Equal(t, int64(3), sub.Dropped())
+	assert.Equal(t, int64(0), sub.Dropped())
+}
+
+func TestLogStream_FanOutBetweenReplicas(t *testing.T) {
+	store := &mockLogStreamStore{logs: map[int64]*models.PostgreSQLLog{}}
+	collectorID := uuid.New()
+
+	var logs []*models.PostgreSQLLog
+	for i := int64(1); i <= notifyBatchSize+1; i++ {
+		entry := streamLog(i, "ERROR", "unknown", "boom")
+		store.logs[i] = entry
+		logs = append(logs, entry)
+	}
+
+	publisher := NewLogStream(store)
+	require.NoError(t, publisher.Publish(context.Background(), collectorID, logs))
+	require.Len(t, store.notified, 2)
+	var n logStreamNotification
+	require.NoError(t, json.Unmarshal([]byte(store.notified[0]), &n))
+	assert.Equal(t, collectorID, n.CollectorID)
+	assert.Len(t, n.IDs, notifyBatchSize)
+	assert.Less(t, len(store.notified[0]), 8000)
+
+	// The publishing replica ignores its own notifications
+	require.NoError(t, publisher.handleNotification(context.Background(), store.notified[0]))
+	assert.Empty(t, store.fetched)
+
+	// Another replica without subscribers does not load anything
+	replica := NewLogStream(store)
+	require.NoError(t, replica.Listen(context.Background(), "postgres://unused"))
+	assert.Empty(t, store.fetched)
+
+	sub := replica.Subscribe(collectorID, LogStreamFilter{}, 1000)
+	defer sub.Close()
+	require.NoError(t, replica.Listen(context.Background(), "postgres://unused"))
+	assert.Len(t, store.fetched, 2)
+	assert.Len(t, sub.Events(), notifyBatchSize+1)
+
+	assert.Error(t, replica.handleNotification(context.Background(), "not json"))
+}
+
+func TestLogStream_Replay(t *testing.T) {
+	store := &mockLogStreamStore{logs: map[int64]*models.PostgreSQLLog{}}
+	for i := int64(1); i <= MaxStreamReplay+10; i++ {
+		level := "LOG"
+		if i%2 == 0 {
+			level = "ERROR"
+		}
+		store.logs[i] = streamLog(i, level, "unknown", "message")
+	}
+	stream := NewLogStream(store)
+	collectorID := uuid.New()
+
+	logs, last, complete, err := stream.Replay(context.Background(), collectorID, MaxStreamReplay, LogStreamFilter{MinLevel: "ERROR"})
+	require.NoError(t, err)
+	assert.True(t, complete)
+	assert.Equal(t, int64(MaxStreamReplay+10), last)
+	require.Len(t, logs, 5)
+	assert.Equal(t, int64(MaxStreamReplay+2), logs[0].ID)
+
+	logs, last, complete, err = stream.Replay(context.Background(), collectorID, 0, LogStreamFilter{})
+	require.NoError(t, err)
+	assert.False(t, complete)
+	assert.Equal(t, int64(MaxStreamReplay), last)
+	assert.Len(t, logs, MaxStreamReplay)
+
+	// Without a store there is nothing to replay
+	logs, last, complete, err = NewLogStream(nil).Replay(context.Background(), collectorID, 7, LogStreamFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, logs)
+	assert.Equal(t, int64(7), last)
+	assert.True(t, complete)
+	assert.Error(t, NewLogStream(nil).Listen(context.Background(), "postgres://unused"))
+}
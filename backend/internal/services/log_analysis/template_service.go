@@ -0,0 +1,285 @@
+package log_analysis
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+const (
+	// templateLoadWindow limits mining to templates seen recently
+	templateLoadWindow = 7 * 24 * time.Hour
+	// maxLoadedTemplates bounds the templates loaded per collector
+	maxLoadedTemplates = 5000
+	// templateCacheTTL is how long a collector's templates are mined in
+	// memory before they are reloaded, picking up templates that other
+	// backend instances mined for the collector
+	templateCacheTTL = 10 * time.Minute
+
+	DefaultBaselineWindows = 24
+	DefaultSpikeFactor     = 3.0
+	DefaultMinSpikeCount   = 10
+)
+
+// TemplateStore is the data access for log templates
+type TemplateStore interface {
+	GetLogTemplates(ctx context.Context, collectorID uuid.UUID, since time.Time, limit int) ([]*models.LogTemplate, error)
+	GetLogTemplatesByID(ctx context.Context, collectorID uuid.UUID, ids []int64) ([]*models.LogTemplate, error)
+	// SaveLogTemplate inserts a template when its ID is zero and sets the ID,
+	// otherwise updates it; added is the number of new messages
+	SaveLogTemplate(ctx context.Context, template *models.LogTemplate, added int64) error
+	AddLogTemplateCounts(ctx context.Context, counts []*models.LogTemplateCount) error
+	GetLogTemplateCounts(ctx context.Context, collectorID uuid.UUID, from, to time.Time) (map[int64]int64, error)
+}
+
+// TemplateReportOptions controls anomaly detection in template reports
+type TemplateReportOptions struct {
+	Limit         int
+	AnomaliesOnly bool
+	// BaselineWindows is the number of window lengths before the window
+	// used to compute each template's normal rate
+	BaselineWindows int
+	SpikeFactor     float64
+	MinSpikeCount   int64
+}
+
+// TemplateService mines log templates at ingest and reports on them
+type TemplateService struct {
+	store  TemplateStore
+	miners sync.Map // collector ID -> *collectorMiner
+}
+
+// collectorMiner is the cached template tree of a collector. Its lock
+// serializes mining per collector within this process; templates mined
+// concurrently by other instances are merged by the store.
+type collectorMiner struct {
+	mu       sync.Mutex
+	miner    *TemplateMiner
+	stored   map[*TemplateCluster]*models.LogTemplate
+	loadedAt time.Time
+}
+
+// NewTemplateService creates a new log template service
+func NewTemplateService(store TemplateStore) *TemplateService {
+	return &TemplateService{store: store}
+}
+
+// templateBatch accumulates the messages of one ingest batch per template
+type templateBatch struct {
+	cluster *TemplateCluster
+	stored  *models.LogTemplate
+	sample  *models.PostgreSQLLog
+	count   int64
+	first   time.Time
+	last    time.Time
+	buckets map[time.Time]int64
+}
+
+// ObserveLogs assigns each log to a template and records per-minute counts
+func (s *TemplateService) ObserveLogs(ctx context.Context, collectorID uuid.UUID, logs []*models.PostgreSQLLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	cached, _ := s.miners.LoadOrStore(collectorID, &collectorMiner{})
+	cm := cached.(*collectorMiner)
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if cm.miner == nil || time.Since(cm.loadedAt) > templateCacheTTL {
+		if err := s.loadMiner(ctx, collectorID, cm); err != nil {
+			return err
+		}
+	}
+	miner, stored := cm.miner, cm.stored
+
+	batches := make(map[*TemplateCluster]*templateBatch)
+	var order []*TemplateCluster
+	for _, entry := range logs {
+		cluster := miner.Add(entry.LogMessage)
+		b, ok := batches[cluster]
+		if !ok {
+			b = &templateBatch{
+				cluster: cluster,
+				stored:  stored[cluster],
+				sample:  entry,
+				first:   entry.LogTimestamp,
+				last:    entry.LogTimestamp,
+				buckets: make(map[time.Time]int64),
+			}
+			batches[cluster] = b
+			order = append(order, cluster)
+		}
+		b.count++
+		if entry.LogTimestamp.Before(b.first) {
+			b.first = entry.LogTimestamp
+		}
+		if entry.LogTimestamp.After(b.last) {
+			b.last = entry.LogTimestamp
+		}
+		b.buckets[entry.LogTimestamp.Truncate(time.Minute)]++
+	}
+
+	var counts []*models.LogTemplateCount
+	for _, cluster := range order {
+		b := batches[cluster]
+		template := b.stored
+		if template == nil {
+			template = &models.LogTemplate{
+				CollectorID:   collectorID,
+				LogLevel:      b.sample.LogLevel,
+				Category:      b.sample.Category,
+				SampleMessage: b.sample.LogMessage,
+				FirstSeen:     b.first,
+			}
+		}
+		if b.first.Before(template.FirstSeen) {
+			template.FirstSeen = b.first
+		}
+		template.Template = cluster.Template()
+		template.TokenCount = len(cluster.Tokens)
+		if b.last.After(template.LastSeen) {
+			template.LastSeen = b.last
+		}
+
+		if err := s.store.SaveLogTemplate(ctx, template, b.count); err != nil {
+			// The tree holds clusters that were not stored; reload it
+			cm.miner = nil
+			return err
+		}
+		cluster.ID = template.ID
+		stored[cluster] = template
+
+		for bucket, n := range b.buckets {
+			counts = append(counts, &models.LogTemplateCount{TemplateID: template.ID, Bucket: bucket, Count: n})
+		}
+	}
+
+	return s.store.AddLogTemplateCounts(ctx, counts)
+}
+
+// loadMiner rebuilds a collector's template tree from its recently seen
+// templates
+func (s *TemplateService) loadMiner(ctx context.Context, collectorID uuid.UUID, cm *collectorMiner) error {
+	existing, err := s.store.GetLogTemplates(ctx, collectorID, time.Now().Add(-templateLoadWindow), maxLoadedTemplates)
+	if err != nil {
+		return err
+	}
+
+	cm.miner = NewTemplateMiner()
+	cm.stored = make(map[*TemplateCluster]*models.LogTemplate, len(existing))
+	for _, t := range existing {
+		cm.stored[cm.miner.Load(t.ID, t.Template)] = t
+	}
+	cm.loadedAt = time.Now()
+	return nil
+}
+
+// TopTemplates lists a collector's most frequent templates in [from, to) and
+// flags templates first seen in the window and templates whose count is far
+// above their rate over the preceding baseline period
+func (s *TemplateService) TopTemplates(ctx context.Context, collectorID uuid.UUID, from, to time.Time, opts TemplateReportOptions) (*models.LogTemplateReport, error) {
+	if opts.BaselineWindows <= 0 {
+		opts.BaselineWindows = DefaultBaselineWindows
+	}
+	if opts.SpikeFactor <= 0 {
+		opts.SpikeFactor = DefaultSpikeFactor
+	}
+	if opts.MinSpikeCount <= 0 {
+		opts.MinSpikeCount = DefaultMinSpikeCount
+	}
+
+	window := to.Sub(from)
+	baselineFrom := from.Add(-window * time.Duration(opts.BaselineWindows))
+
+	report := &models.LogTemplateReport{
+		CollectorID:  collectorID,
+		From:         from,
+		To:           to,
+		BaselineFrom: baselineFrom,
+		Templates:    []*models.LogTemplateStats{},
+		GeneratedAt:  time.Now(),
+	}
+
+	windowCounts, err := s.store.GetLogTemplateCounts(ctx, collectorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(windowCounts) == 0 {
+		return report, nil
+	}
+	baselineCounts, err := s.store.GetLogTemplateCounts(ctx, collectorID, baselineFrom, from)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(windowCounts))
+	for id := range windowCounts {
+		ids = append(ids, id)
+	}
+	templates, err := s.store.GetLogTemplatesByID(ctx, collectorID, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range templates {
+		stats := scoreTemplate(t, windowCounts[t.ID], baselineCounts[t.ID], from, baselineFrom, window, opts)
+		report.TotalMessages += stats.WindowCount
+		report.DistinctTemplates++
+		if stats.Anomaly != "" {
+			report.Anomalies++
+		} else if opts.AnomaliesOnly {
+			continue
+		}
+		report.Templates = append(report.Templates, stats)
+	}
+
+	sort.Slice(report.Templates, func(i, j int) bool {
+		if report.Templates[i].WindowCount != report.Templates[j].WindowCount {
+			return report.Templates[i].WindowCount > report.Templates[j].WindowCount
+		}
+		return report.Templates[i].ID < report.Templates[j].ID
+	})
+	if opts.Limit > 0 && len(report.Templates) > opts.Limit {
+		report.Templates = report.Templates[:opts.Limit]
+	}
+
+	return report, nil
+}
+
+// scoreTemplate compares a template's window count with its baseline rate.
+// The baseline only covers the time since the template was first seen.
+func scoreTemplate(t *models.LogTemplate, count, baseline int64, from, baselineFrom time.Time, window time.Duration, opts TemplateReportOptions) *models.LogTemplateStats {
+	stats := &models.LogTemplateStats{LogTemplate: t, WindowCount: count}
+
+	if !t.FirstSeen.Before(from) {
+		stats.Anomaly = models.LogTemplateAnomalyNew
+		return stats
+	}
+
+	start := baselineFrom
+	if t.FirstSeen.After(start) {
+		start = t.FirstSeen
+	}
+	periods := float64(from.Sub(start)) / float64(window)
+	if periods < 1 {
+		periods = 1
+	}
+	rate := float64(baseline) / periods
+	stats.BaselineRate = math.Round(rate*1000) / 1000
+
+	if rate > 0 {
+		ratio := math.Round(float64(count)/rate*100) / 100
+		stats.SpikeRatio = &ratio
+	}
+	if count >= opts.MinSpikeCount && float64(count) >= opts.SpikeFactor*rate {
+		stats.Anomaly = models.LogTemplateAnomalySpike
+	}
+
+	return stats
+}
@@ -0,0 +1,199 @@
+package log_analysis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// mockTemplateStore keeps templates and per-minute counts in memory
+type mockTemplateStore struct {
+	templates map[int64]*models.LogTemplate
+	counts    map[int64]map[time.Time]int64
+	nextID    int64
+	loads     int
+}
+
+func newMockTemplateStore() *mockTemplateStore {
+	return &mockTemplateStore{
+		templates: make(map[int64]*models.LogTemplate),
+		counts:    make(map[int64]map[time.Time]int64),
+	}
+}
+
+func (m *mockTemplateStore) GetLogTemplates(ctx context.Context, collectorID uuid.UUID, since time.Time, limit int) ([]*models.LogTemplate, error) {
+	m.loads++
+	var out []*models.LogTemplate
+	for _, t := range m.templates {
+		if t.CollectorID == collectorID && !t.LastSeen.Before(since) {
+			copied := *t
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockTemplateStore) GetLogTemplatesByID(ctx context.Context, collectorID uuid.UUID, ids []int64) ([]*models.LogTemplate, error) {
+	var out []*models.LogTemplate
+	for _, id := range ids {
+		if t, ok := m.templates[id]; ok && t.CollectorID == collectorID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockTemplateStore) SaveLogTemplate(ctx context.Context, t *models.LogTemplate, added int64) error {
+	if t.ID == 0 {
+		m.nextID++
+		t.ID = m.nextID
+		t.TotalCount = 0
+	}
+	t.TotalCount += added
+	copied := *t
+	m.templates[t.ID] = &copied
+	return nil
+}
+
+func (m *mockTemplateStore) AddLogTemplateCounts(ctx context.Context, counts []*models.LogTemplateCount) error {
+	for _, c := range counts {
+		if m.counts[c.TemplateID] == nil {
+			m.counts[c.TemplateID] = make(map[time.Time]int64)
+		}
+		m.counts[c.TemplateID][c.Bucket] += c.Count
+	}
+	return nil
+}
+
+func (m *mockTemplateStore) GetLogTemplateCounts(ctx context.Context, collectorID uuid.UUID, from, to time.Time) (map[int64]int64, error) {
+	out := make(map[int64]int64)
+	for id, buckets := range m.counts {
+		for bucket, n := range buckets {
+			if !bucket.Before(from) && bucket.Before(to) {
+				out[id] += n
+			}
+		}
+	}
+	return out, nil
+}
+
+func logsAt(ts time.Time, n int, format string) []*models.PostgreSQLLog {
+	var out []*models.PostgreSQLLog
+	for i := 0; i < n; i++ {
+		out = append(out, &models.PostgreSQLLog{
+			LogTimestamp: ts.Add(time.Duration(i) * time.Second),
+			LogLevel:     "ERROR",
+			LogMessage:   fmt.Sprintf(format, i),
+		})
+	}
+	return out
+}
+
+func TestTemplateService_ObserveLogs(t *testing.T) {
+	store := newMockTemplateStore()
+	service := NewTemplateService(store)
+	collectorID := uuid.New()
+	now := time.Now().Truncate(time.Hour)
+
+	require.NoError(t, service.ObserveLogs(context.Background(), collectorID,
+		logsAt(now, 100, "duplicate key value violates unique constraint for id %d")))
+	require.NoError(t, service.ObserveLogs(context.Background(), collectorID,
+		logsAt(now.Add(time.Minute), 50, "duplicate key value violates unique constraint for id %d")))
+
+	require.Len(t, store.templates, 1)
+	tmpl := store.templates[1]
+	assert.Equal(t, "duplicate key value violates unique constraint for id <*>", tmpl.Template)
+	assert.Equal(t, int64(150), tmpl.TotalCount)
+	assert.Equal(t, now, tmpl.FirstSeen)
+	assert.Equal(t, now.Add(time.Minute+49*time.Second), tmpl.LastSeen)
+	assert.Equal(t, "duplicate key value violates unique constraint for id 0", tmpl.SampleMessage)
+
+	// 100 messages over 100 seconds span two minute buckets
+	assert.Equal(t, int64(60), store.counts[1][now])
+	assert.Equal(t, int64(40+50), store.counts[1][now.Add(time.Minute)])
+}
+
+// TestTemplateService_CachesTemplates mines batches against the cached tree
+// and reloads it once the cache expires
+func TestTemplateService_CachesTemplates(t *testing.T) {
+	store := newMockTemplateStore()
+	service := NewTemplateService(store)
+	collectorID := uuid.New()
+	now := time.Now().Truncate(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, service.ObserveLogs(context.Background(), collectorID,
+			logsAt(now.Add(time.Duration(i)*time.Minute), 10, "canceling statement due to lock timeout %d")))
+	}
+	assert.Equal(t, 1, store.loads)
+	require.Len(t, store.templates, 1)
+	assert.Equal(t, int64(30), store.templates[1].TotalCount)
+
+	cached, ok := service.miners.Load(collectorID)
+	require.True(t, ok)
+	cached.(*collectorMiner).loadedAt = time.Now().Add(-templateCacheTTL - time.Second)
+
+	require.NoError(t, service.ObserveLogs(context.Background(), collectorID,
+		logsAt(now.Add(3*time.Minute), 10, "canceling statement due to lock timeout %d")))
+	assert.Equal(t, 2, store.loads)
+	require.Len(t, store.templates, 1)
+	assert.Equal(t, int64(40), store.templates[1].TotalCount)
+}
+
+func TestTemplateService_TopTemplates(t *testing.T) {
+	store := newMockTemplateStore()
+	service := NewTemplateService(store)
+	collectorID := uuid.New()
+	to := time.Now().Truncate(time.Hour)
+	from := to.Add(-time.Hour)
+
+	// Steady: 10 per hour for the past day and in the window
+	for h := 24; h >= 1; h-- {
+		require.NoError(t, service.ObserveLogs(context.Background(), collectorID,
+			logsAt(from.Add(-time.Duration(h)*time.Hour), 10, "checkpoint starting: time %d")))
+	}
+	require.NoError(t, service.ObserveLogs(context.Background(), collectorID,
+		logsAt(from, 10, "checkpoint starting: time %d")))
+
+	// Spiking: 2 per hour before, 200 in the window
+	for h := 24; h >= 1; h-- {
+		require.NoError(t, service.ObserveLogs(context.Background(), collectorID,
+			logsAt(from.Add(-time.Duration(h)*time.Hour), 2, "could not serialize access due to concurrent update %d")))
+	}
+	require.NoError(t, service.ObserveLogs(context.Background(), collectorID,
+		logsAt(from.Add(time.Minute), 200, "could not serialize access due to concurrent update %d")))
+
+	// New in the window
+	require.NoError(t, service.ObserveLogs(context.Background(), collectorID,
+		logsAt(from.Add(2*time.Minute), 5, "permission denied for table audit_%d")))
+
+	report, err := service.TopTemplates(context.Background(), collectorID, from, to, TemplateReportOptions{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.DistinctTemplates)
+	assert.Equal(t, int64(215), report.TotalMessages)
+	assert.Equal(t, 2, report.Anomalies)
+	require.Len(t, report.Templates, 3)
+
+	spike := report.Templates[0]
+	assert.Equal(t, int64(200), spike.WindowCount)
+	assert.Equal(t, models.LogTemplateAnomalySpike, spike.Anomaly)
+	assert.Equal(t, 2.0, spike.BaselineRate)
+	assert.Equal(t, 100.0, *spike.SpikeRatio)
+
+	steady := report.Templates[1]
+	assert.Equal(t, "checkpoint starting: time <*>", steady.Template)
+	assert.Empty(t, steady.Anomaly)
+	assert.Equal(t, 10.0, steady.BaselineRate)
+
+	assert.Equal(t, models.LogTemplateAnomalyNew, report.Templates[2].Anomaly)
+
+	report, err = service.TopTemplates(context.Background(), collectorID, from, to, TemplateReportOptions{AnomaliesOnly: true})
+	require.NoError(t, err)
+	assert.Len(t, report.Templates, 2)
+}
@@ -0,0 +1,397 @@
+package query_performance
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// durationLogPattern matches log_min_duration_statement and auto_explain
+// messages: "duration: 12.345 ms  statement: ...", "duration: 1.2 ms
+// execute S_1: ..." and "duration: 1503.2 ms  plan:\n..."
+var durationLogPattern = regexp.MustCompile(`(?s)^duration: ([\d.]+) ms\s+(plan|statement|(?:execute|parse|bind) [^:\s]*):\s*(.*)$`)
+
+// planNodePattern matches a node line of a text plan, e.g.
+// "  ->  Seq Scan on orders o  (cost=0.00..35.50 rows=2550 width=4) (actual time=0.01..0.3 rows=2550 loops=1)"
+var planNodePattern = regexp.MustCompile(`^(\s*)(?:->\s+)?(\S.*?)\s+\(cost=[\d.]+\.\.([\d.]+) rows=(\d+) width=(\d+)\)(?:\s+\(actual (?:time=[\d.]+\.\.[\d.]+ )?rows=(\d+) loops=(\d+)\))?`)
+
+// planJoinPattern splits join node labels such as "Hash Left Join"
+var planJoinPattern = regexp.MustCompile(`^(Nested Loop|Hash|Merge)(?: (Left|Right|Full|Semi|Anti|Right Semi|Right Anti))?(?: Join)?$`)
+
+var planBuffersPattern = regexp.MustCompile(`\bshared\b(?: hit=(\d+))?(?: read=(\d+))?`)
+
+// DurationLog is a log_min_duration_statement or auto_explain message
+type DurationLog struct {
+	DurationMs float64
+	QueryText  string
+	// PlanFormat is "json" or "text" for auto_explain messages and empty for
+	// statement durations
+	PlanFormat string
+	// Plan is the plan as EXPLAIN (FORMAT JSON) output, without the query text
+	Plan json.RawMessage
+	// PlanText is the plan of a text format message, without the query text
+	PlanText string
+	Root     *PlanNode
+}
+
+// ParseDurationLog parses a slow query log message. Plans logged by
+// auto_explain in text or JSON format are extracted; other formats keep
+// the duration and query text only.
+func ParseDurationLog(message string) (*DurationLog, bool, error) {
+	m := durationLogPattern.FindStringSubmatch(strings.TrimSpace(message))
+	if m == nil {
+		return nil, false, nil
+	}
+	duration, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	entry := &DurationLog{DurationMs: duration}
+	body := strings.TrimSpace(m[3])
+	if m[2] != "plan" {
+		entry.QueryText = body
+		return entry, true, nil
+	}
+
+	switch {
+	case strings.HasPrefix(body, "{"):
+		err = entry.parseJSONPlan(body)
+	case strings.HasPrefix(body, "Query Text:"):
+		err = entry.parseTextPlan(body)
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	return entry, true, nil
+}
+
+// parseJSONPlan reads an auto_explain.log_format = json payload
+func (d *DurationLog) parseJSONPlan(body string) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return fmt.Errorf("parse auto_explain JSON plan: %w", err)
+	}
+	if raw, ok := fields["Query Text"]; ok {
+		if err := json.Unmarshal(raw, &d.QueryText); err != nil {
+			return fmt.Errorf("parse auto_explain query text: %w", err)
+		}
+		delete(fields, "Query Text")
+	}
+
+	var plan FullExplainPlan
+	if raw, ok := fields["Plan"]; !ok {
+		return fmt.Errorf("auto_explain JSON plan has no Plan")
+	} else if err := json.Unmarshal(raw, &plan.Plan); err != nil {
+		return fmt.Errorf("parse auto_explain JSON plan: %w", err)
+	}
+
+	encoded, err := json.Marshal([]map[string]json.RawMessage{fields})
+	if err != nil {
+		return err
+	}
+	d.PlanFormat = "json"
+	d.Plan = encoded
+	d.Root = plan.Plan
+	return nil
+}
+
+// parseTextPlan reads an auto_explain.log_format = text payload: the query
+// text, which can span lines, followed by the plan tree
+func (d *DurationLog) parseTextPlan(body string) error {
+	lines := strings.Split(strings.TrimPrefix(body, "Query Text:"), "\n")
+	start := len(lines)
+	for i, line := range lines {
+		if i > 0 && planNodePattern.MatchString(line) {
+			start = i
+			break
+		}
+	}
+	if start == len(lines) {
+		return fmt.Errorf("auto_explain text plan has no plan nodes")
+	}
+
+	root, err := ParseTextPlan(strings.Join(lines[start:], "\n"))
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal([]FullExplainPlan{{Plan: root}})
+	if err != nil {
+		return err
+	}
+
+	d.QueryText = strings.TrimSpace(strings.Join(lines[:start], "\n"))
+	d.PlanFormat = "text"
+	d.PlanText = strings.Join(lines[start:], "\n")
+	d.Plan = encoded
+	d.Root = root
+	return nil
+}
+
+// ParseTextPlan builds the plan tree of EXPLAIN text output. Children are
+// attached by the indentation of their "->" marker; Filter, Hash Cond and
+// Buffers lines are kept on the node they follow.
+func ParseTextPlan(text string) (*PlanNode, error) {
+	type level struct {
+		indent int
+		node   *PlanNode
+	}
+	var (
+		root  *PlanNode
+		stack []level
+	)
+
+	for _, line := range strings.Split(text, "\n") {
+		if m := planNodePattern.FindStringSubmatch(line); m != nil {
+			node := newTextPlanNode(m)
+			indent := len(m[1])
+			if root == nil {
+				root = node
+				stack = []level{{indent: -1, node: node}}
+				continue
+			}
+			for len(stack) > 1 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			parent := stack[len(stack)-1].node
+			parent.Plans = append(parent.Plans, node)
+			stack = append(stack, level{indent: indent, node: node})
+			continue
+		}
+
+		if len(stack) == 0 {
+			continue
+		}
+		node := stack[len(stack)-1].node
+		detail := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(detail, "Filter: "):
+			node.Filter = strings.TrimPrefix(detail, "Filter: ")
+		case strings.HasPrefix(detail, "Hash Cond: "):
+			node.HashCond = strings.TrimPrefix(detail, "Hash Cond: ")
+		case strings.HasPrefix(detail, "Buffers: "):
+			if b := planBuffersPattern.FindStringSubmatch(detail); b != nil {
+				node.SharedHit, _ = strconv.ParseInt(b[1], 10, 64)
+				node.SharedRead, _ = strconv.ParseInt(b[2], 10, 64)
+			}
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("text plan has no plan nodes")
+	}
+	return root, nil
+}
+
+// newTextPlanNode converts a node line into the fields EXPLAIN JSON would use
+func newTextPlanNode(m []string) *PlanNode {
+	node := &PlanNode{}
+	node.TotalCost, _ = strconv.ParseFloat(m[3], 64)
+	node.PlanRows, _ = strconv.ParseInt(m[4], 10, 64)
+	node.PlanWidth, _ = strconv.Atoi(m[5])
+	if m[6] != "" {
+		node.ActualRows, _ = strconv.ParseInt(m[6], 10, 64)
+		node.ActualLoops, _ = strconv.ParseInt(m[7], 10, 64)
+	}
+
+	label := strings.TrimPrefix(m[2], "Parallel ")
+	if i := strings.Index(label, " on "); i >= 0 {
+		target := strings.Fields(label[i+len(" on "):])
+		label = label[:i]
+		if len(target) > 0 {
+			node.RelationName = target[0]
+		}
+	}
+	if i := strings.Index(label, " using "); i >= 0 {
+		node.IndexName = label[i+len(" using "):]
+		label = label[:i]
+	}
+	label = strings.TrimSuffix(label, " Backward")
+
+	if label == "Bitmap Index Scan" {
+		node.IndexName, node.RelationName = node.RelationName, ""
+	}
+	if j := planJoinPattern.FindStringSubmatch(label); j != nil {
+		label = j[1]
+		if label != "Nested Loop" {
+			label += " Join"
+		}
+		node.JoinType = "Inner"
+		if j[2] != "" {
+			node.JoinType = j[2]
+		}
+	}
+	node.NodeType = label
+	return node
+}
+
+// AnalyzeExplainPlan runs the query parser over a plan in EXPLAIN JSON form
+// and records the issues and scan types found on the plan
+func AnalyzeExplainPlan(plan *models.ExplainPlan) error {
+	planJSON, err := json.Marshal(plan.PlanJSON)
+	if err != nil {
+		return err
+	}
+	var plans []FullExplainPlan
+	if err := json.Unmarshal(planJSON, &plans); err != nil {
+		return fmt.Errorf("plan is not EXPLAIN (FORMAT JSON) output: %w", err)
+	}
+
+	issues, err := NewQueryParser().DetectIssuesFull(string(planJSON))
+	if err != nil {
+		return err
+	}
+	if issues == nil {
+		issues = []QueryIssue{}
+	}
+	plan.Issues = issues
+
+	for _, p := range plans {
+		walkPlanNodes(p.Plan, func(node *PlanNode) {
+			switch node.NodeType {
+			case "Seq Scan":
+				plan.HasSeqScan = true
+			case "Index Scan", "Index Only Scan":
+				plan.HasIndexScan = true
+			case "Bitmap Heap Scan", "Bitmap Index Scan":
+				plan.HasBitmapScan = true
+			case "Nested Loop":
+				plan.HasNestedLoop = true
+			}
+		})
+	}
+	return nil
+}
+
+func walkPlanNodes(node *PlanNode, visit func(*PlanNode)) {
+	if node == nil {
+		return
+	}
+	visit(node)
+	for _, child := range node.Plans {
+		walkPlanNodes(child, visit)
+	}
+}
+
+// ExplainPlanFromLog builds the explain plan of an auto_explain message,
+// linked to the fingerprint of its query. The query text is redacted like
+// query text, and plan strings such as filters like log messages. It
+// returns nil for messages without a plan.
+func ExplainPlanFromLog(entry *models.PostgreSQLLog, redactor *Redactor) (*models.ExplainPlan, error) {
+	parsed, ok, err := ParseDurationLog(entry.LogMessage)
+	if err != nil || !ok || parsed.Plan == nil {
+		return nil, err
+	}
+
+	var planJSON interface{}
+	if err := json.Unmarshal(parsed.Plan, &planJSON); err != nil {
+		return nil, err
+	}
+	collectorID := entry.CollectorID
+	duration := parsed.DurationMs
+	plan := &models.ExplainPlan{
+		CollectorID:         &collectorID,
+		Source:              models.ExplainPlanSourceAutoExplain,
+		CollectedAt:         entry.LogTimestamp,
+		PlanJSON:            redactPlanStrings(planJSON, redactor),
+		ExecutionDurationMs: &duration,
+	}
+
+	if parsed.QueryText != "" {
+		fingerprint := NewFingerprinter().Fingerprint(parsed.QueryText)
+		queryText := redactor.RedactQuery(parsed.QueryText)
+		plan.QueryFingerprint = &fingerprint
+		plan.QueryText = &queryText
+		plan.QueryHash = fingerprintQueryHash(fingerprint)
+	}
+	// The query_id logged with compute_query_id matches pg_stat_statements
+	if entry.QueryHash != nil {
+		plan.QueryHash = *entry.QueryHash
+	}
+	if parsed.PlanText != "" {
+		planText := redactor.RedactLogMessage(parsed.PlanText)
+		plan.PlanText = &planText
+	}
+
+	if root := parsed.Root; root != nil {
+		plan.RowsExpected = &root.PlanRows
+		if root.ActualLoops > 0 {
+			plan.RowsActual = &root.ActualRows
+		}
+		if root.SharedHit > 0 || root.SharedRead > 0 {
+			plan.TotalBuffersHit = &root.SharedHit
+			plan.TotalBuffersRead = &root.SharedRead
+		}
+	}
+
+	if err := AnalyzeExplainPlan(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// RedactPlanJSON redacts the string values of a stored JSON plan like
+// ExplainPlanFromLog does at ingestion. Plans that do not decode, or that
+// redact to themselves, are returned unchanged.
+func (r *Redactor) RedactPlanJSON(plan string) string {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(plan), &decoded); err != nil {
+		return plan
+	}
+	// Compare canonical encodings, since stored JSONB is formatted differently
+	before, err := json.Marshal(decoded)
+	if err != nil {
+		return plan
+	}
+	after, err := json.Marshal(redactPlanStrings(decoded, r))
+	if err != nil || string(after) == string(before) {
+		return plan
+	}
+	return string(after)
+}
+
+// RedactExplainPlan redacts a plan received from a collector like
+// ExplainPlanFromLog redacts logged plans: the query text like query text,
+// and the text plan and JSON plan strings like log messages
+func (r *Redactor) RedactExplainPlan(plan *models.ExplainPlan) {
+	plan.PlanJSON = redactPlanStrings(plan.PlanJSON, r)
+	if plan.QueryText != nil {
+		queryText := r.RedactQuery(*plan.QueryText)
+		plan.QueryText = &queryText
+	}
+	if plan.PlanText != nil {
+		planText := r.RedactLogMessage(*plan.PlanText)
+		plan.PlanText = &planText
+	}
+}
+
+// redactPlanStrings redacts every string value of a decoded JSON plan
+func redactPlanStrings(value interface{}, redactor *Redactor) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redactor.RedactLogMessage(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = redactPlanStrings(v[i], redactor)
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = redactPlanStrings(v[k], redactor)
+		}
+	}
+	return value
+}
+
+// fingerprintQueryHash derives a query hash from the leading 64 bits of a
+// fingerprint for plans logged without a query_id
+func fingerprintQueryHash(fingerprint string) int64 {
+	if len(fingerprint) < 16 {
+		return 0
+	}
+	hash, _ := strconv.ParseUint(fingerprint[:16], 16, 64)
+	return int64(hash)
+}
@@ -0,0 +1,227 @@
+package query_performance
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+const autoExplainTextMessage = `duration: 1503.221 ms  plan:
+Query Text: SELECT o.*, c.name
+  FROM orders o JOIN customers c ON c.id = o.customer_id
+  WHERE c.email = 'jane@example.com'
+Nested Loop  (cost=0.42..18420.10 rows=5 width=105) (actual time=0.051..1503.100 rows=4 loops=1)
+  Buffers: shared hit=2048 read=6286
+  ->  Seq Scan on customers c  (cost=0.00..18334.00 rows=1 width=12) (actual time=0.015..1502.900 rows=1 loops=1)
+        Filter: (email = 'jane@example.com'::text)
+  ->  Index Scan using orders_customer_id_idx on orders o  (cost=0.42..86.05 rows=5 width=97) (actual time=0.030..0.150 rows=4 loops=1)
+        Index Cond: (customer_id = c.id)`
+
+const autoExplainJSONMessage = `duration: 250.5 ms  plan:
+{
+  "Query Text": "SELECT * FROM events ORDER BY created_at",
+  "Plan": {
+    "Node Type": "Sort",
+    "Total Cost": 250000.0,
+    "Plan Rows": 2000000,
+    "Actual Rows": 2000000,
+    "Actual Loops": 1,
+    "Plans": [
+      {"Node Type": "Seq Scan", "Relation Name": "events", "Total Cost": 35000.0, "Plan Rows": 2000000}
+    ]
+  }
+}`
+
+func TestParseDurationLog_Statement(t *testing.T) {
+	parsed, ok, err := ParseDurationLog("duration: 1234.567 ms  execute S_1: SELECT * FROM users WHERE id = $1")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 1234.567, parsed.DurationMs)
+	assert.Equal(t, "SELECT * FROM users WHERE id = $1", parsed.QueryText)
+	assert.Nil(t, parsed.Plan)
+
+	_, ok, _ = ParseDurationLog("checkpoint starting: time")
+	assert.False(t, ok)
+}
+
+func TestParseDurationLog_TextPlan(t *testing.T) {
+	parsed, ok, err := ParseDurationLog(autoExplainTextMessage)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	assert.Equal(t, "text", parsed.PlanFormat)
+	assert.Contains(t, parsed.QueryText, "WHERE c.email = 'jane@example.com'")
+	assert.NotContains(t, parsed.PlanText, "Query Text")
+
+	root := parsed.Root
+	require.NotNil(t, root)
+	assert.Equal(t, "Nested Loop", root.NodeType)
+	assert.Equal(t, "Inner", root.JoinType)
+	assert.Equal(t, int64(4), root.ActualRows)
+	assert.Equal(t, int64(2048), root.SharedHit)
+	assert.Equal(t, int64(6286), root.SharedRead)
+
+	require.Len(t, root.Plans, 2)
+	assert.Equal(t, "Seq Scan", root.Plans[0].NodeType)
+	assert.Equal(t, "customers", root.Plans[0].RelationName)
+	assert.Equal(t, "(email = 'jane@example.com'::text)", root.Plans[0].Filter)
+	assert.Equal(t, "Index Scan", root.Plans[1].NodeType)
+	assert.Equal(t, "orders_customer_id_idx", root.Plans[1].IndexName)
+	assert.Equal(t, "orders", root.Plans[1].RelationName)
+}
+
+func TestParseTextPlan_JoinsAndNesting(t *testing.T) {
+	root, err := ParseTextPlan(`Hash Left Join  (cost=10.00..50.00 rows=100 width=8)
+  Hash Cond: (a.id = b.a_id)
+  ->  Parallel Seq Scan on a  (cost=0.00..20.00 rows=100 width=4)
+  ->  Hash  (cost=5.00..5.00 rows=10 width=4)
+        ->  Bitmap Heap Scan on b  (cost=1.00..5.00 rows=10 width=4)
+              ->  Bitmap Index Scan on b_a_id_idx  (cost=0.00..1.00 rows=10 width=0)`)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hash Join", root.NodeType)
+	assert.Equal(t, "Left", root.JoinType)
+	assert.Equal(t, "(a.id = b.a_id)", root.HashCond)
+	require.Len(t, root.Plans, 2)
+	assert.Equal(t, "Seq Scan", root.Plans[0].NodeType)
+
+	hash := root.Plans[1]
+	require.Len(t, hash.Plans, 1)
+	heap := hash.Plans[0]
+	assert.Equal(t, "Bitmap Heap Scan", heap.NodeType)
+	require.Len(t, heap.Plans, 1)
+	assert.Equal(t, "b_a_id_idx", heap.Plans[0].IndexName)
+	assert.Empty(t, heap.Plans[0].RelationName)
+
+	_, err = ParseTextPlan("Query Text: SELECT 1")
+	assert.Error(t, err)
+}
+
+func TestExplainPlanFromLog_TextPlan(t *testing.T) {
+	redactor, err := NewRedactor(models.RedactionModeMask, nil)
+	require.NoError(t, err)
+
+	entry := &models.PostgreSQLLog{
+		CollectorID:  uuid.New(),
+		LogTimestamp: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		LogMessage:   autoExplainTextMessage,
+	}
+	plan, err := ExplainPlanFromLog(entry, redactor)
+	require.NoError(t, err)
+	require.NotNil(t, plan)
+
+	assert.Equal(t, models.ExplainPlanSourceAutoExplain, plan.Source)
+	assert.Equal(t, entry.CollectorID, *plan.CollectorID)
+	assert.Equal(t, entry.LogTimestamp, plan.CollectedAt)
+	assert.Equal(t, 1503.221, *plan.ExecutionDurationMs)
+	assert.Equal(t, int64(5), *plan.RowsExpected)
+	assert.Equal(t, int64(4), *plan.RowsActual)
+	assert.Equal(t, int64(6286), *plan.TotalBuffersRead)
+	assert.True(t, plan.HasSeqScan)
+	assert.True(t, plan.HasIndexScan)
+	assert.True(t, plan.HasNestedLoop)
+	assert.False(t, plan.HasBitmapScan)
+
+	// Linked to the fingerprint of the query, with sensitive values redacted
+	require.NotNil(t, plan.QueryFingerprint)
+	assert.Equal(t, NewFingerprinter().Fingerprint("SELECT o.*, c.name FROM orders o JOIN customers c ON c.id = o.customer_id WHERE c.email = 'x'"), *plan.QueryFingerprint)
+	assert.Equal(t, fingerprintQueryHash(*plan.QueryFingerprint), plan.QueryHash)
+	assert.NotContains(t, *plan.QueryText, "jane@example.com")
+	assert.NotContains(t, *plan.PlanText, "jane@example.com")
+	encoded, err := json.Marshal(plan.PlanJSON)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "jane@example.com")
+
+	issues, ok := plan.Issues.([]QueryIssue)
+	require.True(t, ok)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "sequential_scan", issues[0].Type)
+	assert.Equal(t, "customers", issues[0].AffectedNode)
+}
+
+func TestExplainPlanFromLog_JSONPlan(t *testing.T) {
+	redactor, err := NewRedactor(models.RedactionModeNone, nil)
+	require.NoError(t, err)
+
+	queryID := int64(-4217815473958104613)
+	entry := &models.PostgreSQLLog{
+		CollectorID:  uuid.New(),
+		LogTimestamp: time.Now(),
+		LogMessage:   autoExplainJSONMessage,
+		QueryHash:    &queryID,
+	}
+	plan, err := ExplainPlanFromLog(entry, redactor)
+	require.NoError(t, err)
+	require.NotNil(t, plan)
+
+	// The logged query_id links the plan to pg_stat_statements
+	assert.Equal(t, queryID, plan.QueryHash)
+	assert.Equal(t, "SELECT * FROM events ORDER BY created_at", *plan.QueryText)
+	assert.Nil(t, plan.PlanText)
+	assert.Nil(t, plan.TotalBuffersRead)
+
+	encoded, err := json.Marshal(plan.PlanJSON)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "Query Text")
+
+	issues := plan.Issues.([]QueryIssue)
+	types := make([]string, len(issues))
+	for i, issue := range issues {
+		types[i] = issue.Type
+	}
+	assert.ElementsMatch(t, []string{"large_sort", "sequential_scan"}, types)
+}
+
+func TestRedactor_RedactPlanJSON(t *testing.T) {
+	redactor, err := NewRedactor(models.RedactionModeNormalize, nil)
+	require.NoError(t, err)
+
+	// String values are redacted and the plan stays valid JSON
+	redacted := redactor.RedactPlanJSON(`{"Plan": {"Node Type": "Seq Scan", "Filter": "(email = 'carol@example.com'::text)", "Plan Rows": 12}}`)
+	assert.NotContains(t, redacted, "carol@example.com")
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(redacted), &decoded))
+	assert.Equal(t, float64(12), decoded["Plan"].(map[string]interface{})["Plan Rows"])
+
+	// Plans without sensitive strings and invalid JSON are left as stored
+	clean := `{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 12}}`
+	assert.Equal(t, clean, redactor.RedactPlanJSON(clean))
+	assert.Equal(t, `{"Plan":`, redactor.RedactPlanJSON(`{"Plan":`))
+}
+
+func TestRedactor_RedactExplainPlan(t *testing.T) {
+	redactor, err := NewRedactor(models.RedactionModeNormalize, nil)
+	require.NoError(t, err)
+
+	var planJSON interface{}
+	require.NoError(t, json.Unmarshal([]byte(`[{"Plan": {"Node Type": "Seq Scan", "Filter": "(email = 'dave@example.com'::text)"}}]`), &planJSON))
+	queryText := "SELECT * FROM users WHERE email = 'dave@example.com'"
+	planText := "Seq Scan on users  (cost=0.00..1.05 rows=1 width=64)\n  Filter: (email = 'dave@example.com'::text)"
+	plan := &models.ExplainPlan{PlanJSON: planJSON, QueryText: &queryText, PlanText: &planText}
+
+	redactor.RedactExplainPlan(plan)
+
+	encoded, err := json.Marshal(plan.PlanJSON)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "dave@example.com")
+	assert.Contains(t, string(encoded), "Seq Scan")
+	assert.NotContains(t, *plan.QueryText, "dave@example.com")
+	assert.NotContains(t, *plan.PlanText, "dave@example.com")
+}
+
+func TestExplainPlanFromLog_NoPlan(t *testing.T) {
+	redactor, _ := NewRedactor(models.RedactionModeNone, nil)
+
+	plan, err := ExplainPlanFromLog(&models.PostgreSQLLog{LogMessage: "duration: 12.5 ms  statement: SELECT 1"}, redactor)
+	assert.NoError(t, err)
+	assert.Nil(t, plan)
+
+	plan, err = ExplainPlanFromLog(&models.PostgreSQLLog{LogMessage: "duration: 12.5 ms  plan:\n{\"Query Text\": \"SELECT 1\""}, redactor)
+	assert.Error(t, err)
+	assert.Nil(t, plan)
+}
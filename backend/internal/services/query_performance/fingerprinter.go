@@ -3,7 +3,7 @@ package query_performance
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"regexp"
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -30,92 +30,308 @@ func NewFingerprinterWithLogger(logger *zap.Logger) *Fingerprinter {
 	}
 }
 
-// Patterns for normalizing SQL queries
-var (
-	// Match quoted strings (single and double quotes)
-	stringPattern = regexp.MustCompile(`'(?:[^']|'')*'|"(?:[^"]|"")*"`)
-
-	// Match numeric literals (integers and decimals)
-	numberPattern = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
-
-	// Match IN lists: IN (1, 2, 3) -> IN (?)
-	inListPattern = regexp.MustCompile(`\bIN\s*\(\s*[^)]+\)`)
-
-	// Match VALUES lists for INSERT statements
-	valuesPattern = regexp.MustCompile(`VALUES\s*\([^)]+\)`)
-
-	// Match multiple spaces
-	multiSpacePattern = regexp.MustCompile(`\s+`)
-)
-
 // Fingerprint generates a 32-character hex fingerprint hash for a query
 // Queries with the same structure but different parameter values will have the same fingerprint
 func (f *Fingerprinter) Fingerprint(queryText string) string {
-	// Normalize the query to remove specific parameter values
-	normalized := f.normalizeForFingerprint(queryText)
+	tokens, err := tokenizeSQL(queryText)
+	if err != nil {
+		// Truncated query text still fingerprints on the tokens lexed so far
+		f.logger.Debug("Fingerprinting partially lexed query", zap.Error(err))
+	}
 
-	// Hash the normalized query
-	return hashString(normalized)
+	return hashString(canonicalText(normalizeTokens(tokens)))
 }
 
-// Normalize returns a parameterized version of the query
+// Normalize returns a parameterized version of the query in the style of
+// pg_stat_statements: comments are removed, constants become $N placeholders
+// numbered after any parameters already present, and collapsed lists are
+// marked with a "/*, ... */" comment.
 // e.g., "SELECT * FROM users WHERE id = 1" -> "SELECT * FROM users WHERE id = $1"
 func (f *Fingerprinter) Normalize(queryText string) (string, error) {
-	// Normalize the query by replacing literals with placeholders
-	normalized := f.normalizeWithPlaceholders(queryText)
-	return normalized, nil
+	tokens, err := tokenizeSQL(queryText)
+	if err != nil {
+		return "", fmt.Errorf("normalize query: %w", err)
+	}
+
+	return parameterizedText(normalizeTokens(tokens)), nil
+}
+
+// unaryContextWords are keywords after which a leading sign belongs to a constant
+var unaryContextWords = map[string]bool{
+	"select": true, "where": true, "and": true, "or": true, "not": true,
+	"when": true, "then": true, "else": true, "by": true, "in": true,
+	"limit": true, "offset": true, "between": true, "values": true,
+	"return": true, "set": true, "like": true, "having": true,
 }
 
-// normalizeForFingerprint removes literal values from a query for fingerprinting
-func (f *Fingerprinter) normalizeForFingerprint(queryText string) string {
-	// Convert to uppercase for consistent comparison
-	normalized := strings.ToUpper(queryText)
+// normalizeTokens replaces constants and collapses constant lists and
+// repeated VALUES rows so that structurally identical statements produce
+// identical token streams.
+func normalizeTokens(tokens []sqlToken) []sqlToken {
+	// A trailing semicolon does not change the statement
+	for len(tokens) > 0 && tokens[len(tokens)-1].kind == tokenPunct && tokens[len(tokens)-1].text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	out := make([]sqlToken, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
 
-	// Remove string literals
-	normalized = stringPattern.ReplaceAllString(normalized, "?")
+		switch tok.kind {
+		case tokenString, tokenNumber:
+			tok.kind = tokenConst
 
-	// Remove numeric literals
-	normalized = numberPattern.ReplaceAllString(normalized, "?")
+		case tokenWord:
+			lower := strings.ToLower(tok.text)
+			if (lower == "true" || lower == "false") && !followsIs(out) {
+				tok.kind = tokenConst
+			}
 
-	// Normalize IN lists
-	normalized = inListPattern.ReplaceAllStringFunc(normalized, func(match string) string {
-		// Keep the IN keyword but replace the list with (?)
-		return "IN (?)"
-	})
+		case tokenOperator:
+			// Fold a unary sign into the numeric constant it prefixes
+			if (tok.text == "-" || tok.text == "+") && i+1 < len(tokens) &&
+				tokens[i+1].kind == tokenNumber && !tokens[i+1].spaceBefore && isUnaryContext(out) {
+				tok = sqlToken{kind: tokenConst, text: tok.text + tokens[i+1].text, spaceBefore: tok.spaceBefore}
+				i++
+			}
+		}
 
-	// Normalize VALUES lists
-	normalized = valuesPattern.ReplaceAllString(normalized, "VALUES (?)")
+		out = append(out, tok)
+	}
 
-	// Normalize whitespace
-	normalized = multiSpacePattern.ReplaceAllString(normalized, " ")
+	return collapseValuesRows(collapseConstantLists(out))
+}
 
-	// Trim
-	normalized = strings.TrimSpace(normalized)
+// followsIs reports whether the next token is the operand of IS or IS NOT
+func followsIs(prev []sqlToken) bool {
+	n := len(prev)
+	if n > 0 && strings.EqualFold(prev[n-1].text, "is") {
+		return true
+	}
+	return n > 1 && strings.EqualFold(prev[n-1].text, "not") && strings.EqualFold(prev[n-2].text, "is")
+}
 
-	return normalized
+// isUnaryContext reports whether a sign at this position is a unary operator
+func isUnaryContext(prev []sqlToken) bool {
+	if len(prev) == 0 {
+		return true
+	}
+	last := prev[len(prev)-1]
+	switch last.kind {
+	case tokenOperator:
+		return true
+	case tokenPunct:
+		return last.text == "(" || last.text == "," || last.text == "["
+	case tokenWord:
+		return unaryContextWords[strings.ToLower(last.text)]
+	}
+	return false
 }
 
-// normalizeWithPlaceholders replaces literal values with $1, $2, etc.
-func (f *Fingerprinter) normalizeWithPlaceholders(queryText string) string {
-	placeholderNum := 0
-	normalized := queryText
+// collapseConstantLists reduces IN (...) and ARRAY[...] lists made up only
+// of constants and parameters to their first element, so that lists of
+// different lengths share a fingerprint.
+func collapseConstantLists(tokens []sqlToken) []sqlToken {
+	out := make([]sqlToken, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		out = append(out, tok)
 
-	// Replace string literals with $N
-	normalized = stringPattern.ReplaceAllStringFunc(normalized, func(match string) string {
-		placeholderNum++
-		return "$" + strconv.Itoa(placeholderNum)
-	})
+		if tok.kind != tokenWord || i+1 >= len(tokens) {
+			continue
+		}
+		word := strings.ToLower(tok.text)
+		next := tokens[i+1]
+		if !(word == "in" && next.text == "(") && !(word == "array" && next.text == "[") {
+			continue
+		}
 
-	// Replace numeric literals with $N
-	normalized = numberPattern.ReplaceAllStringFunc(normalized, func(match string) string {
-		placeholderNum++
-		return "$" + strconv.Itoa(placeholderNum)
-	})
+		closeIdx := matchingClose(tokens, i+1)
+		if closeIdx < 0 {
+			continue
+		}
+		elements, ok := constantListElements(tokens[i+2 : closeIdx])
+		if !ok {
+			continue
+		}
 
-	// Normalize whitespace
-	normalized = multiSpacePattern.ReplaceAllString(normalized, " ")
+		out = append(out, next)
+		first := append([]sqlToken(nil), elements[0]...)
+		if len(elements) > 1 {
+			first[len(first)-1].elided = true
+		}
+		out = append(out, first...)
+		out = append(out, tokens[closeIdx])
+		i = closeIdx
+	}
+	return out
+}
+
+// constantListElements splits a comma-separated list and returns its
+// elements when each one is a constant or parameter with an optional cast
+func constantListElements(tokens []sqlToken) ([][]sqlToken, bool) {
+	if len(tokens) == 0 {
+		return nil, false
+	}
+
+	var elements [][]sqlToken
+	start := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && !(tokens[i].kind == tokenPunct && tokens[i].text == ",") {
+			continue
+		}
+		element := tokens[start:i]
+		if !isConstantElement(element) {
+			return nil, false
+		}
+		elements = append(elements, element)
+		start = i + 1
+	}
+	return elements, true
+}
 
-	return strings.TrimSpace(normalized)
+// isConstantElement matches "const", "$1", "const::type" and "const::type[]"
+func isConstantElement(tokens []sqlToken) bool {
+	if len(tokens) == 0 || (tokens[0].kind != tokenConst && tokens[0].kind != tokenParam) {
+		return false
+	}
+	rest := tokens[1:]
+	if len(rest) == 0 {
+		return true
+	}
+	if len(rest) < 2 || rest[0].text != "::" || (rest[1].kind != tokenWord && rest[1].kind != tokenQuotedIdent) {
+		return false
+	}
+	rest = rest[2:]
+	return len(rest) == 0 || (len(rest) == 2 && rest[0].text == "[" && rest[1].text == "]")
+}
+
+// collapseValuesRows drops VALUES rows that normalize to the same text as
+// the first row, so multi-row inserts group with single-row inserts.
+func collapseValuesRows(tokens []sqlToken) []sqlToken {
+	out := make([]sqlToken, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		out = append(out, tok)
+
+		if tok.kind != tokenWord || !strings.EqualFold(tok.text, "values") ||
+			i+1 >= len(tokens) || tokens[i+1].text != "(" {
+			continue
+		}
+
+		firstClose := matchingClose(tokens, i+1)
+		if firstClose < 0 {
+			continue
+		}
+		out = append(out, tokens[i+1:firstClose+1]...)
+		firstRow := canonicalText(tokens[i+1 : firstClose+1])
+
+		next := firstClose + 1
+		collapsed := false
+		for next+1 < len(tokens) && tokens[next].text == "," && tokens[next+1].text == "(" {
+			rowClose := matchingClose(tokens, next+1)
+			if rowClose < 0 || canonicalText(tokens[next+1:rowClose+1]) != firstRow {
+				break
+			}
+			collapsed = true
+			next = rowClose + 1
+		}
+		if collapsed {
+			out[len(out)-1].elided = true
+		}
+		i = next - 1
+	}
+	return out
+}
+
+// matchingClose returns the index of the bracket closing the one at open, or -1
+func matchingClose(tokens []sqlToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		if tokens[i].kind != tokenPunct {
+			continue
+		}
+		switch tokens[i].text {
+		case "(", "[":
+			depth++
+		case ")", "]":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// canonicalText renders normalized tokens as the fingerprint input:
+// unquoted identifiers and keywords are case-folded like PostgreSQL does,
+// constants and parameters become "?", and tokens are separated by single
+// spaces regardless of the original layout.
+func canonicalText(tokens []sqlToken) string {
+	parts := make([]string, len(tokens))
+	for i, tok := range tokens {
+		switch tok.kind {
+		case tokenWord:
+			parts[i] = strings.ToLower(tok.text)
+		case tokenQuotedIdent:
+			parts[i] = foldQuotedIdent(tok.text)
+		case tokenConst, tokenParam:
+			parts[i] = "?"
+		default:
+			parts[i] = tok.text
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// foldQuotedIdent strips quotes from identifiers that PostgreSQL would
+// resolve identically without them, so "users" and users match
+func foldQuotedIdent(text string) string {
+	if len(text) < 3 || text[0] != '"' {
+		return text
+	}
+	inner := text[1 : len(text)-1]
+	if !isIdentStart(inner[0]) {
+		return text
+	}
+	for i := 0; i < len(inner); i++ {
+		c := inner[i]
+		if (c >= 'A' && c <= 'Z') || c >= 0x80 || !isIdentChar(c) {
+			return text
+		}
+	}
+	return inner
+}
+
+// parameterizedText renders normalized tokens with $N placeholders,
+// keeping the original spelling of everything else
+func parameterizedText(tokens []sqlToken) string {
+	next := 1
+	for _, tok := range tokens {
+		if tok.kind == tokenParam {
+			if n, err := strconv.Atoi(tok.text[1:]); err == nil && n >= next {
+				next = n + 1
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i, tok := range tokens {
+		if i > 0 && tok.spaceBefore {
+			b.WriteByte(' ')
+		}
+		if tok.kind == tokenConst {
+			b.WriteString("$" + strconv.Itoa(next))
+			next++
+		} else {
+			b.WriteString(tok.text)
+		}
+		if tok.elided {
+			b.WriteString(" /*, ... */")
+		}
+	}
+	return b.String()
 }
 
 // hashString creates a 32-character hex hash from a string
@@ -121,3 +121,120 @@ func TestFingerprint_Consistency(t *testing.T) {
 
 	assert.Equal(t, fingerprint1, fingerprint2, "Same query should always produce same fingerprint")
 }
+
+func TestFingerprint_CaseAndWhitespaceInsensitive(t *testing.T) {
+	fp := NewFingerprinter()
+
+	assert.Equal(t,
+		fp.Fingerprint("SELECT * FROM Users WHERE id=1;"),
+		fp.Fingerprint("select *\n  from users\n where id = 42"),
+	)
+}
+
+func TestFingerprint_QuotedIdentifiers(t *testing.T) {
+	fp := NewFingerprinter()
+
+	// "users" resolves to the same relation as users, "Users" does not
+	assert.Equal(t, fp.Fingerprint(`SELECT * FROM users`), fp.Fingerprint(`SELECT * FROM "users"`))
+	assert.NotEqual(t, fp.Fingerprint(`SELECT * FROM users`), fp.Fingerprint(`SELECT * FROM "Users"`))
+	// Digits inside quoted identifiers are not constants
+	assert.NotEqual(t, fp.Fingerprint(`SELECT "col1" FROM t`), fp.Fingerprint(`SELECT "col2" FROM t`))
+}
+
+func TestFingerprint_Comments(t *testing.T) {
+	fp := NewFingerprinter()
+
+	base := fp.Fingerprint("SELECT id FROM users WHERE id = 1")
+	assert.Equal(t, base, fp.Fingerprint("SELECT id FROM users -- lookup by id\nWHERE id = 2"))
+	assert.Equal(t, base, fp.Fingerprint("/* app:web /* nested */ */ SELECT id FROM users WHERE id = 3"))
+}
+
+func TestFingerprint_DollarQuotedAndEscapedStrings(t *testing.T) {
+	fp := NewFingerprinter()
+
+	base := fp.Fingerprint("SELECT * FROM notes WHERE body = 'x'")
+	assert.Equal(t, base, fp.Fingerprint("SELECT * FROM notes WHERE body = $$it's (not) a list$$"))
+	assert.Equal(t, base, fp.Fingerprint("SELECT * FROM notes WHERE body = $tag$ FROM orders $tag$"))
+	assert.Equal(t, base, fp.Fingerprint(`SELECT * FROM notes WHERE body = E'it\'s'`))
+}
+
+func TestFingerprint_AnyArraysAndCasts(t *testing.T) {
+	fp := NewFingerprinter()
+
+	assert.Equal(t,
+		fp.Fingerprint("SELECT * FROM users WHERE id = ANY($1::int[])"),
+		fp.Fingerprint("SELECT * FROM users WHERE id = ANY($7::int[])"),
+	)
+	assert.Equal(t,
+		fp.Fingerprint("SELECT * FROM users WHERE id = ANY(ARRAY[1, 2, 3])"),
+		fp.Fingerprint("SELECT * FROM users WHERE id = ANY(ARRAY[4])"),
+	)
+	// Casts change the statement, the cast value does not
+	assert.Equal(t,
+		fp.Fingerprint("SELECT '2024-01-01'::date"),
+		fp.Fingerprint("SELECT '2025-06-30'::date"),
+	)
+	assert.NotEqual(t,
+		fp.Fingerprint("SELECT '2024-01-01'::date"),
+		fp.Fingerprint("SELECT '2024-01-01'::timestamptz"),
+	)
+}
+
+func TestFingerprint_InListsOfDifferentLength(t *testing.T) {
+	fp := NewFingerprinter()
+
+	assert.Equal(t,
+		fp.Fingerprint("SELECT * FROM users WHERE id IN (1)"),
+		fp.Fingerprint("SELECT * FROM users WHERE id IN (1, 2, 3, 4, 5)"),
+	)
+	// Subqueries are not constant lists
+	assert.NotEqual(t,
+		fp.Fingerprint("SELECT * FROM users WHERE id IN (1, 2)"),
+		fp.Fingerprint("SELECT * FROM users WHERE id IN (SELECT user_id FROM orders)"),
+	)
+}
+
+func TestFingerprint_MultiRowValues(t *testing.T) {
+	fp := NewFingerprinter()
+
+	assert.Equal(t,
+		fp.Fingerprint("INSERT INTO users (name, age) VALUES ('a', 1)"),
+		fp.Fingerprint("INSERT INTO users (name, age) VALUES ('a', 1), ('b', 2), ('c', 3)"),
+	)
+	assert.NotEqual(t,
+		fp.Fingerprint("INSERT INTO users (name) VALUES ('a')"),
+		fp.Fingerprint("INSERT INTO users (name, age) VALUES ('a', 1)"),
+	)
+}
+
+func TestFingerprint_NormalizeMatchesPgStatStatements(t *testing.T) {
+	fp := NewFingerprinter()
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT * FROM users WHERE id = 1", "SELECT * FROM users WHERE id = $1"},
+		{"SELECT * FROM t WHERE a = $1 AND b = 'x' -- note", "SELECT * FROM t WHERE a = $1 AND b = $2"},
+		{"SELECT * FROM t WHERE id IN (1, 2, 3)", "SELECT * FROM t WHERE id IN ($1 /*, ... */)"},
+		{"INSERT INTO t (a) VALUES (1), (2)", "INSERT INTO t (a) VALUES ($1) /*, ... */"},
+		{"SELECT x FROM t WHERE x > -5 AND flag = true AND y IS TRUE", "SELECT x FROM t WHERE x > $1 AND flag = $2 AND y IS TRUE"},
+		{`SELECT "col1", 'v'::text FROM t`, `SELECT "col1", $1::text FROM t`},
+	}
+
+	for _, tt := range tests {
+		normalized, err := fp.Normalize(tt.query)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, normalized, tt.query)
+	}
+}
+
+func TestFingerprint_TruncatedQuery(t *testing.T) {
+	fp := NewFingerprinter()
+
+	_, err := fp.Normalize("SELECT * FROM users WHERE name = 'trunc")
+	assert.Error(t, err)
+
+	// Fingerprinting still succeeds on truncated text
+	assert.Len(t, fp.Fingerprint("SELECT * FROM users WHERE name = 'trunc"), 32)
+}
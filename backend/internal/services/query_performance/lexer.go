@@ -0,0 +1,317 @@
+package query_performance
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tokenKind classifies a lexical token of a PostgreSQL statement
+type tokenKind int
+
+const (
+	tokenWord        tokenKind = iota // keyword or unquoted identifier
+	tokenQuotedIdent                  // "Quoted" identifier
+	tokenString                       // string constant, including E'', B'', X'', U&'' and $tag$ forms
+	tokenNumber                       // numeric constant
+	tokenParam                        // positional parameter ($1)
+	tokenOperator                     // operator such as =, <>, ||, ::
+	tokenPunct                        // ( ) [ ] , ; .
+	tokenConst                        // constant after normalization
+)
+
+// sqlToken is a single lexical token
+type sqlToken struct {
+	kind tokenKind
+	text string
+	// spaceBefore records whether whitespace or a comment preceded the token
+	spaceBefore bool
+	// elided marks a token after which a list of constants or VALUES rows was collapsed
+	elided bool
+}
+
+// operatorChars are the characters PostgreSQL allows in operator names
+const operatorChars = "+-*/<>=~!@#%^&|`?"
+
+// tokenizeSQL splits a query into tokens following PostgreSQL lexical rules.
+// Comments are dropped. When the input ends inside a quoted string or comment
+// (as happens with query text truncated by track_activity_query_size) the
+// tokens lexed so far are returned together with an error.
+func tokenizeSQL(query string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	space := false
+	i := 0
+	n := len(query)
+
+	emit := func(kind tokenKind, text string) {
+		tokens = append(tokens, sqlToken{kind: kind, text: text, spaceBefore: space})
+		space = false
+	}
+
+	for i < n {
+		c := query[i]
+
+		switch {
+		case isSQLSpace(c):
+			space = true
+			i++
+
+		case c == '-' && i+1 < n && query[i+1] == '-':
+			for i < n && query[i] != '\n' {
+				i++
+			}
+			space = true
+
+		case c == '/' && i+1 < n && query[i+1] == '*':
+			// Block comments nest in PostgreSQL
+			depth := 0
+			for i < n {
+				if i+1 < n && query[i] == '/' && query[i+1] == '*' {
+					depth++
+					i += 2
+				} else if i+1 < n && query[i] == '*' && query[i+1] == '/' {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					i++
+				}
+			}
+			if depth > 0 {
+				return tokens, fmt.Errorf("unterminated comment")
+			}
+			space = true
+
+		case c == '\'':
+			end, err := scanQuoted(query, i, '\'', false)
+			emit(tokenString, query[i:end])
+			if err != nil {
+				return tokens, err
+			}
+			i = end
+
+		case (c == 'e' || c == 'E') && i+1 < n && query[i+1] == '\'':
+			end, err := scanQuoted(query, i+1, '\'', true)
+			emit(tokenString, query[i:end])
+			if err != nil {
+				return tokens, err
+			}
+			i = end
+
+		case (c == 'b' || c == 'B' || c == 'x' || c == 'X' || c == 'n' || c == 'N') && i+1 < n && query[i+1] == '\'':
+			end, err := scanQuoted(query, i+1, '\'', false)
+			emit(tokenString, query[i:end])
+			if err != nil {
+				return tokens, err
+			}
+			i = end
+
+		case (c == 'u' || c == 'U') && i+2 < n && query[i+1] == '&' && (query[i+2] == '\'' || query[i+2] == '"'):
+			quote := query[i+2]
+			end, err := scanQuoted(query, i+2, quote, false)
+			if quote == '\'' {
+				emit(tokenString, query[i:end])
+			} else {
+				emit(tokenQuotedIdent, query[i:end])
+			}
+			if err != nil {
+				return tokens, err
+			}
+			i = end
+
+		case c == '"':
+			end, err := scanQuoted(query, i, '"', false)
+			emit(tokenQuotedIdent, query[i:end])
+			if err != nil {
+				return tokens, err
+			}
+			i = end
+
+		case c == '$' && i+1 < n && isDigit(query[i+1]):
+			j := i + 1
+			for j < n && isDigit(query[j]) {
+				j++
+			}
+			emit(tokenParam, query[i:j])
+			i = j
+
+		case c == '$':
+			tag, ok := scanDollarTag(query, i)
+			if !ok {
+				emit(tokenOperator, "$")
+				i++
+				continue
+			}
+			body := strings.Index(query[i+len(tag):], tag)
+			if body < 0 {
+				emit(tokenString, query[i:])
+				return tokens, fmt.Errorf("unterminated dollar-quoted string")
+			}
+			end := i + len(tag) + body + len(tag)
+			emit(tokenString, query[i:end])
+			i = end
+
+		case isDigit(c) || (c == '.' && i+1 < n && isDigit(query[i+1])):
+			end := scanNumber(query, i)
+			emit(tokenNumber, query[i:end])
+			i = end
+
+		case isIdentStart(c):
+			j := i + 1
+			for j < n && isIdentChar(query[j]) {
+				j++
+			}
+			emit(tokenWord, query[i:j])
+			i = j
+
+		case c == ':' && i+1 < n && query[i+1] == ':':
+			emit(tokenOperator, "::")
+			i += 2
+
+		case c == '(' || c == ')' || c == '[' || c == ']' || c == ',' || c == ';' || c == '.' || c == ':':
+			emit(tokenPunct, string(c))
+			i++
+
+		case strings.IndexByte(operatorChars, c) >= 0:
+			end := scanOperator(query, i)
+			emit(tokenOperator, query[i:end])
+			i = end
+
+		default:
+			// Unknown byte (including multi-byte UTF-8 runes outside identifiers)
+			emit(tokenOperator, string(c))
+			i++
+		}
+	}
+
+	return tokens, nil
+}
+
+// scanQuoted returns the index just past a quoted token starting at start.
+// A doubled quote character is an escaped quote; backslash escapes are
+// honoured only for E-prefixed strings.
+func scanQuoted(query string, start int, quote byte, backslash bool) (int, error) {
+	i := start + 1
+	for i < len(query) {
+		switch {
+		case backslash && query[i] == '\\' && i+1 < len(query):
+			i += 2
+		case query[i] == quote && i+1 < len(query) && query[i+1] == quote:
+			i += 2
+		case query[i] == quote:
+			return i + 1, nil
+		default:
+			i++
+		}
+	}
+	return len(query), fmt.Errorf("unterminated quoted string")
+}
+
+// scanDollarTag returns the opening tag of a dollar-quoted string ($$ or $tag$)
+func scanDollarTag(query string, start int) (string, bool) {
+	j := start + 1
+	if j < len(query) && query[j] == '$' {
+		return "$$", true
+	}
+	if j >= len(query) || !isIdentStart(query[j]) {
+		return "", false
+	}
+	for j < len(query) && query[j] != '$' {
+		if !isIdentChar(query[j]) {
+			return "", false
+		}
+		j++
+	}
+	if j >= len(query) {
+		return "", false
+	}
+	return query[start : j+1], true
+}
+
+// scanNumber returns the index just past a numeric constant
+func scanNumber(query string, start int) int {
+	n := len(query)
+	i := start
+
+	// Hexadecimal, octal and binary integers (PostgreSQL 16+)
+	if query[i] == '0' && i+2 < n {
+		switch query[i+1] {
+		case 'x', 'X', 'o', 'O', 'b', 'B':
+			j := i + 2
+			for j < n && (isHexDigit(query[j]) || query[j] == '_') {
+				j++
+			}
+			if j > i+2 {
+				return j
+			}
+		}
+	}
+
+	for i < n && (isDigit(query[i]) || query[i] == '_') {
+		i++
+	}
+	// A ".." after digits is a range, not a decimal point
+	if i < n && query[i] == '.' && !(i+1 < n && query[i+1] == '.') {
+		i++
+		for i < n && (isDigit(query[i]) || query[i] == '_') {
+			i++
+		}
+	}
+	if i < n && (query[i] == 'e' || query[i] == 'E') {
+		j := i + 1
+		if j < n && (query[j] == '+' || query[j] == '-') {
+			j++
+		}
+		if j < n && isDigit(query[j]) {
+			for j < n && isDigit(query[j]) {
+				j++
+			}
+			i = j
+		}
+	}
+	return i
+}
+
+// scanOperator returns the index just past an operator starting at start.
+// Following the PostgreSQL lexer, an operator stops before an embedded
+// comment start, and a trailing + or - is split off unless the operator
+// contains one of ~ ! @ # % ^ & | ` ? (so "=-1" lexes as "=" and "-1").
+func scanOperator(query string, start int) int {
+	i := start
+	for i < len(query) && strings.IndexByte(operatorChars, query[i]) >= 0 {
+		if i > start && i+1 < len(query) &&
+			((query[i] == '-' && query[i+1] == '-') || (query[i] == '/' && query[i+1] == '*')) {
+			break
+		}
+		i++
+	}
+
+	op := query[start:i]
+	if len(op) > 1 && !strings.ContainsAny(op, "~!@#%^&|`?") {
+		for len(op) > 1 && (op[len(op)-1] == '+' || op[len(op)-1] == '-') {
+			op = op[:len(op)-1]
+		}
+	}
+	return start + len(op)
+}
+
+func isSQLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isIdentStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '$'
+}
@@ -0,0 +1,85 @@
+package query_performance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tokenTexts(tokens []sqlToken) []string {
+	texts := make([]string, len(tokens))
+	for i, tok := range tokens {
+		texts[i] = tok.text
+	}
+	return texts
+}
+
+func TestTokenizeSQL_Basic(t *testing.T) {
+	tokens, err := tokenizeSQL("SELECT a.b, count(*) FROM t WHERE x>=10 AND y::text <> 'z'")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"SELECT", "a", ".", "b", ",", "count", "(", "*", ")", "FROM", "t",
+		"WHERE", "x", ">=", "10", "AND", "y", "::", "text", "<>", "'z'",
+	}, tokenTexts(tokens))
+}
+
+func TestTokenizeSQL_StringForms(t *testing.T) {
+	tokens, err := tokenizeSQL(`'it''s' E'a\'b' B'101' X'1F' U&'\0041' $$x$$ $fn$ 'y' $fn$`)
+	require.NoError(t, err)
+
+	require.Len(t, tokens, 7)
+	for _, tok := range tokens {
+		assert.Equal(t, tokenString, tok.kind, tok.text)
+	}
+	assert.Equal(t, "$fn$ 'y' $fn$", tokens[6].text)
+}
+
+func TestTokenizeSQL_ParamsAndIdentifiers(t *testing.T) {
+	tokens, err := tokenizeSQL(`SELECT $1, "Col ""1""", a$b FROM t`)
+	require.NoError(t, err)
+
+	assert.Equal(t, tokenParam, tokens[1].kind)
+	assert.Equal(t, tokenQuotedIdent, tokens[3].kind)
+	assert.Equal(t, `"Col ""1"""`, tokens[3].text)
+	assert.Equal(t, tokenWord, tokens[5].kind)
+	assert.Equal(t, "a$b", tokens[5].text)
+}
+
+func TestTokenizeSQL_Numbers(t *testing.T) {
+	tokens, err := tokenizeSQL("1 1.5 .5 1e10 2.5E-3 0x1F 1_000")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"1", "1.5", ".5", "1e10", "2.5E-3", "0x1F", "1_000"}, tokenTexts(tokens))
+	for _, tok := range tokens {
+		assert.Equal(t, tokenNumber, tok.kind)
+	}
+}
+
+func TestTokenizeSQL_Comments(t *testing.T) {
+	tokens, err := tokenizeSQL("SELECT 1 -- trailing\n/* a /* b */ c */ + 2")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"SELECT", "1", "+", "2"}, tokenTexts(tokens))
+	assert.True(t, tokens[2].spaceBefore)
+}
+
+func TestTokenizeSQL_OperatorSignSplitting(t *testing.T) {
+	tokens, err := tokenizeSQL("a=-1 b@-1")
+	require.NoError(t, err)
+
+	// "=-" is split so the minus can prefix the constant; "@-" is kept whole
+	assert.Equal(t, []string{"a", "=", "-", "1", "b", "@-", "1"}, tokenTexts(tokens))
+}
+
+func TestTokenizeSQL_Unterminated(t *testing.T) {
+	_, err := tokenizeSQL("SELECT 'abc")
+	assert.Error(t, err)
+
+	_, err = tokenizeSQL("SELECT 1 /* open")
+	assert.Error(t, err)
+
+	_, err = tokenizeSQL("SELECT $q$ body")
+	assert.Error(t, err)
+}
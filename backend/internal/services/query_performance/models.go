@@ -2,12 +2,12 @@ package query_performance
 
 // QueryIssue represents a detected issue in a query plan
 type QueryIssue struct {
-	Type             string
-	Severity         string
-	AffectedNode     string
-	Description      string
-	Recommendation   string
-	EstimatedBenefit float64
+	Type             string  `json:"type"`
+	Severity         string  `json:"severity"`
+	AffectedNode     string  `json:"affected_node"`
+	Description      string  `json:"description"`
+	Recommendation   string  `json:"recommendation"`
+	EstimatedBenefit float64 `json:"estimated_benefit"`
 }
 
 // FullExplainPlan represents EXPLAIN (FORMAT JSON) output
@@ -31,5 +31,7 @@ type PlanNode struct {
 	Filter       string      `json:"Filter"`
 	HashCond     string      `json:"Hash Cond"`
 	JoinType     string      `json:"Join Type"`
+	SharedHit    int64       `json:"Shared Hit Blocks"`
+	SharedRead   int64       `json:"Shared Read Blocks"`
 	Plans        []*PlanNode `json:"Plans"`
 }
@@ -0,0 +1,251 @@
+package query_performance
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// RedactionStore loads the settings a Redactor is built from
+type RedactionStore interface {
+	GetTenantIDByCollectorID(ctx context.Context, collectorID string) (*uuid.UUID, error)
+	GetQueryRedactionPolicy(ctx context.Context, tenantID uuid.UUID) (*models.QueryRedactionPolicy, error)
+	GetCustomPatterns(ctx context.Context, tenantID *uuid.UUID) ([]*models.CustomPattern, error)
+}
+
+// redactionPattern is a compiled sensitive-data pattern
+type redactionPattern struct {
+	name  string
+	regex *regexp.Regexp
+	luhn  bool // matches must also pass the Luhn checksum
+}
+
+// builtinRedactionPatterns are always applied in mask mode, in addition to
+// the tenant's classification patterns
+var builtinRedactionPatterns = []redactionPattern{
+	{name: "EMAIL", regex: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)},
+	{name: "CREDIT_CARD", regex: regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`), luhn: true},
+}
+
+// logStatementPattern finds the SQL embedded in PostgreSQL log messages,
+// e.g. "duration: 1.2 ms  statement: SELECT ..." or "execute S_1: SELECT ..."
+var logStatementPattern = regexp.MustCompile(`(?i)\b(?:statement|(?:execute|parse|bind) [^:\s]*):\s`)
+
+// Redactor removes sensitive literal values from query text before storage
+type Redactor struct {
+	mode          string
+	fingerprinter *Fingerprinter
+	patterns      []redactionPattern
+}
+
+// NewRedactor creates a Redactor for a redaction mode
+// Custom patterns are used in mask mode alongside the built-in EMAIL and
+// CREDIT_CARD patterns; a Luhn validation algorithm is honoured, others
+// match on the regex alone.
+func NewRedactor(mode string, customPatterns []*models.CustomPattern) (*Redactor, error) {
+	switch mode {
+	case models.RedactionModeNone, models.RedactionModeNormalize, models.RedactionModeMask:
+	default:
+		return nil, fmt.Errorf("invalid redaction mode %q", mode)
+	}
+
+	patterns := append([]redactionPattern(nil), builtinRedactionPatterns...)
+	for _, p := range customPatterns {
+		if p == nil || !p.Enabled {
+			continue
+		}
+		re, err := regexp.Compile(p.PatternRegex)
+		if err != nil {
+			return nil, fmt.Errorf("compile pattern %q: %w", p.PatternName, err)
+		}
+		patterns = append(patterns, redactionPattern{
+			name:  p.PatternName,
+			regex: re,
+			luhn:  strings.EqualFold(p.ValidationAlgorithm, "luhn"),
+		})
+	}
+
+	return &Redactor{
+		mode:          mode,
+		fingerprinter: NewFingerprinter(),
+		patterns:      patterns,
+	}, nil
+}
+
+// LoadCollectorRedactor builds the Redactor for the tenant owning a collector
+// Collectors without a tenant keep their query text unredacted.
+func LoadCollectorRedactor(ctx context.Context, store RedactionStore, collectorID string) (*Redactor, error) {
+	tenantID, err := store.GetTenantIDByCollectorID(ctx, collectorID)
+	if err != nil {
+		return nil, err
+	}
+	if tenantID == nil {
+		return NewRedactor(models.RedactionModeNone, nil)
+	}
+	return LoadTenantRedactor(ctx, store, *tenantID)
+}
+
+// LoadTenantRedactor builds the Redactor for a tenant's redaction policy
+func LoadTenantRedactor(ctx context.Context, store RedactionStore, tenantID uuid.UUID) (*Redactor, error) {
+	policy, err := store.GetQueryRedactionPolicy(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var patterns []*models.CustomPattern
+	if policy.Mode == models.RedactionModeMask {
+		patterns, err = store.GetCustomPatterns(ctx, &tenantID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return NewRedactor(policy.Mode, patterns)
+}
+
+// Mode returns the redaction mode
+func (r *Redactor) Mode() string {
+	return r.mode
+}
+
+// RedactQuery applies the redaction mode to a SQL statement
+func (r *Redactor) RedactQuery(queryText string) string {
+	switch r.mode {
+	case models.RedactionModeNormalize:
+		normalized, err := r.fingerprinter.Normalize(queryText)
+		if err == nil {
+			return normalized
+		}
+		// Truncated text: the unterminated literal is the last token and is
+		// replaced like any other constant
+		tokens, _ := tokenizeSQL(queryText)
+		return parameterizedText(normalizeTokens(tokens))
+
+	case models.RedactionModeMask:
+		tokens, _ := tokenizeSQL(queryText)
+		return maskedText(tokens, r.matchSensitive)
+
+	default:
+		return queryText
+	}
+}
+
+// RedactLogMessage applies the redaction mode to a log message
+// SQL following "statement:" or "execute <name>:" is redacted as a query;
+// the remaining free text has sensitive pattern matches masked.
+func (r *Redactor) RedactLogMessage(message string) string {
+	if r.mode == models.RedactionModeNone {
+		return message
+	}
+
+	loc := logStatementPattern.FindStringIndex(message)
+	if loc == nil {
+		return r.maskText(message)
+	}
+	return r.maskText(message[:loc[1]]) + r.RedactQuery(message[loc[1]:])
+}
+
+// maskText replaces every sensitive pattern match in free text
+func (r *Redactor) maskText(text string) string {
+	for _, p := range r.patterns {
+		text = p.regex.ReplaceAllStringFunc(text, func(match string) string {
+			if p.luhn && !validLuhn(match) {
+				return match
+			}
+			return "[REDACTED:" + p.name + "]"
+		})
+	}
+	return text
+}
+
+// matchSensitive returns the name of the first pattern matching a literal
+func (r *Redactor) matchSensitive(literal string) (string, bool) {
+	for _, p := range r.patterns {
+		for _, match := range p.regex.FindAllString(literal, -1) {
+			if !p.luhn || validLuhn(match) {
+				return p.name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// maskedText renders tokens with sensitive constants replaced by a
+// '[REDACTED:NAME]' literal. Comments are not rendered since they can
+// carry the same values.
+func maskedText(tokens []sqlToken, match func(string) (string, bool)) string {
+	var b strings.Builder
+	for i, tok := range tokens {
+		if i > 0 && tok.spaceBefore {
+			b.WriteByte(' ')
+		}
+		if tok.kind == tokenString || tok.kind == tokenNumber {
+			if name, ok := match(tok.text); ok {
+				b.WriteString("'[REDACTED:" + name + "]'")
+				continue
+			}
+		}
+		b.WriteString(tok.text)
+	}
+	return b.String()
+}
+
+// validLuhn reports whether the digits in s pass the Luhn checksum
+func validLuhn(s string) bool {
+	sum := 0
+	digits := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		if !isDigit(c) {
+			return false
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
+
+// RedactionBackfillStore rewrites query samples that were stored before a
+// redaction policy took effect
+type RedactionBackfillStore interface {
+	RedactionStore
+	RedactStoredQueryTexts(ctx context.Context, tenantID uuid.UUID, mode string, redactQuery, redactLog, redactPlan func(string) string) (int64, int64, error)
+}
+
+// BackfillRedaction applies a tenant's current redaction policy to its stored
+// query samples and log messages
+func BackfillRedaction(ctx context.Context, store RedactionBackfillStore, tenantID uuid.UUID) (*models.QueryRedactionBackfillResponse, error) {
+	redactor, err := LoadTenantRedactor(ctx, store, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.QueryRedactionBackfillResponse{
+		TenantID: tenantID,
+		Mode:     redactor.Mode(),
+	}
+	if redactor.Mode() == models.RedactionModeNone {
+		return resp, nil
+	}
+
+	resp.RowsScanned, resp.RowsUpdated, err = store.RedactStoredQueryTexts(ctx, tenantID, redactor.Mode(), redactor.RedactQuery, redactor.RedactLogMessage, redactor.RedactPlanJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
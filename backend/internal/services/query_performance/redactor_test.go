@@ -0,0 +1,174 @@
+package query_performance
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// mockRedactionStore is a mock implementation for testing
+type mockRedactionStore struct {
+	tenantID    *uuid.UUID
+	policy      *models.QueryRedactionPolicy
+	patterns    []*models.CustomPattern
+	storedTexts []string
+	logMessages []string
+	plans       []string
+}
+
+func (m *mockRedactionStore) GetTenantIDByCollectorID(ctx context.Context, collectorID string) (*uuid.UUID, error) {
+	return m.tenantID, nil
+}
+
+func (m *mockRedactionStore) GetQueryRedactionPolicy(ctx context.Context, tenantID uuid.UUID) (*models.QueryRedactionPolicy, error) {
+	return m.policy, nil
+}
+
+func (m *mockRedactionStore) GetCustomPatterns(ctx context.Context, tenantID *uuid.UUID) ([]*models.CustomPattern, error) {
+	return m.patterns, nil
+}
+
+func (m *mockRedactionStore) RedactStoredQueryTexts(ctx context.Context, tenantID uuid.UUID, mode string, redactQuery, redactLog, redactPlan func(string) string) (int64, int64, error) {
+	var updated int64
+	for i, text := range m.storedTexts {
+		if redacted := redactQuery(text); redacted != text {
+			m.storedTexts[i] = redacted
+			updated++
+		}
+	}
+	for i, msg := range m.logMessages {
+		if redacted := redactLog(msg); redacted != msg {
+			m.logMessages[i] = redacted
+			updated++
+		}
+	}
+	for i, plan := range m.plans {
+		if redacted := redactPlan(plan); redacted != plan {
+			m.plans[i] = redacted
+			updated++
+		}
+	}
+	return int64(len(m.storedTexts) + len(m.logMessages) + len(m.plans)), updated, nil
+}
+
+func TestRedactor_InvalidMode(t *testing.T) {
+	_, err := NewRedactor("scramble", nil)
+	assert.Error(t, err)
+}
+
+func TestRedactor_NoneKeepsText(t *testing.T) {
+	r, err := NewRedactor(models.RedactionModeNone, nil)
+	require.NoError(t, err)
+
+	query := "SELECT * FROM users WHERE email = 'alice@example.com'"
+	assert.Equal(t, query, r.RedactQuery(query))
+}
+
+func TestRedactor_NormalizeStoresPlaceholdersOnly(t *testing.T) {
+	r, err := NewRedactor(models.RedactionModeNormalize, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		"SELECT * FROM users WHERE email = $1 AND id = $2",
+		r.RedactQuery("SELECT * FROM users WHERE email = 'alice@example.com' AND id = 7 /* token=abc */"),
+	)
+
+	// Truncated samples must not leak the unterminated literal
+	redacted := r.RedactQuery("SELECT * FROM users WHERE token = 'sk_live_abc")
+	assert.NotContains(t, redacted, "sk_live")
+}
+
+func TestRedactor_MaskBuiltinPatterns(t *testing.T) {
+	r, err := NewRedactor(models.RedactionModeMask, nil)
+	require.NoError(t, err)
+
+	redacted := r.RedactQuery("UPDATE users SET email = 'bob@example.com', card = '4111 1111 1111 1111', age = 42 WHERE id = 1234567890123")
+
+	assert.Contains(t, redacted, "'[REDACTED:EMAIL]'")
+	assert.Contains(t, redacted, "'[REDACTED:CREDIT_CARD]'")
+	// Non-sensitive literals are kept, including numbers failing the Luhn check
+	assert.Contains(t, redacted, "age = 42")
+	assert.Contains(t, redacted, "id = 1234567890123")
+}
+
+func TestRedactor_MaskCustomPatterns(t *testing.T) {
+	r, err := NewRedactor(models.RedactionModeMask, []*models.CustomPattern{
+		{PatternName: "API_TOKEN", PatternRegex: `sk_(live|test)_[A-Za-z0-9]+`, Enabled: true},
+		{PatternName: "DISABLED", PatternRegex: `users`, Enabled: false},
+	})
+	require.NoError(t, err)
+
+	redacted := r.RedactQuery("SELECT * FROM users WHERE token = 'sk_live_abc123'")
+	assert.Equal(t, "SELECT * FROM users WHERE token = '[REDACTED:API_TOKEN]'", redacted)
+}
+
+func TestRedactor_InvalidCustomPattern(t *testing.T) {
+	_, err := NewRedactor(models.RedactionModeMask, []*models.CustomPattern{
+		{PatternName: "BROKEN", PatternRegex: `(`, Enabled: true},
+	})
+	assert.Error(t, err)
+}
+
+func TestRedactor_LogMessage(t *testing.T) {
+	r, err := NewRedactor(models.RedactionModeNormalize, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t,
+		"duration: 12.5 ms  statement: SELECT * FROM users WHERE email = $1",
+		r.RedactLogMessage("duration: 12.5 ms  statement: SELECT * FROM users WHERE email = 'carol@example.com'"),
+	)
+	assert.Equal(t,
+		"Key (email)=([REDACTED:EMAIL]) already exists.",
+		r.RedactLogMessage("Key (email)=(carol@example.com) already exists."),
+	)
+}
+
+func TestLoadCollectorRedactor(t *testing.T) {
+	t.Run("collector without tenant is not redacted", func(t *testing.T) {
+		r, err := LoadCollectorRedactor(context.Background(), &mockRedactionStore{}, "col_demo_001")
+		require.NoError(t, err)
+		assert.Equal(t, models.RedactionModeNone, r.Mode())
+	})
+
+	t.Run("uses tenant policy", func(t *testing.T) {
+		tenantID := uuid.New()
+		store := &mockRedactionStore{
+			tenantID: &tenantID,
+			policy:   &models.QueryRedactionPolicy{TenantID: tenantID, Mode: models.RedactionModeMask},
+		}
+
+		r, err := LoadCollectorRedactor(context.Background(), store, uuid.New().String())
+		require.NoError(t, err)
+		assert.Equal(t, models.RedactionModeMask, r.Mode())
+	})
+}
+
+func TestBackfillRedaction(t *testing.T) {
+	tenantID := uuid.New()
+	store := &mockRedactionStore{
+		policy: &models.QueryRedactionPolicy{TenantID: tenantID, Mode: models.RedactionModeNormalize},
+		storedTexts: []string{
+			"SELECT * FROM users WHERE email = 'dave@example.com'",
+			"SELECT now()",
+		},
+		logMessages: []string{"statement: DELETE FROM sessions WHERE token = 'abc'"},
+		plans:       []string{`{"Plan": {"Node Type": "Seq Scan", "Filter": "(email = 'erin@example.com'::text)"}}`},
+	}
+
+	resp, err := BackfillRedaction(context.Background(), store, tenantID)
+	require.NoError(t, err)
+
+	assert.Equal(t, models.RedactionModeNormalize, resp.Mode)
+	assert.Equal(t, int64(4), resp.RowsScanned)
+	assert.Equal(t, int64(3), resp.RowsUpdated)
+	for _, text := range append(store.storedTexts, store.logMessages...) {
+		assert.False(t, strings.Contains(text, "'"), text)
+	}
+	// Plan strings have sensitive values masked like log messages
+	assert.NotContains(t, store.plans[0], "erin@example.com")
+}
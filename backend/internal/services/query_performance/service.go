@@ -82,6 +82,7 @@ type FingerprintGroup struct {
 	QueryCount      int                 `json:"query_count"`
 	TotalCalls      int64               `json:"total_calls"`
 	AvgTimeMs       float64             `json:"avg_time_ms"`
+	QueryIDs        []int64             `json:"query_ids"` // pg_stat_statements queryids in this group
 	Queries         []storage.SlowQuery `json:"queries"`
 }
 
@@ -241,17 +242,33 @@ func (s *Service) GetQueriesGroupedByFingerprint(ctx context.Context, databaseID
 	// Compute fingerprints and group queries
 	fp := NewFingerprinter()
 	groups := make(map[string]*FingerprintGroup)
+	queryIDFingerprints := make(map[int64]string)
 
 	for _, q := range queries {
 		fpHash := fp.Fingerprint(q.QueryText)
+
+		// A queryid identifies one statement even when its sampled text differs
+		// (e.g. truncated by track_activity_query_size), so link it to the
+		// first fingerprint it was seen with
+		if q.QueryID != 0 {
+			if linked, ok := queryIDFingerprints[q.QueryID]; ok {
+				fpHash = linked
+			} else {
+				queryIDFingerprints[q.QueryID] = fpHash
+			}
+		}
 		q.QueryFingerprintHash = fpHash
 
 		if _, exists := groups[fpHash]; !exists {
 			groups[fpHash] = &FingerprintGroup{
 				FingerprintHash: fpHash,
+				QueryIDs:        []int64{},
 				Queries:         []storage.SlowQuery{},
 			}
 		}
+		if q.QueryID != 0 && !containsQueryID(groups[fpHash].QueryIDs, q.QueryID) {
+			groups[fpHash].QueryIDs = append(groups[fpHash].QueryIDs, q.QueryID)
+		}
 		groups[fpHash].Queries = append(groups[fpHash].Queries, q)
 		groups[fpHash].QueryCount++
 		groups[fpHash].TotalCalls += q.Calls
@@ -286,3 +303,13 @@ func (s *Service) GetQueriesGroupedByFingerprint(ctx context.Context, databaseID
 		Limit:        limit,
 	}, nil
 }
+
+// containsQueryID reports whether ids already holds queryID
+func containsQueryID(ids []int64, queryID int64) bool {
+	for _, id := range ids {
+		if id == queryID {
+			return true
+		}
+	}
+	return false
+}
@@ -173,3 +173,41 @@ func TestService_GetIndexStats(t *testing.T) {
 		assert.Equal(t, 0, response.UnusedCount)
 	})
 }
+
+func TestService_GetQueriesGroupedByFingerprint(t *testing.T) {
+	t.Run("groups queries differing only in constants", func(t *testing.T) {
+		mockStore := &mockQueryPerformanceStore{
+			slowQueries: []storage.SlowQuery{
+				{QueryID: 11, QueryText: "SELECT * FROM users WHERE id IN (1, 2)", Calls: 10, MeanTime: 2.0},
+				{QueryID: 12, QueryText: "select * from users where id in (3, 4, 5) -- app", Calls: 30, MeanTime: 4.0},
+				{QueryID: 13, QueryText: "SELECT * FROM orders", Calls: 5, MeanTime: 1.0},
+			},
+		}
+
+		service := NewServiceWithStore(mockStore, zap.NewNop())
+		response, err := service.GetQueriesGroupedByFingerprint(context.Background(), 1, 20)
+
+		require.NoError(t, err)
+		require.Len(t, response.Fingerprints, 2)
+		assert.Equal(t, 2, response.Fingerprints[0].QueryCount)
+		assert.Equal(t, int64(40), response.Fingerprints[0].TotalCalls)
+		assert.Equal(t, []int64{11, 12}, response.Fingerprints[0].QueryIDs)
+	})
+
+	t.Run("links text variants sharing a queryid", func(t *testing.T) {
+		mockStore := &mockQueryPerformanceStore{
+			slowQueries: []storage.SlowQuery{
+				{QueryID: 42, QueryText: "SELECT a, b FROM t WHERE a = 1", Calls: 10},
+				{QueryID: 42, QueryText: "SELECT a, b FROM t WHE", Calls: 5},
+			},
+		}
+
+		service := NewServiceWithStore(mockStore, zap.NewNop())
+		response, err := service.GetQueriesGroupedByFingerprint(context.Background(), 1, 20)
+
+		require.NoError(t, err)
+		require.Len(t, response.Fingerprints, 1)
+		assert.Equal(t, []int64{42}, response.Fingerprints[0].QueryIDs)
+		assert.Equal(t, 2, response.Fingerprints[0].QueryCount)
+	})
+}
@@ -0,0 +1,85 @@
+package query_regression
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// markdownQueryTextLimit truncates query text in Markdown tables
+const markdownQueryTextLimit = 80
+
+// RenderMarkdown formats a regression report for release reviews
+func RenderMarkdown(report *models.RegressionReport) string {
+	var b strings.Builder
+
+	b.WriteString("# Query Regression Report\n\n")
+	if report.DeployMarker != nil {
+		fmt.Fprintf(&b, "Deploy **%s** at %s\n\n", report.DeployMarker.Version, report.DeployMarker.DeployedAt.UTC().Format(time.RFC3339))
+	}
+	fmt.Fprintf(&b, "- Collector: `%s`\n", report.CollectorID)
+	fmt.Fprintf(&b, "- Baseline: %s to %s\n", report.Baseline.From.UTC().Format(time.RFC3339), report.Baseline.To.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Target: %s to %s\n", report.Target.From.UTC().Format(time.RFC3339), report.Target.To.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Queries compared: %d (%d regressed, %d improved, significance level %.2f)\n",
+		report.QueriesCompared, report.Regressed, report.Improved, report.Alpha)
+	fmt.Fprintf(&b, "- Added DB time: %.1f ms\n\n", report.AddedDBTimeMs)
+
+	if len(report.Queries) == 0 {
+		b.WriteString("No query changes to report.\n")
+		return b.String()
+	}
+
+	b.WriteString("| Status | Database | Query | Mean ms | P95 interval mean ms | Calls | Rows/call | Reads/call | Added DB time ms | p-value |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|---|---|\n")
+	for _, q := range report.Queries {
+		fmt.Fprintf(&b, "| %s | %s | `%s` | %s | %s | %s | %s | %s | %.1f | %s |\n",
+			q.Status,
+			q.DatabaseName,
+			markdownQuery(q.QueryText),
+			markdownChange(q.Baseline, q.Target, func(s *models.QueryWindowStats) float64 { return s.MeanTimeMs }, q.MeanTimeChangePct),
+			markdownChange(q.Baseline, q.Target, func(s *models.QueryWindowStats) float64 { return s.P95IntervalMeanMs }, q.P95IntervalMeanChangePct),
+			markdownChange(q.Baseline, q.Target, func(s *models.QueryWindowStats) float64 { return float64(s.Calls) }, q.CallsChangePct),
+			markdownChange(q.Baseline, q.Target, func(s *models.QueryWindowStats) float64 { return s.RowsPerCall }, q.RowsPerCallChangePct),
+			markdownChange(q.Baseline, q.Target, func(s *models.QueryWindowStats) float64 { return s.ReadsPerCall }, q.ReadsPerCallChangePct),
+			q.AddedDBTimeMs,
+			markdownPValue(q.PValue),
+		)
+	}
+
+	return b.String()
+}
+
+// markdownChange renders "before → after (+x%)" for a metric
+func markdownChange(before, after *models.QueryWindowStats, metric func(*models.QueryWindowStats) float64, pct *float64) string {
+	value := func(s *models.QueryWindowStats) string {
+		if s == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f", metric(s))
+	}
+
+	text := value(before) + " → " + value(after)
+	if pct != nil {
+		text += fmt.Sprintf(" (%+.1f%%)", *pct)
+	}
+	return text
+}
+
+func markdownPValue(p *float64) string {
+	if p == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.4f", *p)
+}
+
+// markdownQuery keeps query text on one line and out of table syntax
+func markdownQuery(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	text = strings.NewReplacer("|", "\\|", "`", "'").Replace(text)
+	if len([]rune(text)) > markdownQueryTextLimit {
+		text = string([]rune(text)[:markdownQueryTextLimit]) + "…"
+	}
+	return text
+}
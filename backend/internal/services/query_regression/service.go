@@ -0,0 +1,242 @@
+package query_regression
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// DefaultAlpha is the significance level used when none is given
+const DefaultAlpha = 0.05
+
+// Store interface for query regression data
+type Store interface {
+	GetQueryStatsSamples(ctx context.Context, collectorID uuid.UUID, from, to time.Time, database *string) ([]*models.QueryStatsSample, error)
+	GetDeployMarker(ctx context.Context, collectorID uuid.UUID, id int64) (*models.DeployMarker, error)
+}
+
+// Service builds query performance regression reports
+type Service struct {
+	store       Store
+	logger      *zap.Logger
+	fingerprint *query_performance.Fingerprinter
+}
+
+// NewService creates a new query regression service
+func NewService(store Store, logger *zap.Logger) *Service {
+	return &Service{
+		store:       store,
+		logger:      logger,
+		fingerprint: query_performance.NewFingerprinterWithLogger(logger),
+	}
+}
+
+// GenerateReport compares every query fingerprint between a baseline and a
+// target window
+func (s *Service) GenerateReport(ctx context.Context, collectorID uuid.UUID, baseline, target models.RegressionWindow, opts models.RegressionOptions) (*models.RegressionReport, error) {
+	if opts.Alpha <= 0 || opts.Alpha >= 1 {
+		opts.Alpha = DefaultAlpha
+	}
+
+	baselineSamples, err := s.store.GetQueryStatsSamples(ctx, collectorID, baseline.From, baseline.To, opts.Database)
+	if err != nil {
+		s.logger.Error("Failed to load baseline query stats", zap.Error(err))
+		return nil, err
+	}
+	targetSamples, err := s.store.GetQueryStatsSamples(ctx, collectorID, target.From, target.To, opts.Database)
+	if err != nil {
+		s.logger.Error("Failed to load target query stats", zap.Error(err))
+		return nil, err
+	}
+
+	report := &models.RegressionReport{
+		CollectorID: collectorID,
+		Baseline:    baseline,
+		Target:      target,
+		Alpha:       opts.Alpha,
+		Queries:     []*models.QueryRegression{},
+		GeneratedAt: time.Now(),
+	}
+
+	queries := compareWindows(
+		aggregateWindow(baselineSamples, s.fingerprint),
+		aggregateWindow(targetSamples, s.fingerprint),
+		opts,
+	)
+	report.QueriesCompared = len(queries)
+
+	for _, q := range queries {
+		switch q.Status {
+		case models.RegressionStatusRegressed:
+			report.Regressed++
+			report.AddedDBTimeMs += q.AddedDBTimeMs
+		case models.RegressionStatusImproved:
+			report.Improved++
+		case models.RegressionStatusNew:
+			report.AddedDBTimeMs += q.AddedDBTimeMs
+		}
+		if opts.SignificantOnly && !q.Significant && q.Status != models.RegressionStatusNew {
+			continue
+		}
+		report.Queries = append(report.Queries, q)
+	}
+	report.AddedDBTimeMs = round3(report.AddedDBTimeMs)
+
+	if opts.Limit > 0 && len(report.Queries) > opts.Limit {
+		report.Queries = report.Queries[:opts.Limit]
+	}
+
+	return report, nil
+}
+
+// GenerateDeployReport compares the window before a deploy marker with the
+// window of the same length after it. The target window ends no later than now.
+func (s *Service) GenerateDeployReport(ctx context.Context, collectorID uuid.UUID, markerID int64, window time.Duration, opts models.RegressionOptions) (*models.RegressionReport, error) {
+	marker, err := s.store.GetDeployMarker(ctx, collectorID, markerID)
+	if err != nil {
+		s.logger.Error("Failed to load deploy marker", zap.Error(err))
+		return nil, err
+	}
+	if marker == nil {
+		return nil, apperrors.NotFound("Deploy marker not found", "")
+	}
+
+	baseline := models.RegressionWindow{From: marker.DeployedAt.Add(-window), To: marker.DeployedAt}
+	target := models.RegressionWindow{From: marker.DeployedAt, To: marker.DeployedAt.Add(window)}
+	if now := time.Now(); target.To.After(now) {
+		target.To = now
+	}
+	if !target.From.Before(target.To) {
+		return nil, apperrors.BadRequest("Deploy marker is in the future", "no data after the deploy yet")
+	}
+
+	report, err := s.GenerateReport(ctx, collectorID, baseline, target, opts)
+	if err != nil {
+		return nil, err
+	}
+	report.DeployMarker = marker
+	return report, nil
+}
+
+// compareWindows builds per-fingerprint comparisons ranked by added DB time
+func compareWindows(baseline, target map[groupKey]*windowGroup, opts models.RegressionOptions) []*models.QueryRegression {
+	keys := make(map[groupKey]bool, len(baseline)+len(target))
+	for k := range baseline {
+		keys[k] = true
+	}
+	for k := range target {
+		keys[k] = true
+	}
+
+	var queries []*models.QueryRegression
+	for k := range keys {
+		b, t := baseline[k], target[k]
+		if callsOf(b) < opts.MinCalls && callsOf(t) < opts.MinCalls {
+			continue
+		}
+		queries = append(queries, compareGroup(k, b, t, opts.Alpha))
+	}
+
+	sort.Slice(queries, func(i, j int) bool {
+		if queries[i].AddedDBTimeMs != queries[j].AddedDBTimeMs {
+			return queries[i].AddedDBTimeMs > queries[j].AddedDBTimeMs
+		}
+		if queries[i].DatabaseName != queries[j].DatabaseName {
+			return queries[i].DatabaseName < queries[j].DatabaseName
+		}
+		return queries[i].QueryFingerprint < queries[j].QueryFingerprint
+	})
+	return queries
+}
+
+// compareGroup compares one fingerprint; b or t is nil when the fingerprint
+// was not seen in that window
+func compareGroup(k groupKey, b, t *windowGroup, alpha float64) *models.QueryRegression {
+	q := &models.QueryRegression{
+		QueryFingerprint: k.fingerprint,
+		DatabaseName:     k.database,
+		Status:           models.RegressionStatusUnchanged,
+	}
+
+	hashes := make(map[int64]bool)
+	for _, g := range []*windowGroup{b, t} {
+		if g == nil {
+			continue
+		}
+		q.QueryText = g.queryText
+		for h := range g.queryHashes {
+			hashes[h] = true
+		}
+	}
+	for h := range hashes {
+		q.QueryHashes = append(q.QueryHashes, h)
+	}
+	sort.Slice(q.QueryHashes, func(i, j int) bool { return q.QueryHashes[i] < q.QueryHashes[j] })
+
+	switch {
+	case b == nil:
+		q.Target = &t.stats
+		q.Status = models.RegressionStatusNew
+		q.AddedDBTimeMs = t.stats.TotalTimeMs
+		return q
+	case t == nil:
+		q.Baseline = &b.stats
+		q.Status = models.RegressionStatusGone
+		return q
+	}
+
+	q.Baseline, q.Target = &b.stats, &t.stats
+	q.MeanTimeChangePct = changePercent(b.stats.MeanTimeMs, t.stats.MeanTimeMs)
+	q.P95IntervalMeanChangePct = changePercent(b.stats.P95IntervalMeanMs, t.stats.P95IntervalMeanMs)
+	q.CallsChangePct = changePercent(float64(b.stats.Calls), float64(t.stats.Calls))
+	q.RowsPerCallChangePct = changePercent(b.stats.RowsPerCall, t.stats.RowsPerCall)
+	q.ReadsPerCallChangePct = changePercent(b.stats.ReadsPerCall, t.stats.ReadsPerCall)
+	q.AddedDBTimeMs = round3(float64(t.stats.Calls) * (t.stats.MeanTimeMs - b.stats.MeanTimeMs))
+
+	if p, ok := welchTTest(b.intervalMeans(), t.intervalMeans()); ok {
+		p = round6(p)
+		q.PValue = &p
+		q.Significant = p < alpha
+	}
+	if q.Significant {
+		if t.stats.MeanTimeMs > b.stats.MeanTimeMs {
+			q.Status = models.RegressionStatusRegressed
+		} else if t.stats.MeanTimeMs < b.stats.MeanTimeMs {
+			q.Status = models.RegressionStatusImproved
+		}
+	}
+
+	return q
+}
+
+func callsOf(g *windowGroup) int64 {
+	if g == nil {
+		return 0
+	}
+	return g.stats.Calls
+}
+
+// changePercent returns the relative change from before to after, or nil when
+// before is zero
+func changePercent(before, after float64) *float64 {
+	if before == 0 {
+		return nil
+	}
+	pct := math.Round((after-before)/before*10000) / 100
+	return &pct
+}
+
+func round3(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+func round6(v float64) float64 {
+	return math.Round(v*1e6) / 1e6
+}
@@ -0,0 +1,197 @@
+package query_regression
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockRegressionStore is a mock implementation for testing
+type mockRegressionStore struct {
+	samples []*models.QueryStatsSample
+	markers map[int64]*models.DeployMarker
+
+	windows []models.RegressionWindow
+}
+
+func (m *mockRegressionStore) GetQueryStatsSamples(ctx context.Context, collectorID uuid.UUID, from, to time.Time, database *string) ([]*models.QueryStatsSample, error) {
+	m.windows = append(m.windows, models.RegressionWindow{From: from, To: to})
+	var out []*models.QueryStatsSample
+	for _, s := range m.samples {
+		if !s.Time.Before(from) && s.Time.Before(to) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockRegressionStore) GetDeployMarker(ctx context.Context, collectorID uuid.UUID, id int64) (*models.DeployMarker, error) {
+	return m.markers[id], nil
+}
+
+var deployTime = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// series generates cumulative samples every minute for an hour starting at
+// start, with the given calls per minute and a latency per call produced by
+// latency(i)
+func series(start time.Time, hash int64, text string, callsPerMin int64, latency func(i int) float64) []*models.QueryStatsSample {
+	var out []*models.QueryStatsSample
+	var calls int64
+	var total float64
+	for i := 0; i <= 60; i++ {
+		if i > 0 {
+			calls += callsPerMin
+			total += float64(callsPerMin) * latency(i)
+		}
+		out = append(out, &models.QueryStatsSample{
+			Time:           start.Add(time.Duration(i) * time.Minute),
+			DatabaseName:   "app",
+			QueryHash:      hash,
+			QueryText:      text,
+			Calls:          calls,
+			TotalTime:      total,
+			Rows:           calls,
+			SharedBlksRead: calls * 2,
+		})
+	}
+	return out
+}
+
+func jitter(base float64) func(i int) float64 {
+	return func(i int) float64 { return base + float64(i%5)*0.1 }
+}
+
+func testWindows() (models.RegressionWindow, models.RegressionWindow) {
+	return models.RegressionWindow{From: deployTime.Add(-time.Hour), To: deployTime},
+		models.RegressionWindow{From: deployTime, To: deployTime.Add(time.Hour + time.Minute)}
+}
+
+// TestGenerateReport_RanksRegressions detects a slower query and ranks it first
+func TestGenerateReport_RanksRegressions(t *testing.T) {
+	var samples []*models.QueryStatsSample
+	// Slower after the deploy
+	samples = append(samples, series(deployTime.Add(-time.Hour), 1, "SELECT * FROM orders WHERE id = 1", 100, jitter(2))...)
+	samples = append(samples, series(deployTime, 1, "SELECT * FROM orders WHERE id = 1", 100, jitter(5))...)
+	// Unchanged; different constant but the same fingerprint in the target window
+	samples = append(samples, series(deployTime.Add(-time.Hour), 2, "SELECT name FROM users WHERE id = 7", 50, jitter(1))...)
+	samples = append(samples, series(deployTime, 3, "SELECT name FROM users WHERE id = 9", 50, jitter(1))...)
+	// Only after the deploy
+	samples = append(samples, series(deployTime, 4, "SELECT count(*) FROM audit", 20, jitter(3))...)
+
+	service := NewService(&mockRegressionStore{samples: samples}, zap.NewNop())
+	baseline, target := testWindows()
+
+	report, err := service.GenerateReport(context.Background(), uuid.New(), baseline, target, models.RegressionOptions{MinCalls: 10})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultAlpha, report.Alpha)
+	assert.Equal(t, 3, report.QueriesCompared)
+	assert.Equal(t, 1, report.Regressed)
+	require.Len(t, report.Queries, 3)
+
+	top := report.Queries[0]
+	assert.Equal(t, models.RegressionStatusRegressed, top.Status)
+	assert.True(t, top.Significant)
+	assert.Equal(t, int64(6000), top.Target.Calls)
+	assert.InDelta(t, 2.2, top.Baseline.MeanTimeMs, 0.01)
+	assert.InDelta(t, 5.2, top.Target.MeanTimeMs, 0.01)
+	assert.InDelta(t, 136.36, *top.MeanTimeChangePct, 0.5)
+	assert.InDelta(t, 6000*3.0, top.AddedDBTimeMs, 60)
+	assert.Equal(t, 1.0, top.Target.RowsPerCall)
+	assert.Equal(t, 2.0, top.Target.ReadsPerCall)
+
+	assert.Equal(t, models.RegressionStatusNew, report.Queries[1].Status)
+
+	unchanged := report.Queries[2]
+	assert.Equal(t, models.RegressionStatusUnchanged, unchanged.Status)
+	assert.Equal(t, []int64{2, 3}, unchanged.QueryHashes)
+	assert.False(t, unchanged.Significant)
+}
+
+// TestGenerateReport_Filters applies min calls and significance filters
+func TestGenerateReport_Filters(t *testing.T) {
+	var samples []*models.QueryStatsSample
+	samples = append(samples, series(deployTime.Add(-time.Hour), 1, "SELECT 1", 100, jitter(1))...)
+	samples = append(samples, series(deployTime, 1, "SELECT 1", 100, jitter(1))...)
+	samples = append(samples, series(deployTime.Add(-time.Hour), 2, "SELECT 2", 0, jitter(1))...)
+
+	service := NewService(&mockRegressionStore{samples: samples}, zap.NewNop())
+	baseline, target := testWindows()
+
+	report, err := service.GenerateReport(context.Background(), uuid.New(), baseline, target,
+		models.RegressionOptions{MinCalls: 10, SignificantOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.QueriesCompared)
+	assert.Empty(t, report.Queries)
+}
+
+// TestAggregateWindow_Reset treats a drop in calls as a statistics reset
+func TestAggregateWindow_Reset(t *testing.T) {
+	base := deployTime
+	samples := []*models.QueryStatsSample{
+		{Time: base, DatabaseName: "app", QueryHash: 1, QueryText: "SELECT 1", Calls: 100, TotalTime: 100},
+		{Time: base.Add(time.Minute), DatabaseName: "app", QueryHash: 1, QueryText: "SELECT 1", Calls: 110, TotalTime: 120},
+		{Time: base.Add(2 * time.Minute), DatabaseName: "app", QueryHash: 1, QueryText: "SELECT 1", Calls: 5, TotalTime: 50},
+	}
+
+	service := NewService(&mockRegressionStore{}, zap.NewNop())
+	groups := aggregateWindow(samples, service.fingerprint)
+	require.Len(t, groups, 1)
+	for _, g := range groups {
+		assert.Equal(t, int64(15), g.stats.Calls)
+		assert.Equal(t, 70.0, g.stats.TotalTimeMs)
+		assert.Equal(t, 2, g.stats.Intervals)
+	}
+}
+
+// TestGenerateDeployReport compares equal windows around the marker
+func TestGenerateDeployReport(t *testing.T) {
+	store := &mockRegressionStore{markers: map[int64]*models.DeployMarker{
+		7: {ID: 7, Version: "v1.2.3", DeployedAt: deployTime},
+	}}
+	service := NewService(store, zap.NewNop())
+
+	report, err := service.GenerateDeployReport(context.Background(), uuid.New(), 7, 30*time.Minute, models.RegressionOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "v1.2.3", report.DeployMarker.Version)
+	require.Len(t, store.windows, 2)
+	assert.Equal(t, deployTime.Add(-30*time.Minute), store.windows[0].From)
+	assert.Equal(t, deployTime, store.windows[1].From)
+	assert.Equal(t, deployTime.Add(30*time.Minute), store.windows[1].To)
+
+	_, err = service.GenerateDeployReport(context.Background(), uuid.New(), 8, time.Hour, models.RegressionOptions{})
+	assert.Error(t, err)
+}
+
+// TestRenderMarkdown renders a summary and one row per query
+func TestRenderMarkdown(t *testing.T) {
+	pct := 50.0
+	p := 0.001
+	report := &models.RegressionReport{
+		Alpha:        0.05,
+		DeployMarker: &models.DeployMarker{Version: "v2", DeployedAt: deployTime},
+		Queries: []*models.QueryRegression{{
+			Status:            models.RegressionStatusRegressed,
+			DatabaseName:      "app",
+			QueryText:         "SELECT a | b\nFROM t",
+			Baseline:          &models.QueryWindowStats{MeanTimeMs: 2, Calls: 10},
+			Target:            &models.QueryWindowStats{MeanTimeMs: 3, Calls: 10},
+			MeanTimeChangePct: &pct,
+			AddedDBTimeMs:     10,
+			PValue:            &p,
+		}},
+	}
+
+	md := RenderMarkdown(report)
+	assert.Contains(t, md, "Deploy **v2**")
+	assert.Contains(t, md, "| regressed | app | `SELECT a \\| b FROM t` | 2.00 → 3.00 (+50.0%) |")
+	assert.Contains(t, md, "0.0010 |")
+
+	report.Queries = nil
+	assert.Contains(t, RenderMarkdown(report), "No query changes to report.")
+}
@@ -0,0 +1,146 @@
+package query_regression
+
+import (
+	"math"
+	"sort"
+)
+
+// welchTTest returns the two-sided p-value of Welch's t-test for a difference
+// in means between a and b. ok is false when either sample has fewer than two
+// values or both have no variance.
+func welchTTest(a, b []float64) (pValue float64, ok bool) {
+	if len(a) < 2 || len(b) < 2 {
+		return 0, false
+	}
+
+	meanA, varA := meanVariance(a)
+	meanB, varB := meanVariance(b)
+	na, nb := float64(len(a)), float64(len(b))
+
+	seA, seB := varA/na, varB/nb
+	se := seA + seB
+	if se == 0 {
+		if meanA == meanB {
+			return 1, true
+		}
+		return 0, true
+	}
+
+	t := (meanA - meanB) / math.Sqrt(se)
+	df := se * se / (seA*seA/(na-1) + seB*seB/(nb-1))
+
+	// Two-sided p-value from the Student t distribution:
+	// P(|T| > |t|) = I_{df/(df+t^2)}(df/2, 1/2)
+	return regularizedIncompleteBeta(df/(df+t*t), df/2, 0.5), true
+}
+
+// meanVariance returns the mean and unbiased sample variance of values
+func meanVariance(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	if len(values) < 2 {
+		return mean, 0
+	}
+	return mean, sq / float64(len(values)-1)
+}
+
+// percentile returns the p-th percentile (0-100) of values using linear
+// interpolation between closest ranks
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower == upper {
+		return sorted[lower]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// regularizedIncompleteBeta evaluates I_x(a, b) with the continued fraction
+// from Numerical Recipes (betacf), using the symmetry relation for
+// convergence when x is above the mean of the distribution
+func regularizedIncompleteBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(x, a, b) / a
+	}
+	return 1 - front*betaContinuedFraction(1-x, b, a)/b
+}
+
+func betaContinuedFraction(x, a, b float64) float64 {
+	const (
+		maxIterations = 200
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+
+	qab, qap, qam := a+b, a+1, a-1
+	c := 1.0
+	d := 1 - qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+
+		if math.Abs(del-1) < epsilon {
+			break
+		}
+	}
+
+	return h
+}
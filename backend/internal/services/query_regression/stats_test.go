@@ -0,0 +1,42 @@
+package query_regression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWelchTTest checks p-values against known results
+func TestWelchTTest(t *testing.T) {
+	p, ok := welchTTest([]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10})
+	assert.True(t, ok)
+	assert.InDelta(t, 0.001052, p, 1e-5)
+
+	p, ok = welchTTest([]float64{10, 11, 9, 10, 10}, []float64{10, 9, 11, 10, 10})
+	assert.True(t, ok)
+	assert.InDelta(t, 1.0, p, 1e-9)
+
+	_, ok = welchTTest([]float64{1}, []float64{1, 2})
+	assert.False(t, ok, "a single interval cannot be tested")
+
+	p, ok = welchTTest([]float64{5, 5, 5}, []float64{7, 7})
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, p, "constant samples with different means")
+}
+
+// TestRegularizedIncompleteBeta checks symmetric and boundary values
+func TestRegularizedIncompleteBeta(t *testing.T) {
+	assert.InDelta(t, 0.5, regularizedIncompleteBeta(0.5, 2, 2), 1e-12)
+	assert.InDelta(t, 0.25, regularizedIncompleteBeta(0.25, 1, 1), 1e-12)
+	assert.Equal(t, 0.0, regularizedIncompleteBeta(0, 3, 4))
+	assert.Equal(t, 1.0, regularizedIncompleteBeta(1, 3, 4))
+}
+
+// TestPercentile interpolates between ranks
+func TestPercentile(t *testing.T) {
+	values := []float64{5, 1, 4, 2, 3}
+	assert.Equal(t, 3.0, percentile(values, 50))
+	assert.InDelta(t, 4.8, percentile(values, 95), 1e-9)
+	assert.Equal(t, 0.0, percentile(nil, 95))
+	assert.Equal(t, []float64{5, 1, 4, 2, 3}, values, "input must not be reordered")
+}
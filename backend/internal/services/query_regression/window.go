@@ -0,0 +1,134 @@
+package query_regression
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// groupKey identifies a query fingerprint within a database
+type groupKey struct {
+	database    string
+	fingerprint string
+}
+
+// windowGroup accumulates a fingerprint's counter deltas over one window
+type windowGroup struct {
+	queryText   string
+	queryHashes map[int64]bool
+	stats       models.QueryWindowStats
+	intervals   map[time.Time]*interval
+}
+
+// interval is the work done between two consecutive samples
+type interval struct {
+	calls     int64
+	totalTime float64
+}
+
+// intervalMeans returns the mean latency of every interval with calls
+func (g *windowGroup) intervalMeans() []float64 {
+	times := make([]time.Time, 0, len(g.intervals))
+	for t := range g.intervals {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	means := make([]float64, 0, len(times))
+	for _, t := range times {
+		iv := g.intervals[t]
+		if iv.calls > 0 {
+			means = append(means, iv.totalTime/float64(iv.calls))
+		}
+	}
+	return means
+}
+
+// aggregateWindow turns cumulative pg_stat_statements samples into per
+// fingerprint deltas. Deltas are taken between consecutive samples of the same
+// database and query hash; a drop in calls means the statistics were reset, in
+// which case the later sample's counters are the delta. Queries sampled only
+// once in the window contribute nothing.
+func aggregateWindow(samples []*models.QueryStatsSample, fp *query_performance.Fingerprinter) map[groupKey]*windowGroup {
+	type seriesKey struct {
+		database string
+		hash     int64
+	}
+	series := make(map[seriesKey][]*models.QueryStatsSample)
+	for _, s := range samples {
+		k := seriesKey{s.DatabaseName, s.QueryHash}
+		series[k] = append(series[k], s)
+	}
+
+	groups := make(map[groupKey]*windowGroup)
+	for k, rows := range series {
+		if len(rows) < 2 {
+			continue
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i].Time.Before(rows[j].Time) })
+
+		latest := rows[len(rows)-1]
+		fingerprint := fp.Fingerprint(latest.QueryText)
+		if fingerprint == "" {
+			fingerprint = strconv.FormatInt(k.hash, 10)
+		}
+		gk := groupKey{k.database, fingerprint}
+		g, ok := groups[gk]
+		if !ok {
+			g = &windowGroup{
+				queryText:   latest.QueryText,
+				queryHashes: make(map[int64]bool),
+				intervals:   make(map[time.Time]*interval),
+			}
+			groups[gk] = g
+		}
+		g.queryHashes[k.hash] = true
+
+		for i := 1; i < len(rows); i++ {
+			prev, cur := rows[i-1], rows[i]
+			calls := cur.Calls - prev.Calls
+			totalTime := cur.TotalTime - prev.TotalTime
+			rowCount := cur.Rows - prev.Rows
+			reads := cur.SharedBlksRead - prev.SharedBlksRead
+			if calls < 0 || totalTime < 0 {
+				calls, totalTime, rowCount, reads = cur.Calls, cur.TotalTime, cur.Rows, cur.SharedBlksRead
+			}
+			if calls <= 0 {
+				continue
+			}
+
+			g.stats.Calls += calls
+			g.stats.TotalTimeMs += totalTime
+			g.stats.Rows += rowCount
+			g.stats.SharedBlksRead += reads
+
+			iv, ok := g.intervals[cur.Time]
+			if !ok {
+				iv = &interval{}
+				g.intervals[cur.Time] = iv
+			}
+			iv.calls += calls
+			iv.totalTime += totalTime
+		}
+	}
+
+	for gk, g := range groups {
+		if g.stats.Calls == 0 {
+			delete(groups, gk)
+			continue
+		}
+		means := g.intervalMeans()
+		calls := float64(g.stats.Calls)
+		g.stats.MeanTimeMs = round3(g.stats.TotalTimeMs / calls)
+		g.stats.P95IntervalMeanMs = round3(percentile(means, 95))
+		g.stats.RowsPerCall = round3(float64(g.stats.Rows) / calls)
+		g.stats.ReadsPerCall = round3(float64(g.stats.SharedBlksRead) / calls)
+		g.stats.TotalTimeMs = round3(g.stats.TotalTimeMs)
+		g.stats.Intervals = len(means)
+	}
+
+	return groups
+}
@@ -0,0 +1,621 @@
+package replication_topology
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// externalPrefix marks the ID of a node that has no collector
+const externalPrefix = "external:"
+
+// builder correlates the replication state of a tenant's collectors
+type builder struct {
+	topology  *models.FleetTopology
+	snapshots []*models.ReplicationNodeSnapshot
+	nodes     map[string]*models.TopologyGraphNode
+	byHost    map[string][]*models.ReplicationNodeSnapshot
+	upstream  map[uuid.UUID]*models.ReplicationNodeSnapshot
+	edges     map[[2]string]*models.TopologyEdge
+}
+
+// Build correlates the WAL receivers, walsenders and subscriptions of a
+// tenant's collectors into a replication graph.
+//
+// A standby's upstream is the collector whose hostname or address is the
+// sender host of its WAL receiver. A walsender's downstream is the collector
+// with a subscription named after its application_name (logical), else the
+// standby with that application_name or client address (physical). Peers
+// without a collector become external nodes. Replication samples older than
+// a node's latest recovery state are dropped first, see currentSnapshots.
+func Build(tenantID uuid.UUID, snapshots []*models.ReplicationNodeSnapshot, now time.Time) *models.FleetTopology {
+	snapshots = currentSnapshots(snapshots)
+	b := &builder{
+		topology: &models.FleetTopology{
+			TenantID:    tenantID,
+			GeneratedAt: now,
+			Nodes:       []*models.TopologyGraphNode{},
+			Edges:       []*models.TopologyEdge{},
+			Clusters:    []*models.TopologyCluster{},
+			Findings:    []string{},
+		},
+		snapshots: snapshots,
+		nodes:     make(map[string]*models.TopologyGraphNode),
+		byHost:    make(map[string][]*models.ReplicationNodeSnapshot),
+		upstream:  make(map[uuid.UUID]*models.ReplicationNodeSnapshot),
+		edges:     make(map[[2]string]*models.TopologyEdge),
+	}
+
+	b.addCollectorNodes()
+	b.resolveUpstreams()
+	b.addSenderEdges()
+	b.addReceiverEdges()
+	b.assignRoles()
+	b.buildClusters()
+	b.sortResult()
+
+	return b.topology
+}
+
+// currentSnapshots drops what a node reported before its latest recovery
+// state: the WAL receiver of a node promoted since, and walsenders missing
+// from the state's sample. The state comes with every pg_replication sample,
+// so a promotion shows before the stale samples leave the freshness window.
+func currentSnapshots(snapshots []*models.ReplicationNodeSnapshot) []*models.ReplicationNodeSnapshot {
+	current := make([]*models.ReplicationNodeSnapshot, len(snapshots))
+	for i, s := range snapshots {
+		current[i] = s
+		if s.State == nil {
+			continue
+		}
+		staleReceiver := s.WalReceiver != nil && !s.State.InRecovery && !s.WalReceiver.Time.After(s.State.Time)
+		staleSenders := len(s.Senders) > 0 && s.Senders[0].Timestamp.Before(s.State.Time)
+		if !staleReceiver && !staleSenders {
+			continue
+		}
+		c := *s
+		if staleReceiver {
+			c.WalReceiver = nil
+		}
+		if staleSenders {
+			c.Senders = []*models.ReplicationStatus{}
+		}
+		current[i] = &c
+	}
+	return current
+}
+
+func (b *builder) addCollectorNodes() {
+	for _, s := range b.snapshots {
+		collectorID := s.CollectorID
+		node := &models.TopologyGraphNode{
+			ID:          collectorID.String(),
+			CollectorID: &collectorID,
+			Name:        displayName(s),
+			Hostname:    s.Hostname,
+			Address:     s.Address,
+			Role:        models.TopologyRolePrimary,
+			Subscriber:  len(s.Subscriptions) > 0,
+		}
+		if s.WalReceiver != nil {
+			node.Role = models.TopologyRoleStandby
+			node.UpstreamHost = s.WalReceiver.SenderHost
+			node.UpstreamPort = s.WalReceiver.SenderPort
+		} else if s.State != nil && s.State.InRecovery {
+			node.Role = models.TopologyRoleStandby
+			b.addFinding(fmt.Sprintf("%s is in recovery without a WAL receiver", node.Name))
+		}
+		b.nodes[node.ID] = node
+		b.topology.Nodes = append(b.topology.Nodes, node)
+
+		for _, key := range hostKeys(s.Hostname, s.Address) {
+			b.byHost[key] = append(b.byHost[key], s)
+		}
+	}
+}
+
+// resolveUpstreams finds the collector each standby's WAL receiver connects
+// to. When several collectors share the host, the one whose walsenders list
+// the standby wins.
+func (b *builder) resolveUpstreams() {
+	for _, s := range b.snapshots {
+		if s.WalReceiver == nil || s.WalReceiver.SenderHost == "" {
+			continue
+		}
+
+		var candidates []*models.ReplicationNodeSnapshot
+		for _, c := range b.lookupHost(s.WalReceiver.SenderHost) {
+			if c.CollectorID == s.CollectorID {
+				continue
+			}
+			if port := addressPort(c.Address); port != 0 && s.WalReceiver.SenderPort != 0 && port != s.WalReceiver.SenderPort {
+				continue
+			}
+			candidates = append(candidates, c)
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		chosen := candidates[0]
+		for _, c := range candidates {
+			if b.sendsTo(c, s) {
+				chosen = c
+				break
+			}
+		}
+		b.upstream[s.CollectorID] = chosen
+		b.nodes[s.CollectorID.String()].UpstreamCollectorID = &chosen.CollectorID
+	}
+}
+
+// sendsTo reports whether one of the upstream's walsenders serves the standby
+func (b *builder) sendsTo(upstream, standby *models.ReplicationNodeSnapshot) bool {
+	appName := receiverApplicationName(standby.WalReceiver)
+	for _, r := range upstream.Senders {
+		if (appName != "" && r.ApplicationName == appName) || b.hostMatches(standby, r.ClientAddr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *builder) addSenderEdges() {
+	for _, u := range b.snapshots {
+		for _, r := range u.Senders {
+			if isTablesyncWorker(r.ApplicationName) {
+				continue // transient initial copy of a subscription
+			}
+
+			edge := &models.TopologyEdge{
+				From:            u.CollectorID.String(),
+				Type:            models.TopologyEdgePhysical,
+				ApplicationName: r.ApplicationName,
+				ClientAddr:      r.ClientAddr,
+				State:           r.State,
+				SyncState:       r.SyncState,
+				WriteLagMs:      r.WriteLagMs,
+				FlushLagMs:      r.FlushLagMs,
+				ReplayLagMs:     r.ReplayLagMs,
+				BehindByMb:      r.BehindByMb,
+			}
+
+			if d := b.findSubscriber(u, r); d != nil {
+				edge.To = d.CollectorID.String()
+				edge.Type = models.TopologyEdgeLogical
+				edge.MatchedBy = "subscription"
+			} else if d, matchedBy := b.findStandby(u, r); d != nil {
+				edge.To = d.CollectorID.String()
+				edge.MatchedBy = matchedBy
+				edge.SlotName = d.WalReceiver.SlotName
+				if b.upstream[d.CollectorID] == nil {
+					b.upstream[d.CollectorID] = u
+					b.nodes[edge.To].UpstreamCollectorID = &u.CollectorID
+				}
+			} else {
+				peer := r.ClientAddr
+				if peer == "" {
+					peer = r.ApplicationName
+				}
+				edge.To = b.externalNode(peer, r.ApplicationName).ID
+				b.addFinding(fmt.Sprintf("%s streams to %s (%s), which has no collector", b.nodes[edge.From].Name, peerLabel(r.ClientAddr), r.ApplicationName))
+			}
+
+			if r.State != "" && r.State != "streaming" {
+				b.addFinding(fmt.Sprintf("Replication from %s to %s is %s", b.nodes[edge.From].Name, b.nodes[edge.To].Name, r.State))
+			}
+			b.addEdge(edge)
+		}
+	}
+}
+
+// findSubscriber returns the collector with a subscription named after the
+// walsender's application_name, preferring one at its client address
+func (b *builder) findSubscriber(u *models.ReplicationNodeSnapshot, r *models.ReplicationStatus) *models.ReplicationNodeSnapshot {
+	if r.ApplicationName == "" {
+		return nil
+	}
+	var found *models.ReplicationNodeSnapshot
+	for _, s := range b.snapshots {
+		if s.CollectorID == u.CollectorID {
+			continue
+		}
+		for _, sub := range s.Subscriptions {
+			if sub.SubName != r.ApplicationName {
+				continue
+			}
+			if b.hostMatches(s, r.ClientAddr) {
+				return s
+			}
+			if found == nil {
+				found = s
+			}
+		}
+	}
+	return found
+}
+
+// findStandby returns the standby a physical walsender serves: first among the
+// standbys whose WAL receiver resolved to the upstream, then any standby at
+// the client address
+func (b *builder) findStandby(u *models.ReplicationNodeSnapshot, r *models.ReplicationStatus) (*models.ReplicationNodeSnapshot, string) {
+	for _, s := range b.snapshots {
+		if s.CollectorID == u.CollectorID || s.WalReceiver == nil || b.upstream[s.CollectorID] != u {
+			continue
+		}
+		if appName := receiverApplicationName(s.WalReceiver); appName != "" && appName == r.ApplicationName {
+			return s, "application_name"
+		}
+	}
+	for _, s := range b.snapshots {
+		if s.CollectorID == u.CollectorID || s.WalReceiver == nil {
+			continue
+		}
+		if up := b.upstream[s.CollectorID]; up != nil && up != u {
+			continue
+		}
+		if b.hostMatches(s, r.ClientAddr) {
+			return s, "client_addr"
+		}
+	}
+	return nil, ""
+}
+
+// addReceiverEdges adds the standbys whose upstream did not list them, and an
+// external upstream for standbys of hosts without a collector
+func (b *builder) addReceiverEdges() {
+	for _, s := range b.snapshots {
+		if s.WalReceiver == nil {
+			continue
+		}
+		to := s.CollectorID.String()
+
+		if u := b.upstream[s.CollectorID]; u != nil {
+			if b.edges[[2]string{u.CollectorID.String(), to}] != nil {
+				continue
+			}
+			b.addEdge(&models.TopologyEdge{
+				From:            u.CollectorID.String(),
+				To:              to,
+				Type:            models.TopologyEdgePhysical,
+				ApplicationName: receiverApplicationName(s.WalReceiver),
+				State:           s.WalReceiver.Status,
+				SlotName:        s.WalReceiver.SlotName,
+				MatchedBy:       "sender_host",
+			})
+			continue
+		}
+
+		peer := s.WalReceiver.SenderHost
+		if peer == "" {
+			peer = "unknown upstream"
+		}
+		if s.WalReceiver.SenderPort != 0 {
+			peer = net.JoinHostPort(peer, strconv.Itoa(s.WalReceiver.SenderPort))
+		}
+		upstream := b.externalNode(peer, peer)
+		b.addEdge(&models.TopologyEdge{
+			From:     upstream.ID,
+			To:       to,
+			Type:     models.TopologyEdgePhysical,
+			State:    s.WalReceiver.Status,
+			SlotName: s.WalReceiver.SlotName,
+		})
+		b.addFinding(fmt.Sprintf("%s replicates from %s, which has no collector", b.nodes[to].Name, peer))
+	}
+}
+
+func (b *builder) assignRoles() {
+	for _, e := range b.topology.Edges {
+		from := b.nodes[e.From]
+		switch e.Type {
+		case models.TopologyEdgeLogical:
+			from.Publisher = true
+		case models.TopologyEdgePhysical:
+			if from.Role == models.TopologyRoleStandby {
+				from.Role = models.TopologyRoleCascadingStandby
+			}
+		}
+	}
+
+	for _, s := range b.snapshots {
+		for _, sub := range s.Subscriptions {
+			found := false
+			for _, e := range b.topology.Edges {
+				if e.Type == models.TopologyEdgeLogical && e.To == s.CollectorID.String() && e.ApplicationName == sub.SubName {
+					found = true
+					break
+				}
+			}
+			if !found {
+				b.addFinding(fmt.Sprintf("Subscription %q on %s has no walsender on a monitored publisher", sub.SubName, displayName(s)))
+			}
+		}
+	}
+}
+
+// buildClusters walks the physical edges breadth first from every node
+// without a physical upstream
+func (b *builder) buildClusters() {
+	children := make(map[string][]*models.TopologyEdge)
+	hasUpstream := make(map[string]bool)
+	for _, e := range b.topology.Edges {
+		if e.Type != models.TopologyEdgePhysical {
+			continue
+		}
+		children[e.From] = append(children[e.From], e)
+		hasUpstream[e.To] = true
+	}
+
+	ordered := make([]*models.TopologyGraphNode, len(b.topology.Nodes))
+	copy(ordered, b.topology.Nodes)
+	sort.SliceStable(ordered, func(i, j int) bool { return nodeLess(ordered[i], ordered[j]) })
+
+	visited := make(map[string]bool)
+	walk := func(root *models.TopologyGraphNode) {
+		cluster := &models.TopologyCluster{RootID: root.ID, NodeIDs: []string{}}
+		root.Depth = 0
+		queue := []*models.TopologyGraphNode{root}
+		visited[root.ID] = true
+		for len(queue) > 0 {
+			n := queue[0]
+			queue = queue[1:]
+			n.ClusterID = root.ID
+			cluster.NodeIDs = append(cluster.NodeIDs, n.ID)
+
+			edges := children[n.ID]
+			sort.SliceStable(edges, func(i, j int) bool { return nodeLess(b.nodes[edges[i].To], b.nodes[edges[j].To]) })
+			for _, e := range edges {
+				if e.SyncState == "sync" || e.SyncState == "quorum" {
+					cluster.SyncStandbys++
+				}
+				if e.ReplayLagMs > cluster.MaxReplayLagMs {
+					cluster.MaxReplayLagMs = e.ReplayLagMs
+				}
+				child := b.nodes[e.To]
+				if visited[child.ID] {
+					continue
+				}
+				visited[child.ID] = true
+				child.Depth = n.Depth + 1
+				queue = append(queue, child)
+			}
+		}
+		b.topology.Clusters = append(b.topology.Clusters, cluster)
+	}
+
+	for _, n := range ordered {
+		if !hasUpstream[n.ID] && !visited[n.ID] {
+			walk(n)
+		}
+	}
+	// Nodes left are in a replication cycle, which a stale snapshot can show
+	for _, n := range ordered {
+		if !visited[n.ID] {
+			b.addFinding(fmt.Sprintf("%s is in a replication cycle; the snapshots of its cluster disagree", n.Name))
+			walk(n)
+		}
+	}
+}
+
+func (b *builder) sortResult() {
+	clusterIndex := make(map[string]int)
+	for i, c := range b.topology.Clusters {
+		clusterIndex[c.RootID] = i
+	}
+	sort.SliceStable(b.topology.Nodes, func(i, j int) bool {
+		a, c := b.topology.Nodes[i], b.topology.Nodes[j]
+		if clusterIndex[a.ClusterID] != clusterIndex[c.ClusterID] {
+			return clusterIndex[a.ClusterID] < clusterIndex[c.ClusterID]
+		}
+		if a.Depth != c.Depth {
+			return a.Depth < c.Depth
+		}
+		return nodeLess(a, c)
+	})
+	sort.SliceStable(b.topology.Edges, func(i, j int) bool {
+		a, c := b.topology.Edges[i], b.topology.Edges[j]
+		if a.From != c.From {
+			return nodeLess(b.nodes[a.From], b.nodes[c.From])
+		}
+		return nodeLess(b.nodes[a.To], b.nodes[c.To])
+	})
+}
+
+func (b *builder) addEdge(e *models.TopologyEdge) {
+	key := [2]string{e.From, e.To}
+	if existing := b.edges[key]; existing != nil && existing.Type == e.Type {
+		return // a standby reconnecting can briefly have two walsenders
+	}
+	b.edges[key] = e
+	b.topology.Edges = append(b.topology.Edges, e)
+}
+
+func (b *builder) addFinding(finding string) {
+	for _, f := range b.topology.Findings {
+		if f == finding {
+			return
+		}
+	}
+	b.topology.Findings = append(b.topology.Findings, finding)
+}
+
+// externalNode returns the node of a peer without a collector
+func (b *builder) externalNode(peer, name string) *models.TopologyGraphNode {
+	id := externalPrefix + peer
+	if n := b.nodes[id]; n != nil {
+		return n
+	}
+	if name == "" {
+		name = peer
+	}
+	n := &models.TopologyGraphNode{
+		ID:      id,
+		Name:    name,
+		Address: peer,
+		Role:    models.TopologyRoleExternal,
+	}
+	b.nodes[id] = n
+	b.topology.Nodes = append(b.topology.Nodes, n)
+	return n
+}
+
+// lookupHost returns the collectors at a host, by full or short name
+func (b *builder) lookupHost(host string) []*models.ReplicationNodeSnapshot {
+	var found []*models.ReplicationNodeSnapshot
+	seen := make(map[uuid.UUID]bool)
+	for _, key := range hostKeys(host, "") {
+		for _, s := range b.byHost[key] {
+			if !seen[s.CollectorID] {
+				seen[s.CollectorID] = true
+				found = append(found, s)
+			}
+		}
+	}
+	return found
+}
+
+func (b *builder) hostMatches(s *models.ReplicationNodeSnapshot, host string) bool {
+	if normalizeHost(host) == "" {
+		return false
+	}
+	for _, c := range b.lookupHost(host) {
+		if c.CollectorID == s.CollectorID {
+			return true
+		}
+	}
+	return false
+}
+
+// hostKeys returns the lookup keys of a hostname and an address: the
+// normalized names and, for DNS names, their first label
+func hostKeys(hostname, address string) []string {
+	var keys []string
+	add := func(h string) {
+		h = normalizeHost(h)
+		if h == "" {
+			return
+		}
+		keys = append(keys, h)
+		if net.ParseIP(h) == nil {
+			if i := strings.IndexByte(h, '.'); i > 0 {
+				keys = append(keys, h[:i])
+			}
+		}
+	}
+	add(hostname)
+	if address != "" {
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			add(host)
+		} else {
+			add(address)
+		}
+	}
+	return keys
+}
+
+// normalizeHost lowercases a host and strips a trailing dot, IPv6 brackets
+// and the prefix length of an inet
+func normalizeHost(h string) string {
+	h = strings.ToLower(strings.TrimSpace(h))
+	if i := strings.IndexByte(h, '/'); i >= 0 {
+		h = h[:i]
+	}
+	h = strings.TrimSuffix(strings.TrimPrefix(h, "["), "]")
+	return strings.TrimSuffix(h, ".")
+}
+
+// addressPort returns the port of a host:port address, or 0
+func addressPort(address string) int {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return 0
+	}
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+// receiverApplicationName returns the application_name a WAL receiver
+// connects with, which is the walsender's application_name upstream
+func receiverApplicationName(w *models.WalReceiver) string {
+	if w == nil {
+		return ""
+	}
+	return conninfoValue(w.ConnInfo, "application_name")
+}
+
+// conninfoValue returns a keyword's value in a libpq key=value connection
+// string, with single-quoted values and backslash escapes
+func conninfoValue(conninfo, keyword string) string {
+	s := conninfo
+	for {
+		s = strings.TrimLeft(s, " \t\n")
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 {
+			return ""
+		}
+		key := strings.TrimSpace(s[:eq])
+		s = strings.TrimLeft(s[eq+1:], " \t\n")
+
+		var value strings.Builder
+		if strings.HasPrefix(s, "'") {
+			i := 1
+			for ; i < len(s) && s[i] != '\''; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			s = s[min(i+1, len(s)):]
+		} else {
+			i := 0
+			for ; i < len(s) && s[i] != ' ' && s[i] != '\t' && s[i] != '\n'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				value.WriteByte(s[i])
+			}
+			s = s[i:]
+		}
+
+		if key == keyword {
+			return value.String()
+		}
+	}
+}
+
+// isTablesyncWorker reports whether an application_name is that of a
+// logical replication table synchronization worker (pg_<oid>_sync_<relid>_<sysid>)
+func isTablesyncWorker(applicationName string) bool {
+	return strings.HasPrefix(applicationName, "pg_") && strings.Contains(applicationName, "_sync_")
+}
+
+func displayName(s *models.ReplicationNodeSnapshot) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	if s.Hostname != "" {
+		return s.Hostname
+	}
+	return s.CollectorID.String()
+}
+
+func peerLabel(clientAddr string) string {
+	if clientAddr == "" {
+		return "a local socket"
+	}
+	return clientAddr
+}
+
+func nodeLess(a, b *models.TopologyGraphNode) bool {
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.ID < b.ID
+}
@@ -0,0 +1,307 @@
+package replication_topology
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockTopologyStore is a mock implementation for testing
+type mockTopologyStore struct {
+	nodes []*models.ReplicationNodeSnapshot
+	err   error
+
+	lastFrom, lastTo time.Time
+}
+
+func (m *mockTopologyStore) GetReplicationNodes(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.ReplicationNodeSnapshot, error) {
+	m.lastFrom, m.lastTo = from, to
+	return m.nodes, m.err
+}
+
+func snapshot(name, hostname, address string) *models.ReplicationNodeSnapshot {
+	return &models.ReplicationNodeSnapshot{
+		CollectorID:   uuid.New(),
+		Name:          name,
+		Hostname:      hostname,
+		Address:       address,
+		Senders:       []*models.ReplicationStatus{},
+		Subscriptions: []*models.LogicalSubscription{},
+	}
+}
+
+// testFleet is a primary with a sync standby, a cascading standby behind it,
+// a logical subscriber, an unmonitored backup client, and a standby of a host
+// without a collector
+type testFleet struct {
+	primary, standby, cascade, subscriber, orphan *models.ReplicationNodeSnapshot
+}
+
+func newTestFleet() *testFleet {
+	f := &testFleet{
+		primary:    snapshot("orders-primary", "db1.example.com", "10.0.0.1:5432"),
+		standby:    snapshot("orders-standby", "db2.example.com", "10.0.0.2"),
+		cascade:    snapshot("orders-reporting", "db3.example.com", "10.0.0.3"),
+		subscriber: snapshot("analytics", "analytics.example.com", "10.0.1.5"),
+		orphan:     snapshot("legacy-standby", "legacy2.example.com", ""),
+	}
+
+	f.primary.Senders = []*models.ReplicationStatus{
+		{ApplicationName: "orders_standby", ClientAddr: "10.0.0.2", State: "streaming", SyncState: "sync", ReplayLagMs: 12},
+		{ApplicationName: "sub_orders", ClientAddr: "10.0.1.5", State: "streaming", SyncState: "async", ReplayLagMs: 40},
+		{ApplicationName: "pg_16390_sync_16384_7312345678901234567", ClientAddr: "10.0.1.5", State: "startup"},
+		{ApplicationName: "pg_basebackup", ClientAddr: "10.9.9.9", State: "backup"},
+	}
+	f.standby.WalReceiver = &models.WalReceiver{
+		Status: "streaming", SenderHost: "10.0.0.1", SenderPort: 5432, SlotName: "standby_slot",
+		ConnInfo: "user=replicator password=******** host=10.0.0.1 port=5432 application_name='orders_standby' sslmode=prefer",
+	}
+	f.standby.Senders = []*models.ReplicationStatus{
+		{ApplicationName: "walreceiver", ClientAddr: "10.0.0.3", State: "streaming", SyncState: "async", ReplayLagMs: 250},
+	}
+	f.cascade.WalReceiver = &models.WalReceiver{Status: "streaming", SenderHost: "db2", SenderPort: 5432}
+	f.subscriber.Subscriptions = []*models.LogicalSubscription{{SubName: "sub_orders", SubState: "ready"}}
+	f.orphan.WalReceiver = &models.WalReceiver{Status: "streaming", SenderHost: "legacy1.example.com", SenderPort: 5433}
+
+	return f
+}
+
+func (f *testFleet) snapshots() []*models.ReplicationNodeSnapshot {
+	return []*models.ReplicationNodeSnapshot{f.subscriber, f.cascade, f.orphan, f.primary, f.standby}
+}
+
+func findNode(t *testing.T, topology *models.FleetTopology, id string) *models.TopologyGraphNode {
+	for _, n := range topology.Nodes {
+		if n.ID == id {
+			return n
+		}
+	}
+	require.Failf(t, "node not found", "%s", id)
+	return nil
+}
+
+func findEdge(t *testing.T, topology *models.FleetTopology, from, to string) *models.TopologyEdge {
+	for _, e := range topology.Edges {
+		if e.From == from && e.To == to {
+			return e
+		}
+	}
+	require.Failf(t, "edge not found", "%s -> %s", from, to)
+	return nil
+}
+
+// TestBuild_ResolvesFleet resolves upstreams and downstreams across collectors
+func TestBuild_ResolvesFleet(t *testing.T) {
+	f := newTestFleet()
+	topology := Build(uuid.New(), f.snapshots(), time.Now())
+
+	primary := findNode(t, topology, f.primary.CollectorID.String())
+	assert.Equal(t, models.TopologyRolePrimary, primary.Role)
+	assert.True(t, primary.Publisher)
+	assert.Nil(t, primary.UpstreamCollectorID)
+
+	standby := findNode(t, topology, f.standby.CollectorID.String())
+	assert.Equal(t, models.TopologyRoleCascadingStandby, standby.Role)
+	require.NotNil(t, standby.UpstreamCollectorID)
+	assert.Equal(t, f.primary.CollectorID, *standby.UpstreamCollectorID)
+	assert.Equal(t, 1, standby.Depth)
+
+	cascade := findNode(t, topology, f.cascade.CollectorID.String())
+	assert.Equal(t, models.TopologyRoleStandby, cascade.Role)
+	require.NotNil(t, cascade.UpstreamCollectorID, "resolved by short hostname")
+	assert.Equal(t, f.standby.CollectorID, *cascade.UpstreamCollectorID)
+	assert.Equal(t, 2, cascade.Depth)
+	assert.Equal(t, primary.ID, cascade.ClusterID)
+
+	subscriber := findNode(t, topology, f.subscriber.CollectorID.String())
+	assert.Equal(t, models.TopologyRolePrimary, subscriber.Role)
+	assert.True(t, subscriber.Subscriber)
+	assert.Equal(t, subscriber.ID, subscriber.ClusterID, "logical subscribers root their own cluster")
+
+	sync := findEdge(t, topology, primary.ID, standby.ID)
+	assert.Equal(t, models.TopologyEdgePhysical, sync.Type)
+	assert.Equal(t, "application_name", sync.MatchedBy)
+	assert.Equal(t, "sync", sync.SyncState)
+	assert.Equal(t, "standby_slot", sync.SlotName)
+
+	assert.Equal(t, "client_addr", findEdge(t, topology, standby.ID, cascade.ID).MatchedBy)
+
+	logical := findEdge(t, topology, primary.ID, subscriber.ID)
+	assert.Equal(t, models.TopologyEdgeLogical, logical.Type)
+	assert.Equal(t, "subscription", logical.MatchedBy)
+
+	backup := findNode(t, topology, "external:10.9.9.9")
+	assert.Equal(t, models.TopologyRoleExternal, backup.Role)
+	assert.Equal(t, "pg_basebackup", backup.Name)
+
+	legacy := findNode(t, topology, "external:legacy1.example.com:5433")
+	orphan := findNode(t, topology, f.orphan.CollectorID.String())
+	assert.Equal(t, legacy.ID, orphan.ClusterID)
+	assert.Nil(t, orphan.UpstreamCollectorID)
+	assert.Equal(t, "legacy1.example.com", orphan.UpstreamHost)
+
+	assert.Len(t, topology.Edges, 5, "tablesync workers are not edges")
+	assert.Len(t, topology.Nodes, 7)
+
+	var primaryCluster *models.TopologyCluster
+	for _, c := range topology.Clusters {
+		if c.RootID == primary.ID {
+			primaryCluster = c
+		}
+	}
+	require.NotNil(t, primaryCluster)
+	assert.Equal(t, []string{primary.ID, standby.ID, backup.ID, cascade.ID}, primaryCluster.NodeIDs)
+	assert.Equal(t, 1, primaryCluster.SyncStandbys)
+	assert.Equal(t, int64(250), primaryCluster.MaxReplayLagMs)
+
+	assert.Contains(t, topology.Findings, "legacy-standby replicates from legacy1.example.com:5433, which has no collector")
+	assert.Contains(t, topology.Findings, "Replication from orders-primary to pg_basebackup is backup")
+}
+
+// TestBuild_ReceiverOnly links a standby whose upstream reported no walsenders
+func TestBuild_ReceiverOnly(t *testing.T) {
+	primary := snapshot("primary", "db1", "")
+	standby := snapshot("standby", "db2", "")
+	standby.WalReceiver = &models.WalReceiver{Status: "streaming", SenderHost: "DB1.", ConnInfo: "application_name=db2"}
+
+	topology := Build(uuid.New(), []*models.ReplicationNodeSnapshot{primary, standby}, time.Now())
+
+	edge := findEdge(t, topology, primary.CollectorID.String(), standby.CollectorID.String())
+	assert.Equal(t, "sender_host", edge.MatchedBy)
+	assert.Equal(t, "db2", edge.ApplicationName)
+	require.Len(t, topology.Clusters, 1)
+	assert.Empty(t, topology.Findings)
+}
+
+// TestBuild_PromotedStandby drops the WAL receiver and walsenders a node
+// reported before its latest state: the standby was promoted, so the old
+// primary is a cluster of its own
+func TestBuild_PromotedStandby(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	f := newTestFleet()
+	f.standby.WalReceiver.Time = at.Add(-5 * time.Minute)
+	for _, r := range f.standby.Senders {
+		r.Timestamp = at.Add(-5 * time.Minute)
+	}
+	f.standby.State = &models.NodeState{Time: at.Add(-time.Minute), CollectorID: f.standby.CollectorID, TimelineID: 2}
+
+	topology := Build(uuid.New(), f.snapshots(), at)
+
+	standby := findNode(t, topology, f.standby.CollectorID.String())
+	assert.Equal(t, models.TopologyRolePrimary, standby.Role)
+	assert.Nil(t, standby.UpstreamCollectorID)
+	assert.Equal(t, standby.ID, standby.ClusterID)
+	assert.NotNil(t, f.standby.WalReceiver, "snapshots are not modified")
+
+	// The old primary still lists the standby, but the standby no longer
+	// replicates from it
+	primary := findNode(t, topology, f.primary.CollectorID.String())
+	assert.Equal(t, primary.ID, primary.ClusterID)
+}
+
+// TestBuild_StandbyWithoutReceiver keeps a node in recovery a standby
+func TestBuild_StandbyWithoutReceiver(t *testing.T) {
+	standby := snapshot("standby", "db2", "")
+	standby.State = &models.NodeState{Time: time.Now(), CollectorID: standby.CollectorID, InRecovery: true}
+
+	topology := Build(uuid.New(), []*models.ReplicationNodeSnapshot{standby}, time.Now())
+	assert.Equal(t, models.TopologyRoleStandby, findNode(t, topology, standby.CollectorID.String()).Role)
+	assert.Equal(t, []string{"standby is in recovery without a WAL receiver"}, topology.Findings)
+}
+
+// TestClusterOf keeps the nodes and edges of one physical cluster
+func TestClusterOf(t *testing.T) {
+	f := newTestFleet()
+	fleet := Build(uuid.New(), f.snapshots(), time.Now())
+
+	cluster := ClusterOf(fleet, f.cascade.CollectorID)
+	require.NotNil(t, cluster)
+	require.Len(t, cluster.Clusters, 1)
+	assert.Equal(t, f.primary.CollectorID.String(), cluster.Clusters[0].RootID)
+	ids := make([]string, 0, len(cluster.Nodes))
+	for _, n := range cluster.Nodes {
+		ids = append(ids, n.ID)
+	}
+	assert.ElementsMatch(t, cluster.Clusters[0].NodeIDs, ids)
+	assert.NotContains(t, ids, f.subscriber.CollectorID.String())
+	for _, e := range cluster.Edges {
+		assert.Equal(t, models.TopologyEdgePhysical, e.Type)
+	}
+
+	assert.Nil(t, ClusterOf(fleet, uuid.New()))
+}
+
+// TestBuild_UnmatchedSubscription reports subscriptions without a publisher
+func TestBuild_UnmatchedSubscription(t *testing.T) {
+	subscriber := snapshot("analytics", "analytics", "")
+	subscriber.Subscriptions = []*models.LogicalSubscription{{SubName: "sub_billing"}}
+
+	topology := Build(uuid.New(), []*models.ReplicationNodeSnapshot{subscriber}, time.Now())
+	assert.Equal(t, []string{`Subscription "sub_billing" on analytics has no walsender on a monitored publisher`}, topology.Findings)
+	assert.Empty(t, topology.Edges)
+}
+
+// TestBuild_Empty returns empty slices
+func TestBuild_Empty(t *testing.T) {
+	topology := Build(uuid.New(), nil, time.Now())
+	assert.NotNil(t, topology.Nodes)
+	assert.NotNil(t, topology.Edges)
+	assert.NotNil(t, topology.Clusters)
+	assert.NotNil(t, topology.Findings)
+}
+
+// TestConninfoValue parses libpq connection strings
+func TestConninfoValue(t *testing.T) {
+	conninfo := `host=10.0.0.1 application_name = 'orders \'east\'' port=5432 options=-c\ x=1`
+	assert.Equal(t, "orders 'east'", conninfoValue(conninfo, "application_name"))
+	assert.Equal(t, "5432", conninfoValue(conninfo, "port"))
+	assert.Equal(t, "-c x=1", conninfoValue(conninfo, "options"))
+	assert.Equal(t, "", conninfoValue(conninfo, "dbname"))
+	assert.Equal(t, "", conninfoValue("", "host"))
+}
+
+// TestGetFleetTopology reads the freshness window and derives node topologies
+func TestGetFleetTopology(t *testing.T) {
+	f := newTestFleet()
+	store := &mockTopologyStore{nodes: f.snapshots()}
+	service := NewService(store, zap.NewNop())
+
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	fleet, err := service.GetFleetTopology(context.Background(), uuid.New(), at)
+	require.NoError(t, err)
+	assert.Equal(t, at.Add(-Freshness), store.lastFrom)
+	assert.Equal(t, at, store.lastTo)
+
+	node := NodeTopology(fleet, f.standby.CollectorID)
+	require.NotNil(t, node)
+	assert.Equal(t, models.TopologyRoleCascadingStandby, node.NodeRole)
+	assert.Equal(t, f.primary.CollectorID, *node.UpstreamCollectorID)
+	require.Equal(t, 1, node.DownstreamCount)
+	assert.Equal(t, f.cascade.CollectorID, node.DownstreamNodes[0].CollectorID)
+
+	assert.Nil(t, NodeTopology(fleet, uuid.New()))
+
+	store.err = errors.New("connection refused")
+	_, err = service.GetFleetTopology(context.Background(), uuid.New(), at)
+	assert.Error(t, err)
+}
+
+// TestRenderDOT renders clusters, roles and edge styles
+func TestRenderDOT(t *testing.T) {
+	f := newTestFleet()
+	dot := RenderDOT(Build(uuid.New(), f.snapshots(), time.Now()))
+
+	assert.Contains(t, dot, "digraph replication {")
+	assert.Contains(t, dot, `subgraph "cluster_0"`)
+	assert.Contains(t, dot, `"`+f.primary.CollectorID.String()+`" [label="orders-primary\nprimary, publisher", penwidth=2];`)
+	assert.Contains(t, dot, `"`+f.primary.CollectorID.String()+`" -> "`+f.standby.CollectorID.String()+`" [label="sync\nreplay lag 12 ms", penwidth=2];`)
+	assert.Contains(t, dot, `[label="logical sub_orders\nreplay lag 40 ms", style=dashed];`)
+	assert.Contains(t, dot, `"external:10.9.9.9" [label="pg_basebackup\nexternal", style="rounded,dotted"];`)
+}
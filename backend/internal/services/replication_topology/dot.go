@@ -0,0 +1,108 @@
+package replication_topology
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// RenderDOT renders a topology as a Graphviz digraph with one subgraph per
+// physical cluster. Logical edges are dashed and unmonitored peers dotted.
+func RenderDOT(t *models.FleetTopology) string {
+	nodes := make(map[string]*models.TopologyGraphNode, len(t.Nodes))
+	for _, n := range t.Nodes {
+		nodes[n.ID] = n
+	}
+
+	var sb strings.Builder
+	sb.WriteString("digraph replication {\n")
+	sb.WriteString("  rankdir=TB;\n")
+	sb.WriteString("  node [shape=box, style=rounded, fontname=\"Helvetica\"];\n")
+	sb.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	for i, c := range t.Clusters {
+		root := nodes[c.RootID]
+		fmt.Fprintf(&sb, "\n  subgraph \"cluster_%d\" {\n", i)
+		fmt.Fprintf(&sb, "    label=%s;\n", dotLabel(root.Name))
+		for _, id := range c.NodeIDs {
+			n := nodes[id]
+			fmt.Fprintf(&sb, "    %s [label=%s%s];\n", dotQuote(n.ID), dotLabel(n.Name, nodeCaption(n)), nodeStyle(n))
+		}
+		sb.WriteString("  }\n")
+	}
+
+	if len(t.Edges) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, e := range t.Edges {
+		attrs := []string{"label=" + dotLabel(edgeCaption(e)...)}
+		if e.Type == models.TopologyEdgeLogical {
+			attrs = append(attrs, "style=dashed")
+		}
+		if e.SyncState == "sync" || e.SyncState == "quorum" {
+			attrs = append(attrs, "penwidth=2")
+		}
+		fmt.Fprintf(&sb, "  %s -> %s [%s];\n", dotQuote(e.From), dotQuote(e.To), strings.Join(attrs, ", "))
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func nodeCaption(n *models.TopologyGraphNode) string {
+	caption := strings.ReplaceAll(n.Role, "_", " ")
+	if n.Publisher {
+		caption += ", publisher"
+	}
+	if n.Subscriber {
+		caption += ", subscriber"
+	}
+	return caption
+}
+
+func nodeStyle(n *models.TopologyGraphNode) string {
+	switch n.Role {
+	case models.TopologyRolePrimary:
+		return ", penwidth=2"
+	case models.TopologyRoleExternal:
+		return ", style=\"rounded,dotted\""
+	default:
+		return ""
+	}
+}
+
+func edgeCaption(e *models.TopologyEdge) []string {
+	lines := []string{}
+	if e.Type == models.TopologyEdgeLogical {
+		lines = append(lines, "logical "+e.ApplicationName)
+	} else if e.SyncState != "" {
+		lines = append(lines, e.SyncState)
+	}
+	if e.State != "" && e.State != "streaming" {
+		lines = append(lines, e.State)
+	}
+	if e.ReplayLagMs > 0 {
+		lines = append(lines, fmt.Sprintf("replay lag %d ms", e.ReplayLagMs))
+	}
+	return lines
+}
+
+// dotLabel quotes lines as one label, centered line by line
+func dotLabel(lines ...string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = dotEscape(line)
+	}
+	return "\"" + strings.Join(escaped, "\\n") + "\""
+}
+
+func dotQuote(s string) string {
+	return "\"" + dotEscape(s) + "\""
+}
+
+func dotEscape(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	return strings.ReplaceAll(s, "\n", " ")
+}
@@ -0,0 +1,132 @@
+package replication_topology
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Freshness is how old the replication state of a collector can be and still
+// count: a standby whose last WAL receiver sample is older is not a standby
+const Freshness = 15 * time.Minute
+
+// Store interface for the replication state of a tenant's collectors
+type Store interface {
+	GetReplicationNodes(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.ReplicationNodeSnapshot, error)
+}
+
+// Service builds the replication topology of a tenant's fleet
+type Service struct {
+	store  Store
+	logger *zap.Logger
+}
+
+// NewService creates a new replication topology service
+func NewService(store Store, logger *zap.Logger) *Service {
+	return &Service{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// GetFleetTopology builds the replication graph of a tenant's collectors from
+// their latest state at or before at
+func (s *Service) GetFleetTopology(ctx context.Context, tenantID uuid.UUID, at time.Time) (*models.FleetTopology, error) {
+	snapshots, err := s.store.GetReplicationNodes(ctx, tenantID, at.Add(-Freshness), at)
+	if err != nil {
+		s.logger.Error("Failed to load replication nodes", zap.Error(err))
+		return nil, err
+	}
+
+	return Build(tenantID, snapshots, at), nil
+}
+
+// NodeTopology returns the topology of one collector of a fleet, or nil when
+// the collector is not part of it
+func NodeTopology(fleet *models.FleetTopology, collectorID uuid.UUID) *models.ReplicationTopology {
+	id := collectorID.String()
+	var node *models.TopologyGraphNode
+	for _, n := range fleet.Nodes {
+		if n.ID == id {
+			node = n
+			break
+		}
+	}
+	if node == nil {
+		return nil
+	}
+
+	topology := &models.ReplicationTopology{
+		CollectorID:         collectorID,
+		NodeRole:            node.Role,
+		UpstreamCollectorID: node.UpstreamCollectorID,
+		UpstreamHost:        node.UpstreamHost,
+		UpstreamPort:        node.UpstreamPort,
+	}
+	for _, e := range fleet.Edges {
+		if e.From != id {
+			continue
+		}
+		downstream := models.TopologyNode{
+			ApplicationName: e.ApplicationName,
+			ClientAddr:      e.ClientAddr,
+			State:           e.State,
+			SyncState:       e.SyncState,
+			ReplayLagMs:     e.ReplayLagMs,
+		}
+		if parsed, err := uuid.Parse(e.To); err == nil {
+			downstream.CollectorID = parsed
+		}
+		topology.DownstreamNodes = append(topology.DownstreamNodes, downstream)
+	}
+	topology.DownstreamCount = len(topology.DownstreamNodes)
+
+	return topology
+}
+
+// ClusterOf returns the physical cluster of a collector within a fleet: its
+// nodes and the edges among them. It is nil when the collector is not part
+// of the fleet.
+func ClusterOf(fleet *models.FleetTopology, collectorID uuid.UUID) *models.FleetTopology {
+	id := collectorID.String()
+	var cluster *models.TopologyCluster
+	for _, c := range fleet.Clusters {
+		for _, nodeID := range c.NodeIDs {
+			if nodeID == id {
+				cluster = c
+				break
+			}
+		}
+	}
+	if cluster == nil {
+		return nil
+	}
+
+	members := make(map[string]bool, len(cluster.NodeIDs))
+	for _, nodeID := range cluster.NodeIDs {
+		members[nodeID] = true
+	}
+
+	sub := &models.FleetTopology{
+		TenantID:    fleet.TenantID,
+		GeneratedAt: fleet.GeneratedAt,
+		Nodes:       []*models.TopologyGraphNode{},
+		Edges:       []*models.TopologyEdge{},
+		Clusters:    []*models.TopologyCluster{cluster},
+		Findings:    []string{},
+	}
+	for _, n := range fleet.Nodes {
+		if members[n.ID] {
+			sub.Nodes = append(sub.Nodes, n)
+		}
+	}
+	for _, e := range fleet.Edges {
+		if members[e.From] && members[e.To] {
+			sub.Edges = append(sub.Edges, e)
+		}
+	}
+	return sub
+}
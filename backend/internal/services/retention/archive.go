@@ -0,0 +1,191 @@
+package retention
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Archiver stores archived data under a slash-separated key
+type Archiver interface {
+	Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error
+}
+
+// DirArchiver archives into a local directory, e.g. a mounted volume
+type DirArchiver struct {
+	dir string
+}
+
+// NewDirArchiver creates an archiver writing below dir
+func NewDirArchiver(dir string) *DirArchiver {
+	return &DirArchiver{dir: dir}
+}
+
+// Put writes the archive next to its destination and renames it into place,
+// so that a partially written archive is never visible under its key
+func (a *DirArchiver) Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error {
+	path := filepath.Join(a.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("create archive directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".archive-*")
+	if err != nil {
+		return fmt.Errorf("create archive file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write archive: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("sync archive: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close archive: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename archive: %w", err)
+	}
+	return nil
+}
+
+// S3Config configures an S3-compatible archive target
+type S3Config struct {
+	Endpoint  string // e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	Prefix    string // Optional key prefix
+}
+
+// S3Archiver archives to an S3-compatible endpoint with path-style requests
+// signed with AWS Signature Version 4
+type S3Archiver struct {
+	config S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Archiver creates an archiver for an S3-compatible endpoint
+func NewS3Archiver(config S3Config) *S3Archiver {
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	return &S3Archiver{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Minute},
+		now:    time.Now,
+	}
+}
+
+// Put uploads an archive with a single PUT request
+func (a *S3Archiver) Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error {
+	if a.config.Prefix != "" {
+		key = strings.Trim(a.config.Prefix, "/") + "/" + key
+	}
+
+	payloadHash := sha256.New()
+	if _, err := io.Copy(payloadHash, body); err != nil {
+		return fmt.Errorf("hash archive: %w", err)
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind archive: %w", err)
+	}
+
+	path := "/" + s3Escape(a.config.Bucket) + "/" + s3Escape(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, a.config.Endpoint+path, io.NopCloser(body))
+	if err != nil {
+		return fmt.Errorf("create archive request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/gzip")
+	a.sign(req, path, hex.EncodeToString(payloadHash.Sum(nil)))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload archive: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload archive: %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// sign adds the Signature Version 4 authorization of a request without a
+// query string
+func (a *S3Archiver) sign(req *http.Request, path, payloadHash string) {
+	now := a.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "content-type;host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		"",
+		"content-type:" + req.Header.Get("Content-Type"),
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + a.config.Region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+a.config.SecretKey), date)
+	key = hmacSHA256(key, a.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		a.config.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3Escape percent-encodes a key as Signature Version 4 requires: every
+// byte except unreserved characters and the slash separators
+func s3Escape(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
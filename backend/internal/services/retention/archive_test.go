@@ -0,0 +1,88 @@
+package retention
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDirArchiver_Put writes the archive under its key
+func TestDirArchiver_Put(t *testing.T) {
+	dir := t.TempDir()
+	archiver := NewDirArchiver(dir)
+
+	err := archiver.Put(context.Background(), "tenant/raw_logs/2026/03/01/a.ndjson.gz", strings.NewReader("data"), 4)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(dir, "tenant", "raw_logs", "2026", "03", "01", "a.ndjson.gz"))
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(content))
+
+	entries, err := os.ReadDir(filepath.Join(dir, "tenant", "raw_logs", "2026", "03", "01"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are removed")
+}
+
+// TestS3Archiver_Put uploads a signed path-style PUT
+func TestS3Archiver_Put(t *testing.T) {
+	var gotPath, gotAuth, gotBody, gotHash string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		gotHash = r.Header.Get("X-Amz-Content-Sha256")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	archiver := NewS3Archiver(S3Config{
+		Endpoint:  server.URL + "/",
+		Bucket:    "archive",
+		Region:    "eu-west-1",
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "secret",
+		Prefix:    "/pganalytics/",
+	})
+	archiver.now = func() time.Time { return time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	err := archiver.Put(context.Background(), "tenant/raw logs.ndjson.gz", strings.NewReader("data"), 4)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/archive/pganalytics/tenant/raw%20logs.ndjson.gz", gotPath)
+	assert.Equal(t, "data", gotBody)
+	assert.Equal(t, "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7", gotHash)
+	assert.True(t, strings.HasPrefix(gotAuth,
+		"AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20260301/eu-west-1/s3/aws4_request, "+
+			"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature="), gotAuth)
+}
+
+// TestS3Archiver_PutError reports the endpoint's error
+func TestS3Archiver_PutError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("<Error><Code>SignatureDoesNotMatch</Code></Error>"))
+	}))
+	defer server.Close()
+
+	archiver := NewS3Archiver(S3Config{Endpoint: server.URL, Bucket: "archive"})
+	err := archiver.Put(context.Background(), "key", strings.NewReader("data"), 4)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "SignatureDoesNotMatch")
+}
+
+// TestS3Escape encodes everything but unreserved characters and slashes
+func TestS3Escape(t *testing.T) {
+	assert.Equal(t, "a/b-c_d.e~f", s3Escape("a/b-c_d.e~f"))
+	assert.Equal(t, "a%20b%2Bc%3D%C3%A9", s3Escape("a b+c=é"))
+}
@@ -0,0 +1,264 @@
+package retention
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// DefaultMaxWindows bounds the days of expired data one policy removes per
+// run, so that the first run after enabling a policy on a large backlog does
+// not hold a transaction for hours
+const DefaultMaxWindows = 31
+
+// windowSize is the span of data removed per transaction
+const windowSize = 24 * time.Hour
+
+// ErrArchiveNotConfigured is returned for policies that archive when no
+// archive target is configured; their data is kept rather than lost
+var ErrArchiveNotConfigured = errors.New("retention archive target is not configured")
+
+// Store interface for retention policies and expiry of raw data
+type Store interface {
+	GetRetentionPolicies(ctx context.Context, tenantID uuid.UUID) ([]*models.RetentionPolicy, error)
+	ListRetentionPolicies(ctx context.Context) ([]*models.RetentionPolicy, error)
+	GetOldestRetainedTime(ctx context.Context, tenantID uuid.UUID, dataClass string, before time.Time) (*time.Time, error)
+	ExpireRetentionWindow(ctx context.Context, window models.RetentionWindow, archive func(writeRows func(w io.Writer) error) error) (int64, int64, error)
+	DeleteExpiredRollups(ctx context.Context, tenantID uuid.UUID, dataClass string, before time.Time) (int64, error)
+	RecordRetentionRun(ctx context.Context, run *models.RetentionRun) error
+}
+
+// Service applies retention policies: expired raw data is rolled up into
+// hourly summaries, optionally archived, then deleted
+type Service struct {
+	store      Store
+	archiver   Archiver
+	logger     *zap.Logger
+	maxWindows int
+	now        func() time.Time
+}
+
+// NewService creates a new retention service; archiver may be nil when no
+// archive target is configured
+func NewService(store Store, archiver Archiver, logger *zap.Logger) *Service {
+	return &Service{
+		store:      store,
+		archiver:   archiver,
+		logger:     logger,
+		maxWindows: DefaultMaxWindows,
+		now:        time.Now,
+	}
+}
+
+// ArchiveConfigured reports whether policies can archive expired data
+func (s *Service) ArchiveConfigured() bool {
+	return s.archiver != nil
+}
+
+// ApplyTenant applies a tenant's retention policies
+func (s *Service) ApplyTenant(ctx context.Context, tenantID uuid.UUID) (*models.RetentionRunResponse, error) {
+	policies, err := s.store.GetRetentionPolicies(ctx, tenantID)
+	if err != nil {
+		s.logger.Error("Failed to load retention policies", zap.Error(err))
+		return nil, err
+	}
+	return s.apply(ctx, tenantID, policies)
+}
+
+// ApplyAll applies the retention policies of every tenant. A failing tenant
+// does not stop the others; the first error is returned.
+func (s *Service) ApplyAll(ctx context.Context) ([]*models.RetentionRunResponse, error) {
+	policies, err := s.store.ListRetentionPolicies(ctx)
+	if err != nil {
+		s.logger.Error("Failed to load retention policies", zap.Error(err))
+		return nil, err
+	}
+
+	var tenants []uuid.UUID
+	byTenant := make(map[uuid.UUID][]*models.RetentionPolicy)
+	for _, policy := range policies {
+		if _, ok := byTenant[policy.TenantID]; !ok {
+			tenants = append(tenants, policy.TenantID)
+		}
+		byTenant[policy.TenantID] = append(byTenant[policy.TenantID], policy)
+	}
+
+	var responses []*models.RetentionRunResponse
+	var firstErr error
+	for _, tenantID := range tenants {
+		response, err := s.apply(ctx, tenantID, byTenant[tenantID])
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		responses = append(responses, response)
+	}
+	return responses, firstErr
+}
+
+func (s *Service) apply(ctx context.Context, tenantID uuid.UUID, policies []*models.RetentionPolicy) (*models.RetentionRunResponse, error) {
+	response := &models.RetentionRunResponse{
+		TenantID: tenantID,
+		Runs:     []*models.RetentionRun{},
+		Complete: true,
+	}
+
+	for _, policy := range policies {
+		if err := s.applyPolicy(ctx, policy, response); err != nil {
+			s.logger.Error("Failed to apply retention policy",
+				zap.String("tenant_id", tenantID.String()),
+				zap.String("data_class", policy.DataClass),
+				zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// applyPolicy expires a policy's data older than the cutoff one window at a
+// time, oldest first, so that an interrupted run resumes where it stopped
+func (s *Service) applyPolicy(ctx context.Context, policy *models.RetentionPolicy, response *models.RetentionRunResponse) error {
+	if policy.Archive && s.archiver == nil {
+		return ErrArchiveNotConfigured
+	}
+
+	now := s.now().UTC()
+	cutoff := now.Add(-time.Duration(policy.RetentionDays) * 24 * time.Hour).Truncate(time.Hour)
+
+	for windows := 0; ; windows++ {
+		oldest, err := s.store.GetOldestRetainedTime(ctx, policy.TenantID, policy.DataClass, cutoff)
+		if err != nil {
+			return err
+		}
+		if oldest == nil {
+			break
+		}
+		if windows == s.maxWindows {
+			response.Complete = false
+			break
+		}
+
+		from := oldest.UTC().Truncate(time.Hour)
+		to := from.Add(windowSize)
+		if to.After(cutoff) {
+			to = cutoff
+		}
+
+		run, err := s.expireWindow(ctx, models.RetentionWindow{
+			TenantID:  policy.TenantID,
+			DataClass: policy.DataClass,
+			From:      from,
+			To:        to,
+			Rollup:    policy.Rollup,
+		}, policy.Archive)
+		if err != nil {
+			return err
+		}
+
+		if err := s.store.RecordRetentionRun(ctx, run); err != nil {
+			return err
+		}
+		response.Runs = append(response.Runs, run)
+		response.RowsDeleted += run.RowsDeleted
+	}
+
+	if policy.Rollup && policy.RollupRetentionDays != nil {
+		before := now.Add(-time.Duration(*policy.RollupRetentionDays) * 24 * time.Hour).Truncate(time.Hour)
+		deleted, err := s.store.DeleteExpiredRollups(ctx, policy.TenantID, policy.DataClass, before)
+		if err != nil {
+			return err
+		}
+		response.RollupsDeleted += deleted
+	}
+
+	return nil
+}
+
+func (s *Service) expireWindow(ctx context.Context, window models.RetentionWindow, archive bool) (*models.RetentionRun, error) {
+	run := &models.RetentionRun{
+		TenantID:    window.TenantID,
+		DataClass:   window.DataClass,
+		WindowStart: window.From,
+		WindowEnd:   window.To,
+	}
+
+	var archiveFn func(writeRows func(w io.Writer) error) error
+	if archive {
+		key := ArchiveKey(window)
+		archiveFn = func(writeRows func(w io.Writer) error) error {
+			size, err := s.archive(ctx, key, writeRows)
+			if err != nil {
+				return err
+			}
+			run.ArchiveKey = &key
+			run.ArchiveBytes = &size
+			return nil
+		}
+	}
+
+	deleted, rollups, err := s.store.ExpireRetentionWindow(ctx, window, archiveFn)
+	if err != nil {
+		return nil, err
+	}
+	run.RowsDeleted = deleted
+	run.RollupsWritten = rollups
+
+	return run, nil
+}
+
+// archive compresses the rows into a temporary file and hands it to the
+// archiver, returning the compressed size
+func (s *Service) archive(ctx context.Context, key string, writeRows func(w io.Writer) error) (int64, error) {
+	f, err := os.CreateTemp("", "pganalytics-retention-*.ndjson.gz")
+	if err != nil {
+		return 0, fmt.Errorf("create archive file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}()
+
+	gz := gzip.NewWriter(f)
+	if err := writeRows(gz); err != nil {
+		return 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, fmt.Errorf("compress archive: %w", err)
+	}
+
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, fmt.Errorf("size archive: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("rewind archive: %w", err)
+	}
+
+	if err := s.archiver.Put(ctx, key, f, size); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// ArchiveKey names the archive of a window, e.g.
+// <tenant>/raw_logs/2026/01/31/20260131T000000Z_20260201T000000Z.ndjson.gz
+func ArchiveKey(window models.RetentionWindow) string {
+	const stamp = "20060102T150405Z"
+	return fmt.Sprintf("%s/%s/%s/%s_%s.ndjson.gz",
+		window.TenantID, window.DataClass, window.From.UTC().Format("2006/01/02"),
+		window.From.UTC().Format(stamp), window.To.UTC().Format(stamp))
+}
@@ -0,0 +1,322 @@
+package retention
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockRetentionStore keeps the row times of each data class in memory
+type mockRetentionStore struct {
+	policies []*models.RetentionPolicy
+	rows     map[string][]time.Time
+	err      error
+
+	windows       []models.RetentionWindow
+	runs          []*models.RetentionRun
+	rollupsBefore map[string]time.Time
+}
+
+func (m *mockRetentionStore) GetRetentionPolicies(ctx context.Context, tenantID uuid.UUID) ([]*models.RetentionPolicy, error) {
+	var policies []*models.RetentionPolicy
+	for _, p := range m.policies {
+		if p.TenantID == tenantID {
+			policies = append(policies, p)
+		}
+	}
+	return policies, m.err
+}
+
+func (m *mockRetentionStore) ListRetentionPolicies(ctx context.Context) ([]*models.RetentionPolicy, error) {
+	return m.policies, m.err
+}
+
+func (m *mockRetentionStore) GetOldestRetainedTime(ctx context.Context, tenantID uuid.UUID, dataClass string, before time.Time) (*time.Time, error) {
+	rows := m.rows[dataClass]
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Before(rows[j]) })
+	if len(rows) == 0 || !rows[0].Before(before) {
+		return nil, m.err
+	}
+	return &rows[0], m.err
+}
+
+func (m *mockRetentionStore) ExpireRetentionWindow(ctx context.Context, window models.RetentionWindow, archive func(writeRows func(w io.Writer) error) error) (int64, int64, error) {
+	m.windows = append(m.windows, window)
+
+	var expired []time.Time
+	var kept []time.Time
+	for _, t := range m.rows[window.DataClass] {
+		if !t.Before(window.From) && t.Before(window.To) {
+			expired = append(expired, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+
+	if archive != nil {
+		err := archive(func(w io.Writer) error {
+			for _, t := range expired {
+				if _, err := io.WriteString(w, `{"time":"`+t.Format(time.RFC3339)+`"}`+"\n"); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	m.rows[window.DataClass] = kept
+	var rollups int64
+	if window.Rollup {
+		rollups = 1
+	}
+	return int64(len(expired)), rollups, nil
+}
+
+func (m *mockRetentionStore) DeleteExpiredRollups(ctx context.Context, tenantID uuid.UUID, dataClass string, before time.Time) (int64, error) {
+	if m.rollupsBefore == nil {
+		m.rollupsBefore = make(map[string]time.Time)
+	}
+	m.rollupsBefore[dataClass] = before
+	return 2, nil
+}
+
+func (m *mockRetentionStore) RecordRetentionRun(ctx context.Context, run *models.RetentionRun) error {
+	m.runs = append(m.runs, run)
+	return nil
+}
+
+// memoryArchiver keeps archives in memory
+type memoryArchiver struct {
+	archives map[string][]byte
+	err      error
+}
+
+func (a *memoryArchiver) Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error {
+	if a.err != nil {
+		return a.err
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if a.archives == nil {
+		a.archives = make(map[string][]byte)
+	}
+	a.archives[key] = data
+	return nil
+}
+
+var testNow = time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+
+func newTestService(store Store, archiver Archiver) *Service {
+	service := NewService(store, archiver, zap.NewNop())
+	service.now = func() time.Time { return testNow }
+	return service
+}
+
+// TestApplyTenant_ExpiresOldestWindowsFirst deletes data before the cutoff
+// in windows of at most a day
+func TestApplyTenant_ExpiresOldestWindowsFirst(t *testing.T) {
+	tenantID := uuid.New()
+	store := &mockRetentionStore{
+		policies: []*models.RetentionPolicy{
+			{TenantID: tenantID, DataClass: models.RetentionClassRawLogs, RetentionDays: 7, Rollup: true},
+		},
+		rows: map[string][]time.Time{
+			models.RetentionClassRawLogs: {
+				time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC),
+				time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 2, 11, 0, 0, 0, time.UTC),
+				time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), // Retained
+			},
+		},
+	}
+	service := newTestService(store, nil)
+
+	response, err := service.ApplyTenant(context.Background(), tenantID)
+	require.NoError(t, err)
+	assert.True(t, response.Complete)
+	assert.Equal(t, int64(3), response.RowsDeleted)
+	require.Len(t, response.Runs, 2)
+
+	assert.Equal(t, time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), store.windows[0].From)
+	assert.Equal(t, time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC), store.windows[0].To)
+	assert.True(t, store.windows[0].Rollup)
+	assert.Equal(t, int64(2), response.Runs[0].RowsDeleted)
+	assert.Equal(t, int64(1), response.Runs[0].RollupsWritten)
+	assert.Nil(t, response.Runs[0].ArchiveKey)
+
+	assert.Equal(t, time.Date(2026, 3, 2, 11, 0, 0, 0, time.UTC), store.windows[1].From)
+	assert.Equal(t, time.Date(2026, 3, 3, 11, 0, 0, 0, time.UTC), store.windows[1].To)
+	assert.Len(t, store.runs, 2)
+	assert.Len(t, store.rows[models.RetentionClassRawLogs], 1)
+}
+
+// TestApplyTenant_WindowEndsAtCutoff never removes data inside retention
+func TestApplyTenant_WindowEndsAtCutoff(t *testing.T) {
+	tenantID := uuid.New()
+	store := &mockRetentionStore{
+		policies: []*models.RetentionPolicy{
+			{TenantID: tenantID, DataClass: models.RetentionClassHostMetrics, RetentionDays: 1},
+		},
+		rows: map[string][]time.Time{
+			models.RetentionClassHostMetrics: {time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+	service := newTestService(store, nil)
+
+	_, err := service.ApplyTenant(context.Background(), tenantID)
+	require.NoError(t, err)
+	require.Len(t, store.windows, 1)
+	assert.Equal(t, time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC), store.windows[0].To)
+	assert.False(t, store.windows[0].Rollup)
+}
+
+// TestApplyTenant_Incomplete stops after the maximum number of windows
+func TestApplyTenant_Incomplete(t *testing.T) {
+	tenantID := uuid.New()
+	var rows []time.Time
+	for day := 0; day < 5; day++ {
+		rows = append(rows, time.Date(2026, 1, 1+day, 0, 0, 0, 0, time.UTC))
+	}
+	store := &mockRetentionStore{
+		policies: []*models.RetentionPolicy{
+			{TenantID: tenantID, DataClass: models.RetentionClassQueryStats, RetentionDays: 30},
+		},
+		rows: map[string][]time.Time{models.RetentionClassQueryStats: rows},
+	}
+	service := newTestService(store, nil)
+	service.maxWindows = 3
+
+	response, err := service.ApplyTenant(context.Background(), tenantID)
+	require.NoError(t, err)
+	assert.False(t, response.Complete)
+	assert.Len(t, response.Runs, 3)
+	assert.Len(t, store.rows[models.RetentionClassQueryStats], 2)
+}
+
+// TestApplyTenant_Archives writes gzipped NDJSON before deleting
+func TestApplyTenant_Archives(t *testing.T) {
+	tenantID := uuid.New()
+	store := &mockRetentionStore{
+		policies: []*models.RetentionPolicy{
+			{TenantID: tenantID, DataClass: models.RetentionClassExplainPlans, RetentionDays: 7, Archive: true},
+		},
+		rows: map[string][]time.Time{
+			models.RetentionClassExplainPlans: {time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)},
+		},
+	}
+	archiver := &memoryArchiver{}
+	service := newTestService(store, archiver)
+
+	response, err := service.ApplyTenant(context.Background(), tenantID)
+	require.NoError(t, err)
+	require.Len(t, response.Runs, 1)
+
+	run := response.Runs[0]
+	require.NotNil(t, run.ArchiveKey)
+	assert.Equal(t, tenantID.String()+"/explain_plans/2026/03/01/20260301T100000Z_20260302T100000Z.ndjson.gz", *run.ArchiveKey)
+
+	data, ok := archiver.archives[*run.ArchiveKey]
+	require.True(t, ok)
+	assert.Equal(t, int64(len(data)), *run.ArchiveBytes)
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	content, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, `{"time":"2026-03-01T10:15:00Z"}`+"\n", string(content))
+}
+
+// TestApplyTenant_ArchiveFailureKeepsData does not delete rows whose archive
+// failed
+func TestApplyTenant_ArchiveFailureKeepsData(t *testing.T) {
+	tenantID := uuid.New()
+	store := &mockRetentionStore{
+		policies: []*models.RetentionPolicy{
+			{TenantID: tenantID, DataClass: models.RetentionClassRawLogs, RetentionDays: 7, Archive: true},
+		},
+		rows: map[string][]time.Time{
+			models.RetentionClassRawLogs: {time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)},
+		},
+	}
+	service := newTestService(store, &memoryArchiver{err: errors.New("bucket unavailable")})
+
+	_, err := service.ApplyTenant(context.Background(), tenantID)
+	require.Error(t, err)
+	assert.Len(t, store.rows[models.RetentionClassRawLogs], 1)
+	assert.Empty(t, store.runs)
+}
+
+// TestApplyTenant_ArchiveNotConfigured refuses to delete data that should
+// have been archived
+func TestApplyTenant_ArchiveNotConfigured(t *testing.T) {
+	tenantID := uuid.New()
+	store := &mockRetentionStore{
+		policies: []*models.RetentionPolicy{
+			{TenantID: tenantID, DataClass: models.RetentionClassRawLogs, RetentionDays: 7, Archive: true},
+		},
+		rows: map[string][]time.Time{
+			models.RetentionClassRawLogs: {time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)},
+		},
+	}
+	service := newTestService(store, nil)
+
+	_, err := service.ApplyTenant(context.Background(), tenantID)
+	assert.ErrorIs(t, err, ErrArchiveNotConfigured)
+	assert.Empty(t, store.windows)
+}
+
+// TestApplyTenant_ExpiresRollups removes rollups past their own retention
+func TestApplyTenant_ExpiresRollups(t *testing.T) {
+	tenantID := uuid.New()
+	days := 90
+	store := &mockRetentionStore{
+		policies: []*models.RetentionPolicy{
+			{TenantID: tenantID, DataClass: models.RetentionClassQueryStats, RetentionDays: 7, Rollup: true, RollupRetentionDays: &days},
+		},
+		rows: map[string][]time.Time{},
+	}
+	service := newTestService(store, nil)
+
+	response, err := service.ApplyTenant(context.Background(), tenantID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), response.RollupsDeleted)
+	assert.Equal(t, time.Date(2025, 12, 10, 12, 0, 0, 0, time.UTC), store.rollupsBefore[models.RetentionClassQueryStats])
+}
+
+// TestApplyAll_ContinuesAfterFailure applies the other tenants' policies
+func TestApplyAll_ContinuesAfterFailure(t *testing.T) {
+	failing, healthy := uuid.New(), uuid.New()
+	store := &mockRetentionStore{
+		policies: []*models.RetentionPolicy{
+			{TenantID: failing, DataClass: models.RetentionClassRawLogs, RetentionDays: 7, Archive: true},
+			{TenantID: healthy, DataClass: models.RetentionClassHostMetrics, RetentionDays: 7},
+		},
+		rows: map[string][]time.Time{
+			models.RetentionClassRawLogs:     {time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+			models.RetentionClassHostMetrics: {time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+	service := newTestService(store, nil)
+
+	responses, err := service.ApplyAll(context.Background())
+	assert.ErrorIs(t, err, ErrArchiveNotConfigured)
+	require.Len(t, responses, 1)
+	assert.Equal(t, healthy, responses[0].TenantID)
+	assert.Equal(t, int64(1), responses[0].RowsDeleted)
+}
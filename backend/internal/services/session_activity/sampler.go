@@ -0,0 +1,89 @@
+package session_activity
+
+import (
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// defaultSampleSeconds is used when a collector does not report its sampling period
+const defaultSampleSeconds = 1.0
+
+// BuildSamples converts pushed pg_stat_activity snapshots into stored samples
+// Only sessions that account for DB time are kept: active client backends and
+// background processes that are not idling in their main loop.
+// Active sessions that are not waiting are labelled CPU. Query text is
+// fingerprinted before the tenant's redaction policy is applied.
+func BuildSamples(collectorID uuid.UUID, req *models.ActivitySamplesRequest, redactor *query_performance.Redactor) []*models.ActivitySample {
+	sampleSeconds := req.IntervalSeconds
+	if sampleSeconds <= 0 {
+		sampleSeconds = defaultSampleSeconds
+	}
+
+	fingerprinter := query_performance.NewFingerprinter()
+	fingerprints := make(map[string]string)
+
+	var samples []*models.ActivitySample
+	for _, snapshot := range req.Snapshots {
+		for _, session := range snapshot.Sessions {
+			if !countsAsDBTime(session) {
+				continue
+			}
+
+			waitType, waitEvent := session.WaitEventType, session.WaitEvent
+			if waitType == "" {
+				waitType, waitEvent = models.WaitEventCPU, models.WaitEventCPU
+			}
+
+			fingerprint := ""
+			queryText := session.Query
+			if queryText != "" {
+				var ok bool
+				if fingerprint, ok = fingerprints[queryText]; !ok {
+					fingerprint = fingerprinter.Fingerprint(queryText)
+					fingerprints[queryText] = fingerprint
+				}
+				if redactor != nil {
+					queryText = redactor.RedactQuery(queryText)
+				}
+			}
+
+			samples = append(samples, &models.ActivitySample{
+				Time:             snapshot.Timestamp,
+				CollectorID:      collectorID,
+				DatabaseName:     session.Database,
+				PID:              session.PID,
+				Username:         session.Username,
+				ApplicationName:  session.ApplicationName,
+				ClientAddr:       session.ClientAddr,
+				BackendType:      session.BackendType,
+				State:            session.State,
+				WaitEventType:    waitType,
+				WaitEvent:        waitEvent,
+				QueryID:          session.QueryID,
+				QueryFingerprint: fingerprint,
+				QueryText:        queryText,
+				BackendStart:     session.BackendStart,
+				XactStart:        session.XactStart,
+				QueryStart:       session.QueryStart,
+				SampleSeconds:    sampleSeconds,
+			})
+		}
+	}
+
+	return samples
+}
+
+// countsAsDBTime reports whether a session was doing work when sampled
+// Background processes have no state; they count unless they are idling in
+// their main loop (wait_event_type Activity).
+func countsAsDBTime(session models.ActivitySession) bool {
+	switch session.State {
+	case "active":
+		return true
+	case "":
+		return session.WaitEventType != "Activity"
+	default:
+		return false
+	}
+}
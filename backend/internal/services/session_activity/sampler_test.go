@@ -0,0 +1,89 @@
+package session_activity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+func TestBuildSamples(t *testing.T) {
+	collectorID := uuid.New()
+	ts := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	queryID := int64(42)
+
+	req := &models.ActivitySamplesRequest{
+		Type:            "pg_activity_samples",
+		IntervalSeconds: 2,
+		Snapshots: []models.ActivitySnapshot{
+			{
+				Timestamp: ts,
+				Sessions: []models.ActivitySession{
+					{PID: 100, Database: "app", State: "active", WaitEventType: "IO", WaitEvent: "DataFileRead", QueryID: &queryID, Query: "SELECT * FROM orders WHERE id = 1"},
+					{PID: 101, Database: "app", State: "active", Query: "SELECT * FROM orders WHERE id = 2"},
+					{PID: 102, Database: "app", State: "idle", WaitEventType: "Client", WaitEvent: "ClientRead", Query: "COMMIT"},
+					{PID: 103, Database: "app", State: "idle in transaction", WaitEventType: "Client", WaitEvent: "ClientRead"},
+					{PID: 50, BackendType: "checkpointer", WaitEventType: "Activity", WaitEvent: "CheckpointerMain"},
+					{PID: 51, BackendType: "walwriter", WaitEventType: "IO", WaitEvent: "WALWrite"},
+				},
+			},
+		},
+	}
+
+	samples := BuildSamples(collectorID, req, nil)
+	require.Len(t, samples, 3)
+
+	assert.Equal(t, 100, samples[0].PID)
+	assert.Equal(t, "IO", samples[0].WaitEventType)
+	assert.Equal(t, "DataFileRead", samples[0].WaitEvent)
+	assert.Equal(t, &queryID, samples[0].QueryID)
+	assert.Equal(t, 2.0, samples[0].SampleSeconds)
+	assert.Equal(t, ts, samples[0].Time)
+	assert.Equal(t, collectorID, samples[0].CollectorID)
+
+	// Active and not waiting is CPU
+	assert.Equal(t, models.WaitEventCPU, samples[1].WaitEventType)
+	assert.Equal(t, models.WaitEventCPU, samples[1].WaitEvent)
+
+	// Literal values do not split fingerprints
+	assert.NotEmpty(t, samples[0].QueryFingerprint)
+	assert.Equal(t, samples[0].QueryFingerprint, samples[1].QueryFingerprint)
+
+	// Background process doing I/O counts, idle ones do not
+	assert.Equal(t, 51, samples[2].PID)
+	assert.Empty(t, samples[2].QueryFingerprint)
+}
+
+func TestBuildSamples_DefaultInterval(t *testing.T) {
+	req := &models.ActivitySamplesRequest{
+		Snapshots: []models.ActivitySnapshot{
+			{Timestamp: time.Now(), Sessions: []models.ActivitySession{{PID: 1, State: "active"}}},
+		},
+	}
+
+	samples := BuildSamples(uuid.New(), req, nil)
+	require.Len(t, samples, 1)
+	assert.Equal(t, defaultSampleSeconds, samples[0].SampleSeconds)
+}
+
+func TestBuildSamples_RedactsQueryText(t *testing.T) {
+	redactor, err := query_performance.NewRedactor(models.RedactionModeNormalize, nil)
+	require.NoError(t, err)
+
+	req := &models.ActivitySamplesRequest{
+		Snapshots: []models.ActivitySnapshot{
+			{Timestamp: time.Now(), Sessions: []models.ActivitySession{
+				{PID: 1, State: "active", Query: "SELECT * FROM users WHERE email = 'eve@example.com'"},
+			}},
+		},
+	}
+
+	samples := BuildSamples(uuid.New(), req, redactor)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "SELECT * FROM users WHERE email = $1", samples[0].QueryText)
+	assert.Equal(t, query_performance.NewFingerprinter().Fingerprint("SELECT * FROM users WHERE email = 'x'"), samples[0].QueryFingerprint)
+}
@@ -0,0 +1,214 @@
+package session_activity
+
+import (
+	"context"
+	"math"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Store interface for active session history data
+type Store interface {
+	GetActivityTotals(ctx context.Context, filter models.ActivityFilter) (*models.ActivityTotals, error)
+	GetActivityWaitEvents(ctx context.Context, filter models.ActivityFilter) ([]*models.WaitEventActivity, error)
+	GetActivitySessions(ctx context.Context, filter models.ActivityFilter) ([]*models.SessionActivity, error)
+	GetActivityQueries(ctx context.Context, filter models.ActivityFilter) ([]*models.QueryActivity, error)
+	GetActivityWaitTimeline(ctx context.Context, filter models.ActivityFilter, bucketSeconds int) ([]*models.WaitEventBucket, error)
+}
+
+const (
+	// timelineBuckets is the target number of points in a wait profile timeline
+	timelineBuckets = 60
+	// minBucketSeconds keeps timeline buckets wider than the sampling period
+	minBucketSeconds = 10
+)
+
+// Service provides active session history analysis
+type Service struct {
+	store  Store
+	logger *zap.Logger
+}
+
+// NewService creates a new active session history service
+func NewService(store Store, logger *zap.Logger) *Service {
+	return &Service{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// GetTopWaitEvents returns the wait events with the most DB time in a window
+func (s *Service) GetTopWaitEvents(ctx context.Context, filter models.ActivityFilter) (*models.TopWaitEventsResponse, error) {
+	envelope, err := s.envelope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.store.GetActivityWaitEvents(ctx, filter)
+	if err != nil {
+		s.logger.Error("Failed to get top wait events", zap.Error(err))
+		return nil, err
+	}
+	for _, e := range events {
+		e.Percent = percentOf(e.DBTimeSeconds, envelope.DBTimeSeconds)
+	}
+
+	return &models.TopWaitEventsResponse{
+		ActivityResponse: *envelope,
+		WaitEvents:       nonNil(events),
+	}, nil
+}
+
+// GetTopSessions returns the backends with the most DB time in a window
+func (s *Service) GetTopSessions(ctx context.Context, filter models.ActivityFilter) (*models.TopSessionsResponse, error) {
+	envelope, err := s.envelope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.store.GetActivitySessions(ctx, filter)
+	if err != nil {
+		s.logger.Error("Failed to get top sessions", zap.Error(err))
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Percent = percentOf(session.DBTimeSeconds, envelope.DBTimeSeconds)
+	}
+
+	return &models.TopSessionsResponse{
+		ActivityResponse: *envelope,
+		Sessions:         nonNil(sessions),
+	}, nil
+}
+
+// GetTopQueries returns the query fingerprints with the most DB time in a window
+func (s *Service) GetTopQueries(ctx context.Context, filter models.ActivityFilter) (*models.TopActivityQueriesResponse, error) {
+	envelope, err := s.envelope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	queries, err := s.store.GetActivityQueries(ctx, filter)
+	if err != nil {
+		s.logger.Error("Failed to get top queries by DB time", zap.Error(err))
+		return nil, err
+	}
+	for _, q := range queries {
+		q.Percent = percentOf(q.DBTimeSeconds, envelope.DBTimeSeconds)
+	}
+
+	return &models.TopActivityQueriesResponse{
+		ActivityResponse: *envelope,
+		Queries:          nonNil(queries),
+	}, nil
+}
+
+// GetFingerprintWaitProfile returns where a query fingerprint spent its DB
+// time, as a wait event breakdown and a timeline by wait event type.
+// Percentages are relative to the fingerprint's own DB time.
+func (s *Service) GetFingerprintWaitProfile(ctx context.Context, filter models.ActivityFilter, fingerprint string) (*models.FingerprintWaitProfile, error) {
+	filter.Fingerprint = &fingerprint
+
+	envelope, err := s.envelope(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &models.FingerprintWaitProfile{
+		ActivityResponse: *envelope,
+		QueryFingerprint: fingerprint,
+		WaitEvents:       []*models.WaitEventActivity{},
+		Timeline:         []*models.WaitEventBucket{},
+		BucketSeconds:    bucketSeconds(filter),
+	}
+	if envelope.TotalSamples == 0 {
+		return profile, nil
+	}
+
+	queries, err := s.store.GetActivityQueries(ctx, models.ActivityFilter{
+		CollectorID: filter.CollectorID,
+		From:        filter.From,
+		To:          filter.To,
+		Database:    filter.Database,
+		Fingerprint: filter.Fingerprint,
+		Limit:       1,
+	})
+	if err != nil {
+		s.logger.Error("Failed to get fingerprint query text", zap.Error(err))
+		return nil, err
+	}
+	if len(queries) > 0 {
+		profile.QueryText = queries[0].QueryText
+	}
+
+	events, err := s.store.GetActivityWaitEvents(ctx, filter)
+	if err != nil {
+		s.logger.Error("Failed to get fingerprint wait events", zap.Error(err))
+		return nil, err
+	}
+	for _, e := range events {
+		e.Percent = percentOf(e.DBTimeSeconds, envelope.DBTimeSeconds)
+	}
+	profile.WaitEvents = nonNil(events)
+
+	timeline, err := s.store.GetActivityWaitTimeline(ctx, filter, profile.BucketSeconds)
+	if err != nil {
+		s.logger.Error("Failed to get fingerprint wait timeline", zap.Error(err))
+		return nil, err
+	}
+	profile.Timeline = nonNil(timeline)
+
+	return profile, nil
+}
+
+// envelope loads the totals shared by every report for a window
+func (s *Service) envelope(ctx context.Context, filter models.ActivityFilter) (*models.ActivityResponse, error) {
+	totals, err := s.store.GetActivityTotals(ctx, filter)
+	if err != nil {
+		s.logger.Error("Failed to get activity totals", zap.Error(err))
+		return nil, err
+	}
+
+	resp := &models.ActivityResponse{
+		CollectorID:   filter.CollectorID,
+		From:          filter.From,
+		To:            filter.To,
+		TotalSamples:  totals.Samples,
+		DBTimeSeconds: totals.DBTimeSeconds,
+	}
+	if window := filter.To.Sub(filter.From).Seconds(); window > 0 {
+		resp.AvgActiveSessions = round2(totals.DBTimeSeconds / window)
+	}
+
+	return resp, nil
+}
+
+// bucketSeconds picks a timeline bucket width giving about timelineBuckets points
+func bucketSeconds(filter models.ActivityFilter) int {
+	seconds := int(filter.To.Sub(filter.From).Seconds()) / timelineBuckets
+	if seconds < minBucketSeconds {
+		return minBucketSeconds
+	}
+	return seconds
+}
+
+// percentOf returns part as a percentage of total, rounded to two decimals
+func percentOf(part, total float64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return round2(part / total * 100)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// nonNil returns an empty slice instead of nil so responses encode as []
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
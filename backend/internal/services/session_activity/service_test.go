@@ -0,0 +1,149 @@
+package session_activity
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockActivityStore is a mock implementation for testing
+type mockActivityStore struct {
+	totals     *models.ActivityTotals
+	waitEvents []*models.WaitEventActivity
+	sessions   []*models.SessionActivity
+	queries    []*models.QueryActivity
+	timeline   []*models.WaitEventBucket
+	err        error
+
+	lastFilter    models.ActivityFilter
+	lastBucketSec int
+}
+
+func (m *mockActivityStore) GetActivityTotals(ctx context.Context, filter models.ActivityFilter) (*models.ActivityTotals, error) {
+	m.lastFilter = filter
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.totals, nil
+}
+
+func (m *mockActivityStore) GetActivityWaitEvents(ctx context.Context, filter models.ActivityFilter) ([]*models.WaitEventActivity, error) {
+	return m.waitEvents, m.err
+}
+
+func (m *mockActivityStore) GetActivitySessions(ctx context.Context, filter models.ActivityFilter) ([]*models.SessionActivity, error) {
+	return m.sessions, m.err
+}
+
+func (m *mockActivityStore) GetActivityQueries(ctx context.Context, filter models.ActivityFilter) ([]*models.QueryActivity, error) {
+	return m.queries, m.err
+}
+
+func (m *mockActivityStore) GetActivityWaitTimeline(ctx context.Context, filter models.ActivityFilter, bucketSeconds int) ([]*models.WaitEventBucket, error) {
+	m.lastBucketSec = bucketSeconds
+	return m.timeline, m.err
+}
+
+func testFilter() models.ActivityFilter {
+	to := time.Date(2026, 3, 1, 13, 0, 0, 0, time.UTC)
+	return models.ActivityFilter{
+		CollectorID: uuid.New(),
+		From:        to.Add(-time.Hour),
+		To:          to,
+		Limit:       10,
+	}
+}
+
+func TestService_GetTopWaitEvents(t *testing.T) {
+	store := &mockActivityStore{
+		totals: &models.ActivityTotals{Samples: 900, DBTimeSeconds: 1800},
+		waitEvents: []*models.WaitEventActivity{
+			{WaitEventType: "IO", WaitEvent: "DataFileRead", Samples: 600, DBTimeSeconds: 1200},
+			{WaitEventType: "CPU", WaitEvent: "CPU", Samples: 300, DBTimeSeconds: 600},
+		},
+	}
+	service := NewService(store, zap.NewNop())
+
+	resp, err := service.GetTopWaitEvents(context.Background(), testFilter())
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(900), resp.TotalSamples)
+	assert.Equal(t, 1800.0, resp.DBTimeSeconds)
+	assert.Equal(t, 0.5, resp.AvgActiveSessions) // 1800s of DB time over a 3600s window
+	require.Len(t, resp.WaitEvents, 2)
+	assert.InDelta(t, 66.67, resp.WaitEvents[0].Percent, 0.001)
+	assert.InDelta(t, 33.33, resp.WaitEvents[1].Percent, 0.001)
+}
+
+func TestService_GetTopSessionsAndQueries(t *testing.T) {
+	store := &mockActivityStore{
+		totals:   &models.ActivityTotals{Samples: 10, DBTimeSeconds: 10},
+		sessions: []*models.SessionActivity{{PID: 1, DBTimeSeconds: 4}},
+		queries:  []*models.QueryActivity{{QueryFingerprint: "abc", DBTimeSeconds: 5}},
+	}
+	service := NewService(store, zap.NewNop())
+
+	sessions, err := service.GetTopSessions(context.Background(), testFilter())
+	require.NoError(t, err)
+	assert.Equal(t, 40.0, sessions.Sessions[0].Percent)
+
+	queries, err := service.GetTopQueries(context.Background(), testFilter())
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, queries.Queries[0].Percent)
+}
+
+func TestService_EmptyWindow(t *testing.T) {
+	store := &mockActivityStore{totals: &models.ActivityTotals{}}
+	service := NewService(store, zap.NewNop())
+
+	resp, err := service.GetTopWaitEvents(context.Background(), testFilter())
+	require.NoError(t, err)
+	assert.NotNil(t, resp.WaitEvents)
+	assert.Empty(t, resp.WaitEvents)
+	assert.Equal(t, 0.0, resp.AvgActiveSessions)
+}
+
+func TestService_GetFingerprintWaitProfile(t *testing.T) {
+	store := &mockActivityStore{
+		totals:     &models.ActivityTotals{Samples: 20, DBTimeSeconds: 20},
+		queries:    []*models.QueryActivity{{QueryFingerprint: "abc", QueryText: "SELECT $1"}},
+		waitEvents: []*models.WaitEventActivity{{WaitEventType: "Lock", WaitEvent: "transactionid", DBTimeSeconds: 15}},
+		timeline:   []*models.WaitEventBucket{{WaitEventType: "Lock", Samples: 15, DBTimeSeconds: 15}},
+	}
+	service := NewService(store, zap.NewNop())
+
+	profile, err := service.GetFingerprintWaitProfile(context.Background(), testFilter(), "abc")
+	require.NoError(t, err)
+
+	require.NotNil(t, store.lastFilter.Fingerprint)
+	assert.Equal(t, "abc", *store.lastFilter.Fingerprint)
+	assert.Equal(t, "SELECT $1", profile.QueryText)
+	assert.Equal(t, 75.0, profile.WaitEvents[0].Percent)
+	assert.Len(t, profile.Timeline, 1)
+	assert.Equal(t, 60, profile.BucketSeconds) // one hour in 60 buckets
+	assert.Equal(t, 60, store.lastBucketSec)
+}
+
+func TestService_StoreError(t *testing.T) {
+	store := &mockActivityStore{err: errors.New("database unavailable")}
+	service := NewService(store, zap.NewNop())
+
+	_, err := service.GetTopQueries(context.Background(), testFilter())
+	assert.Error(t, err)
+}
+
+func TestBucketSeconds(t *testing.T) {
+	filter := testFilter()
+	filter.From = filter.To.Add(-5 * time.Minute)
+	assert.Equal(t, minBucketSeconds, bucketSeconds(filter))
+
+	filter.From = filter.To.Add(-24 * time.Hour)
+	assert.Equal(t, 1440, bucketSeconds(filter))
+}
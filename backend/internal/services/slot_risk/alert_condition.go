@@ -0,0 +1,100 @@
+package slot_risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// Alert metrics of a slot risk report
+const (
+	MetricTotalRetainedBytes = "total_retained_bytes"
+	MetricMaxRetainedBytes   = "max_retained_bytes"
+	MetricInactiveSlots      = "inactive_slots"
+	MetricHoursToDiskFull    = "hours_to_disk_full"
+	MetricMaxCatalogXminAge  = "max_catalog_xmin_age"
+)
+
+var alertMetrics = map[string]bool{
+	MetricTotalRetainedBytes: true,
+	MetricMaxRetainedBytes:   true,
+	MetricInactiveSlots:      true,
+	MetricHoursToDiskFull:    true,
+	MetricMaxCatalogXminAge:  true,
+}
+
+var alertOperators = map[string]bool{"==": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true}
+
+// ParseAlertCondition decodes and validates the condition of a replication
+// slot alert rule
+func ParseAlertCondition(raw json.RawMessage) (*models.SlotAlertCondition, error) {
+	var cond models.SlotAlertCondition
+	if err := json.Unmarshal(raw, &cond); err != nil {
+		return nil, fmt.Errorf("unmarshal replication slot condition: %w", err)
+	}
+
+	if cond.CollectorID == uuid.Nil {
+		return nil, fmt.Errorf("replication slot condition needs a collector_id")
+	}
+	if !alertMetrics[cond.Metric] {
+		metrics := make([]string, 0, len(alertMetrics))
+		for metric := range alertMetrics {
+			metrics = append(metrics, metric)
+		}
+		sort.Strings(metrics)
+		return nil, fmt.Errorf("invalid metric %q: use one of %s", cond.Metric, strings.Join(metrics, ", "))
+	}
+	if !alertOperators[cond.Operator] {
+		return nil, fmt.Errorf("invalid operator %q", cond.Operator)
+	}
+
+	return &cond, nil
+}
+
+// MetricValue returns an alert metric of a report. There is no value without
+// samples, nor a disk fill time when nothing fills the disk.
+func MetricValue(report *models.SlotRiskReport, metric string) (float64, bool) {
+	if report.CollectedAt == nil {
+		return 0, false
+	}
+
+	switch metric {
+	case MetricTotalRetainedBytes:
+		return float64(report.TotalRetainedBytes), true
+	case MetricMaxRetainedBytes:
+		var max int64
+		for _, slot := range report.Slots {
+			if slot.RetainedBytes > max {
+				max = slot.RetainedBytes
+			}
+		}
+		return float64(max), true
+	case MetricInactiveSlots:
+		count := 0
+		for _, slot := range report.Slots {
+			if !slot.Active {
+				count++
+			}
+		}
+		return float64(count), true
+	case MetricHoursToDiskFull:
+		if report.DiskFullAt == nil {
+			return 0, false
+		}
+		return report.DiskFullAt.Sub(*report.CollectedAt).Hours(), true
+	case MetricMaxCatalogXminAge:
+		var oldest int64
+		for _, slot := range report.Slots {
+			if slot.CatalogXminAge != nil && *slot.CatalogXminAge > oldest {
+				oldest = *slot.CatalogXminAge
+			}
+		}
+		return float64(oldest), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,453 @@
+package slot_risk
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// RateLookback is how far back the baseline sample for the WAL rate and
+	// slot progress goes
+	RateLookback = 6 * time.Hour
+
+	// ActivityLookback is how far back a slot's last active sample is looked for
+	ActivityLookback = 7 * 24 * time.Hour
+
+	// InactiveGrace is how long a slot may be inactive before it is flagged,
+	// so that consumers reconnecting are not
+	InactiveGrace = 15 * time.Minute
+
+	// Thresholds on the age of a logical slot's catalog_xmin: vacuum cannot
+	// remove catalog rows newer than it, and it holds back freezing
+	warningCatalogXminAge  = 50000000  // default vacuum_freeze_min_age
+	criticalCatalogXminAge = 150000000 // three quarters of autovacuum_freeze_max_age
+
+	// Disk fill horizons
+	warningHorizon  = 7 * 24 * time.Hour
+	criticalHorizon = 24 * time.Hour
+
+	bytesPerGB = 1 << 30
+)
+
+// Store interface for replication slot, WAL position and host disk samples
+type Store interface {
+	GetWalPosition(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.WalPosition, error)
+	GetWalPositionBaseline(ctx context.Context, collectorID uuid.UUID, from, before time.Time) (*models.WalPosition, error)
+	GetSlots(ctx context.Context, collectorID uuid.UUID, at time.Time) ([]*models.ReplicationSlot, error)
+	GetSlotsBaseline(ctx context.Context, collectorID uuid.UUID, from, before time.Time) ([]*models.ReplicationSlot, error)
+	GetSlotLastActive(ctx context.Context, collectorID uuid.UUID, from, to time.Time) (map[string]time.Time, error)
+	GetHostDisk(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.HostMetrics, error)
+}
+
+// Service assesses replication slots and forecasts WAL retention
+type Service struct {
+	store  Store
+	logger *zap.Logger
+}
+
+// NewService creates a new slot risk service
+func NewService(store Store, logger *zap.Logger) *Service {
+	return &Service{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Samples are the inputs of an assessment. Baselines are nil, or empty, when
+// there are none; Disk is nil when the host does not report disk usage.
+type Samples struct {
+	Position         *models.WalPosition
+	BaselinePosition *models.WalPosition
+	Slots            []*models.ReplicationSlot
+	BaselineSlots    []*models.ReplicationSlot
+	LastActive       map[string]time.Time
+	Disk             *models.HostMetrics
+}
+
+// GetReport assesses the replication slots of a collector's cluster from the
+// latest sample at or before at. Without samples the level is unknown.
+func (s *Service) GetReport(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.SlotRiskReport, error) {
+	report := &models.SlotRiskReport{
+		CollectorID: collectorID,
+		Level:       models.SlotRiskUnknown,
+		Slots:       []*models.SlotRisk{},
+		Findings:    []string{},
+	}
+
+	var samples Samples
+	var err error
+	if samples.Position, err = s.store.GetWalPosition(ctx, collectorID, at); err != nil {
+		s.logger.Error("Failed to find WAL position", zap.Error(err))
+		return nil, err
+	}
+	if samples.Slots, err = s.store.GetSlots(ctx, collectorID, at); err != nil {
+		s.logger.Error("Failed to load replication slots", zap.Error(err))
+		return nil, err
+	}
+
+	var sampleTime time.Time
+	if samples.Position != nil {
+		sampleTime = samples.Position.Time
+	}
+	if len(samples.Slots) > 0 && samples.Slots[0].Timestamp.After(sampleTime) {
+		sampleTime = samples.Slots[0].Timestamp
+	}
+	if sampleTime.IsZero() {
+		return report, nil
+	}
+
+	if samples.BaselinePosition, err = s.store.GetWalPositionBaseline(ctx, collectorID, sampleTime.Add(-RateLookback), sampleTime); err != nil {
+		s.logger.Error("Failed to find WAL position baseline", zap.Error(err))
+		return nil, err
+	}
+	if samples.BaselineSlots, err = s.store.GetSlotsBaseline(ctx, collectorID, sampleTime.Add(-RateLookback), sampleTime); err != nil {
+		s.logger.Error("Failed to load replication slot baseline", zap.Error(err))
+		return nil, err
+	}
+	if samples.LastActive, err = s.store.GetSlotLastActive(ctx, collectorID, sampleTime.Add(-ActivityLookback), sampleTime); err != nil {
+		s.logger.Error("Failed to load replication slot activity", zap.Error(err))
+		return nil, err
+	}
+	if samples.Disk, err = s.store.GetHostDisk(ctx, collectorID, at); err != nil {
+		s.logger.Error("Failed to find host disk usage", zap.Error(err))
+		return nil, err
+	}
+
+	Assess(report, sampleTime, &samples)
+	return report, nil
+}
+
+// Assess fills a report from the samples of sampleTime
+func Assess(report *models.SlotRiskReport, sampleTime time.Time, samples *Samples) {
+	collectedAt := sampleTime
+	report.CollectedAt = &collectedAt
+	report.Level = models.SlotRiskOK
+
+	current, haveCurrent := int64(0), false
+	if pos := samples.Position; pos != nil {
+		report.CurrentWalLsn = pos.CurrentWalLsn
+		report.WalDirectoryBytes = pos.WalDirectoryBytes
+		report.MaxSlotWalKeepSizeBytes = pos.MaxSlotWalKeepSizeBytes
+		current, haveCurrent = ParseLSN(pos.CurrentWalLsn)
+	}
+
+	// WAL generated since the baseline; a standby's position can restart
+	// lower after a rebuild, which gives no rate
+	walAdvanced := true
+	if base := samples.BaselinePosition; haveCurrent && base != nil && samples.Position.Time.After(base.Time) {
+		if start, ok := ParseLSN(base.CurrentWalLsn); ok && current >= start {
+			rate := float64(current-start) / samples.Position.Time.Sub(base.Time).Hours()
+			report.WalBytesPerHour = &rate
+			walAdvanced = current > start
+		}
+	}
+
+	if d := samples.Disk; d != nil && d.DiskTotalGb > 0 {
+		free := d.DiskFreeGb * bytesPerGB
+		total := d.DiskTotalGb * bytesPerGB
+		report.DiskFreeBytes = &free
+		report.DiskTotalBytes = &total
+	}
+
+	baseline := make(map[string]*models.ReplicationSlot, len(samples.BaselineSlots))
+	for _, slot := range samples.BaselineSlots {
+		baseline[slot.SlotName] = slot
+	}
+
+	var holding []*models.SlotRisk   // slots retaining WAL for as long as they stay as they are
+	var droppable []*models.SlotRisk // holding and lost slots
+	for _, slot := range samples.Slots {
+		risk := &models.SlotRisk{
+			SlotName:          slot.SlotName,
+			SlotType:          slot.SlotType,
+			DatabaseName:      slot.DatabaseName,
+			Active:            slot.Active,
+			WalStatus:         slot.WalStatus,
+			RestartLsn:        slot.RestartLsn,
+			ConfirmedFlushLsn: slot.ConfirmedFlushLsn,
+			RetainedBytes:     retainedBytes(slot, current, haveCurrent),
+			CatalogXminAge:    slot.CatalogXminAge,
+			Level:             models.SlotRiskOK,
+			Reasons:           []string{},
+		}
+		report.TotalRetainedBytes += risk.RetainedBytes
+
+		inactive := false
+		if !slot.Active {
+			if last, ok := samples.LastActive[slot.SlotName]; ok {
+				since := last
+				risk.InactiveSince = &since
+				inactive = sampleTime.Sub(last) >= InactiveGrace
+			} else {
+				inactive = true
+			}
+		}
+		if base := baseline[slot.SlotName]; base != nil {
+			advancing := slotPosition(slot) > slotPosition(base)
+			risk.Advancing = &advancing
+		}
+		stuck := risk.Advancing != nil && !*risk.Advancing && walAdvanced
+
+		switch slot.WalStatus {
+		case "lost":
+			raise(risk, models.SlotRiskCritical, "the WAL the slot needs was removed; it can no longer be used")
+		case "unreserved":
+			raise(risk, models.SlotRiskWarning, "the slot's WAL is past max_wal_size and will be removed at the next checkpoint")
+		}
+		if inactive && slot.WalStatus != "lost" {
+			if risk.InactiveSince != nil {
+				raise(risk, models.SlotRiskWarning, fmt.Sprintf("inactive since %s", risk.InactiveSince.UTC().Format(time.RFC3339)))
+			} else {
+				raise(risk, models.SlotRiskWarning, fmt.Sprintf("not active in the last %s", formatDuration(ActivityLookback)))
+			}
+		}
+		if stuck && !inactive && slot.WalStatus != "lost" {
+			raise(risk, models.SlotRiskWarning, fmt.Sprintf("has not advanced in %s although WAL was written", formatDuration(sampleTime.Sub(baseline[slot.SlotName].Timestamp))))
+		}
+		if age := slot.CatalogXminAge; slot.SlotType == "logical" && age != nil {
+			switch {
+			case *age >= criticalCatalogXminAge:
+				raise(risk, models.SlotRiskCritical, fmt.Sprintf("catalog_xmin is %d transactions old: vacuum cannot clean the system catalogs", *age))
+			case *age >= warningCatalogXminAge:
+				raise(risk, models.SlotRiskWarning, fmt.Sprintf("catalog_xmin is %d transactions old: system catalogs are bloating", *age))
+			}
+		}
+		if report.DiskFreeBytes != nil && *report.DiskFreeBytes > 0 && risk.RetainedBytes*4 >= *report.DiskFreeBytes {
+			raise(risk, models.SlotRiskWarning, fmt.Sprintf("retains %s, %.0f%% of the free disk space", formatBytes(risk.RetainedBytes),
+				float64(risk.RetainedBytes)*100/float64(*report.DiskFreeBytes)))
+		}
+
+		switch {
+		case slot.WalStatus == "lost":
+			droppable = append(droppable, risk)
+			risk.Recommendation = "Drop the slot and rebuild its consumer: " + dropStatement(slot.SlotName)
+		case inactive || stuck:
+			holding = append(holding, risk)
+			droppable = append(droppable, risk)
+			risk.Recommendation = "Reconnect the slot's consumer, or drop the slot if it is abandoned: " + dropStatement(slot.SlotName)
+		case risk.Level != models.SlotRiskOK:
+			risk.Recommendation = "The consumer is connected but falling behind; check its apply lag and errors"
+		}
+
+		report.Slots = append(report.Slots, risk)
+	}
+
+	forecast(report, holding, sampleTime)
+
+	for _, risk := range report.Slots {
+		report.Level = worse(report.Level, risk.Level)
+	}
+	report.RecommendedDrop = recommendDrop(droppable)
+	summarize(report, holding)
+}
+
+// forecast projects when the slots holding WAL fill the disk, or are
+// invalidated by max_slot_wal_keep_size first
+func forecast(report *models.SlotRiskReport, holding []*models.SlotRisk, sampleTime time.Time) {
+	rate := report.WalBytesPerHour
+	if len(holding) == 0 || rate == nil || *rate <= 0 {
+		return
+	}
+	// Dates beyond what a time.Duration holds, about 290 years, are never
+	// reached (nil)
+	reachedAt := func(bytes int64) *time.Time {
+		nanos := float64(bytes) / *rate * float64(time.Hour)
+		if nanos >= math.MaxInt64 {
+			return nil
+		}
+		at := sampleTime.Add(time.Duration(nanos))
+		return &at
+	}
+
+	var maxRetained int64
+	for _, risk := range holding {
+		if risk.RetainedBytes > maxRetained {
+			maxRetained = risk.RetainedBytes
+		}
+	}
+
+	// Slots share retained WAL, so pg_wal grows with the slot retaining the
+	// most, and max_slot_wal_keep_size invalidates each slot reaching it
+	limit := report.MaxSlotWalKeepSizeBytes
+	if limit != nil {
+		for _, risk := range holding {
+			at := &sampleTime
+			if remaining := *limit - risk.RetainedBytes; remaining > 0 {
+				at = reachedAt(remaining)
+			}
+			risk.InvalidatedAt = at
+		}
+	}
+
+	if report.DiskFreeBytes == nil {
+		return
+	}
+	if limit != nil && *limit-maxRetained < *report.DiskFreeBytes {
+		return
+	}
+	report.DiskFullAt = reachedAt(*report.DiskFreeBytes)
+	if report.DiskFullAt == nil {
+		return
+	}
+	fullAt := *report.DiskFullAt
+
+	level := models.SlotRiskOK
+	switch {
+	case fullAt.Sub(sampleTime) <= criticalHorizon:
+		level = models.SlotRiskCritical
+	case fullAt.Sub(sampleTime) <= warningHorizon:
+		level = models.SlotRiskWarning
+	}
+	if level == models.SlotRiskOK {
+		return
+	}
+	for _, risk := range holding {
+		raise(risk, level, fmt.Sprintf("holds WAL that fills the disk by %s", fullAt.UTC().Format(time.RFC3339)))
+	}
+}
+
+// recommendDrop picks the slot whose removal frees the most: the abandoned
+// or lost slot retaining the most WAL, then the oldest catalog_xmin
+func recommendDrop(droppable []*models.SlotRisk) string {
+	if len(droppable) == 0 {
+		return ""
+	}
+	candidates := append([]*models.SlotRisk(nil), droppable...)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.RetainedBytes != b.RetainedBytes {
+			return a.RetainedBytes > b.RetainedBytes
+		}
+		if ageA, ageB := catalogAge(a), catalogAge(b); ageA != ageB {
+			return ageA > ageB
+		}
+		return a.SlotName < b.SlotName
+	})
+	return candidates[0].SlotName
+}
+
+func summarize(report *models.SlotRiskReport, holding []*models.SlotRisk) {
+	if len(holding) > 0 {
+		names := make([]string, len(holding))
+		for i, risk := range holding {
+			names[i] = risk.SlotName
+		}
+		report.Findings = append(report.Findings, fmt.Sprintf("%d slot(s) retain WAL without a consumer keeping up: %s",
+			len(holding), strings.Join(names, ", ")))
+	}
+	if report.TotalRetainedBytes > 0 {
+		report.Findings = append(report.Findings, fmt.Sprintf("Slots retain %s of WAL", formatBytes(report.TotalRetainedBytes)))
+	}
+	if report.DiskFullAt != nil {
+		report.Findings = append(report.Findings, fmt.Sprintf("At %s of WAL per hour the disk fills by %s",
+			formatBytes(int64(*report.WalBytesPerHour)), report.DiskFullAt.UTC().Format(time.RFC3339)))
+	} else if len(holding) > 0 && report.DiskFreeBytes == nil {
+		report.Findings = append(report.Findings, "The host reports no disk usage; the disk fill time cannot be forecast")
+	}
+	if len(holding) > 0 && report.MaxSlotWalKeepSizeBytes == nil {
+		report.Findings = append(report.Findings, "max_slot_wal_keep_size is unlimited: an abandoned slot retains WAL until the disk is full")
+	}
+	if report.RecommendedDrop != "" {
+		report.Findings = append(report.Findings, "Drop the abandoned slot first: "+dropStatement(report.RecommendedDrop))
+	}
+}
+
+// ParseLSN parses a pg_lsn in its text form, two hexadecimal 32 bit halves
+// separated by a slash, into a byte position
+func ParseLSN(lsn string) (int64, bool) {
+	hi, lo, ok := strings.Cut(strings.TrimSpace(lsn), "/")
+	if !ok {
+		return 0, false
+	}
+	high, err := strconv.ParseUint(hi, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	low, err := strconv.ParseUint(lo, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int64(high<<32 | low), true
+}
+
+// retainedBytes is the WAL between the current position and the slot's
+// restart_lsn, or what the collector computed when either is unknown
+func retainedBytes(slot *models.ReplicationSlot, current int64, haveCurrent bool) int64 {
+	if restart, ok := ParseLSN(slot.RestartLsn); ok && haveCurrent && current >= restart {
+		return current - restart
+	}
+	if slot.BytesRetained > 0 {
+		return slot.BytesRetained
+	}
+	return slot.WalRetainedMb << 20
+}
+
+// slotPosition is how far the slot's consumer got: confirmed_flush_lsn for
+// logical slots, restart_lsn otherwise
+func slotPosition(slot *models.ReplicationSlot) int64 {
+	if lsn, ok := ParseLSN(slot.ConfirmedFlushLsn); ok {
+		return lsn
+	}
+	lsn, _ := ParseLSN(slot.RestartLsn)
+	return lsn
+}
+
+func catalogAge(risk *models.SlotRisk) int64 {
+	if risk.CatalogXminAge == nil {
+		return 0
+	}
+	return *risk.CatalogXminAge
+}
+
+func raise(risk *models.SlotRisk, level, reason string) {
+	risk.Level = worse(risk.Level, level)
+	risk.Reasons = append(risk.Reasons, reason)
+}
+
+var levelRank = map[string]int{
+	models.SlotRiskUnknown:  0,
+	models.SlotRiskOK:       1,
+	models.SlotRiskWarning:  2,
+	models.SlotRiskCritical: 3,
+}
+
+func worse(a, b string) string {
+	if levelRank[b] > levelRank[a] {
+		return b
+	}
+	return a
+}
+
+func dropStatement(slot string) string {
+	return fmt.Sprintf("SELECT pg_drop_replication_slot('%s');", strings.ReplaceAll(slot, "'", "''"))
+}
+
+func formatBytes(b int64) string {
+	switch {
+	case b >= bytesPerGB:
+		return fmt.Sprintf("%.1f GB", float64(b)/bytesPerGB)
+	case b >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(b)/(1<<20))
+	default:
+		return fmt.Sprintf("%d bytes", b)
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	if d >= 48*time.Hour {
+		return fmt.Sprintf("%d days", int(d.Hours()/24))
+	}
+	if d >= 2*time.Hour {
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d minutes", int(d.Minutes()))
+}
@@ -0,0 +1,277 @@
+package slot_risk
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockSlotStore is a mock implementation for testing
+type mockSlotStore struct {
+	samples Samples
+	err     error
+
+	lastBaselineFrom time.Time
+}
+
+func (m *mockSlotStore) GetWalPosition(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.WalPosition, error) {
+	return m.samples.Position, m.err
+}
+
+func (m *mockSlotStore) GetWalPositionBaseline(ctx context.Context, collectorID uuid.UUID, from, before time.Time) (*models.WalPosition, error) {
+	m.lastBaselineFrom = from
+	return m.samples.BaselinePosition, m.err
+}
+
+func (m *mockSlotStore) GetSlots(ctx context.Context, collectorID uuid.UUID, at time.Time) ([]*models.ReplicationSlot, error) {
+	return m.samples.Slots, m.err
+}
+
+func (m *mockSlotStore) GetSlotsBaseline(ctx context.Context, collectorID uuid.UUID, from, before time.Time) ([]*models.ReplicationSlot, error) {
+	return m.samples.BaselineSlots, m.err
+}
+
+func (m *mockSlotStore) GetSlotLastActive(ctx context.Context, collectorID uuid.UUID, from, to time.Time) (map[string]time.Time, error) {
+	return m.samples.LastActive, m.err
+}
+
+func (m *mockSlotStore) GetHostDisk(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.HostMetrics, error) {
+	return m.samples.Disk, m.err
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+// newTestSamples returns a cluster at A/0 that wrote 4 GB of WAL in the last
+// six hours, with an abandoned slot retaining 8 GB next to an active standby,
+// on a host with freeGB free
+func newTestSamples(at time.Time, freeGB int64) *Samples {
+	baselineAt := at.Add(-RateLookback)
+	return &Samples{
+		Position:         &models.WalPosition{Time: at, CurrentWalLsn: "A/00000000"},
+		BaselinePosition: &models.WalPosition{Time: baselineAt, CurrentWalLsn: "9/00000000"},
+		Slots: []*models.ReplicationSlot{
+			{Timestamp: at, SlotName: "old_standby", SlotType: "physical", RestartLsn: "8/00000000"},
+			{Timestamp: at, SlotName: "standby1", SlotType: "physical", Active: true, RestartLsn: "A/00000000"},
+		},
+		BaselineSlots: []*models.ReplicationSlot{
+			{Timestamp: baselineAt, SlotName: "old_standby", RestartLsn: "8/00000000"},
+			{Timestamp: baselineAt, SlotName: "standby1", RestartLsn: "9/00000000"},
+		},
+		LastActive: map[string]time.Time{
+			"old_standby": at.Add(-48 * time.Hour),
+			"standby1":    at,
+		},
+		Disk: &models.HostMetrics{DiskTotalGb: 100, DiskFreeGb: freeGB},
+	}
+}
+
+func findSlot(t *testing.T, report *models.SlotRiskReport, name string) *models.SlotRisk {
+	for _, slot := range report.Slots {
+		if slot.SlotName == name {
+			return slot
+		}
+	}
+	t.Fatalf("slot %s not in report", name)
+	return nil
+}
+
+// TestGetReport_NoSamples is unknown
+func TestGetReport_NoSamples(t *testing.T) {
+	service := NewService(&mockSlotStore{}, zap.NewNop())
+
+	report, err := service.GetReport(context.Background(), uuid.New(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, models.SlotRiskUnknown, report.Level)
+	assert.Nil(t, report.CollectedAt)
+	assert.Empty(t, report.Slots)
+}
+
+// TestGetReport_AbandonedSlot measures retention from LSNs, forecasts the
+// disk and recommends dropping the abandoned slot
+func TestGetReport_AbandonedSlot(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := &mockSlotStore{samples: *newTestSamples(at, 20)}
+	service := NewService(store, zap.NewNop())
+
+	report, err := service.GetReport(context.Background(), uuid.New(), at)
+	require.NoError(t, err)
+	assert.Equal(t, at.Add(-RateLookback), store.lastBaselineFrom)
+
+	require.NotNil(t, report.WalBytesPerHour)
+	assert.InDelta(t, float64(4<<30)/6, *report.WalBytesPerHour, 1)
+	assert.Equal(t, int64(8<<30), report.TotalRetainedBytes)
+
+	old := findSlot(t, report, "old_standby")
+	assert.Equal(t, int64(8<<30), old.RetainedBytes)
+	require.NotNil(t, old.InactiveSince)
+	assert.Equal(t, at.Add(-48*time.Hour), *old.InactiveSince)
+	assert.Equal(t, models.SlotRiskWarning, old.Level)
+	assert.Contains(t, old.Recommendation, "SELECT pg_drop_replication_slot('old_standby');")
+
+	standby := findSlot(t, report, "standby1")
+	assert.Equal(t, models.SlotRiskOK, standby.Level)
+	require.NotNil(t, standby.Advancing)
+	assert.True(t, *standby.Advancing)
+	assert.Empty(t, standby.Recommendation)
+
+	// 20 GB free at 4 GB per 6 hours
+	require.NotNil(t, report.DiskFullAt)
+	assert.Equal(t, at.Add(30*time.Hour), *report.DiskFullAt)
+	assert.Equal(t, models.SlotRiskWarning, report.Level)
+	assert.Equal(t, "old_standby", report.RecommendedDrop)
+	assert.Contains(t, report.Findings, "max_slot_wal_keep_size is unlimited: an abandoned slot retains WAL until the disk is full")
+}
+
+// TestAssess_ImminentDiskFull is critical within a day
+func TestAssess_ImminentDiskFull(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	report := &models.SlotRiskReport{}
+	Assess(report, at, newTestSamples(at, 10))
+
+	require.NotNil(t, report.DiskFullAt)
+	assert.Equal(t, at.Add(15*time.Hour), *report.DiskFullAt)
+	assert.Equal(t, models.SlotRiskCritical, report.Level)
+	assert.Equal(t, models.SlotRiskCritical, findSlot(t, report, "old_standby").Level)
+}
+
+// TestAssess_MaxSlotWalKeepSize invalidates the slot before the disk fills
+func TestAssess_MaxSlotWalKeepSize(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	samples := newTestSamples(at, 20)
+	samples.Position.MaxSlotWalKeepSizeBytes = int64Ptr(16 << 30)
+
+	report := &models.SlotRiskReport{}
+	Assess(report, at, samples)
+
+	assert.Nil(t, report.DiskFullAt)
+	old := findSlot(t, report, "old_standby")
+	require.NotNil(t, old.InvalidatedAt)
+	assert.Equal(t, at.Add(12*time.Hour), *old.InvalidatedAt)
+}
+
+// TestAssess_LowWalRate leaves dates past what a time.Duration holds unset
+// rather than overflowing into the past
+func TestAssess_LowWalRate(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	samples := newTestSamples(at, 20)
+	samples.BaselinePosition.CurrentWalLsn = "9/FFFFFF00"
+	samples.Position.MaxSlotWalKeepSizeBytes = int64Ptr(16 << 30)
+
+	report := &models.SlotRiskReport{}
+	Assess(report, at, samples)
+
+	require.NotNil(t, report.WalBytesPerHour)
+	assert.Nil(t, report.DiskFullAt)
+	assert.Nil(t, findSlot(t, report, "old_standby").InvalidatedAt)
+	assert.Equal(t, models.SlotRiskOK, findSlot(t, report, "standby1").Level)
+}
+
+// TestAssess_StuckLogicalSlot flags a connected logical slot that stopped
+// confirming and holds catalog_xmin back
+func TestAssess_StuckLogicalSlot(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	samples := &Samples{
+		Position:         &models.WalPosition{Time: at, CurrentWalLsn: "2/00000000"},
+		BaselinePosition: &models.WalPosition{Time: at.Add(-time.Hour), CurrentWalLsn: "1/80000000"},
+		Slots: []*models.ReplicationSlot{
+			{Timestamp: at, SlotName: "cdc", SlotType: "logical", DatabaseName: "shop", Active: true,
+				RestartLsn: "1/00000000", ConfirmedFlushLsn: "1/10000000", CatalogXminAge: int64Ptr(160000000)},
+			{Timestamp: at, SlotName: "sub_ok", SlotType: "logical", Active: true,
+				RestartLsn: "1/F0000000", ConfirmedFlushLsn: "2/00000000", CatalogXminAge: int64Ptr(1000)},
+		},
+		BaselineSlots: []*models.ReplicationSlot{
+			{Timestamp: at.Add(-time.Hour), SlotName: "cdc", RestartLsn: "1/00000000", ConfirmedFlushLsn: "1/10000000"},
+			{Timestamp: at.Add(-time.Hour), SlotName: "sub_ok", RestartLsn: "1/70000000", ConfirmedFlushLsn: "1/80000000"},
+		},
+	}
+
+	report := &models.SlotRiskReport{}
+	Assess(report, at, samples)
+
+	cdc := findSlot(t, report, "cdc")
+	require.NotNil(t, cdc.Advancing)
+	assert.False(t, *cdc.Advancing)
+	assert.Equal(t, models.SlotRiskCritical, cdc.Level)
+	assert.Len(t, cdc.Reasons, 2)
+	assert.Equal(t, int64(1<<32), cdc.RetainedBytes)
+
+	assert.Equal(t, models.SlotRiskOK, findSlot(t, report, "sub_ok").Level)
+	assert.Equal(t, "cdc", report.RecommendedDrop)
+	assert.Equal(t, models.SlotRiskCritical, report.Level)
+	assert.Nil(t, report.DiskFullAt, "no disk usage reported")
+}
+
+// TestAssess_LostSlot is critical and dropped
+func TestAssess_LostSlot(t *testing.T) {
+	at := time.Now()
+	report := &models.SlotRiskReport{}
+	Assess(report, at, &Samples{
+		Slots: []*models.ReplicationSlot{{Timestamp: at, SlotName: "gone", SlotType: "physical", WalStatus: "lost"}},
+	})
+
+	assert.Equal(t, models.SlotRiskCritical, report.Level)
+	assert.Equal(t, "gone", report.RecommendedDrop)
+	assert.Nil(t, report.WalBytesPerHour)
+}
+
+// TestParseLSN parses both halves as hexadecimal
+func TestParseLSN(t *testing.T) {
+	lsn, ok := ParseLSN("16/B374D848")
+	require.True(t, ok)
+	assert.Equal(t, int64(0x16)<<32|0xB374D848, lsn)
+
+	for _, invalid := range []string{"", "16", "G/0", "1/100000000"} {
+		_, ok := ParseLSN(invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+// TestParseAlertCondition validates the collector, metric and operator
+func TestParseAlertCondition(t *testing.T) {
+	collectorID := uuid.New()
+	cond, err := ParseAlertCondition(json.RawMessage(`{"collector_id":"` + collectorID.String() + `","metric":"hours_to_disk_full","operator":"<","value":48}`))
+	require.NoError(t, err)
+	assert.Equal(t, collectorID, cond.CollectorID)
+	assert.Equal(t, MetricHoursToDiskFull, cond.Metric)
+
+	for name, raw := range map[string]string{
+		"collector": `{"metric":"inactive_slots","operator":">","value":0}`,
+		"metric":    `{"collector_id":"` + collectorID.String() + `","metric":"slots","operator":">","value":0}`,
+		"operator":  `{"collector_id":"` + collectorID.String() + `","metric":"inactive_slots","operator":"=>","value":0}`,
+		"json":      `{`,
+	} {
+		_, err := ParseAlertCondition(json.RawMessage(raw))
+		assert.Error(t, err, name)
+	}
+}
+
+// TestMetricValue has no disk fill time when nothing fills the disk
+func TestMetricValue(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	report := &models.SlotRiskReport{}
+	Assess(report, at, newTestSamples(at, 20))
+
+	hours, ok := MetricValue(report, MetricHoursToDiskFull)
+	require.True(t, ok)
+	assert.InDelta(t, 30, hours, 0.001)
+
+	inactive, ok := MetricValue(report, MetricInactiveSlots)
+	require.True(t, ok)
+	assert.Equal(t, float64(1), inactive)
+
+	report.DiskFullAt = nil
+	_, ok = MetricValue(report, MetricHoursToDiskFull)
+	assert.False(t, ok)
+
+	_, ok = MetricValue(&models.SlotRiskReport{}, MetricTotalRetainedBytes)
+	assert.False(t, ok, "no samples")
+}
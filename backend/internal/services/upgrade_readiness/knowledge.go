@@ -0,0 +1,192 @@
+package upgrade_readiness
+
+// extensionRelease is a release line of an extension and the PostgreSQL
+// major versions it supports
+type extensionRelease struct {
+	version  string // matches installed versions equal to it or starting with it and a dot
+	minMajor int
+	maxMajor int
+}
+
+// extensionReleases lists the release lines of third-party extensions whose
+// support for PostgreSQL versions is tied to their own versions. Extensions
+// not listed here or in contribModules are reported as unknown.
+var extensionReleases = map[string][]extensionRelease{
+	"citus": {
+		{version: "11.3", minMajor: 13, maxMajor: 15},
+		{version: "12.1", minMajor: 14, maxMajor: 16},
+		{version: "13.0", minMajor: 15, maxMajor: 17},
+	},
+	"pgaudit": {
+		{version: "1.5", minMajor: 13, maxMajor: 13},
+		{version: "1.6", minMajor: 14, maxMajor: 14},
+		{version: "1.7", minMajor: 15, maxMajor: 15},
+		{version: "16", minMajor: 16, maxMajor: 16},
+		{version: "17", minMajor: 17, maxMajor: 17},
+	},
+	"postgis": {
+		{version: "3.3", minMajor: 11, maxMajor: 15},
+		{version: "3.4", minMajor: 12, maxMajor: 16},
+		{version: "3.5", minMajor: 12, maxMajor: 17},
+	},
+	"timescaledb": {
+		{version: "2.13", minMajor: 13, maxMajor: 16},
+		{version: "2.14", minMajor: 13, maxMajor: 16},
+		{version: "2.15", minMajor: 13, maxMajor: 16},
+		{version: "2.16", minMajor: 14, maxMajor: 16},
+		{version: "2.17", minMajor: 14, maxMajor: 17},
+	},
+}
+
+// contribModules ship with PostgreSQL and are available on every version
+// that has them. removedIn is the first major version without the module.
+var contribModules = map[string]int{
+	"adminpack":          17,
+	"amcheck":            0,
+	"autoinc":            0,
+	"bloom":              0,
+	"btree_gin":          0,
+	"btree_gist":         0,
+	"chkpass":            11,
+	"citext":             0,
+	"cube":               0,
+	"dblink":             0,
+	"dict_int":           0,
+	"dict_xsyn":          0,
+	"earthdistance":      0,
+	"file_fdw":           0,
+	"fuzzystrmatch":      0,
+	"hstore":             0,
+	"insert_username":    0,
+	"intagg":             0,
+	"intarray":           0,
+	"isn":                0,
+	"lo":                 0,
+	"ltree":              0,
+	"moddatetime":        0,
+	"old_snapshot":       17,
+	"pageinspect":        0,
+	"pg_buffercache":     0,
+	"pg_freespacemap":    0,
+	"pg_prewarm":         0,
+	"pg_stat_statements": 0,
+	"pg_surgery":         0,
+	"pg_trgm":            0,
+	"pg_visibility":      0,
+	"pg_walinspect":      0,
+	"pgcrypto":           0,
+	"pgrowlocks":         0,
+	"pgstattuple":        0,
+	"plpgsql":            0,
+	"postgres_fdw":       0,
+	"refint":             0,
+	"seg":                0,
+	"sslinfo":            0,
+	"tablefunc":          0,
+	"tcn":                0,
+	"timetravel":         12,
+	"tsearch2":           10,
+	"tsm_system_rows":    0,
+	"tsm_system_time":    0,
+	"unaccent":           0,
+	"uuid-ossp":          0,
+	"xml2":               0,
+}
+
+// removedSetting is a server setting a major version removed, with what
+// replaced it if anything
+type removedSetting struct {
+	removedIn  int
+	replacedBy string
+}
+
+// removedSettings are settings removed since PostgreSQL 10, keyed by name
+var removedSettings = map[string]removedSetting{
+	"min_parallel_relation_size":        {removedIn: 10, replacedBy: "min_parallel_table_scan_size"},
+	"sql_inheritance":                   {removedIn: 10},
+	"replacement_sort_tuples":           {removedIn: 11},
+	"standby_mode":                      {removedIn: 12, replacedBy: "a standby.signal file"},
+	"wal_keep_segments":                 {removedIn: 13, replacedBy: "wal_keep_size"},
+	"operator_precedence_warning":       {removedIn: 14},
+	"vacuum_cleanup_index_scale_factor": {removedIn: 14},
+	"stats_temp_directory":              {removedIn: 15},
+	"force_parallel_mode":               {removedIn: 16, replacedBy: "debug_parallel_query"},
+	"promote_trigger_file":              {removedIn: 16, replacedBy: "pg_ctl promote or pg_promote()"},
+	"vacuum_defer_cleanup_age":          {removedIn: 16},
+	"db_user_namespace":                 {removedIn: 17},
+	"old_snapshot_threshold":            {removedIn: 17},
+	"trace_recovery_messages":           {removedIn: 17},
+}
+
+// schemaCheck is a column data type or collector-reported object kind that
+// blocks pg_upgrade to versions from since on; since 0 blocks every upgrade
+type schemaCheck struct {
+	since  int
+	title  string
+	detail string
+	action string
+}
+
+// columnTypeChecks are keyed by the data type information_schema reports
+var columnTypeChecks = map[string]schemaCheck{
+	"abstime": {
+		since:  12,
+		title:  "Columns of the removed abstime type",
+		detail: "PostgreSQL 12 removed abstime, reltime and tinterval; pg_upgrade refuses tables that use them.",
+		action: "ALTER TABLE ... ALTER COLUMN ... TYPE timestamptz USING column::timestamptz",
+	},
+	"reltime": {
+		since:  12,
+		title:  "Columns of the removed reltime type",
+		detail: "PostgreSQL 12 removed abstime, reltime and tinterval; pg_upgrade refuses tables that use them.",
+		action: "ALTER TABLE ... ALTER COLUMN ... TYPE interval USING column::interval",
+	},
+	"tinterval": {
+		since:  12,
+		title:  "Columns of the removed tinterval type",
+		detail: "PostgreSQL 12 removed abstime, reltime and tinterval; pg_upgrade refuses tables that use them.",
+		action: "Replace the column with a tstzrange or a pair of timestamptz columns",
+	},
+	"aclitem": {
+		since:  16,
+		title:  "Columns of type aclitem",
+		detail: "PostgreSQL 16 changed the storage format of aclitem; pg_upgrade refuses user tables that store it.",
+		action: "Store privileges as text (aclitem::text) before upgrading",
+	},
+}
+
+// regTypes are the reg* types pg_upgrade refuses in user tables on any
+// upgrade, because they store OIDs that change. regclass, regrole and
+// regtype are preserved and allowed.
+var regTypes = []string{
+	"regcollation",
+	"regconfig",
+	"regdictionary",
+	"regnamespace",
+	"regoper",
+	"regoperator",
+	"regproc",
+	"regprocedure",
+}
+
+// objectChecks are keyed by the kinds of the pg_upgrade_objects metric
+var objectChecks = map[string]schemaCheck{
+	"postfix_operator": {
+		since:  14,
+		title:  "Postfix operators",
+		detail: "PostgreSQL 14 removed postfix operators; pg_upgrade refuses user-defined ones.",
+		action: "Drop the operators and call their functions directly",
+	},
+	"encoding_conversion": {
+		since:  14,
+		title:  "User-defined encoding conversions",
+		detail: "PostgreSQL 14 changed the signature of conversion functions; pg_upgrade refuses user-defined conversions.",
+		action: "Drop the conversions and recreate them with the new signature after the upgrade",
+	},
+	"incompatible_polymorphic": {
+		since:  14,
+		title:  "Objects over changed polymorphic functions",
+		detail: "PostgreSQL 14 changed array_append, array_prepend, array_cat, array_position(s) and array_remove from anyarray to anycompatiblearray; pg_upgrade refuses user-defined aggregates and operators over them.",
+		action: "Drop the aggregates or operators and recreate them over anycompatible types after the upgrade",
+	},
+}
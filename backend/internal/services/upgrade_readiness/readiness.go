@@ -0,0 +1,379 @@
+package upgrade_readiness
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+const (
+	severityCritical = "critical"
+	severityWarning  = "warning"
+	severityInfo     = "info"
+
+	// eolWarningWindow is how close to its end of life a target version is
+	// pointed out
+	eolWarningWindow = 365 * 24 * time.Hour
+)
+
+// Inventory is what is known about an instance's schema and configuration
+type Inventory struct {
+	Extensions []*models.ExtensionInventory
+	OidTables  []*models.TableInventory
+	Columns    []*models.ColumnInventory // of the types ColumnTypes returns
+	Settings   []*models.PgSetting       // nil without a settings snapshot
+	Objects    []*models.UpgradeObject
+}
+
+// ColumnTypes returns the column data types that affect upgrades, as
+// information_schema names them
+func ColumnTypes() []string {
+	types := append([]string{}, regTypes...)
+	for t := range columnTypeChecks {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Assess lists what blocks or affects upgrading an instance from source to
+// target, most severe first
+func Assess(source, target *models.PostgreSQLVersion, inv *Inventory, now time.Time) []*models.UpgradeFinding {
+	findings := []*models.UpgradeFinding{}
+	findings = append(findings, versionFindings(source, target, now)...)
+	findings = append(findings, extensionFindings(source.Major, target.Major, inv.Extensions)...)
+	findings = append(findings, settingFindings(source.Major, target.Major, inv.Settings)...)
+	findings = append(findings, schemaFindings(source.Major, target.Major, inv)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if ri, rj := severityRank[findings[i].Severity], severityRank[findings[j].Severity]; ri != rj {
+			return ri < rj
+		}
+		if ci, cj := categoryRank[findings[i].Category], categoryRank[findings[j].Category]; ci != cj {
+			return ci < cj
+		}
+		return findings[i].Title < findings[j].Title
+	})
+	return findings
+}
+
+// blocks reports whether a change introduced in version since applies to an
+// upgrade from source to target; since 0 applies to every upgrade
+func blocks(since, source, target int) bool {
+	return since == 0 || (source < since && since <= target)
+}
+
+func versionFindings(source, target *models.PostgreSQLVersion, now time.Time) []*models.UpgradeFinding {
+	findings := []*models.UpgradeFinding{}
+	if !target.IsSupported {
+		findings = append(findings, &models.UpgradeFinding{
+			Category: models.UpgradeCategoryVersion,
+			Severity: severityWarning,
+			Title:    fmt.Sprintf("PostgreSQL %d is not supported by the community", target.Major),
+			Detail:   "It no longer receives bug or security fixes.",
+			Action:   "Choose a supported target version",
+		})
+	} else if !target.EOLDate.IsZero() && target.EOLDate.Sub(now) < eolWarningWindow {
+		findings = append(findings, &models.UpgradeFinding{
+			Category: models.UpgradeCategoryVersion,
+			Severity: severityInfo,
+			Title:    fmt.Sprintf("PostgreSQL %d reaches end of life on %s", target.Major, target.EOLDate.Format("2006-01-02")),
+			Detail:   "Another upgrade will be due within a year.",
+		})
+	}
+	if target.Major-source.Major > 1 {
+		findings = append(findings, &models.UpgradeFinding{
+			Category: models.UpgradeCategoryVersion,
+			Severity: severityInfo,
+			Title:    fmt.Sprintf("The upgrade spans %d major versions", target.Major-source.Major),
+			Detail:   fmt.Sprintf("Incompatibilities of every release from %d to %d apply.", source.Major+1, target.Major),
+			Action:   fmt.Sprintf("Read the migration notes of PostgreSQL %d to %d", source.Major+1, target.Major),
+		})
+	}
+	return findings
+}
+
+// extensionFindings checks each installed extension version, across all
+// databases, for a release that supports the target
+func extensionFindings(source, target int, extensions []*models.ExtensionInventory) []*models.UpgradeFinding {
+	type installed struct {
+		name, version string
+		databases     []string
+	}
+	byKey := map[string]*installed{}
+	keys := []string{}
+	for _, e := range extensions {
+		key := e.ExtensionName + "@" + e.ExtensionVersion
+		i, ok := byKey[key]
+		if !ok {
+			i = &installed{name: e.ExtensionName, version: e.ExtensionVersion}
+			byKey[key] = i
+			keys = append(keys, key)
+		}
+		i.databases = append(i.databases, e.DatabaseName)
+	}
+
+	findings := []*models.UpgradeFinding{}
+	for _, key := range keys {
+		i := byKey[key]
+		f := &models.UpgradeFinding{
+			Category: models.UpgradeCategoryExtension,
+			Objects:  i.databases,
+		}
+
+		if removedIn, ok := contribModules[i.name]; ok {
+			if removedIn == 0 || !blocks(removedIn, source, target) {
+				continue
+			}
+			f.Severity = severityCritical
+			f.Title = fmt.Sprintf("Extension %s was removed in PostgreSQL %d", i.name, removedIn)
+			f.Detail = "pg_upgrade fails on databases that still have it installed."
+			f.Action = fmt.Sprintf("DROP EXTENSION %s in each listed database", i.name)
+			findings = append(findings, f)
+			continue
+		}
+
+		releases, ok := extensionReleases[i.name]
+		if !ok {
+			f.Severity = severityInfo
+			f.Title = fmt.Sprintf("Compatibility of extension %s %s is unknown", i.name, i.version)
+			f.Detail = fmt.Sprintf("Check that a build for PostgreSQL %d exists before upgrading.", target)
+			f.Action = fmt.Sprintf("Install %s for PostgreSQL %d on the new server", i.name, target)
+			findings = append(findings, f)
+			continue
+		}
+
+		var current, bridge, targetOnly *extensionRelease
+		for r := range releases {
+			release := &releases[r]
+			if matchesRelease(i.version, release.version) {
+				current = release
+			}
+			supportsTarget := release.minMajor <= target && target <= release.maxMajor
+			if supportsTarget && release.minMajor <= source && source <= release.maxMajor && bridge == nil {
+				bridge = release
+			}
+			if supportsTarget && targetOnly == nil {
+				targetOnly = release
+			}
+		}
+
+		switch {
+		case current != nil && current.minMajor <= target && target <= current.maxMajor:
+			continue
+		case bridge != nil:
+			f.Severity = severityWarning
+			f.Title = fmt.Sprintf("Extension %s %s does not support PostgreSQL %d", i.name, i.version, target)
+			f.Detail = fmt.Sprintf("%s %s supports both PostgreSQL %d and %d.", i.name, bridge.version, source, target)
+			f.Action = fmt.Sprintf("Update %s to %s before upgrading (ALTER EXTENSION %s UPDATE)", i.name, bridge.version, i.name)
+		case targetOnly != nil:
+			f.Severity = severityWarning
+			f.Title = fmt.Sprintf("No %s release supports both PostgreSQL %d and %d", i.name, source, target)
+			f.Detail = fmt.Sprintf("%s %s supports PostgreSQL %d; pg_upgrade needs the installed version on both servers.", i.name, targetOnly.version, target)
+			f.Action = fmt.Sprintf("Upgrade in steps through an intermediate version, or dump and restore into %s %s", i.name, targetOnly.version)
+		default:
+			f.Severity = severityCritical
+			f.Title = fmt.Sprintf("No known %s release supports PostgreSQL %d", i.name, target)
+			f.Detail = "The databases using it cannot be upgraded until one is released."
+			f.Action = fmt.Sprintf("Choose an earlier target version or remove %s", i.name)
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// matchesRelease reports whether an installed version belongs to a release
+// line, e.g. "3.4.2" to "3.4"
+func matchesRelease(installed, release string) bool {
+	return installed == release || strings.HasPrefix(installed, release+".")
+}
+
+// settingFindings flags settings set away from their defaults that the
+// target no longer has. A new server refuses to start with them in
+// postgresql.conf; set per database or role, restoring them fails.
+func settingFindings(source, target int, settings []*models.PgSetting) []*models.UpgradeFinding {
+	findings := []*models.UpgradeFinding{}
+	for _, s := range settings {
+		removed, ok := removedSettings[s.Name]
+		if !ok || s.Source == "" || s.Source == "default" || !blocks(removed.removedIn, source, target) {
+			continue
+		}
+		f := &models.UpgradeFinding{
+			Category: models.UpgradeCategorySetting,
+			Severity: severityWarning,
+			Title:    fmt.Sprintf("Setting %s was removed in PostgreSQL %d", s.Name, removed.removedIn),
+			Detail:   fmt.Sprintf("It is set from %s to %s.", s.Source, s.Setting),
+			Objects:  []string{s.Name},
+			Action:   fmt.Sprintf("Remove %s from the configuration", s.Name),
+		}
+		if s.Source == "configuration file" {
+			f.Severity = severityCritical
+			f.Detail += " The new server will not start with it in postgresql.conf."
+		}
+		if removed.replacedBy != "" {
+			f.Action = fmt.Sprintf("Replace %s with %s", s.Name, removed.replacedBy)
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// schemaFindings groups the tables, columns and objects pg_upgrade refuses
+func schemaFindings(source, target int, inv *Inventory) []*models.UpgradeFinding {
+	findings := []*models.UpgradeFinding{}
+
+	if len(inv.OidTables) > 0 && blocks(12, source, target) {
+		f := &models.UpgradeFinding{
+			Category: models.UpgradeCategorySchema,
+			Severity: severityCritical,
+			Title:    "Tables created WITH OIDS",
+			Detail:   "PostgreSQL 12 removed WITH OIDS; pg_upgrade refuses tables that have it.",
+			Action:   "ALTER TABLE ... SET WITHOUT OIDS on each listed table",
+		}
+		for _, t := range inv.OidTables {
+			f.Objects = append(f.Objects, qualify(t.DatabaseName, t.SchemaName, t.TableName))
+		}
+		findings = append(findings, f)
+	}
+
+	byTitle := map[string]*models.UpgradeFinding{}
+	add := func(check schemaCheck, object string) {
+		if !blocks(check.since, source, target) {
+			return
+		}
+		f, ok := byTitle[check.title]
+		if !ok {
+			f = &models.UpgradeFinding{
+				Category: models.UpgradeCategorySchema,
+				Severity: severityCritical,
+				Title:    check.title,
+				Detail:   check.detail,
+				Action:   check.action,
+			}
+			byTitle[check.title] = f
+			findings = append(findings, f)
+		}
+		f.Objects = append(f.Objects, object)
+	}
+
+	for _, c := range inv.Columns {
+		if check, ok := columnCheck(c.DataType); ok {
+			add(check, qualify(c.DatabaseName, c.SchemaName, c.TableName, c.ColumnName))
+		}
+	}
+	for _, o := range inv.Objects {
+		if check, ok := objectChecks[o.Kind]; ok {
+			add(check, qualify(o.Database, o.Schema, o.Name))
+		}
+	}
+
+	return findings
+}
+
+var regTypeCheck = schemaCheck{
+	title:  "Columns of reg* types",
+	detail: "These types store OIDs that pg_upgrade does not preserve, so it refuses tables that use them. regclass, regrole and regtype are allowed.",
+	action: "ALTER TABLE ... ALTER COLUMN ... TYPE text, or regclass/regtype where that fits",
+}
+
+func columnCheck(dataType string) (schemaCheck, bool) {
+	dataType = strings.ToLower(dataType)
+	for _, t := range regTypes {
+		if t == dataType {
+			return regTypeCheck, true
+		}
+	}
+	check, ok := columnTypeChecks[dataType]
+	return check, ok
+}
+
+// qualify joins the non-empty parts of an object name with dots
+func qualify(parts ...string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, ".")
+}
+
+// Checklist turns findings into upgrade steps: fixing what blocks the
+// upgrade first, then the checks every major-version upgrade needs, then
+// the steps after it
+func Checklist(source, target int, findings []*models.UpgradeFinding, hasExtensions bool) []*models.UpgradeChecklistItem {
+	items := []*models.UpgradeChecklistItem{}
+	add := func(phase, severity, title, detail string) {
+		items = append(items, &models.UpgradeChecklistItem{
+			Step:     len(items) + 1,
+			Phase:    phase,
+			Severity: severity,
+			Title:    title,
+			Detail:   detail,
+		})
+	}
+
+	for _, f := range findings {
+		if f.Severity == severityInfo || f.Action == "" {
+			continue
+		}
+		detail := f.Title
+		if len(f.Objects) > 0 {
+			detail = fmt.Sprintf("%s: %s", f.Title, strings.Join(f.Objects, ", "))
+		}
+		add(models.UpgradePhaseBefore, f.Severity, f.Action, detail)
+	}
+	for _, f := range findings {
+		if f.Severity == severityInfo && f.Action != "" {
+			add(models.UpgradePhaseBefore, f.Severity, f.Action, f.Title)
+		}
+	}
+
+	add(models.UpgradePhaseBefore, severityInfo, "Take a backup and verify that it restores", "")
+	add(models.UpgradePhaseBefore, severityInfo,
+		fmt.Sprintf("Run pg_upgrade --check against a new PostgreSQL %d cluster", target),
+		fmt.Sprintf("It repeats these checks against the live %d catalog, including objects not collected.", source))
+
+	add(models.UpgradePhaseAfter, severityWarning, "Run vacuumdb --all --analyze-in-stages",
+		"pg_upgrade does not carry planner statistics over; queries plan poorly until tables are analyzed.")
+	if hasExtensions {
+		add(models.UpgradePhaseAfter, severityInfo, "Run ALTER EXTENSION ... UPDATE for each extension in each database",
+			"pg_upgrade keeps the installed extension versions.")
+	}
+	add(models.UpgradePhaseAfter, severityInfo, "Rebuild or resync standbys",
+		"Standbys of the old cluster cannot follow the upgraded primary.")
+
+	return items
+}
+
+// Summarize counts findings by severity
+func Summarize(findings []*models.UpgradeFinding) models.UpgradeReadinessSummary {
+	var summary models.UpgradeReadinessSummary
+	for _, f := range findings {
+		switch f.Severity {
+		case severityCritical:
+			summary.Critical++
+		case severityWarning:
+			summary.Warning++
+		default:
+			summary.Info++
+		}
+	}
+	return summary
+}
+
+var severityRank = map[string]int{
+	severityCritical: 0,
+	severityWarning:  1,
+	severityInfo:     2,
+}
+
+var categoryRank = map[string]int{
+	models.UpgradeCategoryVersion:   0,
+	models.UpgradeCategoryExtension: 1,
+	models.UpgradeCategorySetting:   2,
+	models.UpgradeCategorySchema:    3,
+}
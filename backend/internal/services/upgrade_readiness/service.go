@@ -0,0 +1,147 @@
+package upgrade_readiness
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Store interface for the version, inventory and settings an upgrade is
+// assessed from
+type Store interface {
+	GetPostgreSQLVersion(ctx context.Context, collectorID uuid.UUID) (*models.PostgreSQLVersion, error)
+	GetAllSupportedVersions() []*models.PostgreSQLVersion
+	GetLatestExtensionInventory(ctx context.Context, collectorID uuid.UUID) ([]*models.ExtensionInventory, error)
+	GetLatestOidTables(ctx context.Context, collectorID uuid.UUID) ([]*models.TableInventory, error)
+	GetLatestColumnsOfTypes(ctx context.Context, collectorID uuid.UUID, dataTypes []string) ([]*models.ColumnInventory, error)
+	GetCurrentSettings(ctx context.Context, collectorID uuid.UUID) (*models.CollectorSettings, error)
+	GetUpgradeObjects(ctx context.Context, collectorID uuid.UUID) ([]*models.UpgradeObject, error)
+	ReplaceUpgradeObjects(ctx context.Context, collectorID uuid.UUID, at time.Time, objects []*models.UpgradeObject) error
+}
+
+// Service assesses major-version upgrade readiness
+type Service struct {
+	store  Store
+	logger *zap.Logger
+}
+
+// NewService creates a new upgrade readiness service
+func NewService(store Store, logger *zap.Logger) *Service {
+	return &Service{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// IngestObjects stores a collector's pg_upgrade_objects snapshot. Objects
+// without a kind or name are dropped; an empty snapshot clears the previous
+// one.
+func (s *Service) IngestObjects(ctx context.Context, collectorID uuid.UUID, at time.Time, objects []models.UpgradeObject) (int, error) {
+	kept := make([]*models.UpgradeObject, 0, len(objects))
+	for i := range objects {
+		o := objects[i]
+		o.Kind = strings.TrimSpace(o.Kind)
+		o.Name = strings.TrimSpace(o.Name)
+		if o.Kind == "" || o.Name == "" {
+			continue
+		}
+		kept = append(kept, &o)
+	}
+
+	if err := s.store.ReplaceUpgradeObjects(ctx, collectorID, at, kept); err != nil {
+		s.logger.Error("Failed to store upgrade objects", zap.Error(err))
+		return 0, err
+	}
+	return len(kept), nil
+}
+
+// Report assesses upgrading a collector's instance to a target major
+// version, which must be a known version newer than the current one
+func (s *Service) Report(ctx context.Context, collectorID uuid.UUID, targetMajor int, now time.Time) (*models.UpgradeReadinessReport, error) {
+	source, err := s.store.GetPostgreSQLVersion(ctx, collectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *models.PostgreSQLVersion
+	majors := []string{}
+	versions := s.store.GetAllSupportedVersions()
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Major < versions[j].Major })
+	for _, v := range versions {
+		if v.Major > source.Major {
+			majors = append(majors, fmt.Sprintf("%d", v.Major))
+		}
+		if v.Major == targetMajor {
+			target = v
+		}
+	}
+	if target == nil || targetMajor <= source.Major {
+		detail := fmt.Sprintf("PostgreSQL %d is the newest known version", source.Major)
+		if len(majors) > 0 {
+			detail = fmt.Sprintf("expected a version newer than %d: %s", source.Major, strings.Join(majors, ", "))
+		}
+		return nil, apperrors.BadRequest("Invalid target version", detail)
+	}
+
+	inv, notes, err := s.inventory(ctx, collectorID)
+	if err != nil {
+		return nil, err
+	}
+
+	findings := Assess(source, target, inv, now)
+	summary := Summarize(findings)
+	return &models.UpgradeReadinessReport{
+		CollectorID:   collectorID,
+		SourceVersion: *source,
+		TargetVersion: *target,
+		Ready:         summary.Critical == 0,
+		Summary:       summary,
+		Findings:      findings,
+		Checklist:     Checklist(source.Major, target.Major, findings, len(inv.Extensions) > 0),
+		Notes:         notes,
+		GeneratedAt:   now,
+	}, nil
+}
+
+// inventory loads what the assessment needs, with notes on what has not
+// been collected
+func (s *Service) inventory(ctx context.Context, collectorID uuid.UUID) (*Inventory, []string, error) {
+	inv := &Inventory{}
+	notes := []string{}
+	var err error
+
+	if inv.Extensions, err = s.store.GetLatestExtensionInventory(ctx, collectorID); err != nil {
+		return nil, nil, err
+	}
+	if len(inv.Extensions) == 0 {
+		notes = append(notes, "No extension inventory has been collected; extensions were not checked")
+	}
+	if inv.OidTables, err = s.store.GetLatestOidTables(ctx, collectorID); err != nil {
+		return nil, nil, err
+	}
+	if inv.Columns, err = s.store.GetLatestColumnsOfTypes(ctx, collectorID, ColumnTypes()); err != nil {
+		return nil, nil, err
+	}
+	if inv.Objects, err = s.store.GetUpgradeObjects(ctx, collectorID); err != nil {
+		return nil, nil, err
+	}
+
+	settings, err := s.store.GetCurrentSettings(ctx, collectorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(settings.Settings) == 0 {
+		notes = append(notes, "The collector has not reported pg_settings yet; removed settings were not checked")
+	} else {
+		inv.Settings = settings.Settings
+	}
+
+	return inv, notes, nil
+}
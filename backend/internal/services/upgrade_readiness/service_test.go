@@ -0,0 +1,275 @@
+package upgrade_readiness
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockUpgradeStore is a mock implementation for testing
+type mockUpgradeStore struct {
+	version    *models.PostgreSQLVersion
+	extensions []*models.ExtensionInventory
+	oidTables  []*models.TableInventory
+	columns    []*models.ColumnInventory
+	settings   []*models.PgSetting
+	objects    []*models.UpgradeObject
+	err        error
+
+	replaced []*models.UpgradeObject
+}
+
+func (m *mockUpgradeStore) GetPostgreSQLVersion(ctx context.Context, collectorID uuid.UUID) (*models.PostgreSQLVersion, error) {
+	return m.version, m.err
+}
+
+func (m *mockUpgradeStore) GetAllSupportedVersions() []*models.PostgreSQLVersion {
+	versions := []*models.PostgreSQLVersion{}
+	for major := 17; major >= 11; major-- {
+		versions = append(versions, &models.PostgreSQLVersion{
+			Major:       major,
+			IsSupported: major >= 13,
+			EOLDate:     time.Date(2012+major, 11, 10, 0, 0, 0, 0, time.UTC),
+		})
+	}
+	return versions
+}
+
+func (m *mockUpgradeStore) GetLatestExtensionInventory(ctx context.Context, collectorID uuid.UUID) ([]*models.ExtensionInventory, error) {
+	return m.extensions, m.err
+}
+
+func (m *mockUpgradeStore) GetLatestOidTables(ctx context.Context, collectorID uuid.UUID) ([]*models.TableInventory, error) {
+	return m.oidTables, m.err
+}
+
+func (m *mockUpgradeStore) GetLatestColumnsOfTypes(ctx context.Context, collectorID uuid.UUID, dataTypes []string) ([]*models.ColumnInventory, error) {
+	return m.columns, m.err
+}
+
+func (m *mockUpgradeStore) GetCurrentSettings(ctx context.Context, collectorID uuid.UUID) (*models.CollectorSettings, error) {
+	settings := m.settings
+	if settings == nil {
+		settings = []*models.PgSetting{}
+	}
+	return &models.CollectorSettings{CollectorID: collectorID, Settings: settings}, m.err
+}
+
+func (m *mockUpgradeStore) GetUpgradeObjects(ctx context.Context, collectorID uuid.UUID) ([]*models.UpgradeObject, error) {
+	return m.objects, m.err
+}
+
+func (m *mockUpgradeStore) ReplaceUpgradeObjects(ctx context.Context, collectorID uuid.UUID, at time.Time, objects []*models.UpgradeObject) error {
+	m.replaced = objects
+	return m.err
+}
+
+// newTestStore returns a PostgreSQL 13 instance with something wrong in
+// every category for an upgrade to 16
+func newTestStore() *mockUpgradeStore {
+	return &mockUpgradeStore{
+		version: &models.PostgreSQLVersion{Major: 13, Minor: 4, FullVersion: "13.4", IsSupported: true},
+		extensions: []*models.ExtensionInventory{
+			{DatabaseName: "app", ExtensionName: "postgis", ExtensionVersion: "3.3.2"},
+			{DatabaseName: "geo", ExtensionName: "postgis", ExtensionVersion: "3.3.2"},
+			{DatabaseName: "app", ExtensionName: "pg_stat_statements", ExtensionVersion: "1.8"},
+			{DatabaseName: "app", ExtensionName: "pgaudit", ExtensionVersion: "1.5.2"},
+			{DatabaseName: "app", ExtensionName: "timescaledb", ExtensionVersion: "2.14.2"},
+			{DatabaseName: "app", ExtensionName: "in_house", ExtensionVersion: "0.1"},
+		},
+		columns: []*models.ColumnInventory{
+			{DatabaseName: "app", SchemaName: "public", TableName: "jobs", ColumnName: "handler", DataType: "regproc"},
+			{DatabaseName: "app", SchemaName: "public", TableName: "grants", ColumnName: "acl", DataType: "aclitem"},
+		},
+		settings: []*models.PgSetting{
+			{Name: "vacuum_defer_cleanup_age", Setting: "1000", Source: "configuration file"},
+			{Name: "force_parallel_mode", Setting: "on", Source: "database"},
+			{Name: "stats_temp_directory", Setting: "pg_stat_tmp", Source: "default"},
+			{Name: "wal_keep_segments", Setting: "64", Source: "configuration file"},
+		},
+		objects: []*models.UpgradeObject{
+			{Database: "app", Kind: "postfix_operator", Schema: "public", Name: "!"},
+		},
+	}
+}
+
+func TestReport(t *testing.T) {
+	now := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	service := NewService(newTestStore(), zap.NewNop())
+
+	report, err := service.Report(context.Background(), uuid.New(), 16, now)
+	require.NoError(t, err)
+
+	assert.Equal(t, 13, report.SourceVersion.Major)
+	assert.Equal(t, 16, report.TargetVersion.Major)
+	assert.False(t, report.Ready)
+	assert.Equal(t, models.UpgradeReadinessSummary{Critical: 4, Warning: 3, Info: 2}, report.Summary)
+	assert.Empty(t, report.Notes)
+
+	titles := []string{}
+	for _, f := range report.Findings {
+		titles = append(titles, f.Title)
+	}
+	assert.Equal(t, []string{
+		"Setting vacuum_defer_cleanup_age was removed in PostgreSQL 16",
+		"Columns of reg* types",
+		"Columns of type aclitem",
+		"Postfix operators",
+		"Extension postgis 3.3.2 does not support PostgreSQL 16",
+		"No pgaudit release supports both PostgreSQL 13 and 16",
+		"Setting force_parallel_mode was removed in PostgreSQL 16",
+		"The upgrade spans 3 major versions",
+		"Compatibility of extension in_house 0.1 is unknown",
+	}, titles)
+
+	postgis := report.Findings[4]
+	assert.Equal(t, []string{"app", "geo"}, postgis.Objects)
+	assert.Contains(t, postgis.Action, "Update postgis to 3.4")
+
+	assert.Equal(t, []string{"app.public.jobs.handler"}, report.Findings[1].Objects)
+	assert.Equal(t, "Replace force_parallel_mode with debug_parallel_query", report.Findings[6].Action)
+
+	require.Len(t, report.Checklist, 14)
+	assert.Equal(t, 1, report.Checklist[0].Step)
+	assert.Equal(t, severityCritical, report.Checklist[0].Severity)
+	assert.Equal(t, models.UpgradePhaseBefore, report.Checklist[0].Phase)
+	last := report.Checklist[len(report.Checklist)-1]
+	assert.Equal(t, 14, last.Step)
+	assert.Equal(t, models.UpgradePhaseAfter, last.Phase)
+}
+
+func TestReport_Ready(t *testing.T) {
+	store := &mockUpgradeStore{
+		version: &models.PostgreSQLVersion{Major: 16, FullVersion: "16.2", IsSupported: true},
+		extensions: []*models.ExtensionInventory{
+			{DatabaseName: "app", ExtensionName: "postgis", ExtensionVersion: "3.5.0"},
+		},
+		settings: []*models.PgSetting{{Name: "shared_buffers", Setting: "16384", Source: "configuration file"}},
+	}
+
+	report, err := NewService(store, zap.NewNop()).Report(context.Background(), uuid.New(), 17, time.Now())
+	require.NoError(t, err)
+	assert.True(t, report.Ready)
+	assert.Empty(t, report.Findings)
+	assert.Len(t, report.Checklist, 5)
+}
+
+func TestReport_InvalidTarget(t *testing.T) {
+	service := NewService(newTestStore(), zap.NewNop())
+
+	for _, target := range []int{12, 13, 19} {
+		_, err := service.Report(context.Background(), uuid.New(), target, time.Now())
+		require.Error(t, err)
+		appErr, ok := err.(*apperrors.AppError)
+		require.True(t, ok)
+		assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+		assert.Contains(t, appErr.Details, "14, 15, 16, 17")
+	}
+}
+
+func TestReport_MissingInventory(t *testing.T) {
+	store := &mockUpgradeStore{version: &models.PostgreSQLVersion{Major: 15, IsSupported: true}}
+
+	report, err := NewService(store, zap.NewNop()).Report(context.Background(), uuid.New(), 16, time.Now())
+	require.NoError(t, err)
+	assert.Len(t, report.Notes, 2)
+}
+
+func TestAssess_Schema(t *testing.T) {
+	source := &models.PostgreSQLVersion{Major: 11, IsSupported: false}
+	target := &models.PostgreSQLVersion{Major: 12, IsSupported: false}
+	inv := &Inventory{
+		OidTables: []*models.TableInventory{
+			{DatabaseName: "legacy", SchemaName: "public", TableName: "accounts", HasOids: true},
+		},
+		Columns: []*models.ColumnInventory{
+			{DatabaseName: "legacy", SchemaName: "public", TableName: "audit", ColumnName: "at", DataType: "abstime"},
+			{DatabaseName: "legacy", SchemaName: "public", TableName: "grants", ColumnName: "acl", DataType: "aclitem"},
+		},
+		Objects: []*models.UpgradeObject{
+			{Database: "legacy", Kind: "postfix_operator", Schema: "public", Name: "!"},
+		},
+	}
+
+	findings := Assess(source, target, inv, time.Now())
+
+	// aclitem and postfix operators only block upgrades to 16 and 14
+	require.Len(t, findings, 3)
+	assert.Equal(t, "Columns of the removed abstime type", findings[0].Title)
+	assert.Equal(t, []string{"legacy.public.audit.at"}, findings[0].Objects)
+	assert.Equal(t, "Tables created WITH OIDS", findings[1].Title)
+	assert.Equal(t, []string{"legacy.public.accounts"}, findings[1].Objects)
+	assert.Equal(t, "PostgreSQL 12 is not supported by the community", findings[2].Title)
+	assert.Equal(t, severityWarning, findings[2].Severity)
+}
+
+func TestExtensionFindings(t *testing.T) {
+	tests := []struct {
+		name     string
+		ext      string
+		version  string
+		source   int
+		target   int
+		severity string // empty when compatible
+	}{
+		{"compatible", "postgis", "3.5.1", 15, 17, ""},
+		{"update first", "timescaledb", "2.13.1", 14, 17, severityWarning},
+		{"no bridge", "pgaudit", "1.7", 15, 17, severityWarning},
+		{"no release for target", "citus", "11.3.1", 13, 18, severityCritical},
+		{"contrib", "pg_trgm", "1.5", 12, 17, ""},
+		{"removed contrib", "adminpack", "2.1", 16, 17, severityCritical},
+		{"unknown", "in_house", "1.0", 15, 16, severityInfo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := extensionFindings(tt.source, tt.target, []*models.ExtensionInventory{
+				{DatabaseName: "app", ExtensionName: tt.ext, ExtensionVersion: tt.version},
+			})
+			if tt.severity == "" {
+				assert.Empty(t, findings)
+				return
+			}
+			require.Len(t, findings, 1)
+			assert.Equal(t, tt.severity, findings[0].Severity)
+			assert.Equal(t, []string{"app"}, findings[0].Objects)
+		})
+	}
+}
+
+func TestMatchesRelease(t *testing.T) {
+	assert.True(t, matchesRelease("3.4", "3.4"))
+	assert.True(t, matchesRelease("3.4.2", "3.4"))
+	assert.True(t, matchesRelease("16.0", "16"))
+	assert.False(t, matchesRelease("3.40", "3.4"))
+	assert.False(t, matchesRelease("1.16", "16"))
+}
+
+func TestIngestObjects(t *testing.T) {
+	store := &mockUpgradeStore{}
+	service := NewService(store, zap.NewNop())
+
+	stored, err := service.IngestObjects(context.Background(), uuid.New(), time.Now(), []models.UpgradeObject{
+		{Database: "app", Kind: "postfix_operator", Schema: "public", Name: " ! "},
+		{Database: "app", Kind: "", Name: "ignored"},
+		{Database: "app", Kind: "encoding_conversion", Name: ""},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored)
+	require.Len(t, store.replaced, 1)
+	assert.Equal(t, "!", store.replaced[0].Name)
+
+	// An empty snapshot clears the previous one
+	stored, err = service.IngestObjects(context.Background(), uuid.New(), time.Now(), nil)
+	require.NoError(t, err)
+	assert.Zero(t, stored)
+	assert.NotNil(t, store.replaced)
+	assert.Empty(t, store.replaced)
+}
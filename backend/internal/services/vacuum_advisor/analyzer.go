@@ -2,51 +2,318 @@ package vacuum_advisor
 
 import (
 	"context"
+	"fmt"
 	"math"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+const (
+	// ChurnLookback is how far back the baseline sample for churn rates goes
+	ChurnLookback = 24 * time.Hour
+
+	// Thresholds for high priority tables
+	highDeadTuplesRatio = 30.0               // percent of tuples dead
+	staleVacuumAge      = 7 * 24 * time.Hour // no vacuum despite changes
+	rapidChurnRatio     = 0.01               // dead tuples per hour relative to reltuples
+	rapidChurnMinTuples = 10000              // smaller tables churn fast without consequence
 )
 
+// VacuumStore provides the collected vacuum statistics the analyzer works on
+type VacuumStore interface {
+	GetVacuumSnapshot(ctx context.Context, databaseID int64, lookback time.Duration) (*models.VacuumSnapshot, error)
+}
+
 // VacuumAnalyzer provides VACUUM recommendations and analysis
 type VacuumAnalyzer struct {
-	db             interface{} // Would be *storage.PostgresDB in production
+	store          VacuumStore
 	costCalculator *CostCalculator
+	now            func() time.Time
 }
 
 // NewVacuumAnalyzer creates a new VACUUM analyzer
-func NewVacuumAnalyzer(db interface{}) *VacuumAnalyzer {
+// With a nil store there are no collected statistics, so every analysis is empty.
+func NewVacuumAnalyzer(store VacuumStore) *VacuumAnalyzer {
 	return &VacuumAnalyzer{
-		db:             db,
+		store:          store,
 		costCalculator: NewCostCalculator(),
+		now:            time.Now,
 	}
 }
 
-// AnalyzeDatabase returns VACUUM recommendations for all tables in a database
-func (va *VacuumAnalyzer) AnalyzeDatabase(ctx context.Context, databaseID int64) ([]VacuumRecommendation, error) {
-	var recommendations []VacuumRecommendation
+// tableAnalysis is a table's latest statistics with the settings in effect
+// for it and the recommendation derived from them
+type tableAnalysis struct {
+	stat      *models.VacuumTableStat
+	settings  *TableSettings
+	relTuples float64
+	rec       *VacuumRecommendation
+}
 
-	// In production, this would query pg_stat_user_tables
-	// For now, return empty slice
-	if databaseID <= 0 {
-		return recommendations, nil
+// AnalyzeDatabase returns VACUUM recommendations for all tables in a database,
+// most urgent first
+func (va *VacuumAnalyzer) AnalyzeDatabase(ctx context.Context, databaseID int64) ([]VacuumRecommendation, error) {
+	analyses, err := va.analyzeDatabase(ctx, databaseID)
+	if err != nil {
+		return nil, err
 	}
 
+	recommendations := make([]VacuumRecommendation, 0, len(analyses))
+	for _, a := range analyses {
+		recommendations = append(recommendations, *a.rec)
+	}
+	sortRecommendations(recommendations)
 	return recommendations, nil
 }
 
 // AnalyzeTable returns VACUUM recommendation for a specific table
+// tableName is "schema.table" or a bare table name, which prefers the public
+// schema. A table without collected statistics has no recommendation.
 func (va *VacuumAnalyzer) AnalyzeTable(ctx context.Context, databaseID int64, tableName string) (*VacuumRecommendation, error) {
 	if databaseID <= 0 || tableName == "" {
 		return nil, nil
 	}
 
-	// In production, this would query pg_stat_user_tables for the specific table
-	// Return a base recommendation
-	return &VacuumRecommendation{
-		DatabaseID: databaseID,
-		TableName:  tableName,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-	}, nil
+	analyses, err := va.analyzeDatabase(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
+	if a := findTable(analyses, tableName); a != nil {
+		return a.rec, nil
+	}
+	return nil, nil
+}
+
+// analyzeDatabase analyzes every table of the latest snapshot of a database
+func (va *VacuumAnalyzer) analyzeDatabase(ctx context.Context, databaseID int64) ([]*tableAnalysis, error) {
+	if databaseID <= 0 || va.store == nil {
+		return nil, nil
+	}
+
+	snapshot, err := va.store.GetVacuumSnapshot(ctx, databaseID, ChurnLookback)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot == nil {
+		return nil, nil
+	}
+
+	analyses := make([]*tableAnalysis, 0, len(snapshot.Tables))
+	for _, stat := range snapshot.Tables {
+		analyses = append(analyses, va.analyzeTableStat(ctx, snapshot, stat))
+	}
+	return analyses, nil
+}
+
+// findTable finds a table by "schema.table" or by bare name
+func findTable(analyses []*tableAnalysis, tableName string) *tableAnalysis {
+	schema, table, qualified := strings.Cut(tableName, ".")
+	if !qualified {
+		table = tableName
+	}
+
+	var match *tableAnalysis
+	for _, a := range analyses {
+		if a.stat.TableName != table {
+			continue
+		}
+		if qualified {
+			if a.stat.SchemaName == schema {
+				return a
+			}
+			continue
+		}
+		if a.stat.SchemaName == "public" {
+			return a
+		}
+		if match == nil {
+			match = a
+		}
+	}
+	return match
+}
+
+// analyzeTableStat evaluates a table's statistics against its effective
+// autovacuum settings and the churn since its baseline sample
+func (va *VacuumAnalyzer) analyzeTableStat(ctx context.Context, snapshot *models.VacuumSnapshot, stat *models.VacuumTableStat) *tableAnalysis {
+	settings := EffectiveSettings(snapshot.Settings, stat.RelOptions)
+	relTuples := math.Max(stat.RelTuples, 0)
+
+	var deadRatio float64
+	if total := stat.LiveTuples + stat.DeadTuples; total > 0 {
+		deadRatio = float64(stat.DeadTuples) / float64(total) * 100
+	}
+
+	rec := va.analyzeTableMetrics(ctx, &VacuumMetrics{
+		DatabaseID:        snapshot.DatabaseID,
+		TableName:         stat.TableName,
+		TableSize:         stat.TableSizeBytes,
+		DeadTuples:        stat.DeadTuples,
+		LiveTuples:        stat.LiveTuples,
+		DeadTuplesRatio:   deadRatio,
+		LastVacuum:        stat.LastVacuum,
+		LastAutovacuum:    stat.LastAutovacuum,
+		AutovacuumEnabled: settings.Enabled,
+	})
+	rec.SchemaName = stat.SchemaName
+	rec.LiveTuples = stat.LiveTuples
+	rec.LastAnalyze = stat.LastAnalyze
+	rec.LastAutoanalyze = stat.LastAutoanalyze
+	rec.AutovacuumNaptime = settings.Naptime.String()
+	rec.BloatBytes = stat.BloatBytes
+	if stat.BloatBytes != nil {
+		rec.EstimatedGain = float64(*stat.BloatBytes)
+	} else {
+		rec.EstimatedGain = float64(va.costCalculator.CalculateRecoverableSpace(stat.TableSizeBytes, deadRatio))
+	}
+
+	// Trigger points, as autovacuum computes them from reltuples
+	rec.VacuumTriggerPoint = settings.VacuumThreshold + settings.VacuumScaleFactor*relTuples
+	rec.AnalyzeTriggerPoint = settings.AnalyzeThreshold + settings.AnalyzeScaleFactor*relTuples
+	rec.ModSinceAnalyze = stat.ModSinceAnalyze
+	rec.InsSinceVacuum = stat.InsSinceVacuum
+	rec.NeedsVacuum = float64(stat.DeadTuples) > rec.VacuumTriggerPoint
+	rec.NeedsAnalyze = float64(stat.ModSinceAnalyze) > rec.AnalyzeTriggerPoint
+	if settings.InsertSupported && settings.InsertThreshold >= 0 && stat.InsSinceVacuum != nil {
+		insertTrigger := settings.InsertThreshold + settings.InsertScaleFactor*relTuples
+		rec.InsertTriggerPoint = &insertTrigger
+		rec.NeedsInsertVacuum = float64(*stat.InsSinceVacuum) > insertTrigger
+	}
+
+	now := va.now()
+	rec.SecondsSinceVacuum = secondsSince(now, latestOf(stat.LastVacuum, stat.LastAutovacuum))
+	rec.SecondsSinceAnalyze = secondsSince(now, latestOf(stat.LastAnalyze, stat.LastAutoanalyze))
+
+	va.applyChurn(rec, snapshot.Baselines[stat.SchemaName+"."+stat.TableName], stat, relTuples)
+
+	a := &tableAnalysis{stat: stat, settings: settings, relTuples: relTuples, rec: rec}
+	rec.Reasons, rec.Priority = assessTable(a)
+	return a
+}
+
+// applyChurn derives per-hour rates from the counter deltas since the
+// baseline sample; a counter going backwards means the statistics were reset,
+// which leaves the rates unknown
+func (va *VacuumAnalyzer) applyChurn(rec *VacuumRecommendation, baseline, stat *models.VacuumTableStat, relTuples float64) {
+	if baseline == nil || !stat.Time.After(baseline.Time) {
+		return
+	}
+
+	inserted := stat.TuplesInserted - baseline.TuplesInserted
+	updated := stat.TuplesUpdated - baseline.TuplesUpdated
+	deleted := stat.TuplesDeleted - baseline.TuplesDeleted
+	autovacuums := stat.AutovacuumCount - baseline.AutovacuumCount
+	if inserted < 0 || updated < 0 || deleted < 0 || autovacuums < 0 {
+		return
+	}
+
+	hours := stat.Time.Sub(baseline.Time).Hours()
+	rec.ObservedHours = hours
+	rec.DeadTuplesPerHour = float64(updated+deleted) / hours
+	rec.InsertsPerHour = float64(inserted) / hours
+	rec.ModificationsPerHour = float64(inserted+updated+deleted) / hours
+	rec.AutovacuumsPerDay = float64(autovacuums) / hours * 24
+
+	if rec.DeadTuplesPerHour > 0 {
+		between := rec.VacuumTriggerPoint / rec.DeadTuplesPerHour
+		rec.HoursBetweenVacuums = &between
+
+		if rec.SecondsSinceVacuum != nil {
+			efficiency := va.costCalculator.CalculateAutovacuumEfficiency(
+				int64(relTuples)+rec.DeadTuplesCount,
+				rec.DeadTuplesRatio,
+				*rec.SecondsSinceVacuum/86400,
+				int64(rec.DeadTuplesPerHour*24),
+			)
+			rec.AutovacuumEfficiency = &efficiency
+		}
+	}
+}
+
+// assessTable explains what needs attention on a table and how urgently
+func assessTable(a *tableAnalysis) ([]string, string) {
+	rec := a.rec
+	var high, medium []string
+
+	if !a.settings.Enabled {
+		if a.settings.ServerEnabled {
+			high = append(high, "autovacuum is disabled for this table (autovacuum_enabled = false)")
+		} else {
+			high = append(high, "autovacuum is disabled on the server")
+		}
+	}
+	if rec.DeadTuplesRatio > highDeadTuplesRatio {
+		high = append(high, fmt.Sprintf("%.1f%% of tuples are dead", rec.DeadTuplesRatio))
+	}
+	if rec.VacuumTriggerPoint > 0 && float64(rec.DeadTuplesCount) > 2*rec.VacuumTriggerPoint {
+		high = append(high, fmt.Sprintf("%d dead tuples, more than twice the autovacuum trigger point of %.0f", rec.DeadTuplesCount, rec.VacuumTriggerPoint))
+	}
+	if changed := rec.DeadTuplesCount > 0 || rec.ModSinceAnalyze > 0; changed {
+		if rec.SecondsSinceVacuum == nil {
+			high = append(high, "never vacuumed despite changes")
+		} else if *rec.SecondsSinceVacuum > staleVacuumAge.Seconds() {
+			high = append(high, fmt.Sprintf("not vacuumed for %s despite changes", formatHours(*rec.SecondsSinceVacuum/3600)))
+		}
+	}
+	if a.relTuples >= rapidChurnMinTuples && rec.DeadTuplesPerHour >= rapidChurnRatio*a.relTuples {
+		high = append(high, fmt.Sprintf("rapid churn: %.0f dead tuples per hour on %.0f tuples", rec.DeadTuplesPerHour, a.relTuples))
+	}
+
+	if rec.NeedsVacuum && len(high) == 0 {
+		medium = append(medium, fmt.Sprintf("%d dead tuples past the autovacuum trigger point of %.0f", rec.DeadTuplesCount, rec.VacuumTriggerPoint))
+	}
+	if rec.NeedsAnalyze {
+		medium = append(medium, fmt.Sprintf("%d modifications since the last analyze, past the trigger point of %.0f", rec.ModSinceAnalyze, rec.AnalyzeTriggerPoint))
+	}
+	if rec.NeedsInsertVacuum {
+		medium = append(medium, fmt.Sprintf("%d inserts since the last vacuum, past the trigger point of %.0f", *rec.InsSinceVacuum, *rec.InsertTriggerPoint))
+	}
+	if rec.RecommendationType == "full_vacuum" && rec.DeadTuplesRatio <= highDeadTuplesRatio {
+		medium = append(medium, fmt.Sprintf("%.1f%% of tuples are dead", rec.DeadTuplesRatio))
+	}
+
+	reasons := append(high, medium...)
+	switch {
+	case len(high) > 0:
+		return reasons, PriorityHigh
+	case len(medium) > 0:
+		return reasons, PriorityMedium
+	default:
+		return []string{}, PriorityLow
+	}
+}
+
+// sortRecommendations orders recommendations by priority, then dead tuples
+func sortRecommendations(recommendations []VacuumRecommendation) {
+	rank := map[string]int{PriorityHigh: 0, PriorityMedium: 1, PriorityLow: 2}
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if rank[recommendations[i].Priority] != rank[recommendations[j].Priority] {
+			return rank[recommendations[i].Priority] < rank[recommendations[j].Priority]
+		}
+		return recommendations[i].DeadTuplesCount > recommendations[j].DeadTuplesCount
+	})
+}
+
+// latestOf returns the later of a manual and an automatic run
+func latestOf(manual, auto *time.Time) *time.Time {
+	if manual == nil {
+		return auto
+	}
+	if auto == nil || manual.After(*auto) {
+		return manual
+	}
+	return auto
+}
+
+func secondsSince(now time.Time, t *time.Time) *float64 {
+	if t == nil {
+		return nil
+	}
+	seconds := math.Max(now.Sub(*t).Seconds(), 0)
+	return &seconds
 }
 
 // analyzeTableMetrics analyzes a table's VACUUM metrics and returns a recommendation
@@ -127,73 +394,102 @@ func (va *VacuumAnalyzer) calculateEstimatedGain(ctx context.Context, tableSize
 	return math.Max(estimated, 0.0)
 }
 
-// GetAutovacuumConfig returns current autovacuum configuration for a database
-func (va *VacuumAnalyzer) GetAutovacuumConfig(ctx context.Context, databaseID int64) []AutovacuumConfig {
-	var configs []AutovacuumConfig
+// GetAutovacuumConfig returns the autovacuum settings in effect for each
+// table of a database, where they come from and the recommended value
+func (va *VacuumAnalyzer) GetAutovacuumConfig(ctx context.Context, databaseID int64) ([]AutovacuumConfig, error) {
+	configs := []AutovacuumConfig{}
+
+	analyses, err := va.analyzeDatabase(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
 
-	if databaseID <= 0 {
-		return configs
+	now := va.now()
+	for _, a := range analyses {
+		tunings := make(map[string]AutovacuumTuning)
+		for _, tuning := range va.tuneTable(a) {
+			tunings[tuning.Parameter] = tuning
+		}
+
+		for _, setting := range a.settings.values() {
+			config := AutovacuumConfig{
+				DatabaseID:       databaseID,
+				SchemaName:       a.stat.SchemaName,
+				TableName:        a.stat.TableName,
+				SettingName:      setting.name,
+				CurrentValue:     setting.value,
+				Source:           a.settings.Source(setting.name),
+				RecommendedValue: setting.value,
+				Impact:           PriorityLow,
+				CreatedAt:        now,
+			}
+			if tuning, ok := tunings[setting.name]; ok {
+				config.RecommendedValue = tuning.RecommendedValue
+				config.Impact = PriorityMedium
+				if tuning.ExpectedImprovement >= 50 || a.rec.Priority == PriorityHigh {
+					config.Impact = PriorityHigh
+				}
+			}
+			configs = append(configs, config)
+		}
 	}
 
-	// In production, this would query pg_settings and pg_class
-	// Return empty slice for now
-	return configs
+	return configs, nil
 }
 
-// TuneAutovacuum provides autovacuum parameter recommendations for a table
-func (va *VacuumAnalyzer) TuneAutovacuum(ctx context.Context, databaseID int64, tableName string) []AutovacuumTuning {
-	var tunings []AutovacuumTuning
+// TuneAutovacuum provides autovacuum parameter recommendations for a table,
+// derived from its observed churn
+func (va *VacuumAnalyzer) TuneAutovacuum(ctx context.Context, databaseID int64, tableName string) ([]AutovacuumTuning, error) {
+	tunings := []AutovacuumTuning{}
 
 	if databaseID <= 0 || tableName == "" {
-		return tunings
+		return tunings, nil
 	}
 
-	// Recommend autovacuum_naptime adjustment
-	tunings = append(tunings, AutovacuumTuning{
-		TableName:           tableName,
-		Parameter:           "autovacuum_naptime",
-		CurrentValue:        "1min",
-		RecommendedValue:    "30s",
-		Rationale:           "More frequent autovacuum helps prevent table bloat",
-		ExpectedImprovement: 15.0,
-	})
+	analyses, err := va.analyzeDatabase(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
+	if a := findTable(analyses, tableName); a != nil {
+		tunings = append(tunings, va.tuneTable(a)...)
+	}
+	return tunings, nil
+}
 
-	// Recommend scale factor adjustment for high-churn tables
-	tunings = append(tunings, AutovacuumTuning{
-		TableName:           tableName,
-		Parameter:           "autovacuum_vacuum_scale_factor",
-		CurrentValue:        "0.1",
-		RecommendedValue:    "0.05",
-		Rationale:           "Lower scale factor triggers vacuum more frequently",
-		ExpectedImprovement: 20.0,
-	})
+// TuneDatabase provides the autovacuum recommendations for every table of a
+// database, preceded by server-level ones
+func (va *VacuumAnalyzer) TuneDatabase(ctx context.Context, databaseID int64) ([]AutovacuumTuning, error) {
+	tunings := []AutovacuumTuning{}
 
-	// Recommend threshold adjustment
-	tunings = append(tunings, AutovacuumTuning{
-		TableName:           tableName,
-		Parameter:           "autovacuum_vacuum_threshold",
-		CurrentValue:        "50",
-		RecommendedValue:    "1000",
-		Rationale:           "Higher threshold reduces unnecessary autovacuum runs",
-		ExpectedImprovement: 10.0,
-	})
+	analyses, err := va.analyzeDatabase(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
+	if len(analyses) == 0 {
+		return tunings, nil
+	}
 
-	return tunings
+	tunings = append(tunings, tuneServer(analyses)...)
+	for _, a := range analyses {
+		tunings = append(tunings, va.tuneTable(a)...)
+	}
+	return tunings, nil
 }
 
-// GetHighPriorityTables returns tables that need immediate VACUUM attention
-func (va *VacuumAnalyzer) GetHighPriorityTables(ctx context.Context, databaseID int64) []VacuumRecommendation {
-	var recommendations []VacuumRecommendation
-
-	if databaseID <= 0 {
-		return recommendations
+// GetHighPriorityTables returns tables that need immediate VACUUM attention:
+// a dead tuple ratio above 30%, autovacuum disabled or falling behind, no
+// vacuum in over 7 days despite changes, or rapid tuple churn
+func (va *VacuumAnalyzer) GetHighPriorityTables(ctx context.Context, databaseID int64) ([]VacuumRecommendation, error) {
+	recommendations, err := va.AnalyzeDatabase(ctx, databaseID)
+	if err != nil {
+		return nil, err
 	}
 
-	// In production, would query tables with:
-	// - Dead tuple ratio > 30%
-	// - No vacuum in > 7 days
-	// - Autovacuum disabled
-	// - Rapid tuple churn detected
-
-	return recommendations
+	high := []VacuumRecommendation{}
+	for _, rec := range recommendations {
+		if rec.Priority == PriorityHigh {
+			high = append(high, rec)
+		}
+	}
+	return high, nil
 }
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 )
 
 // MockPostgresDB mocks the database interface for testing
@@ -13,6 +15,7 @@ type MockPostgresDB struct {
 	vacuumMetrics    map[string]*VacuumMetrics
 	autovacuumConfig map[string]*AutovacuumConfig
 	recommendations  []VacuumRecommendation
+	snapshot         *models.VacuumSnapshot
 	err              error
 }
 
@@ -21,9 +24,75 @@ func NewMockPostgresDB() *MockPostgresDB {
 		vacuumMetrics:    make(map[string]*VacuumMetrics),
 		autovacuumConfig: make(map[string]*AutovacuumConfig),
 		recommendations:  make([]VacuumRecommendation, 0),
+		snapshot:         newTestSnapshot(),
 	}
 }
 
+func (m *MockPostgresDB) GetVacuumSnapshot(ctx context.Context, databaseID int64, lookback time.Duration) (*models.VacuumSnapshot, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.snapshot, nil
+}
+
+var testSampleTime = time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+// newTestSnapshot returns a database with a high-churn "events" table of 10M
+// tuples under default settings, sampled 24 hours apart
+func newTestSnapshot() *models.VacuumSnapshot {
+	baseline := &models.VacuumTableStat{
+		Time:           testSampleTime.Add(-24 * time.Hour),
+		SchemaName:     "public",
+		TableName:      "events",
+		TuplesInserted: 1000000,
+		TuplesUpdated:  5000000,
+		TuplesDeleted:  1000000,
+	}
+	events := &models.VacuumTableStat{
+		Time:            testSampleTime,
+		SchemaName:      "public",
+		TableName:       "events",
+		LiveTuples:      10000000,
+		DeadTuples:      1500000,
+		ModSinceAnalyze: 400000,
+		TuplesInserted:  1000000 + 24*10000,
+		TuplesUpdated:   5000000 + 24*80000,
+		TuplesDeleted:   1000000 + 24*20000,
+		RelTuples:       10000000,
+		RelPages:        250000,
+		TableSizeBytes:  250000 * 8192,
+		LastAutovacuum:  timePtr(testSampleTime.Add(-20 * time.Hour)),
+		LastAutoanalyze: timePtr(testSampleTime.Add(-2 * time.Hour)),
+		AutovacuumCount: 10,
+	}
+	baseline.AutovacuumCount = 9
+
+	return &models.VacuumSnapshot{
+		DatabaseID:   1,
+		DatabaseName: "app",
+		CollectedAt:  testSampleTime,
+		Settings: map[string]string{
+			"autovacuum":                         "on",
+			"autovacuum_vacuum_threshold":        "50",
+			"autovacuum_vacuum_scale_factor":     "0.2",
+			"autovacuum_vacuum_insert_threshold": "1000",
+			"autovacuum_naptime":                 "60",
+		},
+		Tables:    []*models.VacuumTableStat{events},
+		Baselines: map[string]*models.VacuumTableStat{"public.events": baseline},
+	}
+}
+
+func newTestAnalyzer(db *MockPostgresDB) *VacuumAnalyzer {
+	analyzer := NewVacuumAnalyzer(db)
+	analyzer.now = func() time.Time { return testSampleTime }
+	return analyzer
+}
+
 // TestVacuumAnalyzerCreation verifies VacuumAnalyzer can be created
 func TestVacuumAnalyzerCreation(t *testing.T) {
 	db := NewMockPostgresDB()
@@ -190,7 +259,8 @@ func TestAutovacuumTuning(t *testing.T) {
 	analyzer := NewVacuumAnalyzer(NewMockPostgresDB())
 	ctx := context.Background()
 
-	tunings := analyzer.TuneAutovacuum(ctx, 1, "events")
+	tunings, err := analyzer.TuneAutovacuum(ctx, 1, "events")
+	require.NoError(t, err)
 	assert.NotNil(t, tunings)
 	assert.Greater(t, len(tunings), 0)
 
@@ -209,7 +279,8 @@ func TestAutovacuumConfigRetrieval(t *testing.T) {
 	analyzer := NewVacuumAnalyzer(db)
 	ctx := context.Background()
 
-	configs := analyzer.GetAutovacuumConfig(ctx, 1)
+	configs, err := analyzer.GetAutovacuumConfig(ctx, 1)
+	require.NoError(t, err)
 	// Should return slice (could be empty)
 	assert.IsType(t, []AutovacuumConfig{}, configs)
 }
@@ -245,7 +316,8 @@ func TestGetAutovacuumConfigForSpecificTable(t *testing.T) {
 	ctx := context.Background()
 
 	// Should handle missing table gracefully
-	configs := analyzer.GetAutovacuumConfig(ctx, 1)
+	configs, err := analyzer.GetAutovacuumConfig(ctx, 1)
+	require.NoError(t, err)
 	assert.IsType(t, []AutovacuumConfig{}, configs)
 }
 
@@ -274,3 +346,120 @@ func TestRecommendationMetadataPopulation(t *testing.T) {
 	assert.NotZero(t, rec.CreatedAt)
 	assert.NotZero(t, rec.UpdatedAt)
 }
+
+// TestAnalyzeDatabaseFromSnapshot derives trigger points and churn from collected statistics
+func TestAnalyzeDatabaseFromSnapshot(t *testing.T) {
+	analyzer := newTestAnalyzer(NewMockPostgresDB())
+
+	recs, err := analyzer.AnalyzeDatabase(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+
+	rec := recs[0]
+	assert.Equal(t, "public", rec.SchemaName)
+	assert.InDelta(t, 13.04, rec.DeadTuplesRatio, 0.01)
+	assert.Equal(t, 2000050.0, rec.VacuumTriggerPoint)
+	assert.Equal(t, 1000050.0, rec.AnalyzeTriggerPoint)
+	assert.False(t, rec.NeedsVacuum)
+	assert.False(t, rec.NeedsAnalyze)
+	assert.Equal(t, 100000.0, rec.DeadTuplesPerHour)
+	assert.Equal(t, 10000.0, rec.InsertsPerHour)
+	assert.Equal(t, 110000.0, rec.ModificationsPerHour)
+	assert.Equal(t, 1.0, rec.AutovacuumsPerDay)
+	require.NotNil(t, rec.HoursBetweenVacuums)
+	assert.InDelta(t, 20.0, *rec.HoursBetweenVacuums, 0.01)
+	require.NotNil(t, rec.SecondsSinceVacuum)
+	assert.Equal(t, 20*3600.0, *rec.SecondsSinceVacuum)
+	// 100000 dead tuples per hour on 10M tuples is rapid churn
+	assert.Equal(t, PriorityHigh, rec.Priority)
+	assert.NotEmpty(t, rec.Reasons)
+}
+
+// TestAnalyzeDatabaseIgnoresStatsReset leaves rates unknown when counters go backwards
+func TestAnalyzeDatabaseIgnoresStatsReset(t *testing.T) {
+	db := NewMockPostgresDB()
+	db.snapshot.Baselines["public.events"].TuplesUpdated = 99000000
+	analyzer := newTestAnalyzer(db)
+
+	recs, err := analyzer.AnalyzeDatabase(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, recs, 1)
+	assert.Zero(t, recs[0].ObservedHours)
+	assert.Zero(t, recs[0].DeadTuplesPerHour)
+	assert.Nil(t, recs[0].HoursBetweenVacuums)
+}
+
+// TestAnalyzeTableByName resolves qualified and bare table names
+func TestAnalyzeTableByName(t *testing.T) {
+	analyzer := newTestAnalyzer(NewMockPostgresDB())
+	ctx := context.Background()
+
+	rec, err := analyzer.AnalyzeTable(ctx, 1, "public.events")
+	require.NoError(t, err)
+	require.NotNil(t, rec)
+	assert.Equal(t, "events", rec.TableName)
+
+	rec, err = analyzer.AnalyzeTable(ctx, 1, "events")
+	require.NoError(t, err)
+	require.NotNil(t, rec)
+
+	rec, err = analyzer.AnalyzeTable(ctx, 1, "audit.events")
+	require.NoError(t, err)
+	assert.Nil(t, rec)
+}
+
+// TestAnalyzerWithoutStore returns empty results
+func TestAnalyzerWithoutStore(t *testing.T) {
+	analyzer := NewVacuumAnalyzer(nil)
+	ctx := context.Background()
+
+	recs, err := analyzer.AnalyzeDatabase(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, recs)
+
+	tunings, err := analyzer.TuneDatabase(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, tunings)
+}
+
+// TestGetHighPriorityTables selects only high priority tables
+func TestGetHighPriorityTables(t *testing.T) {
+	db := NewMockPostgresDB()
+	db.snapshot.Tables = append(db.snapshot.Tables, &models.VacuumTableStat{
+		Time:            testSampleTime,
+		SchemaName:      "public",
+		TableName:       "settings",
+		LiveTuples:      100,
+		RelTuples:       100,
+		TableSizeBytes:  8192,
+		LastAutovacuum:  timePtr(testSampleTime.Add(-time.Hour)),
+		LastAutoanalyze: timePtr(testSampleTime.Add(-time.Hour)),
+	})
+	analyzer := newTestAnalyzer(db)
+
+	high, err := analyzer.GetHighPriorityTables(context.Background(), 1)
+	require.NoError(t, err)
+	require.Len(t, high, 1)
+	assert.Equal(t, "events", high[0].TableName)
+}
+
+// TestGetAutovacuumConfigReportsSources reports effective settings with their source
+func TestGetAutovacuumConfigReportsSources(t *testing.T) {
+	db := NewMockPostgresDB()
+	db.snapshot.Tables[0].RelOptions = []string{"autovacuum_analyze_scale_factor=0.02"}
+	analyzer := newTestAnalyzer(db)
+
+	configs, err := analyzer.GetAutovacuumConfig(context.Background(), 1)
+	require.NoError(t, err)
+
+	byName := make(map[string]AutovacuumConfig)
+	for _, config := range configs {
+		byName[config.SettingName] = config
+	}
+	assert.Equal(t, "0.02", byName["autovacuum_analyze_scale_factor"].CurrentValue)
+	assert.Equal(t, SourceTable, byName["autovacuum_analyze_scale_factor"].Source)
+	assert.Equal(t, SourceServer, byName["autovacuum_vacuum_scale_factor"].Source)
+	assert.Equal(t, "0.2", byName["autovacuum_vacuum_scale_factor"].CurrentValue)
+	assert.Equal(t, "0.01", byName["autovacuum_vacuum_scale_factor"].RecommendedValue)
+	assert.Contains(t, byName, "autovacuum_vacuum_insert_threshold")
+}
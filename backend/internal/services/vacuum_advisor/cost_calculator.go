@@ -15,6 +15,10 @@ type CostCalculator struct {
 	CpuTupleOperationCost      float64 // 0.01 - cost per tuple operation
 	CpuIndexTupleOperationCost float64 // 0.005 - cost per index operation
 
+	// Cost-based vacuum delay accounting (vacuum_cost_page_miss, vacuum_cost_page_dirty)
+	VacuumCostPageMiss  float64 // 2 - cost of a page read from disk
+	VacuumCostPageDirty float64 // 20 - cost of dirtying a clean page
+
 	// Table bloat metrics
 	AveragePageSize  int64 // 8192 - PostgreSQL page size in bytes
 	AverageTupleSize int64 // 100-200 bytes typical
@@ -28,6 +32,8 @@ func NewCostCalculator() *CostCalculator {
 		RandomPageCost:             4.0,
 		CpuTupleOperationCost:      0.01,
 		CpuIndexTupleOperationCost: 0.005,
+		VacuumCostPageMiss:         2.0,
+		VacuumCostPageDirty:        20.0,
 		AveragePageSize:            8192,
 		AverageTupleSize:           150,
 	}
@@ -58,6 +64,28 @@ func (cc *CostCalculator) EstimateVacuumDuration(tableSize int64, deadTuples int
 	return totalCost / 1000.0
 }
 
+// EstimateThrottledVacuumDuration estimates how long an autovacuum takes in
+// seconds under cost-based delay: the worker sleeps costDelayMs each time it
+// accumulates costLimit of page costs, which dominates on large tables.
+// Without a delay the unthrottled estimate applies.
+func (cc *CostCalculator) EstimateThrottledVacuumDuration(tableSize int64, deadTuples int64, costLimit float64, costDelayMs float64) float64 {
+	if tableSize <= 0 {
+		return 0.0
+	}
+
+	pages := float64(tableSize) / float64(cc.AveragePageSize)
+
+	// Every page is read; at most one page is dirtied per dead tuple
+	dirtied := math.Min(pages, math.Max(float64(deadTuples), 0))
+	cost := pages*cc.VacuumCostPageMiss + dirtied*cc.VacuumCostPageDirty
+
+	if costLimit <= 0 || costDelayMs <= 0 {
+		return cc.EstimateVacuumDuration(tableSize, deadTuples)
+	}
+
+	return cost / costLimit * costDelayMs / 1000.0
+}
+
 // EstimateVacuumImpact estimates the impact of VACUUM on system performance
 func (cc *CostCalculator) EstimateVacuumImpact(databaseSize int64, tableSize int64) VacuumImpactMetrics {
 	impact := VacuumImpactMetrics{
@@ -159,3 +159,19 @@ func TestEstimateVacuumDurationScales(t *testing.T) {
 	ratio := largeDuration / smallDuration
 	assert.Greater(t, ratio, 100.0) // 1000x size increase should scale significantly
 }
+
+// TestEstimateThrottledVacuumDuration accounts for cost-based delay
+func TestEstimateThrottledVacuumDuration(t *testing.T) {
+	calc := NewCostCalculator()
+
+	// 1GB: 131072 pages read at cost 2, 10000 pages dirtied at cost 20
+	duration := calc.EstimateThrottledVacuumDuration(1<<30, 10000, 200, 2)
+	assert.InDelta(t, (131072*2.0+10000*20.0)/200*2/1000, duration, 0.001)
+
+	// A higher cost limit vacuums faster
+	assert.Less(t, calc.EstimateThrottledVacuumDuration(1<<30, 10000, 2000, 2), duration)
+
+	// Without a delay the unthrottled estimate applies
+	assert.Equal(t, calc.EstimateVacuumDuration(1<<30, 10000), calc.EstimateThrottledVacuumDuration(1<<30, 10000, 200, 0))
+	assert.Equal(t, 0.0, calc.EstimateThrottledVacuumDuration(0, 10000, 200, 2))
+}
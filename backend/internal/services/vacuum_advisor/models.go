@@ -4,44 +4,93 @@ import (
 	"time"
 )
 
+// Recommendation priorities
+const (
+	PriorityHigh   = "high"
+	PriorityMedium = "medium"
+	PriorityLow    = "low"
+)
+
 // VacuumRecommendation represents a single VACUUM recommendation
 type VacuumRecommendation struct {
-	ID                 int64
-	DatabaseID         int64
-	TableName          string
-	TableSize          int64
-	DeadTuplesCount    int64
-	DeadTuplesRatio    float64
-	AutovacuumEnabled  bool
-	AutovacuumNaptime  string
-	LastVacuum         *time.Time
-	LastAutovacuum     *time.Time
-	RecommendationType string // 'full_vacuum', 'analyze_only', 'tune_autovacuum'
-	EstimatedGain      float64
-	CreatedAt          time.Time
-	UpdatedAt          time.Time
+	ID                 int64      `json:"id"`
+	DatabaseID         int64      `json:"database_id"`
+	SchemaName         string     `json:"schema_name"`
+	TableName          string     `json:"table_name"`
+	TableSize          int64      `json:"table_size"`
+	LiveTuples         int64      `json:"live_tuples"`
+	DeadTuplesCount    int64      `json:"dead_tuples_count"`
+	DeadTuplesRatio    float64    `json:"dead_tuples_ratio"`
+	BloatBytes         *int64     `json:"bloat_bytes,omitempty"`
+	AutovacuumEnabled  bool       `json:"autovacuum_enabled"`
+	AutovacuumNaptime  string     `json:"autovacuum_naptime"`
+	LastVacuum         *time.Time `json:"last_vacuum"`
+	LastAutovacuum     *time.Time `json:"last_autovacuum"`
+	LastAnalyze        *time.Time `json:"last_analyze"`
+	LastAutoanalyze    *time.Time `json:"last_autoanalyze"`
+	RecommendationType string     `json:"recommendation_type"` // 'full_vacuum', 'analyze_only', 'tune_autovacuum'
+	EstimatedGain      float64    `json:"estimated_gain"`
+	Priority           string     `json:"priority"`
+
+	// Time since the last manual or automatic vacuum and analyze; nil when
+	// the table has never been vacuumed or analyzed
+	SecondsSinceVacuum  *float64 `json:"seconds_since_vacuum"`
+	SecondsSinceAnalyze *float64 `json:"seconds_since_analyze"`
+
+	// Trigger points from the table's effective settings: autovacuum
+	// vacuums past VacuumTriggerPoint dead tuples, analyzes past
+	// AnalyzeTriggerPoint modifications and, from PostgreSQL 13, vacuums
+	// past InsertTriggerPoint inserts (nil when unsupported or disabled)
+	VacuumTriggerPoint  float64  `json:"vacuum_trigger_point"`
+	AnalyzeTriggerPoint float64  `json:"analyze_trigger_point"`
+	InsertTriggerPoint  *float64 `json:"insert_trigger_point"`
+	ModSinceAnalyze     int64    `json:"mod_since_analyze"`
+	InsSinceVacuum      *int64   `json:"ins_since_vacuum"`
+	NeedsVacuum         bool     `json:"needs_vacuum"`
+	NeedsAnalyze        bool     `json:"needs_analyze"`
+	NeedsInsertVacuum   bool     `json:"needs_insert_vacuum"`
+
+	// Churn observed between the oldest sample in the lookback window and
+	// the latest; zero when there is a single sample
+	ObservedHours        float64  `json:"observed_hours"`
+	DeadTuplesPerHour    float64  `json:"dead_tuples_per_hour"`
+	InsertsPerHour       float64  `json:"inserts_per_hour"`
+	ModificationsPerHour float64  `json:"modifications_per_hour"`
+	AutovacuumsPerDay    float64  `json:"autovacuums_per_day"`
+	HoursBetweenVacuums  *float64 `json:"hours_between_vacuums"` // at the observed churn
+	AutovacuumEfficiency *float64 `json:"autovacuum_efficiency,omitempty"`
+
+	Reasons   []string  `json:"reasons"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // AutovacuumConfig represents current and recommended autovacuum settings
 type AutovacuumConfig struct {
-	ID               int64
-	DatabaseID       int64
-	TableName        string
-	SettingName      string
-	CurrentValue     string
-	RecommendedValue string
-	Impact           string // 'high', 'medium', 'low'
-	CreatedAt        time.Time
+	ID               int64     `json:"id"`
+	DatabaseID       int64     `json:"database_id"`
+	SchemaName       string    `json:"schema_name"`
+	TableName        string    `json:"table_name"`
+	SettingName      string    `json:"setting_name"`
+	CurrentValue     string    `json:"current_value"`
+	Source           string    `json:"source"` // 'server' or 'table'
+	RecommendedValue string    `json:"recommended_value"`
+	Impact           string    `json:"impact"` // 'high', 'medium', 'low'
+	CreatedAt        time.Time `json:"created_at"`
 }
 
 // AutovacuumTuning represents a tuning recommendation for autovacuum
 type AutovacuumTuning struct {
-	TableName           string
-	Parameter           string
-	CurrentValue        string
-	RecommendedValue    string
-	Rationale           string
-	ExpectedImprovement float64 // percentage improvement
+	SchemaName          string  `json:"schema_name"`
+	TableName           string  `json:"table_name"`
+	Scope               string  `json:"scope"` // 'table' or 'server'
+	Parameter           string  `json:"parameter"`
+	CurrentValue        string  `json:"current_value"`
+	RecommendedValue    string  `json:"recommended_value"`
+	Rationale           string  `json:"rationale"`
+	ExpectedImprovement float64 `json:"expected_improvement"` // percentage improvement
+	// Statement applies the recommendation
+	Statement string `json:"statement"`
 }
 
 // VacuumMetrics represents VACUUM-related metrics for a table
@@ -0,0 +1,185 @@
+package vacuum_advisor
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Setting sources
+const (
+	SourceServer = "server" // postgresql.conf or ALTER SYSTEM
+	SourceTable  = "table"  // pg_class.reloptions
+)
+
+// TableSettings are the autovacuum settings in effect for a table: the
+// server settings overridden by the table's storage parameters
+type TableSettings struct {
+	ServerEnabled      bool
+	Enabled            bool
+	VacuumThreshold    float64
+	VacuumScaleFactor  float64
+	AnalyzeThreshold   float64
+	AnalyzeScaleFactor float64
+	// InsertSupported is false before PostgreSQL 13, which has no
+	// insert-driven autovacuum; a negative InsertThreshold disables it
+	InsertSupported   bool
+	InsertThreshold   float64
+	InsertScaleFactor float64
+	CostLimit         float64 // autovacuum_vacuum_cost_limit, or vacuum_cost_limit when -1
+	CostDelayMs       float64
+	Naptime           time.Duration
+	// Sources maps each setting overridden by the table to SourceTable
+	Sources map[string]string
+}
+
+// Source returns where a setting's effective value comes from
+func (s *TableSettings) Source(name string) string {
+	if source, ok := s.Sources[name]; ok {
+		return source
+	}
+	return SourceServer
+}
+
+// PostgreSQL defaults, used for settings the collector did not report
+var defaultServerSettings = map[string]string{
+	"autovacuum":                            "on",
+	"autovacuum_vacuum_threshold":           "50",
+	"autovacuum_vacuum_scale_factor":        "0.2",
+	"autovacuum_analyze_threshold":          "50",
+	"autovacuum_analyze_scale_factor":       "0.1",
+	"autovacuum_vacuum_insert_scale_factor": "0.2",
+	"autovacuum_vacuum_cost_limit":          "-1",
+	"autovacuum_vacuum_cost_delay":          "2",
+	"vacuum_cost_limit":                     "200",
+	"autovacuum_naptime":                    "60",
+}
+
+// EffectiveSettings resolves the autovacuum settings of a table from the
+// server settings (as pg_settings reports them) and its reloptions
+func EffectiveSettings(server map[string]string, reloptions []string) *TableSettings {
+	get := func(name string) string {
+		if v, ok := server[name]; ok && v != "" {
+			return v
+		}
+		return defaultServerSettings[name]
+	}
+
+	s := &TableSettings{
+		ServerEnabled:      parseBool(get("autovacuum")),
+		VacuumThreshold:    parseNumber(get("autovacuum_vacuum_threshold")),
+		VacuumScaleFactor:  parseNumber(get("autovacuum_vacuum_scale_factor")),
+		AnalyzeThreshold:   parseNumber(get("autovacuum_analyze_threshold")),
+		AnalyzeScaleFactor: parseNumber(get("autovacuum_analyze_scale_factor")),
+		CostLimit:          parseNumber(get("autovacuum_vacuum_cost_limit")),
+		CostDelayMs:        parseDuration(get("autovacuum_vacuum_cost_delay"), time.Millisecond).Seconds() * 1000,
+		Naptime:            parseDuration(get("autovacuum_naptime"), time.Second),
+		Sources:            map[string]string{},
+	}
+	if v, ok := server["autovacuum_vacuum_insert_threshold"]; ok {
+		s.InsertSupported = true
+		s.InsertThreshold = parseNumber(v)
+		s.InsertScaleFactor = parseNumber(get("autovacuum_vacuum_insert_scale_factor"))
+	}
+	s.Enabled = s.ServerEnabled
+
+	for _, option := range reloptions {
+		name, value, found := strings.Cut(option, "=")
+		if !found {
+			continue
+		}
+		switch name {
+		case "autovacuum_enabled":
+			s.Enabled = s.ServerEnabled && parseBool(value)
+		case "autovacuum_vacuum_threshold":
+			s.VacuumThreshold = parseNumber(value)
+		case "autovacuum_vacuum_scale_factor":
+			s.VacuumScaleFactor = parseNumber(value)
+		case "autovacuum_analyze_threshold":
+			s.AnalyzeThreshold = parseNumber(value)
+		case "autovacuum_analyze_scale_factor":
+			s.AnalyzeScaleFactor = parseNumber(value)
+		case "autovacuum_vacuum_insert_threshold":
+			s.InsertThreshold = parseNumber(value)
+		case "autovacuum_vacuum_insert_scale_factor":
+			s.InsertScaleFactor = parseNumber(value)
+		case "autovacuum_vacuum_cost_limit":
+			s.CostLimit = parseNumber(value)
+		case "autovacuum_vacuum_cost_delay":
+			s.CostDelayMs = parseDuration(value, time.Millisecond).Seconds() * 1000
+		default:
+			continue
+		}
+		s.Sources[name] = SourceTable
+	}
+
+	if s.CostLimit <= 0 {
+		s.CostLimit = parseNumber(get("vacuum_cost_limit"))
+	}
+	return s
+}
+
+// settingValue is a setting's name and its value as PostgreSQL formats it
+type settingValue struct {
+	name  string
+	value string
+}
+
+// values lists the table-level autovacuum settings in effect
+func (s *TableSettings) values() []settingValue {
+	values := []settingValue{
+		{"autovacuum_enabled", strconv.FormatBool(s.Enabled)},
+		{"autovacuum_vacuum_threshold", formatNumber(s.VacuumThreshold)},
+		{"autovacuum_vacuum_scale_factor", formatNumber(s.VacuumScaleFactor)},
+		{"autovacuum_analyze_threshold", formatNumber(s.AnalyzeThreshold)},
+		{"autovacuum_analyze_scale_factor", formatNumber(s.AnalyzeScaleFactor)},
+	}
+	if s.InsertSupported {
+		values = append(values,
+			settingValue{"autovacuum_vacuum_insert_threshold", formatNumber(s.InsertThreshold)},
+			settingValue{"autovacuum_vacuum_insert_scale_factor", formatNumber(s.InsertScaleFactor)},
+		)
+	}
+	return append(values,
+		settingValue{"autovacuum_vacuum_cost_limit", formatNumber(s.CostLimit)},
+		settingValue{"autovacuum_vacuum_cost_delay", formatNumber(s.CostDelayMs)},
+	)
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func parseBool(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "on", "true", "yes", "1":
+		return true
+	}
+	return false
+}
+
+func parseNumber(v string) float64 {
+	f, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	return f
+}
+
+// parseDuration parses a time setting as pg_settings or reloptions report it,
+// either a bare number in the setting's unit or a number with a unit suffix
+func parseDuration(v string, unit time.Duration) time.Duration {
+	v = strings.TrimSpace(v)
+	for _, suffix := range []struct {
+		name string
+		unit time.Duration
+	}{{"ms", time.Millisecond}, {"min", time.Minute}, {"us", time.Microsecond}, {"s", time.Second}, {"h", time.Hour}, {"d", 24 * time.Hour}} {
+		if strings.HasSuffix(v, suffix.name) {
+			v = strings.TrimSpace(strings.TrimSuffix(v, suffix.name))
+			unit = suffix.unit
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(f * float64(unit))
+}
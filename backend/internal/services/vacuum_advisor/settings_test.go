@@ -0,0 +1,60 @@
+package vacuum_advisor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEffectiveSettingsDefaults falls back to PostgreSQL defaults
+func TestEffectiveSettingsDefaults(t *testing.T) {
+	s := EffectiveSettings(nil, nil)
+
+	assert.True(t, s.Enabled)
+	assert.Equal(t, 50.0, s.VacuumThreshold)
+	assert.Equal(t, 0.2, s.VacuumScaleFactor)
+	assert.Equal(t, 0.1, s.AnalyzeScaleFactor)
+	assert.False(t, s.InsertSupported)
+	assert.Equal(t, 200.0, s.CostLimit) // -1 falls back to vacuum_cost_limit
+	assert.Equal(t, 2.0, s.CostDelayMs)
+	assert.Equal(t, time.Minute, s.Naptime)
+	assert.Equal(t, SourceServer, s.Source("autovacuum_vacuum_scale_factor"))
+}
+
+// TestEffectiveSettingsReloptionsOverride applies table storage parameters
+func TestEffectiveSettingsReloptionsOverride(t *testing.T) {
+	server := map[string]string{
+		"autovacuum_vacuum_scale_factor":     "0.1",
+		"autovacuum_vacuum_insert_threshold": "1000",
+		"autovacuum_vacuum_cost_limit":       "400",
+		"autovacuum_naptime":                 "30s",
+	}
+	s := EffectiveSettings(server, []string{
+		"autovacuum_vacuum_scale_factor=0.01",
+		"autovacuum_vacuum_insert_threshold=-1",
+		"autovacuum_vacuum_cost_delay=10ms",
+		"fillfactor=90",
+	})
+
+	assert.Equal(t, 0.01, s.VacuumScaleFactor)
+	assert.Equal(t, SourceTable, s.Source("autovacuum_vacuum_scale_factor"))
+	assert.True(t, s.InsertSupported)
+	assert.Equal(t, -1.0, s.InsertThreshold)
+	assert.Equal(t, 0.2, s.InsertScaleFactor)
+	assert.Equal(t, 400.0, s.CostLimit)
+	assert.Equal(t, 10.0, s.CostDelayMs)
+	assert.Equal(t, 30*time.Second, s.Naptime)
+	assert.NotContains(t, s.Sources, "fillfactor")
+}
+
+// TestEffectiveSettingsDisabled combines the server and table switches
+func TestEffectiveSettingsDisabled(t *testing.T) {
+	s := EffectiveSettings(nil, []string{"autovacuum_enabled=false"})
+	assert.True(t, s.ServerEnabled)
+	assert.False(t, s.Enabled)
+
+	s = EffectiveSettings(map[string]string{"autovacuum": "off"}, []string{"autovacuum_enabled=true"})
+	assert.False(t, s.ServerEnabled)
+	assert.False(t, s.Enabled)
+}
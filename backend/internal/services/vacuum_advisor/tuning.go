@@ -0,0 +1,227 @@
+package vacuum_advisor
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tuning scopes
+const (
+	ScopeTable  = "table"  // ALTER TABLE ... SET
+	ScopeServer = "server" // ALTER SYSTEM SET
+)
+
+const (
+	// TargetVacuumInterval is how often a churning table should be vacuumed:
+	// tunings size trigger points to about an hour of observed churn
+	TargetVacuumInterval = time.Hour
+	// Vacuums closer together than this mostly rescan the same pages
+	minVacuumInterval = 5 * time.Minute
+
+	// Tables of at least this many tuples get tighter ceilings, as the
+	// default scale factors let millions of dead tuples accumulate
+	largeTableTuples      = 1000000
+	maxDeadRatio          = 0.2
+	maxDeadRatioLarge     = 0.05
+	maxModifiedRatio      = 0.1
+	maxModifiedRatioLarge = 0.05
+	minScaleFactor        = 0.001
+
+	// Tables whose modifications are at least this share of inserts
+	// rely on insert-driven autovacuum
+	insertMostlyRatio      = 0.9
+	defaultInsertThreshold = 1000
+
+	// A vacuum should take at most this share of the interval between
+	// vacuums; autovacuum_vacuum_cost_limit accepts up to 10000
+	maxVacuumShareOfInterval = 0.25
+	maxCostLimit             = 10000
+)
+
+// tuneTable derives per-table autovacuum settings from a table's observed
+// churn: trigger points sized to TargetVacuumInterval but capped as a share
+// of the table, and a cost limit that lets each vacuum keep up
+func (va *VacuumAnalyzer) tuneTable(a *tableAnalysis) []AutovacuumTuning {
+	rec, s := a.rec, a.settings
+	tunings := []AutovacuumTuning{}
+	add := func(parameter, current, recommended, rationale string, improvement float64) {
+		tunings = append(tunings, AutovacuumTuning{
+			SchemaName:          a.stat.SchemaName,
+			TableName:           a.stat.TableName,
+			Scope:               ScopeTable,
+			Parameter:           parameter,
+			CurrentValue:        current,
+			RecommendedValue:    recommended,
+			Rationale:           rationale,
+			ExpectedImprovement: math.Round(math.Max(improvement, 0)*10) / 10,
+			Statement: fmt.Sprintf("ALTER TABLE %s.%s SET (%s = %s);",
+				quoteIdent(a.stat.SchemaName), quoteIdent(a.stat.TableName), parameter, recommended),
+		})
+	}
+
+	if !s.Enabled && s.ServerEnabled && (rec.ModificationsPerHour > 0 || rec.DeadTuplesCount > 0) {
+		add("autovacuum_enabled", "false", "true",
+			fmt.Sprintf("Autovacuum is disabled for this table although it has %d dead tuples; nothing reclaims them or freezes old transaction IDs", rec.DeadTuplesCount),
+			rec.DeadTuplesRatio)
+	}
+
+	// Vacuum trigger point
+	vacuumTrigger := rec.VacuumTriggerPoint
+	deadCeiling := ceilingRatio(a.relTuples, maxDeadRatio, maxDeadRatioLarge)
+	desired := desiredTrigger(rec.DeadTuplesPerHour, s.VacuumThreshold, deadCeiling, a.relTuples)
+	if a.relTuples > 0 && vacuumTrigger > deadCeiling*a.relTuples && outgrown(s.VacuumScaleFactor, s.VacuumThreshold, desired, a.relTuples) {
+		scale := math.Max(roundSignificant((desired-s.VacuumThreshold)/a.relTuples), minScaleFactor)
+		if scale < s.VacuumScaleFactor {
+			newTrigger := s.VacuumThreshold + scale*a.relTuples
+			rationale := fmt.Sprintf("Autovacuum lets %.0f dead tuples (%.1f%% of the table) accumulate before it triggers; a scale factor of %s triggers at %.0f",
+				vacuumTrigger, vacuumTrigger/a.relTuples*100, formatNumber(scale), newTrigger)
+			if rec.DeadTuplesPerHour > 0 {
+				rationale = fmt.Sprintf("At %.0f dead tuples per hour autovacuum triggers every %s, after %.0f dead tuples (%.1f%% of the table); a scale factor of %s triggers at %.0f, about every %s",
+					rec.DeadTuplesPerHour, formatHours(vacuumTrigger/rec.DeadTuplesPerHour), vacuumTrigger, vacuumTrigger/a.relTuples*100,
+					formatNumber(scale), newTrigger, formatHours(newTrigger/rec.DeadTuplesPerHour))
+			}
+			add("autovacuum_vacuum_scale_factor", formatNumber(s.VacuumScaleFactor), formatNumber(scale), rationale,
+				(vacuumTrigger-newTrigger)/vacuumTrigger*100)
+			vacuumTrigger = newTrigger
+		}
+	} else if rec.DeadTuplesPerHour > 0 && vacuumTrigger/rec.DeadTuplesPerHour < minVacuumInterval.Hours() && desired > 2*vacuumTrigger {
+		threshold := roundSignificant(desired - s.VacuumScaleFactor*a.relTuples)
+		if threshold > s.VacuumThreshold {
+			newTrigger := threshold + s.VacuumScaleFactor*a.relTuples
+			add("autovacuum_vacuum_threshold", formatNumber(s.VacuumThreshold), formatNumber(threshold),
+				fmt.Sprintf("At %.0f dead tuples per hour autovacuum triggers every %s; a threshold of %s spaces vacuums about %s apart while keeping dead tuples under %.0f%% of the table",
+					rec.DeadTuplesPerHour, formatHours(vacuumTrigger/rec.DeadTuplesPerHour), formatNumber(threshold),
+					formatHours(newTrigger/rec.DeadTuplesPerHour), deadCeiling*100),
+				(1-vacuumTrigger/newTrigger)*100)
+			vacuumTrigger = newTrigger
+		}
+	}
+
+	// Analyze trigger point
+	analyzeTrigger := rec.AnalyzeTriggerPoint
+	modifiedCeiling := ceilingRatio(a.relTuples, maxModifiedRatio, maxModifiedRatioLarge)
+	desired = desiredTrigger(rec.ModificationsPerHour, s.AnalyzeThreshold, modifiedCeiling, a.relTuples)
+	if a.relTuples > 0 && analyzeTrigger > modifiedCeiling*a.relTuples && outgrown(s.AnalyzeScaleFactor, s.AnalyzeThreshold, desired, a.relTuples) {
+		scale := math.Max(roundSignificant((desired-s.AnalyzeThreshold)/a.relTuples), minScaleFactor)
+		if scale < s.AnalyzeScaleFactor {
+			newTrigger := s.AnalyzeThreshold + scale*a.relTuples
+			add("autovacuum_analyze_scale_factor", formatNumber(s.AnalyzeScaleFactor), formatNumber(scale),
+				fmt.Sprintf("Statistics are refreshed only after %.0f modifications (%.1f%% of the table), so plans rely on stale estimates in between; a scale factor of %s re-analyzes after %.0f",
+					analyzeTrigger, analyzeTrigger/a.relTuples*100, formatNumber(scale), newTrigger),
+				(analyzeTrigger-newTrigger)/analyzeTrigger*100)
+		}
+	}
+
+	// Insert-driven vacuum, for insert-mostly tables
+	if s.InsertSupported && rec.ModificationsPerHour > 0 && rec.InsertsPerHour >= insertMostlyRatio*rec.ModificationsPerHour {
+		insertShare := rec.InsertsPerHour / rec.ModificationsPerHour * 100
+		if s.InsertThreshold < 0 {
+			add("autovacuum_vacuum_insert_threshold", formatNumber(s.InsertThreshold), strconv.Itoa(defaultInsertThreshold),
+				fmt.Sprintf("%.0f%% of modifications are inserts but insert-driven autovacuum is disabled; pages are then only frozen and marked all-visible by anti-wraparound vacuums, which slows index-only scans and makes those vacuums expensive", insertShare),
+				insertShare)
+		} else if rec.InsertTriggerPoint != nil && a.relTuples > 0 {
+			insertTrigger := *rec.InsertTriggerPoint
+			desired = desiredTrigger(rec.InsertsPerHour, s.InsertThreshold, deadCeiling, a.relTuples)
+			if insertTrigger > deadCeiling*a.relTuples && outgrown(s.InsertScaleFactor, s.InsertThreshold, desired, a.relTuples) {
+				scale := math.Max(roundSignificant((desired-s.InsertThreshold)/a.relTuples), minScaleFactor)
+				if scale < s.InsertScaleFactor {
+					newTrigger := s.InsertThreshold + scale*a.relTuples
+					add("autovacuum_vacuum_insert_scale_factor", formatNumber(s.InsertScaleFactor), formatNumber(scale),
+						fmt.Sprintf("%.0f%% of modifications are inserts, at %.0f per hour; insert-driven autovacuum waits for %.0f inserts, while a scale factor of %s sets new pages all-visible after %.0f",
+							insertShare, rec.InsertsPerHour, insertTrigger, formatNumber(scale), newTrigger),
+						(insertTrigger-newTrigger)/insertTrigger*100)
+				}
+			}
+		}
+	}
+
+	// Cost limit: a throttled vacuum should finish well before the next one
+	if rec.DeadTuplesPerHour > 0 && a.stat.TableSizeBytes > 0 && s.CostLimit > 0 && s.CostLimit < maxCostLimit {
+		interval := vacuumTrigger / rec.DeadTuplesPerHour * 3600
+		duration := va.costCalculator.EstimateThrottledVacuumDuration(a.stat.TableSizeBytes, int64(vacuumTrigger), s.CostLimit, s.CostDelayMs)
+		if duration > maxVacuumShareOfInterval*interval {
+			limit := math.Min(maxCostLimit, math.Ceil(s.CostLimit*duration/(maxVacuumShareOfInterval*interval)/100)*100)
+			add("autovacuum_vacuum_cost_limit", formatNumber(s.CostLimit), formatNumber(limit),
+				fmt.Sprintf("Throttled at a cost limit of %s a vacuum takes about %s but triggers every %s; a limit of %s lets it finish within a quarter of that",
+					formatNumber(s.CostLimit), formatHours(duration/3600), formatHours(interval/3600), formatNumber(limit)),
+				(1-s.CostLimit/limit)*100)
+		}
+	}
+
+	return tunings
+}
+
+// tuneServer recommends server settings every table of a database depends on
+func tuneServer(analyses []*tableAnalysis) []AutovacuumTuning {
+	if len(analyses) == 0 || analyses[0].settings.ServerEnabled {
+		return nil
+	}
+
+	var worstDeadRatio float64
+	for _, a := range analyses {
+		worstDeadRatio = math.Max(worstDeadRatio, a.rec.DeadTuplesRatio)
+	}
+	return []AutovacuumTuning{{
+		Scope:               ScopeServer,
+		Parameter:           "autovacuum",
+		CurrentValue:        "off",
+		RecommendedValue:    "on",
+		Rationale:           "Autovacuum is disabled on the server: no table is vacuumed or analyzed automatically and transaction ID wraparound is only prevented by forced anti-wraparound vacuums",
+		ExpectedImprovement: math.Round(worstDeadRatio*10) / 10,
+		Statement:           "ALTER SYSTEM SET autovacuum = on; SELECT pg_reload_conf();",
+	}}
+}
+
+// ceilingRatio returns the share of a table a trigger point should stay under
+func ceilingRatio(relTuples, ratio, largeRatio float64) float64 {
+	if relTuples >= largeTableTuples {
+		return largeRatio
+	}
+	return ratio
+}
+
+// desiredTrigger sizes a trigger point to TargetVacuumInterval of churn,
+// between the base threshold and ceiling of the table; without observed
+// churn it is the ceiling
+func desiredTrigger(ratePerHour, threshold, ceiling, relTuples float64) float64 {
+	limit := math.Max(ceiling*relTuples, threshold)
+	if ratePerHour <= 0 {
+		return limit
+	}
+	return math.Min(math.Max(ratePerHour*TargetVacuumInterval.Hours(), threshold), limit)
+}
+
+// outgrown reports whether a table has outgrown its scale factor: the scaled
+// part of the trigger point is over twice what the desired trigger point needs,
+// so that small tables, where the threshold dominates, keep their settings
+func outgrown(scaleFactor, threshold, desired, relTuples float64) bool {
+	return desired > threshold && scaleFactor*relTuples > 2*(desired-threshold)
+}
+
+// roundSignificant rounds to two significant digits, which is as precise as
+// a recommendation derived from sampled rates can be
+func roundSignificant(f float64) float64 {
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'g', 2, 64), 64)
+	return rounded
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// formatHours formats a number of hours in the most readable unit
+func formatHours(hours float64) string {
+	switch {
+	case hours < 1.0/60:
+		return fmt.Sprintf("%.0f s", hours*3600)
+	case hours < 1:
+		return fmt.Sprintf("%.0f min", hours*60)
+	case hours < 48:
+		return fmt.Sprintf("%.1f h", hours)
+	default:
+		return fmt.Sprintf("%.1f days", hours/24)
+	}
+}
@@ -0,0 +1,135 @@
+package vacuum_advisor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tuningsByParameter(tunings []AutovacuumTuning) map[string]AutovacuumTuning {
+	byParameter := make(map[string]AutovacuumTuning)
+	for _, tuning := range tunings {
+		byParameter[tuning.Parameter] = tuning
+	}
+	return byParameter
+}
+
+// TestTuneAutovacuumFromChurn sizes trigger points to an hour of churn
+func TestTuneAutovacuumFromChurn(t *testing.T) {
+	analyzer := newTestAnalyzer(NewMockPostgresDB())
+
+	tunings, err := analyzer.TuneAutovacuum(context.Background(), 1, "events")
+	require.NoError(t, err)
+	byParameter := tuningsByParameter(tunings)
+
+	vacuum, ok := byParameter["autovacuum_vacuum_scale_factor"]
+	require.True(t, ok)
+	assert.Equal(t, "0.2", vacuum.CurrentValue)
+	assert.Equal(t, "0.01", vacuum.RecommendedValue)
+	assert.Equal(t, ScopeTable, vacuum.Scope)
+	assert.Equal(t, `ALTER TABLE "public"."events" SET (autovacuum_vacuum_scale_factor = 0.01);`, vacuum.Statement)
+	assert.InDelta(t, 95.0, vacuum.ExpectedImprovement, 0.1)
+
+	analyze, ok := byParameter["autovacuum_analyze_scale_factor"]
+	require.True(t, ok)
+	assert.Equal(t, "0.011", analyze.RecommendedValue)
+
+	// Updates dominate, and vacuums stay short at the default cost limit
+	assert.NotContains(t, byParameter, "autovacuum_vacuum_insert_scale_factor")
+	assert.NotContains(t, byParameter, "autovacuum_vacuum_cost_limit")
+}
+
+// TestTuneAutovacuumRaisesThresholdForHotSmallTable spaces out back-to-back vacuums
+func TestTuneAutovacuumRaisesThresholdForHotSmallTable(t *testing.T) {
+	db := NewMockPostgresDB()
+	table := db.snapshot.Tables[0]
+	table.RelTuples = 100000
+	table.LiveTuples = 100000
+	table.DeadTuples = 100
+	table.ModSinceAnalyze = 0
+	table.RelOptions = []string{"autovacuum_vacuum_scale_factor=0.001"}
+	analyzer := newTestAnalyzer(db)
+
+	tunings, err := analyzer.TuneAutovacuum(context.Background(), 1, "events")
+	require.NoError(t, err)
+	byParameter := tuningsByParameter(tunings)
+
+	// 100000 dead tuples per hour reach the trigger point of 150 in seconds;
+	// the new trigger point is capped at 20% of the table
+	threshold, ok := byParameter["autovacuum_vacuum_threshold"]
+	require.True(t, ok)
+	assert.Equal(t, "50", threshold.CurrentValue)
+	assert.Equal(t, "20000", threshold.RecommendedValue)
+	assert.NotContains(t, byParameter, "autovacuum_vacuum_scale_factor")
+}
+
+// TestTuneAutovacuumInsertMostly re-enables insert-driven vacuum
+func TestTuneAutovacuumInsertMostly(t *testing.T) {
+	db := NewMockPostgresDB()
+	baseline := db.snapshot.Baselines["public.events"]
+	table := db.snapshot.Tables[0]
+	table.TuplesUpdated = baseline.TuplesUpdated
+	table.TuplesDeleted = baseline.TuplesDeleted
+	table.InsSinceVacuum = int64Ptr(500000)
+	table.RelOptions = []string{"autovacuum_vacuum_insert_threshold=-1"}
+	analyzer := newTestAnalyzer(db)
+
+	tunings, err := analyzer.TuneAutovacuum(context.Background(), 1, "public.events")
+	require.NoError(t, err)
+	byParameter := tuningsByParameter(tunings)
+
+	insert, ok := byParameter["autovacuum_vacuum_insert_threshold"]
+	require.True(t, ok)
+	assert.Equal(t, "-1", insert.CurrentValue)
+	assert.Equal(t, "1000", insert.RecommendedValue)
+}
+
+// TestTuneAutovacuumCostLimit raises the cost limit when throttled vacuums cannot keep up
+func TestTuneAutovacuumCostLimit(t *testing.T) {
+	db := NewMockPostgresDB()
+	db.snapshot.Settings["autovacuum_vacuum_cost_delay"] = "20ms"
+	db.snapshot.Tables[0].TableSizeBytes = 100 << 30
+	analyzer := newTestAnalyzer(db)
+
+	tunings, err := analyzer.TuneAutovacuum(context.Background(), 1, "events")
+	require.NoError(t, err)
+	byParameter := tuningsByParameter(tunings)
+
+	costLimit, ok := byParameter["autovacuum_vacuum_cost_limit"]
+	require.True(t, ok)
+	assert.Equal(t, "200", costLimit.CurrentValue)
+	assert.Equal(t, "700", costLimit.RecommendedValue)
+}
+
+// TestTuneDatabaseServerDisabled recommends enabling autovacuum once
+func TestTuneDatabaseServerDisabled(t *testing.T) {
+	db := NewMockPostgresDB()
+	db.snapshot.Settings["autovacuum"] = "off"
+	analyzer := newTestAnalyzer(db)
+
+	tunings, err := analyzer.TuneDatabase(context.Background(), 1)
+	require.NoError(t, err)
+	require.NotEmpty(t, tunings)
+	assert.Equal(t, ScopeServer, tunings[0].Scope)
+	assert.Equal(t, "autovacuum", tunings[0].Parameter)
+	assert.Equal(t, "on", tunings[0].RecommendedValue)
+	for _, tuning := range tunings[1:] {
+		assert.Equal(t, ScopeTable, tuning.Scope)
+		assert.NotEqual(t, "autovacuum_enabled", tuning.Parameter)
+	}
+}
+
+// TestTuneAutovacuumNoChurn keeps settings for quiet tables
+func TestTuneAutovacuumNoChurn(t *testing.T) {
+	db := NewMockPostgresDB()
+	table := db.snapshot.Tables[0]
+	table.RelTuples = 5000
+	delete(db.snapshot.Baselines, "public.events")
+	analyzer := newTestAnalyzer(db)
+
+	tunings, err := analyzer.TuneAutovacuum(context.Background(), 1, "events")
+	require.NoError(t, err)
+	assert.Empty(t, tunings)
+}
@@ -0,0 +1,199 @@
+package version_health
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ValidateAssertion checks that an assertion can be evaluated: its type is
+// known and its value parses for that type
+func ValidateAssertion(assertionType, value string) error {
+	switch assertionType {
+	case models.HealthCheckAssertEquals:
+		return nil
+	case models.HealthCheckAssertLessThan:
+		if _, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err != nil {
+			return fmt.Errorf("less_than needs a numeric assertion_value, got %q", value)
+		}
+		return nil
+	case models.HealthCheckAssertRowCountZero:
+		return nil
+	case models.HealthCheckAssertRegex:
+		if _, err := regexp.Compile(value); err != nil {
+			return fmt.Errorf("invalid regex assertion_value: %v", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid assertion_type %q: must be equals, less_than, row_count_zero or regex", assertionType)
+	}
+}
+
+// queryKeywords are the statements a check query may start with
+var queryKeywords = map[string]bool{
+	"select": true,
+	"with":   true,
+	"show":   true,
+	"values": true,
+	"table":  true,
+}
+
+// ValidateQuery checks that a check query is a single read statement. Check
+// queries also run in read-only transactions; this rejects the obvious
+// mistakes before a collector sees them.
+func ValidateQuery(query string) error {
+	query = strings.TrimSpace(query)
+	query = strings.TrimSpace(strings.TrimSuffix(query, ";"))
+	if query == "" {
+		return fmt.Errorf("check query is empty")
+	}
+	if strings.Contains(query, ";") {
+		return fmt.Errorf("check query must be a single statement")
+	}
+	words := strings.FieldsFunc(query, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if len(words) == 0 {
+		return fmt.Errorf("check query has no statement")
+	}
+	if keyword := strings.ToLower(words[0]); !queryKeywords[keyword] {
+		return fmt.Errorf("check query must be a SELECT, WITH, SHOW, VALUES or TABLE statement, not %s", strings.ToUpper(keyword))
+	}
+	return nil
+}
+
+// Evaluate judges the result set of a check query by the check's assertion.
+// A query that failed, returned no value to compare or has no valid
+// assertion does not pass.
+func Evaluate(check *models.VersionHealthCheck, set *models.HealthCheckResultSet, execErr string, at time.Time) *models.HealthCheckResult {
+	result := &models.HealthCheckResult{
+		CheckID:        check.ID,
+		CheckName:      check.CheckName,
+		Severity:       check.Severity,
+		ExpectedResult: check.ExpectedResult,
+		Remediation:    check.Remediation,
+		CheckedAt:      at,
+	}
+
+	if execErr != "" {
+		result.ActualResult = "Query execution failed"
+		result.Message = execErr
+		return result
+	}
+	if set == nil {
+		set = &models.HealthCheckResultSet{}
+	}
+	if err := ValidateAssertion(check.AssertionType, check.AssertionValue); err != nil {
+		result.ActualResult = firstValueText(set)
+		result.Message = err.Error()
+		return result
+	}
+
+	if check.AssertionType == models.HealthCheckAssertRowCountZero {
+		rows := rowCount(set)
+		result.ActualResult = fmt.Sprintf("%d rows", rows)
+		result.Passed = rows == 0
+		if result.Passed {
+			result.Message = "Query returned no rows"
+		} else {
+			result.Message = fmt.Sprintf("Query returned %d rows, expected none", rows)
+		}
+		return result
+	}
+
+	if len(set.Rows) == 0 || len(set.Rows[0]) == 0 {
+		result.ActualResult = "no rows"
+		result.Message = "Query returned no value to compare"
+		return result
+	}
+	value := set.Rows[0][0]
+	if value == nil {
+		result.ActualResult = "NULL"
+		result.Message = "Query returned NULL"
+		return result
+	}
+	actual := strings.TrimSpace(*value)
+	result.ActualResult = actual
+
+	switch check.AssertionType {
+	case models.HealthCheckAssertEquals:
+		result.Passed = valuesEqual(actual, check.AssertionValue)
+		if !result.Passed {
+			result.Message = fmt.Sprintf("Expected %q, got %q", check.AssertionValue, actual)
+		}
+	case models.HealthCheckAssertLessThan:
+		limit, _ := strconv.ParseFloat(strings.TrimSpace(check.AssertionValue), 64)
+		n, err := strconv.ParseFloat(actual, 64)
+		if err != nil {
+			result.Message = fmt.Sprintf("Expected a number below %s, got %q", check.AssertionValue, actual)
+			break
+		}
+		result.Passed = n < limit
+		if !result.Passed {
+			result.Message = fmt.Sprintf("Expected less than %s, got %s", check.AssertionValue, actual)
+		}
+	case models.HealthCheckAssertRegex:
+		result.Passed = regexp.MustCompile(check.AssertionValue).MatchString(actual)
+		if !result.Passed {
+			result.Message = fmt.Sprintf("%q does not match %s", actual, check.AssertionValue)
+		}
+	}
+	if result.Passed {
+		result.Message = "Check passed"
+	}
+
+	return result
+}
+
+// valuesEqual compares a result value with an expected one. Numbers compare
+// by value and booleans in any of PostgreSQL's spellings, so that "t" from
+// libpq and "true" from database/sql both equal "true".
+func valuesEqual(actual, expected string) bool {
+	expected = strings.TrimSpace(expected)
+	if actual == expected {
+		return true
+	}
+	if a, err := strconv.ParseFloat(actual, 64); err == nil {
+		if e, err := strconv.ParseFloat(expected, 64); err == nil {
+			return a == e
+		}
+	}
+	if a, ok := parseBool(actual); ok {
+		if e, ok := parseBool(expected); ok {
+			return a == e
+		}
+	}
+	return false
+}
+
+func parseBool(s string) (bool, bool) {
+	switch strings.ToLower(s) {
+	case "t", "true", "on", "yes":
+		return true, true
+	case "f", "false", "off", "no":
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+func rowCount(set *models.HealthCheckResultSet) int {
+	if set.RowCount > len(set.Rows) {
+		return set.RowCount
+	}
+	return len(set.Rows)
+}
+
+func firstValueText(set *models.HealthCheckResultSet) string {
+	if len(set.Rows) == 0 || len(set.Rows[0]) == 0 {
+		return "no rows"
+	}
+	if set.Rows[0][0] == nil {
+		return "NULL"
+	}
+	return *set.Rows[0][0]
+}
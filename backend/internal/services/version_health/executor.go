@@ -0,0 +1,131 @@
+package version_health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// Executor runs check queries against a monitored instance
+type Executor interface {
+	Query(ctx context.Context, query string) (*models.HealthCheckResultSet, error)
+}
+
+// SQLExecutor runs check queries over a direct connection to the monitored
+// instance. Each query runs in its own read-only transaction with a statement
+// timeout, so a check cannot change or stall the instance.
+type SQLExecutor struct {
+	db      *sql.DB
+	timeout time.Duration
+	maxRows int
+}
+
+// NewSQLExecutor creates an executor on an open connection pool
+func NewSQLExecutor(db *sql.DB) *SQLExecutor {
+	return &SQLExecutor{
+		db:      db,
+		timeout: QueryTimeout,
+		maxRows: MaxRows,
+	}
+}
+
+// Query runs a check query and returns its result set as text. Rows past
+// maxRows are counted but not kept.
+func (e *SQLExecutor) Query(ctx context.Context, query string) (*models.HealthCheckResultSet, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout+time.Second)
+	defer cancel()
+
+	tx, err := e.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", e.timeout.Milliseconds())); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	set := &models.HealthCheckResultSet{Columns: columns, Rows: [][]*string{}}
+	for rows.Next() {
+		set.RowCount++
+		if set.RowCount > e.maxRows {
+			continue
+		}
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make([]*string, len(columns))
+		for i, v := range values {
+			if v.Valid {
+				s := v.String
+				row[i] = &s
+			}
+		}
+		set.Rows = append(set.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// ConnString builds a lib/pq connection string, quoting every value
+func ConnString(host string, port int, user, password, database, sslMode string, connectTimeout int) string {
+	if database == "" {
+		database = "postgres"
+	}
+	if sslMode == "" {
+		sslMode = "require"
+	}
+	if connectTimeout <= 0 {
+		connectTimeout = 5
+	}
+	params := []string{
+		"host=" + quoteConnValue(host),
+		"port=" + strconv.Itoa(port),
+		"user=" + quoteConnValue(user),
+		"password=" + quoteConnValue(password),
+		"dbname=" + quoteConnValue(database),
+		"sslmode=" + quoteConnValue(sslMode),
+		"connect_timeout=" + strconv.Itoa(connectTimeout),
+		"application_name='pganalytics health checks'",
+	}
+	return strings.Join(params, " ")
+}
+
+func quoteConnValue(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+	return "'" + s + "'"
+}
+
+// ParseServerVersionNum returns the major version of a server_version_num,
+// e.g. 160002 -> 16 and 90624 -> 9
+func ParseServerVersionNum(s string) (int, error) {
+	num, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || num <= 0 {
+		return 0, fmt.Errorf("invalid server_version_num %q", s)
+	}
+	return num / 10000, nil
+}
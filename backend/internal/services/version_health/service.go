@@ -0,0 +1,239 @@
+package version_health
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// Interval is how often collectors run their health checks unless a run
+	// is requested
+	Interval = time.Hour
+
+	// QueryTimeout is the statement timeout of one check query
+	QueryTimeout = 10 * time.Second
+
+	// MaxRows is how many rows of a result set are kept
+	MaxRows = 100
+
+	serverVersionQuery = "SELECT current_setting('server_version_num')"
+)
+
+// severityWeights weigh checks in the score: a failed critical check costs
+// five times what a failed info check does
+var severityWeights = map[string]int{
+	"critical": 5,
+	"warning":  3,
+	"info":     1,
+}
+
+// Store interface for health check definitions, results and run requests
+type Store interface {
+	GetHealthChecksForVersion(ctx context.Context, pgVersion int) ([]*models.VersionHealthCheck, error)
+	GetCollectorHealthChecks(ctx context.Context, collectorID uuid.UUID, ids []int) ([]*models.VersionHealthCheck, error)
+	StoreHealthCheckResult(ctx context.Context, collectorID uuid.UUID, result *models.HealthCheckResult) error
+	StoreManagedInstanceHealthCheckResult(ctx context.Context, instanceID int, result *models.HealthCheckResult) error
+	ClearHealthCheckRunRequest(ctx context.Context, collectorID uuid.UUID, upTo time.Time) error
+}
+
+// Service runs version health checks on monitored instances and judges their
+// results
+type Service struct {
+	store  Store
+	logger *zap.Logger
+}
+
+// NewService creates a new version health service
+func NewService(store Store, logger *zap.Logger) *Service {
+	return &Service{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// Config returns the check queries a collector pulls: the built-in checks for
+// its PostgreSQL version and the custom checks scheduled on its groups,
+// flagged to run at once when a run was requested
+func Config(collectorID uuid.UUID, pgVersion int, checks []*models.VersionHealthCheck, scheduled []*models.ScheduledHealthCheck, requestedAt *time.Time) *models.HealthCheckConfig {
+	config := &models.HealthCheckConfig{
+		CollectorID:       collectorID,
+		PostgreSQLVersion: pgVersion,
+		IntervalSeconds:   int(Interval.Seconds()),
+		TimeoutMs:         int(QueryTimeout.Milliseconds()),
+		MaxRows:           MaxRows,
+		RunRequested:      requestedAt != nil,
+		RequestedAt:       requestedAt,
+		Checks:            make([]*models.HealthCheckQuery, 0, len(checks)+len(scheduled)),
+	}
+	for _, check := range checks {
+		config.Checks = append(config.Checks, &models.HealthCheckQuery{
+			CheckID:   check.ID,
+			CheckName: check.CheckName,
+			Query:     check.CheckQuery,
+		})
+	}
+	for _, sc := range scheduled {
+		config.Checks = append(config.Checks, &models.HealthCheckQuery{
+			CheckID:         sc.Check.ID,
+			CheckName:       sc.Check.CheckName,
+			Query:           sc.Check.CheckQuery,
+			Pack:            sc.PackName,
+			IntervalSeconds: sc.IntervalSeconds,
+		})
+	}
+	return config
+}
+
+// IngestCollected judges and stores the check results a collector reported.
+// Results for checks that no longer exist, or that belong to another
+// tenant's pack, are dropped. A pending run request is cleared once results
+// at or after it arrive.
+func (s *Service) IngestCollected(ctx context.Context, collectorID uuid.UUID, at time.Time, collected []*models.CollectedHealthCheck) ([]*models.HealthCheckResult, error) {
+	ids := make([]int, 0, len(collected))
+	for _, c := range collected {
+		ids = append(ids, c.CheckID)
+	}
+	checks, err := s.store.GetCollectorHealthChecks(ctx, collectorID, ids)
+	if err != nil {
+		s.logger.Error("Failed to load health checks", zap.Error(err))
+		return nil, err
+	}
+	byID := make(map[int]*models.VersionHealthCheck, len(checks))
+	for _, check := range checks {
+		byID[check.ID] = check
+	}
+
+	results := make([]*models.HealthCheckResult, 0, len(collected))
+	for _, c := range collected {
+		check := byID[c.CheckID]
+		if check == nil {
+			s.logger.Warn("Dropping result of unknown health check",
+				zap.String("collector_id", collectorID.String()),
+				zap.Int("check_id", c.CheckID))
+			continue
+		}
+		set := c.HealthCheckResultSet
+		result := Evaluate(check, &set, c.Error, at)
+		if err := s.store.StoreHealthCheckResult(ctx, collectorID, result); err != nil {
+			s.logger.Error("Failed to store health check result",
+				zap.String("collector_id", collectorID.String()),
+				zap.Int("check_id", check.ID),
+				zap.Error(err))
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	if len(results) > 0 {
+		if err := s.store.ClearHealthCheckRunRequest(ctx, collectorID, at); err != nil {
+			s.logger.Warn("Failed to clear health check run request",
+				zap.String("collector_id", collectorID.String()),
+				zap.Error(err))
+		}
+	}
+
+	return results, nil
+}
+
+// RunManagedInstance runs the checks for the version of a managed instance
+// over a direct connection and stores the results
+func (s *Service) RunManagedInstance(ctx context.Context, instanceID int, exec Executor) (*models.ManagedInstanceHealthCheckResponse, error) {
+	versionString, major, err := serverVersion(ctx, exec)
+	if err != nil {
+		s.logger.Error("Failed to read managed instance version", zap.Int("instance_id", instanceID), zap.Error(err))
+		return nil, err
+	}
+
+	checks, err := s.store.GetHealthChecksForVersion(ctx, major)
+	if err != nil {
+		s.logger.Error("Failed to load health checks", zap.Int("version", major), zap.Error(err))
+		return nil, err
+	}
+
+	results := Run(ctx, checks, exec)
+	for _, result := range results {
+		if err := s.store.StoreManagedInstanceHealthCheckResult(ctx, instanceID, result); err != nil {
+			s.logger.Error("Failed to store health check result",
+				zap.Int("instance_id", instanceID),
+				zap.Int("check_id", result.CheckID),
+				zap.Error(err))
+			return nil, err
+		}
+	}
+
+	return &models.ManagedInstanceHealthCheckResponse{
+		ManagedInstanceID:       instanceID,
+		PostgreSQLVersion:       major,
+		PostgreSQLVersionString: versionString,
+		Results:                 results,
+		Summary:                 Summarize(results),
+	}, nil
+}
+
+// Run executes each check with an executor and judges its result set. A
+// check whose query fails is a failed check; the others still run.
+func Run(ctx context.Context, checks []*models.VersionHealthCheck, exec Executor) []*models.HealthCheckResult {
+	results := make([]*models.HealthCheckResult, 0, len(checks))
+	for _, check := range checks {
+		set, err := exec.Query(ctx, check.CheckQuery)
+		execErr := ""
+		if err != nil {
+			execErr = err.Error()
+		}
+		results = append(results, Evaluate(check, set, execErr, time.Now()))
+	}
+	return results
+}
+
+// Summarize counts passed checks and failed checks by severity, and scores
+// them: the share of checks passed, weighted by severity, as 0-100. No
+// checks score 100.
+func Summarize(results []*models.HealthCheckResult) models.HealthCheckSummary {
+	summary := models.HealthCheckSummary{
+		TotalChecks: len(results),
+		Score:       100,
+	}
+	total, passed := 0, 0
+	for _, result := range results {
+		weight := severityWeights[result.Severity]
+		if weight == 0 {
+			weight = 1
+		}
+		total += weight
+		if result.Passed {
+			passed += weight
+			summary.PassedChecks++
+			continue
+		}
+		switch result.Severity {
+		case "critical":
+			summary.FailedCritical++
+		case "warning":
+			summary.FailedWarning++
+		case "info":
+			summary.FailedInfo++
+		}
+	}
+	if total > 0 {
+		summary.Score = int(math.Round(float64(passed) * 100 / float64(total)))
+	}
+	return summary
+}
+
+func serverVersion(ctx context.Context, exec Executor) (string, int, error) {
+	set, err := exec.Query(ctx, serverVersionQuery)
+	if err != nil {
+		return "", 0, err
+	}
+	value := firstValueText(set)
+	major, err := ParseServerVersionNum(value)
+	if err != nil {
+		return "", 0, err
+	}
+	return value, major, nil
+}
@@ -0,0 +1,307 @@
+package version_health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+type mockStore struct {
+	checks    []*models.VersionHealthCheck
+	stored    map[uuid.UUID][]*models.HealthCheckResult
+	instances map[int][]*models.HealthCheckResult
+	cleared   []time.Time
+	version   int
+}
+
+func newMockStore(checks ...*models.VersionHealthCheck) *mockStore {
+	return &mockStore{
+		checks:    checks,
+		stored:    make(map[uuid.UUID][]*models.HealthCheckResult),
+		instances: make(map[int][]*models.HealthCheckResult),
+	}
+}
+
+func (m *mockStore) GetHealthChecksForVersion(ctx context.Context, pgVersion int) ([]*models.VersionHealthCheck, error) {
+	m.version = pgVersion
+	var checks []*models.VersionHealthCheck
+	for _, c := range m.checks {
+		if c.MinVersion <= pgVersion && (c.MaxVersion == 0 || c.MaxVersion >= pgVersion) {
+			checks = append(checks, c)
+		}
+	}
+	return checks, nil
+}
+
+func (m *mockStore) GetCollectorHealthChecks(ctx context.Context, collectorID uuid.UUID, ids []int) ([]*models.VersionHealthCheck, error) {
+	wanted := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	var checks []*models.VersionHealthCheck
+	for _, c := range m.checks {
+		if wanted[c.ID] {
+			checks = append(checks, c)
+		}
+	}
+	return checks, nil
+}
+
+func (m *mockStore) StoreHealthCheckResult(ctx context.Context, collectorID uuid.UUID, result *models.HealthCheckResult) error {
+	m.stored[collectorID] = append(m.stored[collectorID], result)
+	return nil
+}
+
+func (m *mockStore) StoreManagedInstanceHealthCheckResult(ctx context.Context, instanceID int, result *models.HealthCheckResult) error {
+	m.instances[instanceID] = append(m.instances[instanceID], result)
+	return nil
+}
+
+func (m *mockStore) ClearHealthCheckRunRequest(ctx context.Context, collectorID uuid.UUID, upTo time.Time) error {
+	m.cleared = append(m.cleared, upTo)
+	return nil
+}
+
+// mockExecutor answers queries from a map; unknown queries fail
+type mockExecutor map[string]*models.HealthCheckResultSet
+
+func (m mockExecutor) Query(ctx context.Context, query string) (*models.HealthCheckResultSet, error) {
+	set, ok := m[query]
+	if !ok {
+		return nil, errors.New(`relation "missing" does not exist`)
+	}
+	return set, nil
+}
+
+func str(s string) *string { return &s }
+
+func values(vs ...string) *models.HealthCheckResultSet {
+	set := &models.HealthCheckResultSet{Columns: []string{"value"}}
+	for _, v := range vs {
+		set.Rows = append(set.Rows, []*string{str(v)})
+	}
+	return set
+}
+
+func check(id int, assertionType, value string) *models.VersionHealthCheck {
+	return &models.VersionHealthCheck{
+		ID:             id,
+		MinVersion:     13,
+		CheckName:      "check",
+		CheckQuery:     "SELECT 1",
+		Severity:       "warning",
+		AssertionType:  assertionType,
+		AssertionValue: value,
+	}
+}
+
+func TestEvaluate_Assertions(t *testing.T) {
+	tests := []struct {
+		name   string
+		check  *models.VersionHealthCheck
+		set    *models.HealthCheckResultSet
+		passed bool
+		actual string
+	}{
+		{"equals", check(1, models.HealthCheckAssertEquals, "on"), values("on"), true, "on"},
+		{"equals mismatch", check(1, models.HealthCheckAssertEquals, "on"), values("off"), false, "off"},
+		{"equals libpq boolean", check(1, models.HealthCheckAssertEquals, "true"), values("t"), true, "t"},
+		{"equals numeric", check(1, models.HealthCheckAssertEquals, "1"), values("1.0"), true, "1.0"},
+		{"less than", check(1, models.HealthCheckAssertLessThan, "100"), values("42"), true, "42"},
+		{"less than equal", check(1, models.HealthCheckAssertLessThan, "100"), values("100"), false, "100"},
+		{"less than not a number", check(1, models.HealthCheckAssertLessThan, "100"), values("lots"), false, "lots"},
+		{"row count zero", check(1, models.HealthCheckAssertRowCountZero, ""), values(), true, "0 rows"},
+		{"row count zero with rows", check(1, models.HealthCheckAssertRowCountZero, ""), values("a", "b"), false, "2 rows"},
+		{"row count past kept rows", check(1, models.HealthCheckAssertRowCountZero, ""),
+			&models.HealthCheckResultSet{Rows: [][]*string{{str("a")}}, RowCount: 250}, false, "250 rows"},
+		{"regex", check(1, models.HealthCheckAssertRegex, "^(lz4|zstd)$"), values("lz4"), true, "lz4"},
+		{"regex mismatch", check(1, models.HealthCheckAssertRegex, "^(lz4|zstd)$"), values("pglz"), false, "pglz"},
+		{"no rows", check(1, models.HealthCheckAssertEquals, "on"), values(), false, "no rows"},
+		{"null", check(1, models.HealthCheckAssertEquals, "on"),
+			&models.HealthCheckResultSet{Rows: [][]*string{{nil}}}, false, "NULL"},
+		{"unknown assertion", check(1, "contains", "on"), values("on"), false, "on"},
+		{"missing assertion", check(1, "", ""), values("on"), false, "on"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Evaluate(tt.check, tt.set, "", time.Now())
+			assert.Equal(t, tt.passed, result.Passed)
+			assert.Equal(t, tt.actual, result.ActualResult)
+			assert.NotEmpty(t, result.Message)
+		})
+	}
+}
+
+func TestEvaluate_QueryError(t *testing.T) {
+	result := Evaluate(check(1, models.HealthCheckAssertRowCountZero, ""), nil, "permission denied for view pg_stat_wal", time.Now())
+
+	assert.False(t, result.Passed)
+	assert.Equal(t, "Query execution failed", result.ActualResult)
+	assert.Equal(t, "permission denied for view pg_stat_wal", result.Message)
+}
+
+func TestValidateAssertion(t *testing.T) {
+	assert.NoError(t, ValidateAssertion(models.HealthCheckAssertEquals, ""))
+	assert.NoError(t, ValidateAssertion(models.HealthCheckAssertLessThan, " 0.9 "))
+	assert.NoError(t, ValidateAssertion(models.HealthCheckAssertRowCountZero, ""))
+	assert.NoError(t, ValidateAssertion(models.HealthCheckAssertRegex, "^on$"))
+
+	assert.Error(t, ValidateAssertion(models.HealthCheckAssertLessThan, "ten"))
+	assert.Error(t, ValidateAssertion(models.HealthCheckAssertRegex, "(unclosed"))
+	assert.Error(t, ValidateAssertion("greater_than", "1"))
+}
+
+func TestIngestCollected(t *testing.T) {
+	walCompression := check(1, models.HealthCheckAssertRegex, "^(lz4|zstd)$")
+	walCompression.Severity = "info"
+	stuckSync := check(2, models.HealthCheckAssertRowCountZero, "")
+	stuckSync.Severity = "critical"
+	store := newMockStore(walCompression, stuckSync)
+	service := NewService(store, zap.NewNop())
+
+	collectorID := uuid.New()
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	results, err := service.IngestCollected(context.Background(), collectorID, at, []*models.CollectedHealthCheck{
+		{CheckID: 1, HealthCheckResultSet: *values("zstd")},
+		{CheckID: 2, HealthCheckResultSet: *values("standby1")},
+		{CheckID: 99, HealthCheckResultSet: *values("x")},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, results, 2)
+	assert.True(t, results[0].Passed)
+	assert.False(t, results[1].Passed)
+	assert.Equal(t, "1 rows", results[1].ActualResult)
+	assert.Equal(t, at, results[1].CheckedAt)
+	assert.Len(t, store.stored[collectorID], 2)
+	assert.Equal(t, []time.Time{at}, store.cleared)
+
+	summary := Summarize(results)
+	assert.Equal(t, models.HealthCheckSummary{TotalChecks: 2, PassedChecks: 1, FailedCritical: 1, Score: 17}, summary)
+}
+
+func TestIngestCollected_Error(t *testing.T) {
+	store := newMockStore(check(1, models.HealthCheckAssertEquals, "1"))
+	service := NewService(store, zap.NewNop())
+
+	collectorID := uuid.New()
+	results, err := service.IngestCollected(context.Background(), collectorID, time.Now(), []*models.CollectedHealthCheck{
+		{CheckID: 1, Error: "canceling statement due to statement timeout"},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, results, 1)
+	assert.False(t, results[0].Passed)
+	assert.Equal(t, "canceling statement due to statement timeout", results[0].Message)
+}
+
+func TestRunManagedInstance(t *testing.T) {
+	eol := check(1, models.HealthCheckAssertEquals, "true")
+	eol.MinVersion, eol.MaxVersion = 11, 12
+	eol.CheckQuery = "SELECT eol"
+	walStats := check(2, models.HealthCheckAssertEquals, "1")
+	walStats.MinVersion = 14
+	walStats.CheckQuery = "SELECT count(*) FROM pg_stat_wal"
+	walStats.Severity = "info"
+	missing := check(3, models.HealthCheckAssertRowCountZero, "")
+	missing.CheckQuery = "SELECT * FROM missing"
+	missing.Severity = "critical"
+	store := newMockStore(eol, walStats, missing)
+	service := NewService(store, zap.NewNop())
+
+	exec := mockExecutor{
+		serverVersionQuery:                 values("160002"),
+		"SELECT count(*) FROM pg_stat_wal": values("1"),
+	}
+	resp, err := service.RunManagedInstance(context.Background(), 7, exec)
+	require.NoError(t, err)
+
+	assert.Equal(t, 16, store.version)
+	assert.Equal(t, 7, resp.ManagedInstanceID)
+	assert.Equal(t, 16, resp.PostgreSQLVersion)
+	require.Len(t, resp.Results, 2)
+	assert.True(t, resp.Results[0].Passed)
+	assert.False(t, resp.Results[1].Passed)
+	assert.Equal(t, "Query execution failed", resp.Results[1].ActualResult)
+	assert.Equal(t, models.HealthCheckSummary{TotalChecks: 2, PassedChecks: 1, FailedCritical: 1, Score: 17}, resp.Summary)
+	assert.Len(t, store.instances[7], 2)
+}
+
+func TestRunManagedInstance_VersionError(t *testing.T) {
+	service := NewService(newMockStore(), zap.NewNop())
+
+	_, err := service.RunManagedInstance(context.Background(), 7, mockExecutor{})
+	assert.Error(t, err)
+}
+
+func TestConfig(t *testing.T) {
+	collectorID := uuid.New()
+	checks := []*models.VersionHealthCheck{check(4, models.HealthCheckAssertEquals, "1")}
+
+	config := Config(collectorID, 16, checks, nil, nil)
+	assert.False(t, config.RunRequested)
+	assert.Equal(t, 3600, config.IntervalSeconds)
+	require.Len(t, config.Checks, 1)
+	assert.Equal(t, &models.HealthCheckQuery{CheckID: 4, CheckName: "check", Query: "SELECT 1"}, config.Checks[0])
+
+	scheduled := []*models.ScheduledHealthCheck{
+		{Check: check(9, models.HealthCheckAssertEquals, "on"), PackName: "security", IntervalSeconds: 300},
+	}
+	config = Config(collectorID, 16, checks, scheduled, nil)
+	require.Len(t, config.Checks, 2)
+	assert.Equal(t, &models.HealthCheckQuery{CheckID: 9, CheckName: "check", Query: "SELECT 1", Pack: "security", IntervalSeconds: 300}, config.Checks[1])
+
+	requestedAt := time.Now()
+	config = Config(collectorID, 16, checks, nil, &requestedAt)
+	assert.True(t, config.RunRequested)
+	assert.Equal(t, &requestedAt, config.RequestedAt)
+}
+
+func TestSummarize_Score(t *testing.T) {
+	assert.Equal(t, 100, Summarize(nil).Score)
+
+	summary := Summarize([]*models.HealthCheckResult{
+		{Severity: "critical", Passed: true},
+		{Severity: "warning", Passed: false},
+		{Severity: "info", Passed: true},
+	})
+	assert.Equal(t, 67, summary.Score)
+	assert.Equal(t, 1, summary.FailedWarning)
+}
+
+func TestValidateQuery(t *testing.T) {
+	assert.NoError(t, ValidateQuery("SELECT current_setting('ssl') ;"))
+	assert.NoError(t, ValidateQuery("  with r AS (SELECT 1) SELECT * FROM r"))
+	assert.NoError(t, ValidateQuery("SHOW ssl"))
+	assert.Error(t, ValidateQuery(""))
+	assert.Error(t, ValidateQuery("SELECT 1; DROP TABLE users"))
+	assert.Error(t, ValidateQuery("DELETE FROM users"))
+	assert.Error(t, ValidateQuery("(((("))
+}
+
+func TestConnString(t *testing.T) {
+	conn := ConnString("db.example.com", 5432, "monitor", `it's a \secret`, "", "", 0)
+
+	assert.Equal(t, `host='db.example.com' port=5432 user='monitor' password='it\'s a \\secret' dbname='postgres' sslmode='require' connect_timeout=5 application_name='pganalytics health checks'`, conn)
+}
+
+func TestParseServerVersionNum(t *testing.T) {
+	major, err := ParseServerVersionNum("170002")
+	require.NoError(t, err)
+	assert.Equal(t, 17, major)
+
+	major, err = ParseServerVersionNum(" 90624\n")
+	require.NoError(t, err)
+	assert.Equal(t, 9, major)
+
+	_, err = ParseServerVersionNum("16.2")
+	assert.Error(t, err)
+}
@@ -0,0 +1,109 @@
+package wraparound
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// Alert metrics of a wraparound status
+const (
+	MetricXIDPercent            = "xid_percent"
+	MetricMXIDPercent           = "mxid_percent"
+	MetricXIDAge                = "xid_age"
+	MetricMXIDAge               = "mxid_age"
+	MetricDaysToXIDFreezeMaxAge = "days_to_xid_freeze_max_age"
+	MetricDaysToXIDHardLimit    = "days_to_xid_hard_limit"
+	MetricDaysToMXIDHardLimit   = "days_to_mxid_hard_limit"
+	MetricOldestBlockerXIDAge   = "oldest_blocker_xid_age"
+	MetricHealthScore           = "health_score"
+)
+
+var alertMetrics = map[string]bool{
+	MetricXIDPercent:            true,
+	MetricMXIDPercent:           true,
+	MetricXIDAge:                true,
+	MetricMXIDAge:               true,
+	MetricDaysToXIDFreezeMaxAge: true,
+	MetricDaysToXIDHardLimit:    true,
+	MetricDaysToMXIDHardLimit:   true,
+	MetricOldestBlockerXIDAge:   true,
+	MetricHealthScore:           true,
+}
+
+var alertOperators = map[string]bool{"==": true, "!=": true, ">": true, ">=": true, "<": true, "<=": true}
+
+// ParseAlertCondition decodes and validates the condition of a wraparound
+// alert rule
+func ParseAlertCondition(raw json.RawMessage) (*models.WraparoundAlertCondition, error) {
+	var cond models.WraparoundAlertCondition
+	if err := json.Unmarshal(raw, &cond); err != nil {
+		return nil, fmt.Errorf("unmarshal wraparound condition: %w", err)
+	}
+
+	if cond.CollectorID == uuid.Nil {
+		return nil, fmt.Errorf("wraparound condition needs a collector_id")
+	}
+	if !alertMetrics[cond.Metric] {
+		metrics := make([]string, 0, len(alertMetrics))
+		for metric := range alertMetrics {
+			metrics = append(metrics, metric)
+		}
+		sort.Strings(metrics)
+		return nil, fmt.Errorf("invalid metric %q: use one of %s", cond.Metric, strings.Join(metrics, ", "))
+	}
+	if !alertOperators[cond.Operator] {
+		return nil, fmt.Errorf("invalid operator %q", cond.Operator)
+	}
+
+	return &cond, nil
+}
+
+// MetricValue returns an alert metric of a status. There is no value without
+// samples, nor a projected time without consumption.
+func MetricValue(status *models.WraparoundStatus, metric string) (float64, bool) {
+	if status.CollectedAt == nil {
+		return 0, false
+	}
+
+	switch metric {
+	case MetricXIDPercent:
+		return status.XID.PercentOfHardLimit, true
+	case MetricMXIDPercent:
+		return status.Multixact.PercentOfHardLimit, true
+	case MetricXIDAge:
+		return float64(status.XID.OldestAge), true
+	case MetricMXIDAge:
+		return float64(status.Multixact.OldestAge), true
+	case MetricDaysToXIDFreezeMaxAge:
+		return daysUntil(status, status.XID.FreezeMaxAgeAt)
+	case MetricDaysToXIDHardLimit:
+		return daysUntil(status, status.XID.HardLimitAt)
+	case MetricDaysToMXIDHardLimit:
+		return daysUntil(status, status.Multixact.HardLimitAt)
+	case MetricOldestBlockerXIDAge:
+		var oldest int64
+		for _, b := range status.Blockers {
+			if b.XIDAge != nil && *b.XIDAge > oldest {
+				oldest = *b.XIDAge
+			}
+		}
+		return float64(oldest), true
+	case MetricHealthScore:
+		return float64(status.HealthScore), true
+	default:
+		return 0, false
+	}
+}
+
+func daysUntil(status *models.WraparoundStatus, at *time.Time) (float64, bool) {
+	if at == nil {
+		return 0, false
+	}
+	return at.Sub(*status.CollectedAt).Hours() / 24, true
+}
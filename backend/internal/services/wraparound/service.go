@@ -0,0 +1,333 @@
+package wraparound
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// HardLimit is the age at which PostgreSQL stops assigning transaction
+	// IDs, or multixacts, to prevent wraparound: 2^31 minus the 3 million ID
+	// safety margin, after which only single-user mode VACUUM helps
+	HardLimit int64 = 1<<31 - 1 - 3000000
+
+	// RateLookback is how far back the baseline sample for consumption rates goes
+	RateLookback = 24 * time.Hour
+
+	// PostgreSQL defaults for settings the collector did not report
+	defaultFreezeMaxAge          = 200000000
+	defaultMultixactFreezeMaxAge = 400000000
+	defaultFreezeMinAge          = 50000000
+
+	// Risk thresholds: a share of the hard limit, or a projected time to it
+	warningPercent  = 25.0 // well past the default autovacuum_freeze_max_age
+	criticalPercent = 50.0 // vacuum_failsafe_age territory
+	warningHorizon  = 30 * 24 * time.Hour
+	criticalHorizon = 7 * 24 * time.Hour
+)
+
+// Store interface for wraparound samples
+type Store interface {
+	GetWraparoundSample(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.WraparoundSample, error)
+	GetWraparoundBaseline(ctx context.Context, collectorID uuid.UUID, from, before time.Time) (*models.WraparoundSample, error)
+	GetWraparoundAges(ctx context.Context, collectorID uuid.UUID, sampleTime time.Time) ([]*models.WraparoundAge, []*models.WraparoundAge, error)
+	GetWraparoundBlockers(ctx context.Context, collectorID uuid.UUID, sampleTime time.Time) ([]*models.WraparoundBlocker, error)
+	GetWraparoundHistory(ctx context.Context, collectorID uuid.UUID, database string, schema, table *string, from, to time.Time) ([]*models.WraparoundAge, error)
+}
+
+// Service tracks transaction ID and multixact wraparound headroom
+type Service struct {
+	store  Store
+	logger *zap.Logger
+}
+
+// NewService creates a new wraparound service
+func NewService(store Store, logger *zap.Logger) *Service {
+	return &Service{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// GetStatus assesses the wraparound risk of a collector's cluster from the
+// latest sample at or before at. Without samples the level is unknown.
+func (s *Service) GetStatus(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.WraparoundStatus, error) {
+	status := &models.WraparoundStatus{
+		CollectorID: collectorID,
+		Level:       models.WraparoundLevelUnknown,
+		HealthScore: 100,
+		Databases:   []*models.DatabaseWraparound{},
+		Tables:      []*models.TableWraparound{},
+		Blockers:    []*models.WraparoundBlocker{},
+		Findings:    []string{},
+	}
+
+	sample, err := s.store.GetWraparoundSample(ctx, collectorID, at)
+	if err != nil {
+		s.logger.Error("Failed to find wraparound sample", zap.Error(err))
+		return nil, err
+	}
+	if sample == nil {
+		return status, nil
+	}
+
+	baseline, err := s.store.GetWraparoundBaseline(ctx, collectorID, sample.Time.Add(-RateLookback), sample.Time)
+	if err != nil {
+		s.logger.Error("Failed to find wraparound baseline", zap.Error(err))
+		return nil, err
+	}
+
+	databases, tables, err := s.store.GetWraparoundAges(ctx, collectorID, sample.Time)
+	if err != nil {
+		s.logger.Error("Failed to load wraparound ages", zap.Error(err))
+		return nil, err
+	}
+
+	blockers, err := s.store.GetWraparoundBlockers(ctx, collectorID, sample.Time)
+	if err != nil {
+		s.logger.Error("Failed to load wraparound blockers", zap.Error(err))
+		return nil, err
+	}
+
+	Assess(status, sample, baseline, databases, tables, blockers)
+	return status, nil
+}
+
+// GetHistory returns the ages of a database, or of one of its tables, over time
+func (s *Service) GetHistory(ctx context.Context, collectorID uuid.UUID, database string, schema, table *string, from, to time.Time) ([]*models.WraparoundAge, error) {
+	ages, err := s.store.GetWraparoundHistory(ctx, collectorID, database, schema, table, from, to)
+	if err != nil {
+		s.logger.Error("Failed to load wraparound history", zap.Error(err))
+		return nil, err
+	}
+	if ages == nil {
+		ages = []*models.WraparoundAge{}
+	}
+	return ages, nil
+}
+
+// Assess fills a status from a sample, the baseline sample for consumption
+// rates (nil when there is none) and the sample's ages and blockers
+func Assess(status *models.WraparoundStatus, sample, baseline *models.WraparoundSample, databases, tables []*models.WraparoundAge, blockers []*models.WraparoundBlocker) {
+	collectedAt := sample.Time
+	status.CollectedAt = &collectedAt
+
+	freezeMaxAge := withDefault(sample.FreezeMaxAge, defaultFreezeMaxAge)
+	mxidFreezeMaxAge := withDefault(sample.MultixactFreezeMaxAge, defaultMultixactFreezeMaxAge)
+	freezeMinAge := withDefault(sample.FreezeMinAge, defaultFreezeMinAge)
+
+	var xidRate, mxidRate *float64
+	if baseline != nil && sample.Time.After(baseline.Time) {
+		hours := sample.Time.Sub(baseline.Time).Hours()
+		// Transaction IDs are epoch-qualified and never go backwards
+		if consumed := sample.NextXID - baseline.NextXID; consumed >= 0 {
+			rate := float64(consumed) / hours
+			xidRate = &rate
+		}
+		// Multixact IDs are 32 bit and wrap around
+		consumed := sample.NextMultixact - baseline.NextMultixact
+		if consumed < 0 {
+			consumed += 1 << 32
+		}
+		rate := float64(consumed) / hours
+		mxidRate = &rate
+	}
+
+	status.XID = &models.WraparoundHeadroom{FreezeMaxAge: freezeMaxAge}
+	status.Multixact = &models.WraparoundHeadroom{FreezeMaxAge: mxidFreezeMaxAge}
+	for _, d := range databases {
+		if d.XIDAge > status.XID.OldestAge || status.XID.OldestDatabase == "" {
+			status.XID.OldestAge = d.XIDAge
+			status.XID.OldestDatabase = d.DatabaseName
+		}
+		if d.MXIDAge > status.Multixact.OldestAge || status.Multixact.OldestDatabase == "" {
+			status.Multixact.OldestAge = d.MXIDAge
+			status.Multixact.OldestDatabase = d.DatabaseName
+		}
+	}
+	project(status.XID, xidRate, collectedAt)
+	project(status.Multixact, mxidRate, collectedAt)
+
+	for _, d := range databases {
+		xid := &models.WraparoundHeadroom{OldestAge: d.XIDAge, FreezeMaxAge: freezeMaxAge}
+		project(xid, xidRate, collectedAt)
+		status.Databases = append(status.Databases, &models.DatabaseWraparound{
+			DatabaseName:   d.DatabaseName,
+			XIDAge:         d.XIDAge,
+			MXIDAge:        d.MXIDAge,
+			XIDPercent:     xid.PercentOfHardLimit,
+			MXIDPercent:    percentOfHardLimit(d.MXIDAge),
+			FreezeMaxAgeAt: xid.FreezeMaxAgeAt,
+			HardLimitAt:    xid.HardLimitAt,
+		})
+	}
+
+	for _, t := range tables {
+		// A table can only lower autovacuum_freeze_max_age
+		effective := freezeMaxAge
+		if t.FreezeMaxAge != nil && *t.FreezeMaxAge > 0 && *t.FreezeMaxAge < effective {
+			effective = *t.FreezeMaxAge
+		}
+		status.Tables = append(status.Tables, &models.TableWraparound{
+			DatabaseName:     t.DatabaseName,
+			SchemaName:       t.SchemaName,
+			TableName:        t.TableName,
+			XIDAge:           t.XIDAge,
+			MXIDAge:          t.MXIDAge,
+			XIDPercent:       percentOfHardLimit(t.XIDAge),
+			TableSizeBytes:   t.TableSizeBytes,
+			FreezeMaxAge:     effective,
+			PastFreezeMaxAge: t.XIDAge > effective,
+		})
+	}
+
+	for _, b := range blockers {
+		assessBlocker(b, freezeMinAge, freezeMaxAge)
+		status.Blockers = append(status.Blockers, b)
+	}
+
+	status.Level, status.HealthScore, status.Findings = summarize(status)
+}
+
+// project fills the headroom of an age against the freeze maximum and the
+// hard limit, and the times they are reached at the consumption rate
+func project(h *models.WraparoundHeadroom, ratePerHour *float64, from time.Time) {
+	h.HardLimit = HardLimit
+	h.PercentOfHardLimit = percentOfHardLimit(h.OldestAge)
+	h.RemainingToFreezeMaxAge = h.FreezeMaxAge - h.OldestAge
+	h.RemainingToHardLimit = HardLimit - h.OldestAge
+	h.ConsumptionPerHour = ratePerHour
+
+	h.FreezeMaxAgeAt = projectAt(h.RemainingToFreezeMaxAge, ratePerHour, from)
+	h.HardLimitAt = projectAt(h.RemainingToHardLimit, ratePerHour, from)
+}
+
+// projectAt returns when remaining IDs are consumed; already when none remain,
+// never (nil) when nothing is consumed or the date lies beyond what a
+// time.Duration holds, about 290 years
+func projectAt(remaining int64, ratePerHour *float64, from time.Time) *time.Time {
+	if remaining <= 0 {
+		return &from
+	}
+	if ratePerHour == nil || *ratePerHour <= 0 {
+		return nil
+	}
+	nanos := float64(remaining) / *ratePerHour * float64(time.Hour)
+	if nanos >= math.MaxInt64 {
+		return nil
+	}
+	at := from.Add(time.Duration(nanos))
+	return &at
+}
+
+// assessBlocker rates a blocker by the age it holds the xmin horizon back to:
+// past vacuum_freeze_min_age vacuum stops freezing, past
+// autovacuum_freeze_max_age anti-wraparound vacuums cannot make progress
+func assessBlocker(b *models.WraparoundBlocker, freezeMinAge, freezeMaxAge int64) {
+	b.Level = models.WraparoundLevelOK
+	if b.XIDAge == nil {
+		return
+	}
+	age := *b.XIDAge
+	switch {
+	case age >= freezeMaxAge:
+		b.Level = models.WraparoundLevelCritical
+	case age >= freezeMinAge:
+		b.Level = models.WraparoundLevelWarning
+	default:
+		return
+	}
+
+	switch b.Type {
+	case models.WraparoundBlockerPreparedTransaction:
+		b.Reason = fmt.Sprintf("Prepared transaction %q holds the xmin horizon %d transactions back; COMMIT PREPARED or ROLLBACK PREPARED it", b.Name, age)
+	case models.WraparoundBlockerReplicationSlot:
+		if b.Active != nil && !*b.Active {
+			b.Reason = fmt.Sprintf("Inactive replication slot %q holds the xmin horizon %d transactions back; drop it with pg_drop_replication_slot if its consumer is gone", b.Name, age)
+		} else {
+			b.Reason = fmt.Sprintf("Replication slot %q holds the xmin horizon %d transactions back; its consumer is lagging", b.Name, age)
+		}
+	case models.WraparoundBlockerLongTransaction:
+		b.Reason = fmt.Sprintf("Transaction of backend %s holds the xmin horizon %d transactions back; end it or terminate the backend with pg_terminate_backend", b.Name, age)
+	default:
+		b.Reason = fmt.Sprintf("%s %q holds the xmin horizon %d transactions back", b.Type, b.Name, age)
+	}
+}
+
+// summarize derives the overall level, a 0-100 health score and findings:
+// the score falls 1.5 points per percent of the hard limit used, with
+// deductions for blockers and caps for an imminent projected hard limit
+func summarize(status *models.WraparoundStatus) (string, int, []string) {
+	findings := []string{}
+	level := models.WraparoundLevelOK
+	raise := func(to string) {
+		if to == models.WraparoundLevelCritical || level == models.WraparoundLevelOK {
+			level = to
+		}
+	}
+
+	score := 100.0
+	for _, h := range []struct {
+		name     string
+		headroom *models.WraparoundHeadroom
+	}{{"Transaction ID", status.XID}, {"Multixact", status.Multixact}} {
+		pct := h.headroom.PercentOfHardLimit
+		score = math.Min(score, 100-1.5*pct)
+
+		if pct >= criticalPercent {
+			raise(models.WraparoundLevelCritical)
+			findings = append(findings, fmt.Sprintf("%s age of database %q is %.1f%% of the wraparound limit", h.name, h.headroom.OldestDatabase, pct))
+		} else if pct >= warningPercent {
+			raise(models.WraparoundLevelWarning)
+			findings = append(findings, fmt.Sprintf("%s age of database %q is %.1f%% of the wraparound limit; anti-wraparound vacuums are not keeping up", h.name, h.headroom.OldestDatabase, pct))
+		}
+
+		if h.headroom.HardLimitAt != nil && status.CollectedAt != nil {
+			remaining := h.headroom.HardLimitAt.Sub(*status.CollectedAt)
+			switch {
+			case remaining <= criticalHorizon:
+				raise(models.WraparoundLevelCritical)
+				score = math.Min(score, 20)
+				findings = append(findings, fmt.Sprintf("%s wraparound limit projected for %s at the current consumption rate", h.name, h.headroom.HardLimitAt.Format(time.RFC3339)))
+			case remaining <= warningHorizon:
+				raise(models.WraparoundLevelWarning)
+				score = math.Min(score, 50)
+				findings = append(findings, fmt.Sprintf("%s wraparound limit projected for %s at the current consumption rate", h.name, h.headroom.HardLimitAt.Format(time.RFC3339)))
+			}
+		}
+	}
+
+	var deduction float64
+	for _, b := range status.Blockers {
+		switch b.Level {
+		case models.WraparoundLevelCritical:
+			raise(models.WraparoundLevelCritical)
+			deduction += 25
+			findings = append(findings, b.Reason)
+		case models.WraparoundLevelWarning:
+			raise(models.WraparoundLevelWarning)
+			deduction += 10
+			findings = append(findings, b.Reason)
+		}
+	}
+	score -= math.Min(deduction, 50)
+
+	return level, int(math.Round(math.Max(0, math.Min(100, score)))), findings
+}
+
+func percentOfHardLimit(age int64) float64 {
+	return float64(age) / float64(HardLimit) * 100
+}
+
+func withDefault(v, def int64) int64 {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
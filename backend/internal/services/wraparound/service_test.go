@@ -0,0 +1,261 @@
+package wraparound
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+	"go.uber.org/zap"
+)
+
+// mockWraparoundStore is a mock implementation for testing
+type mockWraparoundStore struct {
+	sample    *models.WraparoundSample
+	baseline  *models.WraparoundSample
+	databases []*models.WraparoundAge
+	tables    []*models.WraparoundAge
+	blockers  []*models.WraparoundBlocker
+	history   []*models.WraparoundAge
+	err       error
+
+	lastFrom time.Time
+}
+
+func (m *mockWraparoundStore) GetWraparoundSample(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.WraparoundSample, error) {
+	return m.sample, m.err
+}
+
+func (m *mockWraparoundStore) GetWraparoundBaseline(ctx context.Context, collectorID uuid.UUID, from, before time.Time) (*models.WraparoundSample, error) {
+	m.lastFrom = from
+	return m.baseline, m.err
+}
+
+func (m *mockWraparoundStore) GetWraparoundAges(ctx context.Context, collectorID uuid.UUID, sampleTime time.Time) ([]*models.WraparoundAge, []*models.WraparoundAge, error) {
+	return m.databases, m.tables, m.err
+}
+
+func (m *mockWraparoundStore) GetWraparoundBlockers(ctx context.Context, collectorID uuid.UUID, sampleTime time.Time) ([]*models.WraparoundBlocker, error) {
+	return m.blockers, m.err
+}
+
+func (m *mockWraparoundStore) GetWraparoundHistory(ctx context.Context, collectorID uuid.UUID, database string, schema, table *string, from, to time.Time) ([]*models.WraparoundAge, error) {
+	return m.history, m.err
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+// newTestStore returns a sample taken at at that consumed rate XIDs per hour
+// over the last day, with default freeze settings
+func newTestStore(at time.Time, ratePerHour int64, databases ...*models.WraparoundAge) *mockWraparoundStore {
+	return &mockWraparoundStore{
+		sample: &models.WraparoundSample{
+			Time:          at,
+			NextXID:       5000000000,
+			NextMultixact: 1000,
+			FreezeMaxAge:  200000000,
+			FreezeMinAge:  50000000,
+		},
+		baseline: &models.WraparoundSample{
+			Time:          at.Add(-24 * time.Hour),
+			NextXID:       5000000000 - 24*ratePerHour,
+			NextMultixact: 1000,
+		},
+		databases: databases,
+	}
+}
+
+// TestGetStatus_NoSample is unknown and healthy
+func TestGetStatus_NoSample(t *testing.T) {
+	service := NewService(&mockWraparoundStore{}, zap.NewNop())
+
+	status, err := service.GetStatus(context.Background(), uuid.New(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, models.WraparoundLevelUnknown, status.Level)
+	assert.Equal(t, 100, status.HealthScore)
+	assert.Nil(t, status.CollectedAt)
+	assert.NotNil(t, status.Databases)
+	assert.NotNil(t, status.Findings)
+
+	_, ok := MetricValue(status, MetricXIDAge)
+	assert.False(t, ok, "no metric without samples")
+}
+
+// TestGetStatus_Projection projects the freeze maximum and the hard limit at
+// the observed consumption rate
+func TestGetStatus_Projection(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(at, 1000000,
+		&models.WraparoundAge{DatabaseName: "app", XIDAge: 150000000, MXIDAge: 10},
+		&models.WraparoundAge{DatabaseName: "postgres", XIDAge: 20000000, MXIDAge: 5},
+	)
+	service := NewService(store, zap.NewNop())
+
+	status, err := service.GetStatus(context.Background(), uuid.New(), at)
+	require.NoError(t, err)
+	assert.Equal(t, at.Add(-RateLookback), store.lastFrom)
+
+	xid := status.XID
+	assert.Equal(t, "app", xid.OldestDatabase)
+	assert.Equal(t, int64(150000000), xid.OldestAge)
+	assert.Equal(t, int64(50000000), xid.RemainingToFreezeMaxAge)
+	require.NotNil(t, xid.ConsumptionPerHour)
+	assert.InDelta(t, 1000000, *xid.ConsumptionPerHour, 0.001)
+	require.NotNil(t, xid.FreezeMaxAgeAt)
+	assert.Equal(t, at.Add(50*time.Hour), *xid.FreezeMaxAgeAt)
+	require.NotNil(t, xid.HardLimitAt)
+	assert.Equal(t, at.Add(time.Duration(float64(HardLimit-150000000)/1000000*float64(time.Hour))), *xid.HardLimitAt)
+
+	// About 7% of the hard limit and 83 days away: healthy
+	assert.Equal(t, models.WraparoundLevelOK, status.Level)
+	assert.Equal(t, 90, status.HealthScore)
+	require.Len(t, status.Databases, 2)
+
+	days, ok := MetricValue(status, MetricDaysToXIDFreezeMaxAge)
+	require.True(t, ok)
+	assert.InDelta(t, 50.0/24, days, 0.0001)
+
+	_, ok = MetricValue(status, MetricDaysToMXIDHardLimit)
+	assert.False(t, ok, "no projection without multixact consumption")
+}
+
+// TestGetStatus_ImminentHardLimit is critical when the hard limit is less
+// than a week away
+func TestGetStatus_ImminentHardLimit(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(at, 10000000,
+		&models.WraparoundAge{DatabaseName: "app", XIDAge: 1200000000},
+	)
+	service := NewService(store, zap.NewNop())
+
+	status, err := service.GetStatus(context.Background(), uuid.New(), at)
+	require.NoError(t, err)
+	assert.Equal(t, models.WraparoundLevelCritical, status.Level)
+	assert.LessOrEqual(t, status.HealthScore, 20)
+	assert.Len(t, status.Findings, 2, "share of the limit and projection")
+	assert.Equal(t, at, *status.XID.FreezeMaxAgeAt, "already past autovacuum_freeze_max_age")
+}
+
+// TestGetStatus_LowRate leaves projections past what a time.Duration holds
+// unset rather than overflowing into the past
+func TestGetStatus_LowRate(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(at, 100,
+		&models.WraparoundAge{DatabaseName: "app", XIDAge: 1000000},
+	)
+	service := NewService(store, zap.NewNop())
+
+	status, err := service.GetStatus(context.Background(), uuid.New(), at)
+	require.NoError(t, err)
+	require.NotNil(t, status.XID.ConsumptionPerHour)
+	assert.Nil(t, status.XID.HardLimitAt, "more than 290 years away")
+	require.NotNil(t, status.XID.FreezeMaxAgeAt, "227 years away")
+	assert.True(t, status.XID.FreezeMaxAgeAt.After(at))
+	assert.Equal(t, models.WraparoundLevelOK, status.Level)
+
+	_, ok := MetricValue(status, MetricDaysToXIDHardLimit)
+	assert.False(t, ok)
+}
+
+// TestAssess_MultixactWrap accounts for the 32 bit multixact counter wrapping
+func TestAssess_MultixactWrap(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sample := &models.WraparoundSample{Time: at, NextXID: 100, NextMultixact: 500}
+	baseline := &models.WraparoundSample{Time: at.Add(-10 * time.Hour), NextXID: 100, NextMultixact: 1<<32 - 500}
+
+	status := &models.WraparoundStatus{}
+	Assess(status, sample, baseline, []*models.WraparoundAge{{DatabaseName: "app", MXIDAge: 1000}}, nil, nil)
+
+	require.NotNil(t, status.Multixact.ConsumptionPerHour)
+	assert.InDelta(t, 100, *status.Multixact.ConsumptionPerHour, 0.001)
+	assert.Equal(t, int64(400000000), status.Multixact.FreezeMaxAge, "default setting")
+	assert.Nil(t, status.XID.HardLimitAt, "no XIDs consumed")
+}
+
+// TestAssess_Tables uses the lower of the reloption and the server setting
+func TestAssess_Tables(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sample := &models.WraparoundSample{Time: at, FreezeMaxAge: 200000000}
+	tables := []*models.WraparoundAge{
+		{DatabaseName: "app", SchemaName: "public", TableName: "events", XIDAge: 120000000, FreezeMaxAge: int64Ptr(100000000)},
+		{DatabaseName: "app", SchemaName: "public", TableName: "users", XIDAge: 120000000, FreezeMaxAge: int64Ptr(500000000)},
+	}
+
+	status := &models.WraparoundStatus{}
+	Assess(status, sample, nil, nil, tables, nil)
+
+	require.Len(t, status.Tables, 2)
+	assert.Equal(t, int64(100000000), status.Tables[0].FreezeMaxAge)
+	assert.True(t, status.Tables[0].PastFreezeMaxAge)
+	assert.Equal(t, int64(200000000), status.Tables[1].FreezeMaxAge)
+	assert.False(t, status.Tables[1].PastFreezeMaxAge)
+	assert.Nil(t, status.XID.ConsumptionPerHour, "no baseline")
+}
+
+// TestAssess_Blockers rates blockers against the freeze settings
+func TestAssess_Blockers(t *testing.T) {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sample := &models.WraparoundSample{Time: at, FreezeMaxAge: 200000000, FreezeMinAge: 50000000}
+	inactive := false
+	blockers := []*models.WraparoundBlocker{
+		{Type: models.WraparoundBlockerReplicationSlot, Name: "old_replica", XIDAge: int64Ptr(250000000), Active: &inactive},
+		{Type: models.WraparoundBlockerPreparedTransaction, Name: "tx1", XIDAge: int64Ptr(60000000)},
+		{Type: models.WraparoundBlockerLongTransaction, Name: "4242", XIDAge: int64Ptr(1000)},
+		{Type: models.WraparoundBlockerLongTransaction, Name: "4343"},
+	}
+
+	status := &models.WraparoundStatus{}
+	Assess(status, sample, nil, nil, nil, blockers)
+
+	assert.Equal(t, models.WraparoundLevelCritical, blockers[0].Level)
+	assert.Contains(t, blockers[0].Reason, "pg_drop_replication_slot")
+	assert.Equal(t, models.WraparoundLevelWarning, blockers[1].Level)
+	assert.Contains(t, blockers[1].Reason, "COMMIT PREPARED")
+	assert.Equal(t, models.WraparoundLevelOK, blockers[2].Level)
+	assert.Empty(t, blockers[2].Reason)
+	assert.Equal(t, models.WraparoundLevelOK, blockers[3].Level)
+
+	assert.Equal(t, models.WraparoundLevelCritical, status.Level)
+	assert.Equal(t, 65, status.HealthScore)
+	assert.Len(t, status.Findings, 2)
+
+	oldest, ok := MetricValue(status, MetricOldestBlockerXIDAge)
+	require.True(t, ok)
+	assert.Equal(t, float64(250000000), oldest)
+}
+
+// TestGetHistory returns an empty slice without ages
+func TestGetHistory(t *testing.T) {
+	service := NewService(&mockWraparoundStore{}, zap.NewNop())
+
+	ages, err := service.GetHistory(context.Background(), uuid.New(), "app", nil, nil, time.Now().Add(-time.Hour), time.Now())
+	require.NoError(t, err)
+	assert.NotNil(t, ages)
+	assert.Empty(t, ages)
+}
+
+// TestParseAlertCondition validates the collector, metric and operator
+func TestParseAlertCondition(t *testing.T) {
+	collectorID := uuid.New()
+	cond, err := ParseAlertCondition(json.RawMessage(`{"collector_id":"` + collectorID.String() + `","metric":"days_to_xid_hard_limit","operator":"<","value":14}`))
+	require.NoError(t, err)
+	assert.Equal(t, collectorID, cond.CollectorID)
+	assert.Equal(t, MetricDaysToXIDHardLimit, cond.Metric)
+	assert.Equal(t, float64(14), cond.Value)
+
+	for name, raw := range map[string]string{
+		"collector": `{"metric":"xid_age","operator":">","value":1}`,
+		"metric":    `{"collector_id":"` + collectorID.String() + `","metric":"age","operator":">","value":1}`,
+		"operator":  `{"collector_id":"` + collectorID.String() + `","metric":"xid_age","operator":"=>","value":1}`,
+		"json":      `{`,
+	} {
+		_, err := ParseAlertCondition(json.RawMessage(raw))
+		assert.Error(t, err, name)
+	}
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// ACTIVE SESSION HISTORY OPERATIONS
+// ============================================================================
+
+// activityWaitLabel renders a sample's wait event as "Type:Event", or CPU
+const activityWaitLabel = `CASE WHEN wait_event_type = 'CPU' THEN 'CPU'
+	ELSE wait_event_type || ':' || COALESCE(wait_event, '') END`
+
+// activityWhere builds the WHERE clause shared by active session history queries
+func activityWhere(filter models.ActivityFilter) (string, []interface{}) {
+	conditions := []string{"collector_id = $1", "time >= $2", "time < $3"}
+	args := []interface{}{filter.CollectorID, filter.From, filter.To}
+
+	if filter.Database != nil {
+		args = append(args, *filter.Database)
+		conditions = append(conditions, fmt.Sprintf("database_name = $%d", len(args)))
+	}
+	if filter.Fingerprint != nil {
+		args = append(args, *filter.Fingerprint)
+		conditions = append(conditions, fmt.Sprintf("query_fingerprint = $%d", len(args)))
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// activityLimit appends the filter limit as the next query argument
+func activityLimit(filter models.ActivityFilter, args []interface{}) (string, []interface{}) {
+	args = append(args, filter.Limit)
+	return fmt.Sprintf("LIMIT $%d", len(args)), args
+}
+
+// InsertActivitySamples stores pg_stat_activity samples
+func (p *PostgresDB) InsertActivitySamples(ctx context.Context, samples []*models.ActivitySample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO metrics_pg_activity_samples (
+			time, collector_id, database_name, pid, usename, application_name, client_addr,
+			backend_type, state, wait_event_type, wait_event, query_id, query_fingerprint,
+			query_text, backend_start, xact_start, query_start, sample_seconds
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+	`)
+	if err != nil {
+		return apperrors.DatabaseError("prepare activity sample insert", err.Error())
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, s := range samples {
+		if _, err := stmt.ExecContext(ctx,
+			s.Time, s.CollectorID, s.DatabaseName, s.PID, s.Username, s.ApplicationName, s.ClientAddr,
+			s.BackendType, s.State, s.WaitEventType, s.WaitEvent, s.QueryID, s.QueryFingerprint,
+			s.QueryText, s.BackendStart, s.XactStart, s.QueryStart, s.SampleSeconds,
+		); err != nil {
+			return apperrors.DatabaseError("insert activity sample", err.Error())
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit transaction", err.Error())
+	}
+
+	return nil
+}
+
+// GetActivityTotals returns the sample count and DB time in a window
+func (p *PostgresDB) GetActivityTotals(ctx context.Context, filter models.ActivityFilter) (*models.ActivityTotals, error) {
+	where, args := activityWhere(filter)
+
+	totals := &models.ActivityTotals{}
+	err := p.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(sample_seconds), 0)
+		FROM metrics_pg_activity_samples
+		`+where, args...).Scan(&totals.Samples, &totals.DBTimeSeconds)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get activity totals", err.Error())
+	}
+
+	return totals, nil
+}
+
+// GetActivityWaitEvents returns wait events ordered by DB time
+func (p *PostgresDB) GetActivityWaitEvents(ctx context.Context, filter models.ActivityFilter) ([]*models.WaitEventActivity, error) {
+	where, args := activityWhere(filter)
+	limit, args := activityLimit(filter, args)
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT wait_event_type, COALESCE(wait_event, ''), COUNT(*), SUM(sample_seconds) AS db_time
+		FROM metrics_pg_activity_samples
+		`+where+`
+		GROUP BY wait_event_type, COALESCE(wait_event, '')
+		ORDER BY db_time DESC
+		`+limit, args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get activity wait events", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var events []*models.WaitEventActivity
+	for rows.Next() {
+		e := &models.WaitEventActivity{}
+		if err := rows.Scan(&e.WaitEventType, &e.WaitEvent, &e.Samples, &e.DBTimeSeconds); err != nil {
+			return nil, apperrors.DatabaseError("scan activity wait event", err.Error())
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// GetActivitySessions returns backends ordered by DB time
+// A backend is identified by pid and backend_start so that reused pids are
+// reported separately.
+func (p *PostgresDB) GetActivitySessions(ctx context.Context, filter models.ActivityFilter) ([]*models.SessionActivity, error) {
+	where, args := activityWhere(filter)
+	limit, args := activityLimit(filter, args)
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT pid, backend_start,
+		       MAX(database_name), MAX(usename), MAX(application_name), MAX(client_addr), MAX(backend_type),
+		       COUNT(*), SUM(sample_seconds) AS db_time,
+		       mode() WITHIN GROUP (ORDER BY `+activityWaitLabel+`),
+		       COUNT(DISTINCT NULLIF(query_fingerprint, ''))
+		FROM metrics_pg_activity_samples
+		`+where+`
+		GROUP BY pid, backend_start
+		ORDER BY db_time DESC
+		`+limit, args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get activity sessions", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sessions []*models.SessionActivity
+	for rows.Next() {
+		s := &models.SessionActivity{}
+		if err := rows.Scan(
+			&s.PID, &s.BackendStart,
+			&s.DatabaseName, &s.Username, &s.ApplicationName, &s.ClientAddr, &s.BackendType,
+			&s.Samples, &s.DBTimeSeconds, &s.TopWaitEvent, &s.Queries,
+		); err != nil {
+			return nil, apperrors.DatabaseError("scan activity session", err.Error())
+		}
+		sessions = append(sessions, s)
+	}
+
+	return sessions, rows.Err()
+}
+
+// GetActivityQueries returns query fingerprints ordered by DB time
+func (p *PostgresDB) GetActivityQueries(ctx context.Context, filter models.ActivityFilter) ([]*models.QueryActivity, error) {
+	where, args := activityWhere(filter)
+	limit, args := activityLimit(filter, args)
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT query_fingerprint, MAX(query_text),
+		       COALESCE(array_agg(DISTINCT query_id) FILTER (WHERE query_id IS NOT NULL), '{}'),
+		       COUNT(*), SUM(sample_seconds) AS db_time,
+		       mode() WITHIN GROUP (ORDER BY `+activityWaitLabel+`),
+		       COUNT(DISTINCT pid)
+		FROM metrics_pg_activity_samples
+		`+where+` AND query_fingerprint <> ''
+		GROUP BY query_fingerprint
+		ORDER BY db_time DESC
+		`+limit, args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get activity queries", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var queries []*models.QueryActivity
+	for rows.Next() {
+		q := &models.QueryActivity{}
+		if err := rows.Scan(
+			&q.QueryFingerprint, &q.QueryText, pq.Array(&q.QueryIDs),
+			&q.Samples, &q.DBTimeSeconds, &q.TopWaitEvent, &q.Sessions,
+		); err != nil {
+			return nil, apperrors.DatabaseError("scan activity query", err.Error())
+		}
+		queries = append(queries, q)
+	}
+
+	return queries, rows.Err()
+}
+
+// GetActivityWaitTimeline returns DB time per wait event type in time buckets
+func (p *PostgresDB) GetActivityWaitTimeline(ctx context.Context, filter models.ActivityFilter, bucketSeconds int) ([]*models.WaitEventBucket, error) {
+	where, args := activityWhere(filter)
+	args = append(args, bucketSeconds)
+	bucket := fmt.Sprintf("time_bucket(make_interval(secs => $%d), time)", len(args))
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT `+bucket+` AS bucket, wait_event_type, COUNT(*), SUM(sample_seconds)
+		FROM metrics_pg_activity_samples
+		`+where+`
+		GROUP BY bucket, wait_event_type
+		ORDER BY bucket ASC, wait_event_type ASC
+	`, args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get activity wait timeline", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var buckets []*models.WaitEventBucket
+	for rows.Next() {
+		b := &models.WaitEventBucket{}
+		if err := rows.Scan(&b.Time, &b.WaitEventType, &b.Samples, &b.DBTimeSeconds); err != nil {
+			return nil, apperrors.DatabaseError("scan activity wait bucket", err.Error())
+		}
+		buckets = append(buckets, b)
+	}
+
+	return buckets, rows.Err()
+}
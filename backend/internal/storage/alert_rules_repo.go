@@ -17,7 +17,7 @@ type AlertRule struct {
 	UserID               int
 	Name                 string
 	Description          string
-	RuleType             string // "threshold", "change", "anomaly", "composite"
+	RuleType             string // "threshold", "change", "anomaly", "composite", "blocking_chain", "log", "wraparound", "replication_slot", "cluster_event"
 	DatabaseID           *int
 	QueryID              *int
 	MetricName           string
@@ -0,0 +1,642 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// CUSTOM HEALTH CHECK PACK OPERATIONS
+// ============================================================================
+
+const healthCheckPackColumns = `p.id, p.tenant_id, p.name, p.version, COALESCE(p.description, ''),
+			   (SELECT COUNT(*) FROM postgres_health_checks c WHERE c.pack_id = p.id),
+			   p.created_at, p.updated_at`
+
+func scanHealthCheckPack(row rowScanner) (*models.HealthCheckPack, error) {
+	pack := &models.HealthCheckPack{}
+	err := row.Scan(
+		&pack.ID, &pack.TenantID, &pack.Name, &pack.Version, &pack.Description,
+		&pack.CheckCount, &pack.CreatedAt, &pack.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return pack, nil
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// ListHealthCheckPacks returns a tenant's check packs with their check counts
+func (p *PostgresDB) ListHealthCheckPacks(ctx context.Context, tenantID uuid.UUID) ([]*models.HealthCheckPack, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT `+healthCheckPackColumns+`
+		FROM health_check_packs p
+		WHERE p.tenant_id = $1
+		ORDER BY p.name, p.created_at
+	`, tenantID)
+	if err != nil {
+		return nil, apperrors.DatabaseError("list health check packs", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	packs := []*models.HealthCheckPack{}
+	for rows.Next() {
+		pack, err := scanHealthCheckPack(rows)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan health check pack", err.Error())
+		}
+		packs = append(packs, pack)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("list health check packs", err.Error())
+	}
+
+	return packs, nil
+}
+
+// GetHealthCheckPack returns a tenant's check pack with its checks
+func (p *PostgresDB) GetHealthCheckPack(ctx context.Context, tenantID uuid.UUID, id int64) (*models.HealthCheckPack, error) {
+	pack, err := scanHealthCheckPack(p.db.QueryRowContext(ctx, `
+		SELECT `+healthCheckPackColumns+`
+		FROM health_check_packs p
+		WHERE p.tenant_id = $1 AND p.id = $2
+	`, tenantID, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NotFound("Health check pack not found", fmt.Sprintf("id: %d", id))
+		}
+		return nil, apperrors.DatabaseError("get health check pack", err.Error())
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT `+healthCheckColumns+`
+		FROM postgres_health_checks hc
+		WHERE hc.pack_id = $1
+		ORDER BY hc.check_name
+	`, id)
+	if err != nil {
+		return nil, apperrors.DatabaseError("list pack health checks", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	pack.Checks = []*models.VersionHealthCheck{}
+	for rows.Next() {
+		check, err := scanVersionHealthCheck(rows)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan health check", err.Error())
+		}
+		pack.Checks = append(pack.Checks, check)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("list pack health checks", err.Error())
+	}
+
+	return pack, nil
+}
+
+// CreateHealthCheckPack stores a check pack and its checks, setting their IDs
+func (p *PostgresDB) CreateHealthCheckPack(ctx context.Context, pack *models.HealthCheckPack) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO health_check_packs (tenant_id, name, version, description)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`, pack.TenantID, pack.Name, pack.Version, nullString(pack.Description),
+	).Scan(&pack.ID, &pack.CreatedAt, &pack.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return apperrors.Conflict("Health check pack already exists", fmt.Sprintf("%s %s", pack.Name, pack.Version))
+		}
+		return apperrors.DatabaseError("create health check pack", err.Error())
+	}
+
+	for _, check := range pack.Checks {
+		if err := insertPackHealthCheck(ctx, tx, pack.ID, check); err != nil {
+			return err
+		}
+	}
+	pack.CheckCount = len(pack.Checks)
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit health check pack", err.Error())
+	}
+
+	return nil
+}
+
+// ImportHealthCheckPack stores an imported check pack. A pack with the same
+// name and version is a conflict unless replace is set; then its checks are
+// updated by name, new ones added and the ones missing from the import
+// removed, so results of unchanged checks are kept.
+func (p *PostgresDB) ImportHealthCheckPack(ctx context.Context, pack *models.HealthCheckPack, replace bool) error {
+	var existingID int64
+	err := p.db.QueryRowContext(ctx, `
+		SELECT id FROM health_check_packs WHERE tenant_id = $1 AND name = $2 AND version = $3
+	`, pack.TenantID, pack.Name, pack.Version).Scan(&existingID)
+	if err == sql.ErrNoRows {
+		return p.CreateHealthCheckPack(ctx, pack)
+	}
+	if err != nil {
+		return apperrors.DatabaseError("find health check pack", err.Error())
+	}
+	if !replace {
+		return apperrors.Conflict("Health check pack already exists", fmt.Sprintf("%s %s", pack.Name, pack.Version))
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE health_check_packs SET description = $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING id, created_at, updated_at
+	`, existingID, nullString(pack.Description)).Scan(&pack.ID, &pack.CreatedAt, &pack.UpdatedAt)
+	if err != nil {
+		return apperrors.DatabaseError("update health check pack", err.Error())
+	}
+
+	names := make([]string, 0, len(pack.Checks))
+	for _, check := range pack.Checks {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO postgres_health_checks (
+				pack_id, check_name, min_version, max_version, check_query, expected_result,
+				severity, description, remediation, category, assertion_type, assertion_value
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			ON CONFLICT (pack_id, check_name) WHERE pack_id IS NOT NULL DO UPDATE SET
+				min_version = EXCLUDED.min_version,
+				max_version = EXCLUDED.max_version,
+				check_query = EXCLUDED.check_query,
+				expected_result = EXCLUDED.expected_result,
+				severity = EXCLUDED.severity,
+				description = EXCLUDED.description,
+				remediation = EXCLUDED.remediation,
+				category = EXCLUDED.category,
+				assertion_type = EXCLUDED.assertion_type,
+				assertion_value = EXCLUDED.assertion_value,
+				updated_at = NOW()
+			RETURNING id, created_at, updated_at
+		`, append([]interface{}{pack.ID}, packHealthCheckValues(check)...)...,
+		).Scan(&check.ID, &check.CreatedAt, &check.UpdatedAt)
+		if err != nil {
+			return apperrors.DatabaseError("import pack health check", err.Error())
+		}
+		check.PackID = &pack.ID
+		names = append(names, check.CheckName)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM postgres_health_checks WHERE pack_id = $1 AND NOT (check_name = ANY($2))
+	`, pack.ID, pq.Array(names))
+	if err != nil {
+		return apperrors.DatabaseError("remove pack health checks", err.Error())
+	}
+	pack.CheckCount = len(pack.Checks)
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit health check pack", err.Error())
+	}
+
+	return nil
+}
+
+// UpdateHealthCheckPack updates a check pack's name, version and description
+func (p *PostgresDB) UpdateHealthCheckPack(ctx context.Context, pack *models.HealthCheckPack) error {
+	err := p.db.QueryRowContext(ctx, `
+		UPDATE health_check_packs
+		SET name = $3, version = $4, description = $5, updated_at = NOW()
+		WHERE tenant_id = $1 AND id = $2
+		RETURNING created_at, updated_at
+	`, pack.TenantID, pack.ID, pack.Name, pack.Version, nullString(pack.Description),
+	).Scan(&pack.CreatedAt, &pack.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return apperrors.NotFound("Health check pack not found", fmt.Sprintf("id: %d", pack.ID))
+		}
+		if isUniqueViolation(err) {
+			return apperrors.Conflict("Health check pack already exists", fmt.Sprintf("%s %s", pack.Name, pack.Version))
+		}
+		return apperrors.DatabaseError("update health check pack", err.Error())
+	}
+
+	return nil
+}
+
+// DeleteHealthCheckPack removes a check pack with its checks, results and
+// schedules
+func (p *PostgresDB) DeleteHealthCheckPack(ctx context.Context, tenantID uuid.UUID, id int64) error {
+	result, err := p.db.ExecContext(ctx,
+		`DELETE FROM health_check_packs WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return apperrors.DatabaseError("delete health check pack", err.Error())
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.NotFound("Health check pack not found", "")
+	}
+
+	return nil
+}
+
+// CreatePackHealthCheck adds a check to a tenant's pack and sets its ID
+func (p *PostgresDB) CreatePackHealthCheck(ctx context.Context, tenantID uuid.UUID, packID int64, check *models.VersionHealthCheck) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := touchHealthCheckPack(ctx, tx, tenantID, packID); err != nil {
+		return err
+	}
+	if err := insertPackHealthCheck(ctx, tx, packID, check); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit pack health check", err.Error())
+	}
+
+	return nil
+}
+
+// UpdatePackHealthCheck replaces the definition of a check in a tenant's pack
+func (p *PostgresDB) UpdatePackHealthCheck(ctx context.Context, tenantID uuid.UUID, packID int64, check *models.VersionHealthCheck) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := touchHealthCheckPack(ctx, tx, tenantID, packID); err != nil {
+		return err
+	}
+	err = tx.QueryRowContext(ctx, `
+		UPDATE postgres_health_checks
+		SET check_name = $3, min_version = $4, max_version = $5, check_query = $6, expected_result = $7,
+			severity = $8, description = $9, remediation = $10, category = $11,
+			assertion_type = $12, assertion_value = $13, updated_at = NOW()
+		WHERE pack_id = $1 AND id = $2
+		RETURNING created_at, updated_at
+	`, append([]interface{}{packID, check.ID}, packHealthCheckValues(check)...)...,
+	).Scan(&check.CreatedAt, &check.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return apperrors.NotFound("Health check not found", fmt.Sprintf("id: %d", check.ID))
+		}
+		if isUniqueViolation(err) {
+			return apperrors.Conflict("Health check already exists in pack", check.CheckName)
+		}
+		return apperrors.DatabaseError("update pack health check", err.Error())
+	}
+	check.PackID = &packID
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit pack health check", err.Error())
+	}
+
+	return nil
+}
+
+// DeletePackHealthCheck removes a check and its results from a tenant's pack
+func (p *PostgresDB) DeletePackHealthCheck(ctx context.Context, tenantID uuid.UUID, packID int64, checkID int) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := touchHealthCheckPack(ctx, tx, tenantID, packID); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM postgres_health_checks WHERE pack_id = $1 AND id = $2`, packID, checkID)
+	if err != nil {
+		return apperrors.DatabaseError("delete pack health check", err.Error())
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.NotFound("Health check not found", fmt.Sprintf("id: %d", checkID))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit pack health check", err.Error())
+	}
+
+	return nil
+}
+
+// touchHealthCheckPack marks a tenant's pack as updated, failing when the
+// pack is not the tenant's
+func touchHealthCheckPack(ctx context.Context, tx *sql.Tx, tenantID uuid.UUID, packID int64) error {
+	result, err := tx.ExecContext(ctx,
+		`UPDATE health_check_packs SET updated_at = NOW() WHERE tenant_id = $1 AND id = $2`, tenantID, packID)
+	if err != nil {
+		return apperrors.DatabaseError("update health check pack", err.Error())
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.NotFound("Health check pack not found", fmt.Sprintf("id: %d", packID))
+	}
+	return nil
+}
+
+func insertPackHealthCheck(ctx context.Context, tx *sql.Tx, packID int64, check *models.VersionHealthCheck) error {
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO postgres_health_checks (
+			pack_id, check_name, min_version, max_version, check_query, expected_result,
+			severity, description, remediation, category, assertion_type, assertion_value
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at, updated_at
+	`, append([]interface{}{packID}, packHealthCheckValues(check)...)...,
+	).Scan(&check.ID, &check.CreatedAt, &check.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return apperrors.Conflict("Health check already exists in pack", check.CheckName)
+		}
+		return apperrors.DatabaseError("create pack health check", err.Error())
+	}
+	check.PackID = &packID
+	return nil
+}
+
+// packHealthCheckValues are the values of a check in the column order used
+// by the pack check statements, from check_name to assertion_value
+func packHealthCheckValues(check *models.VersionHealthCheck) []interface{} {
+	maxVersion := sql.NullInt64{Int64: int64(check.MaxVersion), Valid: check.MaxVersion != 0}
+	return []interface{}{
+		check.CheckName, check.MinVersion, maxVersion, check.CheckQuery, nullString(check.ExpectedResult),
+		check.Severity, nullString(check.Description), nullString(check.Remediation), check.Category,
+		check.AssertionType, nullString(check.AssertionValue),
+	}
+}
+
+// ============================================================================
+// COLLECTOR GROUP OPERATIONS
+// ============================================================================
+
+// ListCollectorGroups returns a tenant's collector groups with their members
+func (p *PostgresDB) ListCollectorGroups(ctx context.Context, tenantID uuid.UUID) ([]*models.CollectorGroup, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT g.id, g.tenant_id, g.name, COALESCE(g.description, ''), g.created_at,
+			   COALESCE(ARRAY_AGG(m.collector_id::text ORDER BY m.collector_id) FILTER (WHERE m.collector_id IS NOT NULL), '{}')
+		FROM collector_groups g
+		LEFT JOIN collector_group_members m ON m.group_id = g.id
+		WHERE g.tenant_id = $1
+		GROUP BY g.id
+		ORDER BY g.name
+	`, tenantID)
+	if err != nil {
+		return nil, apperrors.DatabaseError("list collector groups", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	groups := []*models.CollectorGroup{}
+	for rows.Next() {
+		group := &models.CollectorGroup{}
+		var members []string
+		if err := rows.Scan(
+			&group.ID, &group.TenantID, &group.Name, &group.Description, &group.CreatedAt,
+			pq.Array(&members),
+		); err != nil {
+			return nil, apperrors.DatabaseError("scan collector group", err.Error())
+		}
+		group.CollectorIDs = make([]uuid.UUID, 0, len(members))
+		for _, m := range members {
+			id, err := uuid.Parse(m)
+			if err != nil {
+				return nil, apperrors.DatabaseError("scan collector group", err.Error())
+			}
+			group.CollectorIDs = append(group.CollectorIDs, id)
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("list collector groups", err.Error())
+	}
+
+	return groups, nil
+}
+
+// CreateCollectorGroup stores a collector group with its members and sets
+// its ID
+func (p *PostgresDB) CreateCollectorGroup(ctx context.Context, group *models.CollectorGroup) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO collector_groups (tenant_id, name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, group.TenantID, group.Name, nullString(group.Description),
+	).Scan(&group.ID, &group.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return apperrors.Conflict("Collector group already exists", group.Name)
+		}
+		return apperrors.DatabaseError("create collector group", err.Error())
+	}
+
+	if err := insertCollectorGroupMembers(ctx, tx, group.TenantID, group.ID, group.CollectorIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit collector group", err.Error())
+	}
+
+	return nil
+}
+
+// SetCollectorGroupMembers replaces the collectors of a tenant's group
+func (p *PostgresDB) SetCollectorGroupMembers(ctx context.Context, tenantID uuid.UUID, groupID int64, collectorIDs []uuid.UUID) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM collector_groups WHERE tenant_id = $1 AND id = $2)`, tenantID, groupID,
+	).Scan(&exists)
+	if err != nil {
+		return apperrors.DatabaseError("find collector group", err.Error())
+	}
+	if !exists {
+		return apperrors.NotFound("Collector group not found", fmt.Sprintf("id: %d", groupID))
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM collector_group_members WHERE group_id = $1`, groupID); err != nil {
+		return apperrors.DatabaseError("clear collector group members", err.Error())
+	}
+	if err := insertCollectorGroupMembers(ctx, tx, tenantID, groupID, collectorIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit collector group members", err.Error())
+	}
+
+	return nil
+}
+
+// insertCollectorGroupMembers adds collectors to a group. Every collector
+// must belong to the group's tenant.
+func insertCollectorGroupMembers(ctx context.Context, tx *sql.Tx, tenantID uuid.UUID, groupID int64, collectorIDs []uuid.UUID) error {
+	if len(collectorIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(collectorIDs))
+	for i, id := range collectorIDs {
+		ids[i] = id.String()
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO collector_group_members (group_id, collector_id)
+		SELECT $1, c.id FROM collectors c
+		WHERE c.tenant_id = $2 AND c.id = ANY($3::uuid[])
+		ON CONFLICT DO NOTHING
+	`, groupID, tenantID, pq.Array(ids))
+	if err != nil {
+		return apperrors.DatabaseError("add collector group members", err.Error())
+	}
+
+	unique := make(map[uuid.UUID]bool, len(collectorIDs))
+	for _, id := range collectorIDs {
+		unique[id] = true
+	}
+	if n, _ := result.RowsAffected(); int(n) != len(unique) {
+		return apperrors.BadRequest("Unknown collector", "every collector in a group must belong to the tenant")
+	}
+
+	return nil
+}
+
+// DeleteCollectorGroup removes a tenant's collector group and its schedules
+func (p *PostgresDB) DeleteCollectorGroup(ctx context.Context, tenantID uuid.UUID, id int64) error {
+	result, err := p.db.ExecContext(ctx,
+		`DELETE FROM collector_groups WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return apperrors.DatabaseError("delete collector group", err.Error())
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.NotFound("Collector group not found", "")
+	}
+
+	return nil
+}
+
+// ============================================================================
+// HEALTH CHECK SCHEDULE OPERATIONS
+// ============================================================================
+
+// ListHealthCheckSchedules returns the schedules of a tenant's pack
+func (p *PostgresDB) ListHealthCheckSchedules(ctx context.Context, tenantID uuid.UUID, packID int64) ([]*models.HealthCheckSchedule, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT s.id, s.pack_id, s.group_id, g.name, s.interval_seconds, s.enabled, s.created_at
+		FROM health_check_schedules s
+		JOIN health_check_packs p ON p.id = s.pack_id
+		JOIN collector_groups g ON g.id = s.group_id
+		WHERE p.tenant_id = $1 AND s.pack_id = $2
+		ORDER BY g.name
+	`, tenantID, packID)
+	if err != nil {
+		return nil, apperrors.DatabaseError("list health check schedules", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	schedules := []*models.HealthCheckSchedule{}
+	for rows.Next() {
+		s := &models.HealthCheckSchedule{}
+		if err := rows.Scan(
+			&s.ID, &s.PackID, &s.GroupID, &s.GroupName, &s.IntervalSeconds, &s.Enabled, &s.CreatedAt,
+		); err != nil {
+			return nil, apperrors.DatabaseError("scan health check schedule", err.Error())
+		}
+		schedules = append(schedules, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("list health check schedules", err.Error())
+	}
+
+	return schedules, nil
+}
+
+// CreateHealthCheckSchedule schedules a tenant's pack on one of its
+// collector groups and sets the schedule's ID
+func (p *PostgresDB) CreateHealthCheckSchedule(ctx context.Context, tenantID uuid.UUID, schedule *models.HealthCheckSchedule) error {
+	err := p.db.QueryRowContext(ctx, `
+		INSERT INTO health_check_schedules (pack_id, group_id, interval_seconds, enabled)
+		SELECT p.id, g.id, $4, $5
+		FROM health_check_packs p, collector_groups g
+		WHERE p.tenant_id = $1 AND p.id = $2 AND g.tenant_id = $1 AND g.id = $3
+		RETURNING id, created_at, (SELECT name FROM collector_groups WHERE id = $3)
+	`, tenantID, schedule.PackID, schedule.GroupID, schedule.IntervalSeconds, schedule.Enabled,
+	).Scan(&schedule.ID, &schedule.CreatedAt, &schedule.GroupName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return apperrors.NotFound("Health check pack or collector group not found", "")
+		}
+		if isUniqueViolation(err) {
+			return apperrors.Conflict("Pack is already scheduled on this group", "")
+		}
+		return apperrors.DatabaseError("create health check schedule", err.Error())
+	}
+
+	return nil
+}
+
+// DeleteHealthCheckSchedule removes a schedule of a tenant's pack
+func (p *PostgresDB) DeleteHealthCheckSchedule(ctx context.Context, tenantID uuid.UUID, packID, id int64) error {
+	result, err := p.db.ExecContext(ctx, `
+		DELETE FROM health_check_schedules s
+		USING health_check_packs p
+		WHERE p.id = s.pack_id AND p.tenant_id = $1 AND s.pack_id = $2 AND s.id = $3
+	`, tenantID, packID, id)
+	if err != nil {
+		return apperrors.DatabaseError("delete health check schedule", err.Error())
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.NotFound("Health check schedule not found", "")
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ClusterEventRepository stores node states and the cluster events found
+// between them. It only needs a *sql.DB so that the alert rule engine can
+// use it; the replication state of a tenant comes from the embedded
+// ReplicationTopologyRepository.
+type ClusterEventRepository struct {
+	*ReplicationTopologyRepository
+	db *sql.DB
+}
+
+// NewClusterEventRepository creates a new ClusterEventRepository
+func NewClusterEventRepository(db *sql.DB) *ClusterEventRepository {
+	return &ClusterEventRepository{
+		ReplicationTopologyRepository: NewReplicationTopologyRepository(db),
+		db:                            db,
+	}
+}
+
+const nodeStateColumns = `n.time, n.collector_id, COALESCE(n.system_identifier, ''), n.timeline_id, n.in_recovery,
+	COALESCE(n.server_version_num, 0), COALESCE(n.upstream_host, ''), COALESCE(n.upstream_port, 0)`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanNodeState(row rowScanner) (*models.NodeState, error) {
+	s := &models.NodeState{}
+	if err := row.Scan(&s.Time, &s.CollectorID, &s.SystemIdentifier, &s.TimelineID, &s.InRecovery,
+		&s.ServerVersionNum, &s.UpstreamHost, &s.UpstreamPort); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// StoreNodeState stores the node state of a pg_replication sample
+func (r *ClusterEventRepository) StoreNodeState(ctx context.Context, state *models.NodeState) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO metrics_node_state (time, collector_id, system_identifier, timeline_id, in_recovery, server_version_num, upstream_host, upstream_port)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, state.Time, state.CollectorID, nullString(state.SystemIdentifier), state.TimelineID, state.InRecovery,
+		state.ServerVersionNum, nullString(state.UpstreamHost), state.UpstreamPort)
+	if err != nil {
+		return apperrors.DatabaseError("insert node state", err.Error())
+	}
+	return nil
+}
+
+// GetPreviousNodeState returns the latest node state of a collector before
+// before, or nil when there is none
+func (r *ClusterEventRepository) GetPreviousNodeState(ctx context.Context, collectorID uuid.UUID, before time.Time) (*models.NodeState, error) {
+	state, err := scanNodeState(r.db.QueryRowContext(ctx, `
+		SELECT `+nodeStateColumns+`
+		FROM metrics_node_state n
+		WHERE n.collector_id = $1 AND n.time < $2
+		ORDER BY n.time DESC
+		LIMIT 1
+	`, collectorID, before))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, apperrors.DatabaseError("get node state", err.Error())
+	}
+	return state, nil
+}
+
+// GetClusterNodeStates returns the latest state between from and to of each
+// collector reporting a system identifier
+func (r *ClusterEventRepository) GetClusterNodeStates(ctx context.Context, systemIdentifier string, from, to time.Time) ([]*models.NodeState, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (n.collector_id) `+nodeStateColumns+`
+		FROM metrics_node_state n
+		WHERE n.system_identifier = $1 AND n.time >= $2 AND n.time <= $3
+		ORDER BY n.collector_id, n.time DESC
+	`, systemIdentifier, from, to)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query cluster node states", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var states []*models.NodeState
+	for rows.Next() {
+		state, err := scanNodeState(rows)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan node state", err.Error())
+		}
+		states = append(states, state)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query cluster node states", err.Error())
+	}
+	return states, nil
+}
+
+// GetLatestWalReceiver returns the latest WAL receiver of a collector between
+// from and to, or nil when it reported none
+func (r *ClusterEventRepository) GetLatestWalReceiver(ctx context.Context, collectorID uuid.UUID, from, to time.Time) (*models.WalReceiver, error) {
+	w := &models.WalReceiver{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT collector_id, time, COALESCE(status, ''), COALESCE(sender_host, ''), COALESCE(sender_port, 0)
+		FROM metrics_wal_receivers
+		WHERE collector_id = $1 AND time >= $2 AND time <= $3
+		ORDER BY time DESC
+		LIMIT 1
+	`, collectorID, from, to).Scan(&w.CollectorID, &w.Time, &w.Status, &w.SenderHost, &w.SenderPort)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, apperrors.DatabaseError("get wal receiver", err.Error())
+	}
+	return w, nil
+}
+
+// GetCollectorTenant returns the tenant of a collector, nil when it has none
+func (r *ClusterEventRepository) GetCollectorTenant(ctx context.Context, collectorID uuid.UUID) (*uuid.UUID, error) {
+	var tenantID uuid.NullUUID
+	err := r.db.QueryRowContext(ctx, `SELECT tenant_id FROM collectors WHERE id = $1`, collectorID).Scan(&tenantID)
+	if err == sql.ErrNoRows || (err == nil && !tenantID.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperrors.DatabaseError("query collector tenant", err.Error())
+	}
+	return &tenantID.UUID, nil
+}
+
+// GetCollectorInstanceIDs returns the IDs of the PostgreSQL instances
+// monitored by a collector
+func (r *ClusterEventRepository) GetCollectorInstanceIDs(ctx context.Context, collectorID uuid.UUID) ([]int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT i.id
+		FROM pganalytics.postgresql_instances i
+		JOIN pganalytics.servers s ON s.id = i.server_id
+		WHERE s.collector_id = $1
+		ORDER BY i.id
+	`, collectorID)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query collector instances", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var instanceIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, apperrors.DatabaseError("scan collector instance", err.Error())
+		}
+		instanceIDs = append(instanceIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query collector instances", err.Error())
+	}
+	return instanceIDs, nil
+}
+
+// StoreClusterEvent stores an event and sets its ID and creation time
+func (r *ClusterEventRepository) StoreClusterEvent(ctx context.Context, event *models.ClusterEvent) error {
+	before, err := json.Marshal(event.Before)
+	if err != nil {
+		return apperrors.DatabaseError("marshal cluster event", err.Error())
+	}
+	after, err := json.Marshal(event.After)
+	if err != nil {
+		return apperrors.DatabaseError("marshal cluster event", err.Error())
+	}
+	beforeTopology, err := nullJSON(event.BeforeTopology)
+	if err != nil {
+		return apperrors.DatabaseError("marshal cluster event", err.Error())
+	}
+	afterTopology, err := nullJSON(event.AfterTopology)
+	if err != nil {
+		return apperrors.DatabaseError("marshal cluster event", err.Error())
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO cluster_events (tenant_id, cluster_id, collector_id, event_type, occurred_at, previous_sample_at,
+			before_state, after_state, before_topology, after_topology, previous_primary_id, summary)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, created_at
+	`, event.TenantID, event.ClusterID, event.CollectorID, event.EventType, event.OccurredAt, event.PreviousSampleAt,
+		before, after, beforeTopology, afterTopology, event.PreviousPrimary, event.Summary,
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return apperrors.DatabaseError("insert cluster event", err.Error())
+	}
+	return nil
+}
+
+// ListClusterEvents returns the events matching a filter, most recent first
+func (r *ClusterEventRepository) ListClusterEvents(ctx context.Context, filter *models.ClusterEventFilter) ([]*models.ClusterEvent, error) {
+	conditions := []string{"occurred_at >= $1", "occurred_at <= $2"}
+	args := []interface{}{filter.From, filter.To}
+	if filter.TenantID != nil {
+		args = append(args, *filter.TenantID)
+		conditions = append(conditions, fmt.Sprintf("tenant_id = $%d", len(args)))
+	}
+	if filter.ClusterID != "" {
+		args = append(args, filter.ClusterID)
+		conditions = append(conditions, fmt.Sprintf("cluster_id = $%d", len(args)))
+	}
+	if len(filter.EventTypes) > 0 {
+		args = append(args, pq.Array(filter.EventTypes))
+		conditions = append(conditions, fmt.Sprintf("event_type = ANY($%d)", len(args)))
+	}
+	args = append(args, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, tenant_id, cluster_id, collector_id, event_type, occurred_at, previous_sample_at,
+			before_state, after_state, before_topology, after_topology, previous_primary_id, summary, created_at
+		FROM cluster_events
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $`+fmt.Sprint(len(args)), args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query cluster events", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	events := []*models.ClusterEvent{}
+	for rows.Next() {
+		e := &models.ClusterEvent{}
+		var tenantID, previousPrimary uuid.NullUUID
+		var before, after, beforeTopology, afterTopology []byte
+		if err := rows.Scan(&e.ID, &tenantID, &e.ClusterID, &e.CollectorID, &e.EventType, &e.OccurredAt, &e.PreviousSampleAt,
+			&before, &after, &beforeTopology, &afterTopology, &previousPrimary, &e.Summary, &e.CreatedAt); err != nil {
+			return nil, apperrors.DatabaseError("scan cluster event", err.Error())
+		}
+		if tenantID.Valid {
+			e.TenantID = &tenantID.UUID
+		}
+		if previousPrimary.Valid {
+			e.PreviousPrimary = &previousPrimary.UUID
+		}
+		for _, field := range []struct {
+			raw    []byte
+			target interface{}
+		}{{before, &e.Before}, {after, &e.After}, {beforeTopology, &e.BeforeTopology}, {afterTopology, &e.AfterTopology}} {
+			if len(field.raw) == 0 {
+				continue
+			}
+			if err := json.Unmarshal(field.raw, field.target); err != nil {
+				return nil, apperrors.DatabaseError("unmarshal cluster event", err.Error())
+			}
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query cluster events", err.Error())
+	}
+	return events, nil
+}
+
+// nullJSON marshals a value, storing nil as NULL
+func nullJSON(v *models.FleetTopology) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,336 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// DATABASE HEALTH SCORE OPERATIONS
+// ============================================================================
+
+// databaseHealthTimeFilters are the history ranges, as in GetHealthScoreHistory
+var databaseHealthTimeFilters = map[string]string{
+	"1h":  "AND time > NOW() - INTERVAL '1 hour'",
+	"24h": "AND time > NOW() - INTERVAL '24 hours'",
+	"7d":  "AND time > NOW() - INTERVAL '7 days'",
+	"30d": "AND time > NOW() - INTERVAL '30 days'",
+}
+
+// GetDatabaseHealthInputs collects the signals of a collector's database
+// health score that come straight from stored metrics, as of at. Wraparound,
+// health check and log anomaly inputs are left to their own services.
+func (p *PostgresDB) GetDatabaseHealthInputs(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.DatabaseHealthInputs, error) {
+	inputs := &models.DatabaseHealthInputs{}
+
+	// pg_stat_statements counters are cumulative, so the ratio is taken over
+	// the latest sample of each statement
+	var cacheHit sql.NullFloat64
+	err := p.db.QueryRowContext(ctx, `
+		WITH latest AS (
+			SELECT DISTINCT ON (database_name, user_name, query_hash)
+				shared_blks_hit, shared_blks_read
+			FROM metrics_pg_stats_query
+			WHERE collector_id = $1 AND time > $2::timestamptz - INTERVAL '24 hours' AND time <= $2
+			ORDER BY database_name, user_name, query_hash, time DESC
+		)
+		SELECT SUM(shared_blks_hit)::float8 / NULLIF(SUM(shared_blks_hit + shared_blks_read), 0)
+		FROM latest
+	`, collectorID, at).Scan(&cacheHit)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query cache hit ratio", err.Error())
+	}
+	if cacheHit.Valid {
+		inputs.CacheHitRatio = &cacheHit.Float64
+	}
+
+	var lag sql.NullInt64
+	err = p.db.QueryRowContext(ctx, `
+		SELECT MAX(replay_lag_ms)
+		FROM metrics_replication_status
+		WHERE collector_id = $1 AND time = (
+			SELECT MAX(time) FROM metrics_replication_status
+			WHERE collector_id = $1 AND time > $2::timestamptz - INTERVAL '1 hour' AND time <= $2
+		)
+	`, collectorID, at).Scan(&lag)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query replication lag", err.Error())
+	}
+	if lag.Valid {
+		inputs.ReplicationLagMs = &lag.Int64
+	}
+
+	var sampledAt sql.NullTime
+	var connections int
+	err = p.db.QueryRowContext(ctx, `
+		WITH sample AS (
+			SELECT MAX(time) AS time FROM metrics_pg_activity_samples
+			WHERE collector_id = $1 AND time > $2::timestamptz - INTERVAL '1 hour' AND time <= $2
+		)
+		SELECT sample.time, COUNT(DISTINCT a.pid)
+		FROM sample
+		LEFT JOIN metrics_pg_activity_samples a
+			ON a.collector_id = $1 AND a.time = sample.time
+			AND COALESCE(a.backend_type, 'client backend') = 'client backend'
+		GROUP BY sample.time
+	`, collectorID, at).Scan(&sampledAt, &connections)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query connections", err.Error())
+	}
+	if sampledAt.Valid {
+		inputs.Connections = &connections
+	}
+
+	var maxConnections sql.NullInt64
+	err = p.db.QueryRowContext(ctx, `
+		SELECT COALESCE(
+			(SELECT setting::int FROM pg_settings_current
+			 WHERE collector_id = $1 AND name = 'max_connections'),
+			(SELECT NULLIF(postgres_max_connections, 0) FROM metrics_host_inventory
+			 WHERE collector_id = $1 ORDER BY time DESC LIMIT 1)
+		)
+	`, collectorID).Scan(&maxConnections)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query max_connections", err.Error())
+	}
+	if maxConnections.Valid {
+		value := int(maxConnections.Int64)
+		inputs.MaxConnections = &value
+	}
+
+	var deadRatio sql.NullFloat64
+	err = p.db.QueryRowContext(ctx, `
+		WITH latest AS (
+			SELECT database_name, MAX(time) AS time
+			FROM metrics_pg_vacuum_tables
+			WHERE collector_id = $1 AND time > $2::timestamptz - INTERVAL '24 hours' AND time <= $2
+			GROUP BY database_name
+		)
+		SELECT SUM(t.dead_tuples)::float8 / NULLIF(SUM(t.live_tuples + t.dead_tuples), 0)
+		FROM metrics_pg_vacuum_tables t
+		JOIN latest l ON l.database_name = t.database_name AND l.time = t.time
+		WHERE t.collector_id = $1
+	`, collectorID, at).Scan(&deadRatio)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query dead tuple ratio", err.Error())
+	}
+	if deadRatio.Valid {
+		inputs.DeadTupleRatio = &deadRatio.Float64
+	}
+
+	var total, errorCount int64
+	err = p.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE UPPER(log_level) IN ('ERROR', 'FATAL', 'PANIC'))
+		FROM pganalytics.postgresql_logs
+		WHERE collector_id = $1 AND log_timestamp > $2::timestamptz - INTERVAL '1 hour' AND log_timestamp <= $2
+	`, collectorID, at).Scan(&total, &errorCount)
+	if err != nil {
+		return nil, apperrors.DatabaseError("count error logs", err.Error())
+	}
+	if total > 0 {
+		rate := float64(errorCount) / 60
+		inputs.ErrorsPerMinute = &rate
+	}
+
+	return inputs, nil
+}
+
+// GetDatabaseHealthWeights returns a tenant's weights, nil when it has none
+func (p *PostgresDB) GetDatabaseHealthWeights(ctx context.Context, tenantID uuid.UUID) (*models.DatabaseHealthWeights, error) {
+	return p.queryDatabaseHealthWeights(ctx, `WHERE tenant_id = $1`, tenantID)
+}
+
+// GetCollectorDatabaseHealthWeights returns the weights of a collector's
+// tenant, nil when the collector has no tenant or the tenant has no weights
+func (p *PostgresDB) GetCollectorDatabaseHealthWeights(ctx context.Context, collectorID uuid.UUID) (*models.DatabaseHealthWeights, error) {
+	return p.queryDatabaseHealthWeights(ctx,
+		`WHERE tenant_id = (SELECT tenant_id FROM collectors WHERE id = $1)`, collectorID)
+}
+
+func (p *PostgresDB) queryDatabaseHealthWeights(ctx context.Context, where string, arg interface{}) (*models.DatabaseHealthWeights, error) {
+	w := &models.DatabaseHealthWeights{}
+	err := p.db.QueryRowContext(ctx, `
+		SELECT cache_hit, replication_lag, connections, bloat,
+			wraparound, health_checks, anomalies, error_logs
+		FROM database_health_weights
+		`+where, arg).Scan(
+		&w.CacheHit, &w.ReplicationLag, &w.Connections, &w.Bloat,
+		&w.Wraparound, &w.HealthChecks, &w.Anomalies, &w.ErrorLogs,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperrors.DatabaseError("query database health weights", err.Error())
+	}
+	return w, nil
+}
+
+// SetDatabaseHealthWeights creates or replaces a tenant's weights
+func (p *PostgresDB) SetDatabaseHealthWeights(ctx context.Context, tenantID uuid.UUID, w *models.DatabaseHealthWeights) error {
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO database_health_weights (
+			tenant_id, cache_hit, replication_lag, connections, bloat,
+			wraparound, health_checks, anomalies, error_logs, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		ON CONFLICT (tenant_id) DO UPDATE SET
+			cache_hit = EXCLUDED.cache_hit,
+			replication_lag = EXCLUDED.replication_lag,
+			connections = EXCLUDED.connections,
+			bloat = EXCLUDED.bloat,
+			wraparound = EXCLUDED.wraparound,
+			health_checks = EXCLUDED.health_checks,
+			anomalies = EXCLUDED.anomalies,
+			error_logs = EXCLUDED.error_logs,
+			updated_at = NOW()
+	`, tenantID, w.CacheHit, w.ReplicationLag, w.Connections, w.Bloat,
+		w.Wraparound, w.HealthChecks, w.Anomalies, w.ErrorLogs)
+	if err != nil {
+		return apperrors.DatabaseError("store database health weights", err.Error())
+	}
+	return nil
+}
+
+// StoreDatabaseHealthScore inserts a database health score
+func (p *PostgresDB) StoreDatabaseHealthScore(ctx context.Context, score *models.DatabaseHealthScore) error {
+	if score == nil {
+		return nil
+	}
+
+	components, err := json.Marshal(score.Components)
+	if err != nil {
+		return apperrors.InternalServerError("Failed to encode health components", err.Error())
+	}
+	weights, err := json.Marshal(score.Weights)
+	if err != nil {
+		return apperrors.InternalServerError("Failed to encode health weights", err.Error())
+	}
+
+	_, err = p.db.ExecContext(ctx, `
+		INSERT INTO metrics_database_health_scores (
+			time, collector_id, health_score, status, components, weights
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (time, collector_id) DO UPDATE SET
+			health_score = EXCLUDED.health_score,
+			status = EXCLUDED.status,
+			components = EXCLUDED.components,
+			weights = EXCLUDED.weights
+	`, score.Time, score.CollectorID, score.HealthScore, score.Status, components, weights)
+	if err != nil {
+		return apperrors.DatabaseError("insert database health score", err.Error())
+	}
+	return nil
+}
+
+const databaseHealthScoreColumns = `time, collector_id, health_score, status, components, weights`
+
+func scanDatabaseHealthScore(row rowScanner) (*models.DatabaseHealthScore, error) {
+	score := &models.DatabaseHealthScore{}
+	var components, weights []byte
+	if err := row.Scan(&score.Time, &score.CollectorID, &score.HealthScore, &score.Status, &components, &weights); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(components, &score.Components); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(weights, &score.Weights); err != nil {
+		return nil, err
+	}
+	return score, nil
+}
+
+// GetLatestDatabaseHealthScore retrieves the latest database health score
+// for a collector
+func (p *PostgresDB) GetLatestDatabaseHealthScore(ctx context.Context, collectorID uuid.UUID) (*models.DatabaseHealthScore, error) {
+	row := p.db.QueryRowContext(ctx, `
+		SELECT `+databaseHealthScoreColumns+`
+		FROM metrics_database_health_scores
+		WHERE collector_id = $1
+		ORDER BY time DESC
+		LIMIT 1
+	`, collectorID)
+
+	score, err := scanDatabaseHealthScore(row)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.NotFound("No database health score for collector", collectorID.String())
+	}
+	if err != nil {
+		return nil, apperrors.DatabaseError("query latest database health score", err.Error())
+	}
+	return score, nil
+}
+
+// GetDatabaseHealthScoreHistory retrieves database health score history for
+// a collector over 1h, 24h (the default), 7d or 30d
+func (p *PostgresDB) GetDatabaseHealthScoreHistory(ctx context.Context, collectorID uuid.UUID, timeRange string, limit, offset int) ([]*models.DatabaseHealthScore, error) {
+	timeFilter, ok := databaseHealthTimeFilters[timeRange]
+	if !ok {
+		timeFilter = databaseHealthTimeFilters["24h"]
+	}
+
+	rows, err := p.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM metrics_database_health_scores
+		WHERE collector_id = $1 %s
+		ORDER BY time DESC
+		LIMIT $2 OFFSET $3
+	`, databaseHealthScoreColumns, timeFilter), collectorID, limit, offset)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query database health score history", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	scores := []*models.DatabaseHealthScore{}
+	for rows.Next() {
+		score, err := scanDatabaseHealthScore(rows)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan database health score", err.Error())
+		}
+		scores = append(scores, score)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("iterate database health scores", err.Error())
+	}
+	return scores, nil
+}
+
+// GetLatestDatabaseHealthScores returns the latest score since since of each
+// of the collectors, keyed by collector
+func (p *PostgresDB) GetLatestDatabaseHealthScores(ctx context.Context, collectorIDs []uuid.UUID, since time.Time) (map[uuid.UUID]*models.DatabaseHealthScore, error) {
+	ids := make([]string, 0, len(collectorIDs))
+	for _, id := range collectorIDs {
+		ids = append(ids, id.String())
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (collector_id) `+databaseHealthScoreColumns+`
+		FROM metrics_database_health_scores
+		WHERE collector_id = ANY($1::uuid[]) AND time > $2
+		ORDER BY collector_id, time DESC
+	`, pq.Array(ids), since)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query latest database health scores", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	scores := map[uuid.UUID]*models.DatabaseHealthScore{}
+	for rows.Next() {
+		score, err := scanDatabaseHealthScore(rows)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan database health score", err.Error())
+		}
+		scores[score.CollectorID] = score
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("iterate database health scores", err.Error())
+	}
+	return scores, nil
+}
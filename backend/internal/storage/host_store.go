@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -310,6 +311,9 @@ func (p *PostgresDB) GetHostInventory(ctx context.Context, collectorID uuid.UUID
 		&inv.PostgresMaxConnections, &inv.PostgresSharedBuffersMb, &inv.PostgresWorkMemMb,
 	)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NotFound("Host inventory not found", fmt.Sprintf("collector: %s", collectorID))
+		}
 		return nil, apperrors.DatabaseError("query host inventory", err.Error())
 	}
 
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// LockSnapshotRepository reads the lock snapshots stored by StoreLockMetrics
+// It only needs a *sql.DB so that background jobs and the MCP server can use it.
+type LockSnapshotRepository struct {
+	db *sql.DB
+}
+
+// NewLockSnapshotRepository creates a new LockSnapshotRepository
+func NewLockSnapshotRepository(db *sql.DB) *LockSnapshotRepository {
+	return &LockSnapshotRepository{db: db}
+}
+
+// GetLockSnapshotTime returns the time of the latest lock snapshot at or before at
+func (r *LockSnapshotRepository) GetLockSnapshotTime(ctx context.Context, collectorID uuid.UUID, at time.Time) (*time.Time, error) {
+	var snapshot sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT MAX(time) FROM metrics_pg_locks
+		WHERE collector_id = $1 AND time <= $2 AND time > $2 - INTERVAL '1 day'
+	`, collectorID, at).Scan(&snapshot)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get lock snapshot time", err.Error())
+	}
+	if !snapshot.Valid {
+		return nil, nil
+	}
+	return &snapshot.Time, nil
+}
+
+// GetLockSnapshot returns the locks and lock waits collected at snapshotTime
+func (r *LockSnapshotRepository) GetLockSnapshot(ctx context.Context, collectorID uuid.UUID, snapshotTime time.Time, database *string) ([]*models.Lock, []*models.LockWait, error) {
+	args := []interface{}{collectorID, snapshotTime}
+	dbFilter := ""
+	if database != nil {
+		dbFilter = ` AND database_name = $3`
+		args = append(args, *database)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT database_name, pid, locktype, mode, granted, relation_id, page_number, tuple_id,
+		       username, session_state, lock_age_seconds, query
+		FROM metrics_pg_locks
+		WHERE collector_id = $1 AND time = $2`+dbFilter, args...)
+	if err != nil {
+		return nil, nil, apperrors.DatabaseError("query lock snapshot", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var locks []*models.Lock
+	for rows.Next() {
+		l := &models.Lock{CollectorID: collectorID, Timestamp: snapshotTime}
+		if err := rows.Scan(&l.DatabaseName, &l.PID, &l.LockType, &l.Mode, &l.Granted, &l.RelationID, &l.PageNumber, &l.TupleID,
+			&l.Username, &l.SessionState, &l.LockAgeSeconds, &l.Query); err != nil {
+			return nil, nil, apperrors.DatabaseError("scan lock", err.Error())
+		}
+		locks = append(locks, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, apperrors.DatabaseError("iterate locks", err.Error())
+	}
+
+	waitRows, err := r.db.QueryContext(ctx, `
+		SELECT database_name, blocked_pid, blocking_pid, COALESCE(blocked_username, ''), COALESCE(blocking_username, ''),
+		       COALESCE(blocked_query, ''), COALESCE(blocking_query, ''), wait_time_seconds,
+		       COALESCE(blocked_application, ''), COALESCE(blocking_application, '')
+		FROM metrics_pg_lock_waits
+		WHERE collector_id = $1 AND time = $2`+dbFilter, args...)
+	if err != nil {
+		return nil, nil, apperrors.DatabaseError("query lock wait snapshot", err.Error())
+	}
+	defer func() { _ = waitRows.Close() }()
+
+	var waits []*models.LockWait
+	for waitRows.Next() {
+		w := &models.LockWait{CollectorID: collectorID, Timestamp: snapshotTime}
+		if err := waitRows.Scan(&w.DatabaseName, &w.BlockedPID, &w.BlockingPID, &w.BlockedUsername, &w.BlockingUsername,
+			&w.BlockedQuery, &w.BlockingQuery, &w.WaitTimeSeconds, &w.BlockedApplication, &w.BlockingApplication); err != nil {
+			return nil, nil, apperrors.DatabaseError("scan lock wait", err.Error())
+		}
+		waits = append(waits, w)
+	}
+
+	return locks, waits, waitRows.Err()
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// LogAlertRepository counts the stored PostgreSQL logs matching log alert
+// conditions. It only needs a *sql.DB so that the alert rule engine can use it.
+type LogAlertRepository struct {
+	db *sql.DB
+}
+
+// NewLogAlertRepository creates a new LogAlertRepository
+func NewLogAlertRepository(db *sql.DB) *LogAlertRepository {
+	return &LogAlertRepository{db: db}
+}
+
+// logAlertGroupColumns maps a group_by value to the column counted per group
+var logAlertGroupColumns = map[string]string{
+	models.LogAlertGroupByDatabase: "database_name",
+	models.LogAlertGroupByUser:     "user_name",
+}
+
+// CountMatchingLogs counts the logs since a time that match a condition,
+// per group when the condition groups, largest count first. levels are the
+// log levels matching the condition's minimum severity. Only logs of the
+// rule's tenant are counted, or of the owner's tenants for rules created
+// before tenancy, whatever collector the condition names.
+//
+// The hourly rollups behind GetLogStatisticsHourly are not used: they hold
+// counts per level only, so they can neither filter by category, SQLSTATE
+// or pattern nor count windows shorter than an hour.
+func (r *LogAlertRepository) CountMatchingLogs(ctx context.Context, cond *models.LogAlertCondition, levels []string, since time.Time, tenantID *uuid.UUID, userID int) ([]*models.LogMatchCount, error) {
+	where := []string{"log_timestamp > $1"}
+	args := []interface{}{since}
+	add := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+
+	if tenantID != nil {
+		add("collector_id IN (SELECT id FROM collectors WHERE tenant_id = $%d)", *tenantID)
+	} else {
+		add(`collector_id IN (
+			SELECT id FROM collectors
+			WHERE tenant_id IN (SELECT tenant_id FROM tenant_users WHERE user_id = $%d)
+		)`, userID)
+	}
+
+	if cond.CollectorID != nil {
+		add("collector_id = $%d", *cond.CollectorID)
+	}
+	if cond.InstanceID != nil {
+		add("instance_id = $%d", *cond.InstanceID)
+	}
+	if cond.Category != "" {
+		add("category = $%d", cond.Category)
+	}
+	if len(levels) > 0 {
+		add("UPPER(log_level) = ANY($%d)", pq.Array(levels))
+	}
+	if len(cond.SQLState) == 5 {
+		add("error_code = $%d", cond.SQLState)
+	} else if cond.SQLState != "" {
+		add("LEFT(error_code, 2) = $%d", cond.SQLState)
+	}
+	if cond.Pattern != "" {
+		add("log_message ~ $%d", cond.Pattern)
+	}
+
+	group := "''"
+	if column, ok := logAlertGroupColumns[cond.GroupBy]; ok {
+		group = "COALESCE(" + column + ", '')"
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+group+` AS grp, COUNT(*), MAX(log_timestamp)
+		FROM pganalytics.postgresql_logs
+		WHERE `+strings.Join(where, " AND ")+`
+		GROUP BY grp
+		ORDER BY COUNT(*) DESC, grp
+		LIMIT 100
+	`, args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("count matching logs", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var counts []*models.LogMatchCount
+	for rows.Next() {
+		count := &models.LogMatchCount{}
+		if err := rows.Scan(&count.Group, &count.Count, &count.LastSeen); err != nil {
+			return nil, apperrors.DatabaseError("scan log match count", err.Error())
+		}
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("count matching logs", err.Error())
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+func TestCountMatchingLogs_TenantScope(t *testing.T) {
+	since := time.Now().Add(-time.Hour)
+	collectorID := uuid.New()
+	cond := &models.LogAlertCondition{CollectorID: &collectorID, Pattern: "deadlock"}
+	countColumns := []string{"grp", "count", "max"}
+
+	t.Run("rule tenant", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		tenantID := uuid.New()
+
+		mock.ExpectQuery(regexp.QuoteMeta("collector_id IN (SELECT id FROM collectors WHERE tenant_id = $2)")).
+			WithArgs(since, tenantID, collectorID, "deadlock").
+			WillReturnRows(sqlmock.NewRows(countColumns).AddRow("", 3, since))
+
+		counts, err := NewLogAlertRepository(db).CountMatchingLogs(context.Background(), cond, nil, since, &tenantID, 7)
+		require.NoError(t, err)
+		require.Len(t, counts, 1)
+		assert.Equal(t, int64(3), counts[0].Count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("owner tenants for rules without a tenant", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT tenant_id FROM tenant_users WHERE user_id = $2")).
+			WithArgs(since, 7, collectorID, "deadlock").
+			WillReturnRows(sqlmock.NewRows(countColumns))
+
+		counts, err := NewLogAlertRepository(db).CountMatchingLogs(context.Background(), cond, nil, since, nil, 7)
+		require.NoError(t, err)
+		assert.Empty(t, counts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// LOG CATEGORY RULE OPERATIONS
+// ============================================================================
+
+// ListLogCategoryRules returns a tenant's log category rules, highest priority first
+func (p *PostgresDB) ListLogCategoryRules(ctx context.Context, tenantID uuid.UUID) ([]*models.LogCategoryRule, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT id, tenant_id, category, sqlstate_prefix, pattern, priority, enabled, created_at
+		FROM log_category_rules
+		WHERE tenant_id = $1
+		ORDER BY priority DESC, id
+	`, tenantID)
+	if err != nil {
+		return nil, apperrors.DatabaseError("list log category rules", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var rules []*models.LogCategoryRule
+	for rows.Next() {
+		rule := &models.LogCategoryRule{}
+		if err := rows.Scan(
+			&rule.ID, &rule.TenantID, &rule.Category, &rule.SQLStatePrefix, &rule.Pattern,
+			&rule.Priority, &rule.Enabled, &rule.CreatedAt,
+		); err != nil {
+			return nil, apperrors.DatabaseError("scan log category rule", err.Error())
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("list log category rules", err.Error())
+	}
+
+	return rules, nil
+}
+
+// CreateLogCategoryRule stores a tenant log category rule and sets its ID
+func (p *PostgresDB) CreateLogCategoryRule(ctx context.Context, rule *models.LogCategoryRule) error {
+	err := p.db.QueryRowContext(ctx, `
+		INSERT INTO log_category_rules (tenant_id, category, sqlstate_prefix, pattern, priority, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, rule.TenantID, rule.Category, rule.SQLStatePrefix, rule.Pattern, rule.Priority, rule.Enabled,
+	).Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		return apperrors.DatabaseError("create log category rule", err.Error())
+	}
+
+	return nil
+}
+
+// DeleteLogCategoryRule removes a tenant log category rule
+func (p *PostgresDB) DeleteLogCategoryRule(ctx context.Context, tenantID uuid.UUID, id int64) error {
+	result, err := p.db.ExecContext(ctx,
+		`DELETE FROM log_category_rules WHERE tenant_id = $1 AND id = $2`, tenantID, id)
+	if err != nil {
+		return apperrors.DatabaseError("delete log category rule", err.Error())
+	}
+
+	if n, _ := result.RowsAffected(); n == 0 {
+		return apperrors.NotFound("Log category rule not found", "")
+	}
+
+	return nil
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// LOG SEARCH
+// ============================================================================
+
+// logFacetLimit is the number of values returned per facet
+const logFacetLimit = 10
+
+// logSearchWhere builds the WHERE clause shared by the search and its facets.
+// condition writes the search query as a SQL condition, binding each value
+// with arg, which returns the value's placeholder.
+func logSearchWhere(params *models.LogSearchParams, condition func(arg func(interface{}) string) string, withCursor bool) (string, []interface{}) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := []string{
+		"log_timestamp >= " + arg(params.From),
+		"log_timestamp < " + arg(params.To),
+	}
+	if params.InstanceIDs != nil {
+		where = append(where, "instance_id = ANY("+arg(pq.Array(params.InstanceIDs))+")")
+	}
+	if withCursor && params.After != nil {
+		where = append(where, "(log_timestamp, id) < ("+arg(params.After.Timestamp)+", "+arg(params.After.ID)+")")
+	}
+	where = append(where, condition(arg))
+
+	return strings.Join(where, " AND "), args
+}
+
+// SearchPostgresqlLogs returns up to params.Limit logs matching a search
+// condition, newest first
+func (p *PostgresDB) SearchPostgresqlLogs(ctx context.Context, params *models.LogSearchParams, condition func(arg func(interface{}) string) string) ([]*models.PostgreSQLLog, error) {
+	where, args := logSearchWhere(params, condition, true)
+	args = append(args, params.Limit)
+
+	rows, err := p.db.QueryContext(
+		ctx,
+		`SELECT id, collector_id, instance_id, database_id, log_timestamp, log_level, log_message,
+			source_location, process_id, query_text, query_hash, error_code, error_detail,
+			error_hint, error_context, user_name, connection_from, session_id, database_name,
+			application_name, backend_type, category, created_at, updated_at
+		 FROM pganalytics.postgresql_logs
+		 WHERE `+where+`
+		 ORDER BY log_timestamp DESC, id DESC
+		 LIMIT `+fmt.Sprintf("$%d", len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, apperrors.DatabaseError("search postgresql logs", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var logs []*models.PostgreSQLLog
+	for rows.Next() {
+		log := &models.PostgreSQLLog{}
+		err := rows.Scan(
+			&log.ID, &log.CollectorID, &log.InstanceID, &log.DatabaseID, &log.LogTimestamp,
+			&log.LogLevel, &log.LogMessage, &log.SourceLocation, &log.ProcessID, &log.QueryText,
+			&log.QueryHash, &log.ErrorCode, &log.ErrorDetail, &log.ErrorHint, &log.ErrorContext,
+			&log.UserName, &log.ConnectionFrom, &log.SessionID, &log.DatabaseName, &log.ApplicationName,
+			&log.BackendType, &log.Category, &log.CreatedAt, &log.UpdatedAt,
+		)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan postgresql log", err.Error())
+		}
+		logs = append(logs, log)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("search postgresql logs", err.Error())
+	}
+
+	return logs, nil
+}
+
+// GetLogSearchFacets counts the logs matching a search condition by
+// category, database and user, keeping the most frequent values of each
+func (p *PostgresDB) GetLogSearchFacets(ctx context.Context, params *models.LogSearchParams, condition func(arg func(interface{}) string) string) (*models.LogSearchFacets, error) {
+	where, args := logSearchWhere(params, condition, false)
+
+	rows, err := p.db.QueryContext(
+		ctx,
+		`WITH matches AS (
+			SELECT category, database_name, user_name
+			FROM pganalytics.postgresql_logs
+			WHERE `+where+`
+		 ), counts AS (
+			SELECT 'category' AS facet, category AS value, COUNT(*) AS count
+			FROM matches WHERE category IS NOT NULL GROUP BY category
+			UNION ALL
+			SELECT 'database', database_name, COUNT(*)
+			FROM matches WHERE database_name IS NOT NULL GROUP BY database_name
+			UNION ALL
+			SELECT 'user', user_name, COUNT(*)
+			FROM matches WHERE user_name IS NOT NULL GROUP BY user_name
+		 )
+		 SELECT facet, value, count FROM (
+			SELECT facet, value, count,
+				ROW_NUMBER() OVER (PARTITION BY facet ORDER BY count DESC, value) AS rank
+			FROM counts
+		 ) ranked
+		 WHERE rank <= `+fmt.Sprintf("%d", logFacetLimit)+`
+		 ORDER BY facet, count DESC, value`,
+		args...,
+	)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get log search facets", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	facets := &models.LogSearchFacets{
+		Categories: []models.LogFacetCount{},
+		Databases:  []models.LogFacetCount{},
+		Users:      []models.LogFacetCount{},
+	}
+	for rows.Next() {
+		var facet string
+		var count models.LogFacetCount
+		if err := rows.Scan(&facet, &count.Value, &count.Count); err != nil {
+			return nil, apperrors.DatabaseError("scan log search facet", err.Error())
+		}
+		switch facet {
+		case "category":
+			facets.Categories = append(facets.Categories, count)
+		case "database":
+			facets.Databases = append(facets.Databases, count)
+		case "user":
+			facets.Users = append(facets.Users, count)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("get log search facets", err.Error())
+	}
+
+	return facets, nil
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// LOG STREAM OPERATIONS
+// ============================================================================
+
+// LogStreamChannel is the NOTIFY channel announcing ingested logs to the
+// other API replicas
+const LogStreamChannel = "pganalytics_log_stream"
+
+const postgresqlLogColumns = `id, collector_id, instance_id, database_id, log_timestamp, log_level, log_message,
+	source_location, process_id, query_text, query_hash, error_code, error_detail,
+	error_hint, error_context, user_name, connection_from, session_id, database_name,
+	application_name, backend_type, category, created_at, updated_at`
+
+// GetPostgresqlLogsByIDs returns the given logs in ID order
+func (p *PostgresDB) GetPostgresqlLogsByIDs(ctx context.Context, ids []int64) ([]*models.PostgreSQLLog, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT `+postgresqlLogColumns+`
+		FROM pganalytics.postgresql_logs
+		WHERE id = ANY($1)
+		ORDER BY id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, apperrors.DatabaseError("get postgresql logs by id", err.Error())
+	}
+	return scanPostgresqlLogs(rows)
+}
+
+// GetPostgresqlLogsAfter returns a collector's logs with an ID above afterID
+// in ID order, for resuming a log stream
+func (p *PostgresDB) GetPostgresqlLogsAfter(ctx context.Context, collectorID uuid.UUID, afterID int64, limit int) ([]*models.PostgreSQLLog, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT `+postgresqlLogColumns+`
+		FROM pganalytics.postgresql_logs
+		WHERE collector_id = $1 AND id > $2
+		ORDER BY id
+		LIMIT $3
+	`, collectorID, afterID, limit)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get postgresql logs after offset", err.Error())
+	}
+	return scanPostgresqlLogs(rows)
+}
+
+func scanPostgresqlLogs(rows *sql.Rows) ([]*models.PostgreSQLLog, error) {
+	defer func() { _ = rows.Close() }()
+
+	var logs []*models.PostgreSQLLog
+	for rows.Next() {
+		log := &models.PostgreSQLLog{}
+		err := rows.Scan(
+			&log.ID, &log.CollectorID, &log.InstanceID, &log.DatabaseID, &log.LogTimestamp,
+			&log.LogLevel, &log.LogMessage, &log.SourceLocation, &log.ProcessID, &log.QueryText,
+			&log.QueryHash, &log.ErrorCode, &log.ErrorDetail, &log.ErrorHint, &log.ErrorContext,
+			&log.UserName, &log.ConnectionFrom, &log.SessionID, &log.DatabaseName, &log.ApplicationName,
+			&log.BackendType, &log.Category, &log.CreatedAt, &log.UpdatedAt,
+		)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan postgresql log", err.Error())
+		}
+		logs = append(logs, log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("get postgresql logs", err.Error())
+	}
+
+	return logs, nil
+}
+
+// NotifyLogStream sends a payload on the log stream channel
+func (p *PostgresDB) NotifyLogStream(ctx context.Context, payload string) error {
+	if _, err := p.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, LogStreamChannel, payload); err != nil {
+		return apperrors.DatabaseError("notify log stream", err.Error())
+	}
+	return nil
+}
+
+// ListenLogStream calls handle with each payload sent on the log stream
+// channel until the context is cancelled. It uses a dedicated connection
+// that reconnects on failure.
+func (p *PostgresDB) ListenLogStream(ctx context.Context, connString string, handle func(payload string)) error {
+	listener := pq.NewListener(connString, time.Second, time.Minute, nil)
+	defer func() { _ = listener.Close() }()
+
+	if err := listener.Listen(LogStreamChannel); err != nil {
+		return apperrors.DatabaseError("listen log stream", err.Error())
+	}
+
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n := <-listener.Notify:
+			// A nil notification follows a reconnect; notifications sent
+			// while disconnected are lost
+			if n != nil {
+				handle(n.Extra)
+			}
+		case <-ping.C:
+			go func() { _ = listener.Ping() }()
+		}
+	}
+}
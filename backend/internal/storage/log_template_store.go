@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// LOG TEMPLATE OPERATIONS
+// ============================================================================
+
+const logTemplateColumns = `id, collector_id, template, token_count, log_level, category,
+	sample_message, total_count, first_seen, last_seen`
+
+// GetLogTemplates returns a collector's templates seen since a time, most
+// recently seen first
+func (p *PostgresDB) GetLogTemplates(ctx context.Context, collectorID uuid.UUID, since time.Time, limit int) ([]*models.LogTemplate, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT `+logTemplateColumns+`
+		FROM log_templates
+		WHERE collector_id = $1 AND last_seen >= $2
+		ORDER BY last_seen DESC
+		LIMIT $3
+	`, collectorID, since, limit)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get log templates", err.Error())
+	}
+	return scanLogTemplates(rows)
+}
+
+// GetLogTemplatesByID returns the given templates of a collector
+func (p *PostgresDB) GetLogTemplatesByID(ctx context.Context, collectorID uuid.UUID, ids []int64) ([]*models.LogTemplate, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT `+logTemplateColumns+`
+		FROM log_templates
+		WHERE collector_id = $1 AND id = ANY($2)
+	`, collectorID, pq.Array(ids))
+	if err != nil {
+		return nil, apperrors.DatabaseError("get log templates", err.Error())
+	}
+	return scanLogTemplates(rows)
+}
+
+func scanLogTemplates(rows *sql.Rows) ([]*models.LogTemplate, error) {
+	defer func() { _ = rows.Close() }()
+
+	var templates []*models.LogTemplate
+	for rows.Next() {
+		t := &models.LogTemplate{}
+		if err := rows.Scan(
+			&t.ID, &t.CollectorID, &t.Template, &t.TokenCount, &t.LogLevel, &t.Category,
+			&t.SampleMessage, &t.TotalCount, &t.FirstSeen, &t.LastSeen,
+		); err != nil {
+			return nil, apperrors.DatabaseError("scan log template", err.Error())
+		}
+		templates = append(templates, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("get log templates", err.Error())
+	}
+
+	return templates, nil
+}
+
+// SaveLogTemplate inserts a new template or updates a generalized one, and
+// adds the number of newly matched messages to its total. A template another
+// backend instance already stored for the collector is added to instead, in
+// which case t takes that template's ID.
+func (p *PostgresDB) SaveLogTemplate(ctx context.Context, t *models.LogTemplate, added int64) error {
+	if t.ID == 0 {
+		err := p.db.QueryRowContext(ctx, `
+			INSERT INTO log_templates (
+				collector_id, template, token_count, log_level, category,
+				sample_message, total_count, first_seen, last_seen
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (collector_id, md5(template)) DO UPDATE
+			SET total_count = log_templates.total_count + EXCLUDED.total_count,
+				first_seen = LEAST(log_templates.first_seen, EXCLUDED.first_seen),
+				last_seen = GREATEST(log_templates.last_seen, EXCLUDED.last_seen)
+			RETURNING id, total_count
+		`, t.CollectorID, t.Template, t.TokenCount, t.LogLevel, t.Category,
+			t.SampleMessage, added, t.FirstSeen, t.LastSeen,
+		).Scan(&t.ID, &t.TotalCount)
+		if err != nil {
+			return apperrors.DatabaseError("insert log template", err.Error())
+		}
+		return nil
+	}
+
+	err := p.db.QueryRowContext(ctx, `
+		UPDATE log_templates
+		SET template = $2, token_count = $3,
+			total_count = total_count + $4,
+			first_seen = LEAST(first_seen, $5),
+			last_seen = GREATEST(last_seen, $6)
+		WHERE id = $1
+		RETURNING total_count
+	`, t.ID, t.Template, t.TokenCount, added, t.FirstSeen, t.LastSeen).Scan(&t.TotalCount)
+	if isUniqueViolation(err) {
+		// Generalized into a template that is already stored
+		t.ID = 0
+		return p.SaveLogTemplate(ctx, t, added)
+	}
+	if err != nil {
+		return apperrors.DatabaseError("update log template", err.Error())
+	}
+
+	return nil
+}
+
+// AddLogTemplateCounts adds per-minute message counts
+func (p *PostgresDB) AddLogTemplateCounts(ctx context.Context, counts []*models.LogTemplateCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO log_template_counts (template_id, bucket, count)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (template_id, bucket) DO UPDATE
+		SET count = log_template_counts.count + EXCLUDED.count
+	`)
+	if err != nil {
+		return apperrors.DatabaseError("prepare log template count insert", err.Error())
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, c := range counts {
+		if _, err := stmt.ExecContext(ctx, c.TemplateID, c.Bucket, c.Count); err != nil {
+			return apperrors.DatabaseError("insert log template count", err.Error())
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit transaction", err.Error())
+	}
+
+	return nil
+}
+
+// GetLogTemplateCounts returns the number of messages per template of a
+// collector in [from, to)
+func (p *PostgresDB) GetLogTemplateCounts(ctx context.Context, collectorID uuid.UUID, from, to time.Time) (map[int64]int64, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT c.template_id, SUM(c.count)
+		FROM log_template_counts c
+		JOIN log_templates t ON t.id = c.template_id
+		WHERE t.collector_id = $1 AND c.bucket >= $2 AND c.bucket < $3
+		GROUP BY c.template_id
+	`, collectorID, from, to)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get log template counts", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	counts := make(map[int64]int64)
+	for rows.Next() {
+		var id, count int64
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, apperrors.DatabaseError("scan log template count", err.Error())
+		}
+		counts[id] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("get log template counts", err.Error())
+	}
+
+	return counts, nil
+}
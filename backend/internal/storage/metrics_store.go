@@ -145,10 +145,13 @@ func (p *PostgresDB) GetSchemaMetrics(ctx context.Context, collectorID uuid.UUID
 // ============================================================================
 
 // StoreLockMetrics inserts lock metrics into the database
+// Rows without a timestamp share a single one so that the batch can be read
+// back as one snapshot.
 func (p *PostgresDB) StoreLockMetrics(ctx context.Context, locks []*models.Lock, waits []*models.LockWait) error {
 	if len(locks) == 0 && len(waits) == 0 {
 		return nil
 	}
+	now := time.Now()
 
 	tx, err := p.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -171,7 +174,7 @@ func (p *PostgresDB) StoreLockMetrics(ctx context.Context, locks []*models.Lock,
 		defer func() { _ = stmt.Close() }()
 
 		for _, l := range locks {
-			if _, err := stmt.ExecContext(ctx, time.Now(), l.CollectorID, l.DatabaseName, l.PID, l.LockType, l.Mode, l.Granted, l.RelationID, l.PageNumber, l.TupleID, l.Username, l.SessionState, l.LockAgeSeconds, l.Query); err != nil {
+			if _, err := stmt.ExecContext(ctx, snapshotTime(l.Timestamp, now), l.CollectorID, l.DatabaseName, l.PID, l.LockType, l.Mode, l.Granted, l.RelationID, l.PageNumber, l.TupleID, l.Username, l.SessionState, l.LockAgeSeconds, l.Query); err != nil {
 				return apperrors.DatabaseError("insert lock", err.Error())
 			}
 		}
@@ -190,7 +193,7 @@ func (p *PostgresDB) StoreLockMetrics(ctx context.Context, locks []*models.Lock,
 		defer func() { _ = stmt.Close() }()
 
 		for _, w := range waits {
-			if _, err := stmt.ExecContext(ctx, time.Now(), w.CollectorID, w.DatabaseName, w.BlockedPID, w.BlockingPID, w.BlockedUsername, w.BlockingUsername, w.BlockedQuery, w.BlockingQuery, w.WaitTimeSeconds, w.BlockedApplication, w.BlockingApplication); err != nil {
+			if _, err := stmt.ExecContext(ctx, snapshotTime(w.Timestamp, now), w.CollectorID, w.DatabaseName, w.BlockedPID, w.BlockingPID, w.BlockedUsername, w.BlockingUsername, w.BlockedQuery, w.BlockingQuery, w.WaitTimeSeconds, w.BlockedApplication, w.BlockingApplication); err != nil {
 				return apperrors.DatabaseError("insert lock wait", err.Error())
 			}
 		}
@@ -232,6 +235,72 @@ func (p *PostgresDB) GetLockMetrics(ctx context.Context, collectorID uuid.UUID,
 	return resp, nil
 }
 
+// ============================================================================
+// WRAPAROUND METRICS OPERATIONS
+// ============================================================================
+
+// StoreWraparoundMetrics inserts one pg_wraparound sample
+// The ages and blockers share the sample's time, the current time when
+// unset, so that the sample can be read back as one snapshot.
+func (p *PostgresDB) StoreWraparoundMetrics(ctx context.Context, sample *models.WraparoundSample, databases []*models.WraparoundAge, tables []*models.WraparoundAge, blockers []*models.WraparoundBlocker) error {
+	sampledAt := snapshotTime(sample.Time, time.Now())
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO metrics_pg_wraparound (time, collector_id, next_xid, next_multixact, freeze_max_age, multixact_freeze_max_age, freeze_min_age)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, sampledAt, sample.CollectorID, sample.NextXID, sample.NextMultixact, sample.FreezeMaxAge, sample.MultixactFreezeMaxAge, sample.FreezeMinAge); err != nil {
+		return apperrors.DatabaseError("insert wraparound sample", err.Error())
+	}
+
+	for _, d := range databases {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO metrics_pg_wraparound_databases (time, collector_id, database_name, xid_age, mxid_age)
+			VALUES ($1, $2, $3, $4, $5)
+		`, sampledAt, sample.CollectorID, d.DatabaseName, d.XIDAge, d.MXIDAge); err != nil {
+			return apperrors.DatabaseError("insert wraparound database age", err.Error())
+		}
+	}
+
+	for _, t := range tables {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO metrics_pg_wraparound_tables (time, collector_id, database_name, schema_name, table_name, xid_age, mxid_age, table_size_bytes, freeze_max_age)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, sampledAt, sample.CollectorID, t.DatabaseName, t.SchemaName, t.TableName, t.XIDAge, t.MXIDAge, t.TableSizeBytes, t.FreezeMaxAge); err != nil {
+			return apperrors.DatabaseError("insert wraparound table age", err.Error())
+		}
+	}
+
+	for _, b := range blockers {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO metrics_pg_wraparound_blockers (time, collector_id, blocker_type, name, database_name, user_name, xid_age, since, active, detail)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		`, sampledAt, sample.CollectorID, b.Type, b.Name, nullString(b.DatabaseName), nullString(b.UserName), b.XIDAge, b.Since, b.Active, nullString(b.Detail)); err != nil {
+			return apperrors.DatabaseError("insert wraparound blocker", err.Error())
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit wraparound metrics", err.Error())
+	}
+	return nil
+}
+
+// snapshotTime returns ts, or fallback when ts is unset
+func snapshotTime(ts, fallback time.Time) time.Time {
+	if ts.IsZero() {
+		return fallback
+	}
+	return ts
+}
+
 // ============================================================================
 // BLOAT METRICS OPERATIONS
 // ============================================================================
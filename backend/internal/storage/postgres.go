@@ -111,6 +111,12 @@ func NewPostgresDB(connString string) (*PostgresDB, error) {
 	return &PostgresDB{pool: pool, readOnlyPool: readOnlyPool, db: db}, nil
 }
 
+// NewPostgresDBFromDB wraps an existing database connection without pools or
+// migrations, for callers that manage the connection themselves
+func NewPostgresDBFromDB(db *sql.DB) *PostgresDB {
+	return &PostgresDB{db: db}
+}
+
 // runMigrations executes pending database migrations
 func runMigrations(ctx context.Context, db *sql.DB) error {
 	// Create a simple logger for migrations
@@ -1239,10 +1245,11 @@ func (p *PostgresDB) GetQueriesByFingerprint(ctx context.Context, fingerprintHas
 func (p *PostgresDB) GetExplainPlan(ctx context.Context, queryHash int64) (*models.ExplainPlan, error) {
 	query := `
 	SELECT
-		id, query_hash, query_fingerprint_hash, collected_at, plan_json, plan_text,
+		id, collector_id, source, query_hash, query_fingerprint_hash, query_fingerprint, query_text,
+		collected_at, plan_json, plan_text,
 		rows_expected, rows_actual, plan_duration_ms, execution_duration_ms,
 		has_seq_scan, has_index_scan, has_bitmap_scan, has_nested_loop,
-		total_buffers_read, total_buffers_hit
+		total_buffers_read, total_buffers_hit, issues
 	FROM explain_plans
 	WHERE query_hash = $1
 	ORDER BY collected_at DESC
@@ -1254,10 +1261,11 @@ func (p *PostgresDB) GetExplainPlan(ctx context.Context, queryHash int64) (*mode
 
 	plan := &models.ExplainPlan{}
 	err := p.db.QueryRowContext(ctx, query, queryHash).Scan(
-		&plan.ID, &plan.QueryHash, &plan.QueryFingerprintHash, &plan.CollectedAt, &plan.PlanJSON, &plan.PlanText,
+		&plan.ID, &plan.CollectorID, &plan.Source, &plan.QueryHash, &plan.QueryFingerprintHash, &plan.QueryFingerprint, &plan.QueryText,
+		&plan.CollectedAt, &plan.PlanJSON, &plan.PlanText,
 		&plan.RowsExpected, &plan.RowsActual, &plan.PlanDurationMs, &plan.ExecutionDurationMs,
 		&plan.HasSeqScan, &plan.HasIndexScan, &plan.HasBitmapScan, &plan.HasNestedLoop,
-		&plan.TotalBuffersRead, &plan.TotalBuffersHit,
+		&plan.TotalBuffersRead, &plan.TotalBuffersHit, &plan.Issues,
 	)
 
 	if err == sql.ErrNoRows {
@@ -1270,6 +1278,57 @@ func (p *PostgresDB) GetExplainPlan(ctx context.Context, queryHash int64) (*mode
 	return plan, nil
 }
 
+// StoreExplainPlan stores an EXPLAIN plan and sets its ID
+func (p *PostgresDB) StoreExplainPlan(ctx context.Context, plan *models.ExplainPlan) error {
+	planJSON, err := json.Marshal(plan.PlanJSON)
+	if err != nil {
+		return apperrors.InvalidJSON(err.Error())
+	}
+	var issues []byte
+	if plan.Issues != nil {
+		if issues, err = json.Marshal(plan.Issues); err != nil {
+			return apperrors.InternalServerError("Failed to encode plan issues", err.Error())
+		}
+	}
+	source := plan.Source
+	if source == "" {
+		source = models.ExplainPlanSourceCollector
+	}
+	collectedAt := plan.CollectedAt
+	if collectedAt.IsZero() {
+		collectedAt = time.Now()
+	}
+
+	query := `
+	INSERT INTO explain_plans (
+		collector_id, source, query_hash, query_fingerprint_hash, query_fingerprint, query_text,
+		collected_at, plan_json, plan_text,
+		rows_expected, rows_actual, plan_duration_ms, execution_duration_ms,
+		has_seq_scan, has_index_scan, has_bitmap_scan, has_nested_loop,
+		total_buffers_read, total_buffers_hit, issues
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+	RETURNING id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err = p.db.QueryRowContext(ctx, query,
+		plan.CollectorID, source, plan.QueryHash, plan.QueryFingerprintHash, plan.QueryFingerprint, plan.QueryText,
+		collectedAt, planJSON, plan.PlanText,
+		plan.RowsExpected, plan.RowsActual, plan.PlanDurationMs, plan.ExecutionDurationMs,
+		plan.HasSeqScan, plan.HasIndexScan, plan.HasBitmapScan, plan.HasNestedLoop,
+		plan.TotalBuffersRead, plan.TotalBuffersHit, issues,
+	).Scan(&plan.ID)
+	if err != nil {
+		return apperrors.DatabaseError("store explain plan", err.Error())
+	}
+
+	plan.Source = source
+	plan.CollectedAt = collectedAt
+	return nil
+}
+
 // GetQueryAnomalies returns detected anomalies for a query
 func (p *PostgresDB) GetQueryAnomalies(ctx context.Context, queryHash int64, days int) ([]*models.QueryAnomaly, error) {
 	if days > 30 {
@@ -2489,19 +2548,23 @@ func (p *PostgresDB) InsertPostgresqlLog(ctx context.Context, log *models.Postgr
 		`INSERT INTO pganalytics.postgresql_logs (
 			collector_id, instance_id, database_id, log_timestamp, log_level, log_message,
 			source_location, process_id, query_text, query_hash, error_code, error_detail,
-			error_hint, error_context, user_name, connection_from, session_id
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+			error_hint, error_context, user_name, connection_from, session_id, database_name,
+			application_name, backend_type, category
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
 		RETURNING id, collector_id, instance_id, database_id, log_timestamp, log_level, log_message,
 			source_location, process_id, query_text, query_hash, error_code, error_detail,
-			error_hint, error_context, user_name, connection_from, session_id, created_at, updated_at`,
+			error_hint, error_context, user_name, connection_from, session_id, database_name,
+			application_name, backend_type, category, created_at, updated_at`,
 		log.CollectorID, log.InstanceID, log.DatabaseID, log.LogTimestamp, log.LogLevel, log.LogMessage,
 		log.SourceLocation, log.ProcessID, log.QueryText, log.QueryHash, log.ErrorCode, log.ErrorDetail,
-		log.ErrorHint, log.ErrorContext, log.UserName, log.ConnectionFrom, log.SessionID,
+		log.ErrorHint, log.ErrorContext, log.UserName, log.ConnectionFrom, log.SessionID, log.DatabaseName,
+		log.ApplicationName, log.BackendType, log.Category,
 	).Scan(
 		&result.ID, &result.CollectorID, &result.InstanceID, &result.DatabaseID, &result.LogTimestamp,
 		&result.LogLevel, &result.LogMessage, &result.SourceLocation, &result.ProcessID, &result.QueryText,
 		&result.QueryHash, &result.ErrorCode, &result.ErrorDetail, &result.ErrorHint, &result.ErrorContext,
-		&result.UserName, &result.ConnectionFrom, &result.SessionID, &result.CreatedAt, &result.UpdatedAt,
+		&result.UserName, &result.ConnectionFrom, &result.SessionID, &result.DatabaseName, &result.ApplicationName,
+		&result.BackendType, &result.Category, &result.CreatedAt, &result.UpdatedAt,
 	)
 
 	if err != nil {
@@ -2517,7 +2580,8 @@ func (p *PostgresDB) GetPostgresqlLogs(ctx context.Context, instanceID int, limi
 		ctx,
 		`SELECT id, collector_id, instance_id, database_id, log_timestamp, log_level, log_message,
 			source_location, process_id, query_text, query_hash, error_code, error_detail,
-			error_hint, error_context, user_name, connection_from, session_id, created_at, updated_at
+			error_hint, error_context, user_name, connection_from, session_id, database_name,
+			application_name, backend_type, category, created_at, updated_at
 		 FROM pganalytics.postgresql_logs
 		 WHERE instance_id = $1
 		 ORDER BY log_timestamp DESC
@@ -2537,7 +2601,8 @@ func (p *PostgresDB) GetPostgresqlLogs(ctx context.Context, instanceID int, limi
 			&log.ID, &log.CollectorID, &log.InstanceID, &log.DatabaseID, &log.LogTimestamp,
 			&log.LogLevel, &log.LogMessage, &log.SourceLocation, &log.ProcessID, &log.QueryText,
 			&log.QueryHash, &log.ErrorCode, &log.ErrorDetail, &log.ErrorHint, &log.ErrorContext,
-			&log.UserName, &log.ConnectionFrom, &log.SessionID, &log.CreatedAt, &log.UpdatedAt,
+			&log.UserName, &log.ConnectionFrom, &log.SessionID, &log.DatabaseName, &log.ApplicationName,
+			&log.BackendType, &log.Category, &log.CreatedAt, &log.UpdatedAt,
 		)
 		if err != nil {
 			return nil, apperrors.DatabaseError("scan postgresql log", err.Error())
@@ -2558,7 +2623,8 @@ func (p *PostgresDB) GetPostgresqlLogsByLevel(ctx context.Context, instanceID in
 		ctx,
 		`SELECT id, collector_id, instance_id, database_id, log_timestamp, log_level, log_message,
 			source_location, process_id, query_text, query_hash, error_code, error_detail,
-			error_hint, error_context, user_name, connection_from, session_id, created_at, updated_at
+			error_hint, error_context, user_name, connection_from, session_id, database_name,
+			application_name, backend_type, category, created_at, updated_at
 		 FROM pganalytics.postgresql_logs
 		 WHERE instance_id = $1 AND log_level = $2
 		 ORDER BY log_timestamp DESC
@@ -2578,7 +2644,8 @@ func (p *PostgresDB) GetPostgresqlLogsByLevel(ctx context.Context, instanceID in
 			&log.ID, &log.CollectorID, &log.InstanceID, &log.DatabaseID, &log.LogTimestamp,
 			&log.LogLevel, &log.LogMessage, &log.SourceLocation, &log.ProcessID, &log.QueryText,
 			&log.QueryHash, &log.ErrorCode, &log.ErrorDetail, &log.ErrorHint, &log.ErrorContext,
-			&log.UserName, &log.ConnectionFrom, &log.SessionID, &log.CreatedAt, &log.UpdatedAt,
+			&log.UserName, &log.ConnectionFrom, &log.SessionID, &log.DatabaseName, &log.ApplicationName,
+			&log.BackendType, &log.Category, &log.CreatedAt, &log.UpdatedAt,
 		)
 		if err != nil {
 			return nil, apperrors.DatabaseError("scan postgresql log", err.Error())
@@ -2599,7 +2666,8 @@ func (p *PostgresDB) GetErrorLogs(ctx context.Context, instanceID int, limit int
 		ctx,
 		`SELECT id, collector_id, instance_id, database_id, log_timestamp, log_level, log_message,
 			source_location, process_id, query_text, query_hash, error_code, error_detail,
-			error_hint, error_context, user_name, connection_from, session_id, created_at, updated_at
+			error_hint, error_context, user_name, connection_from, session_id, database_name,
+			application_name, backend_type, category, created_at, updated_at
 		 FROM pganalytics.postgresql_logs
 		 WHERE instance_id = $1 AND log_level IN ('ERROR', 'FATAL', 'PANIC')
 		 ORDER BY log_timestamp DESC
@@ -2619,7 +2687,8 @@ func (p *PostgresDB) GetErrorLogs(ctx context.Context, instanceID int, limit int
 			&log.ID, &log.CollectorID, &log.InstanceID, &log.DatabaseID, &log.LogTimestamp,
 			&log.LogLevel, &log.LogMessage, &log.SourceLocation, &log.ProcessID, &log.QueryText,
 			&log.QueryHash, &log.ErrorCode, &log.ErrorDetail, &log.ErrorHint, &log.ErrorContext,
-			&log.UserName, &log.ConnectionFrom, &log.SessionID, &log.CreatedAt, &log.UpdatedAt,
+			&log.UserName, &log.ConnectionFrom, &log.SessionID, &log.DatabaseName, &log.ApplicationName,
+			&log.BackendType, &log.Category, &log.CreatedAt, &log.UpdatedAt,
 		)
 		if err != nil {
 			return nil, apperrors.DatabaseError("scan error log", err.Error())
@@ -0,0 +1,344 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// QUERY REDACTION OPERATIONS
+// ============================================================================
+
+// redactionBackfillBatchSize is the number of rows a redaction backfill
+// rewrites per transaction and checkpoint
+const redactionBackfillBatchSize = 1000
+
+// keyColumn is a column of the key a backfill walks a table by
+type keyColumn struct {
+	name    string
+	sqlType string
+}
+
+// redactableColumn is a stored column holding query samples
+type redactableColumn struct {
+	table  string
+	column string
+	key    []keyColumn // Primary key, or the columns identifying a hypertable sample
+	isLog  bool        // log messages embed statements in free text
+	isPlan bool        // JSONB plans whose string values are redacted like log messages
+}
+
+var (
+	idKey             = []keyColumn{{"id", "bigint"}}
+	statsQueryKey     = []keyColumn{{"time", "timestamptz"}, {"collector_id", "uuid"}, {"database_name", "text"}, {"user_name", "text"}, {"query_hash", "bigint"}}
+	activitySampleKey = []keyColumn{{"time", "timestamptz"}, {"collector_id", "uuid"}, {"pid", "integer"}}
+)
+
+// redactableColumns lists the columns a redaction backfill rewrites
+var redactableColumns = []redactableColumn{
+	{table: "metrics_pg_stats_query", column: "query_text", key: statsQueryKey},
+	{table: "metrics_pg_activity_samples", column: "query_text", key: activitySampleKey},
+	{table: "postgresql_logs", column: "query_text", key: idKey},
+	{table: "postgresql_logs", column: "log_message", key: idKey, isLog: true},
+	{table: "postgresql_logs", column: "error_detail", key: idKey, isLog: true},
+	{table: "postgresql_logs", column: "error_hint", key: idKey, isLog: true},
+	{table: "postgresql_logs", column: "error_context", key: idKey, isLog: true},
+	{table: "explain_plans", column: "query_text", key: idKey},
+	{table: "explain_plans", column: "plan_text", key: idKey, isLog: true},
+	{table: "explain_plans", column: "plan_json", key: idKey, isPlan: true},
+}
+
+// GetTenantIDByCollectorID returns the tenant a collector is assigned to, or nil
+// when the collector is unknown or not assigned to a tenant
+func (p *PostgresDB) GetTenantIDByCollectorID(ctx context.Context, collectorID string) (*uuid.UUID, error) {
+	id, err := uuid.Parse(collectorID)
+	if err != nil {
+		return nil, nil
+	}
+
+	var tenantID uuid.NullUUID
+	err = p.db.QueryRowContext(ctx,
+		`SELECT tenant_id FROM collectors WHERE id = $1`, id,
+	).Scan(&tenantID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperrors.DatabaseError("query collector tenant", err.Error())
+	}
+	if !tenantID.Valid {
+		return nil, nil
+	}
+
+	return &tenantID.UUID, nil
+}
+
+// GetQueryRedactionPolicy retrieves a tenant's redaction policy
+// Tenants without a stored policy get mode "none"
+func (p *PostgresDB) GetQueryRedactionPolicy(ctx context.Context, tenantID uuid.UUID) (*models.QueryRedactionPolicy, error) {
+	policy := &models.QueryRedactionPolicy{TenantID: tenantID}
+	var updatedAt sql.NullTime
+
+	err := p.db.QueryRowContext(ctx,
+		`SELECT mode, updated_at FROM query_redaction_policies WHERE tenant_id = $1`, tenantID,
+	).Scan(&policy.Mode, &updatedAt)
+	if err == sql.ErrNoRows {
+		policy.Mode = models.RedactionModeNone
+		return policy, nil
+	}
+	if err != nil {
+		return nil, apperrors.DatabaseError("query redaction policy", err.Error())
+	}
+
+	if updatedAt.Valid {
+		t := updatedAt.Time
+		policy.UpdatedAt = &t
+	}
+
+	return policy, nil
+}
+
+// UpsertQueryRedactionPolicy creates or updates a tenant's redaction policy
+func (p *PostgresDB) UpsertQueryRedactionPolicy(ctx context.Context, policy *models.QueryRedactionPolicy) error {
+	query := `
+		INSERT INTO query_redaction_policies (tenant_id, mode)
+		VALUES ($1, $2)
+		ON CONFLICT (tenant_id) DO UPDATE
+		SET mode = EXCLUDED.mode, updated_at = NOW()
+		RETURNING updated_at
+	`
+
+	var updatedAt sql.NullTime
+	if err := p.db.QueryRowContext(ctx, query, policy.TenantID, policy.Mode).Scan(&updatedAt); err != nil {
+		return apperrors.DatabaseError("upsert redaction policy", err.Error())
+	}
+
+	if updatedAt.Valid {
+		t := updatedAt.Time
+		policy.UpdatedAt = &t
+	}
+
+	return nil
+}
+
+// RedactStoredQueryTexts rewrites stored query samples of a tenant's collectors.
+// Query text columns are rewritten with redactQuery, log messages with
+// redactLog and JSON plans with redactPlan. Each column is walked in key
+// order in batches; rows are redacted in place and rows that redact to
+// themselves are left untouched. Every batch records a checkpoint, so a
+// backfill that fails resumes after the last batch it committed, unless the
+// redaction mode has changed since. Checkpoints are removed on completion.
+// Returns the number of rows scanned and updated.
+func (p *PostgresDB) RedactStoredQueryTexts(ctx context.Context, tenantID uuid.UUID, mode string, redactQuery, redactLog, redactPlan func(string) string) (int64, int64, error) {
+	var scanned, updated int64
+
+	checkpoints, err := p.getRedactionCheckpoints(ctx, tenantID, mode)
+	if err != nil {
+		return scanned, updated, err
+	}
+
+	for _, target := range redactableColumns {
+		redact := redactQuery
+		if target.isLog {
+			redact = redactLog
+		}
+		if target.isPlan {
+			redact = redactPlan
+		}
+
+		lastKey := checkpoints[target.table+"."+target.column]
+		for {
+			batch, err := p.redactColumnBatch(ctx, tenantID, mode, target, redact, lastKey)
+			if err != nil {
+				return scanned, updated, err
+			}
+			scanned += batch.scanned
+			updated += batch.updated
+			if batch.scanned < redactionBackfillBatchSize {
+				break
+			}
+			lastKey = batch.lastKey
+		}
+	}
+
+	if _, err := p.db.ExecContext(ctx,
+		`DELETE FROM query_redaction_backfill_checkpoints WHERE tenant_id = $1`, tenantID,
+	); err != nil {
+		return scanned, updated, apperrors.DatabaseError("delete redaction checkpoints", err.Error())
+	}
+
+	return scanned, updated, nil
+}
+
+// getRedactionCheckpoints returns the last key redacted per "table.column" by
+// an interrupted backfill. Checkpoints of a backfill with another mode are
+// discarded, since the rows before them were redacted differently.
+func (p *PostgresDB) getRedactionCheckpoints(ctx context.Context, tenantID uuid.UUID, mode string) (map[string][]string, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT table_name, column_name, mode, last_key
+		FROM query_redaction_backfill_checkpoints
+		WHERE tenant_id = $1
+	`, tenantID)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query redaction checkpoints", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	checkpoints := map[string][]string{}
+	stale := false
+	for rows.Next() {
+		var table, column, checkpointMode string
+		var lastKey []string
+		if err := rows.Scan(&table, &column, &checkpointMode, pq.Array(&lastKey)); err != nil {
+			return nil, apperrors.DatabaseError("scan redaction checkpoint", err.Error())
+		}
+		if checkpointMode != mode {
+			stale = true
+		}
+		checkpoints[table+"."+column] = lastKey
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("iterate redaction checkpoints", err.Error())
+	}
+
+	if stale {
+		if _, err := p.db.ExecContext(ctx,
+			`DELETE FROM query_redaction_backfill_checkpoints WHERE tenant_id = $1`, tenantID,
+		); err != nil {
+			return nil, apperrors.DatabaseError("delete redaction checkpoints", err.Error())
+		}
+		return map[string][]string{}, nil
+	}
+
+	return checkpoints, nil
+}
+
+// redactionBatch is the outcome of redacting one batch of a column
+type redactionBatch struct {
+	scanned int64
+	updated int64
+	lastKey []string
+}
+
+// redactColumnBatch redacts the next batch of a column after lastKey, or from
+// the start when lastKey is nil, and records the batch's checkpoint in the
+// same transaction as its updates
+func (p *PostgresDB) redactColumnBatch(ctx context.Context, tenantID uuid.UUID, mode string, target redactableColumn, redact func(string) string, lastKey []string) (*redactionBatch, error) {
+	names := make([]string, len(target.key))
+	selects := make([]string, len(target.key))
+	matches := make([]string, len(target.key))
+	for i, k := range target.key {
+		names[i] = k.name
+		selects[i] = k.name + "::text"
+		matches[i] = fmt.Sprintf("%s = $%d::%s", k.name, i+2, k.sqlType)
+	}
+
+	args := []interface{}{tenantID}
+	after := ""
+	if lastKey != nil {
+		params := make([]string, len(target.key))
+		for i, k := range target.key {
+			params[i] = fmt.Sprintf("$%d::%s", i+2, k.sqlType)
+			args = append(args, lastKey[i])
+		}
+		after = "AND (" + strings.Join(names, ", ") + ") > (" + strings.Join(params, ", ") + ")"
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT `+strings.Join(selects, ", ")+`, `+target.column+`::text FROM `+target.table+`
+		WHERE `+target.column+` IS NOT NULL
+		AND collector_id IN (SELECT id FROM collectors WHERE tenant_id = $1)
+		`+after+`
+		ORDER BY `+strings.Join(names, ", ")+`
+		LIMIT `+strconv.Itoa(redactionBackfillBatchSize), args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query stored query texts", err.Error())
+	}
+
+	type storedText struct {
+		key  []string
+		text string
+	}
+	var texts []storedText
+	for rows.Next() {
+		row := storedText{key: make([]string, len(target.key))}
+		dest := make([]interface{}, 0, len(target.key)+1)
+		for i := range row.key {
+			dest = append(dest, &row.key[i])
+		}
+		dest = append(dest, &row.text)
+		if err := rows.Scan(dest...); err != nil {
+			_ = rows.Close()
+			return nil, apperrors.DatabaseError("scan stored query text", err.Error())
+		}
+		texts = append(texts, row)
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return nil, apperrors.DatabaseError("iterate stored query texts", err.Error())
+	}
+
+	batch := &redactionBatch{scanned: int64(len(texts))}
+	if len(texts) == 0 {
+		return batch, nil
+	}
+	batch.lastKey = texts[len(texts)-1].key
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	cast := ""
+	if target.isPlan {
+		cast = "::jsonb"
+	}
+	update := `UPDATE ` + target.table + ` SET ` + target.column + ` = $1` + cast + `
+		WHERE ` + strings.Join(matches, " AND ")
+	for _, row := range texts {
+		redacted := redact(row.text)
+		if redacted == row.text {
+			continue
+		}
+
+		args := []interface{}{redacted}
+		for _, v := range row.key {
+			args = append(args, v)
+		}
+		result, err := tx.ExecContext(ctx, update, args...)
+		if err != nil {
+			return nil, apperrors.DatabaseError("redact stored query text", err.Error())
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			batch.updated += n
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO query_redaction_backfill_checkpoints (tenant_id, table_name, column_name, mode, last_key)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (tenant_id, table_name, column_name) DO UPDATE
+		SET mode = EXCLUDED.mode, last_key = EXCLUDED.last_key, updated_at = NOW()
+	`, tenantID, target.table, target.column, mode, pq.Array(batch.lastKey))
+	if err != nil {
+		return nil, apperrors.DatabaseError("store redaction checkpoint", err.Error())
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, apperrors.DatabaseError("commit transaction", err.Error())
+	}
+
+	return batch, nil
+}
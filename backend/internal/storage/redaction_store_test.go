@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactStoredQueryTexts(t *testing.T) {
+	saved := redactableColumns
+	redactableColumns = []redactableColumn{{table: "postgresql_logs", column: "query_text", key: idKey}}
+	defer func() { redactableColumns = saved }()
+
+	redact := func(text string) string { return strings.ReplaceAll(text, "'secret'", "?") }
+	checkpointColumns := []string{"table_name", "column_name", "mode", "last_key"}
+
+	t.Run("resumes after the checkpoint in key-ordered batches", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		pgDB := &PostgresDB{db: db}
+		tenantID := uuid.New()
+
+		mock.ExpectQuery(regexp.QuoteMeta("FROM query_redaction_backfill_checkpoints")).
+			WithArgs(tenantID).
+			WillReturnRows(sqlmock.NewRows(checkpointColumns).AddRow("postgresql_logs", "query_text", "mask", "{5}"))
+
+		// A full batch continues from the row after the checkpoint
+		batch := sqlmock.NewRows([]string{"id", "query_text"}).AddRow("6", "SELECT 'secret'")
+		for id := 7; id <= 1005; id++ {
+			batch.AddRow(strconv.Itoa(id), "SELECT 1")
+		}
+		mock.ExpectQuery(regexp.QuoteMeta("AND (id) > ($2::bigint)")).
+			WithArgs(tenantID, "5").
+			WillReturnRows(batch)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE postgresql_logs SET query_text = $1")).
+			WithArgs("SELECT ?", "6").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO query_redaction_backfill_checkpoints")).
+			WithArgs(tenantID, "postgresql_logs", "query_text", "mask", pq.Array([]string{"1005"})).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		// A short batch ends the column
+		mock.ExpectQuery(regexp.QuoteMeta("AND (id) > ($2::bigint)")).
+			WithArgs(tenantID, "1005").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "query_text"}).AddRow("1006", "SELECT 2"))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO query_redaction_backfill_checkpoints")).
+			WithArgs(tenantID, "postgresql_logs", "query_text", "mask", pq.Array([]string{"1006"})).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM query_redaction_backfill_checkpoints")).
+			WithArgs(tenantID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		scanned, updated, err := pgDB.RedactStoredQueryTexts(context.Background(), tenantID, "mask", redact, redact, redact)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1001), scanned)
+		assert.Equal(t, int64(1), updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("restarts when the mode changed", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		pgDB := &PostgresDB{db: db}
+		tenantID := uuid.New()
+
+		mock.ExpectQuery(regexp.QuoteMeta("FROM query_redaction_backfill_checkpoints")).
+			WithArgs(tenantID).
+			WillReturnRows(sqlmock.NewRows(checkpointColumns).AddRow("postgresql_logs", "query_text", "normalize", "{5}"))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM query_redaction_backfill_checkpoints")).
+			WithArgs(tenantID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		mock.ExpectQuery(regexp.QuoteMeta("ORDER BY id")).
+			WithArgs(tenantID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "query_text"}))
+
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM query_redaction_backfill_checkpoints")).
+			WithArgs(tenantID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		scanned, updated, err := pgDB.RedactStoredQueryTexts(context.Background(), tenantID, "mask", redact, redact, redact)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), scanned)
+		assert.Equal(t, int64(0), updated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
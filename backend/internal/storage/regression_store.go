@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// QUERY REGRESSION OPERATIONS
+// ============================================================================
+
+// GetQueryStatsSamples returns the query statistics samples of a collector in
+// [from, to), ordered by time within each database and query hash
+func (p *PostgresDB) GetQueryStatsSamples(ctx context.Context, collectorID uuid.UUID, from, to time.Time, database *string) ([]*models.QueryStatsSample, error) {
+	query := `
+		SELECT time, database_name, query_hash, query_text, calls, total_time, rows, shared_blks_read
+		FROM metrics_pg_stats_query
+		WHERE collector_id = $1 AND time >= $2 AND time < $3`
+	args := []interface{}{collectorID, from, to}
+	if database != nil {
+		query += ` AND database_name = $4`
+		args = append(args, *database)
+	}
+	query += ` ORDER BY database_name, query_hash, time`
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get query stats samples", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var samples []*models.QueryStatsSample
+	for rows.Next() {
+		s := &models.QueryStatsSample{}
+		if err := rows.Scan(&s.Time, &s.DatabaseName, &s.QueryHash, &s.QueryText,
+			&s.Calls, &s.TotalTime, &s.Rows, &s.SharedBlksRead); err != nil {
+			return nil, apperrors.DatabaseError("scan query stats sample", err.Error())
+		}
+		samples = append(samples, s)
+	}
+
+	return samples, rows.Err()
+}
+
+// CreateDeployMarker records a deploy marker
+func (p *PostgresDB) CreateDeployMarker(ctx context.Context, marker *models.DeployMarker) error {
+	err := p.db.QueryRowContext(ctx, `
+		INSERT INTO deploy_markers (collector_id, version, description, deployed_at, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, marker.CollectorID, marker.Version, marker.Description, marker.DeployedAt, marker.CreatedBy).
+		Scan(&marker.ID, &marker.CreatedAt)
+	if err != nil {
+		return apperrors.DatabaseError("create deploy marker", err.Error())
+	}
+	return nil
+}
+
+// GetDeployMarker returns a collector's deploy marker, or nil if it does not exist
+func (p *PostgresDB) GetDeployMarker(ctx context.Context, collectorID uuid.UUID, id int64) (*models.DeployMarker, error) {
+	m := &models.DeployMarker{}
+	err := p.db.QueryRowContext(ctx, `
+		SELECT id, collector_id, version, description, deployed_at, created_by, created_at
+		FROM deploy_markers
+		WHERE collector_id = $1 AND id = $2
+	`, collectorID, id).Scan(&m.ID, &m.CollectorID, &m.Version, &m.Description, &m.DeployedAt, &m.CreatedBy, &m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperrors.DatabaseError("get deploy marker", err.Error())
+	}
+	return m, nil
+}
+
+// ListDeployMarkers returns a collector's most recent deploy markers
+func (p *PostgresDB) ListDeployMarkers(ctx context.Context, collectorID uuid.UUID, limit int) ([]*models.DeployMarker, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT id, collector_id, version, description, deployed_at, created_by, created_at
+		FROM deploy_markers
+		WHERE collector_id = $1
+		ORDER BY deployed_at DESC
+		LIMIT $2
+	`, collectorID, limit)
+	if err != nil {
+		return nil, apperrors.DatabaseError("list deploy markers", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var markers []*models.DeployMarker
+	for rows.Next() {
+		m := &models.DeployMarker{}
+		if err := rows.Scan(&m.ID, &m.CollectorID, &m.Version, &m.Description, &m.DeployedAt, &m.CreatedBy, &m.CreatedAt); err != nil {
+			return nil, apperrors.DatabaseError("scan deploy marker", err.Error())
+		}
+		markers = append(markers, m)
+	}
+
+	return markers, rows.Err()
+}
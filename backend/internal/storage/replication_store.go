@@ -15,6 +15,8 @@ import (
 // ============================================================================
 
 // StoreReplicationMetrics inserts replication status metrics into the database
+// Rows without a timestamp share the current time, so that one batch reads
+// back as one snapshot.
 func (p *PostgresDB) StoreReplicationMetrics(ctx context.Context, status []*models.ReplicationStatus) error {
 	if len(status) == 0 {
 		return nil
@@ -38,8 +40,9 @@ func (p *PostgresDB) StoreReplicationMetrics(ctx context.Context, status []*mode
 	}
 	defer func() { _ = stmt.Close() }()
 
+	sampledAt := time.Now()
 	for _, s := range status {
-		if _, err := stmt.ExecContext(ctx, time.Now(), s.CollectorID, s.ServerPID, s.Usename, s.ApplicationName, s.State, s.SyncState, s.WriteLsn, s.FlushLsn, s.ReplayLsn, s.WriteLagMs, s.FlushLagMs, s.ReplayLagMs, s.BehindByMb, s.ClientAddr, s.BackendStart); err != nil {
+		if _, err := stmt.ExecContext(ctx, snapshotTime(s.Timestamp, sampledAt), s.CollectorID, s.ServerPID, s.Usename, s.ApplicationName, s.State, s.SyncState, s.WriteLsn, s.FlushLsn, s.ReplayLsn, s.WriteLagMs, s.FlushLagMs, s.ReplayLagMs, s.BehindByMb, s.ClientAddr, s.BackendStart); err != nil {
 			return apperrors.DatabaseError("insert replication status", err.Error())
 		}
 	}
@@ -91,8 +94,9 @@ func (p *PostgresDB) StoreReplicationSlots(ctx context.Context, slots []*models.
 	}()
 
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO metrics_replication_slots (time, collector_id, database_name, slot_name, slot_type, active, restart_lsn, confirmed_flush_lsn, wal_retained_mb, backend_pid, bytes_retained)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO metrics_replication_slots (time, collector_id, database_name, slot_name, slot_type, active, restart_lsn, confirmed_flush_lsn, wal_retained_mb, backend_pid, bytes_retained,
+			xmin_age, catalog_xmin_age, wal_status, safe_wal_size_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULLIF($14, ''), $15)
 		ON CONFLICT DO NOTHING
 	`)
 	if err != nil {
@@ -100,8 +104,10 @@ func (p *PostgresDB) StoreReplicationSlots(ctx context.Context, slots []*models.
 	}
 	defer func() { _ = stmt.Close() }()
 
+	sampledAt := time.Now()
 	for _, s := range slots {
-		if _, err := stmt.ExecContext(ctx, time.Now(), s.CollectorID, s.DatabaseName, s.SlotName, s.SlotType, s.Active, s.RestartLsn, s.ConfirmedFlushLsn, s.WalRetainedMb, s.BackendPid, s.BytesRetained); err != nil {
+		if _, err := stmt.ExecContext(ctx, snapshotTime(s.Timestamp, sampledAt), s.CollectorID, s.DatabaseName, s.SlotName, s.SlotType, s.Active, s.RestartLsn, s.ConfirmedFlushLsn, s.WalRetainedMb, s.BackendPid, s.BytesRetained,
+			s.XminAge, s.CatalogXminAge, s.WalStatus, s.SafeWalSizeBytes); err != nil {
 			return apperrors.DatabaseError("insert replication slot", err.Error())
 		}
 	}
@@ -109,6 +115,23 @@ func (p *PostgresDB) StoreReplicationSlots(ctx context.Context, slots []*models.
 	return tx.Commit()
 }
 
+// StoreWalPosition stores the WAL insert position of a pg_replication sample
+func (p *PostgresDB) StoreWalPosition(ctx context.Context, pos *models.WalPosition) error {
+	if pos == nil || pos.CurrentWalLsn == "" {
+		return nil
+	}
+
+	_, err := p.db.ExecContext(ctx, `
+		INSERT INTO metrics_wal_position (time, collector_id, current_wal_lsn, wal_directory_bytes, max_slot_wal_keep_size_bytes)
+		VALUES ($1, $2, $3, $4, $5)
+	`, snapshotTime(pos.Time, time.Now()), pos.CollectorID, pos.CurrentWalLsn, pos.WalDirectoryBytes, pos.MaxSlotWalKeepSizeBytes)
+	if err != nil {
+		return apperrors.DatabaseError("insert wal position", err.Error())
+	}
+
+	return nil
+}
+
 // GetReplicationSlots retrieves replication slots for a collector
 func (p *PostgresDB) GetReplicationSlots(ctx context.Context, collectorID uuid.UUID, limit int, offset int) ([]*models.ReplicationSlot, error) {
 	query := `
@@ -165,8 +188,9 @@ func (p *PostgresDB) StoreLogicalSubscriptions(ctx context.Context, subs []*mode
 	}
 	defer func() { _ = stmt.Close() }()
 
+	sampledAt := time.Now()
 	for _, s := range subs {
-		if _, err := stmt.ExecContext(ctx, time.Now(), s.CollectorID, s.DatabaseName, s.SubName, s.SubState, s.SubRecvLsn, s.SubLatestEndLsn, s.SubLastMsgReceiptTime, s.SubWorkerPid); err != nil {
+		if _, err := stmt.ExecContext(ctx, snapshotTime(s.Time, sampledAt), s.CollectorID, s.DatabaseName, s.SubName, s.SubState, s.SubRecvLsn, s.SubLatestEndLsn, s.SubLastMsgReceiptTime, s.SubWorkerPid); err != nil {
 			return apperrors.DatabaseError("insert logical subscription", err.Error())
 		}
 	}
@@ -229,8 +253,9 @@ func (p *PostgresDB) StorePublications(ctx context.Context, pubs []*models.Publi
 	}
 	defer func() { _ = stmt.Close() }()
 
+	sampledAt := time.Now()
 	for _, pub := range pubs {
-		if _, err := stmt.ExecContext(ctx, time.Now(), pub.CollectorID, pub.DatabaseName, pub.PubName, pub.PubOwner, pub.PubAllTables, pub.PubInsert, pub.PubUpdate, pub.PubDelete, pub.PubTruncate); err != nil {
+		if _, err := stmt.ExecContext(ctx, snapshotTime(pub.Time, sampledAt), pub.CollectorID, pub.DatabaseName, pub.PubName, pub.PubOwner, pub.PubAllTables, pub.PubInsert, pub.PubUpdate, pub.PubDelete, pub.PubTruncate); err != nil {
 			return apperrors.DatabaseError("insert publication", err.Error())
 		}
 	}
@@ -293,8 +318,9 @@ func (p *PostgresDB) StoreWalReceivers(ctx context.Context, receivers []*models.
 	}
 	defer func() { _ = stmt.Close() }()
 
+	sampledAt := time.Now()
 	for _, r := range receivers {
-		if _, err := stmt.ExecContext(ctx, time.Now(), r.CollectorID, r.Status, r.SenderHost, r.SenderPort, r.ReceivedLsn, r.LatestEndLsn, r.SlotName, r.ConnInfo); err != nil {
+		if _, err := stmt.ExecContext(ctx, snapshotTime(r.Time, sampledAt), r.CollectorID, r.Status, r.SenderHost, r.SenderPort, r.ReceivedLsn, r.LatestEndLsn, r.SlotName, r.ConnInfo); err != nil {
 			return apperrors.DatabaseError("insert wal receiver", err.Error())
 		}
 	}
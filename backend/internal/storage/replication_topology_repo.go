@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ReplicationTopologyRepository reads the replication state of all collectors
+// of a tenant. It only needs a *sql.DB so that background jobs can use it.
+type ReplicationTopologyRepository struct {
+	db *sql.DB
+}
+
+// NewReplicationTopologyRepository creates a new ReplicationTopologyRepository
+func NewReplicationTopologyRepository(db *sql.DB) *ReplicationTopologyRepository {
+	return &ReplicationTopologyRepository{db: db}
+}
+
+// GetReplicationNodes returns every collector of a tenant with the latest WAL
+// receiver, walsender batch, subscription batch and node state it reported
+// between from and to. A collector without a WAL receiver in that window is
+// not a standby.
+func (r *ReplicationTopologyRepository) GetReplicationNodes(ctx context.Context, tenantID uuid.UUID, from, to time.Time) ([]*models.ReplicationNodeSnapshot, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, hostname, COALESCE(address, ''), last_seen
+		FROM collectors
+		WHERE tenant_id = $1
+		ORDER BY name, id
+	`, tenantID)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query tenant collectors", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var nodes []*models.ReplicationNodeSnapshot
+	byID := make(map[uuid.UUID]*models.ReplicationNodeSnapshot)
+	for rows.Next() {
+		n := &models.ReplicationNodeSnapshot{
+			Senders:       []*models.ReplicationStatus{},
+			Subscriptions: []*models.LogicalSubscription{},
+		}
+		if err := rows.Scan(&n.CollectorID, &n.Name, &n.Hostname, &n.Address, &n.LastSeen); err != nil {
+			return nil, apperrors.DatabaseError("scan tenant collector", err.Error())
+		}
+		nodes = append(nodes, n)
+		byID[n.CollectorID] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query tenant collectors", err.Error())
+	}
+	if len(nodes) == 0 {
+		return nodes, nil
+	}
+
+	if err := r.loadWalReceivers(ctx, tenantID, from, to, byID); err != nil {
+		return nil, err
+	}
+	if err := r.loadSenders(ctx, tenantID, from, to, byID); err != nil {
+		return nil, err
+	}
+	if err := r.loadSubscriptions(ctx, tenantID, from, to, byID); err != nil {
+		return nil, err
+	}
+	if err := r.loadNodeStates(ctx, tenantID, from, to, byID); err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+func (r *ReplicationTopologyRepository) loadWalReceivers(ctx context.Context, tenantID uuid.UUID, from, to time.Time, byID map[uuid.UUID]*models.ReplicationNodeSnapshot) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (w.collector_id)
+			w.collector_id, w.time, COALESCE(w.status, ''), COALESCE(w.sender_host, ''), COALESCE(w.sender_port, 0),
+			COALESCE(w.received_lsn, ''), COALESCE(w.latest_end_lsn, ''), COALESCE(w.slot_name, ''), COALESCE(w.conn_info, '')
+		FROM metrics_wal_receivers w
+		JOIN collectors c ON c.id = w.collector_id
+		WHERE c.tenant_id = $1 AND w.time >= $2 AND w.time <= $3
+		ORDER BY w.collector_id, w.time DESC
+	`, tenantID, from, to)
+	if err != nil {
+		return apperrors.DatabaseError("query wal receivers", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		w := &models.WalReceiver{}
+		if err := rows.Scan(&w.CollectorID, &w.Time, &w.Status, &w.SenderHost, &w.SenderPort,
+			&w.ReceivedLsn, &w.LatestEndLsn, &w.SlotName, &w.ConnInfo); err != nil {
+			return apperrors.DatabaseError("scan wal receiver", err.Error())
+		}
+		if n := byID[w.CollectorID]; n != nil {
+			n.WalReceiver = w
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return apperrors.DatabaseError("query wal receivers", err.Error())
+	}
+	return nil
+}
+
+func (r *ReplicationTopologyRepository) loadSenders(ctx context.Context, tenantID uuid.UUID, from, to time.Time, byID map[uuid.UUID]*models.ReplicationNodeSnapshot) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.collector_id, s.time, COALESCE(s.server_pid, 0), COALESCE(s.usename, ''), COALESCE(s.application_name, ''),
+			COALESCE(s.state, ''), COALESCE(s.sync_state, ''), COALESCE(s.write_lsn, ''), COALESCE(s.flush_lsn, ''), COALESCE(s.replay_lsn, ''),
+			COALESCE(s.write_lag_ms, 0), COALESCE(s.flush_lag_ms, 0), COALESCE(s.replay_lag_ms, 0), COALESCE(s.behind_by_mb, 0),
+			COALESCE(s.client_addr, ''), s.backend_start
+		FROM metrics_replication_status s
+		JOIN collectors c ON c.id = s.collector_id
+		JOIN (
+			SELECT collector_id, MAX(time) AS time
+			FROM metrics_replication_status
+			WHERE time >= $2 AND time <= $3
+			GROUP BY collector_id
+		) latest ON latest.collector_id = s.collector_id AND latest.time = s.time
+		WHERE c.tenant_id = $1
+		ORDER BY s.collector_id, s.application_name, s.server_pid
+	`, tenantID, from, to)
+	if err != nil {
+		return apperrors.DatabaseError("query walsenders", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		s := &models.ReplicationStatus{}
+		if err := rows.Scan(&s.CollectorID, &s.Timestamp, &s.ServerPID, &s.Usename, &s.ApplicationName,
+			&s.State, &s.SyncState, &s.WriteLsn, &s.FlushLsn, &s.ReplayLsn,
+			&s.WriteLagMs, &s.FlushLagMs, &s.ReplayLagMs, &s.BehindByMb,
+			&s.ClientAddr, &s.BackendStart); err != nil {
+			return apperrors.DatabaseError("scan walsender", err.Error())
+		}
+		if n := byID[s.CollectorID]; n != nil {
+			n.Senders = append(n.Senders, s)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return apperrors.DatabaseError("query walsenders", err.Error())
+	}
+	return nil
+}
+
+func (r *ReplicationTopologyRepository) loadSubscriptions(ctx context.Context, tenantID uuid.UUID, from, to time.Time, byID map[uuid.UUID]*models.ReplicationNodeSnapshot) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.collector_id, s.time, COALESCE(s.database_name, ''), COALESCE(s.sub_name, ''), COALESCE(s.sub_state, ''),
+			COALESCE(s.sub_recv_lsn, ''), COALESCE(s.sub_latest_end_lsn, ''), s.sub_last_msg_receipt_time, COALESCE(s.sub_worker_pid, 0)
+		FROM metrics_logical_subscriptions s
+		JOIN collectors c ON c.id = s.collector_id
+		JOIN (
+			SELECT collector_id, MAX(time) AS time
+			FROM metrics_logical_subscriptions
+			WHERE time >= $2 AND time <= $3
+			GROUP BY collector_id
+		) latest ON latest.collector_id = s.collector_id AND latest.time = s.time
+		WHERE c.tenant_id = $1
+		ORDER BY s.collector_id, s.sub_name
+	`, tenantID, from, to)
+	if err != nil {
+		return apperrors.DatabaseError("query logical subscriptions", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		s := &models.LogicalSubscription{}
+		if err := rows.Scan(&s.CollectorID, &s.Time, &s.DatabaseName, &s.SubName, &s.SubState,
+			&s.SubRecvLsn, &s.SubLatestEndLsn, &s.SubLastMsgReceiptTime, &s.SubWorkerPid); err != nil {
+			return apperrors.DatabaseError("scan logical subscription", err.Error())
+		}
+		if n := byID[s.CollectorID]; n != nil {
+			n.Subscriptions = append(n.Subscriptions, s)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return apperrors.DatabaseError("query logical subscriptions", err.Error())
+	}
+	return nil
+}
+
+func (r *ReplicationTopologyRepository) loadNodeStates(ctx context.Context, tenantID uuid.UUID, from, to time.Time, byID map[uuid.UUID]*models.ReplicationNodeSnapshot) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (n.collector_id) `+nodeStateColumns+`
+		FROM metrics_node_state n
+		JOIN collectors c ON c.id = n.collector_id
+		WHERE c.tenant_id = $1 AND n.time >= $2 AND n.time <= $3
+		ORDER BY n.collector_id, n.time DESC
+	`, tenantID, from, to)
+	if err != nil {
+		return apperrors.DatabaseError("query node states", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		state, err := scanNodeState(rows)
+		if err != nil {
+			return err
+		}
+		if n := byID[state.CollectorID]; n != nil {
+			n.State = state
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return apperrors.DatabaseError("query node states", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,403 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// RETENTION OPERATIONS
+// ============================================================================
+
+// retentionTable describes where a data class is stored and how its rows
+// are summarized into hourly rollups. Rollup metrics ending in _max merge by
+// taking the greatest value, all others are sums; cumulative counters such as
+// pg_stat_statements calls are kept as their maximum within the hour.
+type retentionTable struct {
+	table      string
+	timeColumn string
+	// rollup selects collector_id, bucket, dimension_key, dimensions and
+	// metrics from the rows of alias t matching the window filter
+	rollup string
+}
+
+var retentionTables = map[string]retentionTable{
+	models.RetentionClassRawLogs: {
+		table:      "pganalytics.postgresql_logs",
+		timeColumn: "log_timestamp",
+		rollup: `
+			SELECT t.collector_id, date_trunc('hour', t.log_timestamp) AS bucket,
+			       t.log_level || '|' || COALESCE(t.database_name, '') AS dimension_key,
+			       jsonb_build_object('log_level', t.log_level, 'database_name', t.database_name),
+			       jsonb_build_object('count', COUNT(*), 'errors_with_code', COUNT(t.error_code))
+			FROM pganalytics.postgresql_logs t
+			WHERE %s
+			GROUP BY t.collector_id, bucket, t.log_level, t.database_name`,
+	},
+	models.RetentionClassQueryStats: {
+		table:      "metrics_pg_stats_query",
+		timeColumn: "time",
+		rollup: `
+			SELECT t.collector_id, date_trunc('hour', t.time) AS bucket,
+			       t.database_name || '|' || t.query_hash AS dimension_key,
+			       jsonb_build_object('database_name', t.database_name, 'query_hash', t.query_hash),
+			       jsonb_build_object(
+			           'samples', COUNT(*),
+			           'calls_max', MAX(t.calls),
+			           'total_time_max', MAX(t.total_time),
+			           'rows_max', MAX(t.rows),
+			           'shared_blks_hit_max', MAX(t.shared_blks_hit),
+			           'shared_blks_read_max', MAX(t.shared_blks_read),
+			           'mean_time_sum', SUM(t.mean_time),
+			           'max_time_max', MAX(t.max_time))
+			FROM metrics_pg_stats_query t
+			WHERE %s
+			GROUP BY t.collector_id, bucket, t.database_name, t.query_hash`,
+	},
+	models.RetentionClassHostMetrics: {
+		table:      "metrics_host_metrics",
+		timeColumn: "time",
+		rollup: `
+			SELECT t.collector_id, date_trunc('hour', t.time) AS bucket,
+			       '' AS dimension_key, '{}'::jsonb,
+			       jsonb_build_object(
+			           'samples', COUNT(*),
+			           'cpu_user_sum', COALESCE(SUM(t.cpu_user), 0),
+			           'cpu_system_sum', COALESCE(SUM(t.cpu_system), 0),
+			           'cpu_iowait_sum', COALESCE(SUM(t.cpu_iowait), 0),
+			           'cpu_load_1m_max', MAX(t.cpu_load_1m),
+			           'memory_used_percent_sum', COALESCE(SUM(t.memory_used_percent), 0),
+			           'memory_used_percent_max', MAX(t.memory_used_percent),
+			           'disk_used_percent_max', MAX(t.disk_used_percent))
+			FROM metrics_host_metrics t
+			WHERE %s
+			GROUP BY t.collector_id, bucket`,
+	},
+	models.RetentionClassExplainPlans: {
+		table:      "explain_plans",
+		timeColumn: "collected_at",
+		rollup: `
+			SELECT t.collector_id, date_trunc('hour', t.collected_at) AS bucket,
+			       t.query_hash::text AS dimension_key,
+			       jsonb_build_object('query_hash', t.query_hash),
+			       jsonb_build_object(
+			           'plans', COUNT(*),
+			           'seq_scan_plans', COUNT(*) FILTER (WHERE t.has_seq_scan),
+			           'execution_ms_sum', COALESCE(SUM(t.execution_duration_ms), 0),
+			           'execution_ms_max', MAX(t.execution_duration_ms))
+			FROM explain_plans t
+			WHERE %s
+			GROUP BY t.collector_id, bucket, t.query_hash`,
+	},
+}
+
+// retentionFilter selects a tenant's rows of alias t in [$2, $3)
+func retentionFilter(rt retentionTable) string {
+	return `t.collector_id IN (SELECT id FROM collectors WHERE tenant_id = $1)
+		AND t.` + rt.timeColumn + ` >= $2 AND t.` + rt.timeColumn + ` < $3`
+}
+
+// mergeRollupMetrics merges the metrics of a rollup written again for the
+// same hour, e.g. for rows that arrived late
+const mergeRollupMetrics = `(
+	SELECT jsonb_object_agg(k, CASE
+		WHEN k LIKE '%\_max' THEN to_jsonb(GREATEST((data_rollups_hourly.metrics->>k)::numeric, (EXCLUDED.metrics->>k)::numeric))
+		ELSE to_jsonb(COALESCE((data_rollups_hourly.metrics->>k)::numeric, 0) + COALESCE((EXCLUDED.metrics->>k)::numeric, 0))
+	END)
+	FROM jsonb_object_keys(data_rollups_hourly.metrics || EXCLUDED.metrics) AS k
+)`
+
+// GetRetentionPolicies returns a tenant's retention policies
+func (p *PostgresDB) GetRetentionPolicies(ctx context.Context, tenantID uuid.UUID) ([]*models.RetentionPolicy, error) {
+	return p.queryRetentionPolicies(ctx, `WHERE tenant_id = $1`, tenantID)
+}
+
+// ListRetentionPolicies returns the retention policies of every tenant
+func (p *PostgresDB) ListRetentionPolicies(ctx context.Context) ([]*models.RetentionPolicy, error) {
+	return p.queryRetentionPolicies(ctx, ``)
+}
+
+func (p *PostgresDB) queryRetentionPolicies(ctx context.Context, where string, args ...interface{}) ([]*models.RetentionPolicy, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT tenant_id, data_class, retention_days, rollup, archive, rollup_retention_days, updated_at
+		FROM retention_policies `+where+`
+		ORDER BY tenant_id, data_class
+	`, args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query retention policies", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var policies []*models.RetentionPolicy
+	for rows.Next() {
+		policy := &models.RetentionPolicy{}
+		var rollupRetention sql.NullInt64
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&policy.TenantID, &policy.DataClass, &policy.RetentionDays, &policy.Rollup,
+			&policy.Archive, &rollupRetention, &updatedAt); err != nil {
+			return nil, apperrors.DatabaseError("scan retention policy", err.Error())
+		}
+		if rollupRetention.Valid {
+			days := int(rollupRetention.Int64)
+			policy.RollupRetentionDays = &days
+		}
+		if updatedAt.Valid {
+			t := updatedAt.Time
+			policy.UpdatedAt = &t
+		}
+		policies = append(policies, policy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query retention policies", err.Error())
+	}
+
+	return policies, nil
+}
+
+// UpsertRetentionPolicy creates or updates a tenant's policy for a data class
+func (p *PostgresDB) UpsertRetentionPolicy(ctx context.Context, policy *models.RetentionPolicy) error {
+	var updatedAt time.Time
+	err := p.db.QueryRowContext(ctx, `
+		INSERT INTO retention_policies (tenant_id, data_class, retention_days, rollup, archive, rollup_retention_days, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (tenant_id, data_class) DO UPDATE SET
+			retention_days = EXCLUDED.retention_days,
+			rollup = EXCLUDED.rollup,
+			archive = EXCLUDED.archive,
+			rollup_retention_days = EXCLUDED.rollup_retention_days,
+			updated_at = NOW()
+		RETURNING updated_at
+	`, policy.TenantID, policy.DataClass, policy.RetentionDays, policy.Rollup, policy.Archive,
+		policy.RollupRetentionDays).Scan(&updatedAt)
+	if err != nil {
+		return apperrors.DatabaseError("upsert retention policy", err.Error())
+	}
+
+	policy.UpdatedAt = &updatedAt
+	return nil
+}
+
+// DeleteRetentionPolicy removes a tenant's policy for a data class, after
+// which that data is kept indefinitely
+func (p *PostgresDB) DeleteRetentionPolicy(ctx context.Context, tenantID uuid.UUID, dataClass string) error {
+	result, err := p.db.ExecContext(ctx,
+		`DELETE FROM retention_policies WHERE tenant_id = $1 AND data_class = $2`, tenantID, dataClass)
+	if err != nil {
+		return apperrors.DatabaseError("delete retention policy", err.Error())
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return apperrors.NotFound("Retention policy not found", dataClass)
+	}
+	return nil
+}
+
+// GetOldestRetainedTime returns the time of a tenant's oldest row of a data
+// class before a cutoff, or nil when there is none
+func (p *PostgresDB) GetOldestRetainedTime(ctx context.Context, tenantID uuid.UUID, dataClass string, before time.Time) (*time.Time, error) {
+	rt, ok := retentionTables[dataClass]
+	if !ok {
+		return nil, apperrors.BadRequest("Invalid data class", dataClass)
+	}
+
+	var oldest sql.NullTime
+	err := p.db.QueryRowContext(ctx, `
+		SELECT MIN(t.`+rt.timeColumn+`) FROM `+rt.table+` t
+		WHERE t.collector_id IN (SELECT id FROM collectors WHERE tenant_id = $1)
+		AND t.`+rt.timeColumn+` < $2
+	`, tenantID, before).Scan(&oldest)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query oldest retained row", err.Error())
+	}
+	if !oldest.Valid {
+		return nil, nil
+	}
+	return &oldest.Time, nil
+}
+
+// ExpireRetentionWindow removes a tenant's rows of a data class within a
+// window in one transaction. When the window asks for it the rows are first
+// rolled up into hourly summaries; when archive is set it receives a
+// function writing the rows as NDJSON and must make them durable before
+// returning, otherwise nothing is deleted.
+func (p *PostgresDB) ExpireRetentionWindow(ctx context.Context, window models.RetentionWindow, archive func(writeRows func(w io.Writer) error) error) (deleted, rollups int64, err error) {
+	rt, ok := retentionTables[window.DataClass]
+	if !ok {
+		return 0, 0, apperrors.BadRequest("Invalid data class", window.DataClass)
+	}
+	filter := retentionFilter(rt)
+	args := []interface{}{window.TenantID, window.From, window.To}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if window.Rollup {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO data_rollups_hourly (tenant_id, data_class, collector_id, bucket, dimension_key, dimensions, metrics)
+			SELECT $1, '`+window.DataClass+`', r.* FROM (`+fmt.Sprintf(rt.rollup, filter)+`) r
+			ON CONFLICT (tenant_id, data_class, collector_id, bucket, dimension_key) DO UPDATE SET
+				dimensions = EXCLUDED.dimensions,
+				metrics = `+mergeRollupMetrics, args...)
+		if err != nil {
+			return 0, 0, apperrors.DatabaseError("roll up expired rows", err.Error())
+		}
+		rollups, _ = result.RowsAffected()
+	}
+
+	if archive != nil {
+		err := archive(func(w io.Writer) error {
+			rows, err := tx.QueryContext(ctx, `
+				SELECT row_to_json(t)::text FROM `+rt.table+` t
+				WHERE `+filter+`
+				ORDER BY t.`+rt.timeColumn, args...)
+			if err != nil {
+				return apperrors.DatabaseError("query expired rows", err.Error())
+			}
+			defer func() { _ = rows.Close() }()
+
+			for rows.Next() {
+				var line []byte
+				if err := rows.Scan(&line); err != nil {
+					return apperrors.DatabaseError("scan expired row", err.Error())
+				}
+				if _, err := w.Write(append(line, '\n')); err != nil {
+					return err
+				}
+			}
+			if err := rows.Err(); err != nil {
+				return apperrors.DatabaseError("query expired rows", err.Error())
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM `+rt.table+` t WHERE `+filter, args...)
+	if err != nil {
+		return 0, 0, apperrors.DatabaseError("delete expired rows", err.Error())
+	}
+	deleted, _ = result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, apperrors.DatabaseError("commit retention window", err.Error())
+	}
+
+	return deleted, rollups, nil
+}
+
+// DeleteExpiredRollups removes a tenant's hourly rollups of a data class
+// older than a cutoff
+func (p *PostgresDB) DeleteExpiredRollups(ctx context.Context, tenantID uuid.UUID, dataClass string, before time.Time) (int64, error) {
+	result, err := p.db.ExecContext(ctx, `
+		DELETE FROM data_rollups_hourly
+		WHERE tenant_id = $1 AND data_class = $2 AND bucket < $3
+	`, tenantID, dataClass, before)
+	if err != nil {
+		return 0, apperrors.DatabaseError("delete expired rollups", err.Error())
+	}
+	n, _ := result.RowsAffected()
+	return n, nil
+}
+
+// RecordRetentionRun stores the outcome of expiring a window
+func (p *PostgresDB) RecordRetentionRun(ctx context.Context, run *models.RetentionRun) error {
+	err := p.db.QueryRowContext(ctx, `
+		INSERT INTO retention_runs (tenant_id, data_class, window_start, window_end, rows_deleted,
+			rollups_written, archive_key, archive_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`, run.TenantID, run.DataClass, run.WindowStart, run.WindowEnd, run.RowsDeleted,
+		run.RollupsWritten, run.ArchiveKey, run.ArchiveBytes).Scan(&run.ID, &run.CreatedAt)
+	if err != nil {
+		return apperrors.DatabaseError("record retention run", err.Error())
+	}
+	return nil
+}
+
+// ListRetentionRuns returns a tenant's most recent retention runs
+func (p *PostgresDB) ListRetentionRuns(ctx context.Context, tenantID uuid.UUID, limit int) ([]*models.RetentionRun, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT id, tenant_id, data_class, window_start, window_end, rows_deleted, rollups_written,
+		       archive_key, archive_bytes, created_at
+		FROM retention_runs
+		WHERE tenant_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`, tenantID, limit)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query retention runs", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var runs []*models.RetentionRun
+	for rows.Next() {
+		run := &models.RetentionRun{}
+		if err := rows.Scan(&run.ID, &run.TenantID, &run.DataClass, &run.WindowStart, &run.WindowEnd,
+			&run.RowsDeleted, &run.RollupsWritten, &run.ArchiveKey, &run.ArchiveBytes, &run.CreatedAt); err != nil {
+			return nil, apperrors.DatabaseError("scan retention run", err.Error())
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query retention runs", err.Error())
+	}
+
+	return runs, nil
+}
+
+// GetDataRollups returns a tenant's hourly rollups of a data class in
+// [from, to), optionally for one collector
+func (p *PostgresDB) GetDataRollups(ctx context.Context, tenantID uuid.UUID, dataClass string, collectorID *uuid.UUID, from, to time.Time) ([]*models.DataRollup, error) {
+	query := `
+		SELECT data_class, collector_id, bucket, dimensions, metrics
+		FROM data_rollups_hourly
+		WHERE tenant_id = $1 AND data_class = $2 AND bucket >= $3 AND bucket < $4`
+	args := []interface{}{tenantID, dataClass, from, to}
+	if collectorID != nil {
+		query += ` AND collector_id = $5`
+		args = append(args, *collectorID)
+	}
+	query += ` ORDER BY bucket, collector_id, dimension_key LIMIT 10000`
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query data rollups", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var rollups []*models.DataRollup
+	for rows.Next() {
+		rollup := &models.DataRollup{}
+		var dimensions, metrics []byte
+		if err := rows.Scan(&rollup.DataClass, &rollup.CollectorID, &rollup.Bucket, &dimensions, &metrics); err != nil {
+			return nil, apperrors.DatabaseError("scan data rollup", err.Error())
+		}
+		if err := json.Unmarshal(dimensions, &rollup.Dimensions); err != nil {
+			return nil, apperrors.DatabaseError("decode rollup dimensions", err.Error())
+		}
+		if err := json.Unmarshal(metrics, &rollup.Metrics); err != nil {
+			return nil, apperrors.DatabaseError("decode rollup metrics", err.Error())
+		}
+		rollups = append(rollups, rollup)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query data rollups", err.Error())
+	}
+
+	return rollups, nil
+}
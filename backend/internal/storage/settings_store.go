@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// SERVER SETTINGS OPERATIONS
+// ============================================================================
+
+// settingColumns are the columns of pg_settings_current in scan order, after
+// collector_id and collected_at
+const settingColumns = `name, setting, COALESCE(unit, ''), COALESCE(vartype, ''), COALESCE(context, ''),
+	COALESCE(source, ''), COALESCE(boot_val, ''), pending_restart, changed_at`
+
+func scanPgSetting(row rowScanner, extra ...interface{}) (*models.PgSetting, error) {
+	s := &models.PgSetting{}
+	dest := append(extra, &s.Name, &s.Setting, &s.Unit, &s.VarType, &s.Context,
+		&s.Source, &s.BootVal, &s.PendingRestart, &s.ChangedAt)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetCurrentSettings returns the latest settings snapshot of a collector. A
+// collector that has not reported settings has none.
+func (p *PostgresDB) GetCurrentSettings(ctx context.Context, collectorID uuid.UUID) (*models.CollectorSettings, error) {
+	all, err := p.GetSettingsForCollectors(ctx, []uuid.UUID{collectorID})
+	if err != nil {
+		return nil, err
+	}
+	return all[0], nil
+}
+
+// GetSettingsForCollectors returns the latest settings snapshot of each
+// collector, in the order given, with the collector's name
+func (p *PostgresDB) GetSettingsForCollectors(ctx context.Context, collectorIDs []uuid.UUID) ([]*models.CollectorSettings, error) {
+	byID := make(map[uuid.UUID]*models.CollectorSettings, len(collectorIDs))
+	all := make([]*models.CollectorSettings, 0, len(collectorIDs))
+	ids := make([]string, 0, len(collectorIDs))
+	for _, id := range collectorIDs {
+		if _, ok := byID[id]; ok {
+			continue
+		}
+		cs := &models.CollectorSettings{CollectorID: id, Settings: []*models.PgSetting{}}
+		byID[id] = cs
+		all = append(all, cs)
+		ids = append(ids, id.String())
+	}
+	if len(ids) == 0 {
+		return all, nil
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT id, name FROM collectors WHERE id = ANY($1::uuid[])
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, apperrors.DatabaseError("get collector names", err.Error())
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			_ = rows.Close()
+			return nil, apperrors.DatabaseError("scan collector name", err.Error())
+		}
+		byID[id].CollectorName = name
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("get collector names", err.Error())
+	}
+
+	rows, err = p.db.QueryContext(ctx, `
+		SELECT collector_id, collected_at, `+settingColumns+`
+		FROM pg_settings_current
+		WHERE collector_id = ANY($1::uuid[])
+		ORDER BY collector_id, name
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, apperrors.DatabaseError("get settings", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var collectorID uuid.UUID
+		var collectedAt time.Time
+		s, err := scanPgSetting(rows, &collectorID, &collectedAt)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan setting", err.Error())
+		}
+		cs := byID[collectorID]
+		if cs.CollectedAt == nil || collectedAt.After(*cs.CollectedAt) {
+			cs.CollectedAt = &collectedAt
+		}
+		cs.Settings = append(cs.Settings, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("get settings", err.Error())
+	}
+
+	return all, nil
+}
+
+// ReplaceSettings stores a collector's settings snapshot in place of the
+// previous one and appends the changes between them
+func (p *PostgresDB) ReplaceSettings(ctx context.Context, collectorID uuid.UUID, at time.Time, settings []*models.PgSetting, changes []*models.PgSettingChange) error {
+	at = snapshotTime(at, time.Now())
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM pg_settings_current WHERE collector_id = $1`, collectorID); err != nil {
+		return apperrors.DatabaseError("clear settings", err.Error())
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO pg_settings_current (collector_id, collected_at, name, setting, unit, vartype, context,
+			source, boot_val, pending_restart, changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`)
+	if err != nil {
+		return apperrors.DatabaseError("prepare settings insert", err.Error())
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, s := range settings {
+		if _, err := stmt.ExecContext(ctx, collectorID, at, s.Name, s.Setting, nullString(s.Unit), nullString(s.VarType),
+			nullString(s.Context), nullString(s.Source), nullString(s.BootVal), s.PendingRestart,
+			snapshotTime(s.ChangedAt, at)); err != nil {
+			return apperrors.DatabaseError("insert setting", err.Error())
+		}
+	}
+
+	for _, change := range changes {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO pg_settings_changes (collector_id, name, old_value, new_value, unit, changed_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`, collectorID, change.Name, change.OldValue, change.NewValue, nullString(change.Unit),
+			snapshotTime(change.ChangedAt, at)).Scan(&change.ID)
+		if err != nil {
+			return apperrors.DatabaseError("insert setting change", err.Error())
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit settings", err.Error())
+	}
+	return nil
+}
+
+// ListSettingChanges returns a collector's setting changes, newest first
+func (p *PostgresDB) ListSettingChanges(ctx context.Context, filter *models.PgSettingChangeFilter) ([]*models.PgSettingChange, error) {
+	query := `
+		SELECT id, collector_id, name, old_value, new_value, COALESCE(unit, ''), changed_at
+		FROM pg_settings_changes
+		WHERE collector_id = $1 AND changed_at >= $2 AND changed_at <= $3
+	`
+	args := []interface{}{filter.CollectorID, filter.From, filter.To}
+	if filter.Name != "" {
+		args = append(args, filter.Name)
+		query += fmt.Sprintf(" AND name = $%d", len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY changed_at DESC, name LIMIT $%d", len(args))
+
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("list setting changes", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	changes := []*models.PgSettingChange{}
+	for rows.Next() {
+		c := &models.PgSettingChange{}
+		var oldValue, newValue sql.NullString
+		if err := rows.Scan(&c.ID, &c.CollectorID, &c.Name, &oldValue, &newValue, &c.Unit, &c.ChangedAt); err != nil {
+			return nil, apperrors.DatabaseError("scan setting change", err.Error())
+		}
+		if oldValue.Valid {
+			c.OldValue = &oldValue.String
+		}
+		if newValue.Valid {
+			c.NewValue = &newValue.String
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("list setting changes", err.Error())
+	}
+
+	return changes, nil
+}
+
+// GetClusterCollectorIDs returns the system identifier of a collector's
+// server and the collectors whose latest node state within a day shares it,
+// the members of its replication cluster. Without a known identifier the
+// collector is alone.
+func (p *PostgresDB) GetClusterCollectorIDs(ctx context.Context, collectorID uuid.UUID) (string, []uuid.UUID, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		WITH latest AS (
+			SELECT DISTINCT ON (collector_id) collector_id, system_identifier
+			FROM metrics_node_state
+			WHERE time > NOW() - INTERVAL '1 day'
+			ORDER BY collector_id, time DESC
+		), own AS (
+			SELECT system_identifier FROM latest WHERE collector_id = $1 AND system_identifier IS NOT NULL
+		)
+		SELECT l.collector_id, l.system_identifier
+		FROM latest l, own
+		WHERE l.system_identifier = own.system_identifier
+		ORDER BY l.collector_id = $1 DESC, l.collector_id
+	`, collectorID)
+	if err != nil {
+		return "", nil, apperrors.DatabaseError("get cluster collectors", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var systemIdentifier string
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id, &systemIdentifier); err != nil {
+			return "", nil, apperrors.DatabaseError("scan cluster collector", err.Error())
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return "", nil, apperrors.DatabaseError("get cluster collectors", err.Error())
+	}
+
+	return systemIdentifier, ids, nil
+}
+
+// GetCollectorGroupMemberIDs returns the collectors of a tenant's group
+func (p *PostgresDB) GetCollectorGroupMemberIDs(ctx context.Context, tenantID uuid.UUID, groupID int64) ([]uuid.UUID, error) {
+	var exists bool
+	err := p.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM collector_groups WHERE tenant_id = $1 AND id = $2)`, tenantID, groupID,
+	).Scan(&exists)
+	if err != nil {
+		return nil, apperrors.DatabaseError("find collector group", err.Error())
+	}
+	if !exists {
+		return nil, apperrors.NotFound("Collector group not found", fmt.Sprintf("id: %d", groupID))
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT collector_id FROM collector_group_members WHERE group_id = $1 ORDER BY collector_id
+	`, groupID)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get collector group members", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, apperrors.DatabaseError("scan collector group member", err.Error())
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("get collector group members", err.Error())
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// SlotRiskRepository reads the replication slot, WAL position and host disk
+// samples a slot risk assessment needs. It only needs a *sql.DB so that the
+// alert rule engine can use it.
+type SlotRiskRepository struct {
+	db *sql.DB
+}
+
+// NewSlotRiskRepository creates a new SlotRiskRepository
+func NewSlotRiskRepository(db *sql.DB) *SlotRiskRepository {
+	return &SlotRiskRepository{db: db}
+}
+
+// GetWalPosition returns the latest WAL position at or before at, within an
+// hour of it, or nil when there is none
+func (r *SlotRiskRepository) GetWalPosition(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.WalPosition, error) {
+	return r.queryWalPosition(ctx, `
+		SELECT time, collector_id, current_wal_lsn, wal_directory_bytes, max_slot_wal_keep_size_bytes
+		FROM metrics_wal_position
+		WHERE collector_id = $1 AND time <= $2 AND time > $2 - INTERVAL '1 hour'
+		ORDER BY time DESC
+		LIMIT 1
+	`, collectorID, at)
+}
+
+// GetWalPositionBaseline returns the oldest WAL position in [from, before),
+// the baseline of the WAL rate, or nil when there is none
+func (r *SlotRiskRepository) GetWalPositionBaseline(ctx context.Context, collectorID uuid.UUID, from, before time.Time) (*models.WalPosition, error) {
+	return r.queryWalPosition(ctx, `
+		SELECT time, collector_id, current_wal_lsn, wal_directory_bytes, max_slot_wal_keep_size_bytes
+		FROM metrics_wal_position
+		WHERE collector_id = $1 AND time >= $2 AND time < $3
+		ORDER BY time
+		LIMIT 1
+	`, collectorID, from, before)
+}
+
+func (r *SlotRiskRepository) queryWalPosition(ctx context.Context, query string, args ...interface{}) (*models.WalPosition, error) {
+	pos := &models.WalPosition{}
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&pos.Time, &pos.CollectorID, &pos.CurrentWalLsn,
+		&pos.WalDirectoryBytes, &pos.MaxSlotWalKeepSizeBytes)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, apperrors.DatabaseError("get wal position", err.Error())
+	}
+	return pos, nil
+}
+
+// GetSlots returns the slots of the latest sample at or before at, within an
+// hour of it
+func (r *SlotRiskRepository) GetSlots(ctx context.Context, collectorID uuid.UUID, at time.Time) ([]*models.ReplicationSlot, error) {
+	return r.querySlots(ctx, `
+		SELECT `+slotRiskColumns+`
+		FROM metrics_replication_slots
+		WHERE collector_id = $1 AND time = (
+			SELECT MAX(time) FROM metrics_replication_slots
+			WHERE collector_id = $1 AND time <= $2 AND time > $2 - INTERVAL '1 hour'
+		)
+		ORDER BY slot_name
+	`, collectorID, at)
+}
+
+// GetSlotsBaseline returns the slots of the oldest sample in [from, before)
+func (r *SlotRiskRepository) GetSlotsBaseline(ctx context.Context, collectorID uuid.UUID, from, before time.Time) ([]*models.ReplicationSlot, error) {
+	return r.querySlots(ctx, `
+		SELECT `+slotRiskColumns+`
+		FROM metrics_replication_slots
+		WHERE collector_id = $1 AND time = (
+			SELECT MIN(time) FROM metrics_replication_slots
+			WHERE collector_id = $1 AND time >= $2 AND time < $3
+		)
+		ORDER BY slot_name
+	`, collectorID, from, before)
+}
+
+const slotRiskColumns = `time, collector_id, COALESCE(database_name, ''), slot_name, COALESCE(slot_type, ''), COALESCE(active, false),
+	COALESCE(restart_lsn, ''), COALESCE(confirmed_flush_lsn, ''), COALESCE(wal_retained_mb, 0), COALESCE(backend_pid, 0), COALESCE(bytes_retained, 0),
+	xmin_age, catalog_xmin_age, COALESCE(wal_status, ''), safe_wal_size_bytes`
+
+func (r *SlotRiskRepository) querySlots(ctx context.Context, query string, args ...interface{}) ([]*models.ReplicationSlot, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query replication slots", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var slots []*models.ReplicationSlot
+	for rows.Next() {
+		s := &models.ReplicationSlot{}
+		if err := rows.Scan(&s.Timestamp, &s.CollectorID, &s.DatabaseName, &s.SlotName, &s.SlotType, &s.Active,
+			&s.RestartLsn, &s.ConfirmedFlushLsn, &s.WalRetainedMb, &s.BackendPid, &s.BytesRetained,
+			&s.XminAge, &s.CatalogXminAge, &s.WalStatus, &s.SafeWalSizeBytes); err != nil {
+			return nil, apperrors.DatabaseError("scan replication slot", err.Error())
+		}
+		slots = append(slots, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query replication slots", err.Error())
+	}
+
+	return slots, nil
+}
+
+// GetSlotLastActive returns, per slot name, the last time between from and to
+// a sample saw the slot active
+func (r *SlotRiskRepository) GetSlotLastActive(ctx context.Context, collectorID uuid.UUID, from, to time.Time) (map[string]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT slot_name, MAX(time)
+		FROM metrics_replication_slots
+		WHERE collector_id = $1 AND time >= $2 AND time <= $3 AND active
+		GROUP BY slot_name
+	`, collectorID, from, to)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query slot activity", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	lastActive := make(map[string]time.Time)
+	for rows.Next() {
+		var name string
+		var t time.Time
+		if err := rows.Scan(&name, &t); err != nil {
+			return nil, apperrors.DatabaseError("scan slot activity", err.Error())
+		}
+		lastActive[name] = t
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query slot activity", err.Error())
+	}
+
+	return lastActive, nil
+}
+
+// GetHostDisk returns the latest host metrics at or before at, within an
+// hour of it, or nil when the host reports none
+func (r *SlotRiskRepository) GetHostDisk(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.HostMetrics, error) {
+	m := &models.HostMetrics{CollectorID: collectorID}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT time, COALESCE(disk_total_gb, 0), COALESCE(disk_used_gb, 0), COALESCE(disk_free_gb, 0), COALESCE(disk_used_percent, 0)
+		FROM metrics_host_metrics
+		WHERE collector_id = $1 AND time <= $2 AND time > $2 - INTERVAL '1 hour'
+		ORDER BY time DESC
+		LIMIT 1
+	`, collectorID, at).Scan(&m.Time, &m.DiskTotalGb, &m.DiskUsedGb, &m.DiskFreeGb, &m.DiskUsedPercent)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, apperrors.DatabaseError("get host disk", err.Error())
+	}
+	return m, nil
+}
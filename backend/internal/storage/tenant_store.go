@@ -2,10 +2,12 @@ package storage
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
 	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 )
@@ -16,7 +18,7 @@ import (
 
 // GetTenantByUserID retrieves the tenant associated with a user
 // For single-tenant mode, returns the first active tenant for the user
-func (p *PostgresDB) GetTenantByUserID(ctx context.Context, userID uuid.UUID) (*models.Tenant, error) {
+func (p *PostgresDB) GetTenantByUserID(ctx context.Context, userID int) (*models.Tenant, error) {
 	query := `
 		SELECT t.id, t.name, t.slug, t.created_at, t.updated_at, t.is_active
 		FROM tenants t
@@ -114,7 +116,7 @@ func (p *PostgresDB) CreateTenant(ctx context.Context, tenant *models.Tenant) er
 }
 
 // AddUserToTenant adds a user to a tenant with a specified role
-func (p *PostgresDB) AddUserToTenant(ctx context.Context, tenantID, userID uuid.UUID, role string) error {
+func (p *PostgresDB) AddUserToTenant(ctx context.Context, tenantID uuid.UUID, userID int, role string) error {
 	query := `
 		INSERT INTO tenant_users (tenant_id, user_id, role, created_at)
 		VALUES ($1, $2, $3, NOW())
@@ -175,7 +177,7 @@ func (p *PostgresDB) GetTenantByID(ctx context.Context, tenantID uuid.UUID) (*mo
 }
 
 // GetTenantsByUserID retrieves all tenants a user belongs to
-func (p *PostgresDB) GetTenantsByUserID(ctx context.Context, userID uuid.UUID) ([]*models.Tenant, error) {
+func (p *PostgresDB) GetTenantsByUserID(ctx context.Context, userID int) ([]*models.Tenant, error) {
 	query := `
 		SELECT t.id, t.name, t.slug, t.created_at, t.updated_at, t.is_active
 		FROM tenants t
@@ -207,7 +209,7 @@ func (p *PostgresDB) GetTenantsByUserID(ctx context.Context, userID uuid.UUID) (
 }
 
 // RemoveUserFromTenant removes a user from a tenant
-func (p *PostgresDB) RemoveUserFromTenant(ctx context.Context, tenantID, userID uuid.UUID) error {
+func (p *PostgresDB) RemoveUserFromTenant(ctx context.Context, tenantID uuid.UUID, userID int) error {
 	query := `
 		DELETE FROM tenant_users
 		WHERE tenant_id = $1 AND user_id = $2
@@ -221,8 +223,9 @@ func (p *PostgresDB) RemoveUserFromTenant(ctx context.Context, tenantID, userID
 	return nil
 }
 
-// GetUserRoleInTenant gets the role of a user in a specific tenant
-func (p *PostgresDB) GetUserRoleInTenant(ctx context.Context, tenantID, userID uuid.UUID) (string, error) {
+// GetUserRoleInTenant gets the role of a user in a specific tenant. It
+// returns a not found error when the user is not a member.
+func (p *PostgresDB) GetUserRoleInTenant(ctx context.Context, tenantID uuid.UUID, userID int) (string, error) {
 	query := `
 		SELECT role FROM tenant_users
 		WHERE tenant_id = $1 AND user_id = $2
@@ -230,9 +233,54 @@ func (p *PostgresDB) GetUserRoleInTenant(ctx context.Context, tenantID, userID u
 
 	var role string
 	err := p.db.QueryRowContext(ctx, query, tenantID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", apperrors.NotFound("tenant member not found", fmt.Sprintf("tenant: %s, user: %d", tenantID, userID))
+	}
 	if err != nil {
 		return "", apperrors.DatabaseError("query user role", err.Error())
 	}
 
 	return role, nil
 }
+
+// GetInstanceIDsByCollectorIDs returns the IDs of the PostgreSQL instances
+// monitored by the given collectors
+func (p *PostgresDB) GetInstanceIDsByCollectorIDs(ctx context.Context, collectorIDs []uuid.UUID) ([]int, error) {
+	if len(collectorIDs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, len(collectorIDs))
+	for i, id := range collectorIDs {
+		ids[i] = id.String()
+	}
+
+	query := `
+		SELECT i.id
+		FROM pganalytics.postgresql_instances i
+		JOIN pganalytics.servers s ON s.id = i.server_id
+		WHERE s.collector_id = ANY($1::uuid[])
+		ORDER BY i.id
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, apperrors.DatabaseError("query instances by collector", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var instanceIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, apperrors.DatabaseError("scan instance id", err.Error())
+		}
+		instanceIDs = append(instanceIDs, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query instances by collector", err.Error())
+	}
+
+	return instanceIDs, nil
+}
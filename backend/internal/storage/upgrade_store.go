@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// UPGRADE READINESS OPERATIONS
+// ============================================================================
+
+// GetLatestExtensionInventory returns the extensions of each database of a
+// collector as of that database's latest inventory
+func (p *PostgresDB) GetLatestExtensionInventory(ctx context.Context, collectorID uuid.UUID) ([]*models.ExtensionInventory, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT e.time, e.collector_id, e.database_name, e.extension_name, e.extension_version,
+			COALESCE(e.extension_owner, ''), COALESCE(e.extension_schema, ''), COALESCE(e.is_relocatable, FALSE),
+			COALESCE(e.description, '')
+		FROM metrics_extension_inventory e
+		JOIN (
+			SELECT database_name, MAX(time) AS time
+			FROM metrics_extension_inventory
+			WHERE collector_id = $1
+			GROUP BY database_name
+		) latest ON latest.database_name = e.database_name AND latest.time = e.time
+		WHERE e.collector_id = $1
+		ORDER BY e.extension_name, e.database_name
+	`, collectorID)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query latest extension inventory", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	extensions := []*models.ExtensionInventory{}
+	for rows.Next() {
+		ext := &models.ExtensionInventory{}
+		err := rows.Scan(
+			&ext.Time, &ext.CollectorID, &ext.DatabaseName, &ext.ExtensionName, &ext.ExtensionVersion,
+			&ext.ExtensionOwner, &ext.ExtensionSchema, &ext.IsRelocatable, &ext.Description,
+		)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan extension inventory", err.Error())
+		}
+		extensions = append(extensions, ext)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query latest extension inventory", err.Error())
+	}
+
+	return extensions, nil
+}
+
+// GetLatestOidTables returns the tables still created WITH OIDS as of each
+// database's latest table inventory
+func (p *PostgresDB) GetLatestOidTables(ctx context.Context, collectorID uuid.UUID) ([]*models.TableInventory, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT t.time, t.collector_id, t.database_name, t.schema_name, t.table_name, COALESCE(t.table_type, ''),
+			COALESCE(t.row_count, 0), COALESCE(t.total_size_mb, 0), COALESCE(t.table_size_mb, 0),
+			COALESCE(t.index_size_mb, 0), COALESCE(t.toast_size_mb, 0), t.has_oids, t.table_oid
+		FROM metrics_table_inventory t
+		JOIN (
+			SELECT database_name, MAX(time) AS time
+			FROM metrics_table_inventory
+			WHERE collector_id = $1
+			GROUP BY database_name
+		) latest ON latest.database_name = t.database_name AND latest.time = t.time
+		WHERE t.collector_id = $1 AND t.has_oids
+		ORDER BY t.database_name, t.schema_name, t.table_name
+	`, collectorID)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query tables with oids", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	tables := []*models.TableInventory{}
+	for rows.Next() {
+		t := &models.TableInventory{}
+		err := rows.Scan(
+			&t.Time, &t.CollectorID, &t.DatabaseName, &t.SchemaName, &t.TableName, &t.TableType,
+			&t.RowCount, &t.TotalSizeMb, &t.TableSizeMb, &t.IndexSizeMb, &t.ToastSizeMb,
+			&t.HasOids, &t.TableOid,
+		)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan table inventory", err.Error())
+		}
+		tables = append(tables, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query tables with oids", err.Error())
+	}
+
+	return tables, nil
+}
+
+// GetLatestColumnsOfTypes returns the columns of the given data types, as
+// information_schema names them, as of each database's latest column
+// inventory
+func (p *PostgresDB) GetLatestColumnsOfTypes(ctx context.Context, collectorID uuid.UUID, dataTypes []string) ([]*models.ColumnInventory, error) {
+	columns := []*models.ColumnInventory{}
+	if len(dataTypes) == 0 {
+		return columns, nil
+	}
+
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT c.time, c.collector_id, c.database_name, c.schema_name, c.table_name, c.column_name, c.data_type
+		FROM metrics_column_inventory c
+		JOIN (
+			SELECT database_name, MAX(time) AS time
+			FROM metrics_column_inventory
+			WHERE collector_id = $1
+			GROUP BY database_name
+		) latest ON latest.database_name = c.database_name AND latest.time = c.time
+		WHERE c.collector_id = $1 AND LOWER(c.data_type) = ANY($2)
+		ORDER BY c.database_name, c.schema_name, c.table_name, c.ordinal_position
+	`, collectorID, pq.Array(dataTypes))
+	if err != nil {
+		return nil, apperrors.DatabaseError("query columns by type", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		col := &models.ColumnInventory{}
+		err := rows.Scan(&col.Time, &col.CollectorID, &col.DatabaseName, &col.SchemaName, &col.TableName,
+			&col.ColumnName, &col.DataType)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan column inventory", err.Error())
+		}
+		columns = append(columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query columns by type", err.Error())
+	}
+
+	return columns, nil
+}
+
+// ReplaceUpgradeObjects stores a collector's pg_upgrade_objects snapshot in
+// place of the previous one
+func (p *PostgresDB) ReplaceUpgradeObjects(ctx context.Context, collectorID uuid.UUID, at time.Time, objects []*models.UpgradeObject) error {
+	at = snapshotTime(at, time.Now())
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM pg_upgrade_objects_current WHERE collector_id = $1`, collectorID); err != nil {
+		return apperrors.DatabaseError("clear upgrade objects", err.Error())
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO pg_upgrade_objects_current (collector_id, database_name, kind, schema_name, object_name, detail, collected_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (collector_id, database_name, kind, schema_name, object_name) DO NOTHING
+	`)
+	if err != nil {
+		return apperrors.DatabaseError("prepare upgrade objects insert", err.Error())
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, o := range objects {
+		if _, err := stmt.ExecContext(ctx, collectorID, o.Database, o.Kind, o.Schema, o.Name, nullString(o.Detail), at); err != nil {
+			return apperrors.DatabaseError("insert upgrade object", err.Error())
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit upgrade objects", err.Error())
+	}
+	return nil
+}
+
+// GetUpgradeObjects returns a collector's latest pg_upgrade_objects snapshot
+func (p *PostgresDB) GetUpgradeObjects(ctx context.Context, collectorID uuid.UUID) ([]*models.UpgradeObject, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT database_name, kind, schema_name, object_name, COALESCE(detail, '')
+		FROM pg_upgrade_objects_current
+		WHERE collector_id = $1
+		ORDER BY kind, database_name, schema_name, object_name
+	`, collectorID)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query upgrade objects", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	objects := []*models.UpgradeObject{}
+	for rows.Next() {
+		o := &models.UpgradeObject{}
+		if err := rows.Scan(&o.Database, &o.Kind, &o.Schema, &o.Name, &o.Detail); err != nil {
+			return nil, apperrors.DatabaseError("scan upgrade object", err.Error())
+		}
+		objects = append(objects, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query upgrade objects", err.Error())
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// ============================================================================
+// VACUUM STATISTICS OPERATIONS
+// ============================================================================
+
+// vacuumTableColumns are the columns of metrics_pg_vacuum_tables in scan order
+const vacuumTableColumns = `time, collector_id, database_name, schema_name, table_name,
+	live_tuples, dead_tuples, mod_since_analyze, ins_since_vacuum,
+	tuples_inserted, tuples_updated, tuples_deleted, tuples_hot_updated,
+	reltuples, relpages, table_size_bytes, bloat_bytes, reloptions,
+	last_vacuum, last_autovacuum, last_analyze, last_autoanalyze,
+	vacuum_count, autovacuum_count, analyze_count, autoanalyze_count`
+
+// StoreVacuumStats inserts one pg_vacuum_stats sample of a database
+// Every row of the sample shares its time, the current time when unset, so
+// that the sample can be read back as one snapshot.
+func (p *PostgresDB) StoreVacuumStats(ctx context.Context, collectorID uuid.UUID, databaseName string, sampledAt time.Time, settings map[string]string, tables []*models.VacuumTableStat) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	sampledAt = snapshotTime(sampledAt, time.Now())
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return apperrors.DatabaseError("begin transaction", err.Error())
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if len(settings) > 0 {
+		settingsJSON, err := json.Marshal(settings)
+		if err != nil {
+			return apperrors.DatabaseError("marshal vacuum settings", err.Error())
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO metrics_pg_vacuum_settings (time, collector_id, database_name, settings)
+			VALUES ($1, $2, $3, $4)
+		`, sampledAt, collectorID, databaseName, settingsJSON); err != nil {
+			return apperrors.DatabaseError("insert vacuum settings", err.Error())
+		}
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO metrics_pg_vacuum_tables (`+vacuumTableColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26)
+	`)
+	if err != nil {
+		return apperrors.DatabaseError("prepare vacuum stats insert", err.Error())
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, t := range tables {
+		reloptions := t.RelOptions
+		if reloptions == nil {
+			reloptions = []string{}
+		}
+		if _, err := stmt.ExecContext(ctx, sampledAt, collectorID, databaseName, t.SchemaName, t.TableName,
+			t.LiveTuples, t.DeadTuples, t.ModSinceAnalyze, t.InsSinceVacuum,
+			t.TuplesInserted, t.TuplesUpdated, t.TuplesDeleted, t.TuplesHotUpdated,
+			t.RelTuples, t.RelPages, t.TableSizeBytes, t.BloatBytes, pq.Array(reloptions),
+			t.LastVacuum, t.LastAutovacuum, t.LastAnalyze, t.LastAutoanalyze,
+			t.VacuumCount, t.AutovacuumCount, t.AnalyzeCount, t.AutoanalyzeCount); err != nil {
+			return apperrors.DatabaseError("insert vacuum stats", err.Error())
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return apperrors.DatabaseError("commit vacuum stats", err.Error())
+	}
+	return nil
+}
+
+// GetVacuumSnapshot returns the latest vacuum statistics sample of a
+// monitored database, with the oldest sample of each table within lookback
+// before it. A database without samples has no tables.
+func (p *PostgresDB) GetVacuumSnapshot(ctx context.Context, databaseID int64, lookback time.Duration) (*models.VacuumSnapshot, error) {
+	snapshot := &models.VacuumSnapshot{
+		DatabaseID: databaseID,
+		Settings:   map[string]string{},
+		Baselines:  map[string]*models.VacuumTableStat{},
+	}
+
+	var collectorID uuid.NullUUID
+	err := p.db.QueryRowContext(ctx, `
+		SELECT d.name, s.collector_id
+		FROM databases d
+		JOIN postgresql_instances i ON i.id = d.instance_id
+		JOIN servers s ON s.id = i.server_id
+		WHERE d.id = $1
+	`, databaseID).Scan(&snapshot.DatabaseName, &collectorID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NotFound("Database not found", fmt.Sprintf("ID: %d", databaseID))
+		}
+		return nil, apperrors.DatabaseError("get database collector", err.Error())
+	}
+	if !collectorID.Valid {
+		return snapshot, nil
+	}
+	snapshot.CollectorID = collectorID.UUID
+
+	var latest sql.NullTime
+	err = p.db.QueryRowContext(ctx, `
+		SELECT MAX(time) FROM metrics_pg_vacuum_tables
+		WHERE collector_id = $1 AND database_name = $2
+	`, snapshot.CollectorID, snapshot.DatabaseName).Scan(&latest)
+	if err != nil {
+		return nil, apperrors.DatabaseError("get vacuum snapshot time", err.Error())
+	}
+	if !latest.Valid {
+		return snapshot, nil
+	}
+	snapshot.CollectedAt = latest.Time
+
+	snapshot.Tables, err = p.queryVacuumTableStats(ctx, `
+		SELECT `+vacuumTableColumns+`
+		FROM metrics_pg_vacuum_tables
+		WHERE collector_id = $1 AND database_name = $2 AND time = $3
+		ORDER BY schema_name, table_name
+	`, snapshot.CollectorID, snapshot.DatabaseName, latest.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	baselines, err := p.queryVacuumTableStats(ctx, `
+		SELECT DISTINCT ON (schema_name, table_name) `+vacuumTableColumns+`
+		FROM metrics_pg_vacuum_tables
+		WHERE collector_id = $1 AND database_name = $2 AND time >= $3 AND time < $4
+		ORDER BY schema_name, table_name, time
+	`, snapshot.CollectorID, snapshot.DatabaseName, latest.Time.Add(-lookback), latest.Time)
+	if err != nil {
+		return nil, err
+	}
+	for _, baseline := range baselines {
+		snapshot.Baselines[baseline.SchemaName+"."+baseline.TableName] = baseline
+	}
+
+	var settingsJSON []byte
+	err = p.db.QueryRowContext(ctx, `
+		SELECT settings FROM metrics_pg_vacuum_settings
+		WHERE collector_id = $1 AND database_name = $2 AND time <= $3
+		ORDER BY time DESC
+		LIMIT 1
+	`, snapshot.CollectorID, snapshot.DatabaseName, latest.Time).Scan(&settingsJSON)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, apperrors.DatabaseError("get vacuum settings", err.Error())
+	}
+	if len(settingsJSON) > 0 {
+		if err := json.Unmarshal(settingsJSON, &snapshot.Settings); err != nil {
+			return nil, apperrors.DatabaseError("unmarshal vacuum settings", err.Error())
+		}
+	}
+
+	return snapshot, nil
+}
+
+// queryVacuumTableStats runs a query selecting vacuumTableColumns
+func (p *PostgresDB) queryVacuumTableStats(ctx context.Context, query string, args ...interface{}) ([]*models.VacuumTableStat, error) {
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query vacuum stats", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var stats []*models.VacuumTableStat
+	for rows.Next() {
+		t := &models.VacuumTableStat{}
+		if err := rows.Scan(&t.Time, &t.CollectorID, &t.DatabaseName, &t.SchemaName, &t.TableName,
+			&t.LiveTuples, &t.DeadTuples, &t.ModSinceAnalyze, &t.InsSinceVacuum,
+			&t.TuplesInserted, &t.TuplesUpdated, &t.TuplesDeleted, &t.TuplesHotUpdated,
+			&t.RelTuples, &t.RelPages, &t.TableSizeBytes, &t.BloatBytes, pq.Array(&t.RelOptions),
+			&t.LastVacuum, &t.LastAutovacuum, &t.LastAnalyze, &t.LastAutoanalyze,
+			&t.VacuumCount, &t.AutovacuumCount, &t.AnalyzeCount, &t.AutoanalyzeCount); err != nil {
+			return nil, apperrors.DatabaseError("scan vacuum stats", err.Error())
+		}
+		stats = append(stats, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query vacuum stats", err.Error())
+	}
+
+	return stats, nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
 	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 )
@@ -15,15 +16,45 @@ import (
 // VERSION HEALTH CHECK OPERATIONS (VER-03)
 // ============================================================================
 
-// GetHealthChecksForVersion retrieves all health checks applicable to a specific PostgreSQL version
+// healthCheckColumns are the columns of a check, selected from
+// postgres_health_checks as hc
+const healthCheckColumns = `hc.id, hc.min_version, hc.max_version, hc.check_name, hc.check_query, COALESCE(hc.expected_result, ''),
+			   hc.severity, COALESCE(hc.description, ''), COALESCE(hc.remediation, ''), COALESCE(hc.category, ''),
+			   hc.assertion_type, COALESCE(hc.assertion_value, ''), hc.pack_id, hc.created_at, hc.updated_at`
+
+func scanVersionHealthCheck(row rowScanner) (*models.VersionHealthCheck, error) {
+	check := &models.VersionHealthCheck{}
+	var maxVersion, packID sql.NullInt64
+
+	err := row.Scan(
+		&check.ID, &check.MinVersion, &maxVersion, &check.CheckName, &check.CheckQuery,
+		&check.ExpectedResult, &check.Severity, &check.Description, &check.Remediation,
+		&check.Category, &check.AssertionType, &check.AssertionValue, &packID, &check.CreatedAt, &check.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle NULL max_version (0 means no upper limit)
+	if maxVersion.Valid {
+		check.MaxVersion = int(maxVersion.Int64)
+	}
+	if packID.Valid {
+		check.PackID = &packID.Int64
+	}
+
+	return check, nil
+}
+
+// GetHealthChecksForVersion retrieves all built-in health checks applicable to a specific PostgreSQL version
 func (p *PostgresDB) GetHealthChecksForVersion(ctx context.Context, pgVersion int) ([]*models.VersionHealthCheck, error) {
 	query := `
-		SELECT id, min_version, max_version, check_name, check_query, expected_result,
-			   severity, description, remediation, category, created_at, updated_at
-		FROM postgres_health_checks
-		WHERE min_version <= $1
-		  AND (max_version IS NULL OR max_version >= $1)
-		ORDER BY severity DESC, category
+		SELECT ` + healthCheckColumns + `
+		FROM postgres_health_checks hc
+		WHERE hc.pack_id IS NULL
+		  AND hc.min_version <= $1
+		  AND (hc.max_version IS NULL OR hc.max_version >= $1)
+		ORDER BY hc.severity DESC, hc.category
 	`
 
 	rows, err := p.db.QueryContext(ctx, query, pgVersion)
@@ -34,46 +65,25 @@ func (p *PostgresDB) GetHealthChecksForVersion(ctx context.Context, pgVersion in
 
 	var checks []*models.VersionHealthCheck
 	for rows.Next() {
-		check := &models.VersionHealthCheck{}
-		var maxVersion sql.NullInt64
-
-		err := rows.Scan(
-			&check.ID, &check.MinVersion, &maxVersion, &check.CheckName, &check.CheckQuery,
-			&check.ExpectedResult, &check.Severity, &check.Description, &check.Remediation,
-			&check.Category, &check.CreatedAt, &check.UpdatedAt,
-		)
+		check, err := scanVersionHealthCheck(rows)
 		if err != nil {
 			return nil, apperrors.DatabaseError("scan health check", err.Error())
 		}
-
-		// Handle NULL max_version (0 means no upper limit)
-		if maxVersion.Valid {
-			check.MaxVersion = int(maxVersion.Int64)
-		}
-
 		checks = append(checks, check)
 	}
 
 	return checks, nil
 }
 
-// GetHealthCheckByID retrieves a single health check by ID
+// GetHealthCheckByID retrieves a single built-in health check by ID
 func (p *PostgresDB) GetHealthCheckByID(ctx context.Context, id int) (*models.VersionHealthCheck, error) {
 	query := `
-		SELECT id, min_version, max_version, check_name, check_query, expected_result,
-			   severity, description, remediation, category, created_at, updated_at
-		FROM postgres_health_checks
-		WHERE id = $1
+		SELECT ` + healthCheckColumns + `
+		FROM postgres_health_checks hc
+		WHERE hc.id = $1 AND hc.pack_id IS NULL
 	`
 
-	check := &models.VersionHealthCheck{}
-	var maxVersion sql.NullInt64
-
-	err := p.db.QueryRowContext(ctx, query, id).Scan(
-		&check.ID, &check.MinVersion, &maxVersion, &check.CheckName, &check.CheckQuery,
-		&check.ExpectedResult, &check.Severity, &check.Description, &check.Remediation,
-		&check.Category, &check.CreatedAt, &check.UpdatedAt,
-	)
+	check, err := scanVersionHealthCheck(p.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, apperrors.NotFound("health check not found", fmt.Sprintf("id: %d", id))
@@ -81,21 +91,16 @@ func (p *PostgresDB) GetHealthCheckByID(ctx context.Context, id int) (*models.Ve
 		return nil, apperrors.DatabaseError("query health check by id", err.Error())
 	}
 
-	// Handle NULL max_version
-	if maxVersion.Valid {
-		check.MaxVersion = int(maxVersion.Int64)
-	}
-
 	return check, nil
 }
 
-// GetAllHealthChecks retrieves all health checks ordered by version and name
+// GetAllHealthChecks retrieves all built-in health checks ordered by version and name
 func (p *PostgresDB) GetAllHealthChecks(ctx context.Context) ([]*models.VersionHealthCheck, error) {
 	query := `
-		SELECT id, min_version, max_version, check_name, check_query, expected_result,
-			   severity, description, remediation, category, created_at, updated_at
-		FROM postgres_health_checks
-		ORDER BY min_version, check_name
+		SELECT ` + healthCheckColumns + `
+		FROM postgres_health_checks hc
+		WHERE hc.pack_id IS NULL
+		ORDER BY hc.min_version, hc.check_name
 	`
 
 	rows, err := p.db.QueryContext(ctx, query)
@@ -106,74 +111,115 @@ func (p *PostgresDB) GetAllHealthChecks(ctx context.Context) ([]*models.VersionH
 
 	var checks []*models.VersionHealthCheck
 	for rows.Next() {
-		check := &models.VersionHealthCheck{}
-		var maxVersion sql.NullInt64
-
-		err := rows.Scan(
-			&check.ID, &check.MinVersion, &maxVersion, &check.CheckName, &check.CheckQuery,
-			&check.ExpectedResult, &check.Severity, &check.Description, &check.Remediation,
-			&check.Category, &check.CreatedAt, &check.UpdatedAt,
-		)
+		check, err := scanVersionHealthCheck(rows)
 		if err != nil {
 			return nil, apperrors.DatabaseError("scan health check", err.Error())
 		}
+		checks = append(checks, check)
+	}
 
-		// Handle NULL max_version
-		if maxVersion.Valid {
-			check.MaxVersion = int(maxVersion.Int64)
-		}
+	return checks, nil
+}
+
+// GetCollectorHealthChecks retrieves the checks with the given IDs that a
+// collector may report on: built-in checks and the checks in its tenant's
+// packs
+func (p *PostgresDB) GetCollectorHealthChecks(ctx context.Context, collectorID uuid.UUID, ids []int) ([]*models.VersionHealthCheck, error) {
+	query := `
+		SELECT ` + healthCheckColumns + `
+		FROM postgres_health_checks hc
+		LEFT JOIN health_check_packs p ON p.id = hc.pack_id
+		WHERE hc.id = ANY($2)
+		  AND (hc.pack_id IS NULL OR p.tenant_id = (SELECT tenant_id FROM collectors WHERE id = $1))
+	`
+
+	ids64 := make([]int64, len(ids))
+	for i, id := range ids {
+		ids64[i] = int64(id)
+	}
+
+	rows, err := p.db.QueryContext(ctx, query, collectorID, pq.Array(ids64))
+	if err != nil {
+		return nil, apperrors.DatabaseError("query collector health checks", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
 
+	var checks []*models.VersionHealthCheck
+	for rows.Next() {
+		check, err := scanVersionHealthCheck(rows)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan health check", err.Error())
+		}
 		checks = append(checks, check)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query collector health checks", err.Error())
+	}
 
 	return checks, nil
 }
 
-// RunHealthCheck executes a health check query and returns the result
-// This method runs the check query against the monitored database and builds a result
-func (p *PostgresDB) RunHealthCheck(ctx context.Context, collectorID uuid.UUID, check *models.VersionHealthCheck) (*models.HealthCheckResult, error) {
-	result := &models.HealthCheckResult{
-		CheckID:        check.ID,
-		CheckName:      check.CheckName,
-		Severity:       check.Severity,
-		ExpectedResult: check.ExpectedResult,
-		Remediation:    check.Remediation,
-		CheckedAt:      time.Now(),
-	}
-
-	// Execute the check query
-	// Note: This is a simplified implementation that runs the query on the pganalytics database
-	// In a real implementation, this would connect to the monitored database via the collector
-	var actualResult string
-	err := p.db.QueryRowContext(ctx, check.CheckQuery).Scan(&actualResult)
+// GetScheduledHealthChecks retrieves the pack checks scheduled on a
+// collector's groups that apply to its PostgreSQL version. A check scheduled
+// through several groups runs at the shortest of their intervals.
+func (p *PostgresDB) GetScheduledHealthChecks(ctx context.Context, collectorID uuid.UUID, pgVersion int) ([]*models.ScheduledHealthCheck, error) {
+	query := `
+		SELECT ` + healthCheckColumns + `, p.name, MIN(s.interval_seconds)
+		FROM collector_group_members m
+		JOIN health_check_schedules s ON s.group_id = m.group_id AND s.enabled
+		JOIN health_check_packs p ON p.id = s.pack_id
+		JOIN postgres_health_checks hc ON hc.pack_id = p.id
+		WHERE m.collector_id = $1
+		  AND hc.min_version <= $2
+		  AND (hc.max_version IS NULL OR hc.max_version >= $2)
+		GROUP BY hc.id, p.name
+		ORDER BY p.name, hc.check_name
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, collectorID, pgVersion)
 	if err != nil {
-		// Query failed - mark as not passed with error message
-		result.Passed = false
-		result.ActualResult = "Query execution failed"
-		result.Message = err.Error()
-		return result, nil
+		return nil, apperrors.DatabaseError("query scheduled health checks", err.Error())
 	}
+	defer func() { _ = rows.Close() }()
 
-	result.ActualResult = actualResult
-
-	// Simple pass/fail determination based on query result
-	// In a real implementation, this would use more sophisticated comparison logic
-	// For now, we consider the check passed if the query executed successfully
-	result.Passed = true
-	result.Message = "Check executed successfully"
+	scheduled := []*models.ScheduledHealthCheck{}
+	for rows.Next() {
+		sc := &models.ScheduledHealthCheck{Check: &models.VersionHealthCheck{}}
+		var maxVersion, packID sql.NullInt64
+		c := sc.Check
+		err := rows.Scan(
+			&c.ID, &c.MinVersion, &maxVersion, &c.CheckName, &c.CheckQuery,
+			&c.ExpectedResult, &c.Severity, &c.Description, &c.Remediation,
+			&c.Category, &c.AssertionType, &c.AssertionValue, &packID, &c.CreatedAt, &c.UpdatedAt,
+			&sc.PackName, &sc.IntervalSeconds,
+		)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan scheduled health check", err.Error())
+		}
+		if maxVersion.Valid {
+			c.MaxVersion = int(maxVersion.Int64)
+		}
+		if packID.Valid {
+			c.PackID = &packID.Int64
+		}
+		scheduled = append(scheduled, sc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query scheduled health checks", err.Error())
+	}
 
-	return result, nil
+	return scheduled, nil
 }
 
 // StoreHealthCheckResult stores a health check result in the database
 func (p *PostgresDB) StoreHealthCheckResult(ctx context.Context, collectorID uuid.UUID, result *models.HealthCheckResult) error {
 	query := `
-		INSERT INTO postgres_health_check_results (collector_id, check_id, passed, actual_result, checked_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO postgres_health_check_results (collector_id, check_id, passed, actual_result, message, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 	`
 
 	_, err := p.db.ExecContext(ctx, query,
-		collectorID, result.CheckID, result.Passed, result.ActualResult, result.CheckedAt,
+		collectorID, result.CheckID, result.Passed, result.ActualResult, nullString(result.Message), result.CheckedAt,
 	)
 	if err != nil {
 		return apperrors.DatabaseError("store health check result", err.Error())
@@ -182,6 +228,24 @@ func (p *PostgresDB) StoreHealthCheckResult(ctx context.Context, collectorID uui
 	return nil
 }
 
+// StoreManagedInstanceHealthCheckResult stores the result of a health check
+// run over a direct managed instance connection
+func (p *PostgresDB) StoreManagedInstanceHealthCheckResult(ctx context.Context, instanceID int, result *models.HealthCheckResult) error {
+	query := `
+		INSERT INTO postgres_health_check_results (managed_instance_id, check_id, passed, actual_result, message, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := p.db.ExecContext(ctx, query,
+		instanceID, result.CheckID, result.Passed, result.ActualResult, nullString(result.Message), result.CheckedAt,
+	)
+	if err != nil {
+		return apperrors.DatabaseError("store managed instance health check result", err.Error())
+	}
+
+	return nil
+}
+
 // GetRecentHealthCheckResults retrieves recent health check results for a collector
 func (p *PostgresDB) GetRecentHealthCheckResults(ctx context.Context, collectorID uuid.UUID, limit int) ([]*models.HealthCheckResult, error) {
 	if limit <= 0 {
@@ -189,10 +253,12 @@ func (p *PostgresDB) GetRecentHealthCheckResults(ctx context.Context, collectorI
 	}
 
 	query := `
-		SELECT r.check_id, hc.check_name, hc.severity, r.passed, r.actual_result,
-			   hc.expected_result, hc.remediation, r.checked_at
+		SELECT r.check_id, hc.check_name, hc.severity, r.passed, COALESCE(r.actual_result, ''),
+			   COALESCE(r.message, ''), COALESCE(hc.expected_result, ''), COALESCE(hc.remediation, ''), r.checked_at,
+			   COALESCE(p.name, '')
 		FROM postgres_health_check_results r
 		JOIN postgres_health_checks hc ON r.check_id = hc.id
+		LEFT JOIN health_check_packs p ON p.id = hc.pack_id
 		WHERE r.collector_id = $1
 		ORDER BY r.checked_at DESC
 		LIMIT $2
@@ -209,13 +275,111 @@ func (p *PostgresDB) GetRecentHealthCheckResults(ctx context.Context, collectorI
 		result := &models.HealthCheckResult{}
 		err := rows.Scan(
 			&result.CheckID, &result.CheckName, &result.Severity, &result.Passed,
-			&result.ActualResult, &result.ExpectedResult, &result.Remediation, &result.CheckedAt,
+			&result.ActualResult, &result.Message, &result.ExpectedResult, &result.Remediation, &result.CheckedAt,
+			&result.Pack,
+		)
+		if err != nil {
+			return nil, apperrors.DatabaseError("scan health check result", err.Error())
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// GetLatestHealthCheckResults retrieves the latest result of each check a
+// collector reported
+func (p *PostgresDB) GetLatestHealthCheckResults(ctx context.Context, collectorID uuid.UUID) ([]*models.HealthCheckResult, error) {
+	query := `
+		SELECT DISTINCT ON (r.check_id)
+			   r.check_id, hc.check_name, hc.severity, r.passed, COALESCE(r.actual_result, ''),
+			   COALESCE(r.message, ''), COALESCE(hc.expected_result, ''), COALESCE(hc.remediation, ''), r.checked_at,
+			   COALESCE(p.name, '')
+		FROM postgres_health_check_results r
+		JOIN postgres_health_checks hc ON r.check_id = hc.id
+		LEFT JOIN health_check_packs p ON p.id = hc.pack_id
+		WHERE r.collector_id = $1
+		ORDER BY r.check_id, r.checked_at DESC
+	`
+
+	rows, err := p.db.QueryContext(ctx, query, collectorID)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query latest health check results", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	results := []*models.HealthCheckResult{}
+	for rows.Next() {
+		result := &models.HealthCheckResult{}
+		err := rows.Scan(
+			&result.CheckID, &result.CheckName, &result.Severity, &result.Passed,
+			&result.ActualResult, &result.Message, &result.ExpectedResult, &result.Remediation, &result.CheckedAt,
+			&result.Pack,
 		)
 		if err != nil {
 			return nil, apperrors.DatabaseError("scan health check result", err.Error())
 		}
 		results = append(results, result)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query latest health check results", err.Error())
+	}
 
 	return results, nil
 }
+
+// RequestHealthCheckRun asks a collector to run its health checks on its next
+// config pull. A request already pending is kept, with its time.
+func (p *PostgresDB) RequestHealthCheckRun(ctx context.Context, collectorID uuid.UUID) (time.Time, error) {
+	query := `
+		UPDATE collectors
+		SET health_checks_requested_at = COALESCE(health_checks_requested_at, NOW())
+		WHERE id = $1
+		RETURNING health_checks_requested_at
+	`
+
+	var requestedAt time.Time
+	err := p.db.QueryRowContext(ctx, query, collectorID).Scan(&requestedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, apperrors.NotFound("collector not found", collectorID.String())
+		}
+		return time.Time{}, apperrors.DatabaseError("request health check run", err.Error())
+	}
+
+	return requestedAt, nil
+}
+
+// GetHealthCheckRunRequest returns when a pending health check run was
+// requested for a collector, or nil when none is
+func (p *PostgresDB) GetHealthCheckRunRequest(ctx context.Context, collectorID uuid.UUID) (*time.Time, error) {
+	var requestedAt sql.NullTime
+	err := p.db.QueryRowContext(ctx, `SELECT health_checks_requested_at FROM collectors WHERE id = $1`, collectorID).Scan(&requestedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, apperrors.NotFound("collector not found", collectorID.String())
+		}
+		return nil, apperrors.DatabaseError("query health check run request", err.Error())
+	}
+	if !requestedAt.Valid {
+		return nil, nil
+	}
+
+	return &requestedAt.Time, nil
+}
+
+// ClearHealthCheckRunRequest clears a collector's pending run request when it
+// was made at or before upTo, the time of the results that answer it
+func (p *PostgresDB) ClearHealthCheckRunRequest(ctx context.Context, collectorID uuid.UUID, upTo time.Time) error {
+	query := `
+		UPDATE collectors
+		SET health_checks_requested_at = NULL
+		WHERE id = $1 AND health_checks_requested_at <= $2
+	`
+
+	if _, err := p.db.ExecContext(ctx, query, collectorID, upTo); err != nil {
+		return apperrors.DatabaseError("clear health check run request", err.Error())
+	}
+
+	return nil
+}
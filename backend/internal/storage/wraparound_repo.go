@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	apperrors "github.com/torresglauco/pganalytics-v3/backend/pkg/errors"
+	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
+)
+
+// WraparoundRepository reads the wraparound samples stored by
+// StoreWraparoundMetrics. It only needs a *sql.DB so that the alert rule
+// engine can use it.
+type WraparoundRepository struct {
+	db *sql.DB
+}
+
+// NewWraparoundRepository creates a new WraparoundRepository
+func NewWraparoundRepository(db *sql.DB) *WraparoundRepository {
+	return &WraparoundRepository{db: db}
+}
+
+const wraparoundSampleColumns = `time, collector_id, next_xid, next_multixact, freeze_max_age, multixact_freeze_max_age, freeze_min_age`
+
+// GetWraparoundSample returns the latest sample at or before at, within a
+// day of it, or nil when there is none
+func (r *WraparoundRepository) GetWraparoundSample(ctx context.Context, collectorID uuid.UUID, at time.Time) (*models.WraparoundSample, error) {
+	return r.querySample(ctx, `
+		SELECT `+wraparoundSampleColumns+` FROM metrics_pg_wraparound
+		WHERE collector_id = $1 AND time <= $2 AND time > $2 - INTERVAL '1 day'
+		ORDER BY time DESC
+		LIMIT 1
+	`, collectorID, at)
+}
+
+// GetWraparoundBaseline returns the oldest sample in [from, before), the
+// baseline of consumption rates, or nil when there is none
+func (r *WraparoundRepository) GetWraparoundBaseline(ctx context.Context, collectorID uuid.UUID, from, before time.Time) (*models.WraparoundSample, error) {
+	return r.querySample(ctx, `
+		SELECT `+wraparoundSampleColumns+` FROM metrics_pg_wraparound
+		WHERE collector_id = $1 AND time >= $2 AND time < $3
+		ORDER BY time
+		LIMIT 1
+	`, collectorID, from, before)
+}
+
+func (r *WraparoundRepository) querySample(ctx context.Context, query string, args ...interface{}) (*models.WraparoundSample, error) {
+	sample := &models.WraparoundSample{}
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&sample.Time, &sample.CollectorID, &sample.NextXID, &sample.NextMultixact,
+		&sample.FreezeMaxAge, &sample.MultixactFreezeMaxAge, &sample.FreezeMinAge)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, apperrors.DatabaseError("get wraparound sample", err.Error())
+	}
+	return sample, nil
+}
+
+// GetWraparoundAges returns the database and table ages of a sample, oldest first
+func (r *WraparoundRepository) GetWraparoundAges(ctx context.Context, collectorID uuid.UUID, sampleTime time.Time) ([]*models.WraparoundAge, []*models.WraparoundAge, error) {
+	databases, err := r.queryAges(ctx, `
+		SELECT time, database_name, '', '', xid_age, mxid_age, NULL::BIGINT, NULL::BIGINT
+		FROM metrics_pg_wraparound_databases
+		WHERE collector_id = $1 AND time = $2
+		ORDER BY xid_age DESC, database_name
+	`, collectorID, sampleTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tables, err := r.queryAges(ctx, `
+		SELECT time, database_name, schema_name, table_name, xid_age, mxid_age, table_size_bytes, freeze_max_age
+		FROM metrics_pg_wraparound_tables
+		WHERE collector_id = $1 AND time = $2
+		ORDER BY xid_age DESC, database_name, schema_name, table_name
+	`, collectorID, sampleTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return databases, tables, nil
+}
+
+// GetWraparoundHistory returns the ages of a database between from and to,
+// or of one of its tables when table is set, oldest sample first
+func (r *WraparoundRepository) GetWraparoundHistory(ctx context.Context, collectorID uuid.UUID, database string, schema, table *string, from, to time.Time) ([]*models.WraparoundAge, error) {
+	if table != nil {
+		return r.queryAges(ctx, `
+			SELECT time, database_name, schema_name, table_name, xid_age, mxid_age, table_size_bytes, freeze_max_age
+			FROM metrics_pg_wraparound_tables
+			WHERE collector_id = $1 AND database_name = $2 AND schema_name = $3 AND table_name = $4
+			  AND time >= $5 AND time <= $6
+			ORDER BY time
+		`, collectorID, database, *schema, *table, from, to)
+	}
+
+	return r.queryAges(ctx, `
+		SELECT time, database_name, '', '', xid_age, mxid_age, NULL::BIGINT, NULL::BIGINT
+		FROM metrics_pg_wraparound_databases
+		WHERE collector_id = $1 AND database_name = $2 AND time >= $3 AND time <= $4
+		ORDER BY time
+	`, collectorID, database, from, to)
+}
+
+func (r *WraparoundRepository) queryAges(ctx context.Context, query string, args ...interface{}) ([]*models.WraparoundAge, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query wraparound ages", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ages []*models.WraparoundAge
+	for rows.Next() {
+		a := &models.WraparoundAge{}
+		if err := rows.Scan(&a.Time, &a.DatabaseName, &a.SchemaName, &a.TableName, &a.XIDAge, &a.MXIDAge, &a.TableSizeBytes, &a.FreezeMaxAge); err != nil {
+			return nil, apperrors.DatabaseError("scan wraparound age", err.Error())
+		}
+		ages = append(ages, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query wraparound ages", err.Error())
+	}
+
+	return ages, nil
+}
+
+// GetWraparoundBlockers returns the xmin horizon blockers of a sample, oldest first
+func (r *WraparoundRepository) GetWraparoundBlockers(ctx context.Context, collectorID uuid.UUID, sampleTime time.Time) ([]*models.WraparoundBlocker, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT blocker_type, name, database_name, user_name, xid_age, since, active, detail
+		FROM metrics_pg_wraparound_blockers
+		WHERE collector_id = $1 AND time = $2
+		ORDER BY xid_age DESC NULLS LAST, since NULLS LAST, name
+	`, collectorID, sampleTime)
+	if err != nil {
+		return nil, apperrors.DatabaseError("query wraparound blockers", err.Error())
+	}
+	defer func() { _ = rows.Close() }()
+
+	var blockers []*models.WraparoundBlocker
+	for rows.Next() {
+		b := &models.WraparoundBlocker{}
+		var database, user, detail sql.NullString
+		if err := rows.Scan(&b.Type, &b.Name, &database, &user, &b.XIDAge, &b.Since, &b.Active, &detail); err != nil {
+			return nil, apperrors.DatabaseError("scan wraparound blocker", err.Error())
+		}
+		b.DatabaseName = database.String
+		b.UserName = user.String
+		b.Detail = detail.String
+		blockers = append(blockers, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, apperrors.DatabaseError("query wraparound blockers", err.Error())
+	}
+
+	return blockers, nil
+}
+
+// nullString stores an empty string as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
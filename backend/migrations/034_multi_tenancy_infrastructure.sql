@@ -31,7 +31,7 @@ CREATE INDEX IF NOT EXISTS idx_tenants_active ON tenants(is_active) WHERE is_act
 -- Create junction table for user-tenant membership
 CREATE TABLE IF NOT EXISTS tenant_users (
     tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
-    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     role VARCHAR(50) DEFAULT 'viewer',  -- admin, editor, viewer
     created_at TIMESTAMPTZ DEFAULT NOW(),
     PRIMARY KEY (tenant_id, user_id)
@@ -203,7 +203,7 @@ ALTER TABLE tenants ENABLE ROW LEVEL SECURITY;
 -- Policy: Users can only see tenants they belong to
 CREATE POLICY tenant_membership_policy ON tenants
     USING (id IN (
-        SELECT tenant_id FROM tenant_users WHERE user_id = current_setting('app.current_user_id', TRUE)::uuid
+        SELECT tenant_id FROM tenant_users WHERE user_id = current_setting('app.current_user_id', TRUE)::integer
     ));
 
 -- Superuser bypass for tenants
@@ -0,0 +1,22 @@
+-- Migration 037: Query Redaction Policies
+-- Per-tenant control over how query text from pg_query_stats and logs is stored
+-- Modes: none (store as received), normalize ($N placeholders only), mask (mask sensitive literals)
+
+BEGIN;
+
+-- ============================================================================
+-- QUERY REDACTION POLICIES TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS query_redaction_policies (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    mode VARCHAR(20) NOT NULL DEFAULT 'none',
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    CONSTRAINT chk_query_redaction_mode CHECK (mode IN ('none', 'normalize', 'mask'))
+);
+
+COMMENT ON TABLE query_redaction_policies IS 'Per-tenant redaction applied to query samples at ingest';
+COMMENT ON COLUMN query_redaction_policies.mode IS 'none, normalize or mask';
+
+COMMIT;
@@ -0,0 +1,50 @@
+-- Migration 038: Active Session History
+-- Stores periodic pg_stat_activity samples pushed as pg_activity_samples metrics
+-- DB time is derived from sample counts: each active sample accounts for sample_seconds
+
+BEGIN;
+
+-- ============================================================================
+-- ACTIVITY SAMPLES TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS metrics_pg_activity_samples (
+    time TIMESTAMPTZ NOT NULL,
+    collector_id UUID NOT NULL,
+    database_name VARCHAR(255),
+    pid INTEGER NOT NULL,
+    usename VARCHAR(255),
+    application_name VARCHAR(255),
+    client_addr VARCHAR(100),
+    backend_type VARCHAR(64),
+    state VARCHAR(50),              -- active, idle in transaction, ...
+    wait_event_type VARCHAR(64),    -- CPU when the session is active and not waiting
+    wait_event VARCHAR(128),
+    query_id BIGINT,                -- pg_stat_activity.query_id (PostgreSQL 14+)
+    query_fingerprint VARCHAR(32),  -- Fingerprint of the (truncated) query text
+    query_text TEXT,
+    backend_start TIMESTAMPTZ,
+    xact_start TIMESTAMPTZ,
+    query_start TIMESTAMPTZ,
+    sample_seconds DOUBLE PRECISION NOT NULL DEFAULT 1
+);
+
+-- Create TimescaleDB hypertable for activity samples
+SELECT create_hypertable('metrics_pg_activity_samples', 'time',
+    chunk_time_interval => INTERVAL '1 day',
+    if_not_exists => TRUE,
+    migrate_data => FALSE);
+
+-- Create indexes for window queries and fingerprint drill-down
+CREATE INDEX IF NOT EXISTS idx_activity_samples_collector
+    ON metrics_pg_activity_samples (collector_id, time DESC);
+CREATE INDEX IF NOT EXISTS idx_activity_samples_fingerprint
+    ON metrics_pg_activity_samples (collector_id, query_fingerprint, time DESC);
+
+-- Samples are high volume; keep two weeks
+SELECT add_retention_policy('metrics_pg_activity_samples', INTERVAL '14 days', if_not_exists => TRUE);
+
+COMMENT ON TABLE metrics_pg_activity_samples IS 'Active session history sampled from pg_stat_activity';
+COMMENT ON COLUMN metrics_pg_activity_samples.sample_seconds IS 'Sampling period, the DB time each sample represents';
+
+COMMIT;
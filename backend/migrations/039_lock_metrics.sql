@@ -0,0 +1,77 @@
+-- Migration 039: Lock Snapshots
+-- Stores the pg_locks snapshots pushed as pg_locks metrics
+-- Every row of a snapshot shares its collection time so that blocking trees can
+-- be rebuilt for any point in time
+
+BEGIN;
+
+-- ============================================================================
+-- ACTIVE LOCKS TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS metrics_pg_locks (
+    time TIMESTAMPTZ NOT NULL,
+    collector_id UUID NOT NULL,
+    database_name TEXT NOT NULL,
+    pid INTEGER NOT NULL,
+    locktype TEXT NOT NULL,  -- 'relation', 'extend', 'page', 'tuple', 'virtualxid', 'transactionid', etc.
+    mode TEXT NOT NULL,      -- AccessShareLock, RowExclusiveLock, ...
+    granted BOOLEAN NOT NULL,
+    relation_id INTEGER,
+    page_number INTEGER,
+    tuple_id INTEGER,
+    username TEXT,
+    session_state TEXT,      -- idle, active, idle in transaction, ...
+    lock_age_seconds DOUBLE PRECISION,
+    query TEXT
+);
+
+-- A backend holds several locks of the same type in one snapshot, so the
+-- (time, collector_id, database_name, pid, locktype) key of the disabled
+-- migration 012 would drop rows
+ALTER TABLE metrics_pg_locks DROP CONSTRAINT IF EXISTS metrics_pg_locks_pkey;
+
+SELECT create_hypertable('metrics_pg_locks', 'time',
+    chunk_time_interval => INTERVAL '1 day',
+    if_not_exists => TRUE,
+    migrate_data => FALSE);
+
+-- ============================================================================
+-- LOCK WAITS TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS metrics_pg_lock_waits (
+    time TIMESTAMPTZ NOT NULL,
+    collector_id UUID NOT NULL,
+    database_name TEXT NOT NULL,
+    blocked_pid INTEGER NOT NULL,
+    blocking_pid INTEGER NOT NULL,
+    blocked_username TEXT,
+    blocking_username TEXT,
+    blocked_query TEXT,
+    blocking_query TEXT,
+    wait_time_seconds DOUBLE PRECISION,
+    blocked_application TEXT,
+    blocking_application TEXT
+);
+
+ALTER TABLE metrics_pg_lock_waits DROP CONSTRAINT IF EXISTS metrics_pg_lock_waits_pkey;
+
+SELECT create_hypertable('metrics_pg_lock_waits', 'time',
+    chunk_time_interval => INTERVAL '1 day',
+    if_not_exists => TRUE,
+    migrate_data => FALSE);
+
+-- Create indexes for snapshot lookups
+CREATE INDEX IF NOT EXISTS idx_locks_collector_time ON metrics_pg_locks (collector_id, time DESC);
+CREATE INDEX IF NOT EXISTS idx_locks_collector_db_time ON metrics_pg_locks (collector_id, database_name, time DESC);
+CREATE INDEX IF NOT EXISTS idx_lock_waits_collector_time ON metrics_pg_lock_waits (collector_id, time DESC);
+
+-- Lock information is short-lived; keep 30 days
+SELECT add_retention_policy('metrics_pg_locks', INTERVAL '30 days', if_not_exists => TRUE);
+SELECT add_retention_policy('metrics_pg_lock_waits', INTERVAL '30 days', if_not_exists => TRUE);
+
+COMMENT ON TABLE metrics_pg_locks IS 'pg_locks snapshots; rows of one snapshot share their time';
+COMMENT ON TABLE metrics_pg_lock_waits IS 'Blocked/blocking backend pairs of each pg_locks snapshot';
+
+COMMIT;
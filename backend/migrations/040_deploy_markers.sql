@@ -0,0 +1,26 @@
+-- Migration 040: Deploy Markers
+-- Records application releases per collector so that query performance can be
+-- compared before and after a deploy
+
+BEGIN;
+
+-- ============================================================================
+-- DEPLOY MARKERS TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS deploy_markers (
+    id BIGSERIAL PRIMARY KEY,
+    collector_id UUID NOT NULL REFERENCES collectors(id) ON DELETE CASCADE,
+    version VARCHAR(255) NOT NULL,
+    description TEXT,
+    deployed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_deploy_markers_collector
+    ON deploy_markers (collector_id, deployed_at DESC);
+
+COMMENT ON TABLE deploy_markers IS 'Application releases used as boundaries for query regression reports';
+
+COMMIT;
@@ -0,0 +1,20 @@
+-- Migration 041: Structured PostgreSQL Log Fields
+-- Adds the csvlog/jsonlog fields that postgresql_logs did not store so that
+-- logs can be searched and alerted on by database, application and SQLSTATE
+
+BEGIN;
+
+ALTER TABLE pganalytics.postgresql_logs
+    ADD COLUMN IF NOT EXISTS database_name VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS application_name VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS backend_type VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_postgresql_logs_error_code_timestamp
+    ON pganalytics.postgresql_logs (instance_id, error_code, log_timestamp DESC)
+    WHERE error_code IS NOT NULL;
+
+COMMENT ON COLUMN pganalytics.postgresql_logs.database_name IS 'Database the session was connected to (%d / dbname)';
+COMMENT ON COLUMN pganalytics.postgresql_logs.application_name IS 'Client application_name (%a)';
+COMMENT ON COLUMN pganalytics.postgresql_logs.backend_type IS 'Backend type, e.g. client backend or autovacuum worker (%b, PG13+)';
+
+COMMIT;
@@ -0,0 +1,35 @@
+-- Migration 042: Log Category Rules
+-- Stores the category assigned to each PostgreSQL log entry at ingest and
+-- per-tenant custom categories evaluated before the built-in SQLSTATE rules
+
+BEGIN;
+
+ALTER TABLE pganalytics.postgresql_logs
+    ADD COLUMN IF NOT EXISTS category VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_postgresql_logs_category_timestamp
+    ON pganalytics.postgresql_logs (instance_id, category, log_timestamp DESC);
+
+-- ============================================================================
+-- LOG CATEGORY RULES TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS log_category_rules (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    category VARCHAR(64) NOT NULL,
+    sqlstate_prefix VARCHAR(5),
+    pattern TEXT,
+    priority INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT chk_log_category_rule_condition CHECK (sqlstate_prefix IS NOT NULL OR pattern IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_log_category_rules_tenant
+    ON log_category_rules (tenant_id, priority DESC);
+
+COMMENT ON TABLE log_category_rules IS 'Tenant-defined log categories matched by SQLSTATE prefix and/or message regex';
+COMMENT ON COLUMN log_category_rules.priority IS 'Higher priority rules are evaluated first';
+
+COMMIT;
@@ -0,0 +1,44 @@
+-- Migration 043: Log Templates
+-- Groups PostgreSQL log messages into templates with variable slots (<*>)
+-- and keeps per-minute message counts per template for anomaly detection
+
+BEGIN;
+
+-- ============================================================================
+-- LOG TEMPLATES TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS log_templates (
+    id BIGSERIAL PRIMARY KEY,
+    collector_id UUID NOT NULL REFERENCES collectors(id) ON DELETE CASCADE,
+    template TEXT NOT NULL,
+    token_count INTEGER NOT NULL,
+    log_level VARCHAR(20) NOT NULL,
+    category VARCHAR(64),
+    sample_message TEXT NOT NULL,
+    total_count BIGINT NOT NULL DEFAULT 0,
+    first_seen TIMESTAMPTZ NOT NULL,
+    last_seen TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_log_templates_collector_last_seen
+    ON log_templates (collector_id, last_seen DESC);
+
+-- ============================================================================
+-- LOG TEMPLATE COUNTS TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS log_template_counts (
+    template_id BIGINT NOT NULL REFERENCES log_templates(id) ON DELETE CASCADE,
+    bucket TIMESTAMPTZ NOT NULL,
+    count BIGINT NOT NULL,
+    PRIMARY KEY (template_id, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS idx_log_template_counts_bucket
+    ON log_template_counts (bucket);
+
+COMMENT ON TABLE log_templates IS 'Log message templates mined per collector; <*> marks variable tokens';
+COMMENT ON TABLE log_template_counts IS 'Messages per template per one-minute bucket';
+
+COMMIT;
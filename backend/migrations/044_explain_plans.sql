@@ -0,0 +1,48 @@
+-- Migration 044: Explain Plans
+-- Stores EXPLAIN plans sent by collectors and plans extracted from
+-- auto_explain log messages, linked to the query fingerprint, together with
+-- the issues detected in them
+
+BEGIN;
+
+-- ============================================================================
+-- EXPLAIN PLANS TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS explain_plans (
+    id BIGSERIAL PRIMARY KEY,
+    query_hash BIGINT NOT NULL,
+    query_fingerprint_hash BIGINT,
+    collected_at TIMESTAMP DEFAULT NOW(),
+    plan_json JSONB NOT NULL,
+    plan_text TEXT,
+    rows_expected BIGINT,
+    rows_actual BIGINT,
+    plan_duration_ms FLOAT,
+    execution_duration_ms FLOAT,
+    has_seq_scan BOOLEAN DEFAULT FALSE,
+    has_index_scan BOOLEAN DEFAULT FALSE,
+    has_bitmap_scan BOOLEAN DEFAULT FALSE,
+    has_nested_loop BOOLEAN DEFAULT FALSE,
+    total_buffers_read BIGINT,
+    total_buffers_hit BIGINT
+);
+
+ALTER TABLE explain_plans
+    ADD COLUMN IF NOT EXISTS collector_id UUID REFERENCES collectors(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'collector',
+    ADD COLUMN IF NOT EXISTS query_fingerprint VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS query_text TEXT,
+    ADD COLUMN IF NOT EXISTS issues JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_explain_query_hash
+    ON explain_plans (query_hash, collected_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_explain_plans_fingerprint
+    ON explain_plans (query_fingerprint, collected_at DESC)
+    WHERE query_fingerprint IS NOT NULL;
+
+COMMENT ON COLUMN explain_plans.source IS 'collector or auto_explain';
+COMMENT ON COLUMN explain_plans.issues IS 'Issues detected in the plan by the query parser';
+
+COMMIT;
@@ -0,0 +1,82 @@
+-- Migration 045: Retention Policies
+-- Per-tenant retention of raw logs, query stats, host metrics and explain
+-- plans. Expired rows are rolled up into hourly summaries and optionally
+-- archived as compressed NDJSON before they are deleted.
+
+BEGIN;
+
+-- ============================================================================
+-- RETENTION POLICIES TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS retention_policies (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    data_class VARCHAR(32) NOT NULL,
+    retention_days INTEGER NOT NULL,
+    rollup BOOLEAN NOT NULL DEFAULT TRUE,
+    archive BOOLEAN NOT NULL DEFAULT FALSE,
+    rollup_retention_days INTEGER,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, data_class),
+    CONSTRAINT chk_retention_data_class CHECK (data_class IN ('raw_logs', 'query_stats', 'host_metrics', 'explain_plans')),
+    CONSTRAINT chk_retention_days CHECK (retention_days > 0),
+    CONSTRAINT chk_rollup_retention_days CHECK (rollup_retention_days IS NULL OR rollup_retention_days > 0)
+);
+
+COMMENT ON TABLE retention_policies IS 'How long each tenant keeps each class of raw data';
+COMMENT ON COLUMN retention_policies.rollup IS 'Summarize expired rows into hourly rollups before deleting them';
+COMMENT ON COLUMN retention_policies.archive IS 'Archive expired rows as gzipped NDJSON before deleting them';
+COMMENT ON COLUMN retention_policies.rollup_retention_days IS 'How long hourly rollups are kept; NULL keeps them';
+
+-- ============================================================================
+-- HOURLY ROLLUPS TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS data_rollups_hourly (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    data_class VARCHAR(32) NOT NULL,
+    collector_id UUID NOT NULL,
+    bucket TIMESTAMPTZ NOT NULL,
+    dimension_key TEXT NOT NULL,
+    dimensions JSONB NOT NULL,
+    metrics JSONB NOT NULL,
+    PRIMARY KEY (tenant_id, data_class, collector_id, bucket, dimension_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_rollups_hourly_bucket
+    ON data_rollups_hourly (tenant_id, data_class, bucket);
+
+COMMENT ON TABLE data_rollups_hourly IS 'Hourly summaries of raw data removed by retention policies';
+COMMENT ON COLUMN data_rollups_hourly.dimensions IS 'Grouping of the summary, e.g. log level and database';
+COMMENT ON COLUMN data_rollups_hourly.metrics IS 'Aggregated values, e.g. counts, averages and maxima';
+
+-- ============================================================================
+-- RETENTION RUNS TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS retention_runs (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    data_class VARCHAR(32) NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    window_end TIMESTAMPTZ NOT NULL,
+    rows_deleted BIGINT NOT NULL,
+    rollups_written BIGINT NOT NULL,
+    archive_key TEXT,
+    archive_bytes BIGINT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_retention_runs_tenant_created
+    ON retention_runs (tenant_id, created_at DESC);
+
+-- Retention deletes by time across a tenant's collectors
+CREATE INDEX IF NOT EXISTS idx_postgresql_logs_collector_timestamp
+    ON pganalytics.postgresql_logs (collector_id, log_timestamp);
+
+CREATE INDEX IF NOT EXISTS idx_explain_plans_collector_collected
+    ON explain_plans (collector_id, collected_at)
+    WHERE collector_id IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,25 @@
+-- Migration 046: Log Search
+-- Full-text and cursor indexes for searching stored PostgreSQL logs. The
+-- search query language matches messages with the 'simple' configuration,
+-- which lower-cases words without stemming them, so identifiers and
+-- SQLSTATE codes in messages stay searchable as written.
+
+BEGIN;
+
+-- ============================================================================
+-- FULL-TEXT INDEX
+-- ============================================================================
+
+-- Queries must use the same expression for the index to apply
+CREATE INDEX IF NOT EXISTS idx_postgresql_logs_message_fts
+    ON pganalytics.postgresql_logs USING GIN (to_tsvector('simple', log_message));
+
+-- ============================================================================
+-- CURSOR PAGINATION INDEX
+-- ============================================================================
+
+-- Search results are ordered newest first with the ID breaking ties
+CREATE INDEX IF NOT EXISTS idx_postgresql_logs_instance_timestamp_id
+    ON pganalytics.postgresql_logs (instance_id, log_timestamp DESC, id DESC);
+
+COMMIT;
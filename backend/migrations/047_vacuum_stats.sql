@@ -0,0 +1,77 @@
+-- Migration 047: Vacuum Statistics
+-- Stores the pg_vacuum_stats metrics the vacuum advisor analyzes: per-table
+-- pg_stat_user_tables counters with pg_class.reloptions and a bloat estimate,
+-- and the autovacuum settings they were collected under
+-- Rates such as dead tuples per hour come from the difference between samples,
+-- so the counters are stored as reported rather than as deltas
+
+BEGIN;
+
+-- ============================================================================
+-- TABLE STATISTICS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS metrics_pg_vacuum_tables (
+    time TIMESTAMPTZ NOT NULL,
+    collector_id UUID NOT NULL,
+    database_name TEXT NOT NULL,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    live_tuples BIGINT NOT NULL,
+    dead_tuples BIGINT NOT NULL,
+    mod_since_analyze BIGINT NOT NULL,
+    ins_since_vacuum BIGINT,            -- NULL before PostgreSQL 13
+    tuples_inserted BIGINT NOT NULL,
+    tuples_updated BIGINT NOT NULL,
+    tuples_deleted BIGINT NOT NULL,
+    tuples_hot_updated BIGINT NOT NULL,
+    reltuples DOUBLE PRECISION NOT NULL,
+    relpages BIGINT NOT NULL,
+    table_size_bytes BIGINT NOT NULL,
+    bloat_bytes BIGINT,
+    reloptions TEXT[] NOT NULL DEFAULT '{}',
+    last_vacuum TIMESTAMPTZ,
+    last_autovacuum TIMESTAMPTZ,
+    last_analyze TIMESTAMPTZ,
+    last_autoanalyze TIMESTAMPTZ,
+    vacuum_count BIGINT NOT NULL DEFAULT 0,
+    autovacuum_count BIGINT NOT NULL DEFAULT 0,
+    analyze_count BIGINT NOT NULL DEFAULT 0,
+    autoanalyze_count BIGINT NOT NULL DEFAULT 0
+);
+
+SELECT create_hypertable('metrics_pg_vacuum_tables', 'time',
+    chunk_time_interval => INTERVAL '1 day',
+    if_not_exists => TRUE,
+    migrate_data => FALSE);
+
+CREATE INDEX IF NOT EXISTS idx_vacuum_tables_collector_db_time
+    ON metrics_pg_vacuum_tables (collector_id, database_name, time DESC);
+
+-- ============================================================================
+-- AUTOVACUUM SETTINGS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS metrics_pg_vacuum_settings (
+    time TIMESTAMPTZ NOT NULL,
+    collector_id UUID NOT NULL,
+    database_name TEXT NOT NULL,
+    settings JSONB NOT NULL
+);
+
+SELECT create_hypertable('metrics_pg_vacuum_settings', 'time',
+    chunk_time_interval => INTERVAL '7 days',
+    if_not_exists => TRUE,
+    migrate_data => FALSE);
+
+CREATE INDEX IF NOT EXISTS idx_vacuum_settings_collector_db_time
+    ON metrics_pg_vacuum_settings (collector_id, database_name, time DESC);
+
+-- Churn rates need about a day of history; keep 30 days for trends
+SELECT add_retention_policy('metrics_pg_vacuum_tables', INTERVAL '30 days', if_not_exists => TRUE);
+SELECT add_retention_policy('metrics_pg_vacuum_settings', INTERVAL '30 days', if_not_exists => TRUE);
+
+COMMENT ON TABLE metrics_pg_vacuum_tables IS 'pg_stat_user_tables and pg_class samples; rows of one sample share their time';
+COMMENT ON TABLE metrics_pg_vacuum_settings IS 'Autovacuum settings of pg_settings at each pg_vacuum_stats sample';
+
+COMMIT;
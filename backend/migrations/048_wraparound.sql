@@ -0,0 +1,108 @@
+-- Migration 048: Transaction ID Wraparound
+-- Stores the pg_wraparound metrics: the cluster's next transaction ID and
+-- multixact with its freeze settings, the ages of every database and of the
+-- oldest tables, and the prepared transactions, replication slots and long
+-- transactions holding the xmin horizon back
+-- Consumption rates come from the difference between next IDs of samples
+
+BEGIN;
+
+-- ============================================================================
+-- CLUSTER SAMPLES
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS metrics_pg_wraparound (
+    time TIMESTAMPTZ NOT NULL,
+    collector_id UUID NOT NULL,
+    next_xid BIGINT NOT NULL,
+    next_multixact BIGINT NOT NULL,
+    freeze_max_age BIGINT NOT NULL,
+    multixact_freeze_max_age BIGINT NOT NULL,
+    freeze_min_age BIGINT NOT NULL
+);
+
+SELECT create_hypertable('metrics_pg_wraparound', 'time',
+    chunk_time_interval => INTERVAL '7 days',
+    if_not_exists => TRUE,
+    migrate_data => FALSE);
+
+CREATE INDEX IF NOT EXISTS idx_wraparound_collector_time
+    ON metrics_pg_wraparound (collector_id, time DESC);
+
+-- ============================================================================
+-- DATABASE AND TABLE AGES
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS metrics_pg_wraparound_databases (
+    time TIMESTAMPTZ NOT NULL,
+    collector_id UUID NOT NULL,
+    database_name TEXT NOT NULL,
+    xid_age BIGINT NOT NULL,
+    mxid_age BIGINT NOT NULL
+);
+
+SELECT create_hypertable('metrics_pg_wraparound_databases', 'time',
+    chunk_time_interval => INTERVAL '7 days',
+    if_not_exists => TRUE,
+    migrate_data => FALSE);
+
+CREATE INDEX IF NOT EXISTS idx_wraparound_databases_collector_time
+    ON metrics_pg_wraparound_databases (collector_id, time DESC, database_name);
+
+CREATE TABLE IF NOT EXISTS metrics_pg_wraparound_tables (
+    time TIMESTAMPTZ NOT NULL,
+    collector_id UUID NOT NULL,
+    database_name TEXT NOT NULL,
+    schema_name TEXT NOT NULL,
+    table_name TEXT NOT NULL,
+    xid_age BIGINT NOT NULL,
+    mxid_age BIGINT NOT NULL,
+    table_size_bytes BIGINT,
+    freeze_max_age BIGINT              -- autovacuum_freeze_max_age reloption
+);
+
+SELECT create_hypertable('metrics_pg_wraparound_tables', 'time',
+    chunk_time_interval => INTERVAL '7 days',
+    if_not_exists => TRUE,
+    migrate_data => FALSE);
+
+CREATE INDEX IF NOT EXISTS idx_wraparound_tables_collector_time
+    ON metrics_pg_wraparound_tables (collector_id, time DESC, database_name);
+
+-- ============================================================================
+-- XMIN HORIZON BLOCKERS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS metrics_pg_wraparound_blockers (
+    time TIMESTAMPTZ NOT NULL,
+    collector_id UUID NOT NULL,
+    blocker_type TEXT NOT NULL,        -- prepared_transaction, replication_slot, long_transaction
+    name TEXT NOT NULL,
+    database_name TEXT,
+    user_name TEXT,
+    xid_age BIGINT,
+    since TIMESTAMPTZ,
+    active BOOLEAN,
+    detail TEXT
+);
+
+SELECT create_hypertable('metrics_pg_wraparound_blockers', 'time',
+    chunk_time_interval => INTERVAL '7 days',
+    if_not_exists => TRUE,
+    migrate_data => FALSE);
+
+CREATE INDEX IF NOT EXISTS idx_wraparound_blockers_collector_time
+    ON metrics_pg_wraparound_blockers (collector_id, time DESC);
+
+-- Ages move slowly; keep 90 days to see the trend of anti-wraparound vacuums
+SELECT add_retention_policy('metrics_pg_wraparound', INTERVAL '90 days', if_not_exists => TRUE);
+SELECT add_retention_policy('metrics_pg_wraparound_databases', INTERVAL '90 days', if_not_exists => TRUE);
+SELECT add_retention_policy('metrics_pg_wraparound_tables', INTERVAL '90 days', if_not_exists => TRUE);
+SELECT add_retention_policy('metrics_pg_wraparound_blockers', INTERVAL '90 days', if_not_exists => TRUE);
+
+COMMENT ON TABLE metrics_pg_wraparound IS 'Next transaction ID and multixact with freeze settings; rows of one sample share their time with its ages and blockers';
+COMMENT ON TABLE metrics_pg_wraparound_databases IS 'age(datfrozenxid) and mxid_age(datminmxid) per database';
+COMMENT ON TABLE metrics_pg_wraparound_tables IS 'age(relfrozenxid) and mxid_age(relminmxid) of the oldest tables per database';
+COMMENT ON TABLE metrics_pg_wraparound_blockers IS 'Prepared transactions, replication slots and long transactions holding back the xmin horizon';
+
+COMMIT;
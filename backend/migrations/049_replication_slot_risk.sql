@@ -0,0 +1,43 @@
+-- Migration 049: Replication Slot Risk
+-- Adds the slot columns needed to judge a slot (xmin and catalog_xmin ages,
+-- wal_status and safe_wal_size) and stores the WAL insert position of each
+-- pg_replication sample, so retained WAL per slot is the LSN difference to
+-- restart_lsn and the WAL rate the difference between samples
+
+BEGIN;
+
+-- ============================================================================
+-- SLOT COLUMNS
+-- ============================================================================
+
+ALTER TABLE metrics_replication_slots
+    ADD COLUMN IF NOT EXISTS xmin_age BIGINT,
+    ADD COLUMN IF NOT EXISTS catalog_xmin_age BIGINT,
+    ADD COLUMN IF NOT EXISTS wal_status VARCHAR(20),      -- reserved, extended, unreserved, lost
+    ADD COLUMN IF NOT EXISTS safe_wal_size_bytes BIGINT;
+
+-- ============================================================================
+-- WAL POSITION
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS metrics_wal_position (
+    time TIMESTAMPTZ NOT NULL,
+    collector_id UUID NOT NULL,
+    current_wal_lsn VARCHAR(50) NOT NULL,
+    wal_directory_bytes BIGINT,
+    max_slot_wal_keep_size_bytes BIGINT  -- NULL when unlimited
+);
+
+SELECT create_hypertable('metrics_wal_position', 'time',
+    chunk_time_interval => INTERVAL '7 days',
+    if_not_exists => TRUE,
+    migrate_data => FALSE);
+
+CREATE INDEX IF NOT EXISTS idx_wal_position_collector_time
+    ON metrics_wal_position (collector_id, time DESC);
+
+SELECT add_retention_policy('metrics_wal_position', INTERVAL '30 days', if_not_exists => TRUE);
+
+COMMENT ON TABLE metrics_wal_position IS 'WAL insert position per pg_replication sample; shares its time with the slot rows of the sample';
+
+COMMIT;
@@ -0,0 +1,68 @@
+-- Migration 050: Cluster Events
+-- Stores the identity and recovery state of each collector's server per
+-- pg_replication sample, and the role, timeline and upstream changes found by
+-- comparing successive states, with the topology of the node's cluster before
+-- and after. Events are the failover record postmortems rely on and are kept
+-- indefinitely.
+
+BEGIN;
+
+-- ============================================================================
+-- NODE STATE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS metrics_node_state (
+    time TIMESTAMPTZ NOT NULL,
+    collector_id UUID NOT NULL,
+    system_identifier VARCHAR(32),     -- pg_control_system(), shared by a primary and its standbys
+    timeline_id BIGINT NOT NULL,
+    in_recovery BOOLEAN NOT NULL,
+    server_version_num INT,
+    upstream_host VARCHAR(255),
+    upstream_port INT
+);
+
+SELECT create_hypertable('metrics_node_state', 'time',
+    chunk_time_interval => INTERVAL '7 days',
+    if_not_exists => TRUE,
+    migrate_data => FALSE);
+
+CREATE INDEX IF NOT EXISTS idx_node_state_collector_time
+    ON metrics_node_state (collector_id, time DESC);
+
+CREATE INDEX IF NOT EXISTS idx_node_state_system_identifier_time
+    ON metrics_node_state (system_identifier, time DESC);
+
+SELECT add_retention_policy('metrics_node_state', INTERVAL '30 days', if_not_exists => TRUE);
+
+-- ============================================================================
+-- CLUSTER EVENTS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS cluster_events (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    cluster_id VARCHAR(64) NOT NULL,   -- system identifier, or collector:<id> when unknown
+    collector_id UUID NOT NULL,
+    event_type VARCHAR(30) NOT NULL,   -- failover, switchover, promotion, demotion, timeline_change, upstream_change
+    occurred_at TIMESTAMPTZ NOT NULL,
+    previous_sample_at TIMESTAMPTZ NOT NULL,
+    before_state JSONB NOT NULL,
+    after_state JSONB NOT NULL,
+    before_topology JSONB,
+    after_topology JSONB,
+    previous_primary_id UUID,
+    summary TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cluster_events_cluster_time
+    ON cluster_events (cluster_id, occurred_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_cluster_events_tenant_time
+    ON cluster_events (tenant_id, occurred_at DESC);
+
+COMMENT ON TABLE metrics_node_state IS 'Timeline, recovery state and upstream of each collector''s server per pg_replication sample';
+COMMENT ON TABLE cluster_events IS 'Failovers, switchovers, timeline and upstream changes detected between successive node states';
+
+COMMIT;
@@ -0,0 +1,106 @@
+-- Migration 051: Health Check Assertions
+-- Version health checks run on the monitored instance, through the collector
+-- or a direct managed instance connection, and are judged by a typed
+-- assertion on their result set instead of passing whenever the query runs.
+-- Seed checks that only reported a value are rewritten so they can fail.
+
+BEGIN;
+
+-- ============================================================================
+-- ASSERTIONS
+-- ============================================================================
+
+ALTER TABLE postgres_health_checks
+    ADD COLUMN IF NOT EXISTS assertion_type VARCHAR(20),   -- equals, less_than, row_count_zero, regex
+    ADD COLUMN IF NOT EXISTS assertion_value TEXT;         -- compared with the first column of the first row
+
+UPDATE postgres_health_checks SET
+    check_query = 'SELECT current_setting(''server_version_num'')::int >= 130000',
+    expected_result = 'Server runs a supported major version',
+    assertion_type = 'equals', assertion_value = 'true'
+WHERE check_name = 'version_eol_warning' AND min_version = 11;
+
+UPDATE postgres_health_checks SET
+    expected_result = 'wal_keep_segments is not relied on',
+    assertion_type = 'equals', assertion_value = '0'
+WHERE check_name = 'wal_keep_segments_deprecated' AND min_version = 11;
+
+UPDATE postgres_health_checks SET
+    check_query = 'SELECT application_name FROM pg_stat_replication WHERE sync_state IN (''sync'', ''quorum'') AND state <> ''streaming''',
+    expected_result = 'Every synchronous standby is streaming',
+    assertion_type = 'row_count_zero', assertion_value = NULL
+WHERE check_name = 'pg_stat_replication_sync_state' AND min_version = 11;
+
+UPDATE postgres_health_checks SET
+    expected_result = 'wal_keep_size is above zero',
+    assertion_type = 'regex', assertion_value = '^[1-9][0-9]*$'
+WHERE check_name = 'wal_keep_size' AND min_version = 13;
+
+UPDATE postgres_health_checks SET
+    assertion_type = 'regex', assertion_value = '^(on|pglz|lz4|zstd)$'
+WHERE check_name = 'wal_compression' AND min_version = 13;
+
+UPDATE postgres_health_checks SET
+    expected_result = 'pg_stat_wal has its single row',
+    assertion_type = 'equals', assertion_value = '1'
+WHERE check_name = 'pg_stat_wal_available' AND min_version = 14;
+
+UPDATE postgres_health_checks SET
+    check_query = 'SELECT s.subname FROM pg_subscription s WHERE s.subenabled AND NOT EXISTS (SELECT 1 FROM pg_stat_subscription w WHERE w.subid = s.oid AND w.pid IS NOT NULL)',
+    expected_result = 'Every enabled subscription has a running apply worker',
+    assertion_type = 'row_count_zero', assertion_value = NULL
+WHERE check_name = 'logical_replication_workers' AND min_version = 14;
+
+UPDATE postgres_health_checks SET
+    check_query = 'SELECT setting::bigint >= 65536 FROM pg_settings WHERE name = ''logical_decoding_work_mem''',
+    expected_result = 'logical_decoding_work_mem is at least the 64MB default',
+    assertion_type = 'equals', assertion_value = 'true'
+WHERE check_name = 'logical_decoding_work_mem' AND min_version = 15;
+
+UPDATE postgres_health_checks SET
+    check_query = 'SELECT (SELECT count(*) FROM pg_stat_activity WHERE backend_type LIKE ''parallel apply%'') < current_setting(''max_logical_replication_workers'')::int',
+    expected_result = 'Parallel apply workers leave room in max_logical_replication_workers',
+    assertion_type = 'equals', assertion_value = 'true'
+WHERE check_name = 'parallel_apply_workers' AND min_version = 17;
+
+-- Checks added outside the seed have no assertion to judge them by and would
+-- report whatever the query returned as a pass; make them fail until one is set
+UPDATE postgres_health_checks SET
+    assertion_type = 'equals', assertion_value = 'assertion not configured'
+WHERE assertion_type IS NULL;
+
+ALTER TABLE postgres_health_checks
+    ALTER COLUMN assertion_type SET NOT NULL,
+    ADD CONSTRAINT chk_health_check_assertion_type
+        CHECK (assertion_type IN ('equals', 'less_than', 'row_count_zero', 'regex'));
+
+-- ============================================================================
+-- RESULTS
+-- ============================================================================
+
+-- Results come from a collector or from a managed instance connection
+ALTER TABLE postgres_health_check_results
+    ALTER COLUMN collector_id DROP NOT NULL,
+    ADD COLUMN IF NOT EXISTS managed_instance_id INTEGER REFERENCES managed_instances(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS message TEXT,
+    ADD CONSTRAINT chk_health_check_result_target
+        CHECK (collector_id IS NOT NULL OR managed_instance_id IS NOT NULL);
+
+CREATE INDEX IF NOT EXISTS idx_health_check_results_managed_instance
+    ON postgres_health_check_results (managed_instance_id, checked_at DESC)
+    WHERE managed_instance_id IS NOT NULL;
+
+-- ============================================================================
+-- COLLECTOR RUN REQUESTS
+-- ============================================================================
+
+-- Set when a user asks for a run; collectors see it on their next config pull
+-- and it is cleared once they report results
+ALTER TABLE collectors
+    ADD COLUMN IF NOT EXISTS health_checks_requested_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN postgres_health_checks.assertion_type IS 'How the check result set is judged: equals, less_than, row_count_zero or regex';
+COMMENT ON COLUMN postgres_health_checks.assertion_value IS 'Value the first column of the first row is compared with; unused by row_count_zero';
+COMMENT ON COLUMN postgres_health_check_results.managed_instance_id IS 'Managed instance the check ran against when not run by a collector';
+
+COMMIT;
@@ -0,0 +1,91 @@
+-- Migration 052: Custom Health Check Packs
+-- Tenants author their own health checks in versioned packs and schedule
+-- them on groups of collectors. Pack checks live next to the built-in checks
+-- in postgres_health_checks, so they are pulled, judged and stored the same
+-- way; built-in checks are the ones without a pack.
+
+BEGIN;
+
+-- ============================================================================
+-- COLLECTOR GROUPS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS collector_groups (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS collector_group_members (
+    group_id BIGINT NOT NULL REFERENCES collector_groups(id) ON DELETE CASCADE,
+    collector_id UUID NOT NULL REFERENCES collectors(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, collector_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collector_group_members_collector
+    ON collector_group_members (collector_id);
+
+-- ============================================================================
+-- CHECK PACKS
+-- ============================================================================
+
+-- Each version of a pack is its own row, so schedules stay on the version
+-- they were made for until moved
+CREATE TABLE IF NOT EXISTS health_check_packs (
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    description TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, name, version)
+);
+
+ALTER TABLE postgres_health_checks
+    ADD COLUMN IF NOT EXISTS pack_id BIGINT REFERENCES health_check_packs(id) ON DELETE CASCADE;
+
+-- Check names are unique per version range among built-in checks and per pack
+ALTER TABLE postgres_health_checks
+    DROP CONSTRAINT IF EXISTS postgres_health_checks_check_name_min_version_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_health_checks_builtin
+    ON postgres_health_checks (check_name, min_version)
+    WHERE pack_id IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_health_checks_pack
+    ON postgres_health_checks (pack_id, check_name)
+    WHERE pack_id IS NOT NULL;
+
+-- Removing a check from a pack removes its results
+ALTER TABLE postgres_health_check_results
+    DROP CONSTRAINT IF EXISTS postgres_health_check_results_check_id_fkey,
+    ADD CONSTRAINT postgres_health_check_results_check_id_fkey
+        FOREIGN KEY (check_id) REFERENCES postgres_health_checks(id) ON DELETE CASCADE;
+
+-- ============================================================================
+-- SCHEDULES
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS health_check_schedules (
+    id BIGSERIAL PRIMARY KEY,
+    pack_id BIGINT NOT NULL REFERENCES health_check_packs(id) ON DELETE CASCADE,
+    group_id BIGINT NOT NULL REFERENCES collector_groups(id) ON DELETE CASCADE,
+    interval_seconds INTEGER NOT NULL CHECK (interval_seconds >= 60),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (pack_id, group_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_health_check_schedules_group
+    ON health_check_schedules (group_id);
+
+COMMENT ON TABLE collector_groups IS 'Named sets of a tenant''s collectors that check packs are scheduled on';
+COMMENT ON TABLE health_check_packs IS 'Versioned bundles of tenant-authored health checks';
+COMMENT ON TABLE health_check_schedules IS 'Check packs run by the collectors of a group every interval_seconds';
+COMMENT ON COLUMN postgres_health_checks.pack_id IS 'Check pack of a custom check; NULL for built-in checks';
+
+COMMIT;
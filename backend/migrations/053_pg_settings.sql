@@ -0,0 +1,51 @@
+-- Migration 053: Server Settings
+-- Stores the pg_settings snapshots collectors push: the current value of each
+-- setting per collector, and every change seen between snapshots. Changes
+-- are the configuration history incident reviews rely on and are kept
+-- indefinitely; the current values are replaced on each snapshot.
+
+BEGIN;
+
+-- ============================================================================
+-- CURRENT SETTINGS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS pg_settings_current (
+    collector_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    setting TEXT NOT NULL,
+    unit VARCHAR(10),
+    vartype VARCHAR(10),
+    context VARCHAR(20),               -- postmaster settings need a restart
+    source VARCHAR(30),
+    boot_val TEXT,
+    pending_restart BOOLEAN NOT NULL DEFAULT FALSE,
+    collected_at TIMESTAMPTZ NOT NULL, -- shared by the settings of one snapshot
+    changed_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (collector_id, name)
+);
+
+-- ============================================================================
+-- CHANGE HISTORY
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS pg_settings_changes (
+    id BIGSERIAL PRIMARY KEY,
+    collector_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    old_value TEXT,                    -- NULL when the setting appeared
+    new_value TEXT,                    -- NULL when the setting disappeared
+    unit VARCHAR(10),
+    changed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_pg_settings_changes_collector_time
+    ON pg_settings_changes (collector_id, changed_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_pg_settings_changes_collector_name_time
+    ON pg_settings_changes (collector_id, name, changed_at DESC);
+
+COMMENT ON TABLE pg_settings_current IS 'Latest pg_settings snapshot of each collector''s instance';
+COMMENT ON TABLE pg_settings_changes IS 'Setting values that changed between successive pg_settings snapshots';
+
+COMMIT;
@@ -0,0 +1,28 @@
+-- Migration 054: Upgrade Readiness Objects
+-- Stores the catalog objects collectors report that the schema inventory does
+-- not cover but that block or break a major-version upgrade: postfix
+-- operators, user-defined encoding conversions and objects over polymorphic
+-- functions whose signatures changed. Only the latest snapshot of each
+-- collector is kept.
+
+BEGIN;
+
+-- ============================================================================
+-- UPGRADE OBJECTS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS pg_upgrade_objects_current (
+    collector_id UUID NOT NULL REFERENCES collectors(id) ON DELETE CASCADE,
+    database_name VARCHAR(255) NOT NULL,
+    kind VARCHAR(64) NOT NULL,
+    schema_name VARCHAR(255) NOT NULL DEFAULT '',
+    object_name TEXT NOT NULL,
+    detail TEXT,
+    collected_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (collector_id, database_name, kind, schema_name, object_name)
+);
+
+COMMENT ON TABLE pg_upgrade_objects_current IS 'Latest pg_upgrade_objects snapshot per collector, replaced on each report';
+COMMENT ON COLUMN pg_upgrade_objects_current.kind IS 'postfix_operator, encoding_conversion or incompatible_polymorphic';
+
+COMMIT;
@@ -0,0 +1,51 @@
+-- Migration 055: Database Health Scores
+-- Stores database-level health scores that combine cache hit ratio,
+-- replication lag, connection saturation, bloat, wraparound headroom, failed
+-- health checks, log anomalies and error-log rates, next to the host health
+-- scores of migration 033. Tenants can weigh the components themselves.
+
+BEGIN;
+
+-- ============================================================================
+-- DATABASE HEALTH SCORES TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS metrics_database_health_scores (
+    time TIMESTAMPTZ NOT NULL,
+    collector_id UUID NOT NULL REFERENCES collectors(id),
+    health_score INT,             -- 0-100
+    status VARCHAR(20),           -- healthy, degraded, warning, critical
+    components JSONB NOT NULL,    -- Per-component score, value and explanation
+    weights JSONB NOT NULL,       -- Weights the score was calculated with
+    PRIMARY KEY (time, collector_id)
+);
+
+SELECT create_hypertable('metrics_database_health_scores', 'time',
+    if_not_exists => TRUE, migrate_data => FALSE);
+
+CREATE INDEX IF NOT EXISTS idx_database_health_scores_collector
+    ON metrics_database_health_scores (collector_id, time DESC);
+
+SELECT add_retention_policy('metrics_database_health_scores', INTERVAL '90 days', if_not_exists => TRUE);
+
+-- ============================================================================
+-- TENANT WEIGHTS
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS database_health_weights (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    cache_hit DOUBLE PRECISION NOT NULL CHECK (cache_hit >= 0),
+    replication_lag DOUBLE PRECISION NOT NULL CHECK (replication_lag >= 0),
+    connections DOUBLE PRECISION NOT NULL CHECK (connections >= 0),
+    bloat DOUBLE PRECISION NOT NULL CHECK (bloat >= 0),
+    wraparound DOUBLE PRECISION NOT NULL CHECK (wraparound >= 0),
+    health_checks DOUBLE PRECISION NOT NULL CHECK (health_checks >= 0),
+    anomalies DOUBLE PRECISION NOT NULL CHECK (anomalies >= 0),
+    error_logs DOUBLE PRECISION NOT NULL CHECK (error_logs >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE metrics_database_health_scores IS 'Database-level health scores per collector; see database_health_weights for how components are weighed';
+COMMENT ON TABLE database_health_weights IS 'Per-tenant component weights for database health scores; tenants without a row use the defaults';
+
+COMMIT;
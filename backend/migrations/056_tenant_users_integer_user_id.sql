@@ -0,0 +1,37 @@
+-- Migration 056: Integer User IDs in Tenant Memberships
+-- users.id is an integer and access tokens carry it, but migration 034 first
+-- declared tenant_users.user_id as UUID, so no membership could name a user.
+-- Tables created that way are converted; their rows cannot refer to any user
+-- and are dropped. Tables created as INTEGER are left as they are.
+
+BEGIN;
+
+-- ============================================================================
+-- TENANT_USERS.USER_ID
+-- ============================================================================
+
+DROP POLICY IF EXISTS tenant_membership_policy ON tenants;
+
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'tenant_users' AND column_name = 'user_id' AND data_type = 'uuid'
+    ) THEN
+        DELETE FROM tenant_users;
+        ALTER TABLE tenant_users DROP CONSTRAINT IF EXISTS tenant_users_user_id_fkey;
+        ALTER TABLE tenant_users ALTER COLUMN user_id TYPE INTEGER USING NULL;
+        ALTER TABLE tenant_users ADD CONSTRAINT tenant_users_user_id_fkey
+            FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+    END IF;
+END $$;
+
+-- Policy: Users can only see tenants they belong to
+CREATE POLICY tenant_membership_policy ON tenants
+    USING (id IN (
+        SELECT tenant_id FROM tenant_users WHERE user_id = current_setting('app.current_user_id', TRUE)::integer
+    ));
+
+COMMENT ON COLUMN tenant_users.user_id IS 'users.id of the member, as carried in access tokens';
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- Migration 057: Query Redaction Backfill Checkpoints
+-- A redaction backfill walks each redactable column in key order and records
+-- the last key it redacted, so that an interrupted backfill resumes where it
+-- stopped instead of rescanning the tenant's samples
+
+BEGIN;
+
+-- ============================================================================
+-- BACKFILL CHECKPOINTS TABLE
+-- ============================================================================
+
+CREATE TABLE IF NOT EXISTS query_redaction_backfill_checkpoints (
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    table_name VARCHAR(64) NOT NULL,
+    column_name VARCHAR(64) NOT NULL,
+    mode VARCHAR(20) NOT NULL,      -- Redaction mode the backfill started with
+    last_key TEXT[] NOT NULL,       -- Key of the last row redacted, as text
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, table_name, column_name)
+);
+
+COMMENT ON TABLE query_redaction_backfill_checkpoints IS 'Progress of interrupted query redaction backfills; removed when a backfill completes';
+
+COMMIT;
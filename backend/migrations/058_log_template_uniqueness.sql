@@ -0,0 +1,53 @@
+-- Migration 058: Log Template Uniqueness
+-- Backend instances mining the same collector's logs could each store a
+-- template. Duplicates are merged into the oldest copy and a unique index
+-- makes concurrent inserts of a template add to one row.
+
+BEGIN;
+
+-- ============================================================================
+-- MERGE DUPLICATE TEMPLATES
+-- ============================================================================
+
+CREATE TEMP TABLE log_template_duplicates ON COMMIT DROP AS
+SELECT id, keep_id
+FROM (
+    SELECT id, MIN(id) OVER (PARTITION BY collector_id, md5(template)) AS keep_id
+    FROM log_templates
+) t
+WHERE id <> keep_id;
+
+INSERT INTO log_template_counts (template_id, bucket, count)
+SELECT d.keep_id, c.bucket, SUM(c.count)
+FROM log_template_counts c
+JOIN log_template_duplicates d ON d.id = c.template_id
+GROUP BY d.keep_id, c.bucket
+ON CONFLICT (template_id, bucket) DO UPDATE
+SET count = log_template_counts.count + EXCLUDED.count;
+
+UPDATE log_templates t
+SET total_count = t.total_count + m.total_count,
+    first_seen = LEAST(t.first_seen, m.first_seen),
+    last_seen = GREATEST(t.last_seen, m.last_seen)
+FROM (
+    SELECT d.keep_id, SUM(l.total_count) AS total_count,
+           MIN(l.first_seen) AS first_seen, MAX(l.last_seen) AS last_seen
+    FROM log_templates l
+    JOIN log_template_duplicates d ON d.id = l.id
+    GROUP BY d.keep_id
+) m
+WHERE t.id = m.keep_id;
+
+DELETE FROM log_templates t
+USING log_template_duplicates d
+WHERE t.id = d.id;
+
+-- ============================================================================
+-- UNIQUE TEMPLATES PER COLLECTOR
+-- ============================================================================
+
+-- Hashed, since templates of long messages exceed the btree entry size
+CREATE UNIQUE INDEX IF NOT EXISTS idx_log_templates_collector_template
+    ON log_templates (collector_id, md5(template));
+
+COMMIT;
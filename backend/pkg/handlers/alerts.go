@@ -6,6 +6,11 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/cluster_events"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/lock_analysis"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/slot_risk"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/wraparound"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
 	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 	"github.com/torresglauco/pganalytics-v3/backend/pkg/services"
@@ -133,16 +138,21 @@ func (h *AlertRulesHandler) CreateAlertRule(w http.ResponseWriter, r *http.Reque
 
 	// Validate rule type
 	validRuleTypes := map[string]bool{
-		"threshold": true,
-		"change":    true,
-		"anomaly":   true,
-		"composite": true,
+		"threshold":        true,
+		"change":           true,
+		"anomaly":          true,
+		"composite":        true,
+		"blocking_chain":   true,
+		"log":              true,
+		"wraparound":       true,
+		"replication_slot": true,
+		"cluster_event":    true,
 	}
 	if !validRuleTypes[req.Rule.RuleType] {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(CreateAlertRuleResponse{
 			Success: false,
-			Error:   "Invalid rule type. Valid types: threshold, change, anomaly, composite",
+			Error:   "Invalid rule type. Valid types: threshold, change, anomaly, composite, blocking_chain, log, wraparound, replication_slot, cluster_event",
 		})
 		return
 	}
@@ -166,19 +176,14 @@ func (h *AlertRulesHandler) CreateAlertRule(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Validate condition JSON using ConditionValidator if condition is present
-	if len(req.Rule.Condition) > 0 {
-		var condition models.AlertCondition
-		if err := json.Unmarshal(req.Rule.Condition, &condition); err == nil {
-			if err := h.validator.Validate(condition); err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(CreateAlertRuleResponse{
-					Success: false,
-					Error:   "Invalid condition: " + err.Error(),
-				})
-				return
-			}
-		}
+	// Validate condition JSON if condition is present
+	if err := h.validateCondition(req.Rule.RuleType, req.Rule.Condition); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(CreateAlertRuleResponse{
+			Success: false,
+			Error:   "Invalid condition: " + err.Error(),
+		})
+		return
 	}
 
 	// Set defaults
@@ -370,19 +375,29 @@ func (h *AlertRulesHandler) UpdateAlertRule(w http.ResponseWriter, r *http.Reque
 	// Set ID for update
 	req.Rule.ID = id
 
-	// Validate condition if provided
-	if len(req.Rule.Condition) > 0 {
-		var condition models.AlertCondition
-		if err := json.Unmarshal(req.Rule.Condition, &condition); err == nil {
-			if err := h.validator.Validate(condition); err != nil {
-				w.WriteHeader(http.StatusBadRequest)
-				json.NewEncoder(w).Encode(UpdateAlertRuleResponse{
-					Success: false,
-					Error:   "Invalid condition: " + err.Error(),
-				})
-				return
-			}
+	// Keep the stored rule type when the update leaves it out, so that the
+	// condition is validated against the type it will be evaluated as
+	if req.Rule.RuleType == "" {
+		existing, err := h.repo.GetRuleByID(id)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(UpdateAlertRuleResponse{
+				Success: false,
+				Error:   "Alert rule not found: " + err.Error(),
+			})
+			return
 		}
+		req.Rule.RuleType = existing.RuleType
+	}
+
+	// Validate condition if provided
+	if err := h.validateCondition(req.Rule.RuleType, req.Rule.Condition); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(UpdateAlertRuleResponse{
+			Success: false,
+			Error:   "Invalid condition: " + err.Error(),
+		})
+		return
 	}
 
 	// Update rule
@@ -405,6 +420,41 @@ func (h *AlertRulesHandler) UpdateAlertRule(w http.ResponseWriter, r *http.Reque
 	})
 }
 
+// validateCondition validates a rule's condition JSON. Rule types with their
+// own condition schema require a valid condition; other conditions are checked
+// with the ConditionValidator when they decode as a metric condition.
+func (h *AlertRulesHandler) validateCondition(ruleType string, condition json.RawMessage) error {
+	switch ruleType {
+	case "blocking_chain":
+		_, err := lock_analysis.ParseAlertCondition(condition)
+		return err
+
+	case "log":
+		_, err := log_analysis.ParseLogAlertCondition(condition)
+		return err
+
+	case "wraparound":
+		_, err := wraparound.ParseAlertCondition(condition)
+		return err
+
+	case "replication_slot":
+		_, err := slot_risk.ParseAlertCondition(condition)
+		return err
+
+	case "cluster_event":
+		_, err := cluster_events.ParseAlertCondition(condition)
+		return err
+	}
+
+	if len(condition) > 0 {
+		var metricCondition models.AlertCondition
+		if err := json.Unmarshal(condition, &metricCondition); err == nil {
+			return h.validator.Validate(metricCondition)
+		}
+	}
+	return nil
+}
+
 // DeleteAlertRule handles DELETE /api/v1/alert-rules/:id
 func (h *AlertRulesHandler) DeleteAlertRule(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
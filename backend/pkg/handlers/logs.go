@@ -2,22 +2,30 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
 	"github.com/torresglauco/pganalytics-v3/backend/pkg/models"
 	"github.com/torresglauco/pganalytics-v3/backend/pkg/services"
 )
 
-// IngestLogsRequest is the request body for POST /api/v1/logs/ingest
+// IngestLogsRequest is the request body for POST /api/v1/logs/ingest.
+// Logs holds pre-parsed entries; Raw holds server log output in Format
+// (stderr, csvlog or jsonlog), parsed with LogLinePrefix for stderr.
 type IngestLogsRequest struct {
-	CollectorID string                   `json:"collector_id"`
-	InstanceID  int                      `json:"instance_id"`
-	Logs        []map[string]interface{} `json:"logs"`
+	CollectorID   string                   `json:"collector_id"`
+	InstanceID    int                      `json:"instance_id"`
+	Logs          []map[string]interface{} `json:"logs"`
+	Format        string                   `json:"format,omitempty"`
+	LogLinePrefix string                   `json:"log_line_prefix,omitempty"`
+	Raw           string                   `json:"raw,omitempty"`
 }
 
 // IngestLogsResponse is the response body
@@ -28,8 +36,10 @@ type IngestLogsResponse struct {
 	Message  string   `json:"message,omitempty"`
 }
 
-// IngestLogs handles POST /api/v1/logs/ingest
-func IngestLogs(db *storage.PostgresDB, wsManager *services.ConnectionManager) http.HandlerFunc {
+// IngestLogs handles POST /api/v1/logs/ingest. authenticatedCollectorID is
+// the collector whose token authenticated the request; logs are only accepted
+// for that collector.
+func IngestLogs(db *storage.PostgresDB, wsManager *services.ConnectionManager, logStream *log_analysis.LogStream, templates *log_analysis.TemplateService, authenticatedCollectorID string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -39,32 +49,6 @@ func IngestLogs(db *storage.PostgresDB, wsManager *services.ConnectionManager) h
 			return
 		}
 
-		// Extract API token from Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(IngestLogsResponse{
-				Success: false,
-				Message: "Missing authorization header",
-			})
-			return
-		}
-
-		// Validate token format "Bearer {token}"
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(IngestLogsResponse{
-				Success: false,
-				Message: "Invalid authorization header format",
-			})
-			return
-		}
-
-		apiToken := parts[1]
-		// TODO: Validate API token against database
-		_ = apiToken
-
 		// Parse request body
 		var req IngestLogsRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -97,9 +81,122 @@ func IngestLogs(db *storage.PostgresDB, wsManager *services.ConnectionManager) h
 			return
 		}
 
+		// A collector token only authorizes logs of its own collector
+		if req.CollectorID != authenticatedCollectorID {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(IngestLogsResponse{
+				Success: false,
+				Message: "Collector ID mismatch",
+			})
+			return
+		}
+
+		// Redact query text and messages per the collector's tenant policy,
+		// falling back to normalized text if the policy cannot be loaded
+		redactor, _ := query_performance.NewRedactor(models.RedactionModeNone, nil)
+		if db != nil {
+			loaded, err := query_performance.LoadCollectorRedactor(r.Context(), db, req.CollectorID)
+			if err != nil {
+				log.Printf("Failed to load query redaction policy for collector %s: %v", req.CollectorID, err)
+				loaded, _ = query_performance.NewRedactor(models.RedactionModeNormalize, nil)
+			}
+			redactor = loaded
+		}
+
+		// Classify with the tenant's custom categories ahead of the built-in rules
+		classifier := log_analysis.NewLogParser()
+		if db != nil {
+			loaded, err := log_analysis.LoadCollectorLogParser(r.Context(), db, req.CollectorID)
+			if err != nil {
+				log.Printf("Failed to load log category rules for collector %s: %v", req.CollectorID, err)
+			} else {
+				classifier = loaded
+			}
+		}
+
 		// Process each log
 		ingestedCount := 0
 		errors := []string{}
+		var ingested []*models.PostgreSQLLog
+
+		// store persists a validated entry and the auto_explain plan it
+		// carries, and broadcasts it to log viewers
+		store := func(pgLog *models.PostgreSQLLog, plan *models.ExplainPlan, id int) bool {
+			if db != nil {
+				stored, err := db.InsertPostgresqlLog(r.Context(), pgLog)
+				if err != nil {
+					errors = append(errors, fmt.Sprintf("Log %d: failed to store: %v", id, err))
+					return false
+				}
+				pgLog = stored
+
+				if plan != nil {
+					if err := db.StoreExplainPlan(r.Context(), plan); err != nil {
+						log.Printf("Failed to store auto_explain plan for collector %s: %v", req.CollectorID, err)
+					}
+				}
+			}
+			ingestedCount++
+			ingested = append(ingested, pgLog)
+
+			wsManager.BroadcastLogEvent(map[string]interface{}{
+				"id":          id,
+				"timestamp":   pgLog.LogTimestamp.Format(time.RFC3339),
+				"level":       pgLog.LogLevel,
+				"message":     pgLog.LogMessage,
+				"instance_id": req.InstanceID,
+			}, req.InstanceID)
+
+			log.Printf("Ingested log: level=%s, instance=%d", pgLog.LogLevel, req.InstanceID)
+			return true
+		}
+
+		if req.Raw != "" {
+			parser, err := log_analysis.NewStructuredLogParser(log_analysis.LogFormat(req.Format), req.LogLinePrefix)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(IngestLogsResponse{
+					Success: false,
+					Message: "Invalid log format: " + err.Error(),
+				})
+				return
+			}
+
+			parsed, err := parser.Parse(strings.NewReader(req.Raw))
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(IngestLogsResponse{
+					Success: false,
+					Message: "Failed to read raw logs: " + err.Error(),
+				})
+				return
+			}
+			errors = append(errors, parsed.Errors...)
+
+			now := time.Now()
+			for i, pgLog := range parsed.Logs {
+				if pgLog.LogTimestamp.After(now) {
+					errors = append(errors, fmt.Sprintf("Log %d: timestamp in future", i))
+					continue
+				}
+				if now.Sub(pgLog.LogTimestamp) > 24*time.Hour {
+					errors = append(errors, fmt.Sprintf("Log %d: timestamp older than 24h", i))
+					continue
+				}
+
+				pgLog.CollectorID = collectorID
+				pgLog.InstanceID = req.InstanceID
+				category := string(classifier.Classify(pgLog))
+				pgLog.Category = &category
+				pgLog.QueryText = redactOptional(pgLog.QueryText, redactor.RedactQuery)
+				plan := slowQueryPlan(pgLog, redactor)
+				pgLog.LogMessage = redactor.RedactLogMessage(pgLog.LogMessage)
+				pgLog.ErrorDetail = redactOptional(pgLog.ErrorDetail, redactor.RedactLogMessage)
+				pgLog.ErrorHint = redactOptional(pgLog.ErrorHint, redactor.RedactLogMessage)
+				pgLog.ErrorContext = redactOptional(pgLog.ErrorContext, redactor.RedactLogMessage)
+				store(pgLog, plan, i)
+			}
+		}
 
 		for i, logData := range req.Logs {
 			// Validate required fields
@@ -148,39 +245,48 @@ func IngestLogs(db *storage.PostgresDB, wsManager *services.ConnectionManager) h
 
 			// Create PostgreSQLLog model
 			pgLog := &models.PostgreSQLLog{
-				CollectorID:    collectorID,
-				InstanceID:     req.InstanceID,
-				LogTimestamp:   parsedTime,
-				LogLevel:       level,
-				LogMessage:     message,
-				SourceLocation: getOptionalString(logData, "source_location"),
-				ProcessID:      getOptionalInt(logData, "process_id"),
-				QueryText:      getOptionalString(logData, "query_text"),
-				QueryHash:      getOptionalInt64(logData, "query_hash"),
-				ErrorCode:      getOptionalString(logData, "error_code"),
-				ErrorDetail:    getOptionalString(logData, "error_detail"),
-				ErrorHint:      getOptionalString(logData, "error_hint"),
-				ErrorContext:   getOptionalString(logData, "error_context"),
-				UserName:       getOptionalString(logData, "user_name"),
-				ConnectionFrom: getOptionalString(logData, "connection_from"),
-				SessionID:      getOptionalString(logData, "session_id"),
-			}
-
-			// Insert log into database
-			// TODO: Use actual storage method when available
-			_ = pgLog
-			ingestedCount++
+				CollectorID:     collectorID,
+				InstanceID:      req.InstanceID,
+				LogTimestamp:    parsedTime,
+				LogLevel:        level,
+				LogMessage:      message,
+				SourceLocation:  getOptionalString(logData, "source_location"),
+				ProcessID:       getOptionalInt(logData, "process_id"),
+				QueryText:       redactOptional(getOptionalString(logData, "query_text"), redactor.RedactQuery),
+				QueryHash:       getOptionalInt64(logData, "query_hash"),
+				ErrorCode:       getOptionalString(logData, "error_code"),
+				ErrorDetail:     redactOptional(getOptionalString(logData, "error_detail"), redactor.RedactLogMessage),
+				ErrorHint:       redactOptional(getOptionalString(logData, "error_hint"), redactor.RedactLogMessage),
+				ErrorContext:    redactOptional(getOptionalString(logData, "error_context"), redactor.RedactLogMessage),
+				UserName:        getOptionalString(logData, "user_name"),
+				ConnectionFrom:  getOptionalString(logData, "connection_from"),
+				SessionID:       getOptionalString(logData, "session_id"),
+				DatabaseName:    getOptionalString(logData, "database_name"),
+				ApplicationName: getOptionalString(logData, "application_name"),
+				BackendType:     getOptionalString(logData, "backend_type"),
+			}
 
-			// Broadcast WebSocket event
-			wsManager.BroadcastLogEvent(map[string]interface{}{
-				"id":          i,
-				"timestamp":   timestamp,
-				"level":       level,
-				"message":     message,
-				"instance_id": req.InstanceID,
-			}, req.InstanceID)
+			// Classify before redaction so that patterns see the original text
+			category := string(classifier.Classify(pgLog))
+			pgLog.Category = &category
+			plan := slowQueryPlan(pgLog, redactor)
+			pgLog.LogMessage = redactor.RedactLogMessage(message)
 
-			log.Printf("Ingested log: level=%s, instance=%d", level, req.InstanceID)
+			store(pgLog, plan, i)
+		}
+
+		// Push the batch to log stream subscribers on every replica
+		if logStream != nil {
+			if err := logStream.Publish(r.Context(), collectorID, ingested); err != nil {
+				log.Printf("Failed to publish logs for collector %s: %v", req.CollectorID, err)
+			}
+		}
+
+		// Group the batch into log templates for the template views
+		if templates != nil && len(ingested) > 0 {
+			if err := templates.ObserveLogs(r.Context(), collectorID, ingested); err != nil {
+				log.Printf("Failed to mine log templates for collector %s: %v", req.CollectorID, err)
+			}
 		}
 
 		// Return response
@@ -194,6 +300,36 @@ func IngestLogs(db *storage.PostgresDB, wsManager *services.ConnectionManager) h
 }
 
 // Helper functions
+
+// slowQueryPlan reads a log_min_duration_statement or auto_explain message
+// before it is redacted: the statement fills in missing query text and an
+// auto_explain plan is returned for storage
+func slowQueryPlan(pgLog *models.PostgreSQLLog, redactor *query_performance.Redactor) *models.ExplainPlan {
+	parsed, ok, err := query_performance.ParseDurationLog(pgLog.LogMessage)
+	if ok && err == nil && pgLog.QueryText == nil && parsed.QueryText != "" {
+		queryText := redactor.RedactQuery(parsed.QueryText)
+		pgLog.QueryText = &queryText
+	}
+	if !ok {
+		return nil
+	}
+
+	plan, err := query_performance.ExplainPlanFromLog(pgLog, redactor)
+	if err != nil {
+		log.Printf("Failed to extract auto_explain plan: %v", err)
+		return nil
+	}
+	return plan
+}
+
+func redactOptional(val *string, redact func(string) string) *string {
+	if val == nil {
+		return nil
+	}
+	redacted := redact(*val)
+	return &redacted
+}
+
 func getOptionalString(data map[string]interface{}, key string) *string {
 	if val, ok := data[key].(string); ok {
 		return &val
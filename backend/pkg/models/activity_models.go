@@ -0,0 +1,169 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// ACTIVE SESSION HISTORY MODELS
+// ============================================================================
+
+// WaitEventCPU labels active sessions that are not waiting, following the ASH
+// convention of reporting them as on CPU
+const WaitEventCPU = "CPU"
+
+// ActivitySamplesRequest represents pg_stat_activity samples pushed by a collector
+// Each snapshot is one sampling pass over pg_stat_activity; a push usually
+// carries every snapshot taken since the previous push.
+type ActivitySamplesRequest struct {
+	Type            string             `json:"type"`             // "pg_activity_samples"
+	IntervalSeconds float64            `json:"interval_seconds"` // Sampling period, each sample accounts for this much DB time
+	Snapshots       []ActivitySnapshot `json:"snapshots"`
+}
+
+// ActivitySnapshot is the set of sessions seen in a single sampling pass
+type ActivitySnapshot struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Sessions  []ActivitySession `json:"sessions"`
+}
+
+// ActivitySession is a single pg_stat_activity row
+type ActivitySession struct {
+	Database        string     `json:"database"`
+	PID             int        `json:"pid"`
+	Username        string     `json:"username"`
+	ApplicationName string     `json:"application_name"`
+	ClientAddr      string     `json:"client_addr"`
+	BackendType     string     `json:"backend_type"`
+	State           string     `json:"state"`
+	WaitEventType   string     `json:"wait_event_type"`
+	WaitEvent       string     `json:"wait_event"`
+	QueryID         *int64     `json:"query_id,omitempty"` // PostgreSQL 14+ with compute_query_id
+	Query           string     `json:"query"`
+	BackendStart    *time.Time `json:"backend_start,omitempty"`
+	XactStart       *time.Time `json:"xact_start,omitempty"`
+	QueryStart      *time.Time `json:"query_start,omitempty"`
+}
+
+// ActivitySample is a stored active session history row
+type ActivitySample struct {
+	Time             time.Time  `json:"time" db:"time"`
+	CollectorID      uuid.UUID  `json:"collector_id" db:"collector_id"`
+	DatabaseName     string     `json:"database_name" db:"database_name"`
+	PID              int        `json:"pid" db:"pid"`
+	Username         string     `json:"username" db:"usename"`
+	ApplicationName  string     `json:"application_name" db:"application_name"`
+	ClientAddr       string     `json:"client_addr" db:"client_addr"`
+	BackendType      string     `json:"backend_type" db:"backend_type"`
+	State            string     `json:"state" db:"state"`
+	WaitEventType    string     `json:"wait_event_type" db:"wait_event_type"`
+	WaitEvent        string     `json:"wait_event" db:"wait_event"`
+	QueryID          *int64     `json:"query_id,omitempty" db:"query_id"`
+	QueryFingerprint string     `json:"query_fingerprint" db:"query_fingerprint"`
+	QueryText        string     `json:"query_text" db:"query_text"`
+	BackendStart     *time.Time `json:"backend_start,omitempty" db:"backend_start"`
+	XactStart        *time.Time `json:"xact_start,omitempty" db:"xact_start"`
+	QueryStart       *time.Time `json:"query_start,omitempty" db:"query_start"`
+	SampleSeconds    float64    `json:"sample_seconds" db:"sample_seconds"`
+}
+
+// ActivityFilter selects active session history samples
+type ActivityFilter struct {
+	CollectorID uuid.UUID
+	From        time.Time
+	To          time.Time
+	Database    *string
+	Fingerprint *string
+	Limit       int
+}
+
+// ActivityTotals summarizes all samples in a window
+type ActivityTotals struct {
+	Samples       int64   `json:"samples"`
+	DBTimeSeconds float64 `json:"db_time_seconds"`
+}
+
+// WaitEventActivity is DB time spent in a wait event
+type WaitEventActivity struct {
+	WaitEventType string  `json:"wait_event_type"`
+	WaitEvent     string  `json:"wait_event"`
+	Samples       int64   `json:"samples"`
+	DBTimeSeconds float64 `json:"db_time_seconds"`
+	Percent       float64 `json:"percent"` // Share of DB time in the window
+}
+
+// SessionActivity is DB time attributed to a backend
+type SessionActivity struct {
+	PID             int        `json:"pid"`
+	DatabaseName    string     `json:"database_name"`
+	Username        string     `json:"username"`
+	ApplicationName string     `json:"application_name"`
+	ClientAddr      string     `json:"client_addr"`
+	BackendType     string     `json:"backend_type"`
+	BackendStart    *time.Time `json:"backend_start,omitempty"`
+	Samples         int64      `json:"samples"`
+	DBTimeSeconds   float64    `json:"db_time_seconds"`
+	Percent         float64    `json:"percent"`
+	TopWaitEvent    string     `json:"top_wait_event"` // "Type:Event", or CPU
+	Queries         int        `json:"queries"`        // Distinct fingerprints sampled
+}
+
+// QueryActivity is DB time attributed to a query fingerprint
+type QueryActivity struct {
+	QueryFingerprint string  `json:"query_fingerprint"`
+	QueryIDs         []int64 `json:"query_ids"`
+	QueryText        string  `json:"query_text"`
+	Samples          int64   `json:"samples"`
+	DBTimeSeconds    float64 `json:"db_time_seconds"`
+	Percent          float64 `json:"percent"`
+	TopWaitEvent     string  `json:"top_wait_event"`
+	Sessions         int     `json:"sessions"` // Distinct backends sampled
+}
+
+// WaitEventBucket is DB time per wait event type in a time bucket
+type WaitEventBucket struct {
+	Time          time.Time `json:"time"`
+	WaitEventType string    `json:"wait_event_type"`
+	Samples       int64     `json:"samples"`
+	DBTimeSeconds float64   `json:"db_time_seconds"`
+}
+
+// ActivityResponse is the common envelope of active session history reports
+type ActivityResponse struct {
+	CollectorID       uuid.UUID `json:"collector_id"`
+	From              time.Time `json:"from"`
+	To                time.Time `json:"to"`
+	TotalSamples      int64     `json:"total_samples"`
+	DBTimeSeconds     float64   `json:"db_time_seconds"`
+	AvgActiveSessions float64   `json:"avg_active_sessions"` // DB time divided by window length
+}
+
+// TopWaitEventsResponse is the response for top wait events
+type TopWaitEventsResponse struct {
+	ActivityResponse
+	WaitEvents []*WaitEventActivity `json:"wait_events"`
+}
+
+// TopSessionsResponse is the response for top sessions by DB time
+type TopSessionsResponse struct {
+	ActivityResponse
+	Sessions []*SessionActivity `json:"sessions"`
+}
+
+// TopActivityQueriesResponse is the response for top queries by DB time
+type TopActivityQueriesResponse struct {
+	ActivityResponse
+	Queries []*QueryActivity `json:"queries"`
+}
+
+// FingerprintWaitProfile is the wait breakdown of a single query fingerprint
+type FingerprintWaitProfile struct {
+	ActivityResponse
+	QueryFingerprint string               `json:"query_fingerprint"`
+	QueryText        string               `json:"query_text"`
+	WaitEvents       []*WaitEventActivity `json:"wait_events"`
+	Timeline         []*WaitEventBucket   `json:"timeline"`
+	BucketSeconds    int                  `json:"bucket_seconds"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// CUSTOM HEALTH CHECK PACK MODELS
+// ============================================================================
+
+// HealthCheckPack is a tenant's versioned bundle of custom health checks
+type HealthCheckPack struct {
+	ID          int64                 `json:"id" db:"id"`
+	TenantID    uuid.UUID             `json:"tenant_id" db:"tenant_id"`
+	Name        string                `json:"name" db:"name"`
+	Version     string                `json:"version" db:"version"`
+	Description string                `json:"description,omitempty" db:"description"`
+	CheckCount  int                   `json:"check_count" db:"check_count"`
+	Checks      []*VersionHealthCheck `json:"checks,omitempty"`
+	CreatedAt   time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// HealthCheckPackRequest creates a check pack, or updates its name, version
+// and description when Checks is not used
+type HealthCheckPackRequest struct {
+	Name        string                `json:"name" binding:"required"`
+	Version     string                `json:"version" binding:"required"`
+	Description string                `json:"description,omitempty"`
+	Checks      []*HealthCheckRequest `json:"checks,omitempty"`
+}
+
+// HealthCheckRequest authors a custom health check
+type HealthCheckRequest struct {
+	CheckName      string `json:"check_name" binding:"required"`
+	Description    string `json:"description,omitempty"`
+	Category       string `json:"category,omitempty"` // Defaults to configuration
+	Severity       string `json:"severity" binding:"required"`
+	MinVersion     int    `json:"min_version,omitempty"` // 0 = any version
+	MaxVersion     int    `json:"max_version,omitempty"` // 0 = no upper limit
+	CheckQuery     string `json:"check_query" binding:"required"`
+	AssertionType  string `json:"assertion_type" binding:"required"`
+	AssertionValue string `json:"assertion_value,omitempty"`
+	ExpectedResult string `json:"expected_result,omitempty"`
+	Remediation    string `json:"remediation,omitempty"`
+}
+
+// CollectorGroup is a named set of a tenant's collectors that check packs are
+// scheduled on
+type CollectorGroup struct {
+	ID           int64       `json:"id" db:"id"`
+	TenantID     uuid.UUID   `json:"tenant_id" db:"tenant_id"`
+	Name         string      `json:"name" db:"name"`
+	Description  string      `json:"description,omitempty" db:"description"`
+	CollectorIDs []uuid.UUID `json:"collector_ids"`
+	CreatedAt    time.Time   `json:"created_at" db:"created_at"`
+}
+
+// CollectorGroupRequest creates a collector group
+type CollectorGroupRequest struct {
+	Name         string      `json:"name" binding:"required"`
+	Description  string      `json:"description,omitempty"`
+	CollectorIDs []uuid.UUID `json:"collector_ids,omitempty"`
+}
+
+// CollectorGroupMembersRequest replaces the collectors of a group
+type CollectorGroupMembersRequest struct {
+	CollectorIDs []uuid.UUID `json:"collector_ids"`
+}
+
+// HealthCheckSchedule runs a check pack on the collectors of a group
+type HealthCheckSchedule struct {
+	ID              int64     `json:"id" db:"id"`
+	PackID          int64     `json:"pack_id" db:"pack_id"`
+	GroupID         int64     `json:"group_id" db:"group_id"`
+	GroupName       string    `json:"group_name" db:"group_name"`
+	IntervalSeconds int       `json:"interval_seconds" db:"interval_seconds"`
+	Enabled         bool      `json:"enabled" db:"enabled"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+// HealthCheckScheduleRequest schedules a check pack on a collector group
+type HealthCheckScheduleRequest struct {
+	GroupID         int64 `json:"group_id" binding:"required"`
+	IntervalSeconds int   `json:"interval_seconds,omitempty"` // Defaults to one hour
+	Enabled         *bool `json:"enabled,omitempty"`          // Defaults to true
+}
+
+// ScheduledHealthCheck is a custom check a collector runs, with the pack it
+// comes from and the shortest interval it is scheduled at
+type ScheduledHealthCheck struct {
+	Check           *VersionHealthCheck
+	PackName        string
+	IntervalSeconds int
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// CLUSTER EVENT MODELS
+// ============================================================================
+
+// Cluster event types
+const (
+	ClusterEventFailover       = "failover"        // standby promoted while its primary was gone or still primary
+	ClusterEventSwitchover     = "switchover"      // standby promoted and the old primary rejoined as a standby
+	ClusterEventPromotion      = "promotion"       // standby promoted without a known primary
+	ClusterEventDemotion       = "demotion"        // primary restarted as a standby
+	ClusterEventTimelineChange = "timeline_change" // timeline switched without a role change
+	ClusterEventUpstreamChange = "upstream_change" // standby follows another upstream
+)
+
+// NodeState is the identity and recovery state of a collector's server at
+// one pg_replication sample
+type NodeState struct {
+	Time             time.Time `json:"time" db:"time"`
+	CollectorID      uuid.UUID `json:"collector_id" db:"collector_id"`
+	SystemIdentifier string    `json:"system_identifier,omitempty" db:"system_identifier"`
+	TimelineID       int64     `json:"timeline_id" db:"timeline_id"`
+	InRecovery       bool      `json:"in_recovery" db:"in_recovery"`
+	ServerVersionNum int       `json:"server_version_num,omitempty" db:"server_version_num"`
+	UpstreamHost     string    `json:"upstream_host,omitempty" db:"upstream_host"` // from the WAL receiver, standbys only
+	UpstreamPort     int       `json:"upstream_port,omitempty" db:"upstream_port"`
+}
+
+// ClusterEvent is a role, timeline or upstream change of a node of a
+// physical replication cluster. It happened between PreviousSampleAt and
+// OccurredAt, the samples before and after the change.
+type ClusterEvent struct {
+	ID               int64          `json:"id" db:"id"`
+	TenantID         *uuid.UUID     `json:"tenant_id,omitempty" db:"tenant_id"`
+	ClusterID        string         `json:"cluster_id" db:"cluster_id"` // system identifier, or "collector:<id>" when unknown
+	CollectorID      uuid.UUID      `json:"collector_id" db:"collector_id"`
+	EventType        string         `json:"event_type" db:"event_type"`
+	OccurredAt       time.Time      `json:"occurred_at" db:"occurred_at"`
+	PreviousSampleAt time.Time      `json:"previous_sample_at" db:"previous_sample_at"`
+	Before           *NodeState     `json:"before" db:"before_state"`
+	After            *NodeState     `json:"after" db:"after_state"`
+	BeforeTopology   *FleetTopology `json:"before_topology,omitempty" db:"before_topology"` // the node's cluster; nil outside a tenant
+	AfterTopology    *FleetTopology `json:"after_topology,omitempty" db:"after_topology"`
+	PreviousPrimary  *uuid.UUID     `json:"previous_primary_id,omitempty" db:"previous_primary_id"`
+	Summary          string         `json:"summary" db:"summary"`
+	CreatedAt        time.Time      `json:"created_at" db:"created_at"`
+}
+
+// ClusterEventFilter selects cluster events
+type ClusterEventFilter struct {
+	TenantID   *uuid.UUID
+	ClusterID  string
+	EventTypes []string
+	From       time.Time
+	To         time.Time
+	Limit      int
+}
+
+// ClusterEventAlertCondition fires when the cluster of a collector had
+// events of EventTypes, any type when empty, within the last WindowSeconds
+type ClusterEventAlertCondition struct {
+	CollectorID   uuid.UUID `json:"collector_id"`
+	EventTypes    []string  `json:"event_types,omitempty"`
+	WindowSeconds int       `json:"window_seconds"` // default 300
+}
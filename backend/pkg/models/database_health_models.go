@@ -0,0 +1,113 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// DATABASE HEALTH SCORE MODELS
+// ============================================================================
+
+// Database health components, in the order they are reported
+const (
+	DatabaseHealthCacheHit       = "cache_hit"
+	DatabaseHealthReplicationLag = "replication_lag"
+	DatabaseHealthConnections    = "connections"
+	DatabaseHealthBloat          = "bloat"
+	DatabaseHealthWraparound     = "wraparound"
+	DatabaseHealthChecks         = "health_checks"
+	DatabaseHealthAnomalies      = "anomalies"
+	DatabaseHealthErrorLogs      = "error_logs"
+)
+
+// DatabaseHealthWeights defines the weight of each component in a database
+// health score. Weights are relative: they are normalized over the components
+// that have data, so they need not add up to 1.
+type DatabaseHealthWeights struct {
+	CacheHit       float64 `json:"cache_hit" db:"cache_hit"`             // Default: 0.15
+	ReplicationLag float64 `json:"replication_lag" db:"replication_lag"` // Default: 0.10
+	Connections    float64 `json:"connections" db:"connections"`         // Default: 0.15
+	Bloat          float64 `json:"bloat" db:"bloat"`                     // Default: 0.10
+	Wraparound     float64 `json:"wraparound" db:"wraparound"`           // Default: 0.15
+	HealthChecks   float64 `json:"health_checks" db:"health_checks"`     // Default: 0.15
+	Anomalies      float64 `json:"anomalies" db:"anomalies"`             // Default: 0.10
+	ErrorLogs      float64 `json:"error_logs" db:"error_logs"`           // Default: 0.10
+}
+
+// DefaultDatabaseHealthWeights are used for tenants that have not set their own
+var DefaultDatabaseHealthWeights = DatabaseHealthWeights{
+	CacheHit:       0.15,
+	ReplicationLag: 0.10,
+	Connections:    0.15,
+	Bloat:          0.10,
+	Wraparound:     0.15,
+	HealthChecks:   0.15,
+	Anomalies:      0.10,
+	ErrorLogs:      0.10,
+}
+
+// DatabaseHealthInputs are the raw signals a database health score is
+// calculated from. Nil fields have no recent data.
+type DatabaseHealthInputs struct {
+	CacheHitRatio    *float64            `json:"cache_hit_ratio"`    // 0-1, shared buffer hits over reads and hits
+	ReplicationLagMs *int64              `json:"replication_lag_ms"` // Largest replay lag of the latest sample
+	Connections      *int                `json:"connections"`        // Client backends in the latest activity sample
+	MaxConnections   *int                `json:"max_connections"`    // max_connections setting
+	DeadTupleRatio   *float64            `json:"dead_tuple_ratio"`   // 0-1, dead tuples over live and dead
+	WraparoundScore  *int                `json:"wraparound_score"`   // 0-100 from the wraparound assessment
+	WraparoundLevel  string              `json:"wraparound_level"`   // ok, warning, critical
+	HealthChecks     *HealthCheckSummary `json:"health_checks"`
+	LogAnomalies     *int                `json:"log_anomalies"`     // New or spiking log templates in the last hour
+	ErrorsPerMinute  *float64            `json:"errors_per_minute"` // ERROR, FATAL and PANIC log lines
+}
+
+// DatabaseHealthComponent is one component of a database health score with
+// the value it was scored from and why it scored what it did
+type DatabaseHealthComponent struct {
+	Name         string   `json:"name"`
+	Available    bool     `json:"available"`    // False when there is no recent data; the weight is redistributed
+	Score        float64  `json:"score"`        // 0-100
+	Weight       float64  `json:"weight"`       // Configured weight
+	Contribution float64  `json:"contribution"` // Points of the overall score this component accounts for
+	Value        *float64 `json:"value"`        // The measured value, nil when unavailable
+	Explanation  string   `json:"explanation"`
+}
+
+// DatabaseHealthScore is a calculated database health score with its components
+type DatabaseHealthScore struct {
+	Time        time.Time                  `json:"time" db:"time"`
+	CollectorID uuid.UUID                  `json:"collector_id" db:"collector_id"`
+	HealthScore int                        `json:"health_score" db:"health_score"` // 0-100
+	Status      string                     `json:"status" db:"status"`             // healthy, degraded, warning, critical
+	Components  []*DatabaseHealthComponent `json:"components" db:"components"`
+	Weights     DatabaseHealthWeights      `json:"weights" db:"weights"`
+}
+
+// DatabaseHealthHistoryResponse contains historical database health scores with pagination
+type DatabaseHealthHistoryResponse struct {
+	Scores     []*DatabaseHealthScore `json:"scores"`
+	Pagination PaginationParams       `json:"pagination"`
+}
+
+// DatabaseHealthRank is a collector's place in a tenant's fleet ranking.
+// Collectors without a score in the ranking window have a nil score.
+type DatabaseHealthRank struct {
+	Rank          int        `json:"rank"`
+	CollectorID   uuid.UUID  `json:"collector_id"`
+	CollectorName string     `json:"collector_name"`
+	Hostname      string     `json:"hostname"`
+	HealthScore   *int       `json:"health_score"`
+	Status        string     `json:"status"`
+	Weakest       string     `json:"weakest,omitempty"` // Available component with the lowest score
+	ScoredAt      *time.Time `json:"scored_at"`
+}
+
+// DatabaseHealthRanking ranks a tenant's collectors, worst score first
+type DatabaseHealthRanking struct {
+	TenantID    uuid.UUID             `json:"tenant_id"`
+	Collectors  []*DatabaseHealthRank `json:"collectors"`
+	Unscored    int                   `json:"unscored"`
+	GeneratedAt time.Time             `json:"generated_at"`
+}
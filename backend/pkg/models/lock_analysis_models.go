@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// LOCK ANALYSIS MODELS
+// ============================================================================
+
+// Lock finding types
+const (
+	LockFindingDeadlock            = "deadlock"
+	LockFindingLongChain           = "long_chain"
+	LockFindingLockUpgrade         = "lock_upgrade"
+	LockFindingIdleInTransaction   = "idle_in_transaction_blocker"
+	LockFindingExclusiveLockQueued = "exclusive_lock_queue"
+)
+
+// LockMetricsRequest represents pg_locks metrics pushed by a collector
+// pg_locks is cluster-wide, so the same locks may be reported under every
+// monitored database.
+type LockMetricsRequest struct {
+	Type      string                       `json:"type"` // "pg_locks"
+	Timestamp string                       `json:"timestamp"`
+	Databases map[string]DatabaseLockBatch `json:"databases"`
+}
+
+// DatabaseLockBatch is the lock data collected through one database connection
+type DatabaseLockBatch struct {
+	ActiveLocks    []CollectedLock     `json:"active_locks"`
+	LockWaitChains []CollectedLockWait `json:"lock_wait_chains"`
+}
+
+// CollectedLock is a pg_locks row as sent by the collector
+type CollectedLock struct {
+	PID            int      `json:"pid"`
+	LockType       string   `json:"locktype"`
+	Mode           string   `json:"mode"`
+	Granted        bool     `json:"granted"`
+	Relation       *int     `json:"relation,omitempty"`
+	Page           *int     `json:"page,omitempty"`
+	Tuple          *int     `json:"tuple,omitempty"`
+	Username       *string  `json:"username,omitempty"`
+	State          *string  `json:"state,omitempty"`
+	LockAgeSeconds *float64 `json:"lock_age_seconds,omitempty"`
+	Query          *string  `json:"query,omitempty"`
+}
+
+// CollectedLockWait is a blocked/blocking pair as sent by the collector
+type CollectedLockWait struct {
+	BlockedPID          int      `json:"blocked_pid"`
+	BlockingPID         int      `json:"blocking_pid"`
+	BlockedUser         string   `json:"blocked_user"`
+	BlockingUser        string   `json:"blocking_user"`
+	BlockedQuery        string   `json:"blocked_query"`
+	BlockingQuery       string   `json:"blocking_query"`
+	BlockedApplication  string   `json:"blocked_application"`
+	BlockingApplication string   `json:"blocking_application"`
+	WaitTimeSeconds     *float64 `json:"wait_time_seconds,omitempty"`
+}
+
+// LockEdge describes the lock a waiter is queued behind
+type LockEdge struct {
+	LockType   string `json:"locktype"`
+	WaitMode   string `json:"wait_mode"`           // Mode requested by the waiter
+	HeldMode   string `json:"held_mode,omitempty"` // Conflicting mode held by the blocker, when known
+	RelationID *int   `json:"relation_id,omitempty"`
+}
+
+// BlockingNode is a backend in a blocking tree
+type BlockingNode struct {
+	PID             int             `json:"pid"`
+	Username        string          `json:"username,omitempty"`
+	ApplicationName string          `json:"application_name,omitempty"`
+	SessionState    string          `json:"session_state,omitempty"`
+	Query           string          `json:"query,omitempty"`
+	WaitTimeSeconds *float64        `json:"wait_time_seconds,omitempty"` // Unset for the root blocker
+	WaitingOn       *LockEdge       `json:"waiting_on,omitempty"`        // Lock this backend waits for on its parent
+	Waiters         []*BlockingNode `json:"waiters"`
+}
+
+// BlockingTree is a root blocker and every backend waiting on it directly or transitively
+type BlockingTree struct {
+	Root           *BlockingNode `json:"root"`
+	TotalWaiters   int           `json:"total_waiters"`
+	Depth          int           `json:"depth"`            // Longest chain of waits below the root
+	MaxWaitSeconds float64       `json:"max_wait_seconds"` // Longest wait in the tree
+}
+
+// LockFinding is a detected blocking or deadlock-prone pattern
+type LockFinding struct {
+	Type     string `json:"type"`
+	Severity string `json:"severity"` // critical, warning
+	PIDs     []int  `json:"pids"`
+	Message  string `json:"message"`
+}
+
+// BlockingTreeSnapshot is the lock dependency graph at a point in time
+type BlockingTreeSnapshot struct {
+	CollectorID     uuid.UUID       `json:"collector_id"`
+	SnapshotTime    *time.Time      `json:"snapshot_time"` // Collection time of the snapshot used, unset when none exists
+	BlockedSessions int             `json:"blocked_sessions"`
+	Trees           []*BlockingTree `json:"trees"`
+	Findings        []*LockFinding  `json:"findings"`
+}
+
+// BlockingChainAlertCondition fires when a root blocker of a collector's
+// latest lock snapshot holds more than MinWaiters backends for longer than
+// MinDurationSeconds
+type BlockingChainAlertCondition struct {
+	CollectorID        uuid.UUID `json:"collector_id"`
+	MinWaiters         int       `json:"min_waiters"`
+	MinDurationSeconds int       `json:"min_duration_seconds"`
+}
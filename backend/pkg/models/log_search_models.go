@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// ============================================================================
+// LOG SEARCH MODELS
+// ============================================================================
+
+// LogSearchParams bounds a log search
+type LogSearchParams struct {
+	// InstanceIDs limits the search to the caller's instances; nil searches
+	// every instance
+	InstanceIDs []int
+	From        time.Time
+	To          time.Time
+	// After continues a search after the last hit of the previous page
+	After *LogSearchCursor
+	Limit int
+}
+
+// LogSearchCursor is the position of a hit in search order (newest first)
+// and the time range of the search it continues, so that later pages of a
+// search without an explicit end do not move with the clock
+type LogSearchCursor struct {
+	Timestamp time.Time
+	ID        int64
+	From      time.Time
+	To        time.Time
+}
+
+// LogHighlight is a byte range of a message matching the search text
+type LogHighlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// LogSearchHit is a log matching a search
+type LogSearchHit struct {
+	*PostgreSQLLog
+	Highlights []LogHighlight `json:"highlights"`
+}
+
+// LogFacetCount is the number of matching logs with a facet value
+type LogFacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// LogSearchFacets counts the matching logs by category, database and user
+type LogSearchFacets struct {
+	Categories []LogFacetCount `json:"categories"`
+	Databases  []LogFacetCount `json:"databases"`
+	Users      []LogFacetCount `json:"users"`
+}
+
+// LogSearchResponse is a page of log search results
+type LogSearchResponse struct {
+	Hits []*LogSearchHit `json:"hits"`
+	// NextCursor fetches the next page; empty on the last page
+	NextCursor string    `json:"next_cursor,omitempty"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	// Facets are computed over all matches on the first page only
+	Facets *LogSearchFacets `json:"facets,omitempty"`
+}
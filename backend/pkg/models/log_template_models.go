@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// LOG TEMPLATE MODELS
+// ============================================================================
+
+// Log template anomaly kinds
+const (
+	LogTemplateAnomalyNew   = "new"   // first seen inside the window
+	LogTemplateAnomalySpike = "spike" // window count far above the baseline rate
+)
+
+// LogTemplate is a group of log messages that differ only in their variable
+// tokens, which are written as <*> in the template
+type LogTemplate struct {
+	ID            int64     `json:"id" db:"id"`
+	CollectorID   uuid.UUID `json:"collector_id" db:"collector_id"`
+	Template      string    `json:"template" db:"template"`
+	TokenCount    int       `json:"token_count" db:"token_count"`
+	LogLevel      string    `json:"log_level" db:"log_level"`
+	Category      *string   `json:"category,omitempty" db:"category"`
+	SampleMessage string    `json:"sample_message" db:"sample_message"`
+	TotalCount    int64     `json:"total_count" db:"total_count"`
+	FirstSeen     time.Time `json:"first_seen" db:"first_seen"`
+	LastSeen      time.Time `json:"last_seen" db:"last_seen"`
+}
+
+// LogTemplateCount is the number of messages matching a template in a
+// one-minute bucket
+type LogTemplateCount struct {
+	TemplateID int64     `json:"template_id" db:"template_id"`
+	Bucket     time.Time `json:"bucket" db:"bucket"`
+	Count      int64     `json:"count" db:"count"`
+}
+
+// LogTemplateStats is a template's activity in a report window
+type LogTemplateStats struct {
+	*LogTemplate
+	WindowCount  int64    `json:"window_count"`
+	BaselineRate float64  `json:"baseline_rate"` // Mean count per window-length over the baseline period
+	SpikeRatio   *float64 `json:"spike_ratio,omitempty"`
+	Anomaly      string   `json:"anomaly,omitempty"` // new, spike
+}
+
+// LogTemplateReport lists the most frequent templates of a collector in a window
+type LogTemplateReport struct {
+	CollectorID       uuid.UUID           `json:"collector_id"`
+	From              time.Time           `json:"from"`
+	To                time.Time           `json:"to"`
+	BaselineFrom      time.Time           `json:"baseline_from"`
+	TotalMessages     int64               `json:"total_messages"`
+	DistinctTemplates int                 `json:"distinct_templates"`
+	Anomalies         int                 `json:"anomalies"`
+	Templates         []*LogTemplateStats `json:"templates"`
+	GeneratedAt       time.Time           `json:"generated_at"`
+}
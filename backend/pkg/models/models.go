@@ -637,11 +637,21 @@ type QueryFingerprintResponse struct {
 	LastSeen         time.Time `json:"last_seen"`
 }
 
+// Explain plan sources
+const (
+	ExplainPlanSourceCollector   = "collector"    // Captured by the collector with EXPLAIN
+	ExplainPlanSourceAutoExplain = "auto_explain" // Extracted from auto_explain log messages
+)
+
 // ExplainPlan represents a stored EXPLAIN plan output
 type ExplainPlan struct {
 	ID                   int64       `db:"id" json:"id"`
+	CollectorID          *uuid.UUID  `db:"collector_id" json:"collector_id,omitempty"`
+	Source               string      `db:"source" json:"source"`
 	QueryHash            int64       `db:"query_hash" json:"query_hash"`
 	QueryFingerprintHash *int64      `db:"query_fingerprint_hash" json:"query_fingerprint_hash,omitempty"`
+	QueryFingerprint     *string     `db:"query_fingerprint" json:"query_fingerprint,omitempty"`
+	QueryText            *string     `db:"query_text" json:"query_text,omitempty"`
 	CollectedAt          time.Time   `db:"collected_at" json:"collected_at"`
 	PlanJSON             interface{} `db:"plan_json" json:"plan_json"` // JSONB
 	PlanText             *string     `db:"plan_text" json:"plan_text,omitempty"`
@@ -655,6 +665,7 @@ type ExplainPlan struct {
 	HasNestedLoop        bool        `db:"has_nested_loop" json:"has_nested_loop"`
 	TotalBuffersRead     *int64      `db:"total_buffers_read" json:"total_buffers_read,omitempty"`
 	TotalBuffersHit      *int64      `db:"total_buffers_hit" json:"total_buffers_hit,omitempty"`
+	Issues               interface{} `db:"issues" json:"issues,omitempty"` // JSONB
 }
 
 // IndexRecommendation represents a recommended index
@@ -968,26 +979,45 @@ type CreateRegistrationSecretResponse struct {
 
 // PostgreSQLLog represents a PostgreSQL database log entry
 type PostgreSQLLog struct {
-	ID             int64     `db:"id" json:"id"`
-	CollectorID    uuid.UUID `db:"collector_id" json:"collector_id"`
-	InstanceID     int       `db:"instance_id" json:"instance_id"`
-	DatabaseID     *int      `db:"database_id" json:"database_id,omitempty"`
-	LogTimestamp   time.Time `db:"log_timestamp" json:"log_timestamp"`
-	LogLevel       string    `db:"log_level" json:"log_level"` // DEBUG, INFO, NOTICE, WARNING, ERROR, FATAL, PANIC
-	LogMessage     string    `db:"log_message" json:"log_message"`
-	SourceLocation *string   `db:"source_location" json:"source_location,omitempty"`
-	ProcessID      *int      `db:"process_id" json:"process_id,omitempty"`
-	QueryText      *string   `db:"query_text" json:"query_text,omitempty"`
-	QueryHash      *int64    `db:"query_hash" json:"query_hash,omitempty"`
-	ErrorCode      *string   `db:"error_code" json:"error_code,omitempty"`
-	ErrorDetail    *string   `db:"error_detail" json:"error_detail,omitempty"`
-	ErrorHint      *string   `db:"error_hint" json:"error_hint,omitempty"`
-	ErrorContext   *string   `db:"error_context" json:"error_context,omitempty"`
-	UserName       *string   `db:"user_name" json:"user_name,omitempty"`
-	ConnectionFrom *string   `db:"connection_from" json:"connection_from,omitempty"`
-	SessionID      *string   `db:"session_id" json:"session_id,omitempty"`
-	CreatedAt      time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+	ID              int64     `db:"id" json:"id"`
+	CollectorID     uuid.UUID `db:"collector_id" json:"collector_id"`
+	InstanceID      int       `db:"instance_id" json:"instance_id"`
+	DatabaseID      *int      `db:"database_id" json:"database_id,omitempty"`
+	LogTimestamp    time.Time `db:"log_timestamp" json:"log_timestamp"`
+	LogLevel        string    `db:"log_level" json:"log_level"` // DEBUG, INFO, NOTICE, WARNING, ERROR, FATAL, PANIC
+	LogMessage      string    `db:"log_message" json:"log_message"`
+	SourceLocation  *string   `db:"source_location" json:"source_location,omitempty"`
+	ProcessID       *int      `db:"process_id" json:"process_id,omitempty"`
+	QueryText       *string   `db:"query_text" json:"query_text,omitempty"`
+	QueryHash       *int64    `db:"query_hash" json:"query_hash,omitempty"`
+	ErrorCode       *string   `db:"error_code" json:"error_code,omitempty"`
+	ErrorDetail     *string   `db:"error_detail" json:"error_detail,omitempty"`
+	ErrorHint       *string   `db:"error_hint" json:"error_hint,omitempty"`
+	ErrorContext    *string   `db:"error_context" json:"error_context,omitempty"`
+	UserName        *string   `db:"user_name" json:"user_name,omitempty"`
+	ConnectionFrom  *string   `db:"connection_from" json:"connection_from,omitempty"`
+	SessionID       *string   `db:"session_id" json:"session_id,omitempty"`
+	DatabaseName    *string   `db:"database_name" json:"database_name,omitempty"`
+	ApplicationName *string   `db:"application_name" json:"application_name,omitempty"`
+	BackendType     *string   `db:"backend_type" json:"backend_type,omitempty"`
+	Category        *string   `db:"category" json:"category,omitempty"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Log stream message types
+const (
+	LogStreamMessageLog = "log" // A log matching the subscription filter
+	LogStreamMessageGap = "gap" // Logs were skipped; resume from ResumeFrom to fetch them
+)
+
+// LogStreamMessage is sent to log stream subscribers. Offsets are log IDs.
+type LogStreamMessage struct {
+	Type       string         `json:"type"`
+	Offset     int64          `json:"offset,omitempty"`
+	Log        *PostgreSQLLog `json:"log,omitempty"`
+	Dropped    int64          `json:"dropped,omitempty"`
+	ResumeFrom *int64         `json:"resume_from,omitempty"`
 }
 
 // LogEventHourly represents hourly aggregated log events
@@ -1047,6 +1077,36 @@ type AlertCondition struct {
 	Duration   int     `json:"duration"` // Duration in seconds
 }
 
+// Log alert grouping
+const (
+	LogAlertGroupByDatabase = "database"
+	LogAlertGroupByUser     = "user"
+)
+
+// LogAlertCondition fires when more than Threshold stored log entries match
+// within the last WindowSeconds. Matchers combine with AND.
+type LogAlertCondition struct {
+	CollectorID *uuid.UUID `json:"collector_id,omitempty"`
+	InstanceID  *int       `json:"instance_id,omitempty"`
+	Category    string     `json:"category,omitempty"`
+	MinSeverity string     `json:"min_severity,omitempty"` // e.g. ERROR also matches FATAL and PANIC
+	// SQLState matches an exact code (40P01) or a class (28)
+	SQLState      string `json:"sqlstate,omitempty"`
+	Pattern       string `json:"pattern,omitempty"` // Regular expression on the message
+	WindowSeconds int    `json:"window_seconds"`
+	Threshold     int64  `json:"threshold"`
+	// GroupBy counts per database or user; the condition fires for any group
+	GroupBy string `json:"group_by,omitempty"`
+}
+
+// LogMatchCount is the number of log entries matching a log alert condition
+// in one group
+type LogMatchCount struct {
+	Group    string    `json:"group,omitempty"`
+	Count    int64     `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
 // AlertSilence represents a silenced alert rule
 type AlertSilence struct {
 	ID            int64     `db:"id" json:"id"`
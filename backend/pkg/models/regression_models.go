@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// QUERY REGRESSION MODELS
+// ============================================================================
+
+// Regression statuses
+const (
+	RegressionStatusRegressed = "regressed"
+	RegressionStatusImproved  = "improved"
+	RegressionStatusUnchanged = "unchanged"
+	RegressionStatusNew       = "new"  // Only seen in the target window
+	RegressionStatusGone      = "gone" // Only seen in the baseline window
+)
+
+// DeployMarker records an application release against a collector
+type DeployMarker struct {
+	ID          int64     `json:"id" db:"id"`
+	CollectorID uuid.UUID `json:"collector_id" db:"collector_id"`
+	Version     string    `json:"version" db:"version"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	DeployedAt  time.Time `json:"deployed_at" db:"deployed_at"`
+	CreatedBy   *int      `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateDeployMarkerRequest is the body for recording a deploy marker
+type CreateDeployMarkerRequest struct {
+	Version     string     `json:"version" binding:"required"`
+	Description *string    `json:"description,omitempty"`
+	DeployedAt  *time.Time `json:"deployed_at,omitempty"` // Defaults to now
+}
+
+// QueryStatsSample is one stored pg_stat_statements row; counters are
+// cumulative since the last statistics reset
+type QueryStatsSample struct {
+	Time           time.Time `db:"time"`
+	DatabaseName   string    `db:"database_name"`
+	QueryHash      int64     `db:"query_hash"`
+	QueryText      string    `db:"query_text"`
+	Calls          int64     `db:"calls"`
+	TotalTime      float64   `db:"total_time"`
+	Rows           int64     `db:"rows"`
+	SharedBlksRead int64     `db:"shared_blks_read"`
+}
+
+// RegressionWindow is a half-open time window [From, To)
+type RegressionWindow struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// RegressionOptions controls which queries a regression report includes
+type RegressionOptions struct {
+	Database        *string
+	Alpha           float64 // Significance level for the latency change
+	MinCalls        int64   // Queries with fewer calls in both windows are skipped
+	SignificantOnly bool
+	Limit           int
+}
+
+// QueryWindowStats are a fingerprint's statistics over one window
+// Latencies are derived from counter deltas between consecutive samples;
+// P95IntervalMeanMs is the 95th percentile of those per-interval mean latencies.
+type QueryWindowStats struct {
+	Calls             int64   `json:"calls"`
+	TotalTimeMs       float64 `json:"total_time_ms"`
+	MeanTimeMs        float64 `json:"mean_time_ms"`
+	P95IntervalMeanMs float64 `json:"p95_interval_mean_ms"`
+	Rows              int64   `json:"rows"`
+	RowsPerCall       float64 `json:"rows_per_call"`
+	SharedBlksRead    int64   `json:"shared_blks_read"`
+	ReadsPerCall      float64 `json:"shared_blks_read_per_call"`
+	Intervals         int     `json:"intervals"` // Sampling intervals with calls
+}
+
+// QueryRegression compares a fingerprint between the baseline and target windows
+type QueryRegression struct {
+	QueryFingerprint         string            `json:"query_fingerprint"`
+	DatabaseName             string            `json:"database_name"`
+	QueryText                string            `json:"query_text"`
+	QueryHashes              []int64           `json:"query_hashes"`
+	Status                   string            `json:"status"`
+	Baseline                 *QueryWindowStats `json:"baseline"`
+	Target                   *QueryWindowStats `json:"target"`
+	MeanTimeChangePct        *float64          `json:"mean_time_change_percent,omitempty"`
+	P95IntervalMeanChangePct *float64          `json:"p95_interval_mean_change_percent,omitempty"`
+	CallsChangePct           *float64          `json:"calls_change_percent,omitempty"`
+	RowsPerCallChangePct     *float64          `json:"rows_per_call_change_percent,omitempty"`
+	ReadsPerCallChangePct    *float64          `json:"shared_blks_read_per_call_change_percent,omitempty"`
+	AddedDBTimeMs            float64           `json:"added_db_time_ms"` // Extra time the target calls spent at the new mean latency
+	PValue                   *float64          `json:"p_value,omitempty"`
+	Significant              bool              `json:"significant"`
+}
+
+// RegressionReport lists per-fingerprint changes between two windows, ranked
+// by added DB time
+type RegressionReport struct {
+	CollectorID     uuid.UUID          `json:"collector_id"`
+	Baseline        RegressionWindow   `json:"baseline"`
+	Target          RegressionWindow   `json:"target"`
+	DeployMarker    *DeployMarker      `json:"deploy_marker,omitempty"`
+	Alpha           float64            `json:"alpha"`
+	QueriesCompared int                `json:"queries_compared"`
+	Regressed       int                `json:"regressed"`
+	Improved        int                `json:"improved"`
+	AddedDBTimeMs   float64            `json:"added_db_time_ms"` // Sum over regressed queries
+	Queries         []*QueryRegression `json:"queries"`
+	GeneratedAt     time.Time          `json:"generated_at"`
+}
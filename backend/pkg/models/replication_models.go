@@ -46,6 +46,10 @@ type ReplicationSlot struct {
 	WalRetainedMb     int64     `json:"wal_retained_mb" db:"wal_retained_mb"`
 	BackendPid        int64     `json:"backend_pid" db:"backend_pid"`
 	BytesRetained     int64     `json:"bytes_retained" db:"bytes_retained"`
+	XminAge           *int64    `json:"xmin_age,omitempty" db:"xmin_age"`                 // age(xmin), physical slots with hot_standby_feedback
+	CatalogXminAge    *int64    `json:"catalog_xmin_age,omitempty" db:"catalog_xmin_age"` // age(catalog_xmin), logical slots
+	WalStatus         string    `json:"wal_status,omitempty" db:"wal_status"`             // reserved, extended, unreserved, lost (PG 13+)
+	SafeWalSizeBytes  *int64    `json:"safe_wal_size,omitempty" db:"safe_wal_size_bytes"` // WAL left before max_slot_wal_keep_size (PG 13+)
 }
 
 // ReplicationMetricsResponse contains all replication-related metrics
@@ -125,3 +129,186 @@ type ReplicationTopology struct {
 	DownstreamCount     int            `json:"downstream_count" db:"downstream_count"`
 	DownstreamNodes     []TopologyNode `json:"downstream_nodes,omitempty" db:"downstream_nodes"`
 }
+
+// ============================================================================
+// REPLICATION METRIC REQUESTS
+// ============================================================================
+
+// ReplicationMetricsRequest represents the pg_replication metric pushed by a
+// collector: pg_stat_replication and pg_replication_slots
+type ReplicationMetricsRequest struct {
+	Type              string                       `json:"type"` // "pg_replication"
+	Timestamp         string                       `json:"timestamp"`
+	ReplicationSlots  []*ReplicationSlot           `json:"replication_slots"`
+	ReplicationStatus []CollectedReplicationStatus `json:"replication_status"`
+	// CurrentWalLsn is pg_current_wal_lsn(), or pg_last_wal_receive_lsn() on
+	// a standby; slot retention is measured from it
+	CurrentWalLsn string              `json:"current_wal_lsn"`
+	WalStatus     *CollectedWalStatus `json:"wal_status"`
+	// Settings are WAL settings by name as pg_settings reports them, in MB
+	// (max_slot_wal_keep_size, -1 when unlimited, and max_wal_size)
+	Settings map[string]string `json:"settings"`
+	// Node is the server's identity and recovery state, the input of role
+	// change detection
+	Node *CollectedNodeState `json:"node"`
+}
+
+// CollectedNodeState is the identity and recovery state of the monitored
+// server: pg_control_system(), pg_control_checkpoint() and pg_is_in_recovery()
+type CollectedNodeState struct {
+	SystemIdentifier string `json:"system_identifier"` // shared by a primary and its physical standbys
+	TimelineID       int64  `json:"timeline_id"`
+	InRecovery       bool   `json:"in_recovery"`
+	ServerVersionNum int    `json:"server_version_num"`
+}
+
+// CollectedWalStatus is the pg_wal directory summary sent by the collector
+type CollectedWalStatus struct {
+	TotalSegments       int64   `json:"total_segments"`
+	WalDirectorySizeMb  int64   `json:"wal_directory_size_mb"`
+	GrowthRateMbPerHour float64 `json:"growth_rate_mb_per_hour"`
+}
+
+// CollectedReplicationStatus is a pg_stat_replication row as sent by the
+// collector, with backend_start in PostgreSQL text format
+type CollectedReplicationStatus struct {
+	ServerPID       int64  `json:"server_pid"`
+	Usename         string `json:"usename"`
+	ApplicationName string `json:"application_name"`
+	State           string `json:"state"`
+	SyncState       string `json:"sync_state"`
+	WriteLsn        string `json:"write_lsn"`
+	FlushLsn        string `json:"flush_lsn"`
+	ReplayLsn       string `json:"replay_lsn"`
+	WriteLagMs      int64  `json:"write_lag_ms"`
+	FlushLagMs      int64  `json:"flush_lag_ms"`
+	ReplayLagMs     int64  `json:"replay_lag_ms"`
+	BehindByMb      int64  `json:"behind_by_mb"`
+	ClientAddr      string `json:"client_addr"`
+	BackendStart    string `json:"backend_start"`
+}
+
+// LogicalReplicationMetricsRequest represents the pg_logical_replication
+// metric pushed by a collector: subscriptions and publications of every
+// database and the cluster's WAL receiver, empty on a primary
+type LogicalReplicationMetricsRequest struct {
+	Type                 string                  `json:"type"` // "pg_logical_replication"
+	Timestamp            string                  `json:"timestamp"`
+	LogicalSubscriptions []CollectedSubscription `json:"logical_subscriptions"`
+	Publications         []CollectedPublication  `json:"publications"`
+	WalReceiver          *WalReceiver            `json:"wal_receiver"`
+}
+
+// CollectedSubscription is a pg_stat_subscription row as sent by the collector
+type CollectedSubscription struct {
+	Database           string `json:"database"`
+	SubName            string `json:"sub_name"`
+	SubState           string `json:"sub_state"`
+	ReceivedLsn        string `json:"received_lsn"`
+	LatestEndLsn       string `json:"latest_end_lsn"`
+	LastMsgReceiptTime string `json:"last_msg_receipt_time"`
+	LastMsgSendTime    string `json:"last_msg_send_time"`
+	WorkerPid          int64  `json:"worker_pid"`
+	WorkerCount        int    `json:"worker_count"`
+}
+
+// CollectedPublication is a pg_publication row as sent by the collector
+type CollectedPublication struct {
+	Database     string `json:"database"`
+	PubName      string `json:"pub_name"`
+	PubOwner     string `json:"pub_owner"`
+	PubAllTables bool   `json:"pub_all_tables"`
+	PubInsert    bool   `json:"pub_insert"`
+	PubUpdate    bool   `json:"pub_update"`
+	PubDelete    bool   `json:"pub_delete"`
+	PubTruncate  bool   `json:"pub_truncate"`
+}
+
+// ============================================================================
+// FLEET TOPOLOGY MODELS
+// ============================================================================
+
+// Topology node roles
+const (
+	TopologyRolePrimary          = "primary"
+	TopologyRoleStandby          = "standby"
+	TopologyRoleCascadingStandby = "cascading_standby"
+	TopologyRoleExternal         = "external" // peer without a collector
+)
+
+// Topology edge types
+const (
+	TopologyEdgePhysical = "physical"
+	TopologyEdgeLogical  = "logical"
+)
+
+// ReplicationNodeSnapshot is the latest replication state one collector of a
+// tenant reported: its WAL receiver when it is a standby, the walsenders of
+// its downstream nodes and its logical subscriptions
+type ReplicationNodeSnapshot struct {
+	CollectorID   uuid.UUID              `json:"collector_id"`
+	Name          string                 `json:"name"`
+	Hostname      string                 `json:"hostname"`
+	Address       string                 `json:"address,omitempty"`
+	LastSeen      *time.Time             `json:"last_seen,omitempty"`
+	WalReceiver   *WalReceiver           `json:"wal_receiver,omitempty"`
+	Senders       []*ReplicationStatus   `json:"senders"`
+	Subscriptions []*LogicalSubscription `json:"subscriptions"`
+	State         *NodeState             `json:"state,omitempty"` // latest reported recovery state
+}
+
+// TopologyGraphNode is a node of a tenant's replication graph
+type TopologyGraphNode struct {
+	ID                  string     `json:"id"` // collector ID, or "external:<host>" for a peer without a collector
+	CollectorID         *uuid.UUID `json:"collector_id,omitempty"`
+	Name                string     `json:"name"`
+	Hostname            string     `json:"hostname,omitempty"`
+	Address             string     `json:"address,omitempty"`
+	Role                string     `json:"role"`
+	Publisher           bool       `json:"publisher"`  // feeds logical subscribers
+	Subscriber          bool       `json:"subscriber"` // has logical subscriptions
+	UpstreamCollectorID *uuid.UUID `json:"upstream_collector_id,omitempty"`
+	UpstreamHost        string     `json:"upstream_host,omitempty"`
+	UpstreamPort        int        `json:"upstream_port,omitempty"`
+	ClusterID           string     `json:"cluster_id"` // ID of the root of its physical tree
+	Depth               int        `json:"depth"`      // physical hops from the root
+}
+
+// TopologyEdge is a replication connection from an upstream node to a
+// downstream node
+type TopologyEdge struct {
+	From            string `json:"from"`
+	To              string `json:"to"`
+	Type            string `json:"type"` // physical, logical
+	ApplicationName string `json:"application_name,omitempty"`
+	ClientAddr      string `json:"client_addr,omitempty"`
+	State           string `json:"state,omitempty"`
+	SyncState       string `json:"sync_state,omitempty"`
+	WriteLagMs      int64  `json:"write_lag_ms"`
+	FlushLagMs      int64  `json:"flush_lag_ms"`
+	ReplayLagMs     int64  `json:"replay_lag_ms"`
+	BehindByMb      int64  `json:"behind_by_mb"`
+	SlotName        string `json:"slot_name,omitempty"`
+	// MatchedBy is how the downstream was resolved: subscription,
+	// application_name, client_addr or sender_host; empty for external peers
+	MatchedBy string `json:"matched_by,omitempty"`
+}
+
+// TopologyCluster is a physical replication tree: a primary, or an
+// unmonitored upstream, with its standbys and cascading standbys
+type TopologyCluster struct {
+	RootID         string   `json:"root_id"`
+	NodeIDs        []string `json:"node_ids"` // breadth first from the root
+	SyncStandbys   int      `json:"sync_standbys"`
+	MaxReplayLagMs int64    `json:"max_replay_lag_ms"`
+}
+
+// FleetTopology is the replication graph of all collectors of a tenant
+type FleetTopology struct {
+	TenantID    uuid.UUID            `json:"tenant_id"`
+	GeneratedAt time.Time            `json:"generated_at"`
+	Nodes       []*TopologyGraphNode `json:"nodes"`
+	Edges       []*TopologyEdge      `json:"edges"`
+	Clusters    []*TopologyCluster   `json:"clusters"`
+	Findings    []string             `json:"findings"`
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// RETENTION MODELS
+// ============================================================================
+
+// Retention data classes
+const (
+	RetentionClassRawLogs      = "raw_logs"
+	RetentionClassQueryStats   = "query_stats"
+	RetentionClassHostMetrics  = "host_metrics"
+	RetentionClassExplainPlans = "explain_plans"
+)
+
+// RetentionClasses lists the data classes retention policies apply to
+var RetentionClasses = []string{
+	RetentionClassRawLogs,
+	RetentionClassQueryStats,
+	RetentionClassHostMetrics,
+	RetentionClassExplainPlans,
+}
+
+// ValidRetentionClass reports whether a data class can have a policy
+func ValidRetentionClass(class string) bool {
+	for _, c := range RetentionClasses {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// RetentionPolicy controls how long a tenant keeps one class of raw data
+type RetentionPolicy struct {
+	TenantID      uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	DataClass     string    `json:"data_class" db:"data_class"`
+	RetentionDays int       `json:"retention_days" db:"retention_days"`
+	// Rollup summarizes expired rows into hourly rollups before deletion
+	Rollup bool `json:"rollup" db:"rollup"`
+	// Archive writes expired rows as gzipped NDJSON before deletion
+	Archive bool `json:"archive" db:"archive"`
+	// RollupRetentionDays bounds how long rollups are kept; nil keeps them
+	RollupRetentionDays *int       `json:"rollup_retention_days,omitempty" db:"rollup_retention_days"`
+	UpdatedAt           *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// RetentionPolicyRequest is the body for setting a retention policy
+type RetentionPolicyRequest struct {
+	RetentionDays       int   `json:"retention_days" binding:"required,min=1"`
+	Rollup              *bool `json:"rollup,omitempty"` // Defaults to true
+	Archive             bool  `json:"archive"`
+	RollupRetentionDays *int  `json:"rollup_retention_days,omitempty"`
+}
+
+// RetentionWindow is a time range of a tenant's data class that has expired
+type RetentionWindow struct {
+	TenantID  uuid.UUID
+	DataClass string
+	From      time.Time
+	To        time.Time
+	Rollup    bool
+}
+
+// RetentionRun records the expiry of one window
+type RetentionRun struct {
+	ID             int64     `json:"id" db:"id"`
+	TenantID       uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	DataClass      string    `json:"data_class" db:"data_class"`
+	WindowStart    time.Time `json:"window_start" db:"window_start"`
+	WindowEnd      time.Time `json:"window_end" db:"window_end"`
+	RowsDeleted    int64     `json:"rows_deleted" db:"rows_deleted"`
+	RollupsWritten int64     `json:"rollups_written" db:"rollups_written"`
+	ArchiveKey     *string   `json:"archive_key,omitempty" db:"archive_key"`
+	ArchiveBytes   *int64    `json:"archive_bytes,omitempty" db:"archive_bytes"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// RetentionRunResponse summarizes applying a tenant's retention policies
+type RetentionRunResponse struct {
+	TenantID       uuid.UUID       `json:"tenant_id"`
+	Runs           []*RetentionRun `json:"runs"`
+	RowsDeleted    int64           `json:"rows_deleted"`
+	RollupsDeleted int64           `json:"rollups_deleted"`
+	// Complete is false when expired windows remain for the next run
+	Complete bool `json:"complete"`
+}
+
+// DataRollup is an hourly summary of expired raw data
+type DataRollup struct {
+	DataClass   string                 `json:"data_class" db:"data_class"`
+	CollectorID uuid.UUID              `json:"collector_id" db:"collector_id"`
+	Bucket      time.Time              `json:"bucket" db:"bucket"`
+	Dimensions  map[string]interface{} `json:"dimensions" db:"dimensions"`
+	Metrics     map[string]interface{} `json:"metrics" db:"metrics"`
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// SERVER SETTINGS MODELS
+// ============================================================================
+
+// Setting recommendation statuses
+const (
+	SettingStatusOK       = "ok"       // within tolerance of the recommendation
+	SettingStatusIncrease = "increase" // below the recommendation
+	SettingStatusDecrease = "decrease" // above the recommendation
+)
+
+// Setting drift scopes
+const (
+	SettingDriftScopeCluster = "cluster" // a primary and its standbys
+	SettingDriftScopeGroup   = "group"   // a collector group, e.g. an environment
+)
+
+// PgSettingsRequest represents the pg_settings metric pushed by a collector:
+// a full snapshot of pg_settings
+type PgSettingsRequest struct {
+	Type      string             `json:"type"` // "pg_settings"
+	Timestamp string             `json:"timestamp"`
+	Settings  []CollectedSetting `json:"settings"`
+}
+
+// CollectedSetting is a pg_settings row as sent by the collector
+type CollectedSetting struct {
+	Name           string `json:"name"`
+	Setting        string `json:"setting"`
+	Unit           string `json:"unit,omitempty"`    // e.g. "8kB", "kB", "MB", "ms"
+	VarType        string `json:"vartype,omitempty"` // bool, enum, integer, real, string
+	Context        string `json:"context,omitempty"` // postmaster settings need a restart
+	Source         string `json:"source,omitempty"`  // default, configuration file, override, ...
+	BootVal        string `json:"boot_val,omitempty"`
+	PendingRestart bool   `json:"pending_restart,omitempty"`
+}
+
+// PgSetting is the current value of a server setting of a collector's
+// instance
+type PgSetting struct {
+	Name           string    `json:"name" db:"name"`
+	Setting        string    `json:"setting" db:"setting"`
+	Unit           string    `json:"unit,omitempty" db:"unit"`
+	VarType        string    `json:"vartype,omitempty" db:"vartype"`
+	Context        string    `json:"context,omitempty" db:"context"`
+	Source         string    `json:"source,omitempty" db:"source"`
+	BootVal        string    `json:"boot_val,omitempty" db:"boot_val"`
+	PendingRestart bool      `json:"pending_restart" db:"pending_restart"`
+	ChangedAt      time.Time `json:"changed_at" db:"changed_at"` // When the value was last seen to change
+}
+
+// PgSettingChange is a change of a setting between two snapshots. A setting
+// that appeared has no old value; one that disappeared has no new value.
+type PgSettingChange struct {
+	ID          int64     `json:"id" db:"id"`
+	CollectorID uuid.UUID `json:"collector_id" db:"collector_id"`
+	Name        string    `json:"name" db:"name"`
+	OldValue    *string   `json:"old_value" db:"old_value"`
+	NewValue    *string   `json:"new_value" db:"new_value"`
+	Unit        string    `json:"unit,omitempty" db:"unit"`
+	ChangedAt   time.Time `json:"changed_at" db:"changed_at"`
+}
+
+// PgSettingChangeFilter selects setting changes of a collector
+type PgSettingChangeFilter struct {
+	CollectorID uuid.UUID
+	Name        string // all settings when empty
+	From        time.Time
+	To          time.Time
+	Limit       int
+}
+
+// CollectorSettings are the current settings of a collector's instance
+type CollectorSettings struct {
+	CollectorID   uuid.UUID    `json:"collector_id"`
+	CollectorName string       `json:"collector_name,omitempty"`
+	CollectedAt   *time.Time   `json:"collected_at,omitempty"` // nil when never collected
+	Settings      []*PgSetting `json:"settings"`
+}
+
+// SettingRecommendation compares a setting to the value recommended for the
+// instance's host
+type SettingRecommendation struct {
+	Name             string `json:"name"`
+	CurrentValue     string `json:"current_value"` // in display units, e.g. "128MB"
+	RecommendedValue string `json:"recommended_value"`
+	Status           string `json:"status"` // ok, increase, decrease
+	Why              string `json:"why"`
+	Basis            string `json:"basis"` // how the recommendation was derived
+	RequiresRestart  bool   `json:"requires_restart"`
+	PendingRestart   bool   `json:"pending_restart"`
+	Statement        string `json:"statement,omitempty"` // ALTER SYSTEM statement, when a change is recommended
+}
+
+// SettingsAdvice is the best-practice review of a collector's settings
+type SettingsAdvice struct {
+	CollectorID     uuid.UUID                `json:"collector_id"`
+	CollectedAt     *time.Time               `json:"collected_at,omitempty"`
+	MemoryTotalMb   int64                    `json:"memory_total_mb"`
+	CpuCores        int                      `json:"cpu_cores"`
+	DiskTotalGb     int64                    `json:"disk_total_gb"`
+	Storage         string                   `json:"storage"` // ssd or hdd
+	Recommendations []*SettingRecommendation `json:"recommendations"`
+	// Notes explain recommendations that could not be made, e.g. without
+	// host inventory
+	Notes       []string  `json:"notes"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// SettingValue is one instance's value of a drifting setting
+type SettingValue struct {
+	CollectorID   uuid.UUID `json:"collector_id"`
+	CollectorName string    `json:"collector_name,omitempty"`
+	Value         string    `json:"value"`
+}
+
+// SettingDrift is a setting whose value differs between instances that
+// should agree on it
+type SettingDrift struct {
+	Name            string          `json:"name"`
+	Unit            string          `json:"unit,omitempty"`
+	Severity        string          `json:"severity"` // critical, warning, info
+	Why             string          `json:"why"`
+	RequiresRestart bool            `json:"requires_restart"`
+	Values          []*SettingValue `json:"values"`
+}
+
+// SettingsDriftReport lists the settings that differ among the instances of
+// a cluster or collector group
+type SettingsDriftReport struct {
+	Scope        string          `json:"scope"` // cluster or group
+	ScopeID      string          `json:"scope_id"`
+	CollectorIDs []uuid.UUID     `json:"collector_ids"`
+	Drift        []*SettingDrift `json:"drift"`
+	// Missing are collectors in scope that have not reported settings
+	Missing     []uuid.UUID `json:"missing"`
+	GeneratedAt time.Time   `json:"generated_at"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// REPLICATION SLOT RISK MODELS
+// ============================================================================
+
+// Replication slot risk levels
+const (
+	SlotRiskUnknown  = "unknown" // no samples
+	SlotRiskOK       = "ok"
+	SlotRiskWarning  = "warning"
+	SlotRiskCritical = "critical"
+)
+
+// WalPosition is a stored WAL insert position of a collector's cluster, the
+// reference slot retention is measured from
+type WalPosition struct {
+	Time                    time.Time `json:"time" db:"time"`
+	CollectorID             uuid.UUID `json:"collector_id" db:"collector_id"`
+	CurrentWalLsn           string    `json:"current_wal_lsn" db:"current_wal_lsn"`
+	WalDirectoryBytes       *int64    `json:"wal_directory_bytes,omitempty" db:"wal_directory_bytes"`
+	MaxSlotWalKeepSizeBytes *int64    `json:"max_slot_wal_keep_size_bytes,omitempty" db:"max_slot_wal_keep_size_bytes"` // nil when unlimited
+}
+
+// SlotRisk is the assessment of one replication slot
+type SlotRisk struct {
+	SlotName          string     `json:"slot_name"`
+	SlotType          string     `json:"slot_type"`
+	DatabaseName      string     `json:"database_name,omitempty"`
+	Active            bool       `json:"active"`
+	WalStatus         string     `json:"wal_status,omitempty"`
+	RestartLsn        string     `json:"restart_lsn"`
+	ConfirmedFlushLsn string     `json:"confirmed_flush_lsn,omitempty"`
+	RetainedBytes     int64      `json:"retained_bytes"`                    // current LSN - restart_lsn
+	InactiveSince     *time.Time `json:"inactive_since,omitempty"`          // last seen active, within the lookback
+	Advancing         *bool      `json:"advancing,omitempty"`               // restart or confirmed LSN moved since the baseline
+	CatalogXminAge    *int64     `json:"catalog_xmin_age,omitempty"`        // logical slots
+	InvalidatedAt     *time.Time `json:"invalidated_at_forecast,omitempty"` // when max_slot_wal_keep_size drops it
+	Level             string     `json:"level"`
+	Reasons           []string   `json:"reasons"`
+	Recommendation    string     `json:"recommendation,omitempty"`
+}
+
+// SlotRiskReport is the replication slot and WAL retention risk of a
+// collector's cluster
+type SlotRiskReport struct {
+	CollectorID             uuid.UUID   `json:"collector_id"`
+	CollectedAt             *time.Time  `json:"collected_at"` // nil without samples
+	Level                   string      `json:"level"`
+	CurrentWalLsn           string      `json:"current_wal_lsn,omitempty"`
+	WalBytesPerHour         *float64    `json:"wal_bytes_per_hour"` // nil without a baseline
+	TotalRetainedBytes      int64       `json:"total_retained_bytes"`
+	WalDirectoryBytes       *int64      `json:"wal_directory_bytes,omitempty"`
+	MaxSlotWalKeepSizeBytes *int64      `json:"max_slot_wal_keep_size_bytes,omitempty"`
+	DiskFreeBytes           *int64      `json:"disk_free_bytes,omitempty"` // from host metrics
+	DiskTotalBytes          *int64      `json:"disk_total_bytes,omitempty"`
+	DiskFullAt              *time.Time  `json:"disk_full_at"` // nil when no slot holds WAL back indefinitely
+	Slots                   []*SlotRisk `json:"slots"`
+	RecommendedDrop         string      `json:"recommended_drop,omitempty"` // slot whose removal frees the most
+	Findings                []string    `json:"findings"`
+}
+
+// SlotAlertCondition fires when a replication slot metric of a collector's
+// latest sample compares to Value with Operator
+type SlotAlertCondition struct {
+	CollectorID uuid.UUID `json:"collector_id"`
+	// Metric is one of total_retained_bytes, max_retained_bytes,
+	// inactive_slots, hours_to_disk_full or max_catalog_xmin_age
+	Metric   string  `json:"metric"`
+	Operator string  `json:"operator"` // "==", "!=", ">", ">=", "<", "<="
+	Value    float64 `json:"value"`
+}
@@ -25,7 +25,7 @@ type Tenant struct {
 // A user can belong to multiple tenants with different roles
 type TenantUser struct {
 	TenantID  uuid.UUID `json:"tenant_id" db:"tenant_id"`
-	UserID    uuid.UUID `json:"user_id" db:"user_id"`
+	UserID    int       `json:"user_id" db:"user_id"`
 	Role      string    `json:"role" db:"role"` // admin, viewer, editor
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
@@ -64,3 +64,57 @@ type TenantCreateRequest struct {
 type TenantCollectorAssignmentRequest struct {
 	CollectorID uuid.UUID `json:"collector_id" binding:"required"`
 }
+
+// ============================================================================
+// QUERY REDACTION MODELS
+// ============================================================================
+
+// Query redaction modes applied to query text before it is stored
+const (
+	RedactionModeNone      = "none"      // Store query text as received
+	RedactionModeNormalize = "normalize" // Store only normalized text with $N placeholders
+	RedactionModeMask      = "mask"      // Mask literals matching sensitive patterns
+)
+
+// QueryRedactionPolicy controls how a tenant's query samples are stored
+type QueryRedactionPolicy struct {
+	TenantID  uuid.UUID  `json:"tenant_id" db:"tenant_id"`
+	Mode      string     `json:"mode" db:"mode"` // none, normalize, mask
+	UpdatedAt *time.Time `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+// QueryRedactionPolicyRequest represents the request body for updating a redaction policy
+type QueryRedactionPolicyRequest struct {
+	Mode string `json:"mode" binding:"required"`
+}
+
+// QueryRedactionBackfillResponse summarizes a redaction backfill over stored samples
+type QueryRedactionBackfillResponse struct {
+	TenantID    uuid.UUID `json:"tenant_id"`
+	Mode        string    `json:"mode"`
+	RowsScanned int64     `json:"rows_scanned"`
+	RowsUpdated int64     `json:"rows_updated"`
+}
+
+// LogCategoryRule is a tenant-defined log category. Rules are evaluated in
+// descending priority before the built-in SQLSTATE and text classification;
+// a rule matches when every condition it sets matches.
+type LogCategoryRule struct {
+	ID             int64     `json:"id" db:"id"`
+	TenantID       uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Category       string    `json:"category" db:"category"`
+	SQLStatePrefix *string   `json:"sqlstate_prefix,omitempty" db:"sqlstate_prefix"` // e.g. "P0" or "P0001"
+	Pattern        *string   `json:"pattern,omitempty" db:"pattern"`                 // regex over the log message
+	Priority       int       `json:"priority" db:"priority"`
+	Enabled        bool      `json:"enabled" db:"enabled"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// LogCategoryRuleRequest represents the request body for creating a log category rule
+type LogCategoryRuleRequest struct {
+	Category       string  `json:"category" binding:"required"`
+	SQLStatePrefix *string `json:"sqlstate_prefix,omitempty"`
+	Pattern        *string `json:"pattern,omitempty"`
+	Priority       int     `json:"priority"`
+	Enabled        *bool   `json:"enabled,omitempty"`
+}
@@ -44,7 +44,7 @@ func TestTenantFields(t *testing.T) {
 // Test TenantUser struct has all required fields
 func TestTenantUserFields(t *testing.T) {
 	tenantID := uuid.New()
-	userID := uuid.New()
+	userID := 42
 	now := time.Now()
 
 	tenantUser := TenantUser{
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// UPGRADE READINESS MODELS
+// ============================================================================
+
+// Upgrade finding categories
+const (
+	UpgradeCategoryVersion   = "version"   // the target version itself
+	UpgradeCategoryExtension = "extension" // installed extensions
+	UpgradeCategorySetting   = "setting"   // removed or renamed settings in use
+	UpgradeCategorySchema    = "schema"    // objects pg_upgrade rejects or the target no longer supports
+)
+
+// Upgrade checklist phases
+const (
+	UpgradePhaseBefore = "before"
+	UpgradePhaseAfter  = "after"
+)
+
+// UpgradeObjectsRequest represents the pg_upgrade_objects metric pushed by a
+// collector: catalog objects the schema inventory does not cover that block
+// or break a major-version upgrade
+type UpgradeObjectsRequest struct {
+	Type      string          `json:"type"` // "pg_upgrade_objects"
+	Timestamp string          `json:"timestamp"`
+	Objects   []UpgradeObject `json:"objects"`
+}
+
+// UpgradeObject is a catalog object relevant to upgrades, e.g. a postfix
+// operator
+type UpgradeObject struct {
+	Database string `json:"database" db:"database_name"`
+	Kind     string `json:"kind" db:"kind"` // postfix_operator, encoding_conversion, incompatible_polymorphic
+	Schema   string `json:"schema" db:"schema_name"`
+	Name     string `json:"name" db:"object_name"`
+	Detail   string `json:"detail,omitempty" db:"detail"`
+}
+
+// UpgradeFinding is something that blocks or affects upgrading an instance
+// to the target version
+type UpgradeFinding struct {
+	Category string   `json:"category"` // version, extension, setting, schema
+	Severity string   `json:"severity"` // critical, warning, info
+	Title    string   `json:"title"`
+	Detail   string   `json:"detail"`
+	Objects  []string `json:"objects,omitempty"` // affected objects, e.g. "db.schema.table.column"
+	Action   string   `json:"action,omitempty"`
+}
+
+// UpgradeChecklistItem is a step of the upgrade, ordered by phase and
+// severity
+type UpgradeChecklistItem struct {
+	Step     int    `json:"step"`
+	Phase    string `json:"phase"` // before, after
+	Severity string `json:"severity"`
+	Title    string `json:"title"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// UpgradeReadinessSummary counts findings by severity
+type UpgradeReadinessSummary struct {
+	Critical int `json:"critical"`
+	Warning  int `json:"warning"`
+	Info     int `json:"info"`
+}
+
+// UpgradeReadinessReport assesses upgrading a collector's instance from its
+// current major version to a target one
+type UpgradeReadinessReport struct {
+	CollectorID   uuid.UUID               `json:"collector_id"`
+	SourceVersion PostgreSQLVersion       `json:"source_version"`
+	TargetVersion PostgreSQLVersion       `json:"target_version"`
+	Ready         bool                    `json:"ready"` // no critical findings
+	Summary       UpgradeReadinessSummary `json:"summary"`
+	Findings      []*UpgradeFinding       `json:"findings"`
+	Checklist     []*UpgradeChecklistItem `json:"checklist"`
+	// Notes explain checks that could not be made, e.g. without a settings
+	// snapshot
+	Notes       []string  `json:"notes"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
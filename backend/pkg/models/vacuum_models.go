@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// VACUUM STATISTICS MODELS
+// ============================================================================
+
+// VacuumStatsRequest represents the pg_vacuum_stats metric pushed by a
+// collector for one database: pg_stat_user_tables joined with pg_class,
+// a bloat estimate and the autovacuum settings of pg_settings
+type VacuumStatsRequest struct {
+	Type      string `json:"type"` // "pg_vacuum_stats"
+	Timestamp string `json:"timestamp"`
+	Database  string `json:"database"`
+	// Settings are the autovacuum and vacuum cost settings by name, as
+	// pg_settings reports them (e.g. "autovacuum_vacuum_scale_factor": "0.2")
+	Settings map[string]string     `json:"settings"`
+	Tables   []CollectedVacuumStat `json:"tables"`
+}
+
+// CollectedVacuumStat is a table's vacuum statistics as sent by the collector
+type CollectedVacuumStat struct {
+	Schema           string  `json:"schema"`
+	Table            string  `json:"table"`
+	LiveTuples       int64   `json:"n_live_tup"`
+	DeadTuples       int64   `json:"n_dead_tup"`
+	ModSinceAnalyze  int64   `json:"n_mod_since_analyze"`
+	InsSinceVacuum   *int64  `json:"n_ins_since_vacuum,omitempty"` // PostgreSQL 13+
+	TuplesInserted   int64   `json:"n_tup_ins"`
+	TuplesUpdated    int64   `json:"n_tup_upd"`
+	TuplesDeleted    int64   `json:"n_tup_del"`
+	TuplesHotUpdated int64   `json:"n_tup_hot_upd"`
+	RelTuples        float64 `json:"reltuples"` // -1 when never vacuumed or analyzed
+	RelPages         int64   `json:"relpages"`
+	TableSizeBytes   int64   `json:"table_size_bytes"`
+	BloatBytes       *int64  `json:"bloat_bytes,omitempty"`
+	// RelOptions are pg_class.reloptions entries such as
+	// "autovacuum_vacuum_scale_factor=0.01"
+	RelOptions       []string `json:"reloptions,omitempty"`
+	LastVacuum       *string  `json:"last_vacuum,omitempty"`
+	LastAutovacuum   *string  `json:"last_autovacuum,omitempty"`
+	LastAnalyze      *string  `json:"last_analyze,omitempty"`
+	LastAutoanalyze  *string  `json:"last_autoanalyze,omitempty"`
+	VacuumCount      int64    `json:"vacuum_count"`
+	AutovacuumCount  int64    `json:"autovacuum_count"`
+	AnalyzeCount     int64    `json:"analyze_count"`
+	AutoanalyzeCount int64    `json:"autoanalyze_count"`
+}
+
+// VacuumTableStat is a stored sample of a table's vacuum statistics
+type VacuumTableStat struct {
+	Time             time.Time  `json:"time" db:"time"`
+	CollectorID      uuid.UUID  `json:"collector_id" db:"collector_id"`
+	DatabaseName     string     `json:"database_name" db:"database_name"`
+	SchemaName       string     `json:"schema_name" db:"schema_name"`
+	TableName        string     `json:"table_name" db:"table_name"`
+	LiveTuples       int64      `json:"live_tuples" db:"live_tuples"`
+	DeadTuples       int64      `json:"dead_tuples" db:"dead_tuples"`
+	ModSinceAnalyze  int64      `json:"mod_since_analyze" db:"mod_since_analyze"`
+	InsSinceVacuum   *int64     `json:"ins_since_vacuum,omitempty" db:"ins_since_vacuum"`
+	TuplesInserted   int64      `json:"tuples_inserted" db:"tuples_inserted"`
+	TuplesUpdated    int64      `json:"tuples_updated" db:"tuples_updated"`
+	TuplesDeleted    int64      `json:"tuples_deleted" db:"tuples_deleted"`
+	TuplesHotUpdated int64      `json:"tuples_hot_updated" db:"tuples_hot_updated"`
+	RelTuples        float64    `json:"reltuples" db:"reltuples"`
+	RelPages         int64      `json:"relpages" db:"relpages"`
+	TableSizeBytes   int64      `json:"table_size_bytes" db:"table_size_bytes"`
+	BloatBytes       *int64     `json:"bloat_bytes,omitempty" db:"bloat_bytes"`
+	RelOptions       []string   `json:"reloptions" db:"reloptions"`
+	LastVacuum       *time.Time `json:"last_vacuum,omitempty" db:"last_vacuum"`
+	LastAutovacuum   *time.Time `json:"last_autovacuum,omitempty" db:"last_autovacuum"`
+	LastAnalyze      *time.Time `json:"last_analyze,omitempty" db:"last_analyze"`
+	LastAutoanalyze  *time.Time `json:"last_autoanalyze,omitempty" db:"last_autoanalyze"`
+	VacuumCount      int64      `json:"vacuum_count" db:"vacuum_count"`
+	AutovacuumCount  int64      `json:"autovacuum_count" db:"autovacuum_count"`
+	AnalyzeCount     int64      `json:"analyze_count" db:"analyze_count"`
+	AutoanalyzeCount int64      `json:"autoanalyze_count" db:"autoanalyze_count"`
+}
+
+// VacuumSnapshot is the latest vacuum statistics of a database, with the
+// oldest sample of each table in the lookback window for rates
+type VacuumSnapshot struct {
+	DatabaseID   int64
+	CollectorID  uuid.UUID
+	DatabaseName string
+	CollectedAt  time.Time
+	Settings     map[string]string
+	Tables       []*VacuumTableStat
+	// Baselines are keyed by "schema.table"; a table without one has a
+	// single sample in the window
+	Baselines map[string]*VacuumTableStat
+}
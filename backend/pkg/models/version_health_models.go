@@ -10,6 +10,14 @@ import (
 // VERSION-SPECIFIC HEALTH CHECK MODELS (11-04)
 // ============================================================================
 
+// Health check assertion types: how the result set of a check query is judged
+const (
+	HealthCheckAssertEquals       = "equals"         // first column of the first row equals the value
+	HealthCheckAssertLessThan     = "less_than"      // first column of the first row is numerically below the value
+	HealthCheckAssertRowCountZero = "row_count_zero" // the query returns no rows
+	HealthCheckAssertRegex        = "regex"          // first column of the first row matches the value
+)
+
 // VersionHealthCheck represents a version-specific health check definition
 type VersionHealthCheck struct {
 	ID             int       `json:"id" db:"id"`
@@ -22,6 +30,9 @@ type VersionHealthCheck struct {
 	Description    string    `json:"description" db:"description"`         // What this check does
 	Remediation    string    `json:"remediation" db:"remediation"`         // How to fix issues
 	Category       string    `json:"category" db:"category"`               // performance, security, configuration, replication, monitoring
+	AssertionType  string    `json:"assertion_type" db:"assertion_type"`   // equals, less_than, row_count_zero, regex
+	AssertionValue string    `json:"assertion_value,omitempty" db:"assertion_value"`
+	PackID         *int64    `json:"pack_id,omitempty" db:"pack_id"` // Custom check pack, nil for built-in checks
 	CreatedAt      time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -36,9 +47,19 @@ type HealthCheckResult struct {
 	ExpectedResult string    `json:"expected_result" db:"expected_result"`
 	Message        string    `json:"message" db:"message"`
 	Remediation    string    `json:"remediation" db:"remediation"`
+	Pack           string    `json:"pack,omitempty" db:"pack"` // Check pack of a custom check
 	CheckedAt      time.Time `json:"checked_at" db:"checked_at"`
 }
 
+// HealthCheckResultSet is the result set of a check query as text, the way
+// psql would print it. NULL values are nil. RowCount can exceed len(Rows)
+// when the executor stopped reading rows early.
+type HealthCheckResultSet struct {
+	Columns  []string    `json:"columns"`
+	Rows     [][]*string `json:"rows"`
+	RowCount int         `json:"row_count"`
+}
+
 // HealthCheckSummary provides aggregate statistics for health check results
 type HealthCheckSummary struct {
 	TotalChecks    int `json:"total_checks" db:"total_checks"`
@@ -46,6 +67,7 @@ type HealthCheckSummary struct {
 	FailedCritical int `json:"failed_critical" db:"failed_critical"`
 	FailedWarning  int `json:"failed_warning" db:"failed_warning"`
 	FailedInfo     int `json:"failed_info" db:"failed_info"`
+	Score          int `json:"score" db:"score"` // 0-100, passed checks weighted by severity
 }
 
 // VersionHealthCheckResponse represents the complete response for version health checks
@@ -56,3 +78,70 @@ type VersionHealthCheckResponse struct {
 	Results                 []*HealthCheckResult `json:"results"`
 	Summary                 HealthCheckSummary   `json:"summary"`
 }
+
+// ManagedInstanceHealthCheckResponse represents the results of version health
+// checks run directly against a managed instance
+type ManagedInstanceHealthCheckResponse struct {
+	ManagedInstanceID       int                  `json:"managed_instance_id"`
+	PostgreSQLVersion       int                  `json:"postgresql_version"`
+	PostgreSQLVersionString string               `json:"postgresql_version_string"`
+	Results                 []*HealthCheckResult `json:"results"`
+	Summary                 HealthCheckSummary   `json:"summary"`
+}
+
+// RunManagedInstanceHealthChecksRequest carries the credentials to connect to
+// a managed instance with
+type RunManagedInstanceHealthChecksRequest struct {
+	Username string `json:"username"`           // Optional - uses stored username from instance if not provided
+	Password string `json:"password"`           // Optional - uses decrypted password from secret if not provided
+	Database string `json:"database,omitempty"` // Defaults to postgres
+}
+
+// ============================================================================
+// COLLECTOR HEALTH CHECK CONFIG AND RESULTS
+// ============================================================================
+
+// HealthCheckQuery is a check query a collector runs against its database
+type HealthCheckQuery struct {
+	CheckID         int    `json:"check_id"`
+	CheckName       string `json:"check_name"`
+	Query           string `json:"query"`
+	Pack            string `json:"pack,omitempty"`             // Check pack of a custom check
+	IntervalSeconds int    `json:"interval_seconds,omitempty"` // Schedule of a custom check; built-in checks use the config interval
+}
+
+// HealthCheckConfig is the set of check queries a collector pulls. Collectors
+// run them every IntervalSeconds, and on their next pull when RunRequested.
+type HealthCheckConfig struct {
+	CollectorID       uuid.UUID           `json:"collector_id"`
+	PostgreSQLVersion int                 `json:"postgresql_version"`
+	IntervalSeconds   int                 `json:"interval_seconds"`
+	TimeoutMs         int                 `json:"timeout_ms"`
+	MaxRows           int                 `json:"max_rows"`
+	RunRequested      bool                `json:"run_requested"`
+	RequestedAt       *time.Time          `json:"requested_at,omitempty"`
+	Checks            []*HealthCheckQuery `json:"checks"`
+}
+
+// CollectedHealthCheck is the outcome of one check query run by a collector
+type CollectedHealthCheck struct {
+	CheckID int `json:"check_id"`
+	HealthCheckResultSet
+	Error string `json:"error,omitempty"`
+}
+
+// HealthCheckMetricsRequest represents a pg_health_checks metric pushed by a
+// collector after running the check queries it pulled
+type HealthCheckMetricsRequest struct {
+	Type      string                  `json:"type"`
+	Timestamp string                  `json:"timestamp"`
+	Results   []*CollectedHealthCheck `json:"results"`
+}
+
+// HealthCheckRunRequest acknowledges a request for a collector to run its
+// health checks
+type HealthCheckRunRequest struct {
+	CollectorID uuid.UUID `json:"collector_id"`
+	RequestedAt time.Time `json:"requested_at"`
+	Status      string    `json:"status"`
+}
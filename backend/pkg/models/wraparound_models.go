@@ -0,0 +1,207 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// TRANSACTION ID WRAPAROUND MODELS
+// ============================================================================
+
+// Wraparound blocker types
+const (
+	WraparoundBlockerPreparedTransaction = "prepared_transaction"
+	WraparoundBlockerReplicationSlot     = "replication_slot"
+	WraparoundBlockerLongTransaction     = "long_transaction"
+)
+
+// Wraparound risk levels
+const (
+	WraparoundLevelUnknown  = "unknown" // no samples
+	WraparoundLevelOK       = "ok"
+	WraparoundLevelWarning  = "warning"
+	WraparoundLevelCritical = "critical"
+)
+
+// WraparoundMetricsRequest represents the pg_wraparound metric pushed by a
+// collector: transaction ID and multixact ages of every database and of the
+// oldest tables, with what holds the xmin horizon back
+type WraparoundMetricsRequest struct {
+	Type      string `json:"type"` // "pg_wraparound"
+	Timestamp string `json:"timestamp"`
+	// NextXID is the epoch-qualified next transaction ID (txid_current() or
+	// pg_current_xact_id()) and NextMultixact the next multixact ID of
+	// pg_control_checkpoint(); their increase gives the consumption rates
+	NextXID       int64 `json:"next_xid"`
+	NextMultixact int64 `json:"next_multixact"`
+	// Settings are the freeze settings by name, as pg_settings reports them
+	// (autovacuum_freeze_max_age, autovacuum_multixact_freeze_max_age,
+	// vacuum_freeze_min_age)
+	Settings             map[string]string          `json:"settings"`
+	Databases            []CollectedDatabaseAge     `json:"databases"`
+	Tables               []CollectedTableAge        `json:"tables"`
+	PreparedTransactions []CollectedPreparedXact    `json:"prepared_transactions"`
+	ReplicationSlots     []CollectedSlotHorizon     `json:"replication_slots"`
+	LongTransactions     []CollectedLongTransaction `json:"long_transactions"`
+}
+
+// CollectedDatabaseAge is a pg_database row as sent by the collector
+type CollectedDatabaseAge struct {
+	Database string `json:"database"`
+	XIDAge   int64  `json:"datfrozenxid_age"` // age(datfrozenxid)
+	MXIDAge  int64  `json:"datminmxid_age"`   // mxid_age(datminmxid)
+}
+
+// CollectedTableAge is one of the oldest tables of a database as sent by the
+// collector
+type CollectedTableAge struct {
+	Database       string `json:"database"`
+	Schema         string `json:"schema"`
+	Table          string `json:"table"`
+	XIDAge         int64  `json:"relfrozenxid_age"` // age(relfrozenxid)
+	MXIDAge        int64  `json:"relminmxid_age"`   // mxid_age(relminmxid)
+	TableSizeBytes int64  `json:"table_size_bytes"`
+	// FreezeMaxAge is the table's autovacuum_freeze_max_age reloption
+	FreezeMaxAge *int64 `json:"autovacuum_freeze_max_age,omitempty"`
+}
+
+// CollectedPreparedXact is a pg_prepared_xacts row as sent by the collector
+type CollectedPreparedXact struct {
+	GID      string `json:"gid"`
+	Database string `json:"database"`
+	Owner    string `json:"owner"`
+	Prepared string `json:"prepared"`
+	XIDAge   int64  `json:"xid_age"` // age(transaction)
+}
+
+// CollectedSlotHorizon is a pg_replication_slots row as sent by the collector
+type CollectedSlotHorizon struct {
+	SlotName       string `json:"slot_name"`
+	SlotType       string `json:"slot_type"`
+	Database       string `json:"database,omitempty"`
+	Active         bool   `json:"active"`
+	XminAge        *int64 `json:"xmin_age,omitempty"`         // age(xmin)
+	CatalogXminAge *int64 `json:"catalog_xmin_age,omitempty"` // age(catalog_xmin)
+}
+
+// CollectedLongTransaction is a pg_stat_activity row with an old
+// transaction as sent by the collector
+type CollectedLongTransaction struct {
+	PID             int    `json:"pid"`
+	Database        string `json:"database"`
+	User            string `json:"usename"`
+	ApplicationName string `json:"application_name"`
+	State           string `json:"state"`
+	XactStart       string `json:"xact_start"`
+	XIDAge          *int64 `json:"backend_xid_age,omitempty"`  // age(backend_xid)
+	XminAge         *int64 `json:"backend_xmin_age,omitempty"` // age(backend_xmin)
+	Query           string `json:"query"`
+}
+
+// WraparoundSample is a stored cluster-wide pg_wraparound sample
+type WraparoundSample struct {
+	Time                  time.Time `json:"time" db:"time"`
+	CollectorID           uuid.UUID `json:"collector_id" db:"collector_id"`
+	NextXID               int64     `json:"next_xid" db:"next_xid"`
+	NextMultixact         int64     `json:"next_multixact" db:"next_multixact"`
+	FreezeMaxAge          int64     `json:"freeze_max_age" db:"freeze_max_age"`
+	MultixactFreezeMaxAge int64     `json:"multixact_freeze_max_age" db:"multixact_freeze_max_age"`
+	FreezeMinAge          int64     `json:"freeze_min_age" db:"freeze_min_age"`
+}
+
+// WraparoundAge is a stored transaction ID and multixact age of a database,
+// or of a table when TableName is set
+type WraparoundAge struct {
+	Time           time.Time `json:"time" db:"time"`
+	DatabaseName   string    `json:"database_name" db:"database_name"`
+	SchemaName     string    `json:"schema_name,omitempty" db:"schema_name"`
+	TableName      string    `json:"table_name,omitempty" db:"table_name"`
+	XIDAge         int64     `json:"xid_age" db:"xid_age"`
+	MXIDAge        int64     `json:"mxid_age" db:"mxid_age"`
+	TableSizeBytes *int64    `json:"table_size_bytes,omitempty" db:"table_size_bytes"`
+	FreezeMaxAge   *int64    `json:"freeze_max_age,omitempty" db:"freeze_max_age"`
+}
+
+// WraparoundBlocker is something holding back the xmin horizon, so that
+// vacuum cannot freeze transaction IDs newer than it
+type WraparoundBlocker struct {
+	Type         string     `json:"type" db:"blocker_type"`
+	Name         string     `json:"name" db:"name"` // gid, slot name or pid
+	DatabaseName string     `json:"database_name,omitempty" db:"database_name"`
+	UserName     string     `json:"user_name,omitempty" db:"user_name"`
+	XIDAge       *int64     `json:"xid_age" db:"xid_age"` // age of the oldest XID it holds back
+	Since        *time.Time `json:"since,omitempty" db:"since"`
+	Active       *bool      `json:"active,omitempty" db:"active"`
+	Detail       string     `json:"detail,omitempty" db:"detail"` // slot type or query
+	// Level and Reason are assessed against the freeze settings
+	Level  string `json:"level"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// WraparoundHeadroom is the distance of the oldest transaction ID or
+// multixact age to the autovacuum freeze maximum and to the hard limit where
+// PostgreSQL stops assigning IDs, projected at the observed consumption rate
+type WraparoundHeadroom struct {
+	OldestAge               int64      `json:"oldest_age"`
+	OldestDatabase          string     `json:"oldest_database"`
+	FreezeMaxAge            int64      `json:"freeze_max_age"`
+	HardLimit               int64      `json:"hard_limit"`
+	PercentOfHardLimit      float64    `json:"percent_of_hard_limit"`
+	RemainingToFreezeMaxAge int64      `json:"remaining_to_freeze_max_age"` // negative once exceeded
+	RemainingToHardLimit    int64      `json:"remaining_to_hard_limit"`
+	ConsumptionPerHour      *float64   `json:"consumption_per_hour"` // nil without two samples
+	FreezeMaxAgeAt          *time.Time `json:"freeze_max_age_at"`
+	HardLimitAt             *time.Time `json:"hard_limit_at"`
+}
+
+// DatabaseWraparound is the wraparound headroom of one database
+type DatabaseWraparound struct {
+	DatabaseName   string     `json:"database_name"`
+	XIDAge         int64      `json:"xid_age"`
+	MXIDAge        int64      `json:"mxid_age"`
+	XIDPercent     float64    `json:"xid_percent"` // of the hard limit
+	MXIDPercent    float64    `json:"mxid_percent"`
+	FreezeMaxAgeAt *time.Time `json:"freeze_max_age_at"`
+	HardLimitAt    *time.Time `json:"hard_limit_at"`
+}
+
+// TableWraparound is the wraparound headroom of one of the oldest tables
+type TableWraparound struct {
+	DatabaseName     string  `json:"database_name"`
+	SchemaName       string  `json:"schema_name"`
+	TableName        string  `json:"table_name"`
+	XIDAge           int64   `json:"xid_age"`
+	MXIDAge          int64   `json:"mxid_age"`
+	XIDPercent       float64 `json:"xid_percent"`
+	TableSizeBytes   *int64  `json:"table_size_bytes,omitempty"`
+	FreezeMaxAge     int64   `json:"freeze_max_age"` // effective, with the reloption
+	PastFreezeMaxAge bool    `json:"past_freeze_max_age"`
+}
+
+// WraparoundStatus is the wraparound risk of a collector's cluster
+type WraparoundStatus struct {
+	CollectorID uuid.UUID             `json:"collector_id"`
+	CollectedAt *time.Time            `json:"collected_at"` // nil without samples
+	Level       string                `json:"level"`
+	HealthScore int                   `json:"health_score"` // 0-100, 100 without samples
+	XID         *WraparoundHeadroom   `json:"xid"`
+	Multixact   *WraparoundHeadroom   `json:"multixact"`
+	Databases   []*DatabaseWraparound `json:"databases"`
+	Tables      []*TableWraparound    `json:"tables"`
+	Blockers    []*WraparoundBlocker  `json:"blockers"`
+	Findings    []string              `json:"findings"`
+}
+
+// WraparoundAlertCondition fires when a wraparound metric of a collector's
+// latest sample compares to Value with Operator
+type WraparoundAlertCondition struct {
+	CollectorID uuid.UUID `json:"collector_id"`
+	// Metric is one of xid_percent, mxid_percent, xid_age, mxid_age,
+	// days_to_xid_freeze_max_age, days_to_xid_hard_limit,
+	// days_to_mxid_hard_limit, oldest_blocker_xid_age or health_score
+	Metric   string  `json:"metric"`
+	Operator string  `json:"operator"` // "==", "!=", ">", ">=", "<", "<="
+	Value    float64 `json:"value"`
+}
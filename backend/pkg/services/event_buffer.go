@@ -0,0 +1,71 @@
+package services
+
+import "sync"
+
+// DefaultEventBufferSize is the number of broadcast events kept for
+// clients resuming an event stream
+const DefaultEventBufferSize = 1024
+
+// bufferedEvent is a broadcast event with the instances it belongs to, so
+// that replays apply the same access checks as live delivery
+type bufferedEvent struct {
+	event       WebSocketEvent
+	instanceIDs []int
+}
+
+// eventBuffer is a bounded ring of the most recent broadcast events that
+// assigns each event an increasing ID
+type eventBuffer struct {
+	mu     sync.RWMutex
+	events []bufferedEvent
+	start  int // index of the oldest event
+	count  int
+	lastID uint64
+}
+
+func newEventBuffer(size int) *eventBuffer {
+	if size <= 0 {
+		size = DefaultEventBufferSize
+	}
+	return &eventBuffer{events: make([]bufferedEvent, size)}
+}
+
+// add assigns the next ID to an event and stores it, evicting the oldest
+// event when the buffer is full
+func (b *eventBuffer) add(event WebSocketEvent, instanceIDs ...int) WebSocketEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+
+	end := (b.start + b.count) % len(b.events)
+	b.events[end] = bufferedEvent{event: event, instanceIDs: instanceIDs}
+	if b.count < len(b.events) {
+		b.count++
+	} else {
+		b.start = (b.start + 1) % len(b.events)
+	}
+	return event
+}
+
+// since returns the buffered events with an ID above afterID, oldest first,
+// and the ID of the latest event. complete is false when events after
+// afterID were already evicted.
+func (b *eventBuffer) since(afterID uint64) (events []bufferedEvent, lastID uint64, complete bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if afterID >= b.lastID {
+		return nil, b.lastID, true
+	}
+
+	oldestID := b.lastID - uint64(b.count) + 1
+	for i := 0; i < b.count; i++ {
+		e := b.events[(b.start+i)%len(b.events)]
+		if e.event.ID > afterID {
+			events = append(events, e)
+		}
+	}
+	return events, b.lastID, afterID+1 >= oldestID
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// TestEventBufferEvictsOldest tests the ring keeps the most recent events
+func TestEventBufferEvictsOldest(t *testing.T) {
+	b := newEventBuffer(3)
+	for i := 0; i < 5; i++ {
+		event := b.add(WebSocketEvent{Type: "log:new"}, 1)
+		if event.ID != uint64(i+1) {
+			t.Fatalf("Expected event ID %d, got %d", i+1, event.ID)
+		}
+	}
+
+	events, last, complete := b.since(3)
+	if len(events) != 2 || events[0].event.ID != 4 || last != 5 || !complete {
+		t.Errorf("Expected events 4 and 5, got %d events, last %d, complete %v", len(events), last, complete)
+	}
+
+	// Event 2 was evicted
+	events, _, complete = b.since(1)
+	if len(events) != 3 || complete {
+		t.Errorf("Expected an incomplete replay of 3 events, got %d events, complete %v", len(events), complete)
+	}
+
+	events, last, complete = b.since(5)
+	if len(events) != 0 || last != 5 || !complete {
+		t.Error("Expected nothing to replay after the latest event")
+	}
+}
+
+// TestReplayEventsAppliesAccess tests replays apply the same filtering as live delivery
+func TestReplayEventsAppliesAccess(t *testing.T) {
+	cm := NewConnectionManager(zap.NewNop())
+	conn := cm.RegisterStreamConnection("user:1", InstanceAccess{Instances: []int{1}})
+	defer cm.UnregisterConnection("user:1", conn)
+
+	_ = cm.BroadcastLogEvent(map[string]interface{}{"message": "a"}, 1)
+	_ = cm.BroadcastLogEvent(map[string]interface{}{"message": "b"}, 2)
+	_ = cm.BroadcastAlertEvent(map[string]interface{}{"alert_id": 3}, 1)
+
+	if len(conn.Messages()) != 2 {
+		t.Fatalf("Expected 2 live events, got %d", len(conn.Messages()))
+	}
+
+	events, last, complete := cm.ReplayEvents(conn, 0)
+	if len(events) != 2 || last != 3 || !complete {
+		t.Fatalf("Expected 2 replayed events up to 3, got %d up to %d", len(events), last)
+	}
+	if events[0].ID != 1 || events[1].ID != 3 {
+		t.Errorf("Expected events 1 and 3, got %d and %d", events[0].ID, events[1].ID)
+	}
+
+	if _, err := conn.Subscribe(SubscriptionRequest{EventTypes: []string{"alert:triggered"}}); err != nil {
+		t.Fatalf("Unexpected subscribe error: %v", err)
+	}
+	events, _, _ = cm.ReplayEvents(conn, 0)
+	if len(events) != 1 || events[0].Type != "alert:triggered" {
+		t.Errorf("Expected only the alert to be replayed, got %+v", events)
+	}
+}
@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -121,11 +123,102 @@ func (cb *CircuitBreaker) GetState() CircuitBreakerState {
 	return cb.state
 }
 
+// InstanceAccess is the set of instances a user may receive events for
+type InstanceAccess struct {
+	All       bool  // every instance, for global administrators
+	Instances []int // the accessible instances when All is false
+}
+
+// Allows reports whether events of an instance may be delivered
+func (a InstanceAccess) Allows(instanceID int) bool {
+	if a.All {
+		return true
+	}
+	for _, id := range a.Instances {
+		if id == instanceID {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessResolver resolves the instances a user may access, from their
+// tenant memberships and permissions
+type AccessResolver interface {
+	ResolveInstanceAccess(ctx context.Context, userID string) (InstanceAccess, error)
+}
+
+// SubscriptionRequest is the data of a subscribe or unsubscribe message
+type SubscriptionRequest struct {
+	Instances  []int    `json:"instances,omitempty"`
+	EventTypes []string `json:"event_types,omitempty"`
+}
+
+// SubscriptionState describes what a connection currently receives
+type SubscriptionState struct {
+	// AllInstances is true while subscribed to every accessible instance,
+	// except ExcludedInstances
+	AllInstances      bool     `json:"all_instances"`
+	Instances         []int    `json:"instances,omitempty"`
+	ExcludedInstances []int    `json:"excluded_instances,omitempty"`
+	AllEventTypes     bool     `json:"all_event_types"`
+	EventTypes        []string `json:"event_types,omitempty"`
+	ExcludedEvents    []string `json:"excluded_event_types,omitempty"`
+}
+
+// selection is a subscription to either every value except the listed
+// ones (all) or to the listed values only
+type selection[T comparable] struct {
+	all   bool
+	items map[T]struct{}
+}
+
+func newSelection[T comparable]() selection[T] {
+	return selection[T]{all: true, items: make(map[T]struct{})}
+}
+
+func (s selection[T]) has(v T) bool {
+	_, listed := s.items[v]
+	return listed != s.all
+}
+
+// subscribe adds values; the first subscription narrows a subscription to
+// everything down to the given values
+func (s *selection[T]) subscribe(values []T) {
+	if s.all {
+		s.all = false
+		s.items = make(map[T]struct{})
+	}
+	for _, v := range values {
+		s.items[v] = struct{}{}
+	}
+}
+
+func (s *selection[T]) unsubscribe(values []T) {
+	for _, v := range values {
+		if s.all {
+			s.items[v] = struct{}{}
+		} else {
+			delete(s.items, v)
+		}
+	}
+}
+
+func (s selection[T]) list() []T {
+	values := make([]T, 0, len(s.items))
+	for v := range s.items {
+		values = append(values, v)
+	}
+	return values
+}
+
 // ConnectionManager manages all active WebSocket connections
 type ConnectionManager struct {
 	connections map[string][]*Connection // userID -> list of connections
 	mu          sync.RWMutex
 
+	resolver    AccessResolver
+	events      *eventBuffer
 	broadcastCB *CircuitBreaker
 	logger      *zap.Logger
 }
@@ -139,11 +232,31 @@ type Connection struct {
 	send         chan interface{}
 	done         chan struct{}
 	lastPongTime time.Time
+
+	// subMu guards the access and subscriptions, which change while the
+	// connection is live
+	subMu         sync.RWMutex
+	allInstances  bool
+	subscriptions *connectionSubscriptions
+}
+
+// connectionSubscriptions are the instances and event types a client asked
+// for; nil until the client changes them, meaning everything accessible
+type connectionSubscriptions struct {
+	instances  selection[int]
+	eventTypes selection[string]
+}
+
+// clientMessage is a message sent by a client
+type clientMessage struct {
+	Type string          `json:"type"` // ping, subscribe, unsubscribe
+	Data json.RawMessage `json:"data,omitempty"`
 }
 
 // WebSocketEvent represents an event sent to clients
 type WebSocketEvent struct {
-	Type string      `json:"type"` // log:new, metric:update, alert:triggered
+	ID   uint64      `json:"id,omitempty"` // set on broadcast events, for resuming event streams
+	Type string      `json:"type"`         // log:new, metric:update, alert:triggered
 	Data interface{} `json:"data"`
 }
 
@@ -154,20 +267,63 @@ func NewConnectionManager(logger *zap.Logger) *ConnectionManager {
 	}
 	return &ConnectionManager{
 		connections: make(map[string][]*Connection),
+		events:      newEventBuffer(DefaultEventBufferSize),
 		broadcastCB: NewCircuitBreaker(5, 30*time.Second), // Open after 5 failures, retry after 30s
 		logger:      logger,
 	}
 }
 
+// SetAccessResolver sets how access is resolved when it is refreshed
+func (cm *ConnectionManager) SetAccessResolver(resolver AccessResolver) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.resolver = resolver
+}
+
+// ResolveAccess resolves the instances a user may access; without a
+// resolver no instance is accessible
+func (cm *ConnectionManager) ResolveAccess(ctx context.Context, userID string) (InstanceAccess, error) {
+	cm.mu.RLock()
+	resolver := cm.resolver
+	cm.mu.RUnlock()
+
+	if resolver == nil {
+		return InstanceAccess{}, nil
+	}
+	return resolver.ResolveInstanceAccess(ctx, userID)
+}
+
 // RegisterConnection registers a new WebSocket connection
 func (cm *ConnectionManager) RegisterConnection(userID string, instances []int, conn *websocket.Conn) *Connection {
+	return cm.RegisterConnectionWithAccess(userID, InstanceAccess{Instances: instances}, conn)
+}
+
+// RegisterConnectionWithAccess registers a new WebSocket connection that
+// receives the events of the instances the user may access
+func (cm *ConnectionManager) RegisterConnectionWithAccess(userID string, access InstanceAccess, conn *websocket.Conn) *Connection {
+	c := cm.addConnection(userID, access, conn)
+	go c.readPump(cm)
+	go c.writePump()
+	return c
+}
+
+// RegisterStreamConnection registers a connection whose events are written
+// by the caller, such as a Server-Sent Events response, instead of a
+// WebSocket. The caller reads Messages until Done is closed or the client
+// goes away, then unregisters the connection.
+func (cm *ConnectionManager) RegisterStreamConnection(userID string, access InstanceAccess) *Connection {
+	return cm.addConnection(userID, access, nil)
+}
+
+func (cm *ConnectionManager) addConnection(userID string, access InstanceAccess, conn *websocket.Conn) *Connection {
 	cm.mu.Lock()
 	defer cm.mu.Unlock()
 
 	c := &Connection{
 		id:           fmt.Sprintf("%s-%d", userID, time.Now().UnixNano()),
 		userID:       userID,
-		instances:    instances,
+		instances:    access.Instances,
+		allInstances: access.All,
 		conn:         conn,
 		send:         make(chan interface{}, 256),
 		done:         make(chan struct{}),
@@ -175,11 +331,22 @@ func (cm *ConnectionManager) RegisterConnection(userID string, instances []int,
 	}
 
 	cm.connections[userID] = append(cm.connections[userID], c)
-	go c.readPump(cm)
-	go c.writePump()
 	return c
 }
 
+// ReplayEvents returns the buffered broadcast events after an event ID that
+// the connection would have received, oldest first, and the ID of the latest
+// buffered event. complete is false when older events were already evicted.
+func (cm *ConnectionManager) ReplayEvents(c *Connection, afterID uint64) (events []WebSocketEvent, lastID uint64, complete bool) {
+	buffered, lastID, complete := cm.events.since(afterID)
+	for _, e := range buffered {
+		if c.receives(e.event.Type, e.instanceIDs...) {
+			events = append(events, e.event)
+		}
+	}
+	return events, lastID, complete
+}
+
 // UnregisterConnection removes a connection
 func (cm *ConnectionManager) UnregisterConnection(userID string, c *Connection) {
 	cm.mu.Lock()
@@ -202,6 +369,77 @@ func (cm *ConnectionManager) UnregisterConnection(userID string, c *Connection)
 	}
 }
 
+// CloseUserConnections closes every connection of a user, for users that
+// no longer exist
+func (cm *ConnectionManager) CloseUserConnections(userID string) {
+	cm.mu.RLock()
+	conns := append([]*Connection(nil), cm.connections[userID]...)
+	cm.mu.RUnlock()
+
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// RefreshAccess re-resolves a user's access and applies it to their live
+// connections, so that revoked instances stop receiving events at once
+func (cm *ConnectionManager) RefreshAccess(ctx context.Context, userID string) error {
+	cm.mu.RLock()
+	conns := append([]*Connection(nil), cm.connections[userID]...)
+	cm.mu.RUnlock()
+
+	if len(conns) == 0 {
+		return nil
+	}
+
+	access, err := cm.ResolveAccess(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range conns {
+		if c.setAccess(access) {
+			c.SendMessage(WebSocketEvent{Type: "access:updated", Data: c.subscriptionState()})
+		}
+	}
+	return nil
+}
+
+// RefreshAllAccess refreshes the access of every connected user, after
+// changes such as collectors moving between tenants
+func (cm *ConnectionManager) RefreshAllAccess(ctx context.Context) {
+	cm.mu.RLock()
+	userIDs := make([]string, 0, len(cm.connections))
+	for userID := range cm.connections {
+		userIDs = append(userIDs, userID)
+	}
+	cm.mu.RUnlock()
+
+	for _, userID := range userIDs {
+		if err := cm.RefreshAccess(ctx, userID); err != nil {
+			cm.logger.Warn("Failed to refresh WebSocket access",
+				zap.String("user_id", userID),
+				zap.Error(err))
+		}
+	}
+}
+
+// RunAccessRefresh refreshes the access of every connected user at the
+// given interval until the context is cancelled
+func (cm *ConnectionManager) RunAccessRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cm.RefreshAllAccess(ctx)
+		}
+	}
+}
+
 // BroadcastLogEvent sends a log:new event to all connected users with access
 func (cm *ConnectionManager) BroadcastLogEvent(log interface{}, instanceID int) error {
 	event := WebSocketEvent{
@@ -209,7 +447,7 @@ func (cm *ConnectionManager) BroadcastLogEvent(log interface{}, instanceID int)
 		Data: log,
 	}
 
-	return cm.broadcastWithFallback(event, instanceID)
+	return cm.broadcastWithFallback(event, instanceScope(instanceID)...)
 }
 
 // BroadcastMetricEvent sends a metric:update event
@@ -219,7 +457,7 @@ func (cm *ConnectionManager) BroadcastMetricEvent(data interface{}, instanceID i
 		Data: data,
 	}
 
-	return cm.broadcastWithFallback(event, instanceID)
+	return cm.broadcastWithFallback(event, instanceScope(instanceID)...)
 }
 
 // BroadcastAlertEvent sends an alert:triggered event
@@ -229,38 +467,62 @@ func (cm *ConnectionManager) BroadcastAlertEvent(data interface{}, instanceID in
 		Data: data,
 	}
 
-	return cm.broadcastWithFallback(event, instanceID)
+	return cm.broadcastWithFallback(event, instanceScope(instanceID)...)
 }
 
-// Broadcast sends a generic event to all connected clients
+// Broadcast sends a generic event that is not tied to an instance. Such
+// events only reach connections with access to every instance; events about
+// tenant data must be scoped with BroadcastToInstances.
 // This implements the Broadcaster interface for silence notifications
 func (cm *ConnectionManager) Broadcast(event string, data map[string]interface{}) {
-	// Broadcast to all connected users (instanceID = 0 means all)
 	wsEvent := WebSocketEvent{
 		Type: event,
 		Data: data,
 	}
 
-	// Use instanceID 0 to broadcast to all connections
-	_ = cm.broadcastWithFallback(wsEvent, 0)
+	_ = cm.broadcastWithFallback(wsEvent)
+}
+
+// BroadcastToInstances sends a generic event to the connections with access
+// to any of the given instances. Without instances it only reaches
+// connections with access to every instance, like Broadcast.
+func (cm *ConnectionManager) BroadcastToInstances(event string, data map[string]interface{}, instanceIDs []int) {
+	wsEvent := WebSocketEvent{
+		Type: event,
+		Data: data,
+	}
+
+	_ = cm.broadcastWithFallback(wsEvent, instanceIDs...)
+}
+
+// instanceScope is the delivery scope of an event of one instance; 0 means
+// the event is not tied to an instance
+func instanceScope(instanceID int) []int {
+	if instanceID == 0 {
+		return nil
+	}
+	return []int{instanceID}
 }
 
 // broadcastWithFallback sends a message with fallback mechanism for backpressure
-func (cm *ConnectionManager) broadcastWithFallback(event WebSocketEvent, instanceID int) error {
+func (cm *ConnectionManager) broadcastWithFallback(event WebSocketEvent, instanceIDs ...int) error {
 	// Check circuit breaker
 	if cm.broadcastCB.IsOpen() {
 		cm.logger.Warn("Broadcast circuit breaker is open",
 			zap.String("event_type", event.Type),
-			zap.Int("instance_id", instanceID))
+			zap.Ints("instance_ids", instanceIDs))
 		return fmt.Errorf("broadcast circuit breaker open")
 	}
 
+	// Keep the event for clients resuming an event stream
+	event = cm.events.add(event, instanceIDs...)
+
 	cm.mu.RLock()
 	// Create a copy of connections to avoid holding lock during send
 	var connections []*Connection
 	for _, conns := range cm.connections {
 		for _, c := range conns {
-			if c.hasAccessToInstance(instanceID) {
+			if c.receives(event.Type, instanceIDs...) {
 				connections = append(connections, c)
 			}
 		}
@@ -292,7 +554,7 @@ func (cm *ConnectionManager) broadcastWithFallback(event WebSocketEvent, instanc
 	if timeoutCount > 0 {
 		cm.logger.Warn("Dropped WebSocket messages due to backpressure",
 			zap.String("event_type", event.Type),
-			zap.Int("instance_id", instanceID),
+			zap.Ints("instance_ids", instanceIDs),
 			zap.Int("successful", successCount),
 			zap.Int("timed_out", timeoutCount),
 			zap.Int("total_connections", len(connections)))
@@ -311,14 +573,205 @@ func (cm *ConnectionManager) broadcastWithFallback(event WebSocketEvent, instanc
 // Connection methods
 
 func (c *Connection) hasAccessToInstance(instanceID int) bool {
-	for _, id := range c.instances {
-		if id == instanceID {
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+	return c.access().Allows(instanceID)
+}
+
+func (c *Connection) access() InstanceAccess {
+	return InstanceAccess{All: c.allInstances, Instances: c.instances}
+}
+
+// receives reports whether an event of the given instances is delivered to
+// the connection: it must have access to, and be subscribed to, one of them.
+// Events not tied to an instance only reach connections with access to every
+// instance.
+func (c *Connection) receives(eventType string, instanceIDs ...int) bool {
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+
+	if c.subscriptions != nil && !c.subscriptions.eventTypes.has(eventType) {
+		return false
+	}
+
+	access := c.access()
+	if len(instanceIDs) == 0 {
+		return access.All
+	}
+	for _, id := range instanceIDs {
+		if access.Allows(id) && (c.subscriptions == nil || c.subscriptions.instances.has(id)) {
 			return true
 		}
 	}
 	return false
 }
 
+// setAccess replaces the connection's access, dropping subscriptions to
+// instances that are no longer accessible, and reports whether it changed
+func (c *Connection) setAccess(access InstanceAccess) bool {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	changed := access.All != c.allInstances || len(access.Instances) != len(c.instances)
+	if !changed && !access.All {
+		for _, id := range c.instances {
+			if !access.Allows(id) {
+				changed = true
+				break
+			}
+		}
+	}
+
+	c.allInstances = access.All
+	c.instances = access.Instances
+	if c.subscriptions != nil && !c.subscriptions.instances.all {
+		for id := range c.subscriptions.instances.items {
+			if !access.Allows(id) {
+				delete(c.subscriptions.instances.items, id)
+			}
+		}
+	}
+	return changed
+}
+
+// Subscribe adds instances and event types to what the connection
+// receives. The first subscription to instances or event types narrows the
+// default of everything accessible down to the given ones.
+func (c *Connection) Subscribe(req SubscriptionRequest) (SubscriptionState, error) {
+	if err := validateSubscriptionRequest(req); err != nil {
+		return SubscriptionState{}, err
+	}
+
+	c.subMu.Lock()
+	var denied []int
+	for _, id := range req.Instances {
+		if !c.access().Allows(id) {
+			denied = append(denied, id)
+		}
+	}
+	if len(denied) > 0 {
+		c.subMu.Unlock()
+		return SubscriptionState{}, fmt.Errorf("access denied to instances %v", denied)
+	}
+
+	subs := c.ensureSubscriptions()
+	if len(req.Instances) > 0 {
+		subs.instances.subscribe(req.Instances)
+	}
+	if len(req.EventTypes) > 0 {
+		subs.eventTypes.subscribe(req.EventTypes)
+	}
+	c.subMu.Unlock()
+
+	return c.subscriptionState(), nil
+}
+
+// Unsubscribe stops delivering the given instances and event types
+func (c *Connection) Unsubscribe(req SubscriptionRequest) (SubscriptionState, error) {
+	if err := validateSubscriptionRequest(req); err != nil {
+		return SubscriptionState{}, err
+	}
+
+	c.subMu.Lock()
+	subs := c.ensureSubscriptions()
+	subs.instances.unsubscribe(req.Instances)
+	subs.eventTypes.unsubscribe(req.EventTypes)
+	c.subMu.Unlock()
+
+	return c.subscriptionState(), nil
+}
+
+func (c *Connection) ensureSubscriptions() *connectionSubscriptions {
+	if c.subscriptions == nil {
+		c.subscriptions = &connectionSubscriptions{
+			instances:  newSelection[int](),
+			eventTypes: newSelection[string](),
+		}
+	}
+	return c.subscriptions
+}
+
+func validateSubscriptionRequest(req SubscriptionRequest) error {
+	if len(req.Instances) == 0 && len(req.EventTypes) == 0 {
+		return fmt.Errorf("instances or event_types are required")
+	}
+	for _, id := range req.Instances {
+		if id <= 0 {
+			return fmt.Errorf("invalid instance id %d", id)
+		}
+	}
+	for _, eventType := range req.EventTypes {
+		if eventType == "" {
+			return fmt.Errorf("event types cannot be empty")
+		}
+	}
+	return nil
+}
+
+// subscriptionState returns what the connection currently receives
+func (c *Connection) subscriptionState() SubscriptionState {
+	c.subMu.RLock()
+	defer c.subMu.RUnlock()
+
+	state := SubscriptionState{AllInstances: true, AllEventTypes: true}
+	if c.subscriptions == nil {
+		return state
+	}
+
+	instances := c.subscriptions.instances
+	state.AllInstances = instances.all
+	if instances.all {
+		state.ExcludedInstances = instances.list()
+		sort.Ints(state.ExcludedInstances)
+	} else {
+		state.Instances = instances.list()
+		sort.Ints(state.Instances)
+	}
+
+	eventTypes := c.subscriptions.eventTypes
+	state.AllEventTypes = eventTypes.all
+	if eventTypes.all {
+		state.ExcludedEvents = eventTypes.list()
+		sort.Strings(state.ExcludedEvents)
+	} else {
+		state.EventTypes = eventTypes.list()
+		sort.Strings(state.EventTypes)
+	}
+	return state
+}
+
+// handleSubscription applies a subscribe or unsubscribe message and replies
+// with the resulting subscription state or an error
+func (c *Connection) handleSubscription(msgType string, data json.RawMessage) {
+	var req SubscriptionRequest
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.sendError(msgType, "invalid subscription request")
+			return
+		}
+	}
+
+	var state SubscriptionState
+	var err error
+	if msgType == "subscribe" {
+		state, err = c.Subscribe(req)
+	} else {
+		state, err = c.Unsubscribe(req)
+	}
+	if err != nil {
+		c.sendError(msgType, err.Error())
+		return
+	}
+	c.SendMessage(WebSocketEvent{Type: "subscription", Data: state})
+}
+
+func (c *Connection) sendError(request, message string) {
+	c.SendMessage(WebSocketEvent{
+		Type: "error",
+		Data: map[string]string{"request": request, "message": message},
+	})
+}
+
 func (c *Connection) writePump() {
 	ticker := time.NewTicker(pingInterval)
 	defer func() {
@@ -409,7 +862,7 @@ func (c *Connection) readPump(manager *ConnectionManager) {
 	})
 
 	for {
-		var msg map[string]interface{}
+		var msg clientMessage
 
 		err := c.conn.ReadJSON(&msg)
 		if err != nil {
@@ -422,13 +875,26 @@ func (c *Connection) readPump(manager *ConnectionManager) {
 			break
 		}
 
-		// Process message (client can send ping/heartbeat messages)
-		if msgType, ok := msg["type"].(string); ok && msgType == "ping" {
+		// Process message (heartbeats and subscription changes)
+		switch msg.Type {
+		case "ping":
 			c.SendMessage(map[string]string{"type": "pong"})
+		case "subscribe", "unsubscribe":
+			c.handleSubscription(msg.Type, msg.Data)
 		}
 	}
 }
 
+// Messages returns the channel of messages queued for the connection
+func (c *Connection) Messages() <-chan interface{} {
+	return c.send
+}
+
+// Done is closed when the connection is closed by the server
+func (c *Connection) Done() <-chan struct{} {
+	return c.done
+}
+
 // Close closes the connection
 func (c *Connection) Close() {
 	select {
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -328,3 +330,177 @@ func TestConnectionUnregisterOnClose(t *testing.T) {
 		t.Error("Connection not unregistered")
 	}
 }
+
+// stubAccessResolver resolves access from a map of users
+type stubAccessResolver struct {
+	access map[string]InstanceAccess
+}
+
+func (r *stubAccessResolver) ResolveInstanceAccess(ctx context.Context, userID string) (InstanceAccess, error) {
+	return r.access[userID], nil
+}
+
+func newTestConnection(userID string, access InstanceAccess) *Connection {
+	return &Connection{
+		id:           userID + "-conn",
+		userID:       userID,
+		instances:    access.Instances,
+		allInstances: access.All,
+		send:         make(chan interface{}, 256),
+		done:         make(chan struct{}),
+		lastPongTime: time.Now(),
+	}
+}
+
+// TestConnectionReceivesOnlyAccessibleInstances tests events are limited to accessible instances
+func TestConnectionReceivesOnlyAccessibleInstances(t *testing.T) {
+	conn := newTestConnection("user:1", InstanceAccess{Instances: []int{1, 2}})
+
+	if !conn.receives("log:new", 1) || !conn.receives("log:new", 2) {
+		t.Error("Expected events of accessible instances to be delivered")
+	}
+	if conn.receives("log:new", 3) {
+		t.Error("Expected events of other instances to be filtered")
+	}
+	if conn.receives("silence:created") {
+		t.Error("Expected events not tied to an instance to be filtered")
+	}
+	if !conn.receives("cluster:event", 3, 2) {
+		t.Error("Expected events scoped to an accessible instance to be delivered")
+	}
+
+	admin := newTestConnection("user:2", InstanceAccess{All: true})
+	if !admin.receives("alert:triggered", 42) {
+		t.Error("Expected global access to receive every instance")
+	}
+	if !admin.receives("silence:created") {
+		t.Error("Expected global access to receive events not tied to an instance")
+	}
+}
+
+// TestConnectionSubscriptions tests dynamic subscribe and unsubscribe
+func TestConnectionSubscriptions(t *testing.T) {
+	conn := newTestConnection("user:1", InstanceAccess{Instances: []int{1, 2, 3}})
+
+	if _, err := conn.Subscribe(SubscriptionRequest{Instances: []int{4}}); err == nil {
+		t.Error("Expected subscribing to an inaccessible instance to fail")
+	}
+	if _, err := conn.Subscribe(SubscriptionRequest{}); err == nil {
+		t.Error("Expected an empty subscription request to fail")
+	}
+
+	// The first subscription narrows delivery down to the given instances
+	state, err := conn.Subscribe(SubscriptionRequest{Instances: []int{2}, EventTypes: []string{"log:new"}})
+	if err != nil {
+		t.Fatalf("Unexpected subscribe error: %v", err)
+	}
+	if state.AllInstances || len(state.Instances) != 1 || state.Instances[0] != 2 {
+		t.Errorf("Expected subscription to instance 2 only, got %+v", state)
+	}
+	if conn.receives("log:new", 1) || !conn.receives("log:new", 2) {
+		t.Error("Expected only instance 2 to be delivered")
+	}
+	if conn.receives("metric:update", 2) {
+		t.Error("Expected unsubscribed event types to be filtered")
+	}
+
+	// Later subscriptions add to it
+	if _, err := conn.Subscribe(SubscriptionRequest{Instances: []int{3}}); err != nil {
+		t.Fatalf("Unexpected subscribe error: %v", err)
+	}
+	if !conn.receives("log:new", 3) {
+		t.Error("Expected instance 3 to be delivered after subscribing")
+	}
+
+	state, _ = conn.Unsubscribe(SubscriptionRequest{Instances: []int{2}})
+	if conn.receives("log:new", 2) {
+		t.Error("Expected instance 2 to be filtered after unsubscribing")
+	}
+	if len(state.Instances) != 1 || state.Instances[0] != 3 {
+		t.Errorf("Expected subscription to instance 3 only, got %+v", state)
+	}
+
+	// Unsubscribing from the default subscription excludes instances
+	admin := newTestConnection("user:2", InstanceAccess{All: true})
+	state, _ = admin.Unsubscribe(SubscriptionRequest{Instances: []int{7}, EventTypes: []string{"metric:update"}})
+	if !state.AllInstances || len(state.ExcludedInstances) != 1 {
+		t.Errorf("Expected instance 7 to be excluded, got %+v", state)
+	}
+	if admin.receives("log:new", 7) || admin.receives("metric:update", 8) || !admin.receives("log:new", 8) {
+		t.Error("Expected exclusions to be filtered")
+	}
+}
+
+// TestRefreshAccessRevokesInstances tests revoked access applies to live connections
+func TestRefreshAccessRevokesInstances(t *testing.T) {
+	cm := NewConnectionManager(zap.NewNop())
+	resolver := &stubAccessResolver{access: map[string]InstanceAccess{"user:1": {Instances: []int{1}}}}
+	cm.SetAccessResolver(resolver)
+
+	conn := newTestConnection("user:1", InstanceAccess{Instances: []int{1, 2}})
+	cm.mu.Lock()
+	cm.connections["user:1"] = []*Connection{conn}
+	cm.mu.Unlock()
+
+	if _, err := conn.Subscribe(SubscriptionRequest{Instances: []int{1, 2}}); err != nil {
+		t.Fatalf("Unexpected subscribe error: %v", err)
+	}
+
+	if err := cm.RefreshAccess(context.Background(), "user:1"); err != nil {
+		t.Fatalf("Unexpected refresh error: %v", err)
+	}
+	if conn.receives("log:new", 2) {
+		t.Error("Expected revoked instance to be filtered")
+	}
+
+	select {
+	case msg := <-conn.send:
+		event, ok := msg.(WebSocketEvent)
+		if !ok || event.Type != "access:updated" {
+			t.Fatalf("Expected access:updated event, got %+v", msg)
+		}
+		state := event.Data.(SubscriptionState)
+		if len(state.Instances) != 1 || state.Instances[0] != 1 {
+			t.Errorf("Expected revoked subscription to be dropped, got %+v", state)
+		}
+	default:
+		t.Fatal("Expected the client to be notified of the access change")
+	}
+
+	// Unchanged access is not announced again
+	cm.RefreshAllAccess(context.Background())
+	if len(conn.send) != 0 {
+		t.Error("Expected no notification for unchanged access")
+	}
+
+	// Broadcasts skip the revoked instance
+	if err := cm.broadcastWithFallback(WebSocketEvent{Type: "log:new"}, 2); err != nil {
+		t.Fatalf("Unexpected broadcast error: %v", err)
+	}
+	if len(conn.send) != 0 {
+		t.Error("Expected revoked instance events not to be queued")
+	}
+}
+
+// TestHandleSubscriptionMessages tests subscription replies sent to the client
+func TestHandleSubscriptionMessages(t *testing.T) {
+	conn := newTestConnection("user:1", InstanceAccess{Instances: []int{1}})
+
+	conn.handleSubscription("subscribe", json.RawMessage(`{"instances":[1],"event_types":["alert:triggered"]}`))
+	reply := (<-conn.send).(WebSocketEvent)
+	if reply.Type != "subscription" {
+		t.Errorf("Expected subscription reply, got %s", reply.Type)
+	}
+
+	conn.handleSubscription("subscribe", json.RawMessage(`{"instances":[9]}`))
+	reply = (<-conn.send).(WebSocketEvent)
+	if reply.Type != "error" {
+		t.Errorf("Expected error reply, got %s", reply.Type)
+	}
+
+	conn.handleSubscription("unsubscribe", json.RawMessage(`{"instances":"all"}`))
+	reply = (<-conn.send).(WebSocketEvent)
+	if reply.Type != "error" {
+		t.Errorf("Expected error reply for malformed request, got %s", reply.Type)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/torresglauco/pganalytics-v3/backend/internal/services/log_analysis"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/services/query_performance"
 	"github.com/torresglauco/pganalytics-v3/backend/internal/services/vacuum_advisor"
+	"github.com/torresglauco/pganalytics-v3/backend/internal/storage"
 
 	_ "github.com/lib/pq"
 )
@@ -395,7 +396,7 @@ func testBackendEndToEnd(t *testing.T, pgVersion string) {
 	indexRecs := ia.FindMissingIndexes(plans)
 
 	// 3. Vacuum Advisor
-	va := vacuum_advisor.NewVacuumAnalyzer(db)
+	va := vacuum_advisor.NewVacuumAnalyzer(storage.NewPostgresDBFromDB(db))
 	vacRec, vacErr := va.AnalyzeTable(context.Background(), vacuumMetrics.DatabaseID, vacuumMetrics.TableName)
 	_ = vacErr
 
@@ -190,7 +190,8 @@ json PgLockCollector::collectLockWaitChains(const std::string& dbname) {
     PGconn* conn = connectToDatabase(postgresHost_, postgresPort_, postgresUser_, postgresPassword_, dbname);
     if (!conn) return waits;
 
-    // Query for lock wait chains
+    // Query for lock wait chains. Only granted locks block: waiters queued
+    // for the same lock are not reported as blocking each other.
     const char* query = R"(
         SELECT
             blocked_locks.pid AS blocked_pid,
@@ -216,6 +217,7 @@ json PgLockCollector::collectLockWaitChains(const std::string& dbname) {
             AND blocking_locks.objid IS NOT DISTINCT FROM blocked_locks.objid
             AND blocking_locks.objsubid IS NOT DISTINCT FROM blocked_locks.objsubid
             AND blocking_locks.pid != blocked_locks.pid
+            AND blocking_locks.granted
         JOIN pg_catalog.pg_stat_activity blocking_activity ON blocking_activity.pid = blocking_locks.pid
         WHERE NOT blocked_locks.granted
     )";
@@ -161,7 +161,8 @@ function App() {
             <Route path="/channels" element={<ChannelsPage />} />
             <Route path="/collectors" element={<CollectorsManagement />} />
             <Route path="/query-performance/:databaseId" element={<QueryPerformancePage />} />
-            <Route path="/log-analysis/:databaseId" element={<LogAnalysisPage />} />
+            <Route path="/log-analysis" element={<LogAnalysisPage />} />
+            <Route path="/log-analysis/:collectorId" element={<LogAnalysisPage />} />
             <Route path="/index-advisor/:databaseId" element={<IndexAdvisorPage />} />
             <Route path="/vacuum-advisor/:databaseId" element={<VacuumAdvisor />} />
             <Route path="/topology/:collectorId" element={<ReplicationTopologyPage />} />
@@ -298,6 +298,8 @@ describe('Query Performance Integration Tests', () => {
   });
 });
 
+const collectorId = '6f1c2f8e-3b7a-4d2e-9c1a-5e8b7d4a2c10';
+
 describe('Log Analysis Integration Tests', () => {
   beforeEach(() => {
     vi.clearAllMocks();
@@ -309,7 +311,7 @@ describe('Log Analysis Integration Tests', () => {
 
   describe('useLogAnalysis Hook', () => {
     it('should establish WebSocket connection', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       await waitFor(() => {
         expect(result.current.connected).toBe(true);
@@ -317,7 +319,7 @@ describe('Log Analysis Integration Tests', () => {
     });
 
     it('should receive log messages via WebSocket', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       await waitFor(() => {
         expect(result.current.connected).toBe(true);
@@ -330,12 +332,17 @@ describe('Log Analysis Integration Tests', () => {
           mockWebSocket.onmessage(
             new MessageEvent('message', {
               data: JSON.stringify({
-                id: 1,
-                database_id: 1,
-                log_timestamp: new Date().toISOString(),
-                category: 'slow_query',
-                severity: 'LOG',
-                message: 'duration: 123.45 ms  execute query',
+                type: 'log',
+                offset: 1,
+                log: {
+                  id: 1,
+                  collector_id: collectorId,
+                  instance_id: 1,
+                  log_timestamp: new Date().toISOString(),
+                  log_level: 'LOG',
+                  log_message: 'duration: 123.45 ms  execute query',
+                  category: 'slow_query',
+                },
               }),
             })
           );
@@ -343,13 +350,26 @@ describe('Log Analysis Integration Tests', () => {
       }
 
       await waitFor(() => {
-        expect(result.current.logs.length).toBeGreaterThanOrEqual(0);
+        expect(result.current.logs).toHaveLength(1);
       });
+      expect(result.current.logs[0].message).toBe('duration: 123.45 ms  execute query');
+      expect(result.current.logs[0].severity).toBe('INFO');
     });
 
-    it('should handle WebSocket errors', async () => {
+    it('should reject an invalid collector ID without connecting', async () => {
+      lastMockWebSocket = null;
       const { result } = renderHook(() => useLogAnalysis('1'));
 
+      await waitFor(() => {
+        expect(result.current.error).toBe('Invalid collector ID');
+      });
+      expect(result.current.connected).toBe(false);
+      expect(lastMockWebSocket).toBeNull();
+    });
+
+    it('should handle WebSocket errors', async () => {
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
+
       await waitFor(() => {
         // Initial connection may succeed
         expect(result.current).toBeDefined();
@@ -357,7 +377,7 @@ describe('Log Analysis Integration Tests', () => {
     });
 
     it('should close WebSocket on unmount', async () => {
-      const { unmount } = renderHook(() => useLogAnalysis('1'));
+      const { unmount } = renderHook(() => useLogAnalysis(collectorId));
 
       await waitFor(() => {
         // Give WebSocket time to open
@@ -371,14 +391,14 @@ describe('Log Analysis Integration Tests', () => {
     });
 
     it('should initialize with empty logs', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       expect(result.current.logs).toBeDefined();
       expect(Array.isArray(result.current.logs)).toBe(true);
     });
 
     it('should track connection state', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       // Initially not connected
       expect(result.current.connected).toBe(false);
@@ -392,7 +412,7 @@ describe('Log Analysis Integration Tests', () => {
 
   describe('Log Analysis Data Processing', () => {
     it('should categorize error logs', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       await waitFor(() => {
         expect(result.current.connected).toBe(true);
@@ -419,7 +439,7 @@ describe('Log Analysis Integration Tests', () => {
     });
 
     it('should categorize slow queries', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       await waitFor(() => {
         expect(result.current.connected).toBe(true);
@@ -447,7 +467,7 @@ describe('Log Analysis Integration Tests', () => {
     });
 
     it('should maintain log history limit', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       await waitFor(() => {
         expect(result.current.connected).toBe(true);
@@ -481,7 +501,7 @@ describe('Log Analysis Integration Tests', () => {
     });
 
     it('should detect anomalies in log stream', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       await waitFor(() => {
         expect(result.current.connected).toBe(true);
@@ -513,18 +533,18 @@ describe('Log Analysis Integration Tests', () => {
 
   describe('WebSocket Connection Management', () => {
     it('should construct correct WebSocket URL', async () => {
-      const { result } = renderHook(() => useLogAnalysis('test-db-1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       await waitFor(() => {
         expect(result.current).toBeDefined();
       });
 
-      // URL should contain database ID
-      expect(true).toBe(true);
+      // URL should address the collector's stream
+      expect(lastMockWebSocket?.url).toContain(`/api/v1/logs/stream/${collectorId}`);
     });
 
     it('should use correct protocol (ws vs wss)', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       await waitFor(() => {
         expect(result.current.connected).toBe(true);
@@ -535,7 +555,7 @@ describe('Log Analysis Integration Tests', () => {
     });
 
     it('should handle connection timeout gracefully', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       // Connection should be attempted
       await waitFor(() => {
@@ -544,7 +564,7 @@ describe('Log Analysis Integration Tests', () => {
     });
 
     it('should reconnect on connection loss', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       await waitFor(() => {
         expect(result.current.connected).toBe(true);
@@ -567,7 +587,7 @@ describe('Log Analysis Integration Tests', () => {
 
   describe('Error Handling in Log Analysis', () => {
     it('should handle malformed JSON in log message', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       await waitFor(() => {
         expect(result.current.connected).toBe(true);
@@ -590,7 +610,7 @@ describe('Log Analysis Integration Tests', () => {
     });
 
     it('should handle WebSocket errors', async () => {
-      const { result } = renderHook(() => useLogAnalysis('1'));
+      const { result } = renderHook(() => useLogAnalysis(collectorId));
 
       await waitFor(() => {
         expect(result.current.connected).toBe(true);
@@ -676,7 +696,7 @@ describe('End-to-End Data Flow Integration', () => {
   });
 
   it('should complete full log analysis pipeline', async () => {
-    const { result } = renderHook(() => useLogAnalysis('1'));
+    const { result } = renderHook(() => useLogAnalysis(collectorId));
 
     await waitFor(() => {
       expect(result.current.connected).toBe(true);
@@ -1,8 +1,8 @@
 import React from 'react'
 import { useLogAnalysis } from '../../hooks/useLogAnalysis'
 
-export const LogStream: React.FC<{ databaseId: string }> = ({ databaseId }) => {
-  const { logs, connected, error } = useLogAnalysis(databaseId)
+export const LogStream: React.FC<{ collectorId: string }> = ({ collectorId }) => {
+  const { logs, connected, error } = useLogAnalysis(collectorId)
 
   const severityColors = {
     INFO: 'text-blue-600 dark:text-blue-400',
@@ -18,7 +18,7 @@ const navItems: NavItem[] = [
 
   // Main - Advanced Features
   { section: 'main', icon: '⚡', label: 'Query Performance', href: '/query-performance/1' },
-  { section: 'main', icon: '📊', label: 'Log Analysis', href: '/log-analysis/1' },
+  { section: 'main', icon: '📊', label: 'Log Analysis', href: '/log-analysis' },
   { section: 'main', icon: '📇', label: 'Index Advisor', href: '/index-advisor/1' },
   { section: 'main', icon: '🧹', label: 'VACUUM Advisor', href: '/vacuum-advisor/1' },
   { section: 'main', icon: '📁', label: 'Collectors', href: '/collectors' },
@@ -1,19 +1,44 @@
 import { useState, useEffect } from 'react'
-import { LogEntry } from '../types/logAnalysis'
+import { LogEntry, LogStreamMessage } from '../types/logAnalysis'
 
-export const useLogAnalysis = (databaseId: string) => {
+const UUID_PATTERN = /^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$/i
+
+// Maps PostgreSQL log levels onto the severities the log viewer displays
+const toSeverity = (level: string): LogEntry['severity'] => {
+  switch (level) {
+    case 'WARNING':
+      return 'WARNING'
+    case 'ERROR':
+      return 'ERROR'
+    case 'FATAL':
+    case 'PANIC':
+      return 'FATAL'
+    default:
+      return 'INFO'
+  }
+}
+
+export const useLogAnalysis = (collectorId: string) => {
   const [logs, setLogs] = useState<LogEntry[]>([])
   const [connected, setConnected] = useState(false)
   const [error, setError] = useState<string | null>(null)
 
   useEffect(() => {
-    if (!databaseId) {
+    if (!collectorId) {
+      setConnected(false)
+      return
+    }
+    if (!UUID_PATTERN.test(collectorId)) {
+      setError('Invalid collector ID')
       setConnected(false)
       return
     }
 
+    // WHY: Browsers cannot set headers on a WebSocket handshake. The stream
+    // is served from the same origin as the API, so the httpOnly auth_token
+    // cookie set at login authenticates the handshake, like other API calls.
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
-    const wsUrl = `${protocol}//${window.location.host}/api/v1/logs/stream/${databaseId}`
+    const wsUrl = `${protocol}//${window.location.host}/api/v1/logs/stream/${collectorId}`
 
     let ws: WebSocket
 
@@ -27,7 +52,19 @@ export const useLogAnalysis = (databaseId: string) => {
 
       ws.onmessage = (event) => {
         try {
-          const newLog = JSON.parse(event.data) as LogEntry
+          const message = JSON.parse(event.data) as LogStreamMessage
+          if (message.type !== 'log' || !message.log) {
+            return
+          }
+          const log = message.log
+          const newLog: LogEntry = {
+            id: log.id,
+            database_id: log.database_id ?? 0,
+            log_timestamp: log.log_timestamp,
+            category: log.category ?? 'log',
+            severity: toSeverity(log.log_level),
+            message: log.log_message,
+          }
           setLogs((prev) => [newLog, ...prev].slice(0, 100))
         } catch (err) {
           console.error('Failed to parse log message:', err)
@@ -53,7 +90,7 @@ export const useLogAnalysis = (databaseId: string) => {
       setError(err instanceof Error ? err.message : 'Failed to create WebSocket')
       setConnected(false)
     }
-  }, [databaseId])
+  }, [collectorId])
 
   return { logs, connected, error }
 }
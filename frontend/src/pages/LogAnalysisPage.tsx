@@ -1,12 +1,48 @@
 import React from 'react'
-import { useParams } from 'react-router-dom'
+import { Link, useParams } from 'react-router-dom'
 import { MainLayout } from '../components/layout/MainLayout'
 import { LogStream } from '../components/LogInsights/LogStream'
+import { useCollectors } from '../hooks/useCollectors'
+
+// Logs are streamed per collector; without one the page lists the collectors
+// to pick from
+const CollectorPicker: React.FC = () => {
+  const { collectors, loading, error } = useCollectors()
+
+  if (loading) {
+    return <p className="text-slate-600 dark:text-slate-400">Loading collectors...</p>
+  }
+  if (error) {
+    return (
+      <div className="bg-red-50 dark:bg-red-900/20 p-6 rounded-lg border border-red-200 dark:border-red-800">
+        <p className="text-red-800 dark:text-red-300">{error.message}</p>
+      </div>
+    )
+  }
+  if (collectors.length === 0) {
+    return <p className="text-slate-600 dark:text-slate-400">No collectors registered yet.</p>
+  }
+
+  return (
+    <ul className="divide-y divide-slate-200 dark:divide-slate-700">
+      {collectors.map((collector) => (
+        <li key={collector.id} className="py-3">
+          <Link
+            to={`/log-analysis/${collector.id}`}
+            className="text-blue-600 dark:text-blue-400 hover:underline"
+          >
+            {collector.name || collector.hostname}
+          </Link>
+        </li>
+      ))}
+    </ul>
+  )
+}
 
 export const LogAnalysisPage: React.FC = () => {
-  const { databaseId } = useParams<{ databaseId: string }>()
+  const { collectorId } = useParams<{ collectorId: string }>()
 
-  if (!databaseId) {
+  if (!collectorId) {
     return (
       <MainLayout>
         <div className="space-y-6">
@@ -18,10 +54,11 @@ export const LogAnalysisPage: React.FC = () => {
               Real-time PostgreSQL log insights
             </p>
           </div>
-          <div className="bg-red-50 dark:bg-red-900/20 p-6 rounded-lg border border-red-200 dark:border-red-800">
-            <p className="text-red-800 dark:text-red-300">
-              Invalid database ID. Please select a database first.
-            </p>
+          <div className="bg-white dark:bg-slate-800 p-6 rounded-lg shadow">
+            <h2 className="text-xl font-bold mb-4 text-slate-900 dark:text-white">
+              Select a collector
+            </h2>
+            <CollectorPicker />
           </div>
         </div>
       </MainLayout>
@@ -45,7 +82,7 @@ export const LogAnalysisPage: React.FC = () => {
             <h2 className="text-xl font-bold mb-4 text-slate-900 dark:text-white">
               Live Log Stream
             </h2>
-            <LogStream databaseId={databaseId} />
+            <LogStream collectorId={collectorId} />
           </div>
         </div>
       </div>
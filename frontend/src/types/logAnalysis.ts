@@ -24,3 +24,24 @@ export interface LogAnomaly {
   anomaly_score: number
   deviation_from_baseline: number
 }
+
+// A stored PostgreSQL log as pushed by the log stream
+export interface StreamedLog {
+  id: number
+  collector_id: string
+  instance_id: number
+  database_id?: number
+  log_timestamp: string
+  log_level: string
+  log_message: string
+  category?: string
+}
+
+// Message sent on /api/v1/logs/stream/:collector_id; offsets are log IDs
+export interface LogStreamMessage {
+  type: 'log' | 'gap'
+  offset?: number
+  log?: StreamedLog
+  dropped?: number
+  resume_from?: number
+}